@@ -0,0 +1,48 @@
+package deployment
+
+import (
+	"fmt"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/image/imageutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// unresolvedImageError is returned when the custom strategy image stream tag of a deployment
+// config can not be resolved. It fails the rollout rather than being retried.
+type unresolvedImageError string
+
+func (e unresolvedImageError) Error() string {
+	return string(e)
+}
+
+// resolveCustomStrategyImage returns the strategy of the deployment config with the image of its
+// custom parameters resolved from its custom strategy image stream tag, so that the deployer pod
+// runs the image the tag currently points to. The deployment config is not changed.
+func (c *DeploymentController) resolveCustomStrategyImage(config *appsv1.DeploymentConfig) (*appsv1.DeploymentStrategy, error) {
+	namespace, istag, ok := deployutil.CustomStrategyImageStreamTag(config)
+	if !ok {
+		return &config.Spec.Strategy, nil
+	}
+	name, tag, ok := imageutil.SplitImageStreamTag(istag)
+	if !ok {
+		return nil, unresolvedImageError(fmt.Sprintf("invalid custom strategy image stream tag %q", istag))
+	}
+	stream, err := c.isLister.ImageStreams(namespace).Get(name)
+	switch {
+	case kerrors.IsNotFound(err):
+		return nil, unresolvedImageError(fmt.Sprintf("custom strategy image stream %s/%s not found", namespace, name))
+	case err != nil:
+		return nil, fmt.Errorf("couldn't get custom strategy image stream %s/%s: %v", namespace, name, err)
+	}
+	image, ok := imageutil.ResolveLatestTaggedImage(stream, tag)
+	if !ok {
+		return nil, unresolvedImageError(fmt.Sprintf("custom strategy image stream tag %s/%s:%s has no image", namespace, name, tag))
+	}
+
+	strategy := config.Spec.Strategy.DeepCopy()
+	strategy.CustomParams.Image = image
+	return strategy, nil
+}
@@ -0,0 +1,129 @@
+package deployment
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	imagev1lister "github.com/openshift/client-go/image/listers/image/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestHandle_customStrategyImage(t *testing.T) {
+	const digest = "sha256:4a5e2bf7af5f1d3a7d5a7e1f7b42a96fe2a0a4e2b5b6e03f1b6c9d3a7c1e2f3d"
+	tooling := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "tooling"},
+		Status: imagev1.ImageStreamStatus{
+			DockerImageRepository: "image-registry.openshift-image-registry.svc:5000/tooling/deployer",
+			Tags: []imagev1.NamedTagEventList{{
+				Tag:   "stable",
+				Items: []imagev1.TagEvent{{DockerImageReference: "quay.io/tooling/deployer@" + digest, Image: digest}},
+			}},
+		},
+	}
+	local := tooling.DeepCopy()
+	local.Namespace = metav1.NamespaceDefault
+	local.Status.DockerImageRepository = "image-registry.openshift-image-registry.svc:5000/default/deployer"
+	local.Status.Tags[0].Items[0].DockerImageReference = "quay.io/default/deployer@" + digest
+
+	tests := []struct {
+		name       string
+		annotation string
+
+		expectedImage  string
+		expectedReason string
+	}{
+		{
+			name:          "no image stream tag",
+			expectedImage: "openshift/origin-deployer",
+		},
+		{
+			name:          "image stream tag",
+			annotation:    "deployer:stable",
+			expectedImage: "quay.io/default/deployer@" + digest,
+		},
+		{
+			name:          "image stream tag of another namespace",
+			annotation:    "tooling/deployer:stable",
+			expectedImage: "quay.io/tooling/deployer@" + digest,
+		},
+		{
+			name:           "missing tag",
+			annotation:     "tooling/deployer:latest",
+			expectedReason: deployutil.DeploymentFailedCustomStrategyImageUnresolved,
+		},
+		{
+			name:           "missing image stream",
+			annotation:     "other/deployer:stable",
+			expectedReason: deployutil.DeploymentFailedCustomStrategyImageUnresolved,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				createdPod        *corev1.Pod
+				updatedDeployment *corev1.ReplicationController
+			)
+			client := &fake.Clientset{}
+			client.AddReactor("create", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				createdPod = action.(clientgotesting.CreateAction).GetObject().(*corev1.Pod)
+				return true, createdPod, nil
+			})
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				return true, updatedDeployment, nil
+			})
+
+			config := appstest.OkDeploymentConfig(1)
+			config.Spec.Strategy = appstest.OkCustomStrategy()
+			if len(test.annotation) > 0 {
+				config.Annotations = map[string]string{deployutil.CustomStrategyImageStreamTagAnnotation: test.annotation}
+			}
+			deployment, _ := appsutil.MakeDeployment(config)
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusNew)
+
+			controller := okDeploymentController(client, nil, nil, true, corev1.PodUnknown)
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			indexer.Add(tooling)
+			indexer.Add(local)
+			controller.isLister = imagev1lister.NewImageStreamLister(indexer)
+
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(test.expectedReason) > 0 {
+				if createdPod != nil {
+					t.Fatalf("expected no deployer pod to be created")
+				}
+				if updatedDeployment == nil || appsutil.DeploymentStatusFor(updatedDeployment) != appsv1.DeploymentStatusFailed {
+					t.Fatalf("expected the rollout to fail, got %v", updatedDeployment)
+				}
+				if e, a := test.expectedReason, updatedDeployment.Annotations[appsv1.DeploymentStatusReasonAnnotation]; e != a {
+					t.Errorf("expected status reason %q, got %q", e, a)
+				}
+				return
+			}
+			if createdPod == nil {
+				t.Fatalf("expected a deployer pod to be created")
+			}
+			if e, a := test.expectedImage, createdPod.Spec.Containers[0].Image; e != a {
+				t.Errorf("expected the deployer image %q, got %q", e, a)
+			}
+			if e, a := "openshift/origin-deployer", config.Spec.Strategy.CustomParams.Image; e != a {
+				t.Errorf("expected the deployment config to be left unchanged, got image %q", a)
+			}
+		})
+	}
+}
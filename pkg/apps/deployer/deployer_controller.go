@@ -23,11 +23,14 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 
 	appsv1 "github.com/openshift/api/apps/v1"
+	imagev1lister "github.com/openshift/client-go/image/listers/image/v1"
 
 	"github.com/openshift/library-go/pkg/apps/appsserialization"
 	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
 )
 
 // maxRetryCount is the maximum number of times the controller will retry errors.
@@ -59,6 +62,43 @@ type actionableError string
 
 func (e actionableError) Error() string { return string(e) }
 
+// DeployerPodDefaults are the defaults of the deployer pods of all deployment configs.
+type DeployerPodDefaults struct {
+	// Resources are the resource requirements of the deployer pods of deployment configs whose
+	// strategy has none. Deployer pods without resource requirements are defaulted by the limit
+	// range of their namespace.
+	Resources corev1.ResourceRequirements
+	// NodeSelector is merged into the node selector of deployer pods. The deployer node selector
+	// annotation of the deployment config takes precedence.
+	NodeSelector map[string]string
+	// Tolerations are added to the tolerations of deployer pods.
+	Tolerations []corev1.Toleration
+	// Affinity and TopologySpreadConstraints are the affinity and the topology spread constraints
+	// of deployer pods. The deployer affinity and topology spread constraints annotations of the
+	// deployment config replace them.
+	Affinity                  *corev1.Affinity
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
+	// CompleteRetention and FailedRetention are the numbers of complete and failed rollouts of a
+	// deployment config whose deployer and hook pods are kept once they terminated, unless the
+	// deployment config sets its own with the deployer pod retention annotations. The pods of
+	// all rollouts are kept when they are nil.
+	CompleteRetention *int
+	FailedRetention   *int
+	// FailedTTL is how long the deployer and hook pods of failed rollouts are kept once they
+	// terminated. The pods of the latest failed rollout of a deployment config are kept
+	// regardless. They are kept forever when it is zero.
+	FailedTTL time.Duration
+	// FailureLogBytes is the size of the tail of the deployer pod log that is recorded on the
+	// deployments of failed rollouts. No log is recorded when it is zero.
+	FailureLogBytes int
+	// ImagePullPolicy is the pull policy of the deployer image, IfNotPresent when empty.
+	ImagePullPolicy corev1.PullPolicy
+	// ImagePullSecret names a secret that is added to the image pull secrets deployer pods copy
+	// from the pod template of their deployment, e.g. for a mirrored deployer image. It must
+	// exist in the namespaces of the deployments.
+	ImagePullSecret string
+}
+
 // DeploymentController starts a deployment by creating a deployer pod which
 // implements a deployment strategy. The status of the deployment will follow
 // the status of the deployer pod. The deployer pod is correlated to the
@@ -73,6 +113,10 @@ type DeploymentController struct {
 	rn kcoreclient.ReplicationControllersGetter
 	// pn is used for creating, updating, and deleting deployer pods.
 	pn kcoreclient.PodsGetter
+	// sn is used for checking that the deployer service accounts of deployment configs exist.
+	sn kcoreclient.ServiceAccountsGetter
+	// podLogs is used for fetching the logs of the deployer pods of failed rollouts.
+	podLogs podLogsGetter
 
 	// queue contains replication controllers that need to be synced.
 	queue workqueue.RateLimitingInterface
@@ -85,6 +129,10 @@ type DeploymentController struct {
 	podLister kcorelisters.PodLister
 	// podListerSynced makes sure the pod store is synced before reconcling any deployment.
 	podListerSynced cache.InformerSynced
+	// isLister resolves the custom strategy images of deployment configs from image streams.
+	isLister imagev1lister.ImageStreamLister
+	// isListerSynced makes sure the image stream store is synced before reconcling any deployment.
+	isListerSynced cache.InformerSynced
 
 	// deployerImage specifies which container image can support the default strategies.
 	deployerImage string
@@ -93,8 +141,12 @@ type DeploymentController struct {
 	// environment is a set of environment variables which should be injected into all
 	// deployer pod containers.
 	environment []corev1.EnvVar
+	// podDefaults are the defaults of all deployer pods.
+	podDefaults DeployerPodDefaults
 	// recorder is used to record events.
 	recorder record.EventRecorder
+	// clock is used to measure the progress deadline of deployments.
+	clock clock.Clock
 }
 
 // handle processes a deployment and either creates a deployer pod or responds
@@ -109,6 +161,7 @@ func (c *DeploymentController) handle(deployment *corev1.ReplicationController,
 
 	currentStatus := appsutil.DeploymentStatusFor(deployment)
 	nextStatus := currentStatus
+	deployerLogRecorded := false
 
 	deployerPodName := appsutil.DeployerPodNameForDeployment(deployment.Name)
 	deployer, deployerErr := c.podLister.Pods(deployment.Namespace).Get(deployerPodName)
@@ -154,8 +207,28 @@ func (c *DeploymentController) handle(deployment *corev1.ReplicationController,
 				return nil
 			}
 
+			// Fail rollouts whose deployer service account does not exist rather than
+			// waiting for their deployer pod to be admitted.
+			if exists, err := c.deployerServiceAccountExists(config); err != nil {
+				return err
+			} else if !exists {
+				serviceAccount := deployutil.DeployerServiceAccount(config, c.serviceAccount)
+				nextStatus = appsv1.DeploymentStatusFailed
+				updatedAnnotations[appsv1.DeploymentStatusReasonAnnotation] = deployutil.DeploymentFailedDeployerServiceAccountNotFound
+				c.emitDeploymentEvent(deployment, corev1.EventTypeWarning, "FailedCreate", fmt.Sprintf("Error creating deployer pod: deployer service account %q does not exist", serviceAccount))
+				klog.V(4).Infof("Failing deployment %s/%s as its deployer service account %q does not exist", deployment.Namespace, deployment.Name, serviceAccount)
+				break
+			}
+
 			// Generate a deployer pod spec.
 			deployerPod, err := c.makeDeployerPod(deployment)
+			if unresolved, ok := err.(unresolvedImageError); ok {
+				nextStatus = appsv1.DeploymentStatusFailed
+				updatedAnnotations[appsv1.DeploymentStatusReasonAnnotation] = deployutil.DeploymentFailedCustomStrategyImageUnresolved
+				c.emitDeploymentEvent(deployment, corev1.EventTypeWarning, "FailedCreate", fmt.Sprintf("Error creating deployer pod: %v", unresolved))
+				klog.V(4).Infof("Failing deployment %s/%s as its custom strategy image could not be resolved: %v", deployment.Namespace, deployment.Name, unresolved)
+				break
+			}
 			if err != nil {
 				return fatalError(fmt.Sprintf("couldn't make deployer pod for %q: %v", appsutil.LabelForDeployment(deployment), err))
 			}
@@ -166,6 +239,14 @@ func (c *DeploymentController) handle(deployment *corev1.ReplicationController,
 				// if we cannot create a deployment pod (i.e lack of quota), match normal replica set experience and
 				// emit an event.
 				c.emitDeploymentEvent(deployment, corev1.EventTypeWarning, "FailedCreate", fmt.Sprintf("Error creating deployer pod: %v", err))
+				// Let the deployment config explain why the rollout does not start.
+				if isQuotaExceeded(err) && setDeployerPendingReason(updatedAnnotations, deployutil.DeployerQuotaExceededReason, err.Error()) {
+					deploymentCopy := deployment.DeepCopy()
+					deploymentCopy.Annotations = updatedAnnotations
+					if _, err := c.rn.ReplicationControllers(deploymentCopy.Namespace).Update(context.TODO(), deploymentCopy, metav1.UpdateOptions{}); err != nil {
+						klog.V(2).Infof("Unable to record the deployer pod pending reason of %q: %v", appsutil.LabelForDeployment(deployment), err)
+					}
+				}
 				return actionableError(fmt.Sprintf("couldn't create deployer pod for %q: %v", appsutil.LabelForDeployment(deployment), err))
 			}
 			updatedAnnotations[appsv1.DeploymentPodAnnotation] = deploymentPod.Name
@@ -241,12 +322,29 @@ func (c *DeploymentController) handle(deployment *corev1.ReplicationController,
 				if err := c.cleanupDeployerPods(deployment); err != nil {
 					return err
 				}
+			} else if seconds, exceeded := c.progressDeadlineExceeded(deployment, deployer); exceeded && nextStatus != appsv1.DeploymentStatusComplete {
+				// Fail rollouts that did not become available within their progress deadline
+				// and stop their deployer pods. The deployment config controller then scales
+				// the deployments as for any other failed rollout.
+				nextStatus = appsv1.DeploymentStatusFailed
+				updatedAnnotations[appsv1.DeploymentStatusReasonAnnotation] = deployutil.DeploymentFailedProgressDeadlineExceeded
+				c.emitDeploymentEvent(deployment, corev1.EventTypeWarning, deployutil.ProgressDeadlineExceededEventReason,
+					fmt.Sprintf(deployutil.ProgressDeadlineExceededEventMessage, appsutil.LabelForDeployment(deployment), seconds))
+				c.recordDeployerLog(deployment, deployer, updatedAnnotations)
+				deployerLogRecorded = true
+				if err := c.cleanupDeployerPods(deployment); err != nil {
+					return err
+				}
 			} else {
 				// Set an ownerRef for the deployment lifecycle pods so they are cleaned up when the
 				// replication controller is deleted.
 				if err := c.setDeployerPodsOwnerRef(deployment); err != nil {
 					return err
 				}
+				// Stop the lifecycle hook pods that run longer than their own hook timeout.
+				if err := c.enforceHookTimeouts(deployment); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -269,10 +367,35 @@ func (c *DeploymentController) handle(deployment *corev1.ReplicationController,
 		// preserve deployer pods on completed deployments
 	}
 
+	// Record why the deployer pod of a pending rollout does not run, and forget it once the
+	// deployer pod runs.
+	pendingReasonChanged := false
+	if nextStatus != appsv1.DeploymentStatusNew {
+		reason, message := "", ""
+		if deployerErr == nil && nextStatus == appsv1.DeploymentStatusPending {
+			reason, message = deployerPendingReason(deployer)
+		}
+		pendingReasonChanged = setDeployerPendingReason(updatedAnnotations, reason, message)
+	}
+
+	if appsutil.IsTerminatedDeployment(deployment) {
+		if err := c.pruneDeployerPods(deployment); err != nil {
+			return err
+		}
+	}
+
 	deploymentCopy := deployment.DeepCopy()
 
 	// Update only if we need to transition to a new phase.
 	if appsutil.CanTransitionPhase(currentStatus, nextStatus) {
+		// Keep the reason of failed rollouts around once their deployer pod is gone.
+		if nextStatus == appsv1.DeploymentStatusFailed && deployerErr == nil && !deployerLogRecorded && !appsutil.IsDeploymentCancelled(deployment) {
+			c.recordDeployerLog(deployment, deployer, updatedAnnotations)
+		}
+		// Rollouts cancelled with the cancellation annotation alone carry no reason.
+		if nextStatus == appsv1.DeploymentStatusFailed && appsutil.IsDeploymentCancelled(deployment) && len(updatedAnnotations[appsv1.DeploymentStatusReasonAnnotation]) == 0 {
+			updatedAnnotations[appsv1.DeploymentStatusReasonAnnotation] = deployutil.DeploymentFailedCancelled
+		}
 		updatedAnnotations[appsv1.DeploymentStatusAnnotation] = string(nextStatus)
 		deploymentCopy.Annotations = updatedAnnotations
 
@@ -293,11 +416,21 @@ func (c *DeploymentController) handle(deployment *corev1.ReplicationController,
 		if appsutil.IsDeploymentCancelled(deploymentCopy) && appsutil.IsFailedDeployment(deploymentCopy) {
 			c.emitDeploymentEvent(deploymentCopy, corev1.EventTypeNormal, "RolloutCancelled", fmt.Sprintf("Rollout for %q cancelled", appsutil.LabelForDeployment(deploymentCopy)))
 		}
+	} else if pendingReasonChanged {
+		deploymentCopy.Annotations = updatedAnnotations
+		if _, err := c.rn.ReplicationControllers(deploymentCopy.Namespace).Update(context.TODO(), deploymentCopy, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("couldn't update the deployer pod pending reason of %q: %v", appsutil.LabelForDeployment(deploymentCopy), err)
+		}
 	}
 	return nil
 }
 
 func (c *DeploymentController) nextStatus(pod *corev1.Pod, deployment *corev1.ReplicationController, updatedAnnotations map[string]string) appsv1.DeploymentStatus {
+	// Record the start of deployer pods that were not started yet when the deployment
+	// became pending.
+	if _, ok := updatedAnnotations[appsv1.DeployerPodStartedAtAnnotation]; !ok && pod.Status.StartTime != nil {
+		updatedAnnotations[appsv1.DeployerPodStartedAtAnnotation] = pod.Status.StartTime.String()
+	}
 	switch pod.Status.Phase {
 	case corev1.PodPending:
 		return appsv1.DeploymentStatusPending
@@ -306,11 +439,20 @@ func (c *DeploymentController) nextStatus(pod *corev1.Pod, deployment *corev1.Re
 		return appsv1.DeploymentStatusRunning
 
 	case corev1.PodSucceeded:
+		// Deployments whose pods did not stay ready for the minimum ready seconds of
+		// their deployment config keep running until enough of them are available.
+		if !minimumAvailable(deployment) {
+			klog.V(4).Infof("Waiting for the pods of %q to become available before completing its rollout", appsutil.LabelForDeployment(deployment))
+			return appsv1.DeploymentStatusRunning
+		}
 		// If the deployment was cancelled just prior to the deployer pod succeeding
 		// then we need to remove the cancel annotations from the complete deployment
-		// and emit an event letting users know their cancellation failed.
-		if appsutil.IsDeploymentCancelled(deployment) {
+		// and emit an event letting users know their cancellation failed. Cancelling
+		// a deployment that is already complete changes nothing.
+		if appsutil.IsDeploymentCancelled(deployment) && appsutil.DeploymentStatusFor(deployment) != appsv1.DeploymentStatusComplete {
 			appsutil.DeleteStatusReasons(deployment)
+			delete(updatedAnnotations, appsv1.DeploymentStatusReasonAnnotation)
+			delete(updatedAnnotations, appsv1.DeploymentCancelledAnnotation)
 			c.emitDeploymentEvent(deployment, corev1.EventTypeWarning, "FailedCancellation", "Succeeded before cancel recorded")
 		}
 		// Sync the internal replica annotation with the target so that we can
@@ -333,6 +475,25 @@ func (c *DeploymentController) nextStatus(pod *corev1.Pod, deployment *corev1.Re
 	return appsv1.DeploymentStatusNew
 }
 
+// minimumAvailable returns true unless the deployment config of the deployment sets minimum ready
+// seconds and fewer pods of the deployment than its minimum availability requires have been ready
+// for them, according to the status of the deployment. Deployments whose status does not reflect
+// their latest generation yet are not available.
+func minimumAvailable(deployment *corev1.ReplicationController) bool {
+	config, err := appsserialization.DecodeDeploymentConfig(deployment)
+	if err != nil || config.Spec.MinReadySeconds == 0 {
+		return true
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	desired, ok := deployutil.DesiredReplicas(deployment)
+	if !ok {
+		desired = config.Spec.Replicas
+	}
+	return deployment.Status.AvailableReplicas >= desired-appsutil.MaxUnavailable(config)
+}
+
 // getPodTerminatedTimestamp gets the first terminated container in a pod and
 // return its termination timestamp.
 func getPodTerminatedTimestamp(pod *corev1.Pod) *metav1.Time {
@@ -359,7 +520,11 @@ func (c *DeploymentController) makeDeployerPod(deployment *corev1.ReplicationCon
 		return nil, err
 	}
 
-	container := c.makeDeployerContainer(&deploymentConfig.Spec.Strategy)
+	strategy, err := c.resolveCustomStrategyImage(deploymentConfig)
+	if err != nil {
+		return nil, err
+	}
+	container := c.makeDeployerContainer(strategy)
 
 	// Add deployment environment variables to the container.
 	envVars := []corev1.EnvVar{}
@@ -375,6 +540,15 @@ func (c *DeploymentController) makeDeployerPod(deployment *corev1.ReplicationCon
 		maxDeploymentDurationSeconds = *(deploymentConfig.Spec.Strategy.ActiveDeadlineSeconds)
 	}
 
+	nodeSelector, tolerations, err := c.makeDeployerPlacement(deployment, deploymentConfig)
+	if err != nil {
+		return nil, err
+	}
+	affinity, topologySpreadConstraints, err := c.makeDeployerScheduling(deploymentConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	gracePeriod := int64(10)
 	shareProcessNamespace := false
 
@@ -406,7 +580,7 @@ func (c *DeploymentController) makeDeployerPod(deployment *corev1.ReplicationCon
 					Args:                     container.Args,
 					Image:                    container.Image,
 					Env:                      envVars,
-					Resources:                deploymentConfig.Spec.Strategy.Resources,
+					Resources:                container.Resources,
 					TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
 				},
 			},
@@ -414,13 +588,15 @@ func (c *DeploymentController) makeDeployerPod(deployment *corev1.ReplicationCon
 			DNSPolicy:             deployment.Spec.Template.Spec.DNSPolicy,
 			DNSConfig:             deployment.Spec.Template.Spec.DNSConfig,
 			EnableServiceLinks:    deployment.Spec.Template.Spec.EnableServiceLinks,
-			ImagePullSecrets:      deployment.Spec.Template.Spec.ImagePullSecrets,
-			Tolerations:           deployment.Spec.Template.Spec.Tolerations,
+			ImagePullSecrets:      c.makeDeployerImagePullSecrets(deployment),
+			Tolerations:           tolerations,
 			// Setting the node selector on the deployer pod so that it is created
 			// on the same set of nodes as the pods.
-			NodeSelector:                  deployment.Spec.Template.Spec.NodeSelector,
+			NodeSelector:                  nodeSelector,
+			Affinity:                      affinity,
+			TopologySpreadConstraints:     topologySpreadConstraints,
 			RestartPolicy:                 corev1.RestartPolicyNever,
-			ServiceAccountName:            c.serviceAccount,
+			ServiceAccountName:            deployutil.DeployerServiceAccount(deploymentConfig, c.serviceAccount),
 			TerminationGracePeriodSeconds: &gracePeriod,
 			ShareProcessNamespace:         &shareProcessNamespace,
 			ResourceClaims:                []corev1.PodResourceClaim{},
@@ -443,10 +619,116 @@ func (c *DeploymentController) makeDeployerPod(deployment *corev1.ReplicationCon
 	}
 
 	pod.Spec.Containers[0].ImagePullPolicy = corev1.PullIfNotPresent
+	if len(c.podDefaults.ImagePullPolicy) > 0 {
+		pod.Spec.Containers[0].ImagePullPolicy = c.podDefaults.ImagePullPolicy
+	}
 
 	return pod, nil
 }
 
+// makeDeployerImagePullSecrets returns the image pull secrets of the pod template of the
+// deployment with the deployer image pull secret of the controller.
+func (c *DeploymentController) makeDeployerImagePullSecrets(deployment *corev1.ReplicationController) []corev1.LocalObjectReference {
+	secrets := deployment.Spec.Template.Spec.ImagePullSecrets
+	if len(c.podDefaults.ImagePullSecret) == 0 {
+		return secrets
+	}
+	for _, secret := range secrets {
+		if secret.Name == c.podDefaults.ImagePullSecret {
+			return secrets
+		}
+	}
+	return append(append([]corev1.LocalObjectReference{}, secrets...), corev1.LocalObjectReference{Name: c.podDefaults.ImagePullSecret})
+}
+
+// deployerServiceAccountExists returns false if the deployment config has a deployer service
+// account annotation naming a service account that does not exist. The service account of the
+// controller is not checked.
+func (c *DeploymentController) deployerServiceAccountExists(config *appsv1.DeploymentConfig) (bool, error) {
+	serviceAccount := deployutil.DeployerServiceAccount(config, c.serviceAccount)
+	if serviceAccount == c.serviceAccount {
+		return true, nil
+	}
+	_, err := c.sn.ServiceAccounts(config.Namespace).Get(context.TODO(), serviceAccount, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		return false, nil
+	case err != nil:
+		return false, fmt.Errorf("couldn't get deployer service account %s/%s: %v", config.Namespace, serviceAccount, err)
+	}
+	return true, nil
+}
+
+// makeDeployerPlacement returns the node selector and the tolerations of the deployer pod of the
+// deployment. They are copies of those of the pod template of the deployment, merged with the
+// defaults of the controller and the deployer node selector and tolerations annotations of the
+// deployment config, so that the template of the deployment is never changed.
+func (c *DeploymentController) makeDeployerPlacement(deployment *corev1.ReplicationController, config *appsv1.DeploymentConfig) (map[string]string, []corev1.Toleration, error) {
+	configNodeSelector, err := deployutil.DeployerNodeSelector(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	configTolerations, err := deployutil.DeployerTolerations(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var nodeSelector map[string]string
+	for _, selector := range []map[string]string{deployment.Spec.Template.Spec.NodeSelector, c.podDefaults.NodeSelector, configNodeSelector} {
+		if selector != nil && nodeSelector == nil {
+			nodeSelector = map[string]string{}
+		}
+		for k, v := range selector {
+			nodeSelector[k] = v
+		}
+	}
+
+	var tolerations []corev1.Toleration
+	for _, list := range [][]corev1.Toleration{deployment.Spec.Template.Spec.Tolerations, c.podDefaults.Tolerations, configTolerations} {
+		for i := range list {
+			if !hasToleration(tolerations, &list[i]) {
+				tolerations = append(tolerations, *list[i].DeepCopy())
+			}
+		}
+	}
+	return nodeSelector, tolerations, nil
+}
+
+// makeDeployerScheduling returns the affinity and the topology spread constraints of the deployer
+// pod of the deployment config. Unlike the node selector and the tolerations, they are not copied
+// from the pod template, so that the deployer pod is placed independently of the pods it rolls
+// out. The deployer affinity and topology spread constraints annotations of the deployment config
+// replace the defaults of the controller.
+func (c *DeploymentController) makeDeployerScheduling(config *appsv1.DeploymentConfig) (*corev1.Affinity, []corev1.TopologySpreadConstraint, error) {
+	affinity, err := deployutil.DeployerAffinity(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if affinity == nil && c.podDefaults.Affinity != nil {
+		affinity = c.podDefaults.Affinity.DeepCopy()
+	}
+
+	constraints, err := deployutil.DeployerTopologySpreadConstraints(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	if constraints == nil {
+		for i := range c.podDefaults.TopologySpreadConstraints {
+			constraints = append(constraints, *c.podDefaults.TopologySpreadConstraints[i].DeepCopy())
+		}
+	}
+	return affinity, constraints, nil
+}
+
+func hasToleration(tolerations []corev1.Toleration, toleration *corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(toleration) {
+			return true
+		}
+	}
+	return false
+}
+
 // makeDeployerContainer creates containers in the following way:
 //
 //  1. For the Recreate and Rolling strategies, strategy, use the factory's
@@ -456,6 +738,9 @@ func (c *DeploymentController) makeDeployerPod(deployment *corev1.ReplicationCon
 //     the strategy's image for the container image, and use the combination
 //     of the factory's Environment and the strategy's environment as the
 //     container environment.
+//
+// The container uses the resource requirements of the strategy, or the default resources of the
+// controller when the strategy has none.
 func (c *DeploymentController) makeDeployerContainer(strategy *appsv1.DeploymentStrategy) *corev1.Container {
 	image := c.deployerImage
 	var environment []corev1.EnvVar
@@ -490,10 +775,18 @@ func (c *DeploymentController) makeDeployerContainer(strategy *appsv1.Deployment
 		environment = append(environment, env)
 	}
 
+	// Use the default resources unless the strategy sets its own, which the deployer also uses
+	// for the lifecycle hook pods.
+	resources := strategy.Resources
+	if len(resources.Limits) == 0 && len(resources.Requests) == 0 && len(resources.Claims) == 0 {
+		resources = *c.podDefaults.Resources.DeepCopy()
+	}
+
 	return &corev1.Container{
-		Image:   image,
-		Command: command,
-		Env:     environment,
+		Image:     image,
+		Command:   command,
+		Env:       environment,
+		Resources: resources,
 	}
 }
 
@@ -553,6 +846,11 @@ func (c *DeploymentController) cleanupDeployerPods(deployment *corev1.Replicatio
 
 	cleanedAll := true
 	for _, deployerPod := range deployerList {
+		// The pods are deleted gracefully, so that a cancelled deployer pod stops its rollout.
+		// Pods that are already terminating are not deleted again.
+		if deployerPod.DeletionTimestamp != nil {
+			continue
+		}
 		if err := c.pn.Pods(deployerPod.Namespace).Delete(context.TODO(), deployerPod.Name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
 			// if the pod deletion failed, then log the error and continue
 			// we will try to delete any remaining deployer pods and return an error later
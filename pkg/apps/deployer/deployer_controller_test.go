@@ -21,13 +21,18 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/kubernetes/pkg/api/legacyscheme"
 	kapitesting "k8s.io/kubernetes/pkg/api/testing"
 	kapihelper "k8s.io/kubernetes/pkg/apis/core/helper"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	appsv1 "github.com/openshift/api/apps/v1"
+	imagefake "github.com/openshift/client-go/image/clientset/versioned/fake"
+	imageinformers "github.com/openshift/client-go/image/informers/externalversions"
 	"github.com/openshift/library-go/pkg/apps/appsutil"
 	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
 )
 
 var (
@@ -45,8 +50,9 @@ func okDeploymentController(client kclientset.Interface, deployment *corev1.Repl
 	informerFactory := kinformers.NewSharedInformerFactory(client, 0)
 	rcInformer := informerFactory.Core().V1().ReplicationControllers()
 	podInformer := informerFactory.Core().V1().Pods()
+	isInformer := imageinformers.NewSharedInformerFactory(&imagefake.Clientset{}, 0).Image().V1().ImageStreams()
 
-	c := NewDeployerController(rcInformer, podInformer, client, "sa:test", "openshift/origin-deployer", env)
+	c := NewDeployerController(rcInformer, podInformer, isInformer, client, "sa:test", "openshift/origin-deployer", env, DeployerPodDefaults{})
 	c.podListerSynced = alwaysReady
 	c.rcListerSynced = alwaysReady
 
@@ -856,6 +862,128 @@ func TestHandle_cleanupPostNew(t *testing.T) {
 	}
 }
 
+// TestHandle_cancelMidRollout ensures that cancelling a running rollout deletes its deployer and
+// hook pods once, and fails the rollout once the deployer pod is gone.
+func TestHandle_cancelMidRollout(t *testing.T) {
+	hookPods := []string{"pre"}
+	deletedPods := []string{}
+	var updatedDeployment *corev1.ReplicationController
+
+	client := &fake.Clientset{}
+	client.AddReactor("delete", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		deletedPods = append(deletedPods, action.(clientgotesting.DeleteAction).GetName())
+		return true, nil, nil
+	})
+	client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+		return true, updatedDeployment, nil
+	})
+
+	deployment, _ := appsutil.MakeDeployment(appstest.OkDeploymentConfig(1))
+	deployment.CreationTimestamp = metav1.Now()
+	deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusRunning)
+	deployment.Annotations[appsv1.DeploymentCancelledAnnotation] = "true"
+
+	controller := okDeploymentController(client, deployment, hookPods, true, corev1.PodRunning)
+	if err := controller.handle(deployment, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(deletedPods)
+	if e, a := []string{"config-1-deploy", "pre"}, deletedPods; !reflect.DeepEqual(e, a) {
+		t.Fatalf("expected the deployer and hook pods %v to be deleted, got %v", e, a)
+	}
+	if updatedDeployment != nil {
+		t.Fatalf("expected the rollout to keep running until its deployer pod is gone, got %s", appsutil.DeploymentStatusFor(updatedDeployment))
+	}
+
+	// cancelling again while the pods terminate does not delete them again
+	now := metav1.Now()
+	for _, obj := range controller.podIndexer.List() {
+		obj.(*corev1.Pod).DeletionTimestamp = &now
+	}
+	deletedPods = []string{}
+	if err := controller.handle(deployment, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deletedPods) > 0 {
+		t.Errorf("expected the terminating pods not to be deleted again, got %v", deletedPods)
+	}
+
+	// the rollout fails once the deployer pod is gone
+	for _, obj := range controller.podIndexer.List() {
+		controller.podIndexer.Delete(obj)
+	}
+	if err := controller.handle(deployment, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updatedDeployment == nil || !appsutil.IsFailedDeployment(updatedDeployment) {
+		t.Fatalf("expected the cancelled rollout to fail, got %#v", updatedDeployment)
+	}
+	if e, a := deployutil.DeploymentFailedCancelled, updatedDeployment.Annotations[appsv1.DeploymentStatusReasonAnnotation]; e != a {
+		t.Errorf("expected status reason %q, got %q", e, a)
+	}
+}
+
+// TestHandle_cancelNoop ensures that cancelling a complete rollout, or cancelling a failed rollout
+// again, changes nothing.
+func TestHandle_cancelNoop(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   appsv1.DeploymentStatus
+		podPhase corev1.PodPhase
+	}{
+		{
+			name:     "complete",
+			status:   appsv1.DeploymentStatusComplete,
+			podPhase: corev1.PodSucceeded,
+		},
+		{
+			name:     "cancelled",
+			status:   appsv1.DeploymentStatusFailed,
+			podPhase: corev1.PodFailed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &fake.Clientset{}
+			client.AddReactor("delete", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				t.Errorf("unexpected deletion of pod %s", action.(clientgotesting.DeleteAction).GetName())
+				return true, nil, nil
+			})
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				t.Errorf("unexpected call to update a deployment")
+				return true, nil, nil
+			})
+
+			deployment, _ := appsutil.MakeDeployment(appstest.OkDeploymentConfig(1))
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(test.status)
+			appsutil.SetCancelledByUserReason(deployment)
+			deployment.Annotations[appsutil.DeploymentReplicasAnnotation] = "1"
+
+			controller := okDeploymentController(client, deployment, nil, true, test.podPhase)
+			recorder := record.NewFakeRecorder(10)
+			controller.recorder = recorder
+			if test.status == appsv1.DeploymentStatusFailed {
+				// the pods of the cancelled rollout are already terminating
+				now := metav1.Now()
+				for _, obj := range controller.podIndexer.List() {
+					obj.(*corev1.Pod).DeletionTimestamp = &now
+				}
+			}
+
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			select {
+			case event := <-recorder.Events:
+				t.Errorf("unexpected event %q", event)
+			default:
+			}
+		})
+	}
+}
+
 // TestHandle_deployerPodDisappeared ensures that a pending/running deployment
 // is failed when its deployer pod vanishes. Ensure that pending deployments
 // wont fail instantly on a missing deployer pod because it may take some time
@@ -1119,6 +1247,267 @@ func TestDeployerCustomLabelsAndAnnotations(t *testing.T) {
 	}
 }
 
+func TestMakeDeployerPodResources(t *testing.T) {
+	explicit := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+	}
+	defaults := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+		},
+	}
+	tests := []struct {
+		name     string
+		strategy corev1.ResourceRequirements
+		defaults corev1.ResourceRequirements
+		expected corev1.ResourceRequirements
+	}{
+		{
+			name:     "explicit",
+			strategy: explicit,
+			defaults: defaults,
+			expected: explicit,
+		},
+		{
+			name:     "defaulted",
+			defaults: defaults,
+			expected: defaults,
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			controller := okDeploymentController(&fake.Clientset{}, nil, nil, true, corev1.PodUnknown)
+			controller.podDefaults.Resources = test.defaults
+			config := appstest.OkDeploymentConfig(1)
+			config.Spec.Strategy.Resources = test.strategy
+			deployment, _ := appsutil.MakeDeployment(config)
+
+			pod, err := controller.makeDeployerPod(deployment)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if e, a := test.expected, pod.Spec.Containers[0].Resources; !kapihelper.Semantic.DeepEqual(e, a) {
+				t.Errorf("expected resources %v, got %v", e, a)
+			}
+		})
+	}
+}
+
+func TestMakeDeployerPodImage(t *testing.T) {
+	mirrorSecret := corev1.LocalObjectReference{Name: "mirror-pull-secret"}
+	templateSecret := corev1.LocalObjectReference{Name: "app-pull-secret"}
+	tests := []struct {
+		name            string
+		pullPolicy      corev1.PullPolicy
+		pullSecret      string
+		templateSecrets []corev1.LocalObjectReference
+
+		expectedPullPolicy  corev1.PullPolicy
+		expectedPullSecrets []corev1.LocalObjectReference
+	}{
+		{
+			name:                "defaults",
+			templateSecrets:     []corev1.LocalObjectReference{templateSecret},
+			expectedPullPolicy:  corev1.PullIfNotPresent,
+			expectedPullSecrets: []corev1.LocalObjectReference{templateSecret},
+		},
+		{
+			name:                "configured",
+			pullPolicy:          corev1.PullAlways,
+			pullSecret:          mirrorSecret.Name,
+			templateSecrets:     []corev1.LocalObjectReference{templateSecret},
+			expectedPullPolicy:  corev1.PullAlways,
+			expectedPullSecrets: []corev1.LocalObjectReference{templateSecret, mirrorSecret},
+		},
+		{
+			name:                "pull secret of the template",
+			pullSecret:          mirrorSecret.Name,
+			templateSecrets:     []corev1.LocalObjectReference{mirrorSecret},
+			expectedPullPolicy:  corev1.PullIfNotPresent,
+			expectedPullSecrets: []corev1.LocalObjectReference{mirrorSecret},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			controller := okDeploymentController(&fake.Clientset{}, nil, nil, true, corev1.PodUnknown)
+			controller.deployerImage = "mirror.example.com/openshift/origin-deployer:v4"
+			controller.podDefaults.ImagePullPolicy = test.pullPolicy
+			controller.podDefaults.ImagePullSecret = test.pullSecret
+			config := appstest.OkDeploymentConfig(1)
+			config.Spec.Template.Spec.ImagePullSecrets = test.templateSecrets
+			deployment, _ := appsutil.MakeDeployment(config)
+
+			pod, err := controller.makeDeployerPod(deployment)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if e, a := controller.deployerImage, pod.Spec.Containers[0].Image; e != a {
+				t.Errorf("expected image %q, got %q", e, a)
+			}
+			if e, a := test.expectedPullPolicy, pod.Spec.Containers[0].ImagePullPolicy; e != a {
+				t.Errorf("expected image pull policy %q, got %q", e, a)
+			}
+			if e, a := test.expectedPullSecrets, pod.Spec.ImagePullSecrets; !reflect.DeepEqual(e, a) {
+				t.Errorf("expected image pull secrets %v, got %v", e, a)
+			}
+			if len(deployment.Spec.Template.Spec.ImagePullSecrets) != len(test.templateSecrets) {
+				t.Errorf("expected the image pull secrets of the template to be left unchanged, got %v", deployment.Spec.Template.Spec.ImagePullSecrets)
+			}
+		})
+	}
+}
+
+func TestMakeDeployerPodPlacement(t *testing.T) {
+	infraToleration := corev1.Toleration{Key: "node-role.kubernetes.io/infra", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	templateToleration := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "app", Effect: corev1.TaintEffectNoExecute}
+	infraAffinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "node-role.kubernetes.io/infra", Operator: corev1.NodeSelectorOpExists}},
+		}}},
+	}}
+	zoneAffinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+			MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "topology.kubernetes.io/zone", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"c"}}},
+		}}},
+	}}
+	zoneSpread := corev1.TopologySpreadConstraint{
+		MaxSkew:           1,
+		TopologyKey:       "topology.kubernetes.io/zone",
+		WhenUnsatisfiable: corev1.ScheduleAnyway,
+		LabelSelector:     &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: appsv1.DeployerPodForDeploymentLabel, Operator: metav1.LabelSelectorOpExists}}},
+	}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		defaults    DeployerPodDefaults
+
+		expectedNodeSelector map[string]string
+		expectedTolerations  []corev1.Toleration
+		expectedAffinity     *corev1.Affinity
+		expectedConstraints  []corev1.TopologySpreadConstraint
+		expectErr            bool
+	}{
+		{
+			name:                 "template placement",
+			expectedNodeSelector: map[string]string{"tier": "app"},
+			expectedTolerations:  []corev1.Toleration{templateToleration},
+		},
+		{
+			name: "infra taint toleration",
+			annotations: map[string]string{
+				deployutil.DeployerNodeSelectorAnnotation: "node-role.kubernetes.io/infra=,tier=infra",
+				deployutil.DeployerTolerationsAnnotation:  `[{"key":"node-role.kubernetes.io/infra","operator":"Exists","effect":"NoSchedule"}]`,
+			},
+			expectedNodeSelector: map[string]string{"tier": "infra", "node-role.kubernetes.io/infra": ""},
+			expectedTolerations:  []corev1.Toleration{templateToleration, infraToleration},
+		},
+		{
+			name: "controller defaults",
+			annotations: map[string]string{
+				deployutil.DeployerNodeSelectorAnnotation: "tier=infra",
+			},
+			defaults: DeployerPodDefaults{
+				NodeSelector: map[string]string{"tier": "default", "zone": "a"},
+				Tolerations:  []corev1.Toleration{infraToleration, templateToleration},
+			},
+			expectedNodeSelector: map[string]string{"tier": "infra", "zone": "a"},
+			expectedTolerations:  []corev1.Toleration{templateToleration, infraToleration},
+		},
+		{
+			name: "default affinity",
+			defaults: DeployerPodDefaults{
+				Affinity:                  infraAffinity,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{zoneSpread},
+			},
+			expectedNodeSelector: map[string]string{"tier": "app"},
+			expectedTolerations:  []corev1.Toleration{templateToleration},
+			expectedAffinity:     infraAffinity,
+			expectedConstraints:  []corev1.TopologySpreadConstraint{zoneSpread},
+		},
+		{
+			name: "deployment config affinity",
+			annotations: map[string]string{
+				deployutil.DeployerAffinityAnnotation:                  `{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":[{"matchExpressions":[{"key":"topology.kubernetes.io/zone","operator":"NotIn","values":["c"]}]}]}}}`,
+				deployutil.DeployerTopologySpreadConstraintsAnnotation: `[{"maxSkew":1,"topologyKey":"topology.kubernetes.io/zone","whenUnsatisfiable":"ScheduleAnyway","labelSelector":{"matchExpressions":[{"key":"openshift.io/deployer-pod-for.name","operator":"Exists"}]}}]`,
+			},
+			defaults: DeployerPodDefaults{
+				Affinity: infraAffinity,
+			},
+			expectedNodeSelector: map[string]string{"tier": "app"},
+			expectedTolerations:  []corev1.Toleration{templateToleration},
+			expectedAffinity:     zoneAffinity,
+			expectedConstraints:  []corev1.TopologySpreadConstraint{zoneSpread},
+		},
+		{
+			name: "invalid affinity",
+			annotations: map[string]string{
+				deployutil.DeployerAffinityAnnotation: "infra",
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid topology spread constraints",
+			annotations: map[string]string{
+				deployutil.DeployerTopologySpreadConstraintsAnnotation: `{"maxSkew":1}`,
+			},
+			expectErr: true,
+		},
+		{
+			name: "invalid tolerations",
+			annotations: map[string]string{
+				deployutil.DeployerTolerationsAnnotation: "infra",
+			},
+			expectErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			controller := okDeploymentController(&fake.Clientset{}, nil, nil, true, corev1.PodUnknown)
+			controller.podDefaults = test.defaults
+			config := appstest.OkDeploymentConfig(1)
+			config.Annotations = test.annotations
+			config.Spec.Template.Spec.NodeSelector = map[string]string{"tier": "app"}
+			config.Spec.Template.Spec.Tolerations = []corev1.Toleration{templateToleration}
+			// the deployer pod is not placed with the pods it rolls out
+			config.Spec.Template.Spec.Affinity = zoneAffinity
+			config.Spec.Template.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{zoneSpread}
+			deployment, _ := appsutil.MakeDeployment(config)
+			template := deployment.Spec.Template.DeepCopy()
+
+			pod, err := controller.makeDeployerPod(deployment)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if e, a := test.expectedNodeSelector, pod.Spec.NodeSelector; !reflect.DeepEqual(e, a) {
+				t.Errorf("expected node selector %v, got %v", e, a)
+			}
+			if e, a := test.expectedTolerations, pod.Spec.Tolerations; !reflect.DeepEqual(e, a) {
+				t.Errorf("expected tolerations %v, got %v", e, a)
+			}
+			if e, a := test.expectedAffinity, pod.Spec.Affinity; !reflect.DeepEqual(e, a) {
+				t.Errorf("expected affinity %v, got %v", e, a)
+			}
+			if e, a := test.expectedConstraints, pod.Spec.TopologySpreadConstraints; !reflect.DeepEqual(e, a) {
+				t.Errorf("expected topology spread constraints %v, got %v", e, a)
+			}
+			if !kapihelper.Semantic.DeepEqual(template, deployment.Spec.Template) {
+				t.Errorf("expected the deployment template to be unchanged: %s", diff.ObjectReflectDiff(template, deployment.Spec.Template))
+			}
+		})
+	}
+}
+
 func TestMakeDeployerPod(t *testing.T) {
 	client := &fake.Clientset{}
 	controller := okDeploymentController(client, nil, nil, true, corev1.PodUnknown)
@@ -1324,3 +1713,268 @@ func TestRolloutExceededTimeoutSeconds(t *testing.T) {
 
 	}
 }
+
+// TestHandle_progressDeadline ensures that running deployments which do not reach minimum
+// availability within the progress deadline of their deployment config are failed.
+func TestHandle_progressDeadline(t *testing.T) {
+	tests := []struct {
+		name string
+
+		elapsed   time.Duration
+		available int32
+
+		expected       appsv1.DeploymentStatus
+		expectedReason string
+		expectDeleted  bool
+	}{
+		{
+			name: "within the progress deadline",
+
+			elapsed: 30 * time.Second,
+
+			expected: appsv1.DeploymentStatusRunning,
+		},
+		{
+			name: "progress deadline exceeded",
+
+			elapsed: 2 * time.Minute,
+
+			expected:       appsv1.DeploymentStatusFailed,
+			expectedReason: deployutil.DeploymentFailedProgressDeadlineExceeded,
+			expectDeleted:  true,
+		},
+		{
+			name: "available before the progress deadline",
+
+			elapsed:   2 * time.Minute,
+			available: 1,
+
+			expected: appsv1.DeploymentStatusRunning,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var updatedDeployment *corev1.ReplicationController
+			deletedDeployer := false
+
+			client := &fake.Clientset{}
+			client.AddReactor("delete", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				deletedDeployer = true
+				return true, nil, nil
+			})
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				rc := action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				updatedDeployment = rc
+				return true, rc, nil
+			})
+
+			config := appstest.OkDeploymentConfig(1)
+			config.Annotations = map[string]string{deployutil.ProgressDeadlineSecondsAnnotation: "60"}
+			deployment, _ := appsutil.MakeDeployment(config)
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusRunning)
+			deployment.Status.AvailableReplicas = test.available
+
+			controller := okDeploymentController(client, deployment, nil, true, corev1.PodRunning)
+			started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			fakeClock := clocktesting.NewFakeClock(started)
+			controller.clock = fakeClock
+			obj, _, _ := controller.podIndexer.GetByKey(deployment.Namespace + "/" + appsutil.DeployerPodNameForDeployment(deployment.Name))
+			obj.(*corev1.Pod).Status.StartTime = &metav1.Time{Time: started}
+			fakeClock.Step(test.elapsed)
+
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			status := appsv1.DeploymentStatusRunning
+			if updatedDeployment != nil {
+				status = appsutil.DeploymentStatusFor(updatedDeployment)
+			}
+			if e, a := test.expected, status; e != a {
+				t.Fatalf("expected deployment status %s, got %s", e, a)
+			}
+			if len(test.expectedReason) > 0 {
+				if e, a := test.expectedReason, updatedDeployment.Annotations[appsv1.DeploymentStatusReasonAnnotation]; e != a {
+					t.Errorf("expected status reason %q, got %q", e, a)
+				}
+			}
+			if deletedDeployer != test.expectDeleted {
+				t.Errorf("expected deployer delete %v, got %v", test.expectDeleted, deletedDeployer)
+			}
+		})
+	}
+}
+
+// TestHandle_minReadySeconds ensures that deployments are only completed once their pods have
+// been available for the minimum ready seconds of their deployment config.
+func TestHandle_minReadySeconds(t *testing.T) {
+	tests := []struct {
+		name string
+
+		minReadySeconds int32
+		ready           int32
+		available       int32
+
+		expected appsv1.DeploymentStatus
+	}{
+		{
+			name: "no minimum ready seconds",
+
+			expected: appsv1.DeploymentStatusComplete,
+		},
+		{
+			name: "pod ready but not available yet",
+
+			minReadySeconds: 30,
+			ready:           1,
+
+			expected: appsv1.DeploymentStatusRunning,
+		},
+		{
+			name: "pod crashed before minimum ready seconds elapsed",
+
+			minReadySeconds: 30,
+
+			expected: appsv1.DeploymentStatusRunning,
+		},
+		{
+			name: "pod available",
+
+			minReadySeconds: 30,
+			ready:           1,
+			available:       1,
+
+			expected: appsv1.DeploymentStatusComplete,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var updatedDeployment *corev1.ReplicationController
+			client := &fake.Clientset{}
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				return true, updatedDeployment, nil
+			})
+
+			config := appstest.OkDeploymentConfig(1)
+			config.Spec.MinReadySeconds = test.minReadySeconds
+			deployment, _ := appsutil.MakeDeployment(config)
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusRunning)
+			deployment.Status.ReadyReplicas = test.ready
+			deployment.Status.AvailableReplicas = test.available
+
+			controller := okDeploymentController(client, deployment, nil, true, corev1.PodSucceeded)
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			status := appsv1.DeploymentStatusRunning
+			if updatedDeployment != nil {
+				status = appsutil.DeploymentStatusFor(updatedDeployment)
+			}
+			if e, a := test.expected, status; e != a {
+				t.Errorf("expected deployment status %s, got %s", e, a)
+			}
+		})
+	}
+}
+
+// TestHandle_deployerServiceAccount ensures that deployer pods run as the deployer service account
+// of their deployment config, and that rollouts whose deployer service account does not exist fail.
+func TestHandle_deployerServiceAccount(t *testing.T) {
+	tests := []struct {
+		name           string
+		serviceAccount string
+		exists         bool
+
+		expectedServiceAccount string
+		expectedStatus         appsv1.DeploymentStatus
+	}{
+		{
+			name:                   "controller service account",
+			expectedServiceAccount: "sa:test",
+			expectedStatus:         appsv1.DeploymentStatusPending,
+		},
+		{
+			name:                   "custom service account",
+			serviceAccount:         "hooks",
+			exists:                 true,
+			expectedServiceAccount: "hooks",
+			expectedStatus:         appsv1.DeploymentStatusPending,
+		},
+		{
+			name:           "missing service account",
+			serviceAccount: "hooks",
+			expectedStatus: appsv1.DeploymentStatusFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				updatedDeployment *corev1.ReplicationController
+				createdPod        *corev1.Pod
+				lookedUp          []string
+			)
+			client := &fake.Clientset{}
+			client.AddReactor("get", "serviceaccounts", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				name := action.(clientgotesting.GetAction).GetName()
+				lookedUp = append(lookedUp, action.GetNamespace()+"/"+name)
+				if !test.exists {
+					return true, nil, kerrors.NewNotFound(corev1.Resource("serviceaccounts"), name)
+				}
+				return true, &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: action.GetNamespace()}}, nil
+			})
+			client.AddReactor("create", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				createdPod = action.(clientgotesting.CreateAction).GetObject().(*corev1.Pod)
+				return true, createdPod, nil
+			})
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				return true, updatedDeployment, nil
+			})
+
+			config := appstest.OkDeploymentConfig(1)
+			if len(test.serviceAccount) > 0 {
+				config.Annotations = map[string]string{deployutil.DeployerServiceAccountAnnotation: test.serviceAccount}
+			}
+			deployment, _ := appsutil.MakeDeployment(config)
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusNew)
+			deployment.CreationTimestamp = metav1.Now()
+
+			controller := okDeploymentController(client, nil, nil, true, corev1.PodUnknown)
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if updatedDeployment == nil {
+				t.Fatalf("expected an updated deployment")
+			}
+			if e, a := test.expectedStatus, appsutil.DeploymentStatusFor(updatedDeployment); e != a {
+				t.Fatalf("expected updated deployment status %s, got %s", e, a)
+			}
+			if len(test.serviceAccount) == 0 && len(lookedUp) > 0 {
+				t.Errorf("expected the controller service account not to be looked up, got %v", lookedUp)
+			}
+			if test.expectedStatus == appsv1.DeploymentStatusFailed {
+				if createdPod != nil {
+					t.Errorf("expected no deployer pod to be created")
+				}
+				if e, a := deployutil.DeploymentFailedDeployerServiceAccountNotFound, updatedDeployment.Annotations[appsv1.DeploymentStatusReasonAnnotation]; e != a {
+					t.Errorf("expected status reason %q, got %q", e, a)
+				}
+				return
+			}
+			if createdPod == nil {
+				t.Fatalf("expected a deployer pod to be created")
+			}
+			if e, a := test.expectedServiceAccount, createdPod.Spec.ServiceAccountName; e != a {
+				t.Errorf("expected the deployer pod to run as service account %s, got %s", e, a)
+			}
+		})
+	}
+}
@@ -17,7 +17,9 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/api/legacyscheme"
 	kcontroller "k8s.io/kubernetes/pkg/controller"
+	"k8s.io/utils/clock"
 
+	imagev1informer "github.com/openshift/client-go/image/informers/externalversions/image/v1"
 	"github.com/openshift/library-go/pkg/apps/appsutil"
 )
 
@@ -25,10 +27,12 @@ import (
 func NewDeployerController(
 	rcInformer kcoreinformers.ReplicationControllerInformer,
 	podInformer kcoreinformers.PodInformer,
+	isInformer imagev1informer.ImageStreamInformer,
 	kubeClientset kclientset.Interface,
 	sa,
 	image string,
 	env []v1.EnvVar,
+	podDefaults DeployerPodDefaults,
 ) *DeploymentController {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(klog.Infof)
@@ -39,17 +43,24 @@ func NewDeployerController(
 		rn: kubeClientset.CoreV1(),
 		pn: kubeClientset.CoreV1(),
 
+		sn:      kubeClientset.CoreV1(),
+		podLogs: clientPodLogsGetter{pods: kubeClientset.CoreV1()},
+
 		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "deployer"),
 
 		rcLister:        rcInformer.Lister(),
 		rcListerSynced:  rcInformer.Informer().HasSynced,
 		podLister:       podInformer.Lister(),
 		podListerSynced: podInformer.Informer().HasSynced,
+		isLister:        isInformer.Lister(),
+		isListerSynced:  isInformer.Informer().HasSynced,
 
 		serviceAccount: sa,
 		deployerImage:  image,
 		environment:    env,
+		podDefaults:    podDefaults,
 		recorder:       recorder,
+		clock:          clock.RealClock{},
 	}
 
 	rcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
@@ -73,7 +84,7 @@ func (c *DeploymentController) Run(workers int, stopCh <-chan struct{}) {
 	klog.Infof("Starting deployer controller")
 
 	// Wait for the dc store to sync before starting any work in this controller.
-	if !cache.WaitForCacheSync(stopCh, c.rcListerSynced, c.podListerSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.rcListerSynced, c.podListerSynced, c.isListerSynced) {
 		return
 	}
 
@@ -0,0 +1,114 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	kcoreclient "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// maxFailureLogEventBytes is the size of the tail of the deployer pod log the event of a failed
+// rollout carries.
+const maxFailureLogEventBytes = 512
+
+const (
+	// failureLogTailLines is the number of lines of the tail of the deployer pod log that are
+	// fetched for a failed rollout.
+	failureLogTailLines = 100
+	// minFailureLogStreamBytes is the least number of bytes of the tail of the deployer pod log that
+	// are fetched for a failed rollout, unless the failure log bytes setting is larger.
+	minFailureLogStreamBytes = 64 * 1024
+)
+
+// failureLogTimeout bounds the time a deployer worker spends fetching the log of a failed rollout.
+var failureLogTimeout = 10 * time.Second
+
+// podLogsGetter streams the logs of pods.
+type podLogsGetter interface {
+	StreamLogs(ctx context.Context, namespace, name string, options *corev1.PodLogOptions) (io.ReadCloser, error)
+}
+
+// clientPodLogsGetter streams the logs of pods from the API server.
+type clientPodLogsGetter struct {
+	pods kcoreclient.PodsGetter
+}
+
+func (g clientPodLogsGetter) StreamLogs(ctx context.Context, namespace, name string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+	return g.pods.Pods(namespace).GetLogs(name, options).Stream(ctx)
+}
+
+// recordDeployerLog records the tail of the log of the deployer pod of a failed rollout in the
+// deployer pod log annotation of the deployment and emits it in a warning event, so that the reason
+// of the failure outlives the deployer pod. Only the last lines of the log are fetched, within
+// failureLogTimeout. Failing to fetch the log does not change the outcome of the rollout.
+func (c *DeploymentController) recordDeployerLog(deployment *corev1.ReplicationController, deployer *corev1.Pod, updatedAnnotations map[string]string) {
+	if c.podDefaults.FailureLogBytes <= 0 || c.podLogs == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.TODO(), failureLogTimeout)
+	defer cancel()
+	tailLines, limitBytes := int64(failureLogTailLines), int64(minFailureLogStreamBytes)
+	if limit := int64(c.podDefaults.FailureLogBytes); limit > limitBytes {
+		limitBytes = limit
+	}
+	logs, err := c.podLogs.StreamLogs(ctx, deployer.Namespace, deployer.Name, &corev1.PodLogOptions{TailLines: &tailLines, LimitBytes: &limitBytes})
+	if err != nil {
+		klog.V(2).Infof("Unable to fetch the log of deployer pod %s/%s of failed rollout %q: %v", deployer.Namespace, deployer.Name, appsutil.LabelForDeployment(deployment), err)
+		return
+	}
+	defer logs.Close()
+	tail, err := readTail(logs, c.podDefaults.FailureLogBytes)
+	if err != nil {
+		klog.V(2).Infof("Unable to read the log of deployer pod %s/%s of failed rollout %q: %v", deployer.Namespace, deployer.Name, appsutil.LabelForDeployment(deployment), err)
+		return
+	}
+	if len(tail) == 0 {
+		return
+	}
+
+	updatedAnnotations[deployutil.DeployerPodLogAnnotation] = tail
+	c.emitDeploymentEvent(deployment, corev1.EventTypeWarning, deployutil.RolloutFailedEventReason,
+		fmt.Sprintf(deployutil.RolloutFailedEventMessage, appsutil.LabelForDeployment(deployment), truncateHead([]byte(tail), maxFailureLogEventBytes)))
+}
+
+// readTail reads r to its end and returns at most its last limit bytes, see truncateHead.
+func readTail(r io.Reader, limit int) (string, error) {
+	var tail []byte
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		tail = append(tail, buf[:n]...)
+		// keep one byte more than the limit so that the tail is known to be truncated
+		if len(tail) > 2*limit {
+			tail = append(tail[:0], tail[len(tail)-limit-1:]...)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return truncateHead(tail, limit), nil
+}
+
+// truncateHead returns at most the last limit bytes of b. When b is truncated, the returned tail
+// starts at a character boundary and is prefixed with an ellipsis.
+func truncateHead(b []byte, limit int) string {
+	if len(b) <= limit {
+		return string(b)
+	}
+	b = b[len(b)-limit:]
+	for len(b) > 0 && !utf8.RuneStart(b[0]) {
+		b = b[1:]
+	}
+	return "..." + string(b)
+}
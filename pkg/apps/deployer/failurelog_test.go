@@ -0,0 +1,205 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// fakePodLogs returns the same log for all pods.
+type fakePodLogs struct {
+	log  string
+	err  error
+	hung bool
+
+	fetched []string
+	options []*corev1.PodLogOptions
+}
+
+func (f *fakePodLogs) StreamLogs(ctx context.Context, namespace, name string, options *corev1.PodLogOptions) (io.ReadCloser, error) {
+	f.fetched = append(f.fetched, namespace+"/"+name)
+	f.options = append(f.options, options)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.hung {
+		return io.NopCloser(hungReader{ctx: ctx}), nil
+	}
+	return io.NopCloser(strings.NewReader(f.log)), nil
+}
+
+// hungReader blocks until its context is done, like the log stream of an unresponsive kubelet.
+type hungReader struct {
+	ctx context.Context
+}
+
+func (r hungReader) Read([]byte) (int, error) {
+	<-r.ctx.Done()
+	return 0, r.ctx.Err()
+}
+
+func TestHandle_failureLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		limit     int
+		log       string
+		err       error
+		hung      bool
+		cancelled bool
+
+		expectedLog   string
+		expectedEvent bool
+	}{
+		{
+			name:          "short log",
+			limit:         64,
+			log:           "--> Scaling up\nerror: timed out waiting for the pods\n",
+			expectedLog:   "--> Scaling up\nerror: timed out waiting for the pods\n",
+			expectedEvent: true,
+		},
+		{
+			name:          "truncated log",
+			limit:         16,
+			log:           strings.Repeat("--> Scaling up\n", 10) + "error: timed out",
+			expectedLog:   "...error: timed out",
+			expectedEvent: true,
+		},
+		{
+			name:  "log fetch failure",
+			limit: 64,
+			err:   fmt.Errorf("connection refused"),
+		},
+		{
+			name:  "hung log stream",
+			limit: 64,
+			log:   "error: timed out",
+			hung:  true,
+		},
+		{
+			name: "no log recorded",
+			log:  "error: timed out",
+		},
+		{
+			name:      "cancelled rollout",
+			limit:     64,
+			log:       "error: cancelled",
+			cancelled: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var updatedDeployment *corev1.ReplicationController
+			client := &fake.Clientset{}
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				return true, updatedDeployment, nil
+			})
+
+			deployment, _ := appsutil.MakeDeployment(appstest.OkDeploymentConfig(1))
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusRunning)
+			if test.cancelled {
+				deployment.Annotations[appsv1.DeploymentCancelledAnnotation] = "true"
+			}
+
+			controller := okDeploymentController(client, deployment, nil, true, corev1.PodFailed)
+			recorder := record.NewFakeRecorder(10)
+			controller.recorder = recorder
+			logs := &fakePodLogs{log: test.log, err: test.err, hung: test.hung}
+			controller.podLogs = logs
+			controller.podDefaults.FailureLogBytes = test.limit
+
+			defer func(timeout time.Duration) { failureLogTimeout = timeout }(failureLogTimeout)
+			failureLogTimeout = 10 * time.Millisecond
+
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, options := range logs.options {
+				if options.TailLines == nil || *options.TailLines != failureLogTailLines || options.LimitBytes == nil || *options.LimitBytes != minFailureLogStreamBytes {
+					t.Errorf("expected only the tail of the deployer pod log to be fetched, got %#v", options)
+				}
+			}
+
+			if updatedDeployment == nil || !appsutil.IsFailedDeployment(updatedDeployment) {
+				t.Fatalf("expected the rollout to fail, got %#v", updatedDeployment)
+			}
+			if log, ok := updatedDeployment.Annotations[deployutil.DeployerPodLogAnnotation]; log != test.expectedLog || ok != (len(test.expectedLog) > 0) {
+				t.Errorf("expected the deployer pod log %q, got %q", test.expectedLog, log)
+			}
+
+			var event string
+			select {
+			case event = <-recorder.Events:
+			default:
+			}
+			if test.expectedEvent != strings.Contains(event, deployutil.RolloutFailedEventReason) {
+				t.Errorf("unexpected event %q", event)
+			}
+			if test.expectedEvent && !strings.Contains(event, test.expectedLog) {
+				t.Errorf("expected the event to carry the deployer pod log, got %q", event)
+			}
+		})
+	}
+}
+
+func TestReadTail(t *testing.T) {
+	tests := []struct {
+		name     string
+		log      string
+		limit    int
+		expected string
+	}{
+		{
+			name:     "within the limit",
+			log:      "deployed",
+			limit:    8,
+			expected: "deployed",
+		},
+		{
+			name:     "beyond the limit",
+			log:      "not deployed",
+			limit:    8,
+			expected: "...deployed",
+		},
+		{
+			name:     "beyond the read buffer",
+			log:      strings.Repeat("x", 100*1024) + "deployed",
+			limit:    8,
+			expected: "...deployed",
+		},
+		{
+			name:     "character boundary",
+			log:      "führt aus",
+			limit:    8,
+			expected: "...hrt aus",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tail, err := readTail(strings.NewReader(test.log), test.limit)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tail != test.expected {
+				t.Errorf("expected %q, got %q", test.expected, tail)
+			}
+		})
+	}
+}
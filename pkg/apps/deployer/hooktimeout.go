@@ -0,0 +1,61 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kutilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/openshift/library-go/pkg/apps/appsserialization"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/library-go/pkg/build/naming"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// hookPodSuffixes are the suffixes of the pods of the pre, mid and post lifecycle hooks.
+var hookPodSuffixes = []string{appsutil.PreHookPodSuffix, appsutil.MidHookPodSuffix, appsutil.PostHookPodSuffix}
+
+// enforceHookTimeouts lowers the active deadline of the running hook pods of the deployment to
+// the hook timeouts of its deployment config, so that the kubelet stops the hook pods that run
+// longer than their hook timeout. The deployer pod then handles the failed hook according to the
+// failure policy of the hook, while its own active deadline keeps bounding the rollout.
+func (c *DeploymentController) enforceHookTimeouts(deployment *corev1.ReplicationController) error {
+	config, err := appsserialization.DecodeDeploymentConfig(deployment)
+	if err != nil {
+		return nil
+	}
+
+	var errors []error
+	for _, suffix := range hookPodSuffixes {
+		seconds, ok := deployutil.HookTimeoutSeconds(config, suffix)
+		if !ok {
+			continue
+		}
+		pod, err := c.podLister.Pods(deployment.Namespace).Get(naming.GetPodName(deployment.Name, suffix))
+		if kerrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if appsutil.DeploymentNameFor(pod) != deployment.Name || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		// The active deadline of a pod can only be lowered.
+		if pod.Spec.ActiveDeadlineSeconds != nil && *pod.Spec.ActiveDeadlineSeconds <= seconds {
+			continue
+		}
+		klog.V(4).Infof("Setting the active deadline of hook pod %s/%s of %q to its hook timeout of %ds", pod.Namespace, pod.Name, appsutil.LabelForDeployment(deployment), seconds)
+		patch := []byte(fmt.Sprintf(`{"spec":{"activeDeadlineSeconds":%d}}`, seconds))
+		if _, err := c.pn.Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			errors = append(errors, fmt.Errorf("couldn't set the hook timeout of hook pod %s/%s: %v", pod.Namespace, pod.Name, err))
+		}
+	}
+	return kutilerrors.NewAggregate(errors)
+}
@@ -0,0 +1,155 @@
+package deployment
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/library-go/pkg/build/naming"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestHandle_hookTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    appsv1.DeploymentStrategyType
+		annotations map[string]string
+		hook        string
+		phase       corev1.PodPhase
+		deadline    int64
+
+		expectedDeadline int64
+	}{
+		{
+			name:             "pre hook of a rolling strategy",
+			strategy:         appsv1.DeploymentStrategyTypeRolling,
+			annotations:      map[string]string{deployutil.PreHookTimeoutSecondsAnnotation: "30"},
+			hook:             appsutil.PreHookPodSuffix,
+			phase:            corev1.PodRunning,
+			deadline:         appsutil.MaxDeploymentDurationSeconds,
+			expectedDeadline: 30,
+		},
+		{
+			name:             "mid hook of a recreate strategy",
+			strategy:         appsv1.DeploymentStrategyTypeRecreate,
+			annotations:      map[string]string{deployutil.MidHookTimeoutSecondsAnnotation: "45"},
+			hook:             appsutil.MidHookPodSuffix,
+			phase:            corev1.PodPending,
+			expectedDeadline: 45,
+		},
+		{
+			name:        "timeout of another hook",
+			strategy:    appsv1.DeploymentStrategyTypeRolling,
+			annotations: map[string]string{deployutil.PostHookTimeoutSecondsAnnotation: "30"},
+			hook:        appsutil.PreHookPodSuffix,
+			phase:       corev1.PodRunning,
+			deadline:    appsutil.MaxDeploymentDurationSeconds,
+		},
+		{
+			name:        "lower active deadline",
+			strategy:    appsv1.DeploymentStrategyTypeRolling,
+			annotations: map[string]string{deployutil.PreHookTimeoutSecondsAnnotation: "30"},
+			hook:        appsutil.PreHookPodSuffix,
+			phase:       corev1.PodRunning,
+			deadline:    20,
+		},
+		{
+			name:        "invalid timeout",
+			strategy:    appsv1.DeploymentStrategyTypeRolling,
+			annotations: map[string]string{deployutil.PreHookTimeoutSecondsAnnotation: "soon"},
+			hook:        appsutil.PreHookPodSuffix,
+			phase:       corev1.PodRunning,
+			deadline:    appsutil.MaxDeploymentDurationSeconds,
+		},
+		{
+			// The kubelet stopped the hook pod at its hook timeout, the rollout keeps running
+			// for the deployer pod to apply the failure policy of the hook.
+			name:        "hook pod stopped at its timeout",
+			strategy:    appsv1.DeploymentStrategyTypeRolling,
+			annotations: map[string]string{deployutil.PreHookTimeoutSecondsAnnotation: "30"},
+			hook:        appsutil.PreHookPodSuffix,
+			phase:       corev1.PodFailed,
+			deadline:    30,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				updatedDeployment *corev1.ReplicationController
+				patchedDeadline   *int64
+			)
+			client := &fake.Clientset{}
+			client.AddReactor("patch", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				patch := &corev1.Pod{}
+				if err := json.Unmarshal(action.(clientgotesting.PatchAction).GetPatch(), patch); err != nil {
+					t.Fatalf("unexpected patch: %v", err)
+				}
+				if name := action.(clientgotesting.PatchAction).GetName(); patch.Spec.ActiveDeadlineSeconds != nil {
+					if name == appsutil.DeployerPodNameForDeployment("config-1") {
+						t.Errorf("unexpected active deadline patch of the deployer pod")
+					}
+					patchedDeadline = patch.Spec.ActiveDeadlineSeconds
+				}
+				return true, nil, nil
+			})
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				return true, updatedDeployment, nil
+			})
+
+			config := appstest.OkDeploymentConfig(1)
+			config.Spec.Strategy = appstest.OkStrategy()
+			if test.strategy == appsv1.DeploymentStrategyTypeRolling {
+				config.Spec.Strategy = appstest.OkRollingStrategy()
+			}
+			config.Annotations = test.annotations
+			deployment, _ := appsutil.MakeDeployment(config)
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusRunning)
+
+			hookPodName := naming.GetPodName(deployment.Name, test.hook)
+			controller := okDeploymentController(client, deployment, []string{hookPodName}, true, corev1.PodRunning)
+			started := metav1.NewTime(time.Now().Add(-time.Minute))
+			for _, name := range []string{appsutil.DeployerPodNameForDeployment(deployment.Name), hookPodName} {
+				obj, _, _ := controller.podIndexer.GetByKey(deployment.Namespace + "/" + name)
+				pod := obj.(*corev1.Pod)
+				pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ReplicationController", Name: deployment.Name}}
+				pod.Status.StartTime = &started
+				if name == hookPodName {
+					pod.Status.Phase = test.phase
+					if test.deadline > 0 {
+						pod.Spec.ActiveDeadlineSeconds = &test.deadline
+					}
+				}
+			}
+
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// The hook timeout does not change the rollout, which is bounded by the strategy timeout.
+			if updatedDeployment != nil && appsutil.DeploymentStatusFor(updatedDeployment) != appsv1.DeploymentStatusRunning {
+				t.Fatalf("expected the rollout to keep running, got %s", appsutil.DeploymentStatusFor(updatedDeployment))
+			}
+			if test.expectedDeadline == 0 {
+				if patchedDeadline != nil {
+					t.Errorf("expected the hook pod not to be patched, got an active deadline of %d", *patchedDeadline)
+				}
+				return
+			}
+			if patchedDeadline == nil || *patchedDeadline != test.expectedDeadline {
+				t.Errorf("expected the active deadline of the hook pod to be set to %d, got %v", test.expectedDeadline, patchedDeadline)
+			}
+		})
+	}
+}
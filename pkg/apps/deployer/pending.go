@@ -0,0 +1,54 @@
+package deployment
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// deployerPendingReason returns the deployer pod pending reason of a pending deployer pod from
+// its conditions and container statuses, and the message explaining it. It returns empty strings
+// when nothing holds the deployer pod back.
+func deployerPendingReason(pod *corev1.Pod) (string, string) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse && condition.Reason == corev1.PodReasonUnschedulable {
+			return deployutil.DeployerPodUnschedulableReason, condition.Message
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting == nil {
+			continue
+		}
+		switch status.State.Waiting.Reason {
+		case "ImagePullBackOff", "ErrImagePull":
+			return deployutil.DeployerImagePullBackOffReason, status.State.Waiting.Message
+		}
+	}
+	return "", ""
+}
+
+// setDeployerPendingReason records the deployer pod pending reason and message in the
+// annotations of a deployment, or removes them when the reason is empty. It returns true if the
+// annotations changed.
+func setDeployerPendingReason(annotations map[string]string, reason, message string) bool {
+	if annotations[deployutil.DeployerPodPendingReasonAnnotation] == reason && annotations[deployutil.DeployerPodPendingMessageAnnotation] == message {
+		return false
+	}
+	if len(reason) == 0 {
+		delete(annotations, deployutil.DeployerPodPendingReasonAnnotation)
+		delete(annotations, deployutil.DeployerPodPendingMessageAnnotation)
+		return true
+	}
+	annotations[deployutil.DeployerPodPendingReasonAnnotation] = reason
+	annotations[deployutil.DeployerPodPendingMessageAnnotation] = message
+	return true
+}
+
+// isQuotaExceeded returns true if the creation of a pod was forbidden because the pod exceeds
+// the resource quota of its namespace.
+func isQuotaExceeded(err error) bool {
+	return kerrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota")
+}
@@ -0,0 +1,194 @@
+package deployment
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestHandle_deployerPendingReason(t *testing.T) {
+	unschedulable := corev1.PodStatus{
+		Phase: corev1.PodPending,
+		Conditions: []corev1.PodCondition{{
+			Type:    corev1.PodScheduled,
+			Status:  corev1.ConditionFalse,
+			Reason:  corev1.PodReasonUnschedulable,
+			Message: "0/3 nodes are available: 3 Insufficient cpu.",
+		}},
+	}
+	waiting := func(reason, message string) corev1.PodStatus {
+		return corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionTrue,
+			}},
+			ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: message}},
+			}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		pod         corev1.PodStatus
+
+		expectUpdate    bool
+		expectedStatus  appsv1.DeploymentStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "unschedulable",
+			pod:             unschedulable,
+			expectUpdate:    true,
+			expectedStatus:  appsv1.DeploymentStatusPending,
+			expectedReason:  deployutil.DeployerPodUnschedulableReason,
+			expectedMessage: "0/3 nodes are available: 3 Insufficient cpu.",
+		},
+		{
+			name:            "image pull back off",
+			pod:             waiting("ImagePullBackOff", `Back-off pulling image "openshift/origin-deployer"`),
+			expectUpdate:    true,
+			expectedStatus:  appsv1.DeploymentStatusPending,
+			expectedReason:  deployutil.DeployerImagePullBackOffReason,
+			expectedMessage: `Back-off pulling image "openshift/origin-deployer"`,
+		},
+		{
+			name:            "image pull error",
+			pod:             waiting("ErrImagePull", "manifest unknown"),
+			expectUpdate:    true,
+			expectedStatus:  appsv1.DeploymentStatusPending,
+			expectedReason:  deployutil.DeployerImagePullBackOffReason,
+			expectedMessage: "manifest unknown",
+		},
+		{
+			name: "container creating",
+			pod:  waiting("ContainerCreating", ""),
+		},
+		{
+			name: "reason already recorded",
+			annotations: map[string]string{
+				deployutil.DeployerPodPendingReasonAnnotation:  deployutil.DeployerPodUnschedulableReason,
+				deployutil.DeployerPodPendingMessageAnnotation: "0/3 nodes are available: 3 Insufficient cpu.",
+			},
+			pod: unschedulable,
+		},
+		{
+			name: "scheduled",
+			annotations: map[string]string{
+				deployutil.DeployerPodPendingReasonAnnotation:  deployutil.DeployerPodUnschedulableReason,
+				deployutil.DeployerPodPendingMessageAnnotation: "0/3 nodes are available: 3 Insufficient cpu.",
+			},
+			pod:            waiting("ContainerCreating", ""),
+			expectUpdate:   true,
+			expectedStatus: appsv1.DeploymentStatusPending,
+		},
+		{
+			name: "running",
+			annotations: map[string]string{
+				deployutil.DeployerPodPendingReasonAnnotation:  deployutil.DeployerImagePullBackOffReason,
+				deployutil.DeployerPodPendingMessageAnnotation: "manifest unknown",
+			},
+			pod:            corev1.PodStatus{Phase: corev1.PodRunning},
+			expectUpdate:   true,
+			expectedStatus: appsv1.DeploymentStatusRunning,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var updatedDeployment *corev1.ReplicationController
+			client := &fake.Clientset{}
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+				return true, updatedDeployment, nil
+			})
+
+			deployment, _ := appsutil.MakeDeployment(appstest.OkDeploymentConfig(1))
+			deployment.CreationTimestamp = metav1.Now()
+			deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusPending)
+			for key, value := range test.annotations {
+				deployment.Annotations[key] = value
+			}
+
+			controller := okDeploymentController(client, deployment, nil, true, test.pod.Phase)
+			obj, _, _ := controller.podIndexer.GetByKey(deployment.Namespace + "/" + appsutil.DeployerPodNameForDeployment(deployment.Name))
+			pod := obj.(*corev1.Pod)
+			pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "ReplicationController", Name: deployment.Name}}
+			pod.Status = test.pod
+
+			if err := controller.handle(deployment, false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !test.expectUpdate {
+				if updatedDeployment != nil {
+					t.Fatalf("expected no deployment update, got %v", updatedDeployment.Annotations)
+				}
+				return
+			}
+			if updatedDeployment == nil {
+				t.Fatalf("expected a deployment update")
+			}
+			if e, a := test.expectedStatus, appsutil.DeploymentStatusFor(updatedDeployment); e != a {
+				t.Errorf("expected deployment status %s, got %s", e, a)
+			}
+			reason, ok := updatedDeployment.Annotations[deployutil.DeployerPodPendingReasonAnnotation]
+			if reason != test.expectedReason || ok != (len(test.expectedReason) > 0) {
+				t.Errorf("expected the deployer pod pending reason %q, got %q", test.expectedReason, reason)
+			}
+			message, ok := updatedDeployment.Annotations[deployutil.DeployerPodPendingMessageAnnotation]
+			if message != test.expectedMessage || ok != (len(test.expectedReason) > 0) {
+				t.Errorf("expected the deployer pod pending message %q, got %q", test.expectedMessage, message)
+			}
+		})
+	}
+}
+
+func TestHandle_deployerQuotaExceeded(t *testing.T) {
+	var updatedDeployment *corev1.ReplicationController
+	quotaErr := kerrors.NewForbidden(corev1.Resource("pods"), "config-1-deploy", fmt.Errorf("exceeded quota: compute, requested: limits.cpu=10, used: limits.cpu=0, limited: limits.cpu=2"))
+	client := &fake.Clientset{}
+	client.AddReactor("create", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, quotaErr
+	})
+	client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		updatedDeployment = action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+		return true, updatedDeployment, nil
+	})
+
+	deployment, _ := appsutil.MakeDeployment(appstest.OkDeploymentConfig(1))
+	deployment.CreationTimestamp = metav1.Now()
+	deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusNew)
+
+	controller := okDeploymentController(client, nil, nil, true, corev1.PodUnknown)
+	if err := controller.handle(deployment, false); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	if updatedDeployment == nil {
+		t.Fatalf("expected a deployment update")
+	}
+	if e, a := appsv1.DeploymentStatusNew, appsutil.DeploymentStatusFor(updatedDeployment); e != a {
+		t.Errorf("expected deployment status %s, got %s", e, a)
+	}
+	if e, a := deployutil.DeployerQuotaExceededReason, updatedDeployment.Annotations[deployutil.DeployerPodPendingReasonAnnotation]; e != a {
+		t.Errorf("expected the deployer pod pending reason %q, got %q", e, a)
+	}
+	if e, a := quotaErr.Error(), updatedDeployment.Annotations[deployutil.DeployerPodPendingMessageAnnotation]; e != a {
+		t.Errorf("expected the deployer pod pending message %q, got %q", e, a)
+	}
+}
@@ -0,0 +1,46 @@
+package deployment
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+	kcontroller "k8s.io/kubernetes/pkg/controller"
+
+	"github.com/openshift/library-go/pkg/apps/appsserialization"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// progressDeadlineExceeded returns true if the deployment did not reach the minimum availability
+// of its deployment config within the progress deadline of the deployment config, measured from
+// the start of the deployer pod, or from its creation while it is not started. Deployments that
+// may still reach it are requeued for when their progress deadline expires. It also returns the
+// progress deadline in seconds.
+func (c *DeploymentController) progressDeadlineExceeded(deployment *corev1.ReplicationController, deployer *corev1.Pod) (int64, bool) {
+	config, err := appsserialization.DecodeDeploymentConfig(deployment)
+	if err != nil {
+		return 0, false
+	}
+	seconds, ok := deployutil.ProgressDeadlineSeconds(config)
+	if !ok {
+		return 0, false
+	}
+	if deployment.Status.AvailableReplicas >= config.Spec.Replicas-appsutil.MaxUnavailable(config) {
+		return seconds, false
+	}
+
+	started := deployer.CreationTimestamp.Time
+	if deployer.Status.StartTime != nil {
+		started = deployer.Status.StartTime.Time
+	}
+	remaining := started.Add(time.Duration(seconds) * time.Second).Sub(c.clock.Now())
+	if remaining > 0 {
+		if key, err := kcontroller.KeyFunc(deployment); err == nil {
+			c.queue.AddAfter(key, remaining)
+		}
+		return seconds, false
+	}
+	klog.V(4).Infof("Rollout for %q exceeded its progress deadline of %ds", appsutil.LabelForDeployment(deployment), seconds)
+	return seconds, true
+}
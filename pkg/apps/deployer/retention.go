@@ -0,0 +1,119 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+	kcontroller "k8s.io/kubernetes/pkg/controller"
+
+	"github.com/openshift/library-go/pkg/apps/appsserialization"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// pruneDeployerPods deletes the deployer and hook pods of the terminated rollouts of the deployment
+// config of the deployment beyond its deployer pod retention. Complete and failed rollouts are
+// retained separately, newest first, so that the pods of failed rollouts can be kept longer for
+// debugging. The pods of failed rollouts are also deleted once they terminated longer ago than
+// the failed deployer pod TTL, except those of the latest failed rollout, so that the latest
+// failure remains inspectable. The pods of rollouts that did not terminate yet are never deleted.
+func (c *DeploymentController) pruneDeployerPods(deployment *corev1.ReplicationController) error {
+	config, err := appsserialization.DecodeDeploymentConfig(deployment)
+	if err != nil {
+		return nil
+	}
+	completeRetention := deployutil.DeployerPodRetention(config, deployutil.CompleteDeployerPodRetentionAnnotation, c.podDefaults.CompleteRetention)
+	failedRetention := deployutil.DeployerPodRetention(config, deployutil.FailedDeployerPodRetentionAnnotation, c.podDefaults.FailedRetention)
+	failedTTL := c.podDefaults.FailedTTL
+	if completeRetention == nil && failedRetention == nil && failedTTL <= 0 {
+		return nil
+	}
+
+	deployments, err := c.rcLister.ReplicationControllers(deployment.Namespace).List(appsutil.ConfigSelector(config.Name))
+	if err != nil {
+		return fmt.Errorf("couldn't list the deployments of %s: %v", appsutil.LabelForDeploymentConfig(config), err)
+	}
+	sort.Sort(appsutil.ByLatestVersionDesc(deployments))
+
+	var complete, failed int
+	var requeueAfter time.Duration
+	for _, d := range deployments {
+		var retention *int
+		var kept *int
+		switch {
+		case appsutil.IsCompleteDeployment(d):
+			retention, kept = completeRetention, &complete
+		case appsutil.IsFailedDeployment(d):
+			retention, kept = failedRetention, &failed
+		default:
+			continue
+		}
+		pods, err := c.getDeployerPods(d)
+		if err != nil {
+			return fmt.Errorf("couldn't fetch deployer pods for %q: %v", appsutil.LabelForDeployment(d), err)
+		}
+		if len(pods) == 0 {
+			continue
+		}
+		if retention != nil && *kept >= *retention {
+			klog.V(4).Infof("Deleting the deployer pods of %q beyond the deployer pod retention", appsutil.LabelForDeployment(d))
+			c.deleteDeployerPods(d, pods)
+			continue
+		}
+		*kept++
+		if kept != &failed || failed == 1 || failedTTL <= 0 {
+			continue
+		}
+		if remaining := terminatedAt(pods).Add(failedTTL).Sub(c.clock.Now()); remaining > 0 {
+			if requeueAfter == 0 || remaining < requeueAfter {
+				requeueAfter = remaining
+			}
+			continue
+		}
+		klog.V(4).Infof("Deleting the deployer pods of %q after the failed deployer pod TTL", appsutil.LabelForDeployment(d))
+		c.deleteDeployerPods(d, pods)
+		c.emitDeploymentEvent(d, corev1.EventTypeNormal, deployutil.DeployerPodsExpiredEventReason,
+			fmt.Sprintf(deployutil.DeployerPodsExpiredEventMessage, appsutil.LabelForDeployment(d), failedTTL))
+	}
+	// Delete the pods of the failed rollouts once their TTL expires.
+	if requeueAfter > 0 {
+		if key, err := kcontroller.KeyFunc(deployment); err == nil {
+			c.queue.AddAfter(key, requeueAfter)
+		}
+	}
+	return nil
+}
+
+// deleteDeployerPods deletes the deployer and hook pods of the deployment. Failures to delete are
+// logged, the pods are deleted again the next time the deployment is handled.
+func (c *DeploymentController) deleteDeployerPods(deployment *corev1.ReplicationController, pods []*corev1.Pod) {
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	for _, pod := range pods {
+		klog.V(4).Infof("Deleting deployer pod %s/%s of %q", pod.Namespace, pod.Name, appsutil.LabelForDeployment(deployment))
+		if err := c.pn.Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			utilruntime.HandleError(fmt.Errorf("couldn't delete deployer pod %q for %q: %v", pod.Name, appsutil.LabelForDeployment(deployment), err))
+		}
+	}
+}
+
+// terminatedAt returns when the last of the pods terminated, or was created if it did not.
+func terminatedAt(pods []*corev1.Pod) time.Time {
+	var last time.Time
+	for _, pod := range pods {
+		at := pod.CreationTimestamp.Time
+		if terminated := getPodTerminatedTimestamp(pod); terminated != nil {
+			at = terminated.Time
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+	return last
+}
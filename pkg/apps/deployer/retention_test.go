@@ -0,0 +1,216 @@
+package deployment
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	imagefake "github.com/openshift/client-go/image/clientset/versioned/fake"
+	imageinformers "github.com/openshift/client-go/image/informers/externalversions"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestPruneDeployerPods(t *testing.T) {
+	one, two := 1, 2
+	// history is the status of the rollouts of the deployment config by version
+	history := []appsv1.DeploymentStatus{
+		appsv1.DeploymentStatusComplete,
+		appsv1.DeploymentStatusFailed,
+		appsv1.DeploymentStatusComplete,
+		appsv1.DeploymentStatusFailed,
+		appsv1.DeploymentStatusComplete,
+		appsv1.DeploymentStatusComplete,
+		appsv1.DeploymentStatusFailed,
+		appsv1.DeploymentStatusRunning,
+	}
+	tests := []struct {
+		name        string
+		defaults    DeployerPodDefaults
+		annotations map[string]string
+
+		expectedDeleted []string
+	}{
+		{
+			name: "no retention",
+		},
+		{
+			name:     "complete and failed retention",
+			defaults: DeployerPodDefaults{CompleteRetention: &two, FailedRetention: &one},
+			expectedDeleted: []string{
+				"config-4-deploy",
+				"config-3-deploy", "config-3-hook-pre",
+				"config-2-deploy",
+				"config-1-deploy",
+			},
+		},
+		{
+			name:     "failed rollouts kept",
+			defaults: DeployerPodDefaults{CompleteRetention: &one},
+			expectedDeleted: []string{
+				"config-5-deploy",
+				"config-3-deploy", "config-3-hook-pre",
+				"config-1-deploy",
+			},
+		},
+		{
+			name:     "deployment config retention",
+			defaults: DeployerPodDefaults{CompleteRetention: &one, FailedRetention: &one},
+			annotations: map[string]string{
+				deployutil.CompleteDeployerPodRetentionAnnotation: "3",
+				deployutil.FailedDeployerPodRetentionAnnotation:   "2",
+			},
+			expectedDeleted: []string{
+				"config-2-deploy",
+				"config-1-deploy",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var deleted []string
+			client := &fake.Clientset{}
+			client.AddReactor("delete", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				deleted = append(deleted, action.(clientgotesting.DeleteAction).GetName())
+				return true, nil, nil
+			})
+			client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(clientgotesting.UpdateAction).GetObject(), nil
+			})
+			client.AddReactor("update", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, action.(clientgotesting.UpdateAction).GetObject(), nil
+			})
+
+			informerFactory := kinformers.NewSharedInformerFactory(client, 0)
+			rcInformer := informerFactory.Core().V1().ReplicationControllers()
+			podInformer := informerFactory.Core().V1().Pods()
+			isInformer := imageinformers.NewSharedInformerFactory(&imagefake.Clientset{}, 0).Image().V1().ImageStreams()
+			controller := NewDeployerController(rcInformer, podInformer, isInformer, client, "sa:test", "openshift/origin-deployer", env, test.defaults)
+
+			var deployments []*corev1.ReplicationController
+			for i, status := range history {
+				config := appstest.OkDeploymentConfig(int64(i + 1))
+				config.Annotations = test.annotations
+				deployment, _ := appsutil.MakeDeployment(config)
+				deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(status)
+				rcInformer.Informer().GetIndexer().Add(deployment)
+				deployments = append(deployments, deployment)
+
+				pod := deployerPod(deployment, "", true)
+				pod.Status.Phase = corev1.PodSucceeded
+				if status == appsv1.DeploymentStatusFailed {
+					pod.Status.Phase = corev1.PodFailed
+				}
+				if status == appsv1.DeploymentStatusRunning {
+					pod.Status.Phase = corev1.PodRunning
+				}
+				podInformer.Informer().GetIndexer().Add(pod)
+			}
+			podInformer.Informer().GetIndexer().Add(deployerPod(deployments[2], fmt.Sprintf("%s-hook-pre", deployments[2].Name), true))
+
+			if err := controller.handle(deployments[5], false); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(deleted, test.expectedDeleted) {
+				t.Errorf("expected deleted pods %v, got %v", test.expectedDeleted, deleted)
+			}
+		})
+	}
+}
+
+func TestPruneDeployerPodsFailedTTL(t *testing.T) {
+	now := time.Now()
+	// terminated is how long ago the deployer pods of the rollouts of the deployment config
+	// terminated by version
+	history := []struct {
+		status     appsv1.DeploymentStatus
+		terminated time.Duration
+	}{
+		{appsv1.DeploymentStatusFailed, 3 * time.Hour},
+		{appsv1.DeploymentStatusComplete, 3 * time.Hour},
+		{appsv1.DeploymentStatusFailed, 2 * time.Hour},
+		{appsv1.DeploymentStatusFailed, 30 * time.Minute},
+		{appsv1.DeploymentStatusComplete, 2 * time.Hour},
+		// the latest failed rollout is exempt from the TTL
+		{appsv1.DeploymentStatusFailed, 2 * time.Hour},
+		{appsv1.DeploymentStatusRunning, 0},
+	}
+
+	var deleted []string
+	client := &fake.Clientset{}
+	client.AddReactor("delete", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		deleted = append(deleted, action.(clientgotesting.DeleteAction).GetName())
+		return true, nil, nil
+	})
+	client.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, action.(clientgotesting.UpdateAction).GetObject(), nil
+	})
+	client.AddReactor("update", "pods", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, action.(clientgotesting.UpdateAction).GetObject(), nil
+	})
+
+	informerFactory := kinformers.NewSharedInformerFactory(client, 0)
+	rcInformer := informerFactory.Core().V1().ReplicationControllers()
+	podInformer := informerFactory.Core().V1().Pods()
+	isInformer := imageinformers.NewSharedInformerFactory(&imagefake.Clientset{}, 0).Image().V1().ImageStreams()
+	controller := NewDeployerController(rcInformer, podInformer, isInformer, client, "sa:test", "openshift/origin-deployer", env, DeployerPodDefaults{FailedTTL: time.Hour})
+	controller.clock = clocktesting.NewFakeClock(now)
+	recorder := record.NewFakeRecorder(10)
+	controller.recorder = recorder
+
+	var deployments []*corev1.ReplicationController
+	for i, rollout := range history {
+		deployment, _ := appsutil.MakeDeployment(appstest.OkDeploymentConfig(int64(i + 1)))
+		deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(rollout.status)
+		rcInformer.Informer().GetIndexer().Add(deployment)
+		deployments = append(deployments, deployment)
+
+		pod := deployerPod(deployment, "", true)
+		pod.Status.Phase = corev1.PodRunning
+		if rollout.terminated > 0 {
+			pod.Status.Phase = corev1.PodSucceeded
+			if rollout.status == appsv1.DeploymentStatusFailed {
+				pod.Status.Phase = corev1.PodFailed
+			}
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.NewTime(now.Add(-rollout.terminated))}},
+			}}
+		}
+		podInformer.Informer().GetIndexer().Add(pod)
+	}
+	podInformer.Informer().GetIndexer().Add(deployerPod(deployments[2], fmt.Sprintf("%s-hook-pre", deployments[2].Name), true))
+
+	if err := controller.handle(deployments[4], false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedDeleted := []string{
+		"config-3-deploy", "config-3-hook-pre",
+		"config-1-deploy",
+	}
+	if !reflect.DeepEqual(deleted, expectedDeleted) {
+		t.Errorf("expected deleted pods %v, got %v", expectedDeleted, deleted)
+	}
+	var events []string
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, deployutil.DeployerPodsExpiredEventReason) {
+			events = append(events, event)
+		}
+	}
+	if len(events) != 2 || !strings.Contains(events[0], "config-3") || !strings.Contains(events[1], "config-1") {
+		t.Errorf("expected an event for the cleanup of each failed rollout, got %q", events)
+	}
+}
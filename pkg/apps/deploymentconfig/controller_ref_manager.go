@@ -2,19 +2,30 @@ package deploymentconfig
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
 	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	kmetav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	kschema "k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	kutilerrors "k8s.io/apimachinery/pkg/util/errors"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	kclientset "k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	kcontroller "k8s.io/kubernetes/pkg/controller"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	appsv1lister "github.com/openshift/client-go/apps/listers/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
 )
 
 // RSControlInterface is an interface that knows how to add or delete
@@ -43,6 +54,9 @@ type RCControllerRefManager struct {
 	kcontroller.BaseControllerRefManager
 	controllerKind kschema.GroupVersionKind
 	rcControl      RCControlInterface
+	// configLister lists the deployment configs an orphaned ReplicationController may overlap with.
+	configLister appsv1lister.DeploymentConfigLister
+	recorder     record.EventRecorder
 }
 
 // NewRCControllerRefManager returns a RCControllerRefManager that exposes
@@ -57,12 +71,17 @@ type RCControllerRefManager struct {
 //
 //	RCControllerRefManager instance. Create a new instance if it
 //	makes sense to check CanAdopt() again (e.g. in a different sync pass).
+//
+// The configLister is used to find the other deployment configs an orphan matches, and the
+// recorder to report them.
 func NewRCControllerRefManager(
 	rcControl RCControlInterface,
 	controller kmetav1.Object,
 	selector klabels.Selector,
 	controllerKind kschema.GroupVersionKind,
 	canAdopt func(ctx context.Context) error,
+	configLister appsv1lister.DeploymentConfigLister,
+	recorder record.EventRecorder,
 ) *RCControllerRefManager {
 	return &RCControllerRefManager{
 		BaseControllerRefManager: kcontroller.BaseControllerRefManager{
@@ -72,6 +91,8 @@ func NewRCControllerRefManager(
 		},
 		controllerKind: controllerKind,
 		rcControl:      rcControl,
+		configLister:   configLister,
+		recorder:       recorder,
 	}
 }
 
@@ -81,6 +102,14 @@ func NewRCControllerRefManager(
 //   - Adopt the ReplicationController if it's an orphan.
 //   - Release owned ReplicationController if the selector no longer matches.
 //
+// ReplicationControllers whose deployment config annotation or selector names the controller
+// match it even when their labels do not match the selector, so that the deployments linked to a
+// deployment config only by their annotations or selector are adopted too. A ReplicationController
+// only matches when it is a version of the deployment config, see deploymentVersionOf, so owned
+// ReplicationControllers that are no version of it are released. ReplicationControllers controlled
+// by another controller are never adopted, neither are orphans that match several deployment
+// configs.
+//
 // A non-nil error is returned if some form of reconciliation was attempted and
 // failed. Usually, controllers should try again later in case reconciliation
 // is still needed.
@@ -90,7 +119,20 @@ func NewRCControllerRefManager(
 // own the object.
 func (m *RCControllerRefManager) ClaimReplicationController(rc *v1.ReplicationController) (bool, error) {
 	match := func(obj kmetav1.Object) bool {
-		return m.Selector.Matches(klabels.Set(obj.GetLabels()))
+		if !matchesConfig(m.Controller.GetName(), m.Selector, obj.(*v1.ReplicationController)) {
+			return false
+		}
+		if kmetav1.GetControllerOfNoCopy(obj) != nil {
+			return true
+		}
+		if overlaps := m.overlappingConfigs(obj.(*v1.ReplicationController)); len(overlaps) > 0 {
+			if controller, ok := m.Controller.(runtime.Object); ok {
+				m.recorder.Eventf(controller, v1.EventTypeWarning, deployutil.ReplicationControllerOverlapEventReason,
+					deployutil.ReplicationControllerOverlapEventMessage, obj.GetName(), strings.Join(overlaps, ", "))
+			}
+			return false
+		}
+		return true
 	}
 	adopt := func(ctx context.Context, obj kmetav1.Object) error {
 		return m.AdoptReplicationController(obj.(*v1.ReplicationController))
@@ -125,33 +167,113 @@ func (m *RCControllerRefManager) ClaimReplicationControllers(rcs []*v1.Replicati
 			errlist = append(errlist, err)
 			continue
 		}
-		if ok {
-			claimed = append(claimed, rc)
+		if !ok {
+			continue
 		}
+		// The cached ReplicationController of an adoption lacks the annotations the adoption
+		// restored, they are set on a copy for the rest of the sync.
+		if missing := m.missingAnnotations(rc); len(missing) > 0 {
+			rc = rc.DeepCopy()
+			if rc.Annotations == nil {
+				rc.Annotations = map[string]string{}
+			}
+			for key, value := range missing {
+				rc.Annotations[key] = value
+			}
+		}
+		claimed = append(claimed, rc)
 	}
 	return claimed, kutilerrors.NewAggregate(errlist)
 }
 
-// AdoptReplicationController sends a patch to take control of the ReplicationController. It returns the error if
-// the patching fails.
+// AdoptReplicationController sends a patch to take control of the ReplicationController and to restore
+// the deployment config and version annotations it lacks. It returns the error if the patching fails.
 func (m *RCControllerRefManager) AdoptReplicationController(rs *v1.ReplicationController) error {
 	ctx := context.TODO()
 	if err := m.CanAdopt(ctx); err != nil {
 		return fmt.Errorf("can't adopt ReplicationController %s/%s (%s): %v", rs.Namespace, rs.Name, rs.UID, err)
 	}
+	annotations, err := json.Marshal(m.missingAnnotations(rs))
+	if err != nil {
+		return err
+	}
 	// Note that ValidateOwnerReferences() will reject this patch if another
 	// OwnerReference exists with controller=true.
 	addControllerPatch := fmt.Sprintf(
 		`{"metadata":{
 			"ownerReferences":[{"apiVersion":"%s","kind":"%s","name":"%s","uid":"%s","controller":true,"blockOwnerDeletion":true}],
+			"annotations":%s,
 			"uid":"%s"
 			}
 		}`,
 		m.controllerKind.GroupVersion(), m.controllerKind.Kind,
-		m.Controller.GetName(), m.Controller.GetUID(), rs.UID)
+		m.Controller.GetName(), m.Controller.GetUID(), annotations, rs.UID)
 	return m.rcControl.PatchReplicationController(rs.Namespace, rs.Name, []byte(addControllerPatch))
 }
 
+// missingAnnotations returns the deployment config and version annotations the ReplicationController
+// lacks to be a deployment of the controller.
+func (m *RCControllerRefManager) missingAnnotations(rc *v1.ReplicationController) map[string]string {
+	missing := map[string]string{}
+	if _, ok := rc.Annotations[appsv1.DeploymentConfigAnnotation]; !ok {
+		missing[appsv1.DeploymentConfigAnnotation] = m.Controller.GetName()
+	}
+	if _, ok := rc.Annotations[appsv1.DeploymentVersionAnnotation]; !ok {
+		if version, ok := deploymentVersionOf(m.Controller.GetName(), rc); ok {
+			missing[appsv1.DeploymentVersionAnnotation] = strconv.FormatInt(version, 10)
+		}
+	}
+	return missing
+}
+
+// overlappingConfigs returns the names of the other deployment configs of the namespace the
+// ReplicationController matches.
+func (m *RCControllerRefManager) overlappingConfigs(rc *v1.ReplicationController) []string {
+	configs, err := m.configLister.DeploymentConfigs(rc.Namespace).List(klabels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to list deployment configs of %s: %v", rc.Namespace, err))
+		return nil
+	}
+	var overlaps []string
+	for _, config := range configs {
+		if config.UID == m.Controller.GetUID() {
+			continue
+		}
+		if matchesConfig(config.Name, appsutil.ConfigSelector(config.Name), rc) {
+			overlaps = append(overlaps, config.Name)
+		}
+	}
+	sort.Strings(overlaps)
+	return overlaps
+}
+
+// matchesConfig returns true if the ReplicationController is a deployment of the named deployment
+// config: its labels match the selector, or its deployment config annotation or its selector name
+// the deployment config, and it is a version of the deployment config.
+func matchesConfig(name string, selector klabels.Selector, rc *v1.ReplicationController) bool {
+	if !selector.Matches(klabels.Set(rc.Labels)) && rc.Annotations[appsv1.DeploymentConfigAnnotation] != name && rc.Spec.Selector[appsutil.DeploymentConfigLabel] != name {
+		return false
+	}
+	_, ok := deploymentVersionOf(name, rc)
+	return ok
+}
+
+// deploymentVersionOf returns the version of the named deployment config the ReplicationController
+// was rolled out as, from its version annotation or else from its name. It returns false if the
+// ReplicationController is no version of the deployment config.
+func deploymentVersionOf(name string, rc *v1.ReplicationController) (int64, bool) {
+	if value, ok := rc.Annotations[appsv1.DeploymentVersionAnnotation]; ok {
+		version, err := strconv.ParseInt(value, 10, 64)
+		return version, err == nil && version > 0
+	}
+	suffix := strings.TrimPrefix(rc.Name, name+"-")
+	if suffix == rc.Name {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(suffix, 10, 64)
+	return version, err == nil && version > 0 && appsutil.DeploymentNameForConfigVersion(name, version) == rc.Name
+}
+
 // ReleaseReplicationController sends a patch to free the ReplicationController from the control of the Deployment controller.
 // It returns the error if the patching fails. 404 and 422 errors are ignored.
 func (m *RCControllerRefManager) ReleaseReplicationController(rc *v1.ReplicationController) error {
@@ -0,0 +1,222 @@
+package deploymentconfig
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	appslisters "github.com/openshift/client-go/apps/listers/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// fakeRCControl records the patches of replication controllers.
+type fakeRCControl struct {
+	patches map[string]string
+}
+
+func (f *fakeRCControl) PatchReplicationController(namespace, name string, data []byte) error {
+	f.patches[name] = string(data)
+	return nil
+}
+
+func TestClaimReplicationControllers(t *testing.T) {
+	config := appstest.OkDeploymentConfig(1)
+	config.UID = types.UID("dc-uid")
+	isController := true
+	ownedBy := func(kind, name string, uid types.UID) []metav1.OwnerReference {
+		return []metav1.OwnerReference{{APIVersion: appsv1.GroupVersion.String(), Kind: kind, Name: name, UID: uid, Controller: &isController}}
+	}
+	newRC := func(name string, labels, annotations map[string]string, owners []metav1.OwnerReference) *corev1.ReplicationController {
+		return &corev1.ReplicationController{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       config.Namespace,
+			UID:             types.UID(name + "-uid"),
+			Labels:          labels,
+			Annotations:     annotations,
+			OwnerReferences: owners,
+		}}
+	}
+	link := map[string]string{appsv1.DeploymentConfigAnnotation: config.Name}
+	selected := newRC("config-4", nil, nil, nil)
+	selected.Spec.Selector = map[string]string{appsutil.DeploymentConfigLabel: config.Name}
+
+	tests := []struct {
+		name          string
+		rc            *corev1.ReplicationController
+		expectClaimed bool
+		expectAdopted bool
+		expectRelease bool
+		expectEvent   bool
+
+		expectedAnnotations map[string]string
+	}{
+		{
+			name:                "orphan with the deployment config label",
+			rc:                  newRC("config-1", link, nil, nil),
+			expectClaimed:       true,
+			expectAdopted:       true,
+			expectedAnnotations: map[string]string{appsv1.DeploymentConfigAnnotation: config.Name, appsv1.DeploymentVersionAnnotation: "1"},
+		},
+		{
+			name:                "orphan linked by the deployment config annotation",
+			rc:                  newRC("config-2", nil, link, nil),
+			expectClaimed:       true,
+			expectAdopted:       true,
+			expectedAnnotations: map[string]string{appsv1.DeploymentVersionAnnotation: "2"},
+		},
+		{
+			name:                "orphan matched by its selector",
+			rc:                  selected,
+			expectClaimed:       true,
+			expectAdopted:       true,
+			expectedAnnotations: map[string]string{appsv1.DeploymentConfigAnnotation: config.Name, appsv1.DeploymentVersionAnnotation: "4"},
+		},
+		{
+			name:                "orphan with a version annotation",
+			rc:                  newRC("restored", link, map[string]string{appsv1.DeploymentVersionAnnotation: "3"}, nil),
+			expectClaimed:       true,
+			expectAdopted:       true,
+			expectedAnnotations: map[string]string{appsv1.DeploymentConfigAnnotation: config.Name},
+		},
+		{
+			name:          "already owned",
+			rc:            newRC("config-3", nil, link, ownedBy("DeploymentConfig", config.Name, config.UID)),
+			expectClaimed: true,
+		},
+		{
+			name: "orphan that is no version of the deployment config",
+			rc:   newRC("config-canary", link, nil, nil),
+		},
+		{
+			name:          "owned and no version of the deployment config",
+			rc:            newRC("copy", link, link, ownedBy("DeploymentConfig", config.Name, config.UID)),
+			expectRelease: true,
+		},
+		{
+			name: "controlled by another controller",
+			rc:   newRC("config-5", link, link, ownedBy("DeploymentConfig", "other", types.UID("other-uid"))),
+		},
+		{
+			name: "linked to another deployment config",
+			rc:   newRC("other-1", nil, map[string]string{appsv1.DeploymentConfigAnnotation: "other"}, nil),
+		},
+		{
+			name:        "orphan matching another deployment config",
+			rc:          newRC("config-6", link, map[string]string{appsv1.DeploymentConfigAnnotation: "other", appsv1.DeploymentVersionAnnotation: "6"}, nil),
+			expectEvent: true,
+		},
+		{
+			name:          "owned and matching another deployment config",
+			rc:            newRC("config-7", link, map[string]string{appsv1.DeploymentConfigAnnotation: "other", appsv1.DeploymentVersionAnnotation: "7"}, ownedBy("DeploymentConfig", config.Name, config.UID)),
+			expectClaimed: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			control := &fakeRCControl{patches: map[string]string{}}
+			canAdopt := func(ctx context.Context) error { return nil }
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			other := appstest.OkDeploymentConfig(1)
+			other.Name, other.UID = "other", types.UID("other-uid")
+			indexer.Add(config)
+			indexer.Add(other)
+			recorder := record.NewFakeRecorder(10)
+			cm := NewRCControllerRefManager(control, config, appsutil.ConfigSelector(config.Name), appsv1.GroupVersion.WithKind("DeploymentConfig"), canAdopt,
+				appslisters.NewDeploymentConfigLister(indexer), recorder)
+
+			original := tc.rc.DeepCopy()
+			claimed, err := cm.ClaimReplicationControllers([]*corev1.ReplicationController{tc.rc})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectClaimed != (len(claimed) == 1) {
+				t.Errorf("expected claimed %v, got %v", tc.expectClaimed, claimed)
+			}
+			patch, patched := control.patches[tc.rc.Name]
+			if (tc.expectAdopted || tc.expectRelease) != patched {
+				t.Fatalf("expected adopted %v and released %v, got patches %v", tc.expectAdopted, tc.expectRelease, control.patches)
+			}
+			if tc.expectAdopted && (!strings.Contains(patch, `"uid":"dc-uid"`) || !strings.Contains(patch, `"controller":true`)) {
+				t.Errorf("expected a patch adding the controller reference of the deployment config, got %s", patch)
+			}
+			if tc.expectRelease && !strings.Contains(patch, `"$patch":"delete","uid":"dc-uid"`) {
+				t.Errorf("expected a patch removing the controller reference of the deployment config, got %s", patch)
+			}
+			for key, value := range tc.expectedAnnotations {
+				if !strings.Contains(patch, `"`+key+`":"`+value+`"`) {
+					t.Errorf("expected the adoption to restore annotation %s=%s, got %s", key, value, patch)
+				}
+				if claimed[0].Annotations[key] != value {
+					t.Errorf("expected the claimed deployment to have annotation %s=%s, got %v", key, value, claimed[0].Annotations)
+				}
+			}
+			if !reflect.DeepEqual(original, tc.rc) {
+				t.Errorf("unexpected mutation of the cached replication controller")
+			}
+			select {
+			case event := <-recorder.Events:
+				if !tc.expectEvent || !strings.Contains(event, deployutil.ReplicationControllerOverlapEventReason) || !strings.Contains(event, "other") {
+					t.Errorf("unexpected event %q", event)
+				}
+			default:
+				if tc.expectEvent {
+					t.Errorf("expected an event reporting the overlapping deployment configs")
+				}
+			}
+		})
+	}
+}
+
+func TestHandleCreatesOwnedDeployment(t *testing.T) {
+	f := newControllerFixture()
+	config := appstest.OkDeploymentConfig(1)
+	config.UID = types.UID("dc-uid")
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created == nil {
+		t.Fatalf("expected a deployment to be created")
+	}
+	ref := metav1.GetControllerOf(f.created)
+	if ref == nil || ref.Kind != "DeploymentConfig" || ref.Name != config.Name || ref.UID != config.UID || ref.BlockOwnerDeletion == nil || !*ref.BlockOwnerDeletion {
+		t.Errorf("expected the deployment to be controlled by its deployment config, got %#v", f.created.OwnerReferences)
+	}
+}
+
+func TestHandleAdoptsDeploymentWithoutAnnotations(t *testing.T) {
+	config := appstest.OkDeploymentConfig(1)
+	config.Spec.Triggers = []appsv1.DeploymentTriggerPolicy{appstest.OkConfigChangeTrigger()}
+	deployment, _ := appsutil.MakeDeployment(config)
+	deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusComplete)
+	// the controller reference and deployment config annotations were lost by a restore of the
+	// deployment
+	deployment.OwnerReferences = nil
+	delete(deployment.Annotations, appsv1.DeploymentConfigAnnotation)
+	delete(deployment.Annotations, appsv1.DeploymentVersionAnnotation)
+
+	f := newControllerFixture(deployment)
+	control := &fakeRCControl{patches: map[string]string{}}
+	f.controller.rcControl = control
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created != nil {
+		t.Fatalf("expected no deployment to be created, got %s", f.created.Name)
+	}
+	if f.updatedStatus != nil && f.updatedStatus.Status.LatestVersion != 1 {
+		t.Errorf("expected the latest version to stay 1, got %d", f.updatedStatus.Status.LatestVersion)
+	}
+	if patch := control.patches[deployment.Name]; !strings.Contains(patch, `"controller":true`) || !strings.Contains(patch, `"`+appsv1.DeploymentVersionAnnotation+`":"1"`) {
+		t.Errorf("expected the deployment to be adopted with its version, got %q", patch)
+	}
+}
@@ -0,0 +1,66 @@
+package deploymentconfig
+
+import (
+	"time"
+
+	"k8s.io/klog/v2"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// configChange is a change of the template of a deployment config that waits to be rolled out.
+type configChange struct {
+	// generation is the generation of the deployment config the change was last observed at.
+	generation int64
+	// observed is when the generation was first observed.
+	observed time.Time
+}
+
+// debounceConfigChange returns how long the rollout of a config change of the deployment config
+// waits for further changes. The wait starts over whenever the generation of the deployment
+// config changes, so that the changes made within the debounce of each other are rolled out once
+// with the final template. The deployment config is requeued for when the wait is over.
+func (c *DeploymentConfigController) debounceConfigChange(config *appsv1.DeploymentConfig) time.Duration {
+	debounce := deployutil.ConfigChangeDebounce(config, c.configChangeDebounce)
+	key := config.Namespace + "/" + config.Name
+	if debounce == 0 {
+		c.forgetConfigChange(key)
+		return 0
+	}
+
+	c.configChangesLock.Lock()
+	defer c.configChangesLock.Unlock()
+	now := c.clock.Now()
+	change, ok := c.configChanges[key]
+	if !ok || change.generation != config.Generation {
+		change = configChange{generation: config.Generation, observed: now}
+		c.configChanges[key] = change
+	}
+	remaining := change.observed.Add(debounce).Sub(now)
+	if remaining <= 0 {
+		delete(c.configChanges, key)
+		return 0
+	}
+	klog.V(4).Infof("Waiting %s for further changes of %s before rolling out its config change", remaining, appsutil.LabelForDeploymentConfig(config))
+	c.queue.AddAfter(key, remaining)
+	return remaining
+}
+
+// forgetConfigChange forgets the config change of the deployment config with the given key that
+// waited to be rolled out.
+func (c *DeploymentConfigController) forgetConfigChange(key string) {
+	c.configChangesLock.Lock()
+	defer c.configChangesLock.Unlock()
+	delete(c.configChanges, key)
+}
+
+// isConfigChangeRollout returns true if the latest version of the deployment config is rolled out
+// for a change of its template.
+func isConfigChangeRollout(config *appsv1.DeploymentConfig) bool {
+	if appsutil.IsInitialDeployment(config) || config.Status.Details == nil || len(config.Status.Details.Causes) == 0 {
+		return false
+	}
+	return config.Status.Details.Causes[0].Type == appsv1.DeploymentTriggerOnConfigChange
+}
@@ -0,0 +1,87 @@
+package deploymentconfig
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsserialization"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestHandleConfigChangeDebounce(t *testing.T) {
+	f := newControllerFixture(rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete))
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	f.controller.clock = fakeClock
+	f.controller.configChangeDebounce = 10 * time.Second
+
+	config := rollbackConfig(1, "registry/app:v1")
+	config.Annotations = nil
+	config.Generation = 1
+
+	// three template edits in quick succession
+	for i := 1; i <= 3; i++ {
+		config = config.DeepCopy()
+		config.Generation++
+		config.Spec.Template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "EDIT", Value: strconv.Itoa(i)}}
+		if err := f.controller.Handle(config); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if f.updatedStatus != nil && f.updatedStatus.Status.LatestVersion != 1 {
+			t.Fatalf("expected edit %d to wait for further changes, got latest version %d", i, f.updatedStatus.Status.LatestVersion)
+		}
+		if f.updatedStatus != nil && f.updatedStatus.Status.ObservedGeneration >= config.Generation {
+			t.Fatalf("expected edit %d not to be observed while its rollout is debounced, got observed generation %d", i, f.updatedStatus.Status.ObservedGeneration)
+		}
+		fakeClock.Step(3 * time.Second)
+	}
+	if f.created != nil {
+		t.Fatalf("expected no deployment to be created while the edits are debounced")
+	}
+
+	// the edits are rolled out once the deployment config stays unchanged for the debounce
+	fakeClock.Step(10 * time.Second)
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.updatedStatus == nil || f.updatedStatus.Status.LatestVersion != 2 {
+		t.Fatalf("expected the latest version to advance once to 2, got %#v", f.updatedStatus)
+	}
+	if err := f.controller.Handle(f.updatedStatus); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created == nil {
+		t.Fatalf("expected a deployment to be created")
+	}
+	deployed, err := appsserialization.DecodeDeploymentConfig(f.created)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(deployed.Spec.Template, config.Spec.Template) {
+		t.Errorf("expected the deployment of the final template, got env %v", deployed.Spec.Template.Spec.Containers[0].Env)
+	}
+}
+
+func TestConfigChangeDebounceAnnotation(t *testing.T) {
+	config := rollbackConfig(1, "registry/app:v1")
+	for value, expected := range map[string]time.Duration{
+		"":        5 * time.Second,
+		"0s":      0,
+		"30s":     30 * time.Second,
+		"invalid": 5 * time.Second,
+		"-1s":     5 * time.Second,
+	} {
+		config.Annotations = map[string]string{}
+		if len(value) > 0 {
+			config.Annotations[deployutil.ConfigChangeDebounceAnnotation] = value
+		}
+		if actual := deployutil.ConfigChangeDebounce(config, 5*time.Second); actual != expected {
+			t.Errorf("%q: expected debounce %s, got %s", value, expected, actual)
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 
 	"k8s.io/klog/v2"
 	"k8s.io/utils/diff"
@@ -24,6 +26,7 @@ import (
 	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	kcontroller "k8s.io/kubernetes/pkg/controller"
+	"k8s.io/utils/clock"
 
 	appsv1 "github.com/openshift/api/apps/v1"
 	appsv1client "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
@@ -31,6 +34,8 @@ import (
 
 	"github.com/openshift/library-go/pkg/apps/appsserialization"
 	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+	metrics "github.com/openshift/openshift-controller-manager/pkg/apps/metrics/prometheus"
 )
 
 const (
@@ -81,6 +86,16 @@ type DeploymentConfigController struct {
 	// rcControl is used for adopting/releasing replication controllers.
 	rcControl RCControlInterface
 
+	// configChangeDebounce is how long deployment configs without a config change debounce
+	// annotation wait for further changes before a config change rolls them out.
+	configChangeDebounce time.Duration
+	// configChanges are the config changes of deployment configs, by key, that wait for further
+	// changes to be rolled out.
+	configChanges     map[string]configChange
+	configChangesLock sync.Mutex
+	// clock is used to measure the debounce of config changes.
+	clock clock.Clock
+
 	// codec is used to build deployments from configs.
 	codec runtime.Codec
 	// recorder is used to record events.
@@ -114,7 +129,7 @@ func (c *DeploymentConfigController) Handle(config *appsv1.DeploymentConfig) err
 		}
 		return fresh, nil
 	})
-	cm := NewRCControllerRefManager(c.rcControl, config, appsutil.ConfigSelector(config.Name), appsv1.GroupVersion.WithKind("DeploymentConfig"), canAdoptFunc)
+	cm := NewRCControllerRefManager(c.rcControl, config, appsutil.ConfigSelector(config.Name), appsv1.GroupVersion.WithKind("DeploymentConfig"), canAdoptFunc, c.dcLister, c.recorder)
 	existingDeployments, err := cm.ClaimReplicationControllers(rcList)
 	if err != nil {
 		return fmt.Errorf("error while deploymentConfigController claiming replication controllers: %v", err)
@@ -141,13 +156,15 @@ func (c *DeploymentConfigController) Handle(config *appsv1.DeploymentConfig) err
 	configCopy := config.DeepCopy()
 
 	latestExists, latestDeployment := appsutil.LatestDeploymentInfo(config, existingDeployments)
-	candidateVersion := appsutil.DeploymentVersionFor(latestDeployment)
-	if candidateVersion > config.Status.LatestVersion {
-		// FIXME: update LatestVersion to candidateVersion directly when validation allows it in all supported skews
-		configCopy.Status.LatestVersion++
-		_, err := c.appsClient.DeploymentConfigs(configCopy.Namespace).UpdateStatus(context.TODO(), configCopy, metav1.UpdateOptions{})
+	metrics.RecordProgressing(config.Namespace, config.Name, latestExists && !appsutil.IsTerminatedDeployment(latestDeployment))
+	if updated, err := c.reconcileLatestVersion(config, existingDeployments); updated || err != nil {
 		return err
 	}
+	if latestExists {
+		if updated, err := c.finishRollback(config, latestDeployment, existingDeployments); updated || err != nil {
+			return err
+		}
+	}
 
 	if !latestExists {
 		if err := c.cancelRunningRollouts(config, existingDeployments, cm); err != nil {
@@ -173,9 +190,22 @@ func (c *DeploymentConfigController) Handle(config *appsv1.DeploymentConfig) err
 		return c.updateStatus(configCopy, existingDeployments, true)
 	}
 
+	if !shouldTrigger {
+		c.forgetConfigChange(config.Namespace + "/" + config.Name)
+	} else if isConfigChangeRollout(configCopy) && c.debounceConfigChange(config) > 0 {
+		// The rollout of the new generation is only postponed, so it is not observed yet.
+		return c.updateStatus(config, existingDeployments, false)
+	}
+
 	if shouldTrigger {
 		configCopy.Status.LatestVersion++
 		_, err := c.appsClient.DeploymentConfigs(configCopy.Namespace).UpdateStatus(context.TODO(), configCopy, metav1.UpdateOptions{})
+		if err == nil && isConfigChangeRollout(configCopy) {
+			if changes := configChangeDiff(config, latestDeployment); len(changes) > 0 {
+				c.recorder.Eventf(config, v1.EventTypeNormal, deployutil.TemplateChangedEventReason, deployutil.TemplateChangedEventMessage,
+					configCopy.Status.LatestVersion, strings.Join(changes, "; "))
+			}
+		}
 		return err
 	}
 
@@ -185,9 +215,18 @@ func (c *DeploymentConfigController) Handle(config *appsv1.DeploymentConfig) err
 		// If the latest deployment is still running, try again later. We don't
 		// want to compete with the deployer.
 		if !appsutil.IsTerminatedDeployment(latestDeployment) {
+			if err := c.syncRolloutReplicas(config, latestDeployment, cm); err != nil {
+				return err
+			}
 			return c.updateStatus(config, existingDeployments, false)
 		}
 
+		// Roll back failed rollouts first; the deployments are reconciled once the
+		// rollback is observed.
+		if updated, err := c.rollbackFailedDeployment(config, latestDeployment, existingDeployments); updated || err != nil {
+			return err
+		}
+
 		return c.reconcileDeployments(existingDeployments, config, cm)
 	}
 
@@ -197,6 +236,12 @@ func (c *DeploymentConfigController) Handle(config *appsv1.DeploymentConfig) err
 	if err != nil {
 		return fatalError(fmt.Sprintf("couldn't make deployment from (potentially invalid) deployment config %s: %v", appsutil.LabelForDeploymentConfig(config), err))
 	}
+	setRollbackOfVersion(config, deployment)
+	if isConfigChangeRollout(config) {
+		if changes := configChangeDiff(config, previousDeployment(config, existingDeployments)); len(changes) > 0 {
+			deployment.Annotations[deployutil.TemplateDiffAnnotation] = formatTemplateDiff(changes, maxTemplateDiffAnnotationBytes)
+		}
+	}
 	created, err := c.kubeClient.ReplicationControllers(config.Namespace).Create(context.TODO(), deployment, metav1.CreateOptions{})
 	if err != nil {
 		if kapierrors.IsAlreadyExists(err) {
@@ -262,13 +307,9 @@ func (c *DeploymentConfigController) reconcileDeployments(existingDeployments []
 			zero := int32(0)
 			oldReplicaCount = &zero
 		}
-		newReplicaCount := int32(0)
-		if isActiveDeployment {
-			newReplicaCount = config.Spec.Replicas
-		}
+		newReplicaCount := desiredReplicas(config, isActiveDeployment)
 		if config.Spec.Test {
 			klog.V(4).Infof("Deployment config %q is test and deployment %q will be scaled down", appsutil.LabelForDeploymentConfig(config), appsutil.LabelForDeployment(deployment))
-			newReplicaCount = 0
 		}
 
 		// Only update if necessary.
@@ -322,6 +363,7 @@ func (c *DeploymentConfigController) reconcileDeployments(existingDeployments []
 // deployment config status.
 func (c *DeploymentConfigController) updateStatus(config *appsv1.DeploymentConfig, deployments []*v1.ReplicationController, updateObservedGeneration bool, additional ...appsv1.DeploymentCondition) error {
 	newStatus := calculateStatus(config, deployments, updateObservedGeneration, additional...)
+	metrics.RecordReplicas(config, &newStatus)
 
 	// NOTE: We should update the status of the deployment config only if we need to, otherwise
 	// we hotloop between updates.
@@ -475,12 +517,25 @@ func updateConditions(config *appsv1.DeploymentConfig, newStatus *appsv1.Deploym
 	// Condition about progress.
 	if latestRC != nil {
 		switch appsutil.DeploymentStatusFor(latestRC) {
+		case appsv1.DeploymentStatusNew:
+			// The deployer controller records why it can not create the deployer pod.
+			if reason := latestRC.Annotations[deployutil.DeployerPodPendingReasonAnnotation]; len(reason) > 0 {
+				msg := fmt.Sprintf("replication controller %q is waiting for pod %q to be created: %s", latestRC.Name,
+					appsutil.DeployerPodNameForDeployment(latestRC.Name), latestRC.Annotations[deployutil.DeployerPodPendingMessageAnnotation])
+				condition := newDeploymentCondition(appsv1.DeploymentProgressing, v1.ConditionUnknown, reason, msg)
+				appsutil.SetDeploymentCondition(newStatus, *condition)
+			}
 		case appsv1.DeploymentStatusPending:
 			msg := fmt.Sprintf("replication controller %q is waiting for pod %q to run", latestRC.Name, appsutil.DeployerPodNameForDeployment(latestRC.Name))
-			condition := newDeploymentCondition(appsv1.DeploymentProgressing, v1.ConditionUnknown, "", msg)
+			reason := latestRC.Annotations[deployutil.DeployerPodPendingReasonAnnotation]
+			if len(reason) > 0 {
+				msg = fmt.Sprintf("%s: %s", msg, latestRC.Annotations[deployutil.DeployerPodPendingMessageAnnotation])
+			}
+			condition := newDeploymentCondition(appsv1.DeploymentProgressing, v1.ConditionUnknown, reason, msg)
 			appsutil.SetDeploymentCondition(newStatus, *condition)
 		case appsv1.DeploymentStatusRunning:
-			if isProgressing(config, newStatus) {
+			// Forget why the deployer pod was pending once it runs.
+			if isProgressing(config, newStatus) || isDeployerPendingCondition(appsutil.GetDeploymentCondition(*newStatus, appsv1.DeploymentProgressing)) {
 				appsutil.RemoveDeploymentCondition(newStatus, appsv1.DeploymentProgressing)
 				msg := fmt.Sprintf("replication controller %q is progressing", latestRC.Name)
 				condition := newDeploymentCondition(appsv1.DeploymentProgressing, v1.ConditionTrue,
@@ -497,6 +552,12 @@ func updateConditions(config *appsv1.DeploymentConfig, newStatus *appsv1.Deploym
 					appsutil.CancelledRolloutReason, msg)
 			} else {
 				msg := fmt.Sprintf("replication controller %q has failed progressing", latestRC.Name)
+				switch latestRC.Annotations[appsv1.DeploymentStatusReasonAnnotation] {
+				case deployutil.DeploymentFailedProgressDeadlineExceeded:
+					msg = fmt.Sprintf("replication controller %q did not become available within its progress deadline", latestRC.Name)
+				case deployutil.DeploymentFailedDeployerServiceAccountNotFound:
+					msg = fmt.Sprintf("replication controller %q failed because its deployer service account does not exist", latestRC.Name)
+				}
 				condition = newDeploymentCondition(appsv1.DeploymentProgressing, v1.ConditionFalse, appsutil.TimedOutReason, msg)
 			}
 			appsutil.SetDeploymentCondition(newStatus, *condition)
@@ -669,24 +730,45 @@ func triggerActivated(config *appsv1.DeploymentConfig, latestExists bool, latest
 	return false, false, nil
 }
 
-// hasUpdatedImages indicates if the deployment configuration images were updated.
+// hasUpdatedImages indicates if the images of the containers named by the image change triggers
+// of the deployment config differ from the images of the same containers of the replication
+// controller, and returns the updated images. Images are compared per container name, so an image
+// moved to another container is an update, and containers no trigger names are not compared.
 func hasUpdatedImages(dc *appsv1.DeploymentConfig, rc *v1.ReplicationController) (bool, []string) {
-	updatedImages := []string{}
-	rcImages := sets.NewString()
-	for _, c := range rc.Spec.Template.Spec.Containers {
-		rcImages.Insert(c.Image)
-	}
-	for _, c := range dc.Spec.Template.Spec.Containers {
-		if !rcImages.Has(c.Image) {
-			updatedImages = append(updatedImages, c.Image)
+	triggered := sets.NewString()
+	for _, t := range dc.Spec.Triggers {
+		if t.ImageChangeParams != nil {
+			triggered.Insert(t.ImageChangeParams.ContainerNames...)
 		}
 	}
+	updatedImages := []string{}
+	updatedImages = append(updatedImages, updatedContainerImages(triggered, dc.Spec.Template.Spec.InitContainers, rc.Spec.Template.Spec.InitContainers)...)
+	updatedImages = append(updatedImages, updatedContainerImages(triggered, dc.Spec.Template.Spec.Containers, rc.Spec.Template.Spec.Containers)...)
 	if len(updatedImages) == 0 {
 		return false, nil
 	}
 	return true, updatedImages
 }
 
+// updatedContainerImages returns the images of the named containers that differ from the image of
+// the container of the same name in the deployed containers, or that were not deployed.
+func updatedContainerImages(names sets.String, containers, deployed []v1.Container) []string {
+	deployedImages := map[string]string{}
+	for _, c := range deployed {
+		deployedImages[c.Name] = c.Image
+	}
+	var updated []string
+	for _, c := range containers {
+		if !names.Has(c.Name) {
+			continue
+		}
+		if image, ok := deployedImages[c.Name]; !ok || image != c.Image {
+			updated = append(updated, c.Image)
+		}
+	}
+	return updated
+}
+
 // hasLatestPodTemplate checks for differences between current deployment config
 // template and deployment config template encoded in the latest replication
 // controller. If they are different it will return an string diff containing
@@ -709,6 +791,19 @@ func hasLatestPodTemplate(currentConfig *appsv1.DeploymentConfig, rc *v1.Replica
 	return false, diff.ObjectReflectDiff(currentConfig.Spec.Template, latestConfig.Spec.Template), nil
 }
 
+// isDeployerPendingCondition returns true for progressing conditions explaining why the deployer
+// pod of the latest deployment does not run.
+func isDeployerPendingCondition(condition *appsv1.DeploymentCondition) bool {
+	if condition == nil {
+		return false
+	}
+	switch condition.Reason {
+	case deployutil.DeployerPodUnschedulableReason, deployutil.DeployerImagePullBackOffReason, deployutil.DeployerQuotaExceededReason:
+		return true
+	}
+	return false
+}
+
 // isProgressing expects a state deployment config and its updated status in order to
 // determine if there is any progress.
 func isProgressing(config *appsv1.DeploymentConfig, newStatus *appsv1.DeploymentConfigStatus) bool {
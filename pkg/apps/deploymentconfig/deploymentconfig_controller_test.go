@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,6 +19,8 @@ import (
 	"k8s.io/client-go/kubernetes/fake"
 	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
 	"k8s.io/kubernetes/pkg/api/legacyscheme"
 	kapihelper "k8s.io/kubernetes/pkg/apis/core/helper"
 
@@ -26,6 +29,9 @@ import (
 	appslisters "github.com/openshift/client-go/apps/listers/apps/v1"
 	"github.com/openshift/library-go/pkg/apps/appsutil"
 	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+	metrics "github.com/openshift/openshift-controller-manager/pkg/apps/metrics/prometheus"
+	"github.com/openshift/openshift-controller-manager/pkg/image/trigger/deploymentconfigs"
 )
 
 func init() {
@@ -386,7 +392,7 @@ func TestHandleScenarios(t *testing.T) {
 
 		kubeInformerFactory := kinformers.NewSharedInformerFactory(kc, 0)
 		rcInformer := kubeInformerFactory.Core().V1().ReplicationControllers()
-		c := NewDeploymentConfigController(dcInformer, rcInformer, oc, kc)
+		c := NewDeploymentConfigController(dcInformer, rcInformer, oc, kc, 0)
 		c.dcStoreSynced = alwaysReady
 		c.rcListerSynced = alwaysReady
 
@@ -587,3 +593,393 @@ func TestCalculateStatus(t *testing.T) {
 		}
 	}
 }
+
+func TestCalculateStatusFailedReason(t *testing.T) {
+	tests := []struct {
+		reason   string
+		expected string
+	}{
+		{reason: deployutil.DeploymentFailedProgressDeadlineExceeded, expected: "progress deadline"},
+		{reason: deployutil.DeploymentFailedDeployerServiceAccountNotFound, expected: "deployer service account does not exist"},
+	}
+	for _, test := range tests {
+		dc := newDC(2, 2, 0, unavailableCond)
+		latest := newRC(2, 0, 0, 0, 0)
+		latest.Name = "config-2"
+		latest.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusFailed)
+		latest.Annotations[appsv1.DeploymentStatusReasonAnnotation] = test.reason
+		rcs := []*corev1.ReplicationController{latest, newRC(1, 2, 2, 2, 2)}
+
+		status := calculateStatus(dc, rcs, false)
+		condition := appsutil.GetDeploymentCondition(status, appsv1.DeploymentProgressing)
+		if condition == nil {
+			t.Fatalf("%s: expected the progressing condition to be set, got %+v", test.reason, status.Conditions)
+		}
+		if condition.Status != corev1.ConditionFalse || condition.Reason != appsutil.TimedOutReason {
+			t.Errorf("%s: expected the progressing condition to be false with reason %s, got %+v", test.reason, appsutil.TimedOutReason, condition)
+		}
+		if !strings.Contains(condition.Message, test.expected) {
+			t.Errorf("%s: expected the message to mention %q, got %q", test.reason, test.expected, condition.Message)
+		}
+	}
+}
+
+func TestCalculateStatusDeployerPending(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      appsv1.DeploymentStatus
+		reason      string
+		message     string
+		progressing *appsv1.DeploymentCondition
+
+		expectedStatus  corev1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "unschedulable deployer pod",
+			status:          appsv1.DeploymentStatusPending,
+			reason:          deployutil.DeployerPodUnschedulableReason,
+			message:         "0/3 nodes are available: 3 Insufficient cpu.",
+			expectedStatus:  corev1.ConditionUnknown,
+			expectedReason:  deployutil.DeployerPodUnschedulableReason,
+			expectedMessage: `replication controller "config-2" is waiting for pod "config-2-deploy" to run: 0/3 nodes are available: 3 Insufficient cpu.`,
+		},
+		{
+			name:            "deployer image pull back off",
+			status:          appsv1.DeploymentStatusPending,
+			reason:          deployutil.DeployerImagePullBackOffReason,
+			message:         "manifest unknown",
+			expectedStatus:  corev1.ConditionUnknown,
+			expectedReason:  deployutil.DeployerImagePullBackOffReason,
+			expectedMessage: `replication controller "config-2" is waiting for pod "config-2-deploy" to run: manifest unknown`,
+		},
+		{
+			name:            "deployer quota exceeded",
+			status:          appsv1.DeploymentStatusNew,
+			reason:          deployutil.DeployerQuotaExceededReason,
+			message:         "exceeded quota: compute",
+			expectedStatus:  corev1.ConditionUnknown,
+			expectedReason:  deployutil.DeployerQuotaExceededReason,
+			expectedMessage: `replication controller "config-2" is waiting for pod "config-2-deploy" to be created: exceeded quota: compute`,
+		},
+		{
+			name:            "pending deployer pod",
+			status:          appsv1.DeploymentStatusPending,
+			progressing:     &appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionUnknown, Reason: deployutil.DeployerPodUnschedulableReason},
+			expectedStatus:  corev1.ConditionUnknown,
+			expectedMessage: `replication controller "config-2" is waiting for pod "config-2-deploy" to run`,
+		},
+		{
+			name:            "running deployer pod",
+			status:          appsv1.DeploymentStatusRunning,
+			progressing:     &appsv1.DeploymentCondition{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionUnknown, Reason: deployutil.DeployerImagePullBackOffReason},
+			expectedStatus:  corev1.ConditionTrue,
+			expectedReason:  string(appsv1.ReplicationControllerUpdatedReason),
+			expectedMessage: `replication controller "config-2" is progressing`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dc := newDC(2, 2, 0, unavailableCond)
+			if test.progressing != nil {
+				dc.Status.Conditions = append(dc.Status.Conditions, *test.progressing)
+			}
+			latest := newRC(2, 2, 0, 0, 0)
+			latest.Name = "config-2"
+			latest.Annotations[appsv1.DeploymentStatusAnnotation] = string(test.status)
+			if len(test.reason) > 0 {
+				latest.Annotations[deployutil.DeployerPodPendingReasonAnnotation] = test.reason
+				latest.Annotations[deployutil.DeployerPodPendingMessageAnnotation] = test.message
+			}
+			rcs := []*corev1.ReplicationController{latest, newRC(1, 0, 1, 1, 1)}
+
+			status := calculateStatus(dc, rcs, false)
+			condition := appsutil.GetDeploymentCondition(status, appsv1.DeploymentProgressing)
+			if condition == nil {
+				t.Fatalf("expected the progressing condition to be set, got %+v", status.Conditions)
+			}
+			if condition.Status != test.expectedStatus || condition.Reason != test.expectedReason || condition.Message != test.expectedMessage {
+				t.Errorf("expected the progressing condition %s %q %q, got %+v", test.expectedStatus, test.expectedReason, test.expectedMessage, condition)
+			}
+		})
+	}
+}
+
+func TestRolloutMetrics(t *testing.T) {
+	metrics.Register()
+	metrics.RolloutDuration.Reset()
+	metrics.FailedRollouts.Reset()
+	metrics.CancelledRollouts.Reset()
+	metrics.ProgressingDeploymentConfigs.Reset()
+
+	transition := func(c *DeploymentConfigController, old *corev1.ReplicationController, status appsv1.DeploymentStatus, annotations map[string]string) *corev1.ReplicationController {
+		cur := old.DeepCopy()
+		cur.ResourceVersion = old.ResourceVersion + "1"
+		cur.Annotations[appsv1.DeploymentStatusAnnotation] = string(status)
+		for k, v := range annotations {
+			cur.Annotations[k] = v
+		}
+		c.updateReplicationController(old, cur)
+		return cur
+	}
+
+	running := rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusRunning)
+	f := newControllerFixture(running)
+	config := rollbackConfig(1, "registry/app:v1")
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := testutil.GetGaugeMetricValue(metrics.ProgressingDeploymentConfigs.WithLabelValues("test", "config")); err != nil || value != 1 {
+		t.Errorf("expected the deployment config to be progressing, got %v (%v)", value, err)
+	}
+
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	complete := transition(f.controller, running, appsv1.DeploymentStatusComplete, map[string]string{
+		appsv1.DeployerPodStartedAtAnnotation:   metav1.NewTime(started).String(),
+		appsv1.DeployerPodCompletedAtAnnotation: metav1.NewTime(started.Add(90 * time.Second)).String(),
+	})
+	observer := metrics.RolloutDuration.WithLabelValues("test", "config")
+	if count, err := testutil.GetHistogramMetricCount(observer); err != nil || count != 1 {
+		t.Errorf("expected one complete rollout, got %d (%v)", count, err)
+	}
+	if sum, err := testutil.GetHistogramMetricValue(observer); err != nil || sum != 90 {
+		t.Errorf("expected a rollout of 90 seconds, got %v (%v)", sum, err)
+	}
+	// observing the same phase again does not record the rollout again
+	transition(f.controller, complete, appsv1.DeploymentStatusComplete, nil)
+	if count, _ := testutil.GetHistogramMetricCount(observer); count != 1 {
+		t.Errorf("expected one complete rollout, got %d", count)
+	}
+
+	f = newControllerFixture(complete)
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, _ := testutil.GetGaugeMetricValue(metrics.ProgressingDeploymentConfigs.WithLabelValues("test", "config")); value != 0 {
+		t.Errorf("expected the deployment config not to be progressing, got %v", value)
+	}
+
+	failing := rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusRunning)
+	transition(f.controller, failing, appsv1.DeploymentStatusFailed, map[string]string{
+		appsv1.DeploymentStatusReasonAnnotation: deployutil.DeploymentFailedProgressDeadlineExceeded,
+	})
+	if value, err := testutil.GetCounterMetricValue(metrics.FailedRollouts.WithLabelValues("test", "config", deployutil.DeploymentFailedProgressDeadlineExceeded)); err != nil || value != 1 {
+		t.Errorf("expected one failed rollout, got %v (%v)", value, err)
+	}
+
+	cancelled := rollbackDeployment(3, "registry/app:v3", appsv1.DeploymentStatusRunning)
+	appsutil.SetCancelledByNewerDeployment(cancelled)
+	transition(f.controller, cancelled, appsv1.DeploymentStatusFailed, nil)
+	if value, err := testutil.GetCounterMetricValue(metrics.CancelledRollouts.WithLabelValues("test", "config", cancelled.Annotations[appsv1.DeploymentStatusReasonAnnotation])); err != nil || value != 1 {
+		t.Errorf("expected one cancelled rollout, got %v (%v)", value, err)
+	}
+}
+
+// replicaMetricSeries returns the number of replica metric series of a deployment config.
+func replicaMetricSeries(t *testing.T, namespace, name string) int {
+	families, err := legacyregistry.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	series := 0
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), "openshift_deploymentconfig_") {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["namespace"] == namespace && labels["deploymentconfig"] == name {
+				series++
+			}
+		}
+	}
+	return series
+}
+
+func TestReplicaMetrics(t *testing.T) {
+	metrics.Register()
+	f := newControllerFixture(rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete))
+	config := rollbackConfig(1, "registry/app:v1")
+	config.Spec.Replicas = 3
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, err := testutil.GetGaugeMetricValue(metrics.DeploymentConfigSpecReplicas.WithLabelValues("test", "config")); err != nil || value != 3 {
+		t.Errorf("expected 3 desired replicas, got %v (%v)", value, err)
+	}
+	if e, a := 4, replicaMetricSeries(t, "test", "config"); e != a {
+		t.Fatalf("expected %d replica metric series, got %d", e, a)
+	}
+
+	f.controller.deleteDeploymentConfig(config)
+	if e, a := 0, replicaMetricSeries(t, "test", "config"); e != a {
+		t.Errorf("expected the replica metrics to be deleted with the deployment config, got %d series", a)
+	}
+
+	// a sync that was in progress when the deployment config was deleted records the metrics
+	// again, they are deleted when the deletion is processed
+	metrics.RecordReplicas(config, &config.Status)
+	if quit := f.controller.work(); quit {
+		t.Fatalf("unexpected shutdown of the queue")
+	}
+	if e, a := 0, replicaMetricSeries(t, "test", "config"); e != a {
+		t.Errorf("expected the replica metrics of the deleted deployment config to be deleted, got %d series", a)
+	}
+}
+
+// imageStreamTags resolves image stream tag names to image references.
+type imageStreamTags map[string]string
+
+func (t imageStreamTags) ImageStreamTag(namespace, name string) (string, int64, bool) {
+	ref, ok := t[name]
+	return ref, 1, ok
+}
+
+func TestHandleResumeWithPendingImageChange(t *testing.T) {
+	deployment := rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete)
+	f := newControllerFixture(deployment)
+
+	config := rollbackConfig(1, "registry/app:v1")
+	config.Annotations = nil
+	config.Spec.Paused = true
+	config.Spec.Triggers = []appsv1.DeploymentTriggerPolicy{{
+		Type: appsv1.DeploymentTriggerOnImageChange,
+		ImageChangeParams: &appsv1.DeploymentTriggerImageChangeParams{
+			Automatic:          true,
+			ContainerNames:     []string{config.Spec.Template.Spec.Containers[0].Name},
+			From:               corev1.ObjectReference{Kind: "ImageStreamTag", Name: "app:latest"},
+			LastTriggeredImage: "registry/app:v1",
+		},
+	}}
+
+	// the image stream tag is updated while the deployment config is paused
+	updated, resolvable, err := deploymentconfigs.UpdateDeploymentConfigImages(config, imageStreamTags{"app:latest": "registry/app:v2"})
+	if err != nil || !resolvable || updated == nil {
+		t.Fatalf("expected the image of the paused deployment config to be updated, got %v %v", resolvable, err)
+	}
+	config = updated
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created != nil || (f.updatedStatus != nil && f.updatedStatus.Status.LatestVersion != 1) {
+		t.Fatalf("expected no rollout of the paused deployment config")
+	}
+
+	// resuming the deployment config rolls out the new image once
+	config.Spec.Paused = false
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.updatedStatus == nil || f.updatedStatus.Status.LatestVersion != 2 {
+		t.Fatalf("expected the latest version to be incremented to 2, got %#v", f.updatedStatus)
+	}
+	if causes := f.updatedStatus.Status.Details.Causes; len(causes) != 1 || causes[0].Type != appsv1.DeploymentTriggerOnImageChange {
+		t.Errorf("expected an image change cause, got %#v", causes)
+	}
+	if err := f.controller.Handle(f.updatedStatus); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created == nil {
+		t.Fatalf("expected a deployment of version 2 to be created")
+	}
+	if e, a := "2", f.created.Annotations[appsv1.DeploymentVersionAnnotation]; e != a {
+		t.Errorf("expected deployment version %s, got %s", e, a)
+	}
+	if e, a := "registry/app:v2", f.created.Spec.Template.Spec.Containers[0].Image; e != a {
+		t.Errorf("expected the deployment to run image %s, got %s", e, a)
+	}
+}
+
+func TestHandleImageChangePerContainer(t *testing.T) {
+	imageTrigger := func(container, tag string) appsv1.DeploymentTriggerPolicy {
+		return appsv1.DeploymentTriggerPolicy{
+			Type: appsv1.DeploymentTriggerOnImageChange,
+			ImageChangeParams: &appsv1.DeploymentTriggerImageChangeParams{
+				Automatic:      true,
+				ContainerNames: []string{container},
+				From:           corev1.ObjectReference{Kind: "ImageStreamTag", Name: tag},
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		images imageStreamTags
+
+		expectedImages []string
+	}{
+		{
+			name:   "no image change",
+			images: imageStreamTags{"app-1:latest": "registry:8080/repo1:ref1", "app-2:latest": "registry:8080/repo1:ref2"},
+		},
+		{
+			name:           "image change of the first container",
+			images:         imageStreamTags{"app-1:latest": "registry:8080/repo1:ref3", "app-2:latest": "registry:8080/repo1:ref2"},
+			expectedImages: []string{"registry:8080/repo1:ref3", "registry:8080/repo1:ref2", "registry:8080/sidecar:2"},
+		},
+		{
+			// the new image of the second container is the image of the first container
+			name:           "image change of the second container to the image of the first",
+			images:         imageStreamTags{"app-1:latest": "registry:8080/repo1:ref1", "app-2:latest": "registry:8080/repo1:ref1"},
+			expectedImages: []string{"registry:8080/repo1:ref1", "registry:8080/repo1:ref1", "registry:8080/sidecar:2"},
+		},
+		{
+			name:           "simultaneous image changes of both containers",
+			images:         imageStreamTags{"app-1:latest": "registry:8080/repo1:ref3", "app-2:latest": "registry:8080/repo1:ref4"},
+			expectedImages: []string{"registry:8080/repo1:ref3", "registry:8080/repo1:ref4", "registry:8080/sidecar:2"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := appstest.OkDeploymentConfig(1)
+			config.Spec.Template.Spec.Containers = append(config.Spec.Template.Spec.Containers, corev1.Container{Name: "sidecar", Image: "registry:8080/sidecar:2"})
+			config.Spec.Triggers = []appsv1.DeploymentTriggerPolicy{imageTrigger("container1", "app-1:latest"), imageTrigger("container2", "app-2:latest")}
+			previous, _ := appsutil.MakeDeployment(config)
+			previous.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusComplete)
+			f := newControllerFixture(previous)
+
+			// the image of the container no trigger names is not an image change, and the image
+			// change triggers leave it as is
+			config.Spec.Template.Spec.Containers[2].Image = "registry:8080/sidecar:2"
+			if updated, resolvable, err := deploymentconfigs.UpdateDeploymentConfigImages(config, test.images); err != nil || !resolvable {
+				t.Fatalf("expected the image change triggers to resolve, got %v %v", resolvable, err)
+			} else if updated != nil {
+				config = updated
+			}
+			if err := f.controller.Handle(config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.expectedImages == nil {
+				if f.updatedStatus != nil && f.updatedStatus.Status.LatestVersion != 1 {
+					t.Fatalf("expected no rollout, got latest version %d", f.updatedStatus.Status.LatestVersion)
+				}
+				return
+			}
+			if f.updatedStatus == nil || f.updatedStatus.Status.LatestVersion != 2 {
+				t.Fatalf("expected the image change to roll out version 2, got %#v", f.updatedStatus)
+			}
+			if causes := f.updatedStatus.Status.Details.Causes; len(causes) == 0 || causes[0].Type != appsv1.DeploymentTriggerOnImageChange {
+				t.Errorf("expected an image change cause, got %#v", causes)
+			}
+			if err := f.controller.Handle(f.updatedStatus); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.created == nil {
+				t.Fatalf("expected the deployment of version 2 to be created")
+			}
+			var images []string
+			for _, c := range f.created.Spec.Template.Spec.Containers {
+				images = append(images, c.Image)
+			}
+			if !reflect.DeepEqual(test.expectedImages, images) {
+				t.Errorf("expected the deployment to run images %v, got %v", test.expectedImages, images)
+			}
+		})
+	}
+}
@@ -18,10 +18,13 @@ import (
 	"k8s.io/klog/v2"
 	"k8s.io/kubernetes/pkg/api/legacyscheme"
 	kcontroller "k8s.io/kubernetes/pkg/controller"
+	"k8s.io/utils/clock"
 
 	appsv1 "github.com/openshift/api/apps/v1"
 	appsv1client "github.com/openshift/client-go/apps/clientset/versioned"
 	appsv1informer "github.com/openshift/client-go/apps/informers/externalversions/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
 	metrics "github.com/openshift/openshift-controller-manager/pkg/apps/metrics/prometheus"
 )
 
@@ -31,6 +34,7 @@ func NewDeploymentConfigController(
 	rcInformer kcoreinformers.ReplicationControllerInformer,
 	appsClientset appsv1client.Interface,
 	kubeClientset kclientset.Interface,
+	configChangeDebounce time.Duration,
 ) *DeploymentConfigController {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(klog.Infof)
@@ -50,6 +54,10 @@ func NewDeploymentConfigController(
 			Recorder:   recorder,
 		},
 
+		configChangeDebounce: configChangeDebounce,
+		configChanges:        map[string]configChange{},
+		clock:                clock.RealClock{},
+
 		recorder: recorder,
 	}
 
@@ -124,11 +132,24 @@ func (c *DeploymentConfigController) deleteDeploymentConfig(obj interface{}) {
 		}
 	}
 	klog.V(4).Infof("Deleting deployment config %s/%s", dc.Namespace, dc.Name)
+	metrics.RecordProgressing(dc.Namespace, dc.Name, false)
+	metrics.ForgetReplicas(dc.Namespace, dc.Name)
+	metrics.ForgetRollouts(dc.Namespace, dc.Name)
+	c.forgetConfigChange(dc.Namespace + "/" + dc.Name)
 	c.enqueueDeploymentConfig(dc)
 }
 
+// getConfigForController returns the deployment config the replication controller is linked to
+// by its annotation, or else by its label or selector so that the deployment config can adopt
+// replication controllers that lost their annotations.
 func (c *DeploymentConfigController) getConfigForController(rc *v1.ReplicationController) (*appsv1.DeploymentConfig, error) {
 	dcName := rc.Annotations[appsv1.DeploymentConfigAnnotation]
+	if len(dcName) == 0 {
+		dcName = rc.Labels[appsv1.DeploymentConfigAnnotation]
+	}
+	if len(dcName) == 0 {
+		dcName = rc.Spec.Selector[appsutil.DeploymentConfigLabel]
+	}
 	obj, exists, err := c.dcIndex.GetByKey(rc.Namespace + "/" + dcName)
 	if err != nil {
 		return nil, err
@@ -150,11 +171,39 @@ func (c *DeploymentConfigController) updateReplicationController(old, cur interf
 		return
 	}
 
+	recordRolloutTransition(oldRC, curRC)
+
 	if dc, err := c.getConfigForController(curRC); err == nil && dc != nil {
 		c.enqueueDeploymentConfig(dc)
 	}
 }
 
+// recordRolloutTransition records the rollout metrics of a replication controller that transitioned
+// to a terminal phase.
+func recordRolloutTransition(old, cur *v1.ReplicationController) {
+	status := appsutil.DeploymentStatusFor(cur)
+	if status == appsutil.DeploymentStatusFor(old) {
+		return
+	}
+	configName := appsutil.DeploymentConfigNameFor(cur)
+	if len(configName) == 0 {
+		return
+	}
+	switch status {
+	case appsv1.DeploymentStatusComplete:
+		if duration, ok := deployutil.DeployerPodDuration(cur); ok {
+			metrics.RecordRolloutCompleted(cur.Namespace, configName, duration)
+		}
+	case appsv1.DeploymentStatusFailed:
+		reason := cur.Annotations[appsv1.DeploymentStatusReasonAnnotation]
+		if appsutil.IsDeploymentCancelled(cur) {
+			metrics.RecordRolloutCancelled(cur.Namespace, configName, reason)
+		} else {
+			metrics.RecordRolloutFailed(cur.Namespace, configName, reason)
+		}
+	}
+}
+
 // deleteReplicationController enqueues the deployment that manages a replicationcontroller when
 // the replicationcontroller is deleted. obj could be an *v1.ReplicationController, or
 // a DeletionFinalStateUnknown marker item.
@@ -212,6 +261,9 @@ func (c *DeploymentConfigController) work() bool {
 	}
 	dc, err := c.dcLister.DeploymentConfigs(namespace).Get(name)
 	if errors.IsNotFound(err) {
+		// A sync that was in progress when the deployment config was deleted may have recorded
+		// its replica metrics again.
+		metrics.ForgetReplicas(namespace, name)
 		return false
 	}
 	if err != nil {
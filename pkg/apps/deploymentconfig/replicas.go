@@ -0,0 +1,71 @@
+package deploymentconfig
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog/v2"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// desiredReplicas returns the replica count a deployment of the deployment config is reconciled
+// to once no rollout of the deployment config is active. The scale of the deployment config, which
+// users and horizontal pod autoscalers change, takes precedence over the replicas of its
+// deployments:
+//
+//  1. During a rollout, the deployer scales the deployments towards the desired replicas of the
+//     rollout, which syncRolloutReplicas keeps at the scale of the deployment config.
+//  2. Once rolled out, the deployments of test deployment configs are scaled to zero.
+//  3. Otherwise the active deployment is scaled to the scale of the deployment config and all
+//     other deployments to zero.
+func desiredReplicas(config *appsv1.DeploymentConfig, isActiveDeployment bool) int32 {
+	if config.Spec.Test || !isActiveDeployment {
+		return 0
+	}
+	return config.Spec.Replicas
+}
+
+// syncRolloutReplicas updates the desired replicas of the active rollout of the deployment config
+// to the scale of the deployment config, so that the rollout ends at the scale a horizontal pod
+// autoscaler or a user set during the rollout instead of the scale the rollout started with.
+func (c *DeploymentConfigController) syncRolloutReplicas(config *appsv1.DeploymentConfig, deployment *v1.ReplicationController, cm *RCControllerRefManager) error {
+	current, ok := deployutil.DesiredReplicas(deployment)
+	if !ok || current == config.Spec.Replicas || config.Spec.Test {
+		return nil
+	}
+	if err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		rc, err := c.rcLister.ReplicationControllers(deployment.Namespace).Get(deployment.Name)
+		if err != nil {
+			return err
+		}
+		if appsutil.IsTerminatedDeployment(rc) {
+			return nil
+		}
+		isOurs, err := cm.ClaimReplicationController(rc)
+		if err != nil {
+			return fmt.Errorf("error while deploymentConfigController claiming the replication controller %s/%s: %v", rc.Namespace, rc.Name, err)
+		}
+		if !isOurs {
+			return fmt.Errorf("deployment config %s/%s (%v) no longer owns replication controller %s/%s (%v)",
+				config.Namespace, config.Name, config.UID,
+				deployment.Namespace, deployment.Name, deployment.UID,
+			)
+		}
+		copied := rc.DeepCopy()
+		copied.Annotations[appsv1.DesiredReplicasAnnotation] = strconv.Itoa(int(config.Spec.Replicas))
+		_, err = c.kubeClient.ReplicationControllers(copied.Namespace).Update(context.TODO(), copied, metav1.UpdateOptions{})
+		return err
+	}); err != nil {
+		return fmt.Errorf("couldn't update the desired replicas of replication controller %s/%s: %v", deployment.Namespace, deployment.Name, err)
+	}
+	klog.V(4).Infof("Updated the desired replicas of rollout %q from %d to %d", appsutil.LabelForDeployment(deployment), current, config.Spec.Replicas)
+	c.recorder.Eventf(config, v1.EventTypeNormal, "RolloutReplicasUpdated", "Updated the desired replicas of the rollout of replication controller %q from %d to %d", deployment.Name, current, config.Spec.Replicas)
+	return nil
+}
@@ -0,0 +1,102 @@
+package deploymentconfig
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestHandleScaleDuringRollout(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy appsv1.DeploymentStrategy
+		status   appsv1.DeploymentStatus
+		// replicas is the scale of the deployment config set during the rollout
+		replicas int32
+
+		expectedDesired  int32
+		expectedReplicas int32
+	}{
+		{
+			name:            "scale up during a rolling rollout",
+			strategy:        appstest.OkRollingStrategy(),
+			status:          appsv1.DeploymentStatusRunning,
+			replicas:        5,
+			expectedDesired: 5,
+		},
+		{
+			name:            "scale down during a rolling rollout",
+			strategy:        appstest.OkRollingStrategy(),
+			status:          appsv1.DeploymentStatusRunning,
+			replicas:        1,
+			expectedDesired: 1,
+		},
+		{
+			name:            "scale up during a recreate rollout",
+			strategy:        appstest.OkStrategy(),
+			status:          appsv1.DeploymentStatusPending,
+			replicas:        5,
+			expectedDesired: 5,
+		},
+		{
+			name:            "scale down during a recreate rollout",
+			strategy:        appstest.OkStrategy(),
+			status:          appsv1.DeploymentStatusRunning,
+			replicas:        1,
+			expectedDesired: 1,
+		},
+		{
+			name:             "scale after the rollout",
+			strategy:         appstest.OkRollingStrategy(),
+			status:           appsv1.DeploymentStatusComplete,
+			replicas:         5,
+			expectedReplicas: 5,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			active := rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete)
+			latest := rollbackDeployment(2, "registry/app:v2", test.status)
+			latest.Annotations[appsv1.DesiredReplicasAnnotation] = "3"
+			replicas := int32(2)
+			latest.Spec.Replicas = &replicas
+			f := newControllerFixture(active, latest)
+
+			config := rollbackConfig(2, "registry/app:v2")
+			config.Spec.Strategy = test.strategy
+			config.Spec.Replicas = test.replicas
+			if err := f.controller.Handle(config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var updated *corev1.ReplicationController
+			for _, rc := range f.updated {
+				if rc.Name == latest.Name {
+					updated = rc
+				}
+			}
+			if updated == nil {
+				t.Fatalf("expected replication controller %s to be updated", latest.Name)
+			}
+			if test.expectedDesired != 0 {
+				if desired, _ := deployutil.DesiredReplicas(updated); desired != test.expectedDesired {
+					t.Errorf("expected the rollout to scale to %d replicas, got %d", test.expectedDesired, desired)
+				}
+				if *updated.Spec.Replicas != replicas {
+					t.Errorf("expected the replicas of the active rollout to be left to the deployer, got %d", *updated.Spec.Replicas)
+				}
+			}
+			if test.expectedReplicas != 0 && *updated.Spec.Replicas != test.expectedReplicas {
+				t.Errorf("expected %d replicas, got %d", test.expectedReplicas, *updated.Spec.Replicas)
+			}
+			if f.updatedConfig != nil {
+				t.Errorf("expected the scale of the deployment config to be kept, got %d", f.updatedConfig.Spec.Replicas)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package deploymentconfig
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsserialization"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// rollbackFailedDeployment rolls a deployment config that rolls back on failure back to its last
+// complete deployment when the rollout of its latest deployment failed. Every failed version is
+// handled once, and failed automatic rollbacks are not rolled back again, so that a deployment
+// config can not loop between versions that fail. The rollback updates the spec of the deployment
+// config before its status, see finishRollback. It returns true if the deployment config was
+// updated.
+func (c *DeploymentConfigController) rollbackFailedDeployment(config *appsv1.DeploymentConfig, latest *v1.ReplicationController, existingDeployments []*v1.ReplicationController) (bool, error) {
+	if !deployutil.RollbackOnFailure(config) || !appsutil.IsFailedDeployment(latest) || appsutil.IsDeploymentCancelled(latest) {
+		return false, nil
+	}
+	failedVersion := appsutil.DeploymentVersionFor(latest)
+	if handled, ok := deployutil.LastFailedVersion(config); ok && handled >= failedVersion {
+		return false, nil
+	}
+
+	var reason string
+	active := appsutil.ActiveDeployment(existingDeployments)
+	if _, ok := deployutil.RollbackOfVersion(latest); ok {
+		reason = "the failed version is an automatic rollback"
+	} else if active == nil {
+		reason = "there is no previous complete version"
+	}
+	if len(reason) > 0 {
+		klog.V(2).Infof("Not rolling back failed version %d of %s: %s", failedVersion, appsutil.LabelForDeploymentConfig(config), reason)
+		configCopy := config.DeepCopy()
+		configCopy.Annotations[deployutil.LastFailedVersionAnnotation] = strconv.FormatInt(failedVersion, 10)
+		if _, err := c.appsClient.DeploymentConfigs(config.Namespace).Update(context.TODO(), configCopy, metav1.UpdateOptions{}); err != nil {
+			return false, err
+		}
+		c.recorder.Eventf(config, v1.EventTypeWarning, deployutil.RollbackSkippedEventReason, deployutil.RollbackSkippedEventMessage, failedVersion, reason)
+		return true, nil
+	}
+
+	to, err := appsserialization.DecodeDeploymentConfig(active)
+	if err != nil {
+		return false, fatalError(fmt.Sprintf("couldn't decode the deployment config of replication controller %s/%s: %v", active.Namespace, active.Name, err))
+	}
+	rollback := deployutil.GenerateRollback(config, to)
+	rollback.Annotations[deployutil.LastFailedVersionAnnotation] = strconv.FormatInt(failedVersion, 10)
+	rollback.Annotations[deployutil.RollbackOfVersionAnnotation] = strconv.FormatInt(failedVersion, 10)
+	updated, err := c.appsClient.DeploymentConfigs(config.Namespace).Update(context.TODO(), rollback, metav1.UpdateOptions{})
+	if err != nil {
+		return false, err
+	}
+	return true, c.startRollback(updated, failedVersion, appsutil.DeploymentVersionFor(active))
+}
+
+// finishRollback bumps the latest version of a deployment config whose spec was rolled back from
+// its failed latest deployment when the status update of the rollback failed. It runs before the
+// triggers of the deployment config are processed, so that the rollout is recorded as the
+// rollback. It returns true if the deployment config was updated.
+func (c *DeploymentConfigController) finishRollback(config *appsv1.DeploymentConfig, latest *v1.ReplicationController, existingDeployments []*v1.ReplicationController) (bool, error) {
+	if !deployutil.RollbackOnFailure(config) || !appsutil.IsFailedDeployment(latest) {
+		return false, nil
+	}
+	failedVersion := appsutil.DeploymentVersionFor(latest)
+	handled, ok := deployutil.LastFailedVersion(config)
+	if !ok || handled != failedVersion || config.Status.LatestVersion != failedVersion {
+		return false, nil
+	}
+	if rolledBack, ok := deployutil.RollbackOfVersion(config); !ok || rolledBack != failedVersion {
+		return false, nil
+	}
+	active := appsutil.ActiveDeployment(existingDeployments)
+	if active == nil {
+		return false, nil
+	}
+	klog.V(2).Infof("Finishing the rollback of failed version %d of %s", failedVersion, appsutil.LabelForDeploymentConfig(config))
+	return true, c.startRollback(config, failedVersion, appsutil.DeploymentVersionFor(active))
+}
+
+// startRollback bumps the latest version of a deployment config whose spec was rolled back from
+// the failed version to the given version, which starts the rollout of the rollback.
+func (c *DeploymentConfigController) startRollback(config *appsv1.DeploymentConfig, failedVersion, toVersion int64) error {
+	configCopy := config.DeepCopy()
+	configCopy.Status.LatestVersion = failedVersion + 1
+	configCopy.Status.Details = &appsv1.DeploymentDetails{
+		Message: fmt.Sprintf("automatic rollback of failed version %d to version %d", failedVersion, toVersion),
+		Causes:  []appsv1.DeploymentCause{{Type: appsv1.DeploymentTriggerOnConfigChange}},
+	}
+	if _, err := c.appsClient.DeploymentConfigs(config.Namespace).UpdateStatus(context.TODO(), configCopy, metav1.UpdateOptions{}); err != nil {
+		return err
+	}
+	c.recorder.Eventf(config, v1.EventTypeNormal, deployutil.RollbackCreatedEventReason, deployutil.RollbackCreatedEventMessage,
+		failedVersion, toVersion, configCopy.Status.LatestVersion)
+	return nil
+}
+
+// setRollbackOfVersion annotates the deployment of an automatic rollback with the failed version
+// it replaces.
+func setRollbackOfVersion(config *appsv1.DeploymentConfig, deployment *v1.ReplicationController) {
+	if failedVersion, ok := deployutil.RollbackOfVersion(config); ok && failedVersion+1 == config.Status.LatestVersion {
+		deployment.Annotations[deployutil.RollbackOfVersionAnnotation] = strconv.FormatInt(failedVersion, 10)
+	}
+}
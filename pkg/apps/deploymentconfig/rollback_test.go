@@ -0,0 +1,310 @@
+package deploymentconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	kinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	appsfake "github.com/openshift/client-go/apps/clientset/versioned/fake"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// controllerFixture records the updates of the deployment config and the created and updated
+// deployments of a deployment config controller.
+type controllerFixture struct {
+	controller *DeploymentConfigController
+	recorder   *record.FakeRecorder
+	appsClient *appsfake.Clientset
+
+	updatedConfig *appsv1.DeploymentConfig
+	updatedStatus *appsv1.DeploymentConfig
+	created       *corev1.ReplicationController
+	updated       []*corev1.ReplicationController
+}
+
+func newControllerFixture(deployments ...*corev1.ReplicationController) *controllerFixture {
+	f := &controllerFixture{recorder: record.NewFakeRecorder(10)}
+
+	oc := &appsfake.Clientset{}
+	oc.AddReactor("update", "deploymentconfigs", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		dc := action.(clientgotesting.UpdateAction).GetObject().(*appsv1.DeploymentConfig)
+		if action.GetSubresource() == "status" {
+			f.updatedStatus = dc
+		} else {
+			f.updatedConfig = dc
+		}
+		return true, dc, nil
+	})
+	f.appsClient = oc
+	kc := &fake.Clientset{}
+	kc.AddReactor("create", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		f.created = action.(clientgotesting.CreateAction).GetObject().(*corev1.ReplicationController)
+		return true, f.created, nil
+	})
+	kc.AddReactor("update", "replicationcontrollers", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		rc := action.(clientgotesting.UpdateAction).GetObject().(*corev1.ReplicationController)
+		f.updated = append(f.updated, rc)
+		return true, rc, nil
+	})
+
+	dcInformer := &fakeDeploymentConfigInformer{
+		informer: cache.NewSharedIndexInformer(
+			&cache.ListWatch{
+				ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+					return oc.AppsV1().DeploymentConfigs(metav1.NamespaceAll).List(context.TODO(), options)
+				},
+				WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+					return oc.AppsV1().DeploymentConfigs(metav1.NamespaceAll).Watch(context.TODO(), options)
+				},
+			},
+			&appsv1.DeploymentConfig{},
+			2*time.Minute,
+			cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		),
+	}
+	rcInformer := kinformers.NewSharedInformerFactory(kc, 0).Core().V1().ReplicationControllers()
+	f.controller = NewDeploymentConfigController(dcInformer, rcInformer, oc, kc, 0)
+	f.controller.dcStoreSynced = alwaysReady
+	f.controller.rcListerSynced = alwaysReady
+	f.controller.recorder = f.recorder
+	for _, deployment := range deployments {
+		rcInformer.Informer().GetStore().Add(deployment)
+	}
+	return f
+}
+
+// rollbackConfig returns a deployment config that rolls back on failure with the given latest
+// version and image.
+func rollbackConfig(version int64, image string) *appsv1.DeploymentConfig {
+	config := appstest.OkDeploymentConfig(version)
+	config.Namespace = "test"
+	config.Annotations = map[string]string{deployutil.RollbackOnFailureAnnotation: "true"}
+	config.Spec.Template.Spec.Containers[0].Image = image
+	return config
+}
+
+func rollbackDeployment(version int64, image string, status appsv1.DeploymentStatus) *corev1.ReplicationController {
+	deployment, _ := appsutil.MakeDeployment(rollbackConfig(version, image))
+	deployment.Annotations[appsv1.DeploymentStatusAnnotation] = string(status)
+	return deployment
+}
+
+func TestHandleRollbackOnFailure(t *testing.T) {
+	f := newControllerFixture(
+		rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+		rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusFailed),
+	)
+	if err := f.controller.Handle(rollbackConfig(2, "registry/app:v2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if f.updatedConfig == nil || f.updatedStatus == nil {
+		t.Fatalf("expected the deployment config to be rolled back")
+	}
+	if e, a := "registry/app:v1", f.updatedConfig.Spec.Template.Spec.Containers[0].Image; e != a {
+		t.Errorf("expected the template of version 1 with image %s, got %s", e, a)
+	}
+	for _, trigger := range f.updatedConfig.Spec.Triggers {
+		if trigger.ImageChangeParams != nil && trigger.ImageChangeParams.Automatic {
+			t.Errorf("expected the automatic image change triggers to be disabled, got %#v", trigger)
+		}
+	}
+	if e, a := "2", f.updatedConfig.Annotations[deployutil.LastFailedVersionAnnotation]; e != a {
+		t.Errorf("expected the last failed version %s, got %s", e, a)
+	}
+	if e, a := int64(3), f.updatedStatus.Status.LatestVersion; e != a {
+		t.Errorf("expected latest version %d, got %d", e, a)
+	}
+	select {
+	case event := <-f.recorder.Events:
+		if !strings.Contains(event, deployutil.RollbackCreatedEventReason) || !strings.Contains(event, "version 1") {
+			t.Errorf("expected an event describing the rollback, got %q", event)
+		}
+	default:
+		t.Errorf("expected an event describing the rollback")
+	}
+
+	// the rollback deployment is annotated with the version it replaces
+	rolledBack := f.updatedStatus.DeepCopy()
+	rolledBack.Spec = f.updatedConfig.Spec
+	if err := f.controller.Handle(rolledBack); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created == nil {
+		t.Fatalf("expected the rollback deployment to be created")
+	}
+	if e, a := "2", f.created.Annotations[deployutil.RollbackOfVersionAnnotation]; e != a {
+		t.Errorf("expected the rollback deployment to replace version %s, got %q", e, a)
+	}
+	if e, a := int64(3), appsutil.DeploymentVersionFor(f.created); e != a {
+		t.Errorf("expected deployment version %d, got %d", e, a)
+	}
+}
+
+func TestHandleRollbackOnFailureStatusUpdateFailed(t *testing.T) {
+	f := newControllerFixture(
+		rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+		rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusFailed),
+	)
+	oc := f.appsClient
+	oc.PrependReactor("update", "deploymentconfigs", func(action clientgotesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetSubresource() == "status" {
+			return true, nil, fmt.Errorf("conflict")
+		}
+		return false, nil, nil
+	})
+	if err := f.controller.Handle(rollbackConfig(2, "registry/app:v2")); err == nil {
+		t.Fatalf("expected the failed status update to be returned")
+	}
+	if f.updatedConfig == nil {
+		t.Fatalf("expected the spec of the deployment config to be rolled back")
+	}
+
+	// the next sync bumps the latest version of the rolled back deployment config
+	oc.ReactionChain = oc.ReactionChain[1:]
+	halfRolledBack := f.updatedConfig.DeepCopy()
+	halfRolledBack.Status.LatestVersion = 2
+	if err := f.controller.Handle(halfRolledBack); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.updatedStatus == nil || f.updatedStatus.Status.LatestVersion != 3 {
+		t.Fatalf("expected the rollback to be finished with latest version 3, got %#v", f.updatedStatus)
+	}
+	if e, a := "registry/app:v1", f.updatedStatus.Spec.Template.Spec.Containers[0].Image; e != a {
+		t.Errorf("expected the template of version 1 with image %s, got %s", e, a)
+	}
+	if f.updatedStatus.Status.Details == nil || !strings.Contains(f.updatedStatus.Status.Details.Message, "automatic rollback of failed version 2 to version 1") {
+		t.Errorf("expected the details of the rollback, got %#v", f.updatedStatus.Status.Details)
+	}
+}
+
+func TestHandleRollbackOnFailureSkipped(t *testing.T) {
+	failedRollback := rollbackDeployment(3, "registry/app:v1", appsv1.DeploymentStatusFailed)
+	failedRollback.Annotations[deployutil.RollbackOfVersionAnnotation] = "2"
+
+	tests := []struct {
+		name        string
+		config      *appsv1.DeploymentConfig
+		deployments []*corev1.ReplicationController
+		// expectedEvent is the reason of the expected event, if the failed version is handled
+		expectedEvent string
+	}{
+		{
+			name:   "no previous version",
+			config: rollbackConfig(1, "registry/app:v1"),
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusFailed),
+			},
+			expectedEvent: deployutil.RollbackSkippedEventReason,
+		},
+		{
+			name:   "failed rollback",
+			config: rollbackConfig(3, "registry/app:v1"),
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+				rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusFailed),
+				failedRollback,
+			},
+			expectedEvent: deployutil.RollbackSkippedEventReason,
+		},
+		{
+			name: "failed version already rolled back",
+			config: func() *appsv1.DeploymentConfig {
+				config := rollbackConfig(2, "registry/app:v2")
+				config.Annotations[deployutil.LastFailedVersionAnnotation] = "2"
+				return config
+			}(),
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+				rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusFailed),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newControllerFixture(test.deployments...)
+			if err := f.controller.Handle(test.config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if f.created != nil {
+				t.Errorf("expected no new deployment, got %s", f.created.Name)
+			}
+			if f.updatedStatus != nil && f.updatedStatus.Status.LatestVersion != test.config.Status.LatestVersion {
+				t.Errorf("expected no rollback, got latest version %d", f.updatedStatus.Status.LatestVersion)
+			}
+
+			if len(test.expectedEvent) == 0 {
+				if f.updatedConfig != nil {
+					t.Errorf("expected the deployment config not to be updated, got %#v", f.updatedConfig.Annotations)
+				}
+				return
+			}
+			if f.updatedConfig == nil {
+				t.Fatalf("expected the failed version to be recorded")
+			}
+			if e, a := test.config.Spec.Template.Spec.Containers[0].Image, f.updatedConfig.Spec.Template.Spec.Containers[0].Image; e != a {
+				t.Errorf("expected the template to keep image %s, got %s", e, a)
+			}
+			if e, a := test.config.Status.LatestVersion, lastFailedVersion(f.updatedConfig); e != a {
+				t.Errorf("expected the last failed version %d, got %d", e, a)
+			}
+			select {
+			case event := <-f.recorder.Events:
+				if !strings.Contains(event, test.expectedEvent) {
+					t.Errorf("expected a %s event, got %q", test.expectedEvent, event)
+				}
+			default:
+				t.Errorf("expected a %s event", test.expectedEvent)
+			}
+		})
+	}
+}
+
+func lastFailedVersion(config *appsv1.DeploymentConfig) int64 {
+	version, _ := deployutil.LastFailedVersion(config)
+	return version
+}
+
+func TestHandleCancelledRollout(t *testing.T) {
+	active := rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete)
+	zero, one := int32(0), int32(1)
+	active.Spec.Replicas = &zero
+	cancelled := rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusFailed)
+	cancelled.Spec.Replicas = &one
+	cancelled.Annotations[appsv1.DeploymentCancelledAnnotation] = "true"
+	cancelled.Annotations[appsv1.DeploymentStatusReasonAnnotation] = deployutil.DeploymentFailedCancelled
+
+	f := newControllerFixture(active, cancelled)
+	if err := f.controller.Handle(rollbackConfig(2, "registry/app:v2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// cancelled rollouts are not rolled back, the previous deployment is scaled back up
+	if f.updatedConfig != nil {
+		t.Errorf("expected the cancelled rollout not to be rolled back")
+	}
+	replicas := map[string]int32{}
+	for _, rc := range f.updated {
+		replicas[rc.Name] = *rc.Spec.Replicas
+	}
+	if e, a := map[string]int32{"config-1": 1, "config-2": 0}, replicas; !reflect.DeepEqual(e, a) {
+		t.Errorf("expected the replicas %v, got %v", e, a)
+	}
+}
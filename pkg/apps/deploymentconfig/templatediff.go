@@ -0,0 +1,179 @@
+package deploymentconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsserialization"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+)
+
+const (
+	// maxTemplateDiffChanges is the number of template changes the template diff of a config
+	// change lists.
+	maxTemplateDiffChanges = 20
+	// maxTemplateDiffAnnotationBytes is the size of the template diff recorded on deployments.
+	maxTemplateDiffAnnotationBytes = 1024
+)
+
+// configChangeDiff returns the changes from the pod template the deployment was rolled out with
+// to the pod template of the deployment config, see templateDiff.
+func configChangeDiff(config *appsv1.DeploymentConfig, deployment *v1.ReplicationController) []string {
+	if deployment == nil {
+		return nil
+	}
+	previous, err := appsserialization.DecodeDeploymentConfig(deployment)
+	if err != nil || previous.Spec.Template == nil || config.Spec.Template == nil {
+		return nil
+	}
+	return templateDiff(previous.Spec.Template, config.Spec.Template)
+}
+
+// previousDeployment returns the deployment of the version preceding the latest version of the
+// deployment config, or nil if it does not exist.
+func previousDeployment(config *appsv1.DeploymentConfig, deployments []*v1.ReplicationController) *v1.ReplicationController {
+	for _, deployment := range deployments {
+		if appsutil.DeploymentVersionFor(deployment) == config.Status.LatestVersion-1 {
+			return deployment
+		}
+	}
+	return nil
+}
+
+// templateDiff returns the changes from the old to the new pod template as field paths. It lists
+// the changes of labels and of the images, environment variables and resources of containers.
+// The values of environment variables are never listed, as they may be secret. Any other change
+// is listed as a change of the pod spec. At most maxTemplateDiffChanges changes are listed.
+func templateDiff(old, new *v1.PodTemplateSpec) []string {
+	var changes []string
+	changes = append(changes, mapDiff("metadata.labels", old.Labels, new.Labels)...)
+	changes = append(changes, containersDiff("spec.initContainers", old.Spec.InitContainers, new.Spec.InitContainers)...)
+	changes = append(changes, containersDiff("spec.containers", old.Spec.Containers, new.Spec.Containers)...)
+	if len(changes) == 0 && !equality.Semantic.DeepEqual(old.Spec, new.Spec) {
+		changes = append(changes, "spec: changed")
+	}
+	if len(changes) > maxTemplateDiffChanges {
+		changes = append(changes[:maxTemplateDiffChanges], fmt.Sprintf("and %d more", len(changes)-maxTemplateDiffChanges))
+	}
+	return changes
+}
+
+func containersDiff(path string, old, new []v1.Container) []string {
+	var changes []string
+	oldByName := map[string]*v1.Container{}
+	for i := range old {
+		oldByName[old[i].Name] = &old[i]
+	}
+	newNames := map[string]bool{}
+	for i := range new {
+		container := &new[i]
+		newNames[container.Name] = true
+		containerPath := fmt.Sprintf("%s[%s]", path, container.Name)
+		previous, ok := oldByName[container.Name]
+		if !ok {
+			changes = append(changes, containerPath+": added")
+			continue
+		}
+		if previous.Image != container.Image {
+			changes = append(changes, fmt.Sprintf("%s.image: %s -> %s", containerPath, previous.Image, container.Image))
+		}
+		changes = append(changes, envDiff(containerPath+".env", previous.Env, container.Env)...)
+		changes = append(changes, resourcesDiff(containerPath+".resources.limits", previous.Resources.Limits, container.Resources.Limits)...)
+		changes = append(changes, resourcesDiff(containerPath+".resources.requests", previous.Resources.Requests, container.Resources.Requests)...)
+	}
+	for i := range old {
+		if !newNames[old[i].Name] {
+			changes = append(changes, fmt.Sprintf("%s[%s]: removed", path, old[i].Name))
+		}
+	}
+	return changes
+}
+
+// envDiff lists the environment variables that were added, removed or changed by name only.
+func envDiff(path string, old, new []v1.EnvVar) []string {
+	var changes []string
+	oldByName := map[string]*v1.EnvVar{}
+	for i := range old {
+		oldByName[old[i].Name] = &old[i]
+	}
+	newNames := map[string]bool{}
+	for i := range new {
+		newNames[new[i].Name] = true
+		previous, ok := oldByName[new[i].Name]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("%s[%s]: added", path, new[i].Name))
+		case !equality.Semantic.DeepEqual(previous, &new[i]):
+			changes = append(changes, fmt.Sprintf("%s[%s]: changed", path, new[i].Name))
+		}
+	}
+	for i := range old {
+		if !newNames[old[i].Name] {
+			changes = append(changes, fmt.Sprintf("%s[%s]: removed", path, old[i].Name))
+		}
+	}
+	return changes
+}
+
+func resourcesDiff(path string, old, new v1.ResourceList) []string {
+	oldValues := map[string]string{}
+	for name, quantity := range old {
+		oldValues[string(name)] = quantity.String()
+	}
+	newValues := map[string]string{}
+	for name, quantity := range new {
+		newValues[string(name)] = quantity.String()
+	}
+	return mapDiff(path, oldValues, newValues)
+}
+
+// mapDiff lists the keys of the maps that were added, removed or changed, in key order.
+func mapDiff(path string, old, new map[string]string) []string {
+	keys := map[string]bool{}
+	for key := range old {
+		keys[key] = true
+	}
+	for key := range new {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []string
+	for _, key := range sorted {
+		oldValue, inOld := old[key]
+		newValue, inNew := new[key]
+		switch {
+		case !inOld:
+			changes = append(changes, fmt.Sprintf("%s[%s]: added %s", path, key, newValue))
+		case !inNew:
+			changes = append(changes, fmt.Sprintf("%s[%s]: removed", path, key))
+		case oldValue != newValue:
+			changes = append(changes, fmt.Sprintf("%s[%s]: %s -> %s", path, key, oldValue, newValue))
+		}
+	}
+	return changes
+}
+
+// formatTemplateDiff joins the changes of a template diff. When the result is longer than limit
+// bytes, it is truncated at a character boundary and suffixed with an ellipsis.
+func formatTemplateDiff(changes []string, limit int) string {
+	diff := strings.Join(changes, "; ")
+	if len(diff) <= limit {
+		return diff
+	}
+	diff = diff[:limit]
+	for len(diff) > 0 && !utf8.ValidString(diff) {
+		diff = diff[:len(diff)-1]
+	}
+	return diff + "..."
+}
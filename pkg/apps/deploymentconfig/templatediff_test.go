@@ -0,0 +1,157 @@
+package deploymentconfig
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/appstest"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestTemplateDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(*corev1.PodTemplateSpec)
+		expected []string
+	}{
+		{
+			name:   "unchanged",
+			change: func(*corev1.PodTemplateSpec) {},
+		},
+		{
+			name: "image change",
+			change: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Image = "registry:8080/repo1:ref3"
+			},
+			expected: []string{"spec.containers[container1].image: registry:8080/repo1:ref1 -> registry:8080/repo1:ref3"},
+		},
+		{
+			name: "env addition",
+			change: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[1].Env = append(template.Spec.Containers[1].Env, corev1.EnvVar{Name: "PASSWORD", Value: "secret"})
+			},
+			expected: []string{"spec.containers[container2].env[PASSWORD]: added"},
+		},
+		{
+			name: "env changes",
+			change: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers[0].Env = []corev1.EnvVar{{Name: "ENV1", Value: "secret"}, {Name: "ENV2", ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "credentials"}, Key: "password"},
+				}}}
+				template.Spec.Containers[1].Env = []corev1.EnvVar{{Name: "ENV3", Value: "secret"}}
+			},
+			expected: []string{
+				"spec.containers[container1].env[ENV1]: changed",
+				"spec.containers[container1].env[ENV2]: added",
+				"spec.containers[container2].env[ENV3]: added",
+			},
+		},
+		{
+			name: "labels and resources",
+			change: func(template *corev1.PodTemplateSpec) {
+				template.Labels = map[string]string{"tier": "web"}
+				template.Spec.Containers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")}
+			},
+			expected: []string{
+				"metadata.labels[a]: removed",
+				"metadata.labels[tier]: added web",
+				"spec.containers[container1].resources.limits[memory]: added 512Mi",
+			},
+		},
+		{
+			name: "containers",
+			change: func(template *corev1.PodTemplateSpec) {
+				template.Spec.Containers = []corev1.Container{template.Spec.Containers[0], {Name: "sidecar", Image: "registry:8080/sidecar"}}
+			},
+			expected: []string{
+				"spec.containers[sidecar]: added",
+				"spec.containers[container2]: removed",
+			},
+		},
+		{
+			name: "other change",
+			change: func(template *corev1.PodTemplateSpec) {
+				template.Spec.DNSPolicy = corev1.DNSDefault
+			},
+			expected: []string{"spec: changed"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			old := appstest.OkPodTemplate()
+			old.Labels = map[string]string{"a": "b"}
+			new := old.DeepCopy()
+			test.change(new)
+
+			changes := templateDiff(old, new)
+			if !reflect.DeepEqual(changes, test.expected) {
+				t.Errorf("expected changes %q, got %q", test.expected, changes)
+			}
+			if strings.Contains(strings.Join(changes, ""), "secret") {
+				t.Errorf("expected no environment variable values, got %q", changes)
+			}
+		})
+	}
+}
+
+func TestTemplateDiffBounded(t *testing.T) {
+	old := appstest.OkPodTemplate()
+	new := old.DeepCopy()
+	for i := 0; i < 2*maxTemplateDiffChanges; i++ {
+		new.Spec.Containers[0].Env = append(new.Spec.Containers[0].Env, corev1.EnvVar{Name: fmt.Sprintf("VARIABLE_WITH_A_LONG_NAME_%d", i)})
+	}
+
+	changes := templateDiff(old, new)
+	if len(changes) != maxTemplateDiffChanges+1 || changes[maxTemplateDiffChanges] != fmt.Sprintf("and %d more", maxTemplateDiffChanges) {
+		t.Fatalf("expected %d changes and a summary of the others, got %q", maxTemplateDiffChanges, changes)
+	}
+	diff := formatTemplateDiff(changes, 100)
+	if len(diff) != 103 || !strings.HasSuffix(diff, "...") {
+		t.Errorf("expected the diff to be truncated to 100 bytes, got %q", diff)
+	}
+}
+
+func TestHandleConfigChangeTemplateDiff(t *testing.T) {
+	config := appstest.OkDeploymentConfig(1)
+	config.Spec.Triggers = []appsv1.DeploymentTriggerPolicy{appstest.OkConfigChangeTrigger()}
+	previous, _ := appsutil.MakeDeployment(config)
+	previous.Annotations[appsv1.DeploymentStatusAnnotation] = string(appsv1.DeploymentStatusComplete)
+
+	config.Spec.Template.Spec.Containers[0].Image = "registry:8080/repo1:ref3"
+	config.Spec.Template.Spec.Containers[1].Env = []corev1.EnvVar{{Name: "PASSWORD", Value: "secret"}}
+	expected := "spec.containers[container1].image: registry:8080/repo1:ref1 -> registry:8080/repo1:ref3; spec.containers[container2].env[PASSWORD]: added"
+
+	f := newControllerFixture(previous)
+	if err := f.controller.Handle(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.updatedStatus == nil || f.updatedStatus.Status.LatestVersion != 2 {
+		t.Fatalf("expected the config change to roll out version 2, got %#v", f.updatedStatus)
+	}
+	select {
+	case event := <-f.recorder.Events:
+		if !strings.Contains(event, deployutil.TemplateChangedEventReason) || !strings.Contains(event, expected) {
+			t.Errorf("expected an event listing the template changes %q, got %q", expected, event)
+		}
+	default:
+		t.Errorf("expected an event listing the template changes")
+	}
+
+	// the deployment of the config change records the changes
+	if err := f.controller.Handle(f.updatedStatus); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.created == nil {
+		t.Fatalf("expected the deployment of version 2 to be created")
+	}
+	if e, a := expected, f.created.Annotations[deployutil.TemplateDiffAnnotation]; e != a {
+		t.Errorf("expected the template diff %q, got %q", e, a)
+	}
+}
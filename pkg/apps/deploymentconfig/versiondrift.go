@@ -0,0 +1,77 @@
+package deploymentconfig
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+// reconcileLatestVersion corrects the latest version of the deployment config when it drifted
+// from the versions of its deployments, e.g. after an etcd restore or a manual edit of a
+// deployment. The highest version of the deployments is authoritative. The latest version is only
+// ahead of it by one while the deployment of the latest version is created. Status validation only
+// allows the latest version to grow, so a latest version further ahead is reported but never
+// lowered; the deployment cache may also lag behind a new rollout. Deployments sharing a version
+// are reported but never deleted. It returns true if the status of the deployment config was
+// updated.
+func (c *DeploymentConfigController) reconcileLatestVersion(config *appsv1.DeploymentConfig, deployments []*v1.ReplicationController) (bool, error) {
+	for version, names := range duplicateVersions(deployments) {
+		c.recorder.Eventf(config, v1.EventTypeWarning, deployutil.DuplicateDeploymentVersionEventReason, deployutil.DuplicateDeploymentVersionEventMessage,
+			strings.Join(names, ", "), version)
+	}
+
+	var highest int64
+	for _, deployment := range deployments {
+		if version := appsutil.DeploymentVersionFor(deployment); version > highest {
+			highest = version
+		}
+	}
+	latest := config.Status.LatestVersion
+	corrected := latest
+	switch {
+	case highest > latest:
+		// FIXME: update LatestVersion to highest directly when validation allows it in all supported skews
+		corrected = latest + 1
+	case highest > 0 && latest > highest+1:
+		c.recorder.Eventf(config, v1.EventTypeWarning, deployutil.LatestVersionDriftedEventReason, deployutil.LatestVersionAheadEventMessage, latest, highest)
+		return false, nil
+	default:
+		return false, nil
+	}
+
+	klog.V(2).Infof("Correcting the latest version %d of %s to %d after the highest deployment version %d", latest, appsutil.LabelForDeploymentConfig(config), corrected, highest)
+	c.recorder.Eventf(config, v1.EventTypeWarning, deployutil.LatestVersionDriftedEventReason, deployutil.LatestVersionDriftedEventMessage, latest, highest, corrected)
+	configCopy := config.DeepCopy()
+	configCopy.Status.LatestVersion = corrected
+	_, err := c.appsClient.DeploymentConfigs(configCopy.Namespace).UpdateStatus(context.TODO(), configCopy, metav1.UpdateOptions{})
+	return true, err
+}
+
+// duplicateVersions returns the names of the deployments by version for the versions several
+// deployments carry.
+func duplicateVersions(deployments []*v1.ReplicationController) map[int64][]string {
+	byVersion := map[int64][]string{}
+	for _, deployment := range deployments {
+		version := appsutil.DeploymentVersionFor(deployment)
+		if version <= 0 {
+			continue
+		}
+		byVersion[version] = append(byVersion[version], deployment.Name)
+	}
+	for version, names := range byVersion {
+		if len(names) < 2 {
+			delete(byVersion, version)
+			continue
+		}
+		sort.Strings(names)
+	}
+	return byVersion
+}
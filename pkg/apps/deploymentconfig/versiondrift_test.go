@@ -0,0 +1,112 @@
+package deploymentconfig
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+)
+
+func TestHandleLatestVersionDrift(t *testing.T) {
+	tests := []struct {
+		name        string
+		latest      int64
+		deployments []*corev1.ReplicationController
+
+		expectedLatest  int64
+		expectedCreated string
+		expectedEvents  []string
+	}{
+		{
+			name:   "in sync",
+			latest: 2,
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+				rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusComplete),
+			},
+		},
+		{
+			name:   "latest version behind",
+			latest: 1,
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+				rollbackDeployment(3, "registry/app:v2", appsv1.DeploymentStatusComplete),
+			},
+			expectedLatest: 2,
+			expectedEvents: []string{"Warning LatestVersionDrifted Latest version 1 does not match the highest deployment version 3, correcting it to 2"},
+		},
+		{
+			name:   "latest version ahead",
+			latest: 5,
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+				rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusComplete),
+			},
+			expectedCreated: "config-5",
+			expectedEvents:  []string{"Warning LatestVersionDrifted Latest version 5 is ahead of the highest deployment version 2 and cannot be lowered"},
+		},
+		{
+			name:   "latest deployment not created yet",
+			latest: 3,
+			deployments: []*corev1.ReplicationController{
+				rollbackDeployment(1, "registry/app:v1", appsv1.DeploymentStatusComplete),
+				rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusComplete),
+			},
+			expectedCreated: "config-3",
+		},
+		{
+			name:   "duplicate versions",
+			latest: 3,
+			deployments: func() []*corev1.ReplicationController {
+				edited := rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusComplete)
+				edited.Name = "config-5"
+				return []*corev1.ReplicationController{
+					rollbackDeployment(2, "registry/app:v2", appsv1.DeploymentStatusComplete),
+					rollbackDeployment(3, "registry/app:v2", appsv1.DeploymentStatusComplete),
+					edited,
+				}
+			}(),
+			expectedEvents: []string{"Warning DuplicateDeploymentVersion Deployments config-2, config-5 share version 2 and must be removed manually"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := rollbackConfig(test.latest, "registry/app:v2")
+			config.Annotations = nil
+			f := newControllerFixture(test.deployments...)
+			if err := f.controller.Handle(config); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if test.expectedLatest == 0 {
+				if f.updatedStatus != nil && f.updatedStatus.Status.LatestVersion != test.latest {
+					t.Errorf("expected the latest version to stay %d, got %d", test.latest, f.updatedStatus.Status.LatestVersion)
+				}
+			} else if f.updatedStatus == nil || f.updatedStatus.Status.LatestVersion != test.expectedLatest {
+				t.Errorf("expected the latest version to be corrected to %d, got %#v", test.expectedLatest, f.updatedStatus)
+			}
+			created := ""
+			if f.created != nil {
+				created = f.created.Name
+			}
+			if created != test.expectedCreated {
+				t.Errorf("expected created deployment %q, got %q", test.expectedCreated, created)
+			}
+
+			var events []string
+			for len(f.recorder.Events) > 0 {
+				event := <-f.recorder.Events
+				if strings.Contains(event, deployutil.LatestVersionDriftedEventReason) || strings.Contains(event, deployutil.DuplicateDeploymentVersionEventReason) {
+					events = append(events, event)
+				}
+			}
+			if strings.Join(events, "\n") != strings.Join(test.expectedEvents, "\n") {
+				t.Errorf("expected events %q, got %q", test.expectedEvents, events)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package deployutil
+
+import (
+	appsv1 "github.com/openshift/api/apps/v1"
+)
+
+// deployerPodTimeLayout is the layout of the times the deployer pod annotations of deployments
+// record.
+const deployerPodTimeLayout = "2006-01-02 15:04:05.999999999 -0700 MST"
+
+const (
+	// ProgressDeadlineSecondsAnnotation is set on deployment configs to the number of seconds the
+	// latest replication controller may take to reach the minimum availability of the deployment
+	// config, measured from the start of its deployer pod, before its rollout fails.
+	ProgressDeadlineSecondsAnnotation = "apps.openshift.io/progress-deadline-seconds"
+
+	// DeploymentFailedProgressDeadlineExceeded is the status reason of rollouts whose replication
+	// controller did not reach the minimum availability of its deployment config within the
+	// progress deadline.
+	DeploymentFailedProgressDeadlineExceeded = "progress deadline exceeded"
+
+	// RollbackOnFailureAnnotation is set to "true" on deployment configs that roll back to their
+	// last complete deployment when the rollout of their latest deployment fails.
+	RollbackOnFailureAnnotation = "apps.openshift.io/rollback-on-failure"
+
+	// RollbackOfVersionAnnotation is set on deployment configs and on the replication controllers
+	// of their automatic rollbacks to the failed version the rollback replaces.
+	RollbackOfVersionAnnotation = "apps.openshift.io/rollback-of-version"
+
+	// DeployerNodeSelectorAnnotation is set on deployment configs to a node selector, in the form
+	// key1=value1,key2=value2, that is merged into the node selector their deployer pods copy from
+	// the pod template.
+	DeployerNodeSelectorAnnotation = "apps.openshift.io/deployer-node-selector"
+
+	// DeployerTolerationsAnnotation is set on deployment configs to a JSON list of tolerations
+	// that are added to the tolerations their deployer pods copy from the pod template.
+	DeployerTolerationsAnnotation = "apps.openshift.io/deployer-tolerations"
+
+	// DeployerAffinityAnnotation is set on deployment configs to the JSON affinity of their
+	// deployer pods. It replaces the default deployer affinity of the controller.
+	DeployerAffinityAnnotation = "apps.openshift.io/deployer-affinity"
+
+	// DeployerTopologySpreadConstraintsAnnotation is set on deployment configs to a JSON list of
+	// the topology spread constraints of their deployer pods. It replaces the default deployer
+	// topology spread constraints of the controller.
+	DeployerTopologySpreadConstraintsAnnotation = "apps.openshift.io/deployer-topology-spread-constraints"
+
+	// DeploymentFailedCancelled is the status reason of rollouts cancelled with the deployment
+	// cancelled annotation alone, without a reason of their own.
+	DeploymentFailedCancelled = "DeploymentCancelled"
+
+	// DeployerServiceAccountAnnotation is set on deployment configs to the name of the service
+	// account their deployer pods run as, instead of the deployer service account of the
+	// controller. The service account must exist before a rollout starts.
+	DeployerServiceAccountAnnotation = "apps.openshift.io/deployer-service-account"
+
+	// DeploymentFailedDeployerServiceAccountNotFound is the status reason of rollouts whose
+	// deployer service account does not exist.
+	DeploymentFailedDeployerServiceAccountNotFound = "deployer service account not found"
+
+	// CustomStrategyImageStreamTagAnnotation is set on deployment configs whose strategy has
+	// custom parameters to an image stream tag, as [namespace/]name:tag, that the image of the
+	// custom parameters is resolved from when a rollout starts. The namespace defaults to the
+	// namespace of the deployment config.
+	CustomStrategyImageStreamTagAnnotation = "apps.openshift.io/custom-strategy-image-stream-tag"
+
+	// DeploymentFailedCustomStrategyImageUnresolved is the status reason of rollouts whose custom
+	// strategy image stream tag could not be resolved.
+	DeploymentFailedCustomStrategyImageUnresolved = "custom strategy image could not be resolved"
+
+	// PreHookTimeoutSecondsAnnotation, MidHookTimeoutSecondsAnnotation and
+	// PostHookTimeoutSecondsAnnotation are set on deployment configs to the number of seconds the
+	// pod of their pre, mid and post lifecycle hooks may run before it is stopped and the hook
+	// fails. The timeout of the strategy keeps bounding the rollout as a whole.
+	PreHookTimeoutSecondsAnnotation  = "apps.openshift.io/pre-hook-timeout-seconds"
+	MidHookTimeoutSecondsAnnotation  = "apps.openshift.io/mid-hook-timeout-seconds"
+	PostHookTimeoutSecondsAnnotation = "apps.openshift.io/post-hook-timeout-seconds"
+
+	// CompleteDeployerPodRetentionAnnotation and FailedDeployerPodRetentionAnnotation are set on
+	// deployment configs to the numbers of complete and failed rollouts whose deployer and hook
+	// pods are kept once they terminated.
+	CompleteDeployerPodRetentionAnnotation = "apps.openshift.io/complete-deployer-pod-retention"
+	FailedDeployerPodRetentionAnnotation   = "apps.openshift.io/failed-deployer-pod-retention"
+
+	// ConfigChangeDebounceAnnotation is set on deployment configs to the duration, such as "10s",
+	// the spec of the deployment config must stay unchanged before a config change rolls it out,
+	// so that the template changes made in quick succession are rolled out once.
+	ConfigChangeDebounceAnnotation = "apps.openshift.io/config-change-debounce"
+
+	// DeployerPodLogAnnotation is set on failed deployments to the tail of the log of their
+	// deployer pod.
+	DeployerPodLogAnnotation = "apps.openshift.io/deployer-pod-log"
+
+	// DeployerPodPendingReasonAnnotation and DeployerPodPendingMessageAnnotation are set on
+	// deployments whose deployer pod can not be created or does not start to the reason, one of
+	// the deployer pod pending reasons, and to the message explaining why. They are removed once
+	// the deployer pod runs.
+	DeployerPodPendingReasonAnnotation  = "apps.openshift.io/deployer-pod-pending-reason"
+	DeployerPodPendingMessageAnnotation = "apps.openshift.io/deployer-pod-pending-message"
+
+	// TemplateDiffAnnotation is set on the deployments of config change rollouts to the changes
+	// of the pod template since the previous deployment, truncated.
+	TemplateDiffAnnotation = "apps.openshift.io/template-diff"
+
+	// LastFailedVersionAnnotation is set on deployment configs that roll back on failure to the
+	// latest failed version the controller handled, so that every failed version is rolled back
+	// at most once.
+	LastFailedVersionAnnotation = "apps.openshift.io/last-failed-version"
+)
+
+const (
+	// DeployerPodUnschedulableReason is the deployer pod pending reason of deployments whose
+	// deployer pod can not be scheduled.
+	DeployerPodUnschedulableReason = "DeployerPodUnschedulable"
+	// DeployerImagePullBackOffReason is the deployer pod pending reason of deployments whose
+	// deployer pod can not pull its image.
+	DeployerImagePullBackOffReason = "DeployerImagePullBackOff"
+	// DeployerQuotaExceededReason is the deployer pod pending reason of deployments whose
+	// deployer pod can not be created because it exceeds the resource quota of its namespace.
+	DeployerQuotaExceededReason = "DeployerQuotaExceeded"
+)
+
+const (
+	// ProgressDeadlineExceededEventReason is the reason associated with the event registered when
+	// a rollout fails because it exceeded its progress deadline.
+	ProgressDeadlineExceededEventReason = "ProgressDeadlineExceeded"
+	// ProgressDeadlineExceededEventMessage is the message associated with the event registered when
+	// a rollout fails because it exceeded its progress deadline.
+	ProgressDeadlineExceededEventMessage = "Rollout for %q did not reach minimum availability within its progress deadline of %ds"
+)
+
+const (
+	// TemplateChangedEventReason is the reason associated with the event registered when a config
+	// change triggers a rollout, listing the changes of the pod template.
+	TemplateChangedEventReason = "TemplateChanged"
+	// TemplateChangedEventMessage is the message associated with the event registered when a
+	// config change triggers a rollout, listing the changes of the pod template.
+	TemplateChangedEventMessage = "Rolling out version %d for changes of the pod template: %s"
+)
+
+const (
+	// RolloutFailedEventReason is the reason associated with the event registered when a rollout
+	// fails, carrying the tail of the log of its deployer pod.
+	RolloutFailedEventReason = "RolloutFailed"
+	// RolloutFailedEventMessage is the message associated with the event registered when a rollout
+	// fails, carrying the tail of the log of its deployer pod.
+	RolloutFailedEventMessage = "Rollout for %q failed, deployer pod log:\n%s"
+)
+
+const (
+	// DeployerPodsExpiredEventReason is the reason associated with the event registered when the
+	// deployer and hook pods of a failed rollout are deleted after the failed deployer pod TTL.
+	DeployerPodsExpiredEventReason = "DeployerPodsExpired"
+	// DeployerPodsExpiredEventMessage is the message associated with the event registered when the
+	// deployer and hook pods of a failed rollout are deleted after the failed deployer pod TTL.
+	DeployerPodsExpiredEventMessage = "Deleted the deployer pods of failed rollout %q %s after they terminated"
+)
+
+const (
+	// LatestVersionDriftedEventReason is the reason associated with the event registered when the
+	// latest version of a deployment config drifted from the highest version of its deployments.
+	LatestVersionDriftedEventReason = "LatestVersionDrifted"
+	// LatestVersionDriftedEventMessage is the message associated with the event registered when the
+	// latest version of a deployment config is corrected to the highest version of its deployments.
+	LatestVersionDriftedEventMessage = "Latest version %d does not match the highest deployment version %d, correcting it to %d"
+	// LatestVersionAheadEventMessage is the message associated with the event registered when the
+	// latest version of a deployment config is ahead of the highest version of its deployments by
+	// more than one, which cannot be corrected.
+	LatestVersionAheadEventMessage = "Latest version %d is ahead of the highest deployment version %d and cannot be lowered"
+	// DuplicateDeploymentVersionEventReason is the reason associated with the event registered
+	// when several deployments of a deployment config carry the same version.
+	DuplicateDeploymentVersionEventReason = "DuplicateDeploymentVersion"
+	// DuplicateDeploymentVersionEventMessage is the message associated with the event registered
+	// when several deployments of a deployment config carry the same version.
+	DuplicateDeploymentVersionEventMessage = "Deployments %s share version %d and must be removed manually"
+)
+
+const (
+	// RollbackCreatedEventReason is the reason associated with the event registered when a
+	// deployment config is rolled back after the rollout of its latest deployment failed.
+	RollbackCreatedEventReason = "RollbackCreated"
+	// RollbackCreatedEventMessage is the message associated with the event registered when a
+	// deployment config is rolled back after the rollout of its latest deployment failed.
+	RollbackCreatedEventMessage = "Rolling back failed version %d to version %d as version %d"
+	// RollbackSkippedEventReason is the reason associated with the event registered when a
+	// deployment config can not be rolled back after the rollout of its latest deployment failed.
+	RollbackSkippedEventReason = "RollbackSkipped"
+	// RollbackSkippedEventMessage is the message associated with the event registered when a
+	// deployment config can not be rolled back after the rollout of its latest deployment failed.
+	RollbackSkippedEventMessage = "Not rolling back failed version %d: %s"
+)
+
+const (
+	// ImageTriggerAccessDenied is the type of the condition the image trigger controller sets to
+	// true on deployment configs whose pod service account is not allowed to pull an image stream
+	// tag of another namespace referenced by one of their automatic image change triggers. The
+	// images of the deployment config are not updated while the condition is true.
+	ImageTriggerAccessDenied appsv1.DeploymentConditionType = "ImageTriggerAccessDenied"
+	// ImagePullAccessDeniedReason is the reason of the image trigger access denied condition of
+	// deployment configs whose pod service account is not allowed to pull an image stream tag.
+	ImagePullAccessDeniedReason = "ImagePullAccessDenied"
+)
+
+const (
+	// ReplicationControllerOverlapEventReason is the reason associated with the event registered
+	// when a replication controller without a controller matches several deployment configs.
+	ReplicationControllerOverlapEventReason = "ReplicationControllerOverlap"
+	// ReplicationControllerOverlapEventMessage is the message associated with the event registered
+	// when a replication controller without a controller matches several deployment configs.
+	ReplicationControllerOverlapEventMessage = "Not adopting replication controller %q, it also matches deployment configs %s"
+)
@@ -0,0 +1,248 @@
+package deployutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+)
+
+// ProgressDeadlineSeconds returns the progress deadline of the deployment config from its
+// progress deadline annotation. It returns false when the deployment config has no valid
+// progress deadline.
+func ProgressDeadlineSeconds(config *appsv1.DeploymentConfig) (int64, bool) {
+	value, ok := config.Annotations[ProgressDeadlineSecondsAnnotation]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on deployment config %s/%s", ProgressDeadlineSecondsAnnotation, value, config.Namespace, config.Name)
+		return 0, false
+	}
+	return seconds, true
+}
+
+// hookTimeoutSecondsAnnotations are the hook timeout annotations of deployment configs by the
+// suffix of the pods of their lifecycle hooks.
+var hookTimeoutSecondsAnnotations = map[string]string{
+	appsutil.PreHookPodSuffix:  PreHookTimeoutSecondsAnnotation,
+	appsutil.MidHookPodSuffix:  MidHookTimeoutSecondsAnnotation,
+	appsutil.PostHookPodSuffix: PostHookTimeoutSecondsAnnotation,
+}
+
+// HookTimeoutSeconds returns the number of seconds the pod of the lifecycle hook of the
+// deployment config with the given hook pod suffix may run, and false if the hook has no timeout
+// of its own.
+func HookTimeoutSeconds(config *appsv1.DeploymentConfig, hookPodSuffix string) (int64, bool) {
+	annotation, ok := hookTimeoutSecondsAnnotations[hookPodSuffix]
+	if !ok {
+		return 0, false
+	}
+	value, ok := config.Annotations[annotation]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on deployment config %s/%s", annotation, value, config.Namespace, config.Name)
+		return 0, false
+	}
+	return seconds, true
+}
+
+// RollbackOnFailure returns true if the deployment config rolls back to its last complete
+// deployment when the rollout of its latest deployment fails.
+func RollbackOnFailure(config *appsv1.DeploymentConfig) bool {
+	return config.Annotations[RollbackOnFailureAnnotation] == "true"
+}
+
+// RollbackOfVersion returns the failed version the object, a deployment config or a replication
+// controller, was rolled back from, and false if it was not rolled back automatically.
+func RollbackOfVersion(obj metav1.Object) (int64, bool) {
+	return versionAnnotation(obj, RollbackOfVersionAnnotation)
+}
+
+// LastFailedVersion returns the latest failed version of the deployment config that was handled
+// for its automatic rollback, and false if there is none.
+func LastFailedVersion(config *appsv1.DeploymentConfig) (int64, bool) {
+	return versionAnnotation(config, LastFailedVersionAnnotation)
+}
+
+func versionAnnotation(obj metav1.Object, annotation string) (int64, bool) {
+	value, ok := obj.GetAnnotations()[annotation]
+	if !ok {
+		return 0, false
+	}
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return version, true
+}
+
+// GenerateRollback returns a copy of the deployment config from with the pod template of the
+// deployment config to, which is usually decoded from the deployment to roll back to, and the
+// next latest version. The automatic image change triggers of the rollback are disabled, so
+// that they do not immediately roll out the images that were rolled back again.
+func GenerateRollback(from, to *appsv1.DeploymentConfig) *appsv1.DeploymentConfig {
+	rollback := from.DeepCopy()
+	rollback.Spec.Template = to.Spec.Template.DeepCopy()
+	for i := range rollback.Spec.Triggers {
+		if params := rollback.Spec.Triggers[i].ImageChangeParams; params != nil {
+			params.Automatic = false
+		}
+	}
+	rollback.Status.LatestVersion++
+	return rollback
+}
+
+// DeployerNodeSelector returns the node selector of the deployer node selector annotation of the
+// deployment config, or nil if it has none.
+func DeployerNodeSelector(config *appsv1.DeploymentConfig) (map[string]string, error) {
+	value, ok := config.Annotations[DeployerNodeSelectorAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	selector, err := labels.ConvertSelectorToLabelsMap(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s annotation %q: %v", DeployerNodeSelectorAnnotation, value, err)
+	}
+	return selector, nil
+}
+
+// DeployerTolerations returns the tolerations of the deployer tolerations annotation of the
+// deployment config, or nil if it has none.
+func DeployerTolerations(config *appsv1.DeploymentConfig) ([]corev1.Toleration, error) {
+	value, ok := config.Annotations[DeployerTolerationsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var tolerations []corev1.Toleration
+	if err := json.Unmarshal([]byte(value), &tolerations); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation %q: %v", DeployerTolerationsAnnotation, value, err)
+	}
+	return tolerations, nil
+}
+
+// DeployerAffinity returns the affinity of the deployer affinity annotation of the deployment
+// config, or nil if it has none.
+func DeployerAffinity(config *appsv1.DeploymentConfig) (*corev1.Affinity, error) {
+	value, ok := config.Annotations[DeployerAffinityAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	affinity := &corev1.Affinity{}
+	if err := json.Unmarshal([]byte(value), affinity); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation %q: %v", DeployerAffinityAnnotation, value, err)
+	}
+	return affinity, nil
+}
+
+// DeployerTopologySpreadConstraints returns the topology spread constraints of the deployer
+// topology spread constraints annotation of the deployment config, or nil if it has none.
+func DeployerTopologySpreadConstraints(config *appsv1.DeploymentConfig) ([]corev1.TopologySpreadConstraint, error) {
+	value, ok := config.Annotations[DeployerTopologySpreadConstraintsAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	var constraints []corev1.TopologySpreadConstraint
+	if err := json.Unmarshal([]byte(value), &constraints); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation %q: %v", DeployerTopologySpreadConstraintsAnnotation, value, err)
+	}
+	return constraints, nil
+}
+
+// DeployerPodDuration returns the time from the start to the completion of the deployer pod of
+// the deployment, as recorded by the deployer pod annotations of the deployment. It returns false
+// when they are missing.
+func DeployerPodDuration(deployment *corev1.ReplicationController) (time.Duration, bool) {
+	started, err := time.Parse(deployerPodTimeLayout, deployment.Annotations[appsv1.DeployerPodStartedAtAnnotation])
+	if err != nil {
+		return 0, false
+	}
+	completed, err := time.Parse(deployerPodTimeLayout, deployment.Annotations[appsv1.DeployerPodCompletedAtAnnotation])
+	if err != nil || completed.Before(started) {
+		return 0, false
+	}
+	return completed.Sub(started), true
+}
+
+// DesiredReplicas returns the replica count the rollout of the deployment scales it to, from
+// its desired replicas annotation, and false if the deployment has none.
+func DesiredReplicas(deployment *corev1.ReplicationController) (int32, bool) {
+	value, ok := deployment.Annotations[appsv1.DesiredReplicasAnnotation]
+	if !ok {
+		return 0, false
+	}
+	replicas, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(replicas), true
+}
+
+// DeployerServiceAccount returns the name of the service account the deployer pods of the
+// deployment config run as from its deployer service account annotation, or the default service
+// account when the deployment config has none.
+func DeployerServiceAccount(config *appsv1.DeploymentConfig, defaultServiceAccount string) string {
+	if name := config.Annotations[DeployerServiceAccountAnnotation]; len(name) > 0 {
+		return name
+	}
+	return defaultServiceAccount
+}
+
+// CustomStrategyImageStreamTag returns the namespace and the name of the image stream tag the
+// custom strategy image of the deployment config is resolved from, and false when the deployment
+// config has no custom strategy image stream tag annotation or its strategy no custom parameters.
+func CustomStrategyImageStreamTag(config *appsv1.DeploymentConfig) (string, string, bool) {
+	value := config.Annotations[CustomStrategyImageStreamTagAnnotation]
+	if len(value) == 0 || config.Spec.Strategy.CustomParams == nil {
+		return "", "", false
+	}
+	if namespace, name, ok := strings.Cut(value, "/"); ok {
+		return namespace, name, true
+	}
+	return config.Namespace, value, true
+}
+
+// DeployerPodRetention returns the number of rollouts of the deployment config whose deployer
+// and hook pods are kept from the given retention annotation, or the default retention when the
+// deployment config has no valid retention. A nil retention keeps the pods of all rollouts.
+func DeployerPodRetention(config *appsv1.DeploymentConfig, annotation string, defaultRetention *int) *int {
+	value, ok := config.Annotations[annotation]
+	if !ok {
+		return defaultRetention
+	}
+	retention, err := strconv.Atoi(value)
+	if err != nil || retention < 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on deployment config %s/%s", annotation, value, config.Namespace, config.Name)
+		return defaultRetention
+	}
+	return &retention
+}
+
+// ConfigChangeDebounce returns how long the deployment config waits for further changes of its
+// spec before a config change rolls it out, from its config change debounce annotation, or the
+// default debounce when the deployment config has no valid debounce.
+func ConfigChangeDebounce(config *appsv1.DeploymentConfig, defaultDebounce time.Duration) time.Duration {
+	value, ok := config.Annotations[ConfigChangeDebounceAnnotation]
+	if !ok {
+		return defaultDebounce
+	}
+	debounce, err := time.ParseDuration(value)
+	if err != nil || debounce < 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on deployment config %s/%s", ConfigChangeDebounceAnnotation, value, config.Namespace, config.Name)
+		return defaultDebounce
+	}
+	return debounce
+}
@@ -79,6 +79,7 @@ func InitializeMetricsCollector(dcLister appsv1listers.DeploymentConfigLister, r
 	if !apps.IsCreated() {
 		legacyregistry.MustRegister(&apps)
 	}
+	Register()
 	klog.V(4).Info("apps metrics registered with prometheus")
 }
 
@@ -0,0 +1,66 @@
+package prometheus
+
+import (
+	"sync"
+
+	k8smetrics "k8s.io/component-base/metrics"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+)
+
+var (
+	// DeploymentConfigSpecReplicas, DeploymentConfigAvailableReplicas,
+	// DeploymentConfigUpdatedReplicas and DeploymentConfigPaused mirror the replica metrics
+	// kube-state-metrics publishes for deployments, by namespace and deployment config. Unlike the
+	// rollout metrics, they are not subject to the deployment config label limit.
+	DeploymentConfigSpecReplicas = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: "openshift_deploymentconfig_spec_replicas",
+		Help: "Number of desired replicas by namespace and deployment config",
+	}, []string{"namespace", "deploymentconfig"})
+	DeploymentConfigAvailableReplicas = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: "openshift_deploymentconfig_status_available_replicas",
+		Help: "Number of available replicas by namespace and deployment config",
+	}, []string{"namespace", "deploymentconfig"})
+	DeploymentConfigUpdatedReplicas = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: "openshift_deploymentconfig_status_updated_replicas",
+		Help: "Number of replicas of the latest version by namespace and deployment config",
+	}, []string{"namespace", "deploymentconfig"})
+	DeploymentConfigPaused = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: "openshift_deploymentconfig_paused",
+		Help: "Whether the deployment config is paused by namespace and deployment config",
+	}, []string{"namespace", "deploymentconfig"})
+
+	replicaGauges = []*k8smetrics.GaugeVec{
+		DeploymentConfigSpecReplicas,
+		DeploymentConfigAvailableReplicas,
+		DeploymentConfigUpdatedReplicas,
+		DeploymentConfigPaused,
+	}
+	// replicasLock keeps the replica metrics of a deployment config from being deleted while they
+	// are recorded.
+	replicasLock sync.Mutex
+)
+
+// RecordReplicas records the replica metrics of a deployment config from its spec and the given
+// status.
+func RecordReplicas(config *appsv1.DeploymentConfig, status *appsv1.DeploymentConfigStatus) {
+	replicasLock.Lock()
+	defer replicasLock.Unlock()
+	paused := 0.0
+	if config.Spec.Paused {
+		paused = 1
+	}
+	DeploymentConfigSpecReplicas.WithLabelValues(config.Namespace, config.Name).Set(float64(config.Spec.Replicas))
+	DeploymentConfigAvailableReplicas.WithLabelValues(config.Namespace, config.Name).Set(float64(status.AvailableReplicas))
+	DeploymentConfigUpdatedReplicas.WithLabelValues(config.Namespace, config.Name).Set(float64(status.UpdatedReplicas))
+	DeploymentConfigPaused.WithLabelValues(config.Namespace, config.Name).Set(paused)
+}
+
+// ForgetReplicas deletes the replica metrics of a deployment config that was deleted.
+func ForgetReplicas(namespace, name string) {
+	replicasLock.Lock()
+	defer replicasLock.Unlock()
+	for _, gauge := range replicaGauges {
+		gauge.DeleteLabelValues(namespace, name)
+	}
+}
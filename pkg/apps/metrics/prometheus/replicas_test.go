@@ -0,0 +1,55 @@
+package prometheus
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
+
+	appsv1 "github.com/openshift/api/apps/v1"
+)
+
+var replicaMetricNames = []string{
+	"openshift_deploymentconfig_spec_replicas",
+	"openshift_deploymentconfig_status_available_replicas",
+	"openshift_deploymentconfig_status_updated_replicas",
+	"openshift_deploymentconfig_paused",
+}
+
+func TestRecordReplicas(t *testing.T) {
+	Register()
+	for _, gauge := range replicaGauges {
+		gauge.Reset()
+	}
+
+	for _, name := range []string{"a", "b"} {
+		config := &appsv1.DeploymentConfig{}
+		config.Namespace, config.Name = "test", name
+		config.Spec.Replicas = 3
+		config.Spec.Paused = name == "b"
+		RecordReplicas(config, &appsv1.DeploymentConfigStatus{AvailableReplicas: 2, UpdatedReplicas: 1})
+	}
+	ForgetReplicas("test", "a")
+	// forgetting a deployment config twice or one without metrics is a no-op
+	ForgetReplicas("test", "a")
+	ForgetReplicas("test", "c")
+
+	expected := `
+# HELP openshift_deploymentconfig_paused [ALPHA] Whether the deployment config is paused by namespace and deployment config
+# TYPE openshift_deploymentconfig_paused gauge
+openshift_deploymentconfig_paused{deploymentconfig="b",namespace="test"} 1
+# HELP openshift_deploymentconfig_spec_replicas [ALPHA] Number of desired replicas by namespace and deployment config
+# TYPE openshift_deploymentconfig_spec_replicas gauge
+openshift_deploymentconfig_spec_replicas{deploymentconfig="b",namespace="test"} 3
+# HELP openshift_deploymentconfig_status_available_replicas [ALPHA] Number of available replicas by namespace and deployment config
+# TYPE openshift_deploymentconfig_status_available_replicas gauge
+openshift_deploymentconfig_status_available_replicas{deploymentconfig="b",namespace="test"} 2
+# HELP openshift_deploymentconfig_status_updated_replicas [ALPHA] Number of replicas of the latest version by namespace and deployment config
+# TYPE openshift_deploymentconfig_status_updated_replicas gauge
+openshift_deploymentconfig_status_updated_replicas{deploymentconfig="b",namespace="test"} 1
+`
+	if err := testutil.GatherAndCompare(legacyregistry.DefaultGatherer, strings.NewReader(expected), replicaMetricNames...); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,198 @@
+package prometheus
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8smetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	rolloutDurationSeconds = "rollout_duration_seconds"
+	failedRolloutsTotal    = "failed_rollouts_total"
+	cancelledRolloutsTotal = "cancelled_rollouts_total"
+	progressingDeployments = "progressing"
+	unknownRolloutReason   = "Unknown"
+	aggregatedLabelValue   = ""
+)
+
+var (
+	// RolloutDuration observes the duration of complete rollouts from the start of their deployer
+	// pod to its completion.
+	RolloutDuration = k8smetrics.NewHistogramVec(&k8smetrics.HistogramOpts{
+		Name:    nameToQuery(rolloutDurationSeconds),
+		Help:    "Duration of complete rollouts from the start to the completion of their deployer pod by namespace and deployment config",
+		Buckets: k8smetrics.ExponentialBuckets(10, 2, 10),
+	}, []string{"namespace", "deploymentconfig"})
+	// FailedRollouts counts the rollouts that failed by the namespace and deployment config of the
+	// rollout and the reason it failed for.
+	FailedRollouts = k8smetrics.NewCounterVec(&k8smetrics.CounterOpts{
+		Name: nameToQuery(failedRolloutsTotal),
+		Help: "Counts failed rollouts by namespace, deployment config and reason",
+	}, []string{"namespace", "deploymentconfig", "reason"})
+	// CancelledRollouts counts the rollouts that were cancelled by the namespace and deployment
+	// config of the rollout and the reason it was cancelled for.
+	CancelledRollouts = k8smetrics.NewCounterVec(&k8smetrics.CounterOpts{
+		Name: nameToQuery(cancelledRolloutsTotal),
+		Help: "Counts cancelled rollouts by namespace, deployment config and reason",
+	}, []string{"namespace", "deploymentconfig", "reason"})
+	// ProgressingDeploymentConfigs counts the deployment configs whose latest rollout is in
+	// progress by namespace and deployment config.
+	ProgressingDeploymentConfigs = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: nameToQuery(progressingDeployments),
+		Help: "Counts deployment configs with a rollout in progress by namespace and deployment config",
+	}, []string{"namespace", "deploymentconfig"})
+	registerOnce sync.Once
+
+	rollouts = rolloutTracker{
+		labeled:          sets.New[string](),
+		progressing:      map[string][]string{},
+		failedReasons:    map[string]sets.Set[string]{},
+		cancelledReasons: map[string]sets.Set[string]{},
+	}
+)
+
+// Register registers the metrics recorded by the deployment config controller with the legacy
+// registry. It is safe to call it more than once.
+func Register() {
+	registerOnce.Do(func() {
+		legacyregistry.MustRegister(RolloutDuration)
+		legacyregistry.MustRegister(FailedRollouts)
+		legacyregistry.MustRegister(CancelledRollouts)
+		legacyregistry.MustRegister(ProgressingDeploymentConfigs)
+		for _, gauge := range replicaGauges {
+			legacyregistry.MustRegister(gauge)
+		}
+	})
+}
+
+// rolloutTracker caps the number of deployment configs the rollout metrics are labeled with and
+// holds the labels of the deployment configs ProgressingDeploymentConfigs counts. It also holds the
+// reasons the failed and cancelled rollouts of the deployment configs with their own labels were
+// recorded with, so that their series can be deleted with the deployment config.
+type rolloutTracker struct {
+	lock sync.Mutex
+	// limit is the number of deployment configs with their own labels. Zero is unlimited.
+	limit            int
+	labeled          sets.Set[string]
+	progressing      map[string][]string
+	failedReasons    map[string]sets.Set[string]
+	cancelledReasons map[string]sets.Set[string]
+}
+
+// labels returns the namespace and deployment config labels of a deployment config. Once the
+// limit of labeled deployment configs is reached, the other deployment configs are aggregated
+// under empty labels.
+func (t *rolloutTracker) labels(namespace, name string) []string {
+	if t.limit == 0 {
+		return []string{namespace, name}
+	}
+	key := namespace + "/" + name
+	if !t.labeled.Has(key) {
+		if t.labeled.Len() >= t.limit {
+			return []string{aggregatedLabelValue, aggregatedLabelValue}
+		}
+		t.labeled.Insert(key)
+	}
+	return []string{namespace, name}
+}
+
+// recordReason remembers the reason a rollout of a deployment config with its own labels was
+// recorded with.
+func recordReason(reasons map[string]sets.Set[string], labels []string, reason string) {
+	if labels[0] == aggregatedLabelValue && labels[1] == aggregatedLabelValue {
+		return
+	}
+	key := labels[0] + "/" + labels[1]
+	if reasons[key] == nil {
+		reasons[key] = sets.New[string]()
+	}
+	reasons[key].Insert(reason)
+}
+
+// SetDeploymentConfigLabelLimit limits the number of deployment configs the rollout metrics are
+// labeled with. The rollouts of the deployment configs beyond the limit are recorded with empty
+// namespace and deployment config labels. Zero is unlimited.
+func SetDeploymentConfigLabelLimit(limit int) {
+	rollouts.lock.Lock()
+	defer rollouts.lock.Unlock()
+	rollouts.limit = limit
+}
+
+// RecordRolloutCompleted observes the duration of a complete rollout of a deployment config.
+func RecordRolloutCompleted(namespace, name string, duration time.Duration) {
+	rollouts.lock.Lock()
+	defer rollouts.lock.Unlock()
+	RolloutDuration.WithLabelValues(rollouts.labels(namespace, name)...).Observe(duration.Seconds())
+}
+
+// RecordRolloutFailed records a failed rollout of a deployment config and the status reason it
+// failed for.
+func RecordRolloutFailed(namespace, name, reason string) {
+	rollouts.lock.Lock()
+	defer rollouts.lock.Unlock()
+	labels := rollouts.labels(namespace, name)
+	recordReason(rollouts.failedReasons, labels, rolloutReason(reason))
+	FailedRollouts.WithLabelValues(append(labels, rolloutReason(reason))...).Inc()
+}
+
+// RecordRolloutCancelled records a cancelled rollout of a deployment config and the status reason
+// it was cancelled for.
+func RecordRolloutCancelled(namespace, name, reason string) {
+	rollouts.lock.Lock()
+	defer rollouts.lock.Unlock()
+	labels := rollouts.labels(namespace, name)
+	recordReason(rollouts.cancelledReasons, labels, rolloutReason(reason))
+	CancelledRollouts.WithLabelValues(append(labels, rolloutReason(reason))...).Inc()
+}
+
+// RecordProgressing records whether the latest rollout of a deployment config is in progress.
+func RecordProgressing(namespace, name string, progressing bool) {
+	rollouts.lock.Lock()
+	defer rollouts.lock.Unlock()
+	key := namespace + "/" + name
+	labels, counted := rollouts.progressing[key]
+	if progressing == counted {
+		return
+	}
+	if !progressing {
+		ProgressingDeploymentConfigs.WithLabelValues(labels...).Dec()
+		delete(rollouts.progressing, key)
+		return
+	}
+	labels = rollouts.labels(namespace, name)
+	ProgressingDeploymentConfigs.WithLabelValues(labels...).Inc()
+	rollouts.progressing[key] = labels
+}
+
+// ForgetRollouts deletes the rollout metrics of a deployment config that was deleted and frees its
+// labels for another deployment config. The rollouts aggregated under empty labels are kept.
+func ForgetRollouts(namespace, name string) {
+	rollouts.lock.Lock()
+	defer rollouts.lock.Unlock()
+	key := namespace + "/" + name
+	labels := map[string]string{"namespace": namespace, "deploymentconfig": name}
+	RolloutDuration.Delete(labels)
+	for _, vec := range []struct {
+		counter *k8smetrics.CounterVec
+		reasons map[string]sets.Set[string]
+	}{
+		{FailedRollouts, rollouts.failedReasons},
+		{CancelledRollouts, rollouts.cancelledReasons},
+	} {
+		for reason := range vec.reasons[key] {
+			vec.counter.Delete(map[string]string{"namespace": namespace, "deploymentconfig": name, "reason": reason})
+		}
+		delete(vec.reasons, key)
+	}
+	rollouts.labeled.Delete(key)
+}
+
+func rolloutReason(reason string) string {
+	if len(reason) == 0 {
+		return unknownRolloutReason
+	}
+	return reason
+}
@@ -0,0 +1,87 @@
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestDeploymentConfigLabelLimit(t *testing.T) {
+	Register()
+	FailedRollouts.Reset()
+	ProgressingDeploymentConfigs.Reset()
+	SetDeploymentConfigLabelLimit(2)
+	defer SetDeploymentConfigLabelLimit(0)
+
+	for _, name := range []string{"a", "b", "c", "d", "a"} {
+		RecordRolloutFailed("test", name, "")
+		RecordProgressing("test", name, true)
+	}
+
+	expected := map[[2]string]float64{
+		{"test", "a"}: 2,
+		{"test", "b"}: 1,
+		{"", ""}:      2,
+	}
+	for labels, count := range expected {
+		value, err := testutil.GetCounterMetricValue(FailedRollouts.WithLabelValues(labels[0], labels[1], unknownRolloutReason))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != count {
+			t.Errorf("expected %v failed rollouts for %v, got %v", count, labels, value)
+		}
+	}
+
+	if value, _ := testutil.GetGaugeMetricValue(ProgressingDeploymentConfigs.WithLabelValues("", "")); value != 2 {
+		t.Errorf("expected 2 aggregated progressing deployment configs, got %v", value)
+	}
+	RecordProgressing("test", "c", false)
+	RecordProgressing("test", "a", false)
+	if value, _ := testutil.GetGaugeMetricValue(ProgressingDeploymentConfigs.WithLabelValues("", "")); value != 1 {
+		t.Errorf("expected 1 aggregated progressing deployment config, got %v", value)
+	}
+	if value, _ := testutil.GetGaugeMetricValue(ProgressingDeploymentConfigs.WithLabelValues("test", "a")); value != 0 {
+		t.Errorf("expected deployment config a not to be progressing, got %v", value)
+	}
+}
+
+func TestForgetRollouts(t *testing.T) {
+	Register()
+	RolloutDuration.Reset()
+	FailedRollouts.Reset()
+	CancelledRollouts.Reset()
+	rollouts.labeled.Clear()
+	SetDeploymentConfigLabelLimit(1)
+	defer SetDeploymentConfigLabelLimit(0)
+
+	RecordRolloutFailed("test", "a", "ProgressDeadlineExceeded")
+	RecordRolloutCancelled("test", "a", "")
+	RecordRolloutCompleted("test", "a", time.Minute)
+	RecordRolloutFailed("test", "b", "")
+	ForgetRollouts("test", "a")
+
+	for _, labels := range []map[string]string{
+		{"namespace": "test", "deploymentconfig": "a", "reason": "ProgressDeadlineExceeded"},
+		{"namespace": "test", "deploymentconfig": "a", "reason": unknownRolloutReason},
+	} {
+		if FailedRollouts.Delete(labels) || CancelledRollouts.Delete(labels) {
+			t.Errorf("expected the rollout counters of the deleted deployment config to be deleted, got %v", labels)
+		}
+	}
+	if RolloutDuration.Delete(map[string]string{"namespace": "test", "deploymentconfig": "a"}) {
+		t.Errorf("expected the rollout duration of the deleted deployment config to be deleted")
+	}
+
+	// the label of the deleted deployment config is free for another one, whose earlier rollout
+	// was aggregated
+	RecordRolloutFailed("test", "b", "")
+	value, err := testutil.GetCounterMetricValue(FailedRollouts.WithLabelValues("test", "b", unknownRolloutReason))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != 1 {
+		t.Errorf("expected 1 failed rollout for deployment config b, got %v", value)
+	}
+}
@@ -2,30 +2,119 @@ package defaultrolebindings
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/wait"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	rbacinformers "k8s.io/client-go/informers/rbac/v1"
+	"k8s.io/client-go/kubernetes"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	rbacclient "k8s.io/client-go/kubernetes/typed/rbac/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	rbaclisters "k8s.io/client-go/listers/rbac/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	"k8s.io/kubernetes/pkg/api/legacyscheme"
+
+	"github.com/openshift/openshift-controller-manager/pkg/authorization/metrics"
+)
+
+const (
+	// CreateDefaultRoleBindingsAnnotation can be set to "false" on a namespace to prevent the
+	// controllers from creating the default role bindings in it. Existing role bindings are left alone.
+	// Setting it to "true" opts a namespace matching one of the skipped prefixes back in.
+	CreateDefaultRoleBindingsAnnotation = "rbac.openshift.io/create-default-rolebindings"
+
+	// ManagedRoleBindingAnnotation can be set to "true" on a default role binding to have the
+	// controllers restore its subjects and role reference whenever they drift from the defaults.
+	// Role bindings without it are considered customized and are never modified.
+	ManagedRoleBindingAnnotation = "rbac.openshift.io/managed"
+
+	// DefaultRoleBindingsErrorAnnotation is set on a namespace to the last error a controller hit while
+	// creating or reconciling its default role bindings. Its value is prefixed with the name of the
+	// controller that set it, and the same controller removes it after its next successful sync.
+	DefaultRoleBindingsErrorAnnotation = "rbac.openshift.io/default-rolebindings-error"
 )
 
+// DefaultSkippedNamespacePrefixes are the namespace name prefixes of platform namespaces that do not
+// get default role bindings unless they opt in.
+var DefaultSkippedNamespacePrefixes = []string{"kube-", "openshift-"}
+
+// dryRunLogLevel is the verbosity at which controllers in dry-run mode report missing role bindings.
+const dryRunLogLevel = 2
+
+// RoleBindingControllerOptions holds the optional settings of a RoleBindingController.
+type RoleBindingControllerOptions struct {
+	// SkippedNamespacePrefixes lists the name prefixes of namespaces that are not synced.
+	SkippedNamespacePrefixes []string
+	// AdditionalRoleBindings are created in every synced namespace next to the built-in role bindings.
+	// They must pass ValidateAdditionalRoleBindings.
+	AdditionalRoleBindings []AdditionalRoleBinding
+
+	// Cleanup makes the controller delete the role bindings it created instead of creating them. It is
+	// used when the capability the role bindings belong to is disabled. Only role bindings carrying
+	// the CreatedByLabel are deleted.
+	Cleanup bool
+	// MaxCleanupDeletesPerMinute limits the number of role bindings deleted per minute in cleanup mode.
+	MaxCleanupDeletesPerMinute int
+
+	// CreateRateLimiter limits the rate of role binding creations. It is meant to be shared by all
+	// the default rolebindings controllers. Creations are not limited when unset.
+	CreateRateLimiter flowcontrol.RateLimiter
+	// MaxRetryDelay caps the per-namespace exponential backoff after failed syncs. The workqueue
+	// default is used when unset.
+	MaxRetryDelay time.Duration
+	// MaxRetries is the number of times a failing namespace is retried before it is dropped from the
+	// queue with a warning event. Dropped namespaces are synced again on the next resync of the
+	// namespace informer. Namespaces are retried forever when unset.
+	MaxRetries int
+
+	// DryRun makes the controller only report missing role bindings, through its logs and the
+	// missing role bindings metric, without creating, updating or deleting anything.
+	DryRun bool
+}
+
+// GetCleanupRateLimiter returns a flowcontrol rate limiter based on the maximum number of
+// deletes (MaxCleanupDeletesPerMinute) setting.
+func (opts RoleBindingControllerOptions) GetCleanupRateLimiter() flowcontrol.RateLimiter {
+	if opts.MaxCleanupDeletesPerMinute <= 0 {
+		return flowcontrol.NewFakeAlwaysRateLimiter()
+	}
+
+	deleteRate := float32(opts.MaxCleanupDeletesPerMinute) / float32(time.Minute/time.Second)
+	return flowcontrol.NewTokenBucketRateLimiter(deleteRate, 1)
+}
+
+func (opts RoleBindingControllerOptions) getQueueRateLimiter() workqueue.RateLimiter {
+	if opts.MaxRetryDelay <= 0 {
+		return workqueue.DefaultControllerRateLimiter()
+	}
+	return workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, opts.MaxRetryDelay)
+}
+
 // RoleBindingController is a controller to combine cluster roles
 type RoleBindingController struct {
 	name              string
 	roleBindingClient rbacclient.RoleBindingsGetter
+	namespaceClient   v1core.NamespacesGetter
 
 	roleBindingLister rbaclisters.RoleBindingLister
 	roleBindingSynced cache.InformerSynced
@@ -35,24 +124,100 @@ type RoleBindingController struct {
 	syncHandler      func(namespace string) error
 	queue            workqueue.RateLimitingInterface
 	roleBindingsFunc projectRoleBindings
+
+	recorder record.EventRecorder
+
+	skippedNamespacePrefixes []string
+
+	cleanup            bool
+	cleanupRateLimiter flowcontrol.RateLimiter
+
+	createRateLimiter flowcontrol.RateLimiter
+	maxRetries        int
+
+	dryRun bool
 }
 
 // NewRoleBinding creates a new controller
-func NewRoleBindingsController(roleBindingInformer rbacinformers.RoleBindingInformer, namespaceInformer coreinformers.NamespaceInformer, roleBindingClient rbacclient.RoleBindingsGetter, controllerName string) *RoleBindingController {
+func NewRoleBindingsController(roleBindingInformer rbacinformers.RoleBindingInformer, namespaceInformer coreinformers.NamespaceInformer, kubeClient kubernetes.Interface, controllerName string, options RoleBindingControllerOptions) *RoleBindingController {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	metrics.Register()
+
 	c := &RoleBindingController{
 		name:              controllerName,
-		roleBindingClient: roleBindingClient,
+		roleBindingClient: kubeClient.RbacV1(),
+		namespaceClient:   kubeClient.CoreV1(),
 
 		roleBindingLister: roleBindingInformer.Lister(),
 		roleBindingSynced: roleBindingInformer.Informer().HasSynced,
 		namespaceLister:   namespaceInformer.Lister(),
 		namespaceSynced:   namespaceInformer.Informer().HasSynced,
 
-		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), controllerName),
+		queue:    workqueue.NewNamedRateLimitingQueue(options.getQueueRateLimiter(), controllerName),
+		recorder: eventBroadcaster.NewRecorder(legacyscheme.Scheme, corev1.EventSource{Component: controllerName}),
+
+		skippedNamespacePrefixes: options.SkippedNamespacePrefixes,
+
+		cleanup:            options.Cleanup,
+		cleanupRateLimiter: options.GetCleanupRateLimiter(),
+
+		createRateLimiter: options.CreateRateLimiter,
+		maxRetries:        options.MaxRetries,
+
+		dryRun: options.DryRun,
+	}
+	if c.createRateLimiter == nil {
+		c.createRateLimiter = flowcontrol.NewFakeAlwaysRateLimiter()
 	}
 	c.syncHandler = c.syncNamespace
-	c.roleBindingsFunc = GetRoleBindingsForController(controllerName)
+	c.roleBindingsFunc = GetRoleBindingsForControllerWithAdditional(controllerName, options.AdditionalRoleBindings)
+
+	if !c.cleanup {
+		c.addRoleBindingDeleteHandler(roleBindingInformer)
+	}
+	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueNamespace,
+		UpdateFunc: c.namespaceResynced,
+	})
+	return c
+}
+
+// namespaceResynced queues namespaces on the resyncs of the namespace informer, so that namespaces
+// dropped from the queue after MaxRetries are synced again later. Other updates are ignored.
+func (c *RoleBindingController) namespaceResynced(old, cur interface{}) {
+	oldMetadata, err := meta.Accessor(old)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	curMetadata, err := meta.Accessor(cur)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if oldMetadata.GetResourceVersion() != curMetadata.GetResourceVersion() {
+		return
+	}
+	c.enqueueNamespace(cur)
+}
+
+// enqueueNamespace queues a namespace that should be synced.
+func (c *RoleBindingController) enqueueNamespace(obj interface{}) {
+	metadata, err := meta.Accessor(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	if !c.shouldSyncNamespace(metadata) {
+		return
+	}
+	c.queue.Add(metadata.GetName())
+}
 
+// addRoleBindingDeleteHandler requeues namespaces whenever one of the role bindings managed by the
+// controller is deleted from them.
+func (c *RoleBindingController) addRoleBindingDeleteHandler(roleBindingInformer rbacinformers.RoleBindingInformer) {
 	roleBindingNames := GetBootstrapServiceAccountProjectRoleBindingNames(c.roleBindingsFunc)
 
 	roleBindingInformer.Informer().AddEventHandler(cache.FilteringResourceEventHandler{
@@ -64,49 +229,73 @@ func NewRoleBindingsController(roleBindingInformer rbacinformers.RoleBindingInfo
 			return roleBindingNames.Has(metadata.GetName())
 		},
 		Handler: cache.ResourceEventHandlerFuncs{
-			DeleteFunc: func(uncast interface{}) {
-				metadata, err := meta.Accessor(uncast)
-				if err == nil {
-					c.queue.Add(metadata.GetNamespace())
-					return
-				}
-
-				tombstone, ok := uncast.(cache.DeletedFinalStateUnknown)
-				if !ok {
-					utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", uncast))
-					return
-				}
-				metadata, err = meta.Accessor(tombstone.Obj)
-				if err != nil {
-					utilruntime.HandleError(err)
-					return
-				}
-				c.queue.Add(metadata.GetNamespace())
-			},
-		},
-	})
-	namespaceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
-		AddFunc: func(obj interface{}) {
-			metadata, err := meta.Accessor(obj)
-			if err != nil {
-				utilruntime.HandleError(err)
-				return
-			}
-			c.queue.Add(metadata.GetName())
+			DeleteFunc: c.roleBindingDeleted,
 		},
 	})
-	return c
 }
 
-func (c *RoleBindingController) syncNamespace(namespaceName string) error {
+// shouldSyncNamespace returns false for namespaces that opted out of default role bindings and for
+// namespaces matching one of the skipped prefixes that did not explicitly opt in.
+func (c *RoleBindingController) shouldSyncNamespace(namespace metav1.Object) bool {
+	switch namespace.GetAnnotations()[CreateDefaultRoleBindingsAnnotation] {
+	case "false":
+		return false
+	case "true":
+		return true
+	}
+	for _, prefix := range c.skippedNamespacePrefixes {
+		if strings.HasPrefix(namespace.GetName(), prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// roleBindingDeleted requeues the namespace of a deleted role binding so that it is recreated
+// right away instead of on the next resync. Deletes caused by namespace termination are ignored.
+func (c *RoleBindingController) roleBindingDeleted(uncast interface{}) {
+	metadata, err := meta.Accessor(uncast)
+	if err != nil {
+		tombstone, ok := uncast.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", uncast))
+			return
+		}
+		metadata, err = meta.Accessor(tombstone.Obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return
+		}
+	}
+
+	namespace, err := c.namespaceLister.Get(metadata.GetNamespace())
+	if err != nil || namespace.DeletionTimestamp != nil || !c.shouldSyncNamespace(namespace) {
+		return
+	}
+	klog.V(4).Infof("%v: role binding %s/%s was deleted, requeueing namespace", c.name, metadata.GetNamespace(), metadata.GetName())
+	c.queue.Add(metadata.GetNamespace())
+}
+
+func (c *RoleBindingController) syncNamespace(namespaceName string) (err error) {
+	defer func(start time.Time) {
+		metrics.RecordSync(c.name, start, err)
+	}(time.Now())
+
 	namespace, err := c.namespaceLister.Get(namespaceName)
 	if errors.IsNotFound(err) {
+		c.forgetMissing(namespaceName)
 		return nil
 	}
 	if err != nil {
 		return err
 	}
 	if namespace.DeletionTimestamp != nil {
+		c.forgetMissing(namespaceName)
+		return nil
+	}
+	if !c.shouldSyncNamespace(namespace) {
+		klog.V(4).Infof("%v: skipping namespace %q, default role bindings are disabled for it", c.name, namespaceName)
+		c.forgetMissing(namespaceName)
 		return nil
 	}
 
@@ -115,37 +304,235 @@ func (c *RoleBindingController) syncNamespace(namespaceName string) error {
 		return err
 	}
 
+	if c.dryRun {
+		c.reportMissing(namespaceName, roleBindings)
+		return nil
+	}
+	if c.cleanup {
+		return c.cleanupNamespace(namespaceName, roleBindings)
+	}
+
 	errs := []error{}
-	desiredRoleBindings := GetRoleBindingsForController(c.name)(namespaceName)
+	desiredRoleBindings := c.roleBindingsFunc(namespaceName)
 
 	for i := range desiredRoleBindings {
 		desiredRoleBinding := desiredRoleBindings[i]
-		found := false
+		var found *rbacv1.RoleBinding
 		for _, existingRoleBinding := range roleBindings {
 			if existingRoleBinding.Name == desiredRoleBinding.Name {
-				found = true
+				found = existingRoleBinding
 				break
 			}
 		}
-		if found {
+		if found != nil {
+			if err := c.reconcileRoleBinding(namespace, found, &desiredRoleBinding); err != nil {
+				errs = append(errs, err)
+			}
 			continue
 		}
 
+		desiredRoleBinding.Annotations[CreatedAtAnnotation] = metav1.Now().UTC().Format(time.RFC3339)
+		c.createRateLimiter.Accept()
 		_, err := c.roleBindingClient.RoleBindings(namespaceName).Create(context.TODO(), &desiredRoleBinding, metav1.CreateOptions{})
-		if err != nil && !errors.IsAlreadyExists(err) {
+		switch {
+		case err == nil:
+			metrics.RecordCreated(c.name, desiredRoleBinding.Name)
+			c.recorder.Eventf(namespace, corev1.EventTypeNormal, c.name+"CreatedRoleBinding", "Created role binding %s", desiredRoleBinding.Name)
+		case errors.IsAlreadyExists(err):
+			// another worker or an informer lagging behind raced us, continue with the live object
+			klog.V(4).Infof("%v: role binding %s/%s already exists", c.name, namespaceName, desiredRoleBinding.Name)
+			live, err := c.roleBindingClient.RoleBindings(namespaceName).Get(context.TODO(), desiredRoleBinding.Name, metav1.GetOptions{})
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if err := c.reconcileRoleBinding(namespace, live, &desiredRoleBinding); err != nil {
+				errs = append(errs, err)
+			}
+		default:
+			c.recorder.Eventf(namespace, corev1.EventTypeWarning, c.name+"FailedCreateRoleBinding", "Failed to create role binding %s: %v", desiredRoleBinding.Name, err)
 			errs = append(errs, err)
 		}
 	}
 
-	if len(errs) == 0 {
+	var syncErr error
+	switch len(errs) {
+	case 0:
+	case 1:
+		syncErr = errs[0]
+	default:
+		syncErr = utilerrors.NewAggregate(errs)
+	}
+	if err := c.updateErrorAnnotation(namespace, syncErr); err != nil {
+		if syncErr != nil {
+			utilruntime.HandleError(err)
+			return syncErr
+		}
+		return err
+	}
+	return syncErr
+}
+
+// updateErrorAnnotation records syncErr in the DefaultRoleBindingsErrorAnnotation of the namespace,
+// or removes the annotation when syncErr is nil and the controller was the one that set it.
+func (c *RoleBindingController) updateErrorAnnotation(namespace *corev1.Namespace, syncErr error) error {
+	current, hasAnnotation := namespace.Annotations[DefaultRoleBindingsErrorAnnotation]
+	prefix := c.name + ": "
+
+	var value *string
+	switch {
+	case syncErr != nil:
+		message := prefix + syncErr.Error()
+		if hasAnnotation && current == message {
+			return nil
+		}
+		value = &message
+	case hasAnnotation && strings.HasPrefix(current, prefix):
+		// a nil value removes the annotation
+	default:
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{DefaultRoleBindingsErrorAnnotation: value},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = c.namespaceClient.Namespaces().Patch(context.TODO(), namespace.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if errors.IsNotFound(err) {
 		return nil
 	}
-	if len(errs) == 1 {
-		return errs[0]
+	return err
+}
+
+// reportMissing logs and records the role bindings that would be created in the namespace
+// without creating them.
+func (c *RoleBindingController) reportMissing(namespaceName string, roleBindings []*rbacv1.RoleBinding) {
+	existing := sets.New[string]()
+	for _, roleBinding := range roleBindings {
+		existing.Insert(roleBinding.Name)
+	}
+	for _, desired := range c.roleBindingsFunc(namespaceName) {
+		missing := !existing.Has(desired.Name)
+		if missing {
+			klog.V(dryRunLogLevel).Infof("%v: dry run, role binding %s/%s is missing", c.name, namespaceName, desired.Name)
+		}
+		metrics.RecordMissing(namespaceName, desired.Name, missing)
+	}
+}
+
+// forgetMissing clears the dry-run report of a namespace that is no longer synced.
+func (c *RoleBindingController) forgetMissing(namespaceName string) {
+	if !c.dryRun {
+		return
+	}
+	for _, desired := range c.roleBindingsFunc(namespaceName) {
+		metrics.RecordMissing(namespaceName, desired.Name, false)
+	}
+}
+
+// cleanupNamespace deletes the role bindings of the controller that were created by one of the
+// default rolebindings controllers. Role bindings without the CreatedByLabel are left alone.
+func (c *RoleBindingController) cleanupNamespace(namespaceName string, roleBindings []*rbacv1.RoleBinding) error {
+	names := GetBootstrapServiceAccountProjectRoleBindingNames(c.roleBindingsFunc)
+
+	errs := []error{}
+	for _, roleBinding := range roleBindings {
+		if !names.Has(roleBinding.Name) || roleBinding.Labels[CreatedByLabel] != CreatedByLabelValue {
+			continue
+		}
+
+		c.cleanupRateLimiter.Accept()
+		klog.V(2).Infof("%v: deleting role binding %s/%s", c.name, namespaceName, roleBinding.Name)
+		err := c.roleBindingClient.RoleBindings(namespaceName).Delete(context.TODO(), roleBinding.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &roleBinding.UID}})
+		if err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, err)
+		}
 	}
 	return utilerrors.NewAggregate(errs)
 }
 
+// recreateError is returned when a role binding that was deleted to restore its role reference
+// could not be created again. The namespace is requeued right away, because the role binding is
+// missing until the next sync creates it.
+type recreateError struct {
+	err error
+}
+
+func (e *recreateError) Error() string {
+	return e.err.Error()
+}
+
+// isRecreateError returns true if the error, or one of the errors it aggregates, is a recreateError.
+func isRecreateError(err error) bool {
+	if agg, ok := err.(utilerrors.Aggregate); ok {
+		for _, err := range agg.Errors() {
+			if isRecreateError(err) {
+				return true
+			}
+		}
+		return false
+	}
+	_, ok := err.(*recreateError)
+	return ok
+}
+
+// reconcileRoleBinding restores the subjects and role reference of an existing role binding
+// carrying the ManagedRoleBindingAnnotation to the desired values.
+func (c *RoleBindingController) reconcileRoleBinding(namespace *corev1.Namespace, existing, desired *rbacv1.RoleBinding) error {
+	if existing.Annotations[ManagedRoleBindingAnnotation] != "true" {
+		return nil
+	}
+	if existing.RoleRef == desired.RoleRef && equality.Semantic.DeepEqual(existing.Subjects, desired.Subjects) {
+		return nil
+	}
+
+	client := c.roleBindingClient.RoleBindings(existing.Namespace)
+
+	if existing.RoleRef != desired.RoleRef {
+		// roleRef is immutable, so the role binding has to be recreated
+		klog.V(2).Infof("%v: recreating role binding %s/%s with drifted roleRef %v", c.name, existing.Namespace, existing.Name, existing.RoleRef)
+		err := client.Delete(context.TODO(), existing.Name, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{UID: &existing.UID}})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		recreated := desired.DeepCopy()
+		recreated.Annotations[ManagedRoleBindingAnnotation] = "true"
+		recreated.Annotations[CreatedAtAnnotation] = metav1.Now().UTC().Format(time.RFC3339)
+		c.createRateLimiter.Accept()
+		if _, err := client.Create(context.TODO(), recreated, metav1.CreateOptions{}); err != nil {
+			c.recorder.Eventf(namespace, corev1.EventTypeWarning, c.name+"FailedRecreateRoleBinding", "Failed to recreate role binding %s after deleting it to restore its roleRef: %v", existing.Name, err)
+			return &recreateError{err: err}
+		}
+		return nil
+	}
+
+	klog.V(2).Infof("%v: restoring subjects of role binding %s/%s", c.name, existing.Namespace, existing.Name)
+	current := existing
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		updated := current.DeepCopy()
+		updated.Subjects = desired.Subjects
+		_, err := client.Update(context.TODO(), updated, metav1.UpdateOptions{})
+		if !errors.IsConflict(err) {
+			return err
+		}
+
+		// the lister was stale, retry against the live object unless someone else already fixed it
+		live, getErr := client.Get(context.TODO(), existing.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return getErr
+		}
+		if live.Annotations[ManagedRoleBindingAnnotation] != "true" || equality.Semantic.DeepEqual(live.Subjects, desired.Subjects) {
+			return nil
+		}
+		current = live
+		return err
+	})
+}
+
 // Run starts the controller and blocks until stopCh is closed.
 func (c *RoleBindingController) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
@@ -182,8 +569,24 @@ func (c *RoleBindingController) processNextWorkItem() bool {
 		c.queue.Forget(dsKey)
 		return true
 	}
+	// the next sync creates the missing role binding through the regular path, which backs off
+	if isRecreateError(err) {
+		utilruntime.HandleError(fmt.Errorf("%v failed to recreate a role binding, requeueing: %v", dsKey, err))
+		c.queue.Add(dsKey)
+		return true
+	}
+
+	retries := c.queue.NumRequeues(dsKey)
+	if c.maxRetries > 0 && retries >= c.maxRetries {
+		utilruntime.HandleError(fmt.Errorf("%v failed after %d retries, dropping it from the queue: %v", dsKey, retries, err))
+		c.queue.Forget(dsKey)
+		if namespace, getErr := c.namespaceLister.Get(dsKey.(string)); getErr == nil {
+			c.recorder.Eventf(namespace, corev1.EventTypeWarning, c.name+"GaveUp", "Giving up on default role bindings after %d retries: %v", retries, err)
+		}
+		return true
+	}
 
-	utilruntime.HandleError(fmt.Errorf("%v failed with : %v", dsKey, err))
+	utilruntime.HandleError(fmt.Errorf("%v failed with (retry %d): %v", dsKey, retries, err))
 	c.queue.AddRateLimited(dsKey)
 
 	return true
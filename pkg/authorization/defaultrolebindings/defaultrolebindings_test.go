@@ -1,19 +1,35 @@
 package defaultrolebindings
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
 	kubeclientfake "k8s.io/client-go/kubernetes/fake"
 	corelisters "k8s.io/client-go/listers/core/v1"
 	rbaclisters "k8s.io/client-go/listers/rbac/v1"
 	clienttesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/client-go/util/workqueue"
+	basemetrics "k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/testutil"
 	"k8s.io/kubernetes/pkg/controller"
+
+	"github.com/openshift/openshift-controller-manager/pkg/authorization/metrics"
 )
 
 var controllerNames = []string{
@@ -23,14 +39,36 @@ var controllerNames = []string{
 	"DeployerRoleBindingController",
 }
 
+func counterValue(t *testing.T, m basemetrics.CounterMetric) float64 {
+	t.Helper()
+	v, err := testutil.GetCounterMetricValue(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func histogramCount(t *testing.T, m basemetrics.ObserverMetric) uint64 {
+	t.Helper()
+	v, err := testutil.GetHistogramMetricCount(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
 func TestSync(t *testing.T) {
+	metrics.Register()
+
 	tests := []struct {
 		name                      string
 		controller                string
 		startingNamespaces        []*corev1.Namespace
 		startingRoleBindings      []*rbacv1.RoleBinding
 		namespaceToSync           string
+		dryRun                    bool
 		expectedRoleBindingsNames []string
+		expectedMissingNames      []string
 	}{
 		{
 			name:       "create-default-all",
@@ -108,6 +146,85 @@ func TestSync(t *testing.T) {
 			},
 			namespaceToSync: "foo",
 		},
+		{
+			name:       "dry-run",
+			controller: "DefaultRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "audit"}},
+			},
+			startingRoleBindings: []*rbacv1.RoleBinding{
+				{ObjectMeta: metav1.ObjectMeta{Namespace: "audit", Name: "bar"}},
+			},
+			namespaceToSync:      "audit",
+			dryRun:               true,
+			expectedMissingNames: []string{"system:image-pullers", "system:image-builders", "system:deployers"},
+		},
+		{
+			name:       "opt-out-missing",
+			controller: "DefaultRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{CreateDefaultRoleBindingsAnnotation: "false"}}},
+			},
+			startingRoleBindings: []*rbacv1.RoleBinding{
+				{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "bar"}},
+			},
+			namespaceToSync: "foo",
+		},
+		{
+			name:       "opt-out-existing",
+			controller: "DefaultRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{CreateDefaultRoleBindingsAnnotation: "false"}}},
+			},
+			startingRoleBindings: []*rbacv1.RoleBinding{
+				{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "system:image-pullers"}},
+				{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "system:image-builders"}},
+			},
+			namespaceToSync: "foo",
+		},
+		{
+			name:       "skip-kube-system",
+			controller: "DefaultRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+			},
+			namespaceToSync: "kube-system",
+		},
+		{
+			name:       "skip-openshift-prefix",
+			controller: "BuilderRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "openshift-monitoring"}},
+			},
+			namespaceToSync: "openshift-monitoring",
+		},
+		{
+			name:       "platform-namespace-opt-in",
+			controller: "BuilderRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "openshift-builds", Annotations: map[string]string{CreateDefaultRoleBindingsAnnotation: "true"}}},
+			},
+			namespaceToSync:           "openshift-builds",
+			expectedRoleBindingsNames: []string{"system:image-builders"},
+		},
+		{
+			name:       "openshift-without-dash",
+			controller: "ImagePullerRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "openshift"}},
+			},
+			namespaceToSync:           "openshift",
+			expectedRoleBindingsNames: []string{"system:image-pullers"},
+		},
+		{
+			name:       "opt-out-not-false",
+			controller: "ImagePullerRoleBindingController",
+			startingNamespaces: []*corev1.Namespace{
+				{ObjectMeta: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{CreateDefaultRoleBindingsAnnotation: "true"}}},
+			},
+			namespaceToSync:           "foo",
+			expectedRoleBindingsNames: []string{"system:image-pullers"},
+		},
 	}
 
 	for _, test := range tests {
@@ -125,22 +242,47 @@ func TestSync(t *testing.T) {
 			}
 			fakeClient := kubeclientfake.NewSimpleClientset(objs...)
 			for _, cName := range controllerNames {
+				recorder := record.NewFakeRecorder(10)
 				c := RoleBindingController{
-					name:              cName,
-					roleBindingClient: fakeClient.RbacV1(),
-					roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
-					namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+					name:                     cName,
+					roleBindingClient:        fakeClient.RbacV1(),
+					roleBindingsFunc:         GetRoleBindingsForController(cName),
+					roleBindingLister:        rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+					namespaceLister:          corelisters.NewNamespaceLister(namespaceIndexer),
+					recorder:                 recorder,
+					createRateLimiter:        flowcontrol.NewFakeAlwaysRateLimiter(),
+					skippedNamespacePrefixes: DefaultSkippedNamespacePrefixes,
+					dryRun:                   test.dryRun,
 				}
 
 				if c.name != test.controller {
 					continue
 				}
 
+				createdBefore := map[string]float64{}
+				for _, name := range test.expectedRoleBindingsNames {
+					createdBefore[name] = counterValue(t, metrics.CreatedTotal.WithLabelValues(cName, name))
+				}
+				syncsBefore := histogramCount(t, metrics.SyncDuration.WithLabelValues(cName))
+				errorsBefore := counterValue(t, metrics.SyncErrorsTotal.WithLabelValues(cName))
+
 				err := c.syncNamespace(test.namespaceToSync)
 				if err != nil {
 					t.Fatal(err)
 				}
 
+				for _, name := range test.expectedRoleBindingsNames {
+					if delta := counterValue(t, metrics.CreatedTotal.WithLabelValues(cName, name)) - createdBefore[name]; delta != 1 {
+						t.Errorf("expected created_total for %v to increase by 1, got %v", name, delta)
+					}
+				}
+				if delta := histogramCount(t, metrics.SyncDuration.WithLabelValues(cName)) - syncsBefore; delta != 1 {
+					t.Errorf("expected one sync duration observation, got %v", delta)
+				}
+				if delta := counterValue(t, metrics.SyncErrorsTotal.WithLabelValues(cName)) - errorsBefore; delta != 0 {
+					t.Errorf("expected no sync errors, got %v", delta)
+				}
+
 				allActions := fakeClient.Actions()
 				createActions := []clienttesting.CreateAction{}
 				for i := range allActions {
@@ -165,12 +307,825 @@ func TestSync(t *testing.T) {
 					if name != metadata.GetName() {
 						t.Errorf("expected %v, got %v", name, metadata.GetName())
 					}
+					if metadata.GetLabels()[CreatedByLabel] != CreatedByLabelValue {
+						t.Errorf("expected %v label on %v, got %v", CreatedByLabel, name, metadata.GetLabels())
+					}
+					if metadata.GetAnnotations()[CreatedByControllerAnnotation] != cName {
+						t.Errorf("expected %v annotation %v on %v, got %v", CreatedByControllerAnnotation, cName, name, metadata.GetAnnotations())
+					}
+					if _, err := time.Parse(time.RFC3339, metadata.GetAnnotations()[CreatedAtAnnotation]); err != nil {
+						t.Errorf("expected valid %v annotation on %v: %v", CreatedAtAnnotation, name, err)
+					}
 					if action.GetNamespace() != test.namespaceToSync {
 						t.Errorf("expected %v, got %v", test.namespaceToSync, action.GetNamespace())
 					}
 				}
+
+				for _, name := range test.expectedMissingNames {
+					if v, err := testutil.GetGaugeMetricValue(metrics.MissingRoleBindings.WithLabelValues(test.namespaceToSync, name)); err != nil || v != 1 {
+						t.Errorf("expected %v to be reported missing, got %v (%v)", name, v, err)
+					}
+				}
+
+				if len(recorder.Events) != len(test.expectedRoleBindingsNames) {
+					t.Fatalf("expected %d events, got %d", len(test.expectedRoleBindingsNames), len(recorder.Events))
+				}
+				for _, name := range test.expectedRoleBindingsNames {
+					expected := fmt.Sprintf("Normal %sCreatedRoleBinding Created role binding %s", cName, name)
+					if event := <-recorder.Events; event != expected {
+						t.Errorf("expected event %q, got %q", expected, event)
+					}
+				}
 			}
 		})
 	}
 
 }
+
+func TestSyncOptOutAnnotationToggle(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	roleBindings := []*rbacv1.RoleBinding{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "system:image-pullers"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: "system:image-builders"}},
+	}
+
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	objs := []runtime.Object{namespace}
+	namespaceIndexer.Add(namespace)
+	for _, obj := range roleBindings {
+		objs = append(objs, obj)
+		roleBindingIndexer.Add(obj)
+	}
+	fakeClient := kubeclientfake.NewSimpleClientset(objs...)
+	c := RoleBindingController{
+		name:              "DefaultRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("DefaultRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:          record.NewFakeRecorder(10),
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+
+	// opting out after the bindings already exist must neither create nor delete anything
+	optedOut := namespace.DeepCopy()
+	optedOut.Annotations = map[string]string{CreateDefaultRoleBindingsAnnotation: "false"}
+	namespaceIndexer.Update(optedOut)
+	if err := c.syncNamespace("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if actions := fakeClient.Actions(); len(actions) != 0 {
+		t.Fatalf("expected no actions, got %#v", actions)
+	}
+
+	// removing the annotation again restores the missing binding
+	namespaceIndexer.Update(namespace)
+	if err := c.syncNamespace("foo"); err != nil {
+		t.Fatal(err)
+	}
+	actions := fakeClient.Actions()
+	if len(actions) != 1 {
+		t.Fatalf("expected one action, got %#v", actions)
+	}
+	createAction, ok := actions[0].(clienttesting.CreateAction)
+	if !ok {
+		t.Fatalf("unexpected action %#v", actions[0])
+	}
+	metadata, err := meta.Accessor(createAction.GetObject())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata.GetName() != "system:deployers" {
+		t.Errorf("expected %v, got %v", "system:deployers", metadata.GetName())
+	}
+}
+
+func TestSyncReconcilesManagedRoleBindings(t *testing.T) {
+	deployers := GetDeployerServiceAccountProjectRoleBindings("foo")
+	wrongSubjects := []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: "rbac.authorization.k8s.io", Name: "mallory"}}
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		roleRef         rbacv1.RoleRef
+		updateConflicts int
+		expectedVerbs   []string
+	}{
+		{
+			name:          "managed-wrong-subjects",
+			annotations:   map[string]string{ManagedRoleBindingAnnotation: "true"},
+			roleRef:       deployers.RoleRef,
+			expectedVerbs: []string{"update"},
+		},
+		{
+			name:          "unmanaged-wrong-subjects",
+			roleRef:       deployers.RoleRef,
+			expectedVerbs: []string{},
+		},
+		{
+			name:            "managed-update-conflict",
+			annotations:     map[string]string{ManagedRoleBindingAnnotation: "true"},
+			roleRef:         deployers.RoleRef,
+			updateConflicts: 1,
+			expectedVerbs:   []string{"update", "get", "update"},
+		},
+		{
+			name:          "managed-wrong-roleref",
+			annotations:   map[string]string{ManagedRoleBindingAnnotation: "true"},
+			roleRef:       rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "admin"},
+			expectedVerbs: []string{"delete", "create"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+			existing := &rbacv1.RoleBinding{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: DeployerRoleBindingName, Annotations: test.annotations},
+				RoleRef:    test.roleRef,
+				Subjects:   wrongSubjects,
+			}
+
+			roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+			namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+			roleBindingIndexer.Add(existing)
+			namespaceIndexer.Add(namespace)
+			fakeClient := kubeclientfake.NewSimpleClientset(namespace, existing)
+			conflicts := test.updateConflicts
+			fakeClient.PrependReactor("update", "rolebindings", func(action clienttesting.Action) (bool, runtime.Object, error) {
+				if conflicts == 0 {
+					return false, nil, nil
+				}
+				conflicts--
+				return true, nil, kapierrors.NewConflict(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "rolebindings"}, DeployerRoleBindingName, nil)
+			})
+
+			c := RoleBindingController{
+				name:              "DeployerRoleBindingController",
+				roleBindingClient: fakeClient.RbacV1(),
+				roleBindingsFunc:  GetRoleBindingsForController("DeployerRoleBindingController"),
+				roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+				namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+				recorder:          record.NewFakeRecorder(10),
+				createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+			}
+			if err := c.syncNamespace("foo"); err != nil {
+				t.Fatal(err)
+			}
+
+			actions := fakeClient.Actions()
+			verbs := []string{}
+			for _, action := range actions {
+				verbs = append(verbs, action.GetVerb())
+			}
+			if !equality.Semantic.DeepEqual(verbs, test.expectedVerbs) {
+				t.Fatalf("expected %v, got %v", test.expectedVerbs, verbs)
+			}
+			if len(actions) == 0 {
+				return
+			}
+
+			last, ok := actions[len(actions)-1].(clienttesting.CreateAction)
+			if !ok {
+				t.Fatalf("unexpected action %#v", actions[len(actions)-1])
+			}
+			roleBinding := last.GetObject().(*rbacv1.RoleBinding)
+			if !equality.Semantic.DeepEqual(roleBinding.Subjects, deployers.Subjects) {
+				t.Errorf("expected subjects %v, got %v", deployers.Subjects, roleBinding.Subjects)
+			}
+			if roleBinding.RoleRef != deployers.RoleRef {
+				t.Errorf("expected roleRef %v, got %v", deployers.RoleRef, roleBinding.RoleRef)
+			}
+			if roleBinding.Annotations[ManagedRoleBindingAnnotation] != "true" {
+				t.Errorf("expected role binding to remain managed, got annotations %v", roleBinding.Annotations)
+			}
+		})
+	}
+}
+
+func TestSyncRecordsCreateFailures(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace)
+	fakeClient.PrependReactor("create", "rolebindings", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kapierrors.NewForbidden(schema.GroupResource{Group: "rbac.authorization.k8s.io", Resource: "rolebindings"}, ImagePullerRoleBindingName, fmt.Errorf("denied"))
+	})
+
+	recorder := record.NewFakeRecorder(10)
+	c := RoleBindingController{
+		name:              "ImagePullerRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		namespaceClient:   fakeClient.CoreV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("ImagePullerRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:          recorder,
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+	metrics.Register()
+	errorsBefore := counterValue(t, metrics.SyncErrorsTotal.WithLabelValues(c.name))
+	if err := c.syncNamespace("foo"); !kapierrors.IsForbidden(err) {
+		t.Fatalf("expected forbidden error, got %v", err)
+	}
+	if delta := counterValue(t, metrics.SyncErrorsTotal.WithLabelValues(c.name)) - errorsBefore; delta != 1 {
+		t.Errorf("expected sync_errors_total to increase by 1, got %v", delta)
+	}
+
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event, got %d", len(recorder.Events))
+	}
+	event := <-recorder.Events
+	expected := "Warning ImagePullerRoleBindingControllerFailedCreateRoleBinding Failed to create role binding system:image-pullers"
+	if !strings.HasPrefix(event, expected) || !strings.Contains(event, "denied") {
+		t.Errorf("expected event %q to start with %q and contain the API error", event, expected)
+	}
+}
+
+func TestSyncErrorAnnotation(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace)
+
+	createErr := fmt.Errorf("webhook unavailable")
+	fakeClient.PrependReactor("create", "rolebindings", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if createErr != nil {
+			return true, nil, kapierrors.NewInternalError(createErr)
+		}
+		return false, nil, nil
+	})
+
+	c := RoleBindingController{
+		name:              "ImagePullerRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		namespaceClient:   fakeClient.CoreV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("ImagePullerRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:          record.NewFakeRecorder(100),
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+
+	// sync runs the controller and feeds the live namespace back into the lister, the way the
+	// namespace informer would.
+	sync := func() (string, bool) {
+		t.Helper()
+		c.syncNamespace("foo")
+		live, err := fakeClient.CoreV1().Namespaces().Get(context.TODO(), "foo", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		namespaceIndexer.Update(live)
+		value, ok := live.Annotations[DefaultRoleBindingsErrorAnnotation]
+		return value, ok
+	}
+
+	value, ok := sync()
+	if !ok || !strings.HasPrefix(value, "ImagePullerRoleBindingController: ") || !strings.Contains(value, "webhook unavailable") {
+		t.Fatalf("expected the failure to be recorded, got %q (set: %v)", value, ok)
+	}
+
+	createErr = fmt.Errorf("quota exceeded")
+	value, ok = sync()
+	if !ok || !strings.Contains(value, "quota exceeded") || strings.Contains(value, "webhook unavailable") {
+		t.Fatalf("expected the annotation to hold the last failure, got %q (set: %v)", value, ok)
+	}
+
+	countPatches := func() int {
+		patches := 0
+		for _, action := range fakeClient.Actions() {
+			if action.Matches("patch", "namespaces") {
+				patches++
+			}
+		}
+		return patches
+	}
+	patches := countPatches()
+	sync()
+	if countPatches() != patches {
+		t.Errorf("expected no patch when the failure message is unchanged")
+	}
+
+	createErr = nil
+	if value, ok = sync(); ok {
+		t.Fatalf("expected the annotation to be cleared after a successful sync, got %q", value)
+	}
+}
+
+func TestSyncErrorAnnotationOfOtherController(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:        "foo",
+		Annotations: map[string]string{DefaultRoleBindingsErrorAnnotation: "BuilderRoleBindingController: denied"},
+	}}
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace)
+
+	c := RoleBindingController{
+		name:              "ImagePullerRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		namespaceClient:   fakeClient.CoreV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("ImagePullerRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:          record.NewFakeRecorder(10),
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+	if err := c.syncNamespace("foo"); err != nil {
+		t.Fatal(err)
+	}
+	for _, action := range fakeClient.Actions() {
+		if action.Matches("patch", "namespaces") {
+			t.Errorf("expected the annotation set by another controller to be left alone")
+		}
+	}
+}
+
+func TestRoleBindingDeletedRequeuesNamespace(t *testing.T) {
+	now := metav1.Now()
+	tests := []struct {
+		name            string
+		namespace       *corev1.Namespace
+		deleted         interface{}
+		expectRecreated bool
+	}{
+		{
+			name:            "deleted",
+			namespace:       &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			deleted:         &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: ImagePullerRoleBindingName}},
+			expectRecreated: true,
+		},
+		{
+			name:      "tombstone",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}},
+			deleted: cache.DeletedFinalStateUnknown{
+				Key: "foo/" + ImagePullerRoleBindingName,
+				Obj: &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: ImagePullerRoleBindingName}},
+			},
+			expectRecreated: true,
+		},
+		{
+			name:      "namespace-terminating",
+			namespace: &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", DeletionTimestamp: &now}},
+			deleted:   &rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: ImagePullerRoleBindingName}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+			namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+			namespaceIndexer.Add(test.namespace)
+			fakeClient := kubeclientfake.NewSimpleClientset(test.namespace)
+			c := &RoleBindingController{
+				name:              "ImagePullerRoleBindingController",
+				roleBindingClient: fakeClient.RbacV1(),
+				roleBindingsFunc:  GetRoleBindingsForController("ImagePullerRoleBindingController"),
+				roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+				namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+				queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+				recorder:          record.NewFakeRecorder(10),
+				createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+			}
+			c.syncHandler = c.syncNamespace
+			defer c.queue.ShutDown()
+
+			c.roleBindingDeleted(test.deleted)
+
+			if !test.expectRecreated {
+				if c.queue.Len() != 0 {
+					t.Fatalf("expected empty queue, got %d items", c.queue.Len())
+				}
+				return
+			}
+			if c.queue.Len() != 1 {
+				t.Fatalf("expected namespace to be queued, got %d items", c.queue.Len())
+			}
+			c.processNextWorkItem()
+
+			actions := fakeClient.Actions()
+			if len(actions) != 1 {
+				t.Fatalf("expected one action, got %#v", actions)
+			}
+			createAction, ok := actions[0].(clienttesting.CreateAction)
+			if !ok {
+				t.Fatalf("unexpected action %#v", actions[0])
+			}
+			metadata, err := meta.Accessor(createAction.GetObject())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if metadata.GetNamespace() != "foo" || metadata.GetName() != ImagePullerRoleBindingName {
+				t.Errorf("expected foo/%v to be recreated, got %v/%v", ImagePullerRoleBindingName, metadata.GetNamespace(), metadata.GetName())
+			}
+		})
+	}
+}
+
+func TestShouldSyncNamespace(t *testing.T) {
+	c := &RoleBindingController{skippedNamespacePrefixes: DefaultSkippedNamespacePrefixes}
+	tests := []struct {
+		namespace metav1.ObjectMeta
+		expected  bool
+	}{
+		{namespace: metav1.ObjectMeta{Name: "foo"}, expected: true},
+		{namespace: metav1.ObjectMeta{Name: "kube-system"}, expected: false},
+		{namespace: metav1.ObjectMeta{Name: "kube-public"}, expected: false},
+		{namespace: metav1.ObjectMeta{Name: "openshift-etcd"}, expected: false},
+		{namespace: metav1.ObjectMeta{Name: "openshift-ci", Annotations: map[string]string{CreateDefaultRoleBindingsAnnotation: "true"}}, expected: true},
+		{namespace: metav1.ObjectMeta{Name: "foo", Annotations: map[string]string{CreateDefaultRoleBindingsAnnotation: "false"}}, expected: false},
+	}
+	for _, test := range tests {
+		if actual := c.shouldSyncNamespace(&test.namespace); actual != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.namespace.Name, test.expected, actual)
+		}
+	}
+}
+
+func TestSyncAdditionalRoleBindings(t *testing.T) {
+	additional := []AdditionalRoleBinding{
+		{
+			Name:     "ci-robot",
+			RoleRef:  rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"},
+			Subjects: []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ci", Name: "robot"}, {Kind: rbacv1.ServiceAccountKind, Name: "local"}},
+		},
+	}
+	if err := ValidateAdditionalRoleBindings("DefaultRoleBindingController", additional); err != nil {
+		t.Fatal(err)
+	}
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace)
+	c := RoleBindingController{
+		name:              "DefaultRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		roleBindingsFunc:  GetRoleBindingsForControllerWithAdditional("DefaultRoleBindingController", additional),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:          record.NewFakeRecorder(10),
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+	if err := c.syncNamespace("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	created := map[string]*rbacv1.RoleBinding{}
+	for _, action := range fakeClient.Actions() {
+		createAction, ok := action.(clienttesting.CreateAction)
+		if !ok {
+			t.Fatalf("unexpected action %#v", action)
+		}
+		roleBinding := createAction.GetObject().(*rbacv1.RoleBinding)
+		created[roleBinding.Name] = roleBinding
+	}
+	for _, name := range []string{"system:image-pullers", "system:image-builders", "system:deployers", "ci-robot"} {
+		if _, ok := created[name]; !ok {
+			t.Errorf("expected %v to be created, got %v", name, created)
+		}
+	}
+
+	ciRobot := created["ci-robot"]
+	if ciRobot == nil {
+		t.FailNow()
+	}
+	expectedSubjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Namespace: "ci", Name: "robot"}, {Kind: rbacv1.ServiceAccountKind, Namespace: "foo", Name: "local"}}
+	if !equality.Semantic.DeepEqual(ciRobot.Subjects, expectedSubjects) {
+		t.Errorf("expected subjects %v, got %v", expectedSubjects, ciRobot.Subjects)
+	}
+	if ciRobot.RoleRef != additional[0].RoleRef {
+		t.Errorf("expected roleRef %v, got %v", additional[0].RoleRef, ciRobot.RoleRef)
+	}
+	if ciRobot.Labels[CreatedByLabel] != CreatedByLabelValue {
+		t.Errorf("expected %v label, got %v", CreatedByLabel, ciRobot.Labels)
+	}
+}
+
+func TestSyncCleanup(t *testing.T) {
+	createdBy := map[string]string{CreatedByLabel: CreatedByLabelValue}
+	namespaces := []*corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "generated"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "handmade"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "mixed"}},
+	}
+	roleBindings := []*rbacv1.RoleBinding{
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "generated", Name: "system:image-builders", Labels: createdBy}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "generated", Name: "system:deployers", Labels: createdBy}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "handmade", Name: "system:image-builders"}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "mixed", Name: "system:image-builders", Labels: createdBy}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "mixed", Name: "other", Labels: createdBy}},
+	}
+
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	objs := []runtime.Object{}
+	for _, obj := range namespaces {
+		objs = append(objs, obj)
+		namespaceIndexer.Add(obj)
+	}
+	for _, obj := range roleBindings {
+		objs = append(objs, obj)
+		roleBindingIndexer.Add(obj)
+	}
+	fakeClient := kubeclientfake.NewSimpleClientset(objs...)
+
+	c := RoleBindingController{
+		name:               "BuilderRoleBindingController",
+		roleBindingClient:  fakeClient.RbacV1(),
+		roleBindingsFunc:   GetRoleBindingsForController("BuilderRoleBindingController"),
+		roleBindingLister:  rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:    corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:           record.NewFakeRecorder(10),
+		createRateLimiter:  flowcontrol.NewFakeAlwaysRateLimiter(),
+		cleanup:            true,
+		cleanupRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+	for _, namespace := range namespaces {
+		if err := c.syncNamespace(namespace.Name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted := []string{}
+	for _, action := range fakeClient.Actions() {
+		deleteAction, ok := action.(clienttesting.DeleteAction)
+		if !ok {
+			t.Fatalf("unexpected action %#v", action)
+		}
+		deleted = append(deleted, deleteAction.GetNamespace()+"/"+deleteAction.GetName())
+	}
+	expected := []string{"generated/system:image-builders", "mixed/system:image-builders"}
+	if !equality.Semantic.DeepEqual(deleted, expected) {
+		t.Errorf("expected %v to be deleted, got %v", expected, deleted)
+	}
+}
+
+func TestGetCleanupRateLimiter(t *testing.T) {
+	if !(RoleBindingControllerOptions{}).GetCleanupRateLimiter().TryAccept() {
+		t.Errorf("expected unlimited rate limiter")
+	}
+	if qps := (RoleBindingControllerOptions{MaxCleanupDeletesPerMinute: 120}).GetCleanupRateLimiter().QPS(); qps != 2 {
+		t.Errorf("expected 2 qps, got %v", qps)
+	}
+}
+
+func TestSyncToleratesAlreadyExists(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	// the role binding exists on the server, but the informer has not observed it yet
+	raced := GetBuilderServiceAccountProjectRoleBindings("foo")
+
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace, &raced)
+
+	recorder := record.NewFakeRecorder(10)
+	c := RoleBindingController{
+		name:              "DefaultRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("DefaultRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		recorder:          recorder,
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+	if err := c.syncNamespace("foo"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	created := []string{}
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() != "create" {
+			continue
+		}
+		createAction := action.(clienttesting.CreateAction)
+		created = append(created, createAction.GetObject().(*rbacv1.RoleBinding).Name)
+	}
+	expected := []string{ImagePullerRoleBindingName, ImageBuilderRoleBindingName, DeployerRoleBindingName}
+	if !equality.Semantic.DeepEqual(created, expected) {
+		t.Errorf("expected create attempts for %v, got %v", expected, created)
+	}
+
+	for _, name := range []string{ImagePullerRoleBindingName, DeployerRoleBindingName} {
+		if _, err := fakeClient.RbacV1().RoleBindings("foo").Get(context.TODO(), name, metav1.GetOptions{}); err != nil {
+			t.Errorf("expected %v to be created: %v", name, err)
+		}
+	}
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.HasPrefix(event, corev1.EventTypeWarning) {
+			t.Errorf("unexpected warning event %q", event)
+		}
+	}
+}
+
+func TestProcessNextWorkItemBackoff(t *testing.T) {
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace)
+	fakeClient.PrependReactor("create", "rolebindings", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, kapierrors.NewInternalError(fmt.Errorf("webhook unavailable"))
+	})
+
+	options := RoleBindingControllerOptions{MaxRetryDelay: 10 * time.Millisecond, MaxRetries: 3}
+	recorder := record.NewFakeRecorder(100)
+	c := &RoleBindingController{
+		name:              "ImagePullerRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		namespaceClient:   fakeClient.CoreV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("ImagePullerRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		queue:             workqueue.NewNamedRateLimitingQueue(options.getQueueRateLimiter(), "test"),
+		recorder:          recorder,
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+		maxRetries:        options.MaxRetries,
+	}
+	c.syncHandler = c.syncNamespace
+	defer c.queue.ShutDown()
+
+	c.queue.Add("foo")
+	for i := 0; i < options.MaxRetries; i++ {
+		c.processNextWorkItem()
+		if requeues := c.queue.NumRequeues("foo"); requeues != i+1 {
+			t.Fatalf("expected %d requeues, got %d", i+1, requeues)
+		}
+	}
+
+	// the final failure drops the namespace from the queue
+	c.processNextWorkItem()
+	if requeues := c.queue.NumRequeues("foo"); requeues != 0 {
+		t.Errorf("expected namespace to be forgotten, got %d requeues", requeues)
+	}
+	if c.queue.Len() != 0 {
+		t.Errorf("expected empty queue, got %d items", c.queue.Len())
+	}
+
+	creates := 0
+	for _, action := range fakeClient.Actions() {
+		if action.GetVerb() == "create" {
+			creates++
+		}
+	}
+	if creates != options.MaxRetries+1 {
+		t.Errorf("expected %d create attempts, got %d", options.MaxRetries+1, creates)
+	}
+
+	gaveUp := false
+	for len(recorder.Events) > 0 {
+		if strings.HasPrefix(<-recorder.Events, "Warning ImagePullerRoleBindingControllerGaveUp Giving up on default role bindings after 3 retries") {
+			gaveUp = true
+		}
+	}
+	if !gaveUp {
+		t.Errorf("expected a terminal warning event")
+	}
+}
+
+func TestProcessNextWorkItemRecreateFailure(t *testing.T) {
+	deployers := GetDeployerServiceAccountProjectRoleBindings("foo")
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	existing := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "foo", Name: DeployerRoleBindingName, Annotations: map[string]string{ManagedRoleBindingAnnotation: "true"}},
+		RoleRef:    rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "admin"},
+		Subjects:   deployers.Subjects,
+	}
+	roleBindingIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	namespaceIndexer := cache.NewIndexer(controller.KeyFunc, cache.Indexers{})
+	roleBindingIndexer.Add(existing)
+	namespaceIndexer.Add(namespace)
+	fakeClient := kubeclientfake.NewSimpleClientset(namespace, existing)
+	failCreate := true
+	fakeClient.PrependReactor("create", "rolebindings", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		if !failCreate {
+			return false, nil, nil
+		}
+		failCreate = false
+		return true, nil, kapierrors.NewInternalError(fmt.Errorf("webhook unavailable"))
+	})
+
+	recorder := record.NewFakeRecorder(10)
+	c := &RoleBindingController{
+		name:              "DeployerRoleBindingController",
+		roleBindingClient: fakeClient.RbacV1(),
+		namespaceClient:   fakeClient.CoreV1(),
+		roleBindingsFunc:  GetRoleBindingsForController("DeployerRoleBindingController"),
+		roleBindingLister: rbaclisters.NewRoleBindingLister(roleBindingIndexer),
+		namespaceLister:   corelisters.NewNamespaceLister(namespaceIndexer),
+		queue:             workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+		recorder:          recorder,
+		createRateLimiter: flowcontrol.NewFakeAlwaysRateLimiter(),
+	}
+	c.syncHandler = c.syncNamespace
+	defer c.queue.ShutDown()
+
+	// the role binding is deleted but cannot be created again
+	c.queue.Add("foo")
+	c.processNextWorkItem()
+	if c.queue.Len() != 1 || c.queue.NumRequeues("foo") != 0 {
+		t.Fatalf("expected the namespace to be requeued without backoff, got %d items and %d requeues", c.queue.Len(), c.queue.NumRequeues("foo"))
+	}
+	if len(recorder.Events) != 1 {
+		t.Fatalf("expected one event, got %d", len(recorder.Events))
+	}
+	expected := "Warning DeployerRoleBindingControllerFailedRecreateRoleBinding Failed to recreate role binding system:deployers"
+	if event := <-recorder.Events; !strings.HasPrefix(event, expected) {
+		t.Errorf("expected event %q to start with %q", event, expected)
+	}
+
+	// the next sync creates the missing role binding
+	roleBindingIndexer.Delete(existing)
+	c.processNextWorkItem()
+	roleBinding, err := fakeClient.RbacV1().RoleBindings("foo").Get(context.TODO(), DeployerRoleBindingName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the role binding to be created, got %v", err)
+	}
+	if roleBinding.RoleRef != deployers.RoleRef {
+		t.Errorf("expected roleRef %v, got %v", deployers.RoleRef, roleBinding.RoleRef)
+	}
+	if c.queue.Len() != 0 {
+		t.Errorf("expected empty queue, got %d items", c.queue.Len())
+	}
+}
+
+func TestNamespaceResync(t *testing.T) {
+	c := &RoleBindingController{
+		queue:                    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "test"),
+		skippedNamespacePrefixes: DefaultSkippedNamespacePrefixes,
+	}
+	defer c.queue.ShutDown()
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", ResourceVersion: "1"}}
+	updated := namespace.DeepCopy()
+	updated.ResourceVersion = "2"
+	c.namespaceResynced(namespace, updated)
+	if c.queue.Len() != 0 {
+		t.Errorf("expected namespace updates to be ignored, got %d items", c.queue.Len())
+	}
+
+	c.namespaceResynced(namespace, namespace.DeepCopy())
+	if c.queue.Len() != 1 {
+		t.Errorf("expected the resynced namespace to be queued, got %d items", c.queue.Len())
+	}
+
+	platform := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "openshift-foo", ResourceVersion: "1"}}
+	c.namespaceResynced(platform, platform.DeepCopy())
+	if c.queue.Len() != 1 {
+		t.Errorf("expected skipped namespaces not to be queued, got %d items", c.queue.Len())
+	}
+}
+
+func TestRunWithMultipleWorkers(t *testing.T) {
+	objs := []runtime.Object{}
+	for i := 0; i < 20; i++ {
+		objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("ns-%d", i)}})
+	}
+	fakeClient := kubeclientfake.NewSimpleClientset(objs...)
+	informerFactory := informers.NewSharedInformerFactory(fakeClient, 0)
+	c := NewRoleBindingsController(
+		informerFactory.Rbac().V1().RoleBindings(),
+		informerFactory.Core().V1().Namespaces(),
+		fakeClient,
+		DefaultRoleBindingControllerName,
+		RoleBindingControllerOptions{},
+	)
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	go c.Run(5, stopCh)
+
+	countCreates := func() map[string]int {
+		creates := map[string]int{}
+		for _, action := range fakeClient.Actions() {
+			createAction, ok := action.(clienttesting.CreateAction)
+			if !ok || action.GetResource().Resource != "rolebindings" {
+				continue
+			}
+			roleBinding := createAction.GetObject().(*rbacv1.RoleBinding)
+			creates[roleBinding.Namespace+"/"+roleBinding.Name]++
+		}
+		return creates
+	}
+
+	expected := len(objs) * len(Names(DefaultRoleBindingControllerName))
+	err := wait.PollImmediate(10*time.Millisecond, wait.ForeverTestTimeout, func() (bool, error) {
+		return len(countCreates()) == expected, nil
+	})
+	if err != nil {
+		t.Fatalf("expected %d role bindings to be created, got %d", expected, len(countCreates()))
+	}
+	for key, count := range countCreates() {
+		if count != 1 {
+			t.Errorf("expected %v to be created once, got %d", key, count)
+		}
+	}
+}
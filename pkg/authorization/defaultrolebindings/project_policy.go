@@ -1,9 +1,12 @@
 package defaultrolebindings
 
 import (
+	"fmt"
+
 	"github.com/openshift/api/annotations"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apiserver/pkg/authentication/serviceaccount"
 )
@@ -21,11 +24,34 @@ const (
 
 	BuilderServiceAccountName  = "builder"
 	DeployerServiceAccountName = "deployer"
+
+	// CreatedByLabel is set on every role binding generated by the default rolebindings controllers.
+	CreatedByLabel      = "rbac.openshift.io/created-by"
+	CreatedByLabelValue = "openshift-controller-manager"
+
+	// CreatedByControllerAnnotation records the name of the controller that generated the role binding.
+	CreatedByControllerAnnotation = "rbac.openshift.io/created-by-controller"
+	// CreatedAtAnnotation records when the controller created the role binding, in RFC3339 format.
+	CreatedAtAnnotation = "rbac.openshift.io/created-at"
+
+	DefaultRoleBindingControllerName     = "DefaultRoleBindingController"
+	BuilderRoleBindingControllerName     = "BuilderRoleBindingController"
+	DeployerRoleBindingControllerName    = "DeployerRoleBindingController"
+	ImagePullerRoleBindingControllerName = "ImagePullerRoleBindingController"
 )
 
 type projectRoleBindings func(namespace string) []rbacv1.RoleBinding
 type serviceAccountRoleBinding func(namespace string) rbacv1.RoleBinding
 
+// AdditionalRoleBinding describes a role binding that is created in every namespace next to the
+// built-in default role bindings. ServiceAccount subjects without a namespace refer to the service
+// account of that name in the namespace being synced.
+type AdditionalRoleBinding struct {
+	Name     string           `json:"name"`
+	RoleRef  rbacv1.RoleRef   `json:"roleRef"`
+	Subjects []rbacv1.Subject `json:"subjects"`
+}
+
 // GetImagePullerProjectRoleBindings generates a role binding that allows all pods to pull ImageStream images associated with given namespace.
 // These should only be created if the "ImageRegistry" capability is enabled on the cluster.
 func GetImagePullerProjectRoleBindings(namespace string) rbacv1.RoleBinding {
@@ -63,22 +89,106 @@ func composeRoleBindings(roleBindings ...serviceAccountRoleBinding) projectRoleB
 	}
 }
 
-// GetRoleBindingsForController returns the appropriate generator function for the
-// given named controller that will reconcile role bindings in a namespace.
-func GetRoleBindingsForController(controller string) projectRoleBindings {
-	switch controller {
-	case "BuilderRoleBindingController":
-		return composeRoleBindings(GetBuilderServiceAccountProjectRoleBindings)
-	case "DeployerRoleBindingController":
-		return composeRoleBindings(GetDeployerServiceAccountProjectRoleBindings)
-	case "ImagePullerRoleBindingController":
-		return composeRoleBindings(GetImagePullerProjectRoleBindings)
+// BindingsFor returns the role bindings the named controller creates in the given namespace.
+// Unknown controller names get the bindings of the DefaultRoleBindingController. Every binding
+// carries the CreatedByLabel and the CreatedByControllerAnnotation; the controller only adds the
+// CreatedAtAnnotation when it creates the binding. The result is stable and meant to be consumed
+// by components that pre-create or validate these role bindings.
+func BindingsFor(controllerName, namespace string) []rbacv1.RoleBinding {
+	var roleBindings projectRoleBindings
+	switch controllerName {
+	case BuilderRoleBindingControllerName:
+		roleBindings = composeRoleBindings(GetBuilderServiceAccountProjectRoleBindings)
+	case DeployerRoleBindingControllerName:
+		roleBindings = composeRoleBindings(GetDeployerServiceAccountProjectRoleBindings)
+	case ImagePullerRoleBindingControllerName:
+		roleBindings = composeRoleBindings(GetImagePullerProjectRoleBindings)
 	default:
-		return composeRoleBindings(GetImagePullerProjectRoleBindings,
+		roleBindings = composeRoleBindings(GetImagePullerProjectRoleBindings,
 			GetBuilderServiceAccountProjectRoleBindings,
 			GetDeployerServiceAccountProjectRoleBindings,
 		)
 	}
+
+	bindings := roleBindings(namespace)
+	for i := range bindings {
+		bindings[i].Annotations[CreatedByControllerAnnotation] = controllerName
+	}
+	return bindings
+}
+
+// Names returns the names of the role bindings the named controller creates in every namespace,
+// in the order BindingsFor returns them.
+func Names(controllerName string) []string {
+	names := []string{}
+	for _, roleBinding := range BindingsFor(controllerName, "default") {
+		names = append(names, roleBinding.Name)
+	}
+	return names
+}
+
+// GetRoleBindingsForController returns the appropriate generator function for the
+// given named controller that will reconcile role bindings in a namespace.
+func GetRoleBindingsForController(controller string) projectRoleBindings {
+	return func(namespace string) []rbacv1.RoleBinding {
+		return BindingsFor(controller, namespace)
+	}
+}
+
+// GetRoleBindingsForControllerWithAdditional returns the generator function of the named controller,
+// extended with the given additional role bindings.
+func GetRoleBindingsForControllerWithAdditional(controller string, additional []AdditionalRoleBinding) projectRoleBindings {
+	roleBindings := GetRoleBindingsForController(controller)
+	if len(additional) == 0 {
+		return roleBindings
+	}
+
+	return func(namespace string) []rbacv1.RoleBinding {
+		bindings := roleBindings(namespace)
+		for _, a := range additional {
+			binding := newOriginRoleBinding(a.Name, a.RoleRef, namespace)
+			for _, subject := range a.Subjects {
+				if subject.Kind == rbacv1.ServiceAccountKind && len(subject.Namespace) == 0 {
+					subject.Namespace = namespace
+				}
+				binding.Subjects = append(binding.Subjects, subject)
+			}
+			binding.Annotations[CreatedByControllerAnnotation] = controller
+			bindings = append(bindings, binding)
+		}
+		return bindings
+	}
+}
+
+// ValidateAdditionalRoleBindings checks that the additional role bindings are well formed and that
+// their names collide neither with each other nor with the built-in role bindings of the controller.
+func ValidateAdditionalRoleBindings(controller string, additional []AdditionalRoleBinding) error {
+	names := sets.New[string](Names(controller)...)
+	errs := []error{}
+	for i, a := range additional {
+		if len(a.Name) == 0 {
+			errs = append(errs, fmt.Errorf("additional role binding %d: name is required", i))
+			continue
+		}
+		if names.Has(a.Name) {
+			errs = append(errs, fmt.Errorf("additional role binding %q: name collides with another role binding of %s", a.Name, controller))
+		}
+		names.Insert(a.Name)
+
+		if a.RoleRef.APIGroup != rbacv1.GroupName {
+			errs = append(errs, fmt.Errorf("additional role binding %q: roleRef apiGroup must be %q", a.Name, rbacv1.GroupName))
+		}
+		if a.RoleRef.Kind != "ClusterRole" && a.RoleRef.Kind != "Role" {
+			errs = append(errs, fmt.Errorf("additional role binding %q: roleRef kind must be ClusterRole or Role, got %q", a.Name, a.RoleRef.Kind))
+		}
+		if len(a.RoleRef.Name) == 0 {
+			errs = append(errs, fmt.Errorf("additional role binding %q: roleRef name is required", a.Name))
+		}
+		if len(a.Subjects) == 0 {
+			errs = append(errs, fmt.Errorf("additional role binding %q: at least one subject is required", a.Name))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
 }
 
 func GetBootstrapServiceAccountProjectRoleBindingNames(roleBindings projectRoleBindings) sets.Set[string] {
@@ -92,11 +202,24 @@ func GetBootstrapServiceAccountProjectRoleBindingNames(roleBindings projectRoleB
 	return names
 }
 
+func newOriginRoleBinding(bindingName string, roleRef rbacv1.RoleRef, namespace string) rbacv1.RoleBinding {
+	return rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        bindingName,
+			Namespace:   namespace,
+			Labels:      map[string]string{CreatedByLabel: CreatedByLabelValue},
+			Annotations: map[string]string{},
+		},
+		RoleRef: roleRef,
+	}
+}
+
 func newOriginRoleBindingForClusterRoleWithGroup(bindingName, roleName, namespace, group string) rbacv1.RoleBinding {
 	return rbacv1.RoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        bindingName,
 			Namespace:   namespace,
+			Labels:      map[string]string{CreatedByLabel: CreatedByLabelValue},
 			Annotations: map[string]string{},
 		},
 		RoleRef: rbacv1.RoleRef{
@@ -115,6 +238,7 @@ func newOriginRoleBindingForClusterRoleWithSA(bindingName, roleName, namespace,
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        bindingName,
 			Namespace:   namespace,
+			Labels:      map[string]string{CreatedByLabel: CreatedByLabelValue},
 			Annotations: map[string]string{},
 		},
 		RoleRef: rbacv1.RoleRef{
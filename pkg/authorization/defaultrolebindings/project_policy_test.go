@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 )
 
@@ -45,3 +47,139 @@ func TestRoleBindingsForController(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAdditionalRoleBindings(t *testing.T) {
+	editRef := rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "edit"}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "robot"}}
+
+	tests := map[string]struct {
+		additional  []AdditionalRoleBinding
+		expectedErr string
+	}{
+		"valid": {
+			additional: []AdditionalRoleBinding{{Name: "ci-robot", RoleRef: editRef, Subjects: subjects}},
+		},
+		"collides-with-builtin": {
+			additional:  []AdditionalRoleBinding{{Name: "system:deployers", RoleRef: editRef, Subjects: subjects}},
+			expectedErr: `additional role binding "system:deployers": name collides with another role binding of DefaultRoleBindingController`,
+		},
+		"collides-with-additional": {
+			additional: []AdditionalRoleBinding{
+				{Name: "ci-robot", RoleRef: editRef, Subjects: subjects},
+				{Name: "ci-robot", RoleRef: editRef, Subjects: subjects},
+			},
+			expectedErr: `additional role binding "ci-robot": name collides with another role binding of DefaultRoleBindingController`,
+		},
+		"bad-roleref-kind": {
+			additional:  []AdditionalRoleBinding{{Name: "ci-robot", RoleRef: rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "User", Name: "edit"}, Subjects: subjects}},
+			expectedErr: `additional role binding "ci-robot": roleRef kind must be ClusterRole or Role, got "User"`,
+		},
+		"missing-subjects": {
+			additional:  []AdditionalRoleBinding{{Name: "ci-robot", RoleRef: editRef}},
+			expectedErr: `additional role binding "ci-robot": at least one subject is required`,
+		},
+	}
+
+	for tName, tCase := range tests {
+		t.Run(tName, func(t *testing.T) {
+			err := ValidateAdditionalRoleBindings("DefaultRoleBindingController", tCase.additional)
+			if len(tCase.expectedErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tCase.expectedErr {
+				t.Fatalf("expected error %q, got %v", tCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestBindingsFor(t *testing.T) {
+	labels := map[string]string{"rbac.openshift.io/created-by": "openshift-controller-manager"}
+	imagePullers := rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "system:image-pullers",
+			Namespace: "foo",
+			Labels:    labels,
+			Annotations: map[string]string{
+				"openshift.io/description":                "Allows all pods in this namespace to pull images from this namespace.  It is auto-managed by a controller; remove subjects to disable.",
+				"rbac.openshift.io/created-by-controller": "CONTROLLER",
+			},
+		},
+		RoleRef:  rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "system:image-puller"},
+		Subjects: []rbacv1.Subject{{Kind: "Group", APIGroup: "rbac.authorization.k8s.io", Name: "system:serviceaccounts:foo"}},
+	}
+	imageBuilders := rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "system:image-builders",
+			Namespace: "foo",
+			Labels:    labels,
+			Annotations: map[string]string{
+				"openshift.io/description":                "Allows builds in this namespace to push images to this namespace.  It is auto-managed by a controller; remove subjects to disable.",
+				"rbac.openshift.io/created-by-controller": "CONTROLLER",
+			},
+		},
+		RoleRef:  rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "system:image-builder"},
+		Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Namespace: "foo", Name: "builder"}},
+	}
+	deployers := rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "system:deployers",
+			Namespace: "foo",
+			Labels:    labels,
+			Annotations: map[string]string{
+				"openshift.io/description":                "Allows deploymentconfigs in this namespace to rollout pods in this namespace.  It is auto-managed by a controller; remove subjects to disable.",
+				"rbac.openshift.io/created-by-controller": "CONTROLLER",
+			},
+		},
+		RoleRef:  rbacv1.RoleRef{APIGroup: "rbac.authorization.k8s.io", Kind: "ClusterRole", Name: "system:deployer"},
+		Subjects: []rbacv1.Subject{{Kind: "ServiceAccount", Namespace: "foo", Name: "deployer"}},
+	}
+
+	tests := map[string]struct {
+		controller    string
+		expected      []rbacv1.RoleBinding
+		expectedNames []string
+	}{
+		"default": {
+			controller:    DefaultRoleBindingControllerName,
+			expected:      []rbacv1.RoleBinding{imagePullers, imageBuilders, deployers},
+			expectedNames: []string{"system:image-pullers", "system:image-builders", "system:deployers"},
+		},
+		"builder": {
+			controller:    BuilderRoleBindingControllerName,
+			expected:      []rbacv1.RoleBinding{imageBuilders},
+			expectedNames: []string{"system:image-builders"},
+		},
+		"deployer": {
+			controller:    DeployerRoleBindingControllerName,
+			expected:      []rbacv1.RoleBinding{deployers},
+			expectedNames: []string{"system:deployers"},
+		},
+		"image-puller": {
+			controller:    ImagePullerRoleBindingControllerName,
+			expected:      []rbacv1.RoleBinding{imagePullers},
+			expectedNames: []string{"system:image-pullers"},
+		},
+	}
+
+	for tName, tCase := range tests {
+		t.Run(tName, func(t *testing.T) {
+			expected := []rbacv1.RoleBinding{}
+			for _, roleBinding := range tCase.expected {
+				roleBinding = *roleBinding.DeepCopy()
+				roleBinding.Annotations["rbac.openshift.io/created-by-controller"] = tCase.controller
+				expected = append(expected, roleBinding)
+			}
+
+			if diff := cmp.Diff(expected, BindingsFor(tCase.controller, "foo")); len(diff) > 0 {
+				t.Errorf("unexpected role bindings (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tCase.expectedNames, Names(tCase.controller)); len(diff) > 0 {
+				t.Errorf("unexpected role binding names (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,2 @@
+// Package metrics contains code for default rolebindings controller metrics
+package metrics
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/component-base/metrics"
+	"k8s.io/component-base/metrics/legacyregistry"
+)
+
+const (
+	namespace = "openshift"
+	subsystem = "default_rolebindings"
+)
+
+var (
+	// CreatedTotal counts the role bindings created by each of the default rolebindings controllers.
+	CreatedTotal = metrics.NewCounterVec(&metrics.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "created_total",
+		Help:      "Total count of role bindings created by the default rolebindings controllers",
+	}, []string{"controller", "rolebinding"})
+
+	// SyncErrorsTotal counts the namespace syncs that returned an error.
+	SyncErrorsTotal = metrics.NewCounterVec(&metrics.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "sync_errors_total",
+		Help:      "Total count of failed namespace syncs in the default rolebindings controllers",
+	}, []string{"controller"})
+
+	// SyncDuration observes how long a single namespace sync takes.
+	SyncDuration = metrics.NewHistogramVec(&metrics.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of namespace syncs in the default rolebindings controllers",
+		Buckets:   metrics.DefBuckets,
+	}, []string{"controller"})
+
+	// MissingRoleBindings reports default role bindings that a controller in dry-run mode would
+	// have created.
+	MissingRoleBindings = metrics.NewGaugeVec(&metrics.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "missing",
+		Help:      "Default role bindings missing from a namespace, reported by controllers in dry-run mode",
+	}, []string{"namespace", "rolebinding"})
+
+	registerOnce sync.Once
+)
+
+// Register registers the default rolebindings controller metrics with the legacy registry.
+// It is safe to call it more than once.
+func Register() {
+	registerOnce.Do(func() {
+		legacyregistry.MustRegister(CreatedTotal, SyncErrorsTotal, SyncDuration, MissingRoleBindings)
+	})
+}
+
+// RecordCreated increments the count of role bindings created by the named controller.
+func RecordCreated(controller, roleBinding string) {
+	CreatedTotal.WithLabelValues(controller, roleBinding).Inc()
+}
+
+// RecordSync records the duration of a namespace sync and whether it failed.
+func RecordSync(controller string, start time.Time, err error) {
+	SyncDuration.WithLabelValues(controller).Observe(time.Since(start).Seconds())
+	if err != nil {
+		SyncErrorsTotal.WithLabelValues(controller).Inc()
+	}
+}
+
+// RecordMissing reports whether the role binding is missing from the namespace.
+func RecordMissing(namespace, roleBinding string, missing bool) {
+	if missing {
+		MissingRoleBindings.WithLabelValues(namespace, roleBinding).Set(1)
+		return
+	}
+	MissingRoleBindings.Delete(map[string]string{"namespace": namespace, "rolebinding": roleBinding})
+}
@@ -1,5 +1,9 @@
 package buildutil
 
+import (
+	buildv1 "github.com/openshift/api/build/v1"
+)
+
 const (
 	// BuildStartedEventReason is the reason associated with the event registered when a build is started (pod is created).
 	BuildStartedEventReason = "BuildStarted"
@@ -17,4 +21,367 @@ const (
 	BuildCancelledEventReason = "BuildCancelled"
 	// BuildCancelledEventMessage is the message associated with the event registered when build is cancelled.
 	BuildCancelledEventMessage = "Build %s/%s has been cancelled"
+	// BuildRetriedEventReason is the reason associated with the event registered when a failed build is retried.
+	BuildRetriedEventReason = "BuildRetried"
+	// BuildRetriedEventMessage is the message associated with the event registered when a failed build is retried.
+	BuildRetriedEventMessage = "Build %s/%s failed with reason %s, retrying as build %s (attempt %d of %d)"
+	// BuildPodRescheduledEventReason is the reason associated with the event registered when a new
+	// build pod is created for a running build whose build pod was lost.
+	BuildPodRescheduledEventReason = "BuildPodRescheduled"
+	// BuildPodRescheduledEventMessage is the message associated with the event registered when a
+	// new build pod is created for a running build whose build pod was lost.
+	BuildPodRescheduledEventMessage = "The pod of build %s/%s was deleted before the build completed, creating a new build pod (attempt %d of %d)"
+	// BuildTrustedCARefreshedEventReason is the reason associated with the event registered when
+	// the proxy certificate authority configMap of a build whose pod did not start yet is refreshed.
+	BuildTrustedCARefreshedEventReason = "TrustedCARefreshed"
+	// BuildTrustedCARefreshedEventMessage is the message associated with the event registered when
+	// the proxy certificate authority configMap of a build whose pod did not start yet is refreshed.
+	BuildTrustedCARefreshedEventMessage = "Refreshed the proxy certificate authority configMap %s of build %s/%s with the changed trusted CA bundle of the cluster"
+	// BuildInvalidLogLevelEventReason is the reason associated with the event registered when the
+	// log level annotation of a build is not a valid log level.
+	BuildInvalidLogLevelEventReason = "InvalidLogLevel"
+	// BuildInvalidLogLevelEventMessage is the message associated with the event registered when the
+	// log level annotation of a build is not a valid log level.
+	BuildInvalidLogLevelEventMessage = "Ignoring the log level %q of build %s/%s, which must be an integer from %d to %d"
+	// BuildInvalidImageLabelEventReason is the reason associated with the event registered when an
+	// image label template of the build defaults has an invalid name.
+	BuildInvalidImageLabelEventReason = "InvalidImageLabel"
+	// BuildInvalidImageLabelEventMessage is the message associated with the event registered when
+	// an image label template of the build defaults has an invalid name.
+	BuildInvalidImageLabelEventMessage = "Ignoring the default image label %q of build %s/%s with an invalid name: %s"
+	// BuildInvalidPostCommitTimeoutEventReason is the reason associated with the event registered
+	// when the post-commit timeout annotation of a build is invalid.
+	BuildInvalidPostCommitTimeoutEventReason = "InvalidPostCommitTimeout"
+	// BuildInvalidPostCommitTimeoutEventMessage is the message associated with the event
+	// registered when the post-commit timeout annotation of a build is invalid, or its post-commit
+	// hook only passes arguments to the image entrypoint.
+	BuildInvalidPostCommitTimeoutEventMessage = "Ignoring the post-commit timeout %q of build %s/%s, which must be a positive number of seconds of a hook with a script or a command"
+	// BuildPodOrphanedEventReason is the reason associated with the event registered when a build
+	// pod whose build was deleted is deleted.
+	BuildPodOrphanedEventReason = "OrphanedBuildPodDeleted"
+	// BuildPodOrphanedEventMessage is the message associated with the event registered when a
+	// build pod whose build was deleted is deleted.
+	BuildPodOrphanedEventMessage = "Deleted build pod %s/%s of deleted build %s/%s"
+)
+
+const (
+	// BuildRetentionAnnotation can be set on a BuildConfig to the maximum age, as a duration such as
+	// "720h", of its completed builds. Older builds are pruned in addition to the ones exceeding the
+	// history limits. It overrides the cluster default, and "0" disables age based pruning.
+	BuildRetentionAnnotation = "build.openshift.io/build-retention"
+
+	// SkipInitialBuildAnnotation can be set to "true" on a BuildConfig with a ConfigChange trigger
+	// so that creating the build config does not start a build. Its first build is started by the
+	// first change of its spec instead.
+	SkipInitialBuildAnnotation = "build.openshift.io/skip-initial-build"
+
+	// InitialBuildSkippedAnnotation is set by the build config change controller to the generation
+	// of a BuildConfig whose initial build it skipped. A build is started once the generation of the
+	// build config is past it.
+	InitialBuildSkippedAnnotation = "build.openshift.io/initial-build-skipped"
+
+	// BuildConfigLastTriggerFailureAnnotation is set by the trigger controllers to the JSON encoded
+	// TriggerFailure of the last trigger of a BuildConfig that failed to instantiate a build. It is
+	// removed once a trigger instantiates a build. The BuildConfig status has no field to record
+	// it in.
+	BuildConfigLastTriggerFailureAnnotation = "build.openshift.io/last-trigger-failure"
+
+	// TriggerFailureReasonImagePullAccessDenied is the reason of the trigger failure of an image
+	// change trigger whose image stream tag of another namespace the build service account of the
+	// BuildConfig is not allowed to pull.
+	TriggerFailureReasonImagePullAccessDenied = "ImagePullAccessDenied"
+
+	// CancelledBuildsHistoryLimitAnnotation can be set on a BuildConfig to the number of its
+	// cancelled builds to keep. Cancelled builds are then pruned separately from the failed builds,
+	// which otherwise share the failedBuildsHistoryLimit with them.
+	CancelledBuildsHistoryLimitAnnotation = "build.openshift.io/cancelled-builds-history-limit"
+
+	// MaxConcurrentBuildsAnnotation can be set on a namespace to the maximum number of its builds
+	// that may have a build pod at the same time. It overrides the cluster default, and "0" removes
+	// the limit for the namespace.
+	MaxConcurrentBuildsAnnotation = "build.openshift.io/max-concurrent-builds"
+
+	// BuildPriorityClassNameAnnotation can be set on a BuildConfig or a Build to the name of the
+	// PriorityClass of the build pod. It takes precedence over the build defaults and is replaced
+	// by the build overrides.
+	BuildPriorityClassNameAnnotation = "build.openshift.io/priority-class-name"
+
+	// BuildLogLevelAnnotation can be set on a Build to the log level of its build pod, from
+	// MinBuildLogLevel to MaxBuildLogLevel. It replaces the BuildLogLevelEnvVar of the build and
+	// the build defaults, so that a single build can be debugged without editing its BuildConfig.
+	BuildLogLevelAnnotation = "build.openshift.io/log-level"
+
+	// BuildPostCommitTimeoutAnnotation can be set on a Build to the number of seconds its
+	// post-commit hook may run. The hook is wrapped in a script that runs it with timeout, which
+	// needs /bin/sh and timeout in the output image. Builds whose hook runs longer fail with the
+	// PostCommitHookTimeout reason instead of running until their completion deadline.
+	BuildPostCommitTimeoutAnnotation = "build.openshift.io/post-commit-timeout-seconds"
+
+	// BuildRetryPolicyAnnotation can be set on a BuildConfig or a Build to a JSON encoded retry
+	// policy, such as {"maxRetries":3,"backoffSeconds":60,"retryableReasons":["BuildPodEvicted"]}.
+	// Builds failing with a retryable reason are cloned until maxRetries retries were made.
+	BuildRetryPolicyAnnotation = "build.openshift.io/retry-policy"
+	// BuildRetryOfAnnotation is set on retry builds to the name of the build that started the
+	// retry chain.
+	BuildRetryOfAnnotation = "build.openshift.io/retry-of"
+	// BuildRetryAttemptAnnotation is set on retry builds to their attempt number, starting at 1
+	// for the first retry of the original build.
+	BuildRetryAttemptAnnotation = "build.openshift.io/retry-attempt"
+	// BuildRetryStartedAnnotation is set on a failed build to its own name before it is cloned as
+	// its retry. The retry build carries it with the name of the cloned build until its lineage is
+	// recorded, so that a retry whose lineage could not be recorded is found instead of cloned again.
+	BuildRetryStartedAnnotation = "build.openshift.io/retry-started"
+
+	// BuildRescheduleLostPodAnnotation can be set on a Build to "true" or "false" to opt in to or
+	// out of getting a new build pod when its build pod is deleted while it runs, such as when its
+	// node fails. The build defaults decide for builds without the annotation.
+	BuildRescheduleLostPodAnnotation = "build.openshift.io/reschedule-lost-pod"
+	// BuildPodRescheduleAttemptAnnotation is set on builds to the number of build pods created for
+	// them after their build pod was lost.
+	BuildPodRescheduleAttemptAnnotation = "build.openshift.io/pod-reschedule-attempt"
+
+	// BuildCancelRequestedAnnotation is set on cancelled builds to the RFC 3339 time their
+	// cancellation was requested, when their build pod was signalled to terminate. The completion
+	// timestamp of the build records when the cancellation completed.
+	BuildCancelRequestedAnnotation = "build.openshift.io/cancel-requested-timestamp"
+	// BuildPodCancelledAnnotation is set to "true" on the build pod of a cancelled build before it
+	// is deleted, so that the builder trapping SIGTERM knows to clean up a cancelled build.
+	BuildPodCancelledAnnotation = "build.openshift.io/cancelled"
+
+	// BuildConfigKeepRunningBuildsAnnotation can be set to "true" on a BuildConfig to let its running
+	// builds finish when it is deleted. Its new and pending builds are cancelled either way.
+	BuildConfigKeepRunningBuildsAnnotation = "build.openshift.io/keep-running-builds-on-delete"
+
+	// BuildPushedImageReferenceAnnotation is set on completed builds to the fully qualified
+	// reference by digest of the image they pushed, also recorded in status.output.to.imageDigest.
+	BuildPushedImageReferenceAnnotation = "build.openshift.io/pushed-image-reference"
+
+	// BuildConfigPendingImageChangesAnnotation is set on BuildConfigs with paused image change
+	// triggers to a JSON map from the image stream tags of the triggers to the newest images they
+	// have not built yet. A paused trigger starts a single build for that image when it is unpaused.
+	BuildConfigPendingImageChangesAnnotation = "build.openshift.io/pending-image-changes"
+
+	// BuildConfigImageChangeDebounceAnnotation can be set on BuildConfigs to the duration, such as
+	// "30s", that the image change trigger controller waits after an image of their image change
+	// triggers changes before it starts a build for the newest images. Changes within the duration
+	// result in a single build. "0s" starts a build for every change.
+	BuildConfigImageChangeDebounceAnnotation = "build.openshift.io/image-change-debounce"
+
+	// BuildConfigStrategyUnsupportedAnnotation is set on BuildConfigs of the removed JenkinsPipeline
+	// build strategy, whose builds fail, to the message that explains why. The BuildConfig status
+	// has no conditions to record it in.
+	BuildConfigStrategyUnsupportedAnnotation = "build.openshift.io/strategy-unsupported"
+
+	// BuildCacheAnnotation can be set on a BuildConfig with a source strategy to a JSON encoded
+	// persistent build cache, either {"claimName":"maven-cache"} to mount an existing claim or
+	// {"volumeClaimTemplate":{"spec":{...}}} to mount a claim created for the BuildConfig.
+	BuildCacheAnnotation = "build.openshift.io/build-cache"
+
+	// BuildOutputAdditionalTagsAnnotation can be set on a BuildConfig or a Build with an
+	// ImageStreamTag output to a comma separated list of tags, such as "stable,v1.2", of the output
+	// image stream that the output image is tagged into once the build completed.
+	BuildOutputAdditionalTagsAnnotation = "build.openshift.io/additional-tags"
+	// BuildAdditionalTagReferencesAnnotation is set on completed builds with additional tags to a
+	// JSON map from each additional tag to the reference by digest of the image it was tagged with.
+	BuildAdditionalTagReferencesAnnotation = "build.openshift.io/additional-tag-references"
+
+	// BuildStrippedEnvAnnotation lists the comma separated names of the environment variables the
+	// build overrides stripped from the environment of the custom builder image of the build.
+	BuildStrippedEnvAnnotation = "build.openshift.io/stripped-env"
+
+	// TargetArchitectureAnnotation can be set on the output ImageStream of builds to the
+	// architecture, such as "arm64", of the images pushed to it. The build pods of builds without a
+	// kubernetes.io/arch node selector are then scheduled to nodes of that architecture.
+	TargetArchitectureAnnotation = "image.openshift.io/target-architecture"
+
+	// BuildCommitAuthorAnnotation, BuildCommitMessageAnnotation, BuildCommitIDAnnotation and
+	// BuildCommitRefAnnotation are set on builds triggered by a webhook to the author, the message,
+	// the id and the ref of the commit the webhook reported, as recorded in the revision of the
+	// webhook cause of the build. The message is truncated to MaxCommitMessageAnnotationLength.
+	BuildCommitAuthorAnnotation  = "build.openshift.io/commit.author"
+	BuildCommitMessageAnnotation = "build.openshift.io/commit.message"
+	BuildCommitIDAnnotation      = "build.openshift.io/commit.id"
+	BuildCommitRefAnnotation     = "build.openshift.io/commit.ref"
+
+	// MaxCommitMessageAnnotationLength is the maximum length in bytes of the commit message
+	// annotation of builds.
+	MaxCommitMessageAnnotationLength = 1024
+)
+
+const (
+	// BuildConditionQueued is the condition set on new builds that wait for other builds of their
+	// namespace to finish before their build pod is created.
+	BuildConditionQueued buildv1.BuildConditionType = "Queued"
+	// BuildQueuedReason is the reason of the queued condition while a build is held back by the
+	// concurrent build limit of its namespace.
+	BuildQueuedReason = "ConcurrentBuildLimitReached"
+	// BuildAdmittedReason is the reason of the queued condition once a queued build is admitted.
+	BuildAdmittedReason = "BuildAdmitted"
+	// BuildCacheInUseReason is the reason of the queued condition while a build waits for another
+	// build of its BuildConfig to release their ReadWriteOnce build cache claim.
+	BuildCacheInUseReason = "BuildCacheInUse"
+
+	// BuildConditionWaitingForCapacity is set to true on new builds that wait for the build pods of
+	// the cluster to fall below the build pod limit of the build controller, and to false once
+	// their build pod is created.
+	BuildConditionWaitingForCapacity buildv1.BuildConditionType = "WaitingForCapacity"
+	// BuildPodLimitReachedReason is the reason of the waiting for capacity condition while the
+	// cluster build pod limit holds the build back.
+	BuildPodLimitReachedReason = "BuildPodLimitReached"
+	// BuildCapacityAvailableReason is the reason of the waiting for capacity condition once the
+	// build is admitted under the cluster build pod limit.
+	BuildCapacityAvailableReason = "CapacityAvailable"
+
+	// BuildConditionImageDigestRecorded is set to false on completed docker and source builds whose
+	// pushed image digest could not be determined.
+	BuildConditionImageDigestRecorded buildv1.BuildConditionType = "ImageDigestRecorded"
+	// ImageDigestNotReportedReason is the reason of the image digest condition when the builder
+	// did not report the digest of the pushed image.
+	ImageDigestNotReportedReason = "ImageDigestNotReported"
+	// InvalidImageDigestReason is the reason of the image digest condition when the reported
+	// digest or the output image reference is malformed.
+	InvalidImageDigestReason = "InvalidImageDigest"
+
+	// BuildConditionAdditionalTagsTagged is set to false on completed builds whose output image
+	// could not be tagged into all of their additional tags, and to true once a retry tagged them.
+	BuildConditionAdditionalTagsTagged buildv1.BuildConditionType = "AdditionalTagsTagged"
+	// AdditionalTagsTagFailedReason is the reason of the additional tags condition when the tags
+	// failed permanently. Its message lists the tags that could not be tagged and why.
+	AdditionalTagsTagFailedReason = "AdditionalTagsTagFailed"
+	// AdditionalTagsTagRetryingReason is the reason of the additional tags condition when some tags
+	// failed with errors that may go away. The build controller retries them with backoff.
+	AdditionalTagsTagRetryingReason = "AdditionalTagsTagRetrying"
+	// AdditionalTagsTaggedReason is the reason of the additional tags condition once a retry tagged
+	// all the additional tags.
+	AdditionalTagsTaggedReason = "AdditionalTagsTagged"
+
+	// BuildConditionSourceSecretSelected is set on git builds without a source secret that the
+	// build controller selected one for, by matching the source secret match URI annotations of the
+	// secrets of their namespace against their git URI.
+	BuildConditionSourceSecretSelected buildv1.BuildConditionType = "SourceSecretSelected"
+	// SourceSecretMatchedURIReason is the reason of the source secret selected condition.
+	SourceSecretMatchedURIReason = "MatchedSourceURI"
+
+	// BuildConditionWaitingForImageImport is set to true on new builds whose strategy image stream
+	// tag has a scheduled import policy and was not imported yet, and to false once it resolved.
+	BuildConditionWaitingForImageImport buildv1.BuildConditionType = "WaitingForImageImport"
+	// ScheduledImportPendingReason is the reason of the waiting for image import condition while
+	// the build waits for the first scheduled import of its image stream tag.
+	ScheduledImportPendingReason = "ScheduledImportPending"
+	// ImageImportedReason is the reason of the waiting for image import condition once the image
+	// stream tag of the build was imported.
+	ImageImportedReason = "ImageImported"
+	// ImageImportTimedOutReason is the reason of the waiting for image import condition of builds
+	// that failed waiting for the import.
+	ImageImportTimedOutReason = "ImageImportTimedOut"
+
+	// BuildConditionQuotaExceeded is set to true on new builds whose build pod a resource quota of
+	// their namespace denied, and to false once their build pod is created.
+	BuildConditionQuotaExceeded buildv1.BuildConditionType = "QuotaExceeded"
+	// ResourceQuotaExceededReason is the reason of the quota exceeded condition while a resource
+	// quota denies the build pod. Its message names the quota and the exceeded resources.
+	ResourceQuotaExceededReason = "ResourceQuotaExceeded"
+	// BuildPodCreatedReason is the reason of the quota exceeded condition once the build pod was
+	// created.
+	BuildPodCreatedReason = "BuildPodCreated"
+
+	// BuildConditionWaitingForInputResource is set to true on new builds that reference a secret or
+	// config map which does not exist yet, and to false once their build pod is created.
+	BuildConditionWaitingForInputResource buildv1.BuildConditionType = "WaitingForInputResource"
+	// InputResourcePendingReason is the reason of the waiting for input resource condition while
+	// the build waits for the secrets and config maps it references. Its message names them.
+	InputResourcePendingReason = "InputResourcePending"
+	// InputResourcesFoundReason is the reason of the waiting for input resource condition once the
+	// secrets and config maps the build references exist.
+	InputResourcesFoundReason = "InputResourcesFound"
+	// InputResourceMissingReason is the reason of the waiting for input resource condition of builds
+	// that failed because a secret or config map they reference does not exist.
+	InputResourceMissingReason = "InputResourceMissing"
+
+	// BuildConditionPushFailed is set on builds the builder reported as failed to push their output
+	// image. Its reason is the error category of the failure found in the build log.
+	BuildConditionPushFailed buildv1.BuildConditionType = "PushFailed"
+	// PushUnauthorizedCategory is the category of push failures with an HTTP 401 or 403 status:
+	// the registry refused the credentials of the build.
+	PushUnauthorizedCategory = "Unauthorized"
+	// PushBlobUploadFailedCategory is the category of push failures uploading an image layer.
+	PushBlobUploadFailedCategory = "BlobUploadFailed"
+	// PushManifestInvalidCategory is the category of push failures the registry rejected the
+	// image manifest of.
+	PushManifestInvalidCategory = "ManifestInvalid"
+	// PushRegistryUnavailableCategory is the category of push failures with an HTTP 5xx status or
+	// without a response from the registry.
+	PushRegistryUnavailableCategory = "RegistryUnavailable"
+	// PushUnknownCategory is the category of the other push failures.
+	PushUnknownCategory = "Unknown"
+
+	// StatusReasonPushUnauthorized is the reason of builds that failed because the registry
+	// refused the credentials they pushed their output image with.
+	StatusReasonPushUnauthorized buildv1.StatusReason = "PushUnauthorized"
+
+	// StatusReasonBuilderServiceAccountMissing is the reason of builds that failed before their
+	// build pod was created because their service account does not exist.
+	StatusReasonBuilderServiceAccountMissing buildv1.StatusReason = "BuilderServiceAccountMissing"
+	// StatusReasonMissingPushSecret is the reason of builds that failed before their build pod was
+	// created because their service account has no secret to push their output image with.
+	StatusReasonMissingPushSecret buildv1.StatusReason = "MissingPushSecret"
+
+	// StatusReasonPipelineStrategyUnsupported is the reason of builds that failed because they use
+	// the removed JenkinsPipeline build strategy.
+	StatusReasonPipelineStrategyUnsupported buildv1.StatusReason = "PipelineStrategyUnsupported"
+
+	// StatusReasonBuildTimeout is the reason of builds that failed because their build pod exceeded
+	// its completion deadline.
+	StatusReasonBuildTimeout buildv1.StatusReason = "BuildTimeout"
+	// StatusReasonBuildPendingTimeout is the reason of builds that failed because their build pod
+	// was pending for longer than the pending timeout of the build controller.
+	StatusReasonBuildPendingTimeout buildv1.StatusReason = "BuildPendingTimeout"
+	// StatusReasonImageImportTimeout is the reason of builds that failed because the scheduled
+	// import of their strategy image stream tag did not succeed within the image import timeout of
+	// the build controller.
+	StatusReasonImageImportTimeout buildv1.StatusReason = "ImageImportTimeout"
+
+	// StatusReasonInvalidBuildVolume is the reason of builds that failed before their build pod was
+	// created because their build volumes are invalid.
+	StatusReasonInvalidBuildVolume buildv1.StatusReason = "InvalidBuildVolume"
+
+	// StatusReasonMissingInputResource is the reason of builds that failed before their build pod
+	// was created because a secret or config map they reference does not exist.
+	StatusReasonMissingInputResource buildv1.StatusReason = "MissingInputResource"
+
+	// StatusReasonInvalidBuildDefaults is the reason of builds that failed before their build pod
+	// was created because an environment variable of the build defaults references a secret or
+	// config map key of the openshift-config namespace that does not exist.
+	StatusReasonInvalidBuildDefaults buildv1.StatusReason = "InvalidBuildDefaults"
+
+	// StatusReasonBuildConfigDeleted is the reason of builds that were cancelled because their
+	// BuildConfig was deleted.
+	StatusReasonBuildConfigDeleted buildv1.StatusReason = "BuildConfigDeleted"
+
+	// StatusReasonSupersededByNewerBuild is the reason of queued builds that were cancelled because
+	// a newer build of their SerialLatestOnly BuildConfig was created.
+	StatusReasonSupersededByNewerBuild buildv1.StatusReason = "SupersededByNewerBuild"
+
+	// StatusReasonPostCommitHookTimeout is the reason of builds that failed because their
+	// post-commit hook ran longer than their post-commit timeout. Their output image was built but
+	// not pushed.
+	StatusReasonPostCommitHookTimeout buildv1.StatusReason = "PostCommitHookTimeout"
+)
+
+const (
+	// GitCloneDepthEnvVar is the environment variable of builds with the depth of their git clone.
+	// A depth of 0 clones the full history.
+	GitCloneDepthEnvVar = "GIT_CLONE_DEPTH"
+	// GitDisableSubmodulesEnvVar is set to "true" in the environment of builds that skip the git
+	// submodules of their source.
+	GitDisableSubmodulesEnvVar = "GIT_DISABLE_SUBMODULES"
+	// BuildLogLevelEnvVar is the environment variable of builds with the log level of their build
+	// pod.
+	BuildLogLevelEnvVar = "BUILD_LOGLEVEL"
+	// MinBuildLogLevel and MaxBuildLogLevel bound the log levels of build pods.
+	MinBuildLogLevel = 0
+	MaxBuildLogLevel = 5
+	// BuildCacheDirEnvVar is the environment variable of the build container with the directory of
+	// the persistent build cache.
+	BuildCacheDirEnvVar = "BUILD_CACHE_DIR"
 )
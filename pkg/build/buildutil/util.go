@@ -2,9 +2,13 @@ package buildutil
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	k8stypes "k8s.io/apimachinery/pkg/types"
@@ -39,6 +43,10 @@ const (
 	// reused within a build pod.
 	BuildBlobsContentCache = "/var/cache/blobs"
 
+	// BuildCacheMountPath is the directory of the build container where the persistent build cache
+	// of incremental source builds is mounted.
+	BuildCacheMountPath = "/var/cache/build"
+
 	// buildPodSuffix is the suffix used to append to a build pod name given a build name
 	buildPodSuffix           = "build"
 	caConfigMapSuffix        = "ca"
@@ -211,6 +219,59 @@ func FetchServiceAccountSecrets(secretStore v1lister.SecretLister, serviceAccoun
 	return result, nil
 }
 
+// TriggerFailure is the failure of a trigger of a BuildConfig to instantiate a build, recorded in
+// the BuildConfigLastTriggerFailureAnnotation of the BuildConfig.
+type TriggerFailure struct {
+	Time        metav1.Time              `json:"time"`
+	Reason      string                   `json:"reason"`
+	Message     string                   `json:"message"`
+	TriggerType buildv1.BuildTriggerType `json:"triggerType"`
+}
+
+// NewTriggerFailure returns the failure of a trigger of the given type to instantiate a build. Its
+// reason is the reason of the API error, or QuotaExceeded when a resource quota denied the build.
+func NewTriggerFailure(triggerType buildv1.BuildTriggerType, err error) *TriggerFailure {
+	reason := string(kerrors.ReasonForError(err))
+	switch {
+	case kerrors.IsForbidden(err) && strings.Contains(err.Error(), "exceeded quota"):
+		reason = "QuotaExceeded"
+	case len(reason) == 0 || reason == string(metav1.StatusReasonUnknown):
+		reason = "InstantiateFailed"
+	}
+	return &TriggerFailure{
+		Time:        metav1.Now(),
+		Reason:      reason,
+		Message:     err.Error(),
+		TriggerType: triggerType,
+	}
+}
+
+// TriggerFailurePatch returns the merge patch of a BuildConfig that records the trigger failure in
+// its BuildConfigLastTriggerFailureAnnotation, or removes the annotation when failure is nil.
+func TriggerFailurePatch(failure *TriggerFailure) ([]byte, error) {
+	var value *string
+	if failure != nil {
+		data, err := json.Marshal(failure)
+		if err != nil {
+			return nil, err
+		}
+		v := string(data)
+		value = &v
+	}
+	return json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{BuildConfigLastTriggerFailureAnnotation: value},
+		},
+	})
+}
+
+// IsValidBuildLogLevel returns true if the log level is an integer from MinBuildLogLevel to
+// MaxBuildLogLevel.
+func IsValidBuildLogLevel(level string) bool {
+	l, err := strconv.Atoi(level)
+	return err == nil && l >= MinBuildLogLevel && l <= MaxBuildLogLevel
+}
+
 // UpdateCustomImageEnv updates base image env variable reference with the new image for a custom build strategy.
 // If no env variable reference exists, create a new env variable.
 func UpdateCustomImageEnv(strategy *buildv1.CustomBuildStrategy, newImage string) {
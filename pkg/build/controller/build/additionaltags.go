@@ -0,0 +1,240 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	digest "github.com/opencontainers/go-digest"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	sharedbuildutil "github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/library-go/pkg/image/imageutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// tagPattern matches the valid tags of an image repository.
+var tagPattern = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+
+// additionalTagsError is returned when some additional tags of a completed build could not be
+// tagged because of errors that may go away. The build is retried with backoff until they are
+// tagged.
+type additionalTagsError struct {
+	failures []string
+}
+
+func (e *additionalTagsError) Error() string {
+	return fmt.Sprintf("failed to tag additional tags: %s", strings.Join(e.failures, ", "))
+}
+
+// isAdditionalTagsError returns true if the error is an additionalTagsError.
+func isAdditionalTagsError(err error) bool {
+	_, ok := err.(*additionalTagsError)
+	return ok
+}
+
+// isPermanentTagError returns true if tagging failed because the image API rejected the tag, which
+// retrying does not change.
+func isPermanentTagError(err error) bool {
+	return errors.IsInvalid(err) || errors.IsBadRequest(err)
+}
+
+// retryingAdditionalTags returns true if the additional tags of the completed build failed with
+// errors that may go away and are retried.
+func retryingAdditionalTags(build *buildv1.Build) bool {
+	condition := findBuildCondition(build, buildutil.BuildConditionAdditionalTagsTagged)
+	return condition != nil && condition.Status == corev1.ConditionFalse && condition.Reason == buildutil.AdditionalTagsTagRetryingReason
+}
+
+// requestedAdditionalTags returns the additional tags requested through the additional tags
+// annotation of the build or, failing that, of its build config.
+func (bc *BuildController) requestedAdditionalTags(build *buildv1.Build) (string, bool) {
+	if value, ok := build.Annotations[buildutil.BuildOutputAdditionalTagsAnnotation]; ok {
+		return value, true
+	}
+	bcName := sharedbuildutil.ConfigNameForBuild(build)
+	if len(bcName) == 0 {
+		return "", false
+	}
+	buildConfig, err := bc.buildConfigLister.BuildConfigs(build.Namespace).Get(bcName)
+	if err != nil {
+		klog.V(4).Infof("Unable to get build config %s/%s of build %s to look up its additional tags: %v", build.Namespace, bcName, buildDesc(build), err)
+		return "", false
+	}
+	value, ok := buildConfig.Annotations[buildutil.BuildOutputAdditionalTagsAnnotation]
+	return value, ok
+}
+
+// additionalTags returns the validated additional tags of a build, without duplicates and without
+// the tag of the output itself. Only builds with an ImageStreamTag output can have additional tags,
+// which are tagged in the output image stream through the image API: the builder only pushes the
+// output image.
+func (bc *BuildController) additionalTags(build *buildv1.Build) ([]string, error) {
+	if build.Spec.Output.To == nil {
+		return nil, nil
+	}
+	value, ok := bc.requestedAdditionalTags(build)
+	if !ok {
+		return nil, nil
+	}
+	seen := map[string]bool{}
+	if build.Spec.Output.To.Kind == "ImageStreamTag" {
+		_, outputTag, err := imageutil.ParseImageStreamTagName(build.Spec.Output.To.Name)
+		if err != nil {
+			return nil, err
+		}
+		seen[outputTag] = true
+	}
+	tags := []string{}
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) == 0 || seen[tag] {
+			continue
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("%q is not a valid tag", tag)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > 0 && build.Spec.Output.To.Kind != "ImageStreamTag" {
+		return nil, fmt.Errorf("additional tags require an ImageStreamTag output, not %s", build.Spec.Output.To.Kind)
+	}
+	return tags, nil
+}
+
+// tagImage points the tag of the image stream at the image of the given digest in the same image
+// stream, creating the image stream tag if it does not exist.
+func (bc *BuildController) tagImage(namespace, imageStream, tag, imageDigest string) error {
+	name := imageutil.JoinImageStreamTag(imageStream, tag)
+	from := &corev1.ObjectReference{Kind: "ImageStreamImage", Name: imageutil.JoinImageStreamImage(imageStream, imageDigest)}
+	client := bc.imageStreamTagClient.ImageStreamTags(namespace)
+
+	istag, err := client.Get(context.TODO(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err = client.Create(context.TODO(), &imagev1.ImageStreamTag{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+			Tag:        &imagev1.TagReference{Name: tag, From: from},
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if istag.Tag == nil {
+		istag.Tag = &imagev1.TagReference{Name: tag}
+	}
+	istag.Tag.From = from
+	_, err = client.Update(context.TODO(), istag, metav1.UpdateOptions{})
+	return err
+}
+
+// tagAdditionalTags tags the image pushed by a completed build into the additional tags of its
+// output image stream, by the digest the builder reported in the build status, and records the
+// references by digest of the tagged images. Each tag is updated on its own, so when some of them
+// could not be tagged the AdditionalTagsTagged condition of the build lists them while the tagged
+// ones are still recorded. When a tag failed with an error that may go away, the condition says the
+// tags are retried and an additionalTagsError is returned so the build is requeued with backoff;
+// the retry tags all the additional tags again and sets the condition to true once they are tagged.
+func (bc *BuildController) tagAdditionalTags(build *buildv1.Build, update *buildUpdate) error {
+	phase := build.Status.Phase
+	if update.phase != nil {
+		phase = *update.phase
+	}
+	if phase != buildv1.BuildPhaseComplete || (build.Status.CompletionTimestamp != nil && !retryingAdditionalTags(build)) {
+		return nil
+	}
+	tags, err := bc.additionalTags(build)
+	if err != nil || len(tags) == 0 {
+		return nil
+	}
+
+	notTagged := func(failures []string, reason string) {
+		sort.Strings(failures)
+		message := fmt.Sprintf("Failed to tag %d of %d additional tags: %s.", len(failures), len(tags), strings.Join(failures, ", "))
+		// retries that fail the same way do not update the build
+		if existing := findBuildCondition(build, buildutil.BuildConditionAdditionalTagsTagged); existing != nil &&
+			existing.Status == corev1.ConditionFalse && existing.Reason == reason && existing.Message == message {
+			return
+		}
+		update.setCondition(buildv1.BuildCondition{
+			Type:               buildutil.BuildConditionAdditionalTagsTagged,
+			Status:             corev1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			LastUpdateTime:     metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+		})
+	}
+	allFailed := func(reason string) error {
+		failures := []string{}
+		for _, tag := range tags {
+			failures = append(failures, fmt.Sprintf("%s (%s)", tag, reason))
+		}
+		notTagged(failures, buildutil.AdditionalTagsTagFailedReason)
+		return nil
+	}
+
+	if build.Status.Output.To == nil {
+		return allFailed("the builder did not report the digest of the output image")
+	}
+	d, err := digest.Parse(build.Status.Output.To.ImageDigest)
+	if err != nil {
+		return allFailed(fmt.Sprintf("invalid output image digest %q", build.Status.Output.To.ImageDigest))
+	}
+	ref, err := pushedImageReference(build, d.String())
+	if err != nil {
+		return allFailed(fmt.Sprintf("invalid output image reference %q", build.Status.OutputDockerImageReference))
+	}
+	imageStream, _, _ := imageutil.ParseImageStreamTagName(build.Spec.Output.To.Name)
+	namespace := build.Spec.Output.To.Namespace
+	if len(namespace) == 0 {
+		namespace = build.Namespace
+	}
+
+	tagged := map[string]string{}
+	failures := []string{}
+	retry := false
+	for _, tag := range tags {
+		if err := bc.tagImage(namespace, imageStream, tag, d.String()); err != nil {
+			failures = append(failures, fmt.Sprintf("%s (%v)", tag, err))
+			retry = retry || !isPermanentTagError(err)
+			continue
+		}
+		tagged[tag] = ref
+	}
+
+	if len(tagged) > 0 {
+		// json.Marshal sorts map keys, so the annotation is stable
+		data, err := json.Marshal(tagged)
+		if err == nil && build.Annotations[buildutil.BuildAdditionalTagReferencesAnnotation] != string(data) {
+			update.setAdditionalTagRefs(string(data))
+		}
+	}
+	switch {
+	case retry:
+		notTagged(failures, buildutil.AdditionalTagsTagRetryingReason)
+		return &additionalTagsError{failures: failures}
+	case len(failures) > 0:
+		notTagged(failures, buildutil.AdditionalTagsTagFailedReason)
+	case findBuildCondition(build, buildutil.BuildConditionAdditionalTagsTagged) != nil:
+		update.setCondition(buildv1.BuildCondition{
+			Type:               buildutil.BuildConditionAdditionalTagsTagged,
+			Status:             corev1.ConditionTrue,
+			Reason:             buildutil.AdditionalTagsTaggedReason,
+			Message:            fmt.Sprintf("Tagged all %d additional tags.", len(tags)),
+			LastUpdateTime:     metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+		})
+	}
+	return nil
+}
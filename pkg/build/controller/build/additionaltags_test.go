@@ -0,0 +1,236 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	fakeimagev1client "github.com/openshift/client-go/image/clientset/versioned/fake"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func additionalTagsBuild(phase buildv1.BuildPhase, tags string) *buildv1.Build {
+	build := dockerStrategy(mockBuild(phase, buildv1.BuildOutput{
+		To: &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "app:latest"},
+	}))
+	build.Annotations[buildutil.BuildOutputAdditionalTagsAnnotation] = tags
+	return build
+}
+
+func TestAdditionalTags(t *testing.T) {
+	tests := []struct {
+		name          string
+		build         *buildv1.Build
+		expectedTags  []string
+		expectFailure bool
+	}{
+		{
+			name:         "two additional tags",
+			build:        additionalTagsBuild(buildv1.BuildPhaseNew, "stable, 3f2a9c1"),
+			expectedTags: []string{"stable", "3f2a9c1"},
+		},
+		{
+			name:         "duplicates and the output tag are skipped",
+			build:        additionalTagsBuild(buildv1.BuildPhaseNew, "latest,stable,stable"),
+			expectedTags: []string{"stable"},
+		},
+		{
+			name:          "invalid tag",
+			build:         additionalTagsBuild(buildv1.BuildPhaseNew, "stable,-bad"),
+			expectFailure: true,
+		},
+		{
+			name: "docker image output",
+			build: func() *buildv1.Build {
+				build := additionalTagsBuild(buildv1.BuildPhaseNew, "stable")
+				build.Spec.Output.To = &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.io/namespace/app:latest"}
+				return build
+			}(),
+			expectFailure: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			imageStream := &imagev1.ImageStream{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace", Name: "app"}}
+			imageStream.Status.DockerImageRepository = "registry.io/namespace/app"
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+			bc := newFakeBuildController(nil, fakeImageClient(imageStream), kubeClient, nil, nil)
+			defer bc.stop()
+
+			update, err := bc.createBuildPod(tc.build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectFailure {
+				if update.phase == nil || *update.phase != buildv1.BuildPhaseFailed || *update.reason != buildv1.StatusReasonInvalidOutputReference {
+					t.Errorf("expected the build to fail with an invalid output reference, got %v", update)
+				}
+				return
+			}
+			if _, err := kubeClient.CoreV1().Pods("namespace").Get(context.TODO(), buildutil.GetBuildPodName(tc.build), metav1.GetOptions{}); err != nil {
+				t.Fatalf("expected the build pod to be created: %v", err)
+			}
+			tags, err := bc.additionalTags(tc.build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(tags) != fmt.Sprint(tc.expectedTags) {
+				t.Errorf("expected additional tags %v, got %v", tc.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestTagAdditionalTags(t *testing.T) {
+	const otherDigest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	existing := &imagev1.ImageStreamTag{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "namespace", Name: "app:stable"},
+		Tag:        &imagev1.TagReference{Name: "stable", From: &corev1.ObjectReference{Kind: "ImageStreamImage", Name: "app@" + otherDigest}},
+	}
+	tests := []struct {
+		name             string
+		reportedDigest   string
+		createErr        error
+		expectedRefs     string
+		expectedReason   string
+		expectedFailures string
+	}{
+		{
+			name:           "both tags tagged",
+			reportedDigest: testImageDigest,
+			expectedRefs:   `{"3f2a9c1":"registry.io/namespace/app@` + testImageDigest + `","stable":"registry.io/namespace/app@` + testImageDigest + `"}`,
+		},
+		{
+			name:             "one tag failed",
+			reportedDigest:   testImageDigest,
+			createErr:        fmt.Errorf("connection refused"),
+			expectedRefs:     `{"stable":"registry.io/namespace/app@` + testImageDigest + `"}`,
+			expectedReason:   buildutil.AdditionalTagsTagRetryingReason,
+			expectedFailures: "Failed to tag 1 of 2 additional tags: 3f2a9c1 (connection refused).",
+		},
+		{
+			name:             "one tag rejected",
+			reportedDigest:   testImageDigest,
+			createErr:        errors.NewBadRequest("invalid tag"),
+			expectedRefs:     `{"stable":"registry.io/namespace/app@` + testImageDigest + `"}`,
+			expectedReason:   buildutil.AdditionalTagsTagFailedReason,
+			expectedFailures: "Failed to tag 1 of 2 additional tags: 3f2a9c1 (invalid tag).",
+		},
+		{
+			name:             "digest not reported",
+			expectedReason:   buildutil.AdditionalTagsTagFailedReason,
+			expectedFailures: "Failed to tag 2 of 2 additional tags: 3f2a9c1 (the builder did not report the digest of the output image), stable (the builder did not report the digest of the output image).",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			imageClient := fakeimagev1client.NewSimpleClientset(existing.DeepCopy())
+			if tc.createErr != nil {
+				imageClient.PrependReactor("create", "imagestreamtags", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+					return true, nil, tc.createErr
+				})
+			}
+			bc := newFakeBuildController(nil, imageClient, nil, nil, nil)
+			defer bc.stop()
+
+			build := additionalTagsBuild(buildv1.BuildPhaseComplete, "stable,3f2a9c1")
+			build.Status.OutputDockerImageReference = "registry.io/namespace/app:latest"
+			if len(tc.reportedDigest) > 0 {
+				build.Status.Output.To = &buildv1.BuildStatusOutputTo{ImageDigest: tc.reportedDigest}
+			}
+
+			update := &buildUpdate{}
+			err := bc.tagAdditionalTags(build, update)
+			update.apply(build)
+			if retry := tc.expectedReason == buildutil.AdditionalTagsTagRetryingReason; isAdditionalTagsError(err) != retry {
+				t.Errorf("expected a retry %v, got %v", retry, err)
+			}
+
+			if got := build.Annotations[buildutil.BuildAdditionalTagReferencesAnnotation]; got != tc.expectedRefs {
+				t.Errorf("expected additional tag references %s, got %s", tc.expectedRefs, got)
+			}
+			if len(tc.expectedRefs) > 0 {
+				istag, err := imageClient.ImageV1().ImageStreamTags("namespace").Get(context.TODO(), "app:stable", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if from := istag.Tag.From; from == nil || from.Kind != "ImageStreamImage" || from.Name != "app@"+tc.reportedDigest {
+					t.Errorf("expected the stable tag to point at the pushed image, got %#v", from)
+				}
+			}
+			condition := findBuildCondition(build, buildutil.BuildConditionAdditionalTagsTagged)
+			if len(tc.expectedFailures) == 0 {
+				if condition != nil {
+					t.Errorf("expected no additional tags condition, got %#v", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != tc.expectedReason {
+				t.Fatalf("expected the additional tags condition to be false with reason %s, got %#v", tc.expectedReason, condition)
+			}
+			if condition.Message != tc.expectedFailures {
+				t.Errorf("expected message %q, got %q", tc.expectedFailures, condition.Message)
+			}
+		})
+	}
+}
+
+func TestTagAdditionalTagsRetry(t *testing.T) {
+	imageClient := fakeimagev1client.NewSimpleClientset()
+	failing := true
+	imageClient.PrependReactor("create", "imagestreamtags", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		if failing {
+			return true, nil, fmt.Errorf("connection refused")
+		}
+		return false, nil, nil
+	})
+	bc := newFakeBuildController(nil, imageClient, nil, nil, nil)
+	defer bc.stop()
+
+	build := additionalTagsBuild(buildv1.BuildPhaseComplete, "stable")
+	build.Status.OutputDockerImageReference = "registry.io/namespace/app:latest"
+	build.Status.Output.To = &buildv1.BuildStatusOutputTo{ImageDigest: testImageDigest}
+
+	update := &buildUpdate{}
+	if err := bc.tagAdditionalTags(build, update); !isAdditionalTagsError(err) {
+		t.Fatalf("expected the additional tags to be retried, got %v", err)
+	}
+	update.setCompletionTime(metav1.Now())
+	update.apply(build)
+	if shouldIgnore(build) {
+		t.Fatalf("expected the completed build to be handled while its additional tags are retried")
+	}
+
+	// a retry that fails the same way does not update the build
+	update = &buildUpdate{}
+	if err := bc.tagAdditionalTags(build, update); !isAdditionalTagsError(err) || !update.isEmpty() {
+		t.Errorf("expected an unchanged retry without an update, got %v and %v", err, update)
+	}
+
+	// the retry tags the image and sets the condition to true
+	failing = false
+	update = &buildUpdate{}
+	if err := bc.tagAdditionalTags(build, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	update.apply(build)
+	condition := findBuildCondition(build, buildutil.BuildConditionAdditionalTagsTagged)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != buildutil.AdditionalTagsTaggedReason {
+		t.Errorf("expected the additional tags condition to be true, got %#v", condition)
+	}
+	expectedRefs := `{"stable":"registry.io/namespace/app@` + testImageDigest + `"}`
+	if got := build.Annotations[buildutil.BuildAdditionalTagReferencesAnnotation]; got != expectedRefs {
+		t.Errorf("expected additional tag references %s, got %s", expectedRefs, got)
+	}
+	if !shouldIgnore(build) {
+		t.Errorf("expected the completed build to be ignored once its additional tags are tagged")
+	}
+}
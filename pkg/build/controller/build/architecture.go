@@ -0,0 +1,78 @@
+package build
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/library-go/pkg/image/imageutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// outputTargetArchitecture returns the architecture the target architecture annotation of the
+// output ImageStream of the build asks images to be built for, or an empty string if the build
+// does not push to an image stream with a valid annotation. It must be called before the output
+// reference of the build is resolved to a container image reference.
+func (bc *BuildController) outputTargetArchitecture(build *buildv1.Build) string {
+	ref := build.Spec.Output.To
+	if ref == nil {
+		return ""
+	}
+	name := ref.Name
+	switch ref.Kind {
+	case "ImageStream":
+	case "ImageStreamTag":
+		var ok bool
+		if name, _, ok = imageutil.SplitImageStreamTag(ref.Name); !ok {
+			return ""
+		}
+	default:
+		return ""
+	}
+	namespace := ref.Namespace
+	if len(namespace) == 0 {
+		namespace = build.Namespace
+	}
+	stream, err := bc.imageStreamStore.ImageStreams(namespace).Get(name)
+	if err != nil {
+		return ""
+	}
+	arch := strings.TrimSpace(stream.Annotations[buildutil.TargetArchitectureAnnotation])
+	if len(arch) == 0 {
+		return ""
+	}
+	if errs := validation.IsValidLabelValue(arch); len(errs) > 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q of image stream %s/%s: %s", buildutil.TargetArchitectureAnnotation, arch, namespace, name, strings.Join(errs, ", "))
+		return ""
+	}
+	return arch
+}
+
+// setupTargetArchitecture schedules the build pod to nodes of the target architecture of the
+// output image stream of the build. The kubernetes.io/arch node selector of the build spec and of
+// the build overrides take precedence over the target architecture, which in turn takes precedence
+// over the node selector of the build defaults.
+func (bc *BuildController) setupTargetArchitecture(build *buildv1.Build, pod *corev1.Pod, arch string) {
+	if len(arch) == 0 {
+		return
+	}
+	if _, ok := build.Spec.NodeSelector[corev1.LabelArchStable]; ok {
+		return
+	}
+	if bc.buildOverrides.Config != nil {
+		if override, ok := bc.buildOverrides.Config.NodeSelector[corev1.LabelArchStable]; ok {
+			if override != arch {
+				klog.V(4).Infof("The build overrides schedule build pod %s/%s to %s nodes instead of the target architecture %s of its output image stream", pod.Namespace, pod.Name, override, arch)
+			}
+			return
+		}
+	}
+	if pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = map[string]string{}
+	}
+	klog.V(5).Infof("Adding target architecture nodeselector %s=%s to build pod %s/%s", corev1.LabelArchStable, arch, pod.Namespace, pod.Name)
+	pod.Spec.NodeSelector[corev1.LabelArchStable] = arch
+}
@@ -0,0 +1,105 @@
+package build
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	openshiftcontrolplanev1 "github.com/openshift/api/openshiftcontrolplane/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func TestCreateBuildPodTargetArchitecture(t *testing.T) {
+	tests := []struct {
+		name                 string
+		targetArch           string
+		buildNodeSelector    map[string]string
+		defaultsNodeSelector map[string]string
+		overrideNodeSelector map[string]string
+		expectedNodeSelector map[string]string
+	}{
+		{
+			name:                 "no target architecture",
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux"},
+		},
+		{
+			name:                 "target architecture of the output image stream",
+			targetArch:           "arm64",
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux", corev1.LabelArchStable: "arm64"},
+		},
+		{
+			name:                 "invalid target architecture",
+			targetArch:           "arm 64",
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux"},
+		},
+		{
+			name:                 "architecture of the build spec",
+			targetArch:           "arm64",
+			buildNodeSelector:    map[string]string{corev1.LabelArchStable: "amd64"},
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux", corev1.LabelArchStable: "amd64"},
+		},
+		{
+			name:                 "architecture of the build defaults",
+			targetArch:           "arm64",
+			defaultsNodeSelector: map[string]string{corev1.LabelArchStable: "amd64", "node-role.kubernetes.io/builder": ""},
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux", corev1.LabelArchStable: "arm64", "node-role.kubernetes.io/builder": ""},
+		},
+		{
+			name:                 "architecture of the build overrides",
+			targetArch:           "arm64",
+			overrideNodeSelector: map[string]string{corev1.LabelArchStable: "amd64"},
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux", corev1.LabelArchStable: "amd64"},
+		},
+		{
+			name:                 "other build overrides",
+			targetArch:           "arm64",
+			overrideNodeSelector: map[string]string{"node-role.kubernetes.io/builder": ""},
+			expectedNodeSelector: map[string]string{corev1.LabelOSStable: "linux", corev1.LabelArchStable: "arm64", "node-role.kubernetes.io/builder": ""},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stream := &imagev1.ImageStream{
+				ObjectMeta: metav1.ObjectMeta{Name: "output", Namespace: "namespace"},
+			}
+			stream.Status.DockerImageRepository = "registry.example.com/namespace/output"
+			if len(tc.targetArch) > 0 {
+				stream.Annotations = map[string]string{buildutil.TargetArchitectureAnnotation: tc.targetArch}
+			}
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+			bc := newFakeBuildController(nil, fakeImageClient(stream), kubeClient, nil, nil)
+			defer bc.stop()
+			if tc.defaultsNodeSelector != nil {
+				bc.buildDefaults.Config = &openshiftcontrolplanev1.BuildDefaultsConfig{NodeSelector: tc.defaultsNodeSelector}
+			}
+			if tc.overrideNodeSelector != nil {
+				bc.buildOverrides.Config = &openshiftcontrolplanev1.BuildOverridesConfig{NodeSelector: tc.overrideNodeSelector}
+			}
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{
+				To:         &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "output:latest"},
+				PushSecret: &corev1.LocalObjectReference{},
+			}))
+			build.Spec.NodeSelector = tc.buildNodeSelector
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+				t.Fatalf("expected the build pod to be created, got %v", update)
+			}
+			pod, err := kubeClient.CoreV1().Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(pod.Spec.NodeSelector, tc.expectedNodeSelector) {
+				t.Errorf("expected node selector %v, got %v", tc.expectedNodeSelector, pod.Spec.NodeSelector)
+			}
+		})
+	}
+}
@@ -34,6 +34,7 @@ import (
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/clock"
 
 	sysregistriesv2 "github.com/containers/image/v5/pkg/sysregistriesv2"
 	rutil "github.com/openshift/runtime-utils/pkg/registries"
@@ -49,6 +50,8 @@ import (
 	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
 	configv1informer "github.com/openshift/client-go/config/informers/externalversions/config/v1"
 	configv1lister "github.com/openshift/client-go/config/listers/config/v1"
+	imagev1client "github.com/openshift/client-go/image/clientset/versioned"
+	imageclientv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
 	imagev1informer "github.com/openshift/client-go/image/informers/externalversions/image/v1"
 	imagev1lister "github.com/openshift/client-go/image/listers/image/v1"
 	operatorv1alpha1informer "github.com/openshift/client-go/operator/informers/externalversions/operator/v1alpha1"
@@ -144,11 +147,14 @@ type BuildController struct {
 	buildLister                 buildv1lister.BuildLister
 	buildConfigLister           buildv1lister.BuildConfigLister
 	buildDeleter                buildclientv1.BuildsGetter
+	buildCloner                 buildclientv1.BuildsGetter
+	buildConfigUpdater          buildclientv1.BuildConfigsGetter
 	buildControllerConfigLister configv1lister.BuildLister
 	imageConfigLister           configv1lister.ImageLister
 	podClient                   ktypedclient.PodsGetter
 	configMapClient             ktypedclient.ConfigMapsGetter
 	kubeClient                  kubernetes.Interface
+	imageStreamTagClient        imageclientv1.ImageStreamTagsGetter
 	proxyCfgLister              configv1lister.ProxyLister
 
 	imageContentSourcePolicyLister operatorv1alpha1lister.ImageContentSourcePolicyLister
@@ -159,6 +165,7 @@ type BuildController struct {
 	imageStreamQueue      *resourceTriggerQueue
 	buildConfigQueue      workqueue.RateLimitingInterface
 	controllerConfigQueue workqueue.RateLimitingInterface
+	buildRetryQueue       workqueue.RateLimitingInterface
 
 	buildStore                      buildv1lister.BuildLister
 	secretStore                     v1lister.SecretLister
@@ -168,9 +175,13 @@ type BuildController struct {
 	imageStreamStore                imagev1lister.ImageStreamLister
 	openShiftConfigConfigMapStore   v1lister.ConfigMapLister
 	controllerManagerConfigMapStore v1lister.ConfigMapLister
+	namespaceStore                  v1lister.NamespaceLister
+	persistentVolumeClaimStore      v1lister.PersistentVolumeClaimLister
 
 	podInformer      cache.SharedIndexInformer
 	buildInformer    cache.SharedIndexInformer
+	podIndexer       cache.Indexer
+	buildIndexer     cache.Indexer
 	proxyCfgInformer cache.SharedIndexInformer
 
 	imageContentSourcePolicyInformer cache.SharedIndexInformer
@@ -190,14 +201,50 @@ type BuildController struct {
 	imageContentSourcePolicySynched       cache.InformerSynced
 	imageDigestMirrorSetSynched           cache.InformerSynced
 	imageTagMirrorSetSynched              cache.InformerSynced
+	namespaceStoreSynced                  cache.InformerSynced
+	persistentVolumeClaimStoreSynced      cache.InformerSynced
 
 	runPolicies              []policy.RunPolicy
 	createStrategy           buildPodCreationStrategy
 	buildDefaults            builddefaults.BuildDefaults
 	buildOverrides           buildoverrides.BuildOverrides
 	internalRegistryHostname string
+	buildRetention           common.BuildRetentionPolicy
 	buildCSIVolumesEnabled   bool
 
+	// defaultMaxConcurrentBuilds is the concurrent build limit of namespaces that do not set one.
+	defaultMaxConcurrentBuilds int
+	// maxActiveBuildPods is the limit of pending and running build pods across the cluster, or 0
+	// when the number is not limited.
+	maxActiveBuildPods int
+	// legacyCompletionDeadline makes the kubelet enforce the completion deadline of builds from
+	// before their build pod runs, instead of the build controller from the start of the build.
+	legacyCompletionDeadline bool
+	// pendingTimeout is how long a build pod may be pending before its build fails. Build pods
+	// may be pending forever when it is zero.
+	pendingTimeout time.Duration
+	// imageImportTimeout is how long a new build waits for the scheduled import of its strategy
+	// image stream tag before it fails. Builds wait forever when it is zero.
+	imageImportTimeout time.Duration
+	// cancelGracePeriod is how long the build pod of a cancelled build may take to terminate
+	// before it is force deleted. Build pods are deleted with their default grace period and
+	// builds are cancelled at once when it is zero.
+	cancelGracePeriod time.Duration
+	// generatedObjectsRetention is how long the configMaps generated for the build pod are
+	// kept after the build completed. They are kept until the build is deleted when it is zero.
+	generatedObjectsRetention time.Duration
+	// buildConfigMetricsNamespaces are the namespaces whose build configs the per build config
+	// build metrics are recorded for, to bound their cardinality.
+	buildConfigMetricsNamespaces sets.Set[string]
+	// orphanedPodGracePeriod is how long build pods whose build was deleted are kept before they
+	// are deleted. They are never deleted when it is zero.
+	orphanedPodGracePeriod time.Duration
+	// orphanedBuildPods are the times the orphaned build pods were first found at, by the key of
+	// the pod. It is only accessed by the orphaned build pod sweep.
+	orphanedBuildPods map[string]time.Time
+	// clock is used to enforce the deadlines of builds.
+	clock clock.Clock
+
 	recorder                record.EventRecorder
 	registryConfData        string
 	signaturePolicyData     string
@@ -223,14 +270,48 @@ type BuildControllerParams struct {
 	ImageContentSourcePolicyInformer   operatorv1alpha1informer.ImageContentSourcePolicyInformer
 	ImageDigestMirrorSetInformer       configv1informer.ImageDigestMirrorSetInformer
 	ImageTagMirrorSetInformer          configv1informer.ImageTagMirrorSetInformer
+	NamespaceInformer                  kubeinformers.NamespaceInformer
+	PersistentVolumeClaimInformer      kubeinformers.PersistentVolumeClaimInformer
 	KubeClient                         kubernetes.Interface
 	BuildClient                        buildv1client.Interface
+	ImageClient                        imagev1client.Interface
 	DockerBuildStrategy                *strategy.DockerBuildStrategy
 	SourceBuildStrategy                *strategy.SourceBuildStrategy
 	CustomBuildStrategy                *strategy.CustomBuildStrategy
 	BuildDefaults                      builddefaults.BuildDefaults
 	BuildOverrides                     buildoverrides.BuildOverrides
 	InternalRegistryHostname           string
+	BuildRetention                     common.BuildRetentionPolicy
+	// MaxConcurrentBuildsPerNamespace is the default limit of builds with a build pod per
+	// namespace. Builds are not limited when it is zero.
+	MaxConcurrentBuildsPerNamespace int
+	// MaxActiveBuildPods is the limit of pending and running build pods across the cluster. New
+	// builds beyond it are admitted round robin across namespaces. Builds are not limited when it
+	// is zero.
+	MaxActiveBuildPods int
+	// LegacyCompletionDeadline measures the completion deadline of builds from the creation of
+	// their build pod, as before the deadline was measured from the start of the build.
+	// Deprecated: it will be removed in the next release.
+	LegacyCompletionDeadline bool
+	// BuildPendingTimeout is how long a build pod may be pending before its build fails. Build
+	// pods may be pending forever when it is zero.
+	BuildPendingTimeout time.Duration
+	// ImageImportTimeout is how long a new build waits for the scheduled import of its strategy
+	// image stream tag before it fails. Builds wait forever when it is zero.
+	ImageImportTimeout time.Duration
+	// CancellationGracePeriod is how long the build pod of a cancelled build may take to terminate
+	// before it is force deleted. Build pods are deleted with their default grace period and
+	// builds are cancelled at once when it is zero.
+	CancellationGracePeriod time.Duration
+	// GeneratedObjectsRetention is how long the configMaps generated for the build pod are
+	// kept after the build completed. They are kept until the build is deleted when it is zero.
+	GeneratedObjectsRetention time.Duration
+	// BuildConfigMetricsNamespaces are the namespaces whose build configs the per build config
+	// build metrics are recorded for. They are not recorded for any build config when it is empty.
+	BuildConfigMetricsNamespaces []string
+	// OrphanedBuildPodGracePeriod is how long build pods whose build was deleted are kept before
+	// they are deleted. They are never deleted when it is zero.
+	OrphanedBuildPodGracePeriod time.Duration
 }
 
 // NewBuildController creates a new BuildController.
@@ -245,6 +326,8 @@ func NewBuildController(params *BuildControllerParams) *BuildController {
 		buildLister:                      buildLister,
 		buildConfigLister:                buildConfigGetter,
 		buildDeleter:                     params.BuildClient.BuildV1(),
+		buildCloner:                      params.BuildClient.BuildV1(),
+		buildConfigUpdater:               params.BuildClient.BuildV1(),
 		buildControllerConfigLister:      params.BuildControllerConfigInformer.Lister(),
 		proxyCfgLister:                   params.ProxyConfigInformer.Lister(),
 		imageContentSourcePolicyLister:   params.ImageContentSourcePolicyInformer.Lister(),
@@ -258,10 +341,15 @@ func NewBuildController(params *BuildControllerParams) *BuildController {
 		configMapClient:                  params.KubeClient.CoreV1(),
 		openShiftConfigConfigMapStore:    params.OpenshiftConfigConfigMapInformer.Lister(),
 		controllerManagerConfigMapStore:  params.ControllerManagerConfigMapInformer.Lister(),
+		namespaceStore:                   params.NamespaceInformer.Lister(),
+		persistentVolumeClaimStore:       params.PersistentVolumeClaimInformer.Lister(),
 		kubeClient:                       params.KubeClient,
+		imageStreamTagClient:             params.ImageClient.ImageV1(),
 		podInformer:                      params.PodInformer.Informer(),
 		podStore:                         params.PodInformer.Lister(),
 		buildInformer:                    params.BuildInformer.Informer(),
+		podIndexer:                       params.PodInformer.Informer().GetIndexer(),
+		buildIndexer:                     params.BuildInformer.Informer().GetIndexer(),
 		buildStore:                       params.BuildInformer.Lister(),
 		proxyCfgInformer:                 params.ProxyConfigInformer.Informer(),
 		imageContentSourcePolicyInformer: params.ImageContentSourcePolicyInformer.Informer(),
@@ -276,16 +364,39 @@ func NewBuildController(params *BuildControllerParams) *BuildController {
 		buildDefaults:            params.BuildDefaults,
 		buildOverrides:           params.BuildOverrides,
 		internalRegistryHostname: params.InternalRegistryHostname,
+		buildRetention:           params.BuildRetention,
+
+		defaultMaxConcurrentBuilds:   params.MaxConcurrentBuildsPerNamespace,
+		maxActiveBuildPods:           params.MaxActiveBuildPods,
+		legacyCompletionDeadline:     params.LegacyCompletionDeadline,
+		pendingTimeout:               params.BuildPendingTimeout,
+		imageImportTimeout:           params.ImageImportTimeout,
+		cancelGracePeriod:            params.CancellationGracePeriod,
+		generatedObjectsRetention:    params.GeneratedObjectsRetention,
+		buildConfigMetricsNamespaces: sets.New(params.BuildConfigMetricsNamespaces...),
+		orphanedPodGracePeriod:       params.OrphanedBuildPodGracePeriod,
+		clock:                        clock.RealClock{},
 
 		buildQueue:            workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "build"),
 		imageStreamQueue:      newResourceTriggerQueue(),
 		buildConfigQueue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "build-completed"),
 		controllerConfigQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "build-controller-config"),
+		buildRetryQueue:       workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "build-retry"),
 
 		recorder:    eventBroadcaster.NewRecorder(buildscheme.EncoderScheme, corev1.EventSource{Component: "build-controller"}),
 		runPolicies: policy.GetAllRunPolicies(buildLister, params.BuildClient.BuildV1()),
 	}
 
+	if err := c.podInformer.AddIndexers(cache.Indexers{activeBuildPodIndex: activeBuildPodIndexFunc}); err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to index the active build pods: %v", err))
+	}
+	if err := c.buildInformer.AddIndexers(cache.Indexers{
+		competingForCapacityIndex: competingForCapacityIndexFunc,
+		retryOfIndex:              retryOfIndexFunc,
+	}); err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to index the builds: %v", err))
+	}
+
 	c.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		UpdateFunc: c.podUpdated,
 		DeleteFunc: c.podDeleted,
@@ -334,6 +445,10 @@ func NewBuildController(params *BuildControllerParams) *BuildController {
 		UpdateFunc: c.configMapUpdated,
 		DeleteFunc: c.configMapDeleted,
 	})
+	params.ControllerManagerConfigMapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.trustedCABundleAdded,
+		UpdateFunc: c.trustedCABundleUpdated,
+	})
 
 	c.buildStoreSynced = c.buildInformer.HasSynced
 	c.podStoreSynced = c.podInformer.HasSynced
@@ -348,6 +463,8 @@ func NewBuildController(params *BuildControllerParams) *BuildController {
 	c.imageConfigStoreSynced = params.ImageConfigInformer.Informer().HasSynced
 	c.openshiftConfigConfigMapStoreSynced = params.OpenshiftConfigConfigMapInformer.Informer().HasSynced
 	c.controllerManagerConfigMapStoreSynced = params.ControllerManagerConfigMapInformer.Informer().HasSynced
+	c.namespaceStoreSynced = params.NamespaceInformer.Informer().HasSynced
+	c.persistentVolumeClaimStoreSynced = params.PersistentVolumeClaimInformer.Informer().HasSynced
 
 	return c
 }
@@ -404,9 +521,36 @@ func (bc *BuildController) defaults() builddefaults.BuildDefaults {
 	bc.configLock.Lock()
 	defer bc.configLock.Unlock()
 	copy := builddefaults.BuildDefaults{
-		Config:       bc.buildDefaults.Config.DeepCopy(),
-		DefaultProxy: bc.buildDefaults.DefaultProxy.DeepCopy(),
+		Config:            bc.buildDefaults.Config.DeepCopy(),
+		DefaultProxy:      bc.buildDefaults.DefaultProxy.DeepCopy(),
+		PriorityClassName: bc.buildDefaults.PriorityClassName,
+		DisableSubmodules: bc.buildDefaults.DisableSubmodules,
+
+		RescheduleLostPods: bc.buildDefaults.RescheduleLostPods,
+		MaxPodReschedules:  bc.buildDefaults.MaxPodReschedules,
+	}
+	for _, toleration := range bc.buildDefaults.Tolerations {
+		copy.Tolerations = append(copy.Tolerations, *toleration.DeepCopy())
 	}
+	copy.PodAffinity = bc.buildDefaults.PodAffinity.DeepCopy()
+	copy.PodAntiAffinity = bc.buildDefaults.PodAntiAffinity.DeepCopy()
+	copy.StrategyResources = bc.buildDefaults.StrategyResources.DeepCopy()
+	for _, constraint := range bc.buildDefaults.TopologySpreadConstraints {
+		copy.TopologySpreadConstraints = append(copy.TopologySpreadConstraints, *constraint.DeepCopy())
+	}
+	if bc.buildDefaults.CompletionDeadlineSeconds != nil {
+		deadline := *bc.buildDefaults.CompletionDeadlineSeconds
+		copy.CompletionDeadlineSeconds = &deadline
+	}
+	if bc.buildDefaults.DefaultGitCloneDepth != nil {
+		depth := *bc.buildDefaults.DefaultGitCloneDepth
+		copy.DefaultGitCloneDepth = &depth
+	}
+	if bc.buildDefaults.LogLevel != nil {
+		level := *bc.buildDefaults.LogLevel
+		copy.LogLevel = &level
+	}
+	copy.ImageLabelTemplates = append([]buildv1.ImageLabel(nil), bc.buildDefaults.ImageLabelTemplates...)
 	return copy
 }
 
@@ -470,6 +614,7 @@ func (bc *BuildController) Run(workers int, stopCh <-chan struct{}) {
 	defer bc.buildQueue.ShutDown()
 	defer bc.buildConfigQueue.ShutDown()
 	defer bc.controllerConfigQueue.ShutDown()
+	defer bc.buildRetryQueue.ShutDown()
 
 	// Wait for the controller stores to sync before starting any work in this controller.
 	if !cache.WaitForCacheSync(stopCh,
@@ -479,7 +624,9 @@ func (bc *BuildController) Run(workers int, stopCh <-chan struct{}) {
 		bc.serviceAccountStoreSynced,
 		bc.imageStreamStoreSynced,
 		bc.openshiftConfigConfigMapStoreSynced,
-		bc.controllerManagerConfigMapStoreSynced) {
+		bc.controllerManagerConfigMapStoreSynced,
+		bc.namespaceStoreSynced,
+		bc.persistentVolumeClaimStoreSynced) {
 		utilruntime.HandleError(fmt.Errorf("timed out waiting for caches to sync"))
 		return
 	}
@@ -515,6 +662,14 @@ func (bc *BuildController) Run(workers int, stopCh <-chan struct{}) {
 		go wait.Until(bc.buildConfigWorker, time.Second, stopCh)
 	}
 
+	for i := 0; i < workers; i++ {
+		go wait.Until(bc.buildRetryWorker, time.Second, stopCh)
+	}
+
+	if bc.orphanedPodGracePeriod > 0 {
+		go wait.Until(bc.pruneOrphanedBuildPods, orphanedBuildPodSweepInterval, stopCh)
+	}
+
 	metrics.IntializeMetricsCollector(bc.buildLister)
 
 	<-stopCh
@@ -582,6 +737,37 @@ func (bc *BuildController) buildConfigWork() bool {
 	return false
 }
 
+func (bc *BuildController) buildRetryWorker() {
+	for {
+		if quit := bc.buildRetryWork(); quit {
+			return
+		}
+	}
+}
+
+// buildRetryWork gets the next failed build from the buildRetryQueue and invokes handleBuildRetry on it
+func (bc *BuildController) buildRetryWork() bool {
+	key, quit := bc.buildRetryQueue.Get()
+	if quit {
+		return true
+	}
+	defer bc.buildRetryQueue.Done(key)
+
+	build, err := bc.getBuildByKey(key.(string))
+	if err != nil {
+		bc.handleBuildRetryError(err, key)
+		return false
+	}
+	if build == nil {
+		bc.buildRetryQueue.Forget(key)
+		return false
+	}
+
+	err = bc.handleBuildRetry(build)
+	bc.handleBuildRetryError(err, key)
+	return false
+}
+
 func parseBuildConfigKey(key string) (string, string, error) {
 	parts := strings.SplitN(key, "/", 2)
 	if len(parts) != 2 {
@@ -598,12 +784,18 @@ func (bc *BuildController) handleBuild(build *buildv1.Build) error {
 	// If pipeline build, handle pruning.
 	if build.Spec.Strategy.JenkinsPipelineStrategy != nil {
 		if buildutil.IsBuildComplete(build) {
-			if err := common.HandleBuildPruning(sharedbuildutil.ConfigNameForBuild(build), build.Namespace, bc.buildLister, bc.buildConfigLister, bc.buildDeleter); err != nil {
+			if err := common.HandleBuildPruning(sharedbuildutil.ConfigNameForBuild(build), build.Namespace, bc.buildLister, bc.buildConfigLister, bc.buildDeleter, bc.buildRetention); err != nil {
 				utilruntime.HandleError(fmt.Errorf("failed to prune builds for %s/%s: %v", build.Namespace, build.Name, err))
 			}
 		}
 	}
 
+	if buildutil.IsBuildComplete(build) && build.DeletionTimestamp == nil {
+		if err := bc.cleanupGeneratedObjects(build); err != nil {
+			utilruntime.HandleError(fmt.Errorf("failed to clean up the generated objects of build %s/%s: %v", build.Namespace, build.Name, err))
+		}
+	}
+
 	if shouldIgnore(build) {
 		return nil
 	}
@@ -625,8 +817,11 @@ func (bc *BuildController) handleBuild(build *buildv1.Build) error {
 	switch {
 	case shouldCancel(build):
 		update, err = bc.cancelBuild(build)
+	case build.Spec.Strategy.JenkinsPipelineStrategy != nil:
+		update = bc.rejectPipelineBuild(build)
 	case build.Status.Phase == buildv1.BuildPhaseNew:
 		update, err = bc.handleNewBuild(build, pod)
+		update = setCommitAnnotations(build, update)
 	case build.Status.Phase == buildv1.BuildPhasePending,
 		build.Status.Phase == buildv1.BuildPhaseRunning:
 		update, err = bc.handleActiveBuild(build, pod)
@@ -646,14 +841,17 @@ func (bc *BuildController) handleBuild(build *buildv1.Build) error {
 }
 
 // shouldIgnore returns true if a build should be ignored by the controller.
-// These include pipeline builds as well as builds that are in a terminal state.
+// These include pipeline builds that are not new as well as builds that are in a terminal state.
 // However if the build is either complete or failed and its completion timestamp
 // has not been set, then it returns false so that the build's completion timestamp
 // gets updated.
 func shouldIgnore(build *buildv1.Build) bool {
-	// If pipeline build, do nothing.
-	// These builds are processed/updated/etc by the jenkins sync plugin
+	// If pipeline build, do nothing unless it is new.
+	// New builds of the removed JenkinsPipeline strategy are failed by the controller.
 	if build.Spec.Strategy.JenkinsPipelineStrategy != nil {
+		if build.Status.Phase == buildv1.BuildPhaseNew && build.DeletionTimestamp == nil {
+			return false
+		}
 		klog.V(4).Infof("Ignoring build %s with jenkins pipeline strategy", buildDesc(build))
 		return true
 	}
@@ -668,10 +866,11 @@ func shouldIgnore(build *buildv1.Build) bool {
 	// state and its completion time or logsnippet is not set, then we should at least attempt to set its
 	// completion time and logsnippet if possible because the build pod may have put the build in
 	// this state and it would have not set the completion timestamp or logsnippet data.
+	// Completed builds whose additional tags are retried are not ignored either.
 	if buildutil.IsBuildComplete(build) {
 		switch build.Status.Phase {
 		case buildv1.BuildPhaseComplete:
-			if build.Status.CompletionTimestamp == nil {
+			if build.Status.CompletionTimestamp == nil || retryingAdditionalTags(build) {
 				return false
 			}
 		case buildv1.BuildPhaseFailed:
@@ -696,11 +895,22 @@ func (bc *BuildController) cancelBuild(build *buildv1.Build) (*buildUpdate, erro
 	klog.V(4).Infof("Cancelling build %s", buildDesc(build))
 
 	podName := buildutil.GetBuildPodName(build)
-	err := bc.podClient.Pods(build.Namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
-	if err != nil && !errors.IsNotFound(err) {
-		return nil, fmt.Errorf("could not delete build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+	if bc.cancelGracePeriod > 0 {
+		// The build pod is given the grace period to terminate before the build is cancelled.
+		if update, terminated, err := bc.terminateBuildPod(build, podName); err != nil || !terminated {
+			return update, err
+		}
+	} else {
+		err := bc.podClient.Pods(build.Namespace).Delete(context.TODO(), podName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("could not delete build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+		}
 	}
 
+	// Builds cancelled by the buildconfig controller or by the run policy keep the reason they set
+	if build.Status.Reason == buildutil.StatusReasonBuildConfigDeleted || build.Status.Reason == buildutil.StatusReasonSupersededByNewerBuild {
+		return transitionToPhase(buildv1.BuildPhaseCancelled, build.Status.Reason, build.Status.Message), nil
+	}
 	return transitionToPhase(buildv1.BuildPhaseCancelled, buildv1.StatusReasonCancelledBuild, "The build was cancelled by the user."), nil
 }
 
@@ -746,10 +956,64 @@ func (bc *BuildController) handleNewBuild(build *buildv1.Build, pod *corev1.Pod)
 
 	// The runPolicy decides whether to execute this build or not.
 	if run, err := runPolicy.IsRunnable(build); err != nil || !run {
+		if err == nil {
+			metrics.RecordBuildPending(build, metrics.PendingReasonSerialPolicy)
+		}
 		return nil, err
 	}
 
-	return bc.createBuildPod(build)
+	// The namespace concurrent build limit decides when to execute it.
+	if queued, update, err := bc.checkConcurrencyLimit(build); err != nil || queued {
+		if queued {
+			metrics.RecordBuildPending(build, metrics.PendingReasonConcurrencyLimit)
+		}
+		return update, err
+	}
+
+	// The cluster build pod limit decides when to execute it, fairly across namespaces.
+	if queued, update, err := bc.checkClusterCapacity(build); err != nil || queued {
+		if queued {
+			metrics.RecordBuildPending(build, metrics.PendingReasonClusterCapacity)
+			update = admitUnderConcurrencyLimit(build, update)
+		}
+		return update, err
+	}
+
+	// A build cache claim that cannot be shared serializes the builds of the BuildConfig.
+	if queued, update, err := bc.checkBuildCacheInUse(build); err != nil || queued {
+		if queued {
+			metrics.RecordBuildPending(build, metrics.PendingReasonBuildCacheInUse)
+		}
+		return update, err
+	}
+
+	update, err := bc.createBuildPod(build)
+	metrics.RecordBuildPending(build, pendingReason(update))
+	if update != nil && err == nil {
+		admitQueuedBuild(build, update)
+		admitWaitingForCapacity(build, update)
+		setImageImported(build, update)
+		setInputResourcesFound(build, update)
+		clearQuotaExceeded(build, update)
+	}
+	return update, err
+}
+
+// pendingReason returns the metrics reason a new build whose build pod could not be created is
+// held back for, or an empty string if the build pod was created or the reason is not tracked.
+func pendingReason(update *buildUpdate) string {
+	if update == nil || update.reason == nil || (update.phase != nil && *update.phase != buildv1.BuildPhaseNew) {
+		return ""
+	}
+	switch *update.reason {
+	case buildv1.StatusReasonInvalidOutputReference, buildv1.StatusReasonInvalidImageReference:
+		return metrics.PendingReasonImageNotResolved
+	case buildv1.StatusReasonCannotCreateBuildPod:
+		if update.message != nil && strings.Contains(*update.message, "exceeded quota") {
+			return metrics.PendingReasonQuotaExceeded
+		}
+	}
+	return ""
 }
 
 // createPodSpec creates a pod spec for the given build, with all references already resolved.
@@ -768,17 +1032,28 @@ func (bc *BuildController) createPodSpec(build *buildv1.Build, caData map[string
 	// Invoke the strategy to create a build pod.
 	podSpec, err := bc.createStrategy.CreateBuildPod(build, caData, bc.internalRegistryHostname)
 	if err != nil {
+		if _, ok := err.(*strategy.BuildVolumeError); ok {
+			return nil, err
+		}
 		if strategy.IsFatal(err) {
 			return nil, &strategy.FatalError{Reason: fmt.Sprintf("failed to create a build pod spec for build %s/%s: %v", build.Namespace, build.Name, err)}
 		}
 		return nil, fmt.Errorf("failed to create a build pod spec for build %s/%s: %v", build.Namespace, build.Name, err)
 	}
-	if err := bc.defaults().ApplyDefaults(podSpec); err != nil {
+	podSpec.Spec.PriorityClassName = bc.requestedPriorityClassName(build)
+	defaults := bc.defaults()
+	if defaults.Config != nil {
+		if defaults.Config.Env, err = bc.resolveBuildDefaultsEnv(defaults.Config.Env); err != nil {
+			return nil, err
+		}
+	}
+	if err := defaults.ApplyDefaults(podSpec); err != nil {
 		return nil, fmt.Errorf("failed to apply build defaults for build %s/%s: %v", build.Namespace, build.Name, err)
 	}
 	if err := bc.buildOverrides.ApplyOverrides(podSpec); err != nil {
 		return nil, fmt.Errorf("failed to apply build overrides for build %s/%s: %v", build.Namespace, build.Name, err)
 	}
+	bc.setupPodActiveDeadline(podSpec)
 
 	// Handle resolving ValueFrom references in build environment variables
 	if err := common.ResolveValueFrom(podSpec, bc.kubeClient); err != nil {
@@ -787,6 +1062,25 @@ func (bc *BuildController) createPodSpec(build *buildv1.Build, caData map[string
 	return podSpec, nil
 }
 
+// requestedPriorityClassName returns the priority class requested for the build pod through the
+// priority class annotation of the build or, failing that, of its build config. Build defaults only
+// apply when no class is requested, and build overrides replace the requested class.
+func (bc *BuildController) requestedPriorityClassName(build *buildv1.Build) string {
+	if name, ok := build.Annotations[buildutil.BuildPriorityClassNameAnnotation]; ok {
+		return name
+	}
+	bcName := sharedbuildutil.ConfigNameForBuild(build)
+	if len(bcName) == 0 {
+		return ""
+	}
+	buildConfig, err := bc.buildConfigLister.BuildConfigs(build.Namespace).Get(bcName)
+	if err != nil {
+		klog.V(4).Infof("Unable to get build config %s/%s of build %s to look up its priority class: %v", build.Namespace, bcName, buildDesc(build), err)
+		return ""
+	}
+	return buildConfig.Annotations[buildutil.BuildPriorityClassNameAnnotation]
+}
+
 // resolveImageSecretAsReference returns a LocalObjectReference to a secret that should
 // be able to push/pull at the image location.
 // Note that we are using controller level permissions to resolve the secret,
@@ -841,6 +1135,9 @@ var (
 	// errNoIntegratedRegistry is a marker error for when the output image points to a registry
 	// that cannot be resolved.
 	errNoIntegratedRegistry = fmt.Errorf("the integrated registry is not configured")
+	// errWaitingForImageImport is a marker error for when a build waits for the scheduled import
+	// of its strategy image stream tag.
+	errWaitingForImageImport = fmt.Errorf("waiting for the scheduled import of the strategy image stream tag")
 )
 
 // unresolvedImageStreamReferences finds all image stream references in the provided
@@ -1118,6 +1415,11 @@ func (bc *BuildController) resolveImageReferences(build *buildv1.Build, update *
 	})
 
 	if len(errs) > 0 {
+		// A strategy image stream tag that is imported by a scheduled import policy may not be
+		// imported yet, the build waits for the import instead of failing.
+		if bc.waitForImageImport(build, update) {
+			return errWaitingForImageImport
+		}
 		update.setReason(buildv1.StatusReasonInvalidImageReference)
 		update.setMessage("Referenced image could not be resolved.")
 		return errs.ToAggregate()
@@ -1152,11 +1454,42 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 	// TODO: Rename this to buildCopy
 	build = build.DeepCopy()
 
+	// The build pod runs with the build service account, which needs a secret to push the output
+	// image with unless the build sets one.
+	serviceAccountUpdate, err := bc.checkBuilderServiceAccount(build)
+	if err != nil {
+		return update, err
+	}
+	if serviceAccountUpdate != nil {
+		return serviceAccountUpdate, nil
+	}
+
+	// Image streams of other namespaces are pushed to and pulled from with the credentials of the
+	// build service account, which needs access to them.
+	accessUpdate, err := bc.checkImageStreamAccess(build)
+	if err != nil {
+		return update, err
+	}
+	if accessUpdate != nil {
+		return accessUpdate, nil
+	}
+
+	// The target architecture of the output image stream is looked up before the output reference
+	// is resolved to a container image reference.
+	targetArch := bc.outputTargetArchitecture(build)
+
+	// Additional tags are validated against the ImageStreamTag output before the output reference
+	// is resolved to a container image reference.
+	if _, err := bc.additionalTags(build); err != nil {
+		return transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonInvalidOutputReference, fmt.Sprintf("%v: %v",
+			"Invalid additional tags", err.Error())), nil
+	}
+
 	// Resolve all Docker image references to valid values.
 	if err := bc.resolveImageReferences(build, update); err != nil {
 		// if we're waiting for an image stream to exist, we will get an update via the
 		// trigger, and thus don't need to be requeued.
-		if hasError(err, errors.IsNotFound, field.NewErrorTypeMatcher(field.ErrorTypeNotFound)) {
+		if err == errWaitingForImageImport || hasError(err, errors.IsNotFound, field.NewErrorTypeMatcher(field.ErrorTypeNotFound)) {
 			return update, nil
 		}
 		return update, err
@@ -1177,6 +1510,13 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 	}
 	build.Spec.Output.PushSecret = pushSecret
 
+	// Select the source secret of git builds without one by the source URI patterns of the secrets
+	// of the namespace.
+	sourceSecret, err := bc.resolveSourceSecret(build)
+	if err != nil {
+		return update, err
+	}
+
 	// Set the pullSecret that will be needed by the build to pull the base/builder image.
 	var pullSecret *corev1.LocalObjectReference
 	var imageName string
@@ -1242,10 +1582,16 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 		buildutil.UpdateCustomImageEnv(build.Spec.Strategy.CustomStrategy, build.Spec.Strategy.CustomStrategy.From.Name)
 	}
 
+	bc.applyLogLevelAnnotation(build)
+	bc.reportInvalidImageLabelTemplates(build)
+
 	// Get a copy of the additional trusted CAs
 	// We want to wire the same copy of the CA data through to avoid data races.
 	additionalCAs := bc.additionalTrustedCAs()
 
+	// The post-commit hook is wrapped in the copy of the build that is passed to the builder.
+	bc.setupPostCommitTimeout(build)
+
 	// Create the build pod spec
 	buildPod, err := bc.createPodSpec(build, additionalCAs)
 	if err != nil {
@@ -1253,6 +1599,14 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 		case common.ErrEnvVarResolver:
 			update = transitionToPhase(buildv1.BuildPhaseError, buildv1.StatusReasonUnresolvableEnvironmentVariable, fmt.Sprintf("%v, %v",
 				"Unable to resolve build environment variable reference.", err.Error()))
+		case *strategy.BuildVolumeError:
+			update = transitionToPhase(buildv1.BuildPhaseFailed, buildutil.StatusReasonInvalidBuildVolume, fmt.Sprintf("%v: %v",
+				"Invalid build volumes", err.Error()))
+			return update, nil
+		case *buildDefaultsError:
+			update = transitionToPhase(buildv1.BuildPhaseFailed, buildutil.StatusReasonInvalidBuildDefaults, fmt.Sprintf("%v: %v",
+				"Invalid build defaults", err.Error()))
+			return update, nil
 		default:
 			update.setReason(buildv1.StatusReasonCannotCreateBuildPodSpec)
 			update.setMessage(fmt.Sprintf("Failed to create pod spec: %s", err.Error()))
@@ -1275,6 +1629,17 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 		utilruntime.HandleError(err)
 		return update, nil
 	}
+	bc.setupTargetArchitecture(build, buildPod, targetArch)
+
+	cacheClaimName, err := bc.ensureBuildCacheClaim(build)
+	if err != nil {
+		update.setReason(buildv1.StatusReasonCannotCreateBuildPod)
+		update.setMessage(fmt.Sprintf("Failed creating build cache claim: %s", err.Error()))
+		return update, err
+	}
+	if len(cacheClaimName) > 0 {
+		setupBuildCache(buildPod, cacheClaimName)
+	}
 
 	klog.V(4).Infof("Pod %s/%s for build %s is about to be created", build.Namespace, buildPod.Name, buildDesc(build))
 	pod, err := bc.podClient.Pods(build.Namespace).Create(context.TODO(), buildPod, metav1.CreateOptions{})
@@ -1283,6 +1648,11 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 		bc.recorder.Eventf(build, corev1.EventTypeWarning, "FailedCreate", "Error creating build pod: %v", err)
 		update.setReason(buildv1.StatusReasonCannotCreateBuildPod)
 		update.setMessage(fmt.Sprintf("Failed creating build pod: %s", err.Error()))
+		if quotaErr := parseQuotaExceeded(err); quotaErr != nil {
+			metrics.RecordQuotaBlockedPodCreation()
+			setQuotaExceeded(build, update, quotaErr)
+			return update, quotaErr
+		}
 		return update, fmt.Errorf("failed to create build pod: %v", err)
 
 	} else if err != nil {
@@ -1382,6 +1752,15 @@ func (bc *BuildController) createBuildPod(build *buildv1.Build) (*buildUpdate, e
 	if build.Spec.Output.To != nil {
 		update.setOutputRef(build.Spec.Output.To.Name)
 	}
+	if sourceSecret != nil {
+		setSourceSecretSelected(update, sourceSecret)
+	}
+	// Record the environment variables the build overrides stripped from custom builds
+	if podBuild, err := common.GetBuildFromPod(buildPod); err == nil {
+		if strippedEnv, ok := podBuild.Annotations[buildutil.BuildStrippedEnvAnnotation]; ok {
+			update.setStrippedEnv(strippedEnv)
+		}
+	}
 
 	return update, nil
 }
@@ -1391,6 +1770,9 @@ func (bc *BuildController) handleActiveBuild(build *buildv1.Build, pod *corev1.P
 	if pod == nil {
 		pod = bc.findMissingPod(build)
 		if pod == nil {
+			if update, err := bc.rescheduleLostPod(build); update != nil || err != nil {
+				return update, err
+			}
 			klog.V(4).Infof("Failed to find the build pod for build %s. Moving it to Error state", buildDesc(build))
 			return transitionToPhase(buildv1.BuildPhaseError, buildv1.StatusReasonBuildPodDeleted, "The pod for this build was deleted before the build completed."), nil
 		}
@@ -1412,6 +1794,13 @@ func (bc *BuildController) handleActiveBuild(build *buildv1.Build, pod *corev1.P
 			}
 		}
 	}
+	// The proxy certificate authorities of builds whose build pod did not start yet follow the
+	// changes of the trusted CA bundle of the cluster.
+	if podPhase == corev1.PodPending {
+		if err := bc.refreshBuildGlobalCAConfigMap(build, pod); err != nil {
+			return nil, err
+		}
+	}
 	switch podPhase {
 	case corev1.PodPending:
 		// only move to pending if phase is new; if already at a subsequent phase, then we possibly have multiple leaders
@@ -1459,10 +1848,12 @@ func (bc *BuildController) handleActiveBuild(build *buildv1.Build, pod *corev1.P
 		}
 	case corev1.PodFailed:
 		if isOOMKilled(pod) {
-			update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonOutOfMemoryKilled, "The build pod was killed due to an out of memory condition.")
+			update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonOutOfMemoryKilled, oomKilledMessage(pod))
 		} else if isPodEvicted(pod) {
 			// Use the pod status message to report why the build pod was evicted.
-			update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonBuildPodEvicted, pod.Status.Message)
+			update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonBuildPodEvicted, evictedMessage(pod))
+		} else if isPodDeadlineExceeded(pod) {
+			update = transitionToPhase(buildv1.BuildPhaseFailed, buildutil.StatusReasonBuildTimeout, deadlineExceededMessage(pod))
 		} else if build.Status.Phase != buildv1.BuildPhaseFailed {
 			// If a DeletionTimestamp has been set, it means that the pod will
 			// soon be deleted. The build should be transitioned to the Error phase.
@@ -1473,7 +1864,7 @@ func (bc *BuildController) handleActiveBuild(build *buildv1.Build, pod *corev1.P
 			}
 		}
 	}
-	return update, nil
+	return bc.checkBuildDeadlines(build, pod, update)
 }
 
 func isOOMKilled(pod *corev1.Pod) bool {
@@ -1483,19 +1874,67 @@ func isOOMKilled(pod *corev1.Pod) bool {
 	if pod.Status.Reason == "OOMKilled" {
 		return true
 	}
+	return len(terminatedContainer(pod, "OOMKilled")) > 0
+}
+
+// terminatedContainer returns the name of the first init or build container of the pod that was
+// terminated for the given reason, or an empty string if there is none.
+func terminatedContainer(pod *corev1.Pod, reason string) string {
 	for _, c := range pod.Status.InitContainerStatuses {
 		terminated := c.State.Terminated
-		if terminated != nil && terminated.Reason == "OOMKilled" {
-			return true
+		if terminated != nil && terminated.Reason == reason {
+			return c.Name
 		}
 	}
 	for _, c := range pod.Status.ContainerStatuses {
 		terminated := c.State.Terminated
-		if terminated != nil && terminated.Reason == "OOMKilled" {
-			return true
+		if terminated != nil && terminated.Reason == reason {
+			return c.Name
 		}
 	}
-	return false
+	return ""
+}
+
+// oomKilledMessage returns the message of builds whose pod was OOMKilled, including the memory
+// limit of the container that ran out of memory.
+func oomKilledMessage(pod *corev1.Pod) string {
+	name := terminatedContainer(pod, "OOMKilled")
+	var container *corev1.Container
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			if containers[i].Name == name {
+				container = &containers[i]
+			}
+		}
+	}
+	// the pod itself was OOMKilled, report the limit of the build container
+	if container == nil && len(name) == 0 && len(pod.Spec.Containers) > 0 {
+		container = &pod.Spec.Containers[0]
+	}
+	if container == nil {
+		return "The build pod was killed due to an out of memory condition."
+	}
+	limit, ok := container.Resources.Limits[corev1.ResourceMemory]
+	if !ok || limit.IsZero() {
+		return fmt.Sprintf("The build pod was killed due to an out of memory condition: container %q has no memory limit.", container.Name)
+	}
+	return fmt.Sprintf("The build pod was killed due to an out of memory condition: container %q exceeded its memory limit of %s.", container.Name, limit.String())
+}
+
+// isPodDeadlineExceeded returns true if the build pod was stopped because it ran longer than its
+// activeDeadlineSeconds.
+func isPodDeadlineExceeded(pod *corev1.Pod) bool {
+	if pod == nil {
+		return false
+	}
+	return pod.Status.Reason == "DeadlineExceeded"
+}
+
+func deadlineExceededMessage(pod *corev1.Pod) string {
+	if pod.Spec.ActiveDeadlineSeconds == nil {
+		return "The build exceeded its completion deadline."
+	}
+	return fmt.Sprintf("The build exceeded its completion deadline of %d seconds.", *pod.Spec.ActiveDeadlineSeconds)
 }
 
 func isPodEvicted(pod *corev1.Pod) bool {
@@ -1505,7 +1944,36 @@ func isPodEvicted(pod *corev1.Pod) bool {
 	if pod.Status.Reason == "Evicted" {
 		return true
 	}
-	return false
+	if evictionCondition(pod) != nil {
+		return true
+	}
+	return len(terminatedContainer(pod, "Evicted")) > 0
+}
+
+// evictionCondition returns the DisruptionTarget condition of a pod that was evicted by the
+// kubelet or through the eviction API.
+func evictionCondition(pod *corev1.Pod) *corev1.PodCondition {
+	for i, c := range pod.Status.Conditions {
+		if c.Type != corev1.DisruptionTarget || c.Status != corev1.ConditionTrue {
+			continue
+		}
+		if c.Reason == corev1.PodReasonTerminationByKubelet || c.Reason == "EvictionByEvictionAPI" {
+			return &pod.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// evictedMessage returns the message of builds whose pod was evicted, which explains why the pod
+// was evicted.
+func evictedMessage(pod *corev1.Pod) string {
+	if len(pod.Status.Message) > 0 {
+		return pod.Status.Message
+	}
+	if condition := evictionCondition(pod); condition != nil && len(condition.Message) > 0 {
+		return condition.Message
+	}
+	return "The build pod was evicted."
 }
 
 // handleCompletedBuild will only be called on builds that are already in a terminal phase.  It is used to setup the
@@ -1514,11 +1982,15 @@ func (bc *BuildController) handleCompletedBuild(build *buildv1.Build, pod *corev
 
 	update := &buildUpdate{}
 	if isOOMKilled(pod) {
-		update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonOutOfMemoryKilled, "The build pod was killed due to an out of memory condition.")
+		update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonOutOfMemoryKilled, oomKilledMessage(pod))
+	} else if build.Status.Phase == buildv1.BuildPhaseFailed && build.Status.Reason != buildv1.StatusReasonBuildPodEvicted && isPodEvicted(pod) {
+		// the build pod may have reported a generic failure before it was evicted
+		update = transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonBuildPodEvicted, evictedMessage(pod))
 	}
 	setBuildCompletionData(build, pod, update)
+	err := bc.tagAdditionalTags(build, update)
 
-	return update, nil
+	return update, err
 }
 
 // updateBuild is the single place where any update to a build is done in the build controller.
@@ -1526,6 +1998,7 @@ func (bc *BuildController) handleCompletedBuild(build *buildv1.Build, pod *corev
 // and apply the buildUpdate object as a patch.
 func (bc *BuildController) updateBuild(build *buildv1.Build, update *buildUpdate, pod *corev1.Pod) error {
 
+	var tagErr error
 	stateTransition := false
 	// Check whether we are transitioning to a different build phase
 	if update.phase != nil && (*update.phase) != build.Status.Phase {
@@ -1543,7 +2016,7 @@ func (bc *BuildController) updateBuild(build *buildv1.Build, update *buildUpdate
 
 	if stateTransition {
 		// Make sure that the transition is valid
-		if !isValidTransition(build.Status.Phase, *update.phase) {
+		if !isValidTransition(build.Status.Phase, *update.phase) && !isPodReschedule(build, update) {
 			return fmt.Errorf("invalid phase transition %s -> %s", buildDesc(build), *update.phase)
 		}
 
@@ -1556,6 +2029,7 @@ func (bc *BuildController) updateBuild(build *buildv1.Build, update *buildUpdate
 		// Update build completion timestamp if transitioning to a terminal phase
 		if buildutil.IsTerminalPhase(*update.phase) {
 			setBuildCompletionData(build, pod, update)
+			tagErr = bc.tagAdditionalTags(build, update)
 		}
 		klog.V(4).Infof("Updating build %s -> %s%s", buildDesc(build), *update.phase, reasonText)
 	}
@@ -1588,22 +2062,30 @@ func (bc *BuildController) updateBuild(build *buildv1.Build, update *buildUpdate
 			bc.recorder.Eventf(patchedBuild, corev1.EventTypeNormal, buildutil.BuildFailedEventReason, fmt.Sprintf(buildutil.BuildFailedEventMessage,
 				patchedBuild.Namespace, patchedBuild.Name))
 		}
+		if *update.phase != buildv1.BuildPhaseNew {
+			metrics.RecordBuildNotPending(patchedBuild)
+		}
 		if buildutil.IsTerminalPhase(*update.phase) {
+			metrics.RecordBuildCompleted(patchedBuild)
+			if bc.buildConfigMetricsNamespaces.Has(patchedBuild.Namespace) {
+				metrics.RecordBuildConfigBuildCompleted(patchedBuild)
+			}
 			bc.handleBuildCompletion(patchedBuild)
 		}
 	}
-	return nil
+	return tagErr
 }
 
 func (bc *BuildController) handleBuildCompletion(build *buildv1.Build) {
 	bcName := sharedbuildutil.ConfigNameForBuild(build)
 	if len(strings.TrimSpace(bcName)) != 0 {
 		bc.enqueueBuildConfig(build.Namespace, bcName)
-		if err := common.HandleBuildPruning(bcName, build.Namespace, bc.buildLister, bc.buildConfigLister, bc.buildDeleter); err != nil {
+		if err := common.HandleBuildPruning(bcName, build.Namespace, bc.buildLister, bc.buildConfigLister, bc.buildDeleter, bc.buildRetention); err != nil {
 			utilruntime.HandleError(fmt.Errorf("failed to prune builds for %s/%s: %v", build.Namespace, build.Name, err))
 		}
 	}
-
+	bc.enqueueQueuedBuilds(build.Namespace)
+	bc.enqueueBuildsWaitingForCapacity()
 }
 
 func (bc *BuildController) enqueueBuildConfig(ns, name string) {
@@ -1736,6 +2218,7 @@ func (bc *BuildController) podDeleted(obj interface{}) {
 func (bc *BuildController) buildAdded(obj interface{}) {
 	build := obj.(*buildv1.Build)
 	bc.enqueueBuild(build)
+	bc.scheduleBuildRetry(build)
 }
 
 // buildUpdated is called by the build informer event handler whenever a build
@@ -1743,6 +2226,7 @@ func (bc *BuildController) buildAdded(obj interface{}) {
 func (bc *BuildController) buildUpdated(old, cur interface{}) {
 	build := cur.(*buildv1.Build)
 	bc.enqueueBuild(build)
+	bc.scheduleBuildRetry(build)
 }
 
 // buildDeleted is called by the build informer event handler whenever a build
@@ -1761,12 +2245,18 @@ func (bc *BuildController) buildDeleted(obj interface{}) {
 			return
 		}
 	}
+	metrics.RecordBuildNotPending(build)
 	// If the build was not in a complete state, poke the buildconfig to run the next build
 	if !buildutil.IsBuildComplete(build) {
 		bcName := sharedbuildutil.ConfigNameForBuild(build)
 		if len(strings.TrimSpace(bcName)) != 0 {
 			bc.enqueueBuildConfig(build.Namespace, bcName)
 		}
+		// Free the slot of the build under the concurrent build limit
+		if build.Status.Phase != buildv1.BuildPhaseNew {
+			bc.enqueueQueuedBuilds(build.Namespace)
+			bc.enqueueBuildsWaitingForCapacity()
+		}
 	}
 }
 
@@ -1812,7 +2302,9 @@ func (bc *BuildController) handleBuildError(err error, key interface{}) {
 		return
 	}
 
-	if bc.buildQueue.NumRequeues(key) < maxRetries {
+	// Builds denied by a resource quota are retried with backoff until the quota admits them, and
+	// completed builds until their additional tags are tagged.
+	if bc.buildQueue.NumRequeues(key) < maxRetries || isQuotaExceeded(err) || isAdditionalTagsError(err) {
 		klog.V(4).Infof("Retrying key %v: %v", key, err)
 		bc.buildQueue.AddRateLimited(key)
 		return
@@ -1841,6 +2333,25 @@ func (bc *BuildController) handleBuildConfigError(err error, key interface{}) {
 	bc.buildConfigQueue.Forget(key)
 }
 
+// handleBuildRetryError is called by the buildRetry work loop to check the return of calling handleBuildRetry.
+// If an error occurred, then the key is re-added to the buildRetryQueue unless it has been retried too many
+// times.
+func (bc *BuildController) handleBuildRetryError(err error, key interface{}) {
+	if err == nil {
+		bc.buildRetryQueue.Forget(key)
+		return
+	}
+
+	if bc.buildRetryQueue.NumRequeues(key) < maxRetries {
+		klog.V(4).Infof("Retrying key %v: %v", key, err)
+		bc.buildRetryQueue.AddRateLimited(key)
+		return
+	}
+
+	klog.V(2).Infof("Giving up retrying %v: %v", key, err)
+	bc.buildRetryQueue.Forget(key)
+}
+
 // createBuildGlobalCAConfigMap creates a ConfigMap container certificate authorities used by the build pod
 // that are injected via the platform's proxy support based on setting a particular annotation on the config map
 func (bc *BuildController) createBuildGlobalCAConfigMap(build *buildv1.Build, buildPod *corev1.Pod, update *buildUpdate) (*buildUpdate, error) {
@@ -1872,14 +2383,15 @@ func (bc *BuildController) createBuildCAConfigMap(build *buildv1.Build, buildPod
 
 // createBuildCAConfigMapSpec creates a ConfigMap template to hold certificate authorities
 // used by the build pod.
-// The returned ConfigMap has an owner reference to the provided pod, ensuring proper
-// garbage collection.
+// The returned ConfigMap has owner references to the provided pod and to the build, ensuring
+// proper garbage collection.
 func (bc *BuildController) createBuildCAConfigMapSpec(build *buildv1.Build, buildPod *corev1.Pod, caData map[string]string) *corev1.ConfigMap {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: buildutil.GetBuildCAConfigMapName(build),
 			OwnerReferences: []metav1.OwnerReference{
 				makeBuildPodOwnerRef(buildPod),
+				strategy.MakeOwnerReference(build),
 			},
 		},
 		Data: caData,
@@ -1911,13 +2423,15 @@ func (bc *BuildController) createBuildCAConfigMapSpec(build *buildv1.Build, buil
 }
 
 // createBuildGlobalCAConfigMapSpec creates a ConfigMap template to hold certificate authorities provided by the platform's proxy support
-// to be used by thebuild pod.  The returned ConfigMap has an owner reference to the provided pod, ensuring proper garbage collection.
+// to be used by thebuild pod.  The returned ConfigMap has owner references to the provided pod and to the build, ensuring proper
+// garbage collection.
 func (bc *BuildController) createBuildGlobalCAConfigMapSpec(build *buildv1.Build, buildPod *corev1.Pod) *corev1.ConfigMap {
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: buildutil.GetBuildGlobalCAConfigMapName(build),
 			OwnerReferences: []metav1.OwnerReference{
 				makeBuildPodOwnerRef(buildPod),
+				strategy.MakeOwnerReference(build),
 			},
 		},
 		Data: map[string]string{
@@ -1925,7 +2439,7 @@ func (bc *BuildController) createBuildGlobalCAConfigMapSpec(build *buildv1.Build
 		},
 	}
 
-	globalCAMap, err := bc.controllerManagerConfigMapStore.ConfigMaps("openshift-controller-manager").Get("openshift-user-ca")
+	globalCAMap, err := bc.controllerManagerConfigMapStore.ConfigMaps(trustedCABundleNamespace).Get(trustedCABundleName)
 	// If a trusted CA is not configured on the cluster proxy config, this ConfigMap will not be present.
 	if errors.IsNotFound(err) {
 		return cm
@@ -1981,6 +2495,7 @@ func (bc *BuildController) createBuildSystemConfigMapSpec(build *buildv1.Build,
 			Name: buildutil.GetBuildSystemConfigMapName(build),
 			OwnerReferences: []metav1.OwnerReference{
 				makeBuildPodOwnerRef(buildPod),
+				strategy.MakeOwnerReference(build),
 			},
 		},
 		Data: make(map[string]string),
@@ -2394,8 +2909,8 @@ func (bc *BuildController) configMapDeleted(obj interface{}) {
 
 // checkForNonExistantResources checks for Config Maps, Secrets and Volumes used by the Build
 // which might not exist in the project. In case it finds non existing resources, it
-// fills in the buildUpdate object, setting the message enumerating missing resources and
-// returns it. In case of an error, it just returns an error.
+// returns a buildUpdate that waits for them or fails the build with a message
+// enumerating the missing resources. In case of an error, it just returns an error.
 func (bc *BuildController) checkForNonExistantResources(build *buildv1.Build) (*buildUpdate, error) {
 	update := &buildUpdate{}
 
@@ -2439,18 +2954,9 @@ func (bc *BuildController) checkForNonExistantResources(build *buildv1.Build) (*
 	if len(missingResources) > 0 {
 		nonExistantMessage := fmt.Sprintf("These resources do not exist:%v", missingResources)
 
-		// Only fail if Build type is binary, otherwise update the build
-		// setting an error message
-		// This will show build error for the user, letting the Build to be
-		// reconciled again, later, and also Pod will not get created until this
-		// problem is fixed
-		if build.Spec.Source.Type == buildv1.BuildSourceBinary {
-			update.setPhase(buildv1.BuildPhaseFailed)
-		}
-		update.setReason(buildv1.StatusReasonGenericBuildFailed)
-		update.setMessage(nonExistantMessage)
-
-		return update, nil
+		// Binary builds fail right away since their input can not be uploaded again, other
+		// builds wait for the resources for a short while before they fail.
+		return bc.waitForInputResources(build, nonExistantMessage), nil
 	}
 
 	return nil, nil
@@ -2477,12 +2983,38 @@ func (bc *BuildController) checkConfigMapsExist(build *buildv1.Build) ([]string,
 	return nonExistantConfigMaps, nil
 }
 
-// checkSecretsExist checks whether all Secrets specified by the Build are existing
+// checkSecretsExist checks whether all Secrets specified by the Build are existing,
+// including its source secret, the push secret of its output and the pull secrets of
+// its strategy and source images
 // if they are not, return the list of all non existing Secrets
 func (bc *BuildController) checkSecretsExist(build *buildv1.Build) ([]string, error) {
-	nonExistantSecrets := make([]string, 0, 3)
+	names := make([]string, 0, len(build.Spec.Source.Secrets)+3)
 	for _, cm := range build.Spec.Source.Secrets {
-		name := cm.Secret.Name
+		names = append(names, cm.Secret.Name)
+	}
+	if build.Spec.Strategy.CustomStrategy != nil {
+		for _, secret := range build.Spec.Strategy.CustomStrategy.Secrets {
+			names = append(names, secret.SecretSource.Name)
+		}
+	}
+	// The source, push and pull secrets are mounted separately into the build pod
+	secrets := []*corev1.LocalObjectReference{build.Spec.Source.SourceSecret, build.Spec.Output.PushSecret, strategyPullSecret(build)}
+	for _, image := range build.Spec.Source.Images {
+		secrets = append(secrets, image.PullSecret)
+	}
+	for _, secret := range secrets {
+		if secret != nil && len(secret.Name) > 0 {
+			names = append(names, secret.Name)
+		}
+	}
+
+	nonExistantSecrets := make([]string, 0, 3)
+	seen := sets.New[string]()
+	for _, name := range names {
+		if seen.Has(name) {
+			continue
+		}
+		seen.Insert(name)
 		_, err := bc.secretStore.Secrets(build.Namespace).Get(name)
 
 		if err != nil && errors.IsNotFound(err) {
@@ -2498,6 +3030,19 @@ func (bc *BuildController) checkSecretsExist(build *buildv1.Build) ([]string, er
 	return nonExistantSecrets, nil
 }
 
+// strategyPullSecret returns the secret the build pulls the image of its strategy with.
+func strategyPullSecret(build *buildv1.Build) *corev1.LocalObjectReference {
+	switch {
+	case build.Spec.Strategy.DockerStrategy != nil:
+		return build.Spec.Strategy.DockerStrategy.PullSecret
+	case build.Spec.Strategy.SourceStrategy != nil:
+		return build.Spec.Strategy.SourceStrategy.PullSecret
+	case build.Spec.Strategy.CustomStrategy != nil:
+		return build.Spec.Strategy.CustomStrategy.PullSecret
+	}
+	return nil
+}
+
 // checkBuildVolumeSources checks whether all Secrets and ConfigMaps specified by the
 // Build Volume Sources are existing
 // if they are not, return the lists of all non existing ConfigMaps and Secrets
@@ -2687,6 +3232,13 @@ func setBuildCompletionData(build *buildv1.Build, pod *corev1.Pod, update *build
 		}
 	}
 
+	if build.Status.CompletionTimestamp == nil {
+		setBuildImageDigest(build, update)
+		setBuildPushFailure(build, pod, update)
+		setBuildPostCommitTimeout(build, pod, update)
+		setBuildStages(build, update)
+	}
+
 }
 
 // hasError returns true if any error (aggregate or no) matches any of the
@@ -19,11 +19,13 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
@@ -33,6 +35,7 @@ import (
 	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/testutil"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	configv1 "github.com/openshift/api/config/v1"
@@ -40,6 +43,7 @@ import (
 	buildv1client "github.com/openshift/client-go/build/clientset/versioned"
 	fakebuildv1client "github.com/openshift/client-go/build/clientset/versioned/fake"
 	buildv1informer "github.com/openshift/client-go/build/informers/externalversions"
+	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
 	configv1client "github.com/openshift/client-go/config/clientset/versioned"
 	fakeconfigv1client "github.com/openshift/client-go/config/clientset/versioned/fake"
 	configv1informer "github.com/openshift/client-go/config/informers/externalversions"
@@ -57,6 +61,7 @@ import (
 	"github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
 	"github.com/openshift/openshift-controller-manager/pkg/build/controller/policy"
 	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+	metrics "github.com/openshift/openshift-controller-manager/pkg/build/metrics/prometheus"
 )
 
 const (
@@ -167,6 +172,12 @@ func TestHandleBuild(t *testing.T) {
 		build.Status.CompletionTimestamp = &now
 		return build
 	}
+	withDeadlineExceeded := func(pod *corev1.Pod) *corev1.Pod {
+		deadline := int64(600)
+		pod.Spec.ActiveDeadlineSeconds = &deadline
+		pod.Status.Reason = "DeadlineExceeded"
+		return pod
+	}
 	withLogSnippet := func(build *buildv1.Build) *buildv1.Build {
 		build.Status.LogSnippet = "termination message"
 		return build
@@ -299,6 +310,18 @@ func TestHandleBuild(t *testing.T) {
 				completionTime(now).
 				update,
 		},
+		{
+			name:  "running -> failed on completion deadline",
+			build: build(buildv1.BuildPhaseRunning),
+			pod:   withDeadlineExceeded(pod(corev1.PodFailed)),
+			expectUpdate: newUpdate().
+				phase(buildv1.BuildPhaseFailed).
+				reason(buildutil.StatusReasonBuildTimeout).
+				message("The build exceeded its completion deadline of 600 seconds.").
+				startTime(now).
+				completionTime(now).
+				update,
+		},
 		{
 			name:         "pending -> pending",
 			build:        build(buildv1.BuildPhasePending),
@@ -527,6 +550,84 @@ func TestWorkWithNewBuild(t *testing.T) {
 	}
 }
 
+// TestBuildDurationMetrics drives builds to a terminal phase and checks that their duration is
+// observed by strategy and phase.
+func TestBuildDurationMetrics(t *testing.T) {
+	metrics.Register()
+	started := metav1.NewTime(time.Now().Add(-2 * time.Minute).Round(time.Second))
+
+	tests := []struct {
+		name          string
+		build         *buildv1.Build
+		podPhase      corev1.PodPhase
+		strategy      string
+		expectedPhase buildv1.BuildPhase
+	}{
+		{
+			name:          "docker build completes",
+			build:         dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{})),
+			podPhase:      corev1.PodSucceeded,
+			strategy:      "Docker",
+			expectedPhase: buildv1.BuildPhaseComplete,
+		},
+		{
+			name:          "source build fails",
+			build:         sourceStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{})),
+			podPhase:      corev1.PodFailed,
+			strategy:      "Source",
+			expectedPhase: buildv1.BuildPhaseFailed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics.BuildDuration.Reset()
+			build := tc.build
+			build.Status.StartTimestamp = &started
+			common.SetBuildPodNameAnnotation(build, buildutil.GetBuildPodName(build))
+			pod := mockBuildPod(build)
+			pod.Status.Phase = tc.podPhase
+			exitCode := int32(0)
+			if tc.podPhase == corev1.PodFailed {
+				exitCode = 1
+			}
+			pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+				{
+					Name: "container",
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode},
+					},
+				},
+			}
+
+			var patchedBuild *buildv1.Build
+			buildClient := fakeBuildClient(build)
+			buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds", applyBuildPatchReaction(t, build, &patchedBuild))
+			bc := newFakeBuildController(buildClient, nil, fakeKubeExternalClientSet(pod, registryCAConfigMap), nil, nil)
+			defer bc.stop()
+
+			if err := bc.handleBuild(build); err != nil {
+				t.Fatal(err)
+			}
+			if patchedBuild == nil || patchedBuild.Status.Phase != tc.expectedPhase {
+				t.Fatalf("expected the build to transition to %s, got %v", tc.expectedPhase, patchedBuild)
+			}
+
+			observer := metrics.BuildDuration.WithLabelValues(tc.strategy, string(tc.expectedPhase))
+			count, err := testutil.GetHistogramMetricCount(observer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum, err := testutil.GetHistogramMetricValue(observer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != 1 || sum < 119 {
+				t.Errorf("expected one observation of at least two minutes, got %d observations summing to %vs", count, sum)
+			}
+		})
+	}
+}
+
 func TestCreateBuildPod(t *testing.T) {
 	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
 	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
@@ -588,6 +689,545 @@ func TestCreateBuildPod(t *testing.T) {
 	}
 }
 
+func TestUpdateBuildBuildConfigMetrics(t *testing.T) {
+	metrics.Register()
+	tests := []struct {
+		name       string
+		namespaces []string
+		recorded   bool
+	}{
+		{
+			name:       "allowed namespace",
+			namespaces: []string{"other", "namespace"},
+			recorded:   true,
+		},
+		{
+			name:       "namespace not allowed",
+			namespaces: []string{"other"},
+		},
+		{
+			name: "no allowed namespaces",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			metrics.BuildConfigBuilds.Reset()
+			metrics.BuildConfigLastSuccessfulBuild.Reset()
+			for _, phase := range []buildv1.BuildPhase{buildv1.BuildPhaseFailed, buildv1.BuildPhaseComplete, buildv1.BuildPhaseComplete} {
+				build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+				var patchedBuild *buildv1.Build
+				buildClient := fakeBuildClient(build)
+				buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds", applyBuildPatchReaction(t, build, &patchedBuild))
+				bc := newFakeBuildController(buildClient, nil, nil, nil, nil)
+				bc.buildConfigMetricsNamespaces = sets.New(tc.namespaces...)
+
+				update := transitionToPhase(phase, "", "")
+				update.setCompletionTime(metav1.Now())
+				if err := bc.updateBuild(build, update, nil); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				bc.stop()
+			}
+
+			expected := map[buildv1.BuildPhase]float64{buildv1.BuildPhaseFailed: 1, buildv1.BuildPhaseComplete: 2}
+			for phase, count := range expected {
+				if !tc.recorded {
+					count = 0
+				}
+				value, err := testutil.GetCounterMetricValue(metrics.BuildConfigBuilds.WithLabelValues("namespace", "test-bc", string(phase)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if value != count {
+					t.Errorf("expected %v %s builds, got %v", count, phase, value)
+				}
+			}
+			lastSuccess, err := testutil.GetGaugeMetricValue(metrics.BuildConfigLastSuccessfulBuild.WithLabelValues("namespace", "test-bc"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (lastSuccess != 0) != tc.recorded {
+				t.Errorf("expected the last successful build to be recorded: %v, got %v", tc.recorded, lastSuccess)
+			}
+		})
+	}
+}
+
+func TestHandleNewBuildPendingReasons(t *testing.T) {
+	metrics.Register()
+	reasons := []string{
+		metrics.PendingReasonSerialPolicy,
+		metrics.PendingReasonConcurrencyLimit,
+		metrics.PendingReasonImageNotResolved,
+		metrics.PendingReasonQuotaExceeded,
+	}
+	gauges := func() map[string]float64 {
+		values := map[string]float64{}
+		for _, reason := range reasons {
+			value, err := testutil.GetGaugeMetricValue(metrics.PendingBuilds.WithLabelValues(reason))
+			if err != nil {
+				t.Fatal(err)
+			}
+			values[reason] = value
+		}
+		return values
+	}
+	serialized := func() float64 {
+		value, err := testutil.GetCounterMetricValue(metrics.SerializedBuilds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	initialGauges, initialSerialized := gauges(), serialized()
+	expectPending := func(step, reason string, serializedBuilds float64) {
+		for r, value := range gauges() {
+			expected := initialGauges[r]
+			if r == reason {
+				expected++
+			}
+			if value != expected {
+				t.Errorf("%s: expected %v builds pending for %s, got %v", step, expected, r, value)
+			}
+		}
+		if value := serialized(); value != initialSerialized+serializedBuilds {
+			t.Errorf("%s: expected %v serialized builds, got %v", step, initialSerialized+serializedBuilds, value-initialSerialized)
+		}
+	}
+
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+	quotaExceeded := true
+	kubeClient.(*fake.Clientset).PrependReactor("create", "pods", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		if !quotaExceeded {
+			return false, nil, nil
+		}
+		return true, nil, errors.NewForbidden(corev1.Resource("pods"), "data-build-build", fmt.Errorf("exceeded quota: compute-resources"))
+	})
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	runPolicy := &fakeRunPolicy{notRunnable: true}
+	bc.runPolicies = []policy.RunPolicy{runPolicy}
+
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+	build.Name = "pending-build"
+	running := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+	running.Name = "running-build"
+	buildIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	buildIndexer.Add(build)
+	buildIndexer.Add(running)
+	bc.buildLister = buildv1lister.NewBuildLister(buildIndexer)
+
+	handle := func() *buildUpdate {
+		update, _ := bc.handleNewBuild(build, nil)
+		return update
+	}
+
+	handle()
+	expectPending("serial policy", metrics.PendingReasonSerialPolicy, 1)
+	handle()
+	expectPending("serial policy again", metrics.PendingReasonSerialPolicy, 1)
+
+	runPolicy.notRunnable = false
+	bc.defaultMaxConcurrentBuilds = 1
+	handle()
+	expectPending("concurrency limit", metrics.PendingReasonConcurrencyLimit, 1)
+
+	bc.defaultMaxConcurrentBuilds = 0
+	build.Spec.Output.To = &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "missing:latest"}
+	handle()
+	expectPending("image not resolved", metrics.PendingReasonImageNotResolved, 1)
+
+	build.Spec.Output.To = nil
+	handle()
+	expectPending("quota exceeded", metrics.PendingReasonQuotaExceeded, 1)
+
+	quotaExceeded = false
+	if update := handle(); update == nil || update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		t.Fatalf("expected the build to start, got %v", update)
+	}
+	expectPending("started", "", 1)
+
+	// builds deleted while pending are no longer counted
+	build = build.DeepCopy()
+	build.Name = "deleted-build"
+	runPolicy.notRunnable = true
+	handle()
+	expectPending("serial policy", metrics.PendingReasonSerialPolicy, 2)
+	bc.buildDeleted(build)
+	expectPending("deleted", "", 2)
+}
+
+func TestCreateBuildPodOwnerReferences(t *testing.T) {
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+	build.UID = "build-uid"
+
+	if _, err := bc.createBuildPod(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectBuildControllerRef := func(kind string, obj metav1.Object) {
+		for _, ref := range obj.GetOwnerReferences() {
+			if ref.Kind != "Build" {
+				continue
+			}
+			if ref.UID != build.UID || ref.Name != build.Name || ref.Controller == nil || !*ref.Controller {
+				t.Errorf("expected %s %s to be controlled by build %s (%s), got owner reference %#v", kind, obj.GetName(), build.Name, build.UID, ref)
+			}
+			return
+		}
+		t.Errorf("expected %s %s to have an owner reference to build %s, got %#v", kind, obj.GetName(), build.Name, obj.GetOwnerReferences())
+	}
+
+	pod, err := kubeClient.CoreV1().Pods("namespace").Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectBuildControllerRef("pod", pod)
+	for _, name := range []string{buildutil.GetBuildCAConfigMapName(build), buildutil.GetBuildGlobalCAConfigMapName(build), buildutil.GetBuildSystemConfigMapName(build)} {
+		configMap, err := kubeClient.CoreV1().ConfigMaps("namespace").Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectBuildControllerRef("configMap", configMap)
+		if !hasBuildPodOwnerRef(pod, configMap) {
+			t.Errorf("expected configMap %s to keep its owner reference to the build pod", name)
+		}
+	}
+}
+
+func TestCreateBuildPodStrippedEnv(t *testing.T) {
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	bc.buildOverrides.DeniedEnvNames = []string{"TOKEN"}
+	build := mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{})
+	build.Spec.Strategy = buildv1.BuildStrategy{
+		CustomStrategy: &buildv1.CustomBuildStrategy{
+			From: corev1.ObjectReference{Kind: "DockerImage", Name: "builder/image"},
+			Env:  []corev1.EnvVar{{Name: "TOKEN", Value: "secret"}, {Name: "MIRROR", Value: "https://mirror.example.com"}},
+		},
+	}
+
+	update, err := bc.createBuildPod(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.strippedEnv == nil || *update.strippedEnv != "TOKEN" {
+		t.Errorf("expected the stripped environment variables to be recorded on the build, got %v", update)
+	}
+	pod, err := kubeClient.CoreV1().Pods("namespace").Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range pod.Spec.Containers[0].Env {
+		if e.Name == "TOKEN" {
+			t.Errorf("expected TOKEN to be stripped from the custom builder environment")
+		}
+	}
+}
+
+func TestCreateBuildPodMissingPushAndPullSecrets(t *testing.T) {
+	existing := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "namespace"}}
+	tests := []struct {
+		name            string
+		pushSecret      string
+		pullSecret      string
+		expectedMessage string
+	}{
+		{
+			name:       "existing secrets",
+			pushSecret: "registry",
+			pullSecret: "registry",
+		},
+		{
+			name:            "missing push secret",
+			pushSecret:      "internal-registry",
+			pullSecret:      "registry",
+			expectedMessage: "These resources do not exist: Secrets [internal-registry]",
+		},
+		{
+			name:            "missing pull secret",
+			pushSecret:      "registry",
+			pullSecret:      "external-registry",
+			expectedMessage: "These resources do not exist: Secrets [external-registry]",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap, existing)
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{
+				To:         &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.io/namespace/app:latest"},
+				PushSecret: &corev1.LocalObjectReference{Name: tc.pushSecret},
+			}))
+			build.Spec.Strategy.DockerStrategy.PullSecret = &corev1.LocalObjectReference{Name: tc.pullSecret}
+			build.CreationTimestamp = metav1.Now()
+
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.expectedMessage) == 0 {
+				if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+					t.Errorf("expected the build pod to be created, got %v", update)
+				}
+				return
+			}
+			if update.phase != nil || len(update.conditions) != 1 || update.conditions[0].Message != tc.expectedMessage {
+				t.Errorf("expected the build to wait for its secrets with message %q, got %v", tc.expectedMessage, update.conditions)
+			}
+		})
+	}
+}
+
+func TestCreateBuildPodBuildVolumes(t *testing.T) {
+	secretVolume := buildv1.BuildVolume{
+		Name: "vault-certs",
+		Source: buildv1.BuildVolumeSource{
+			Type:   buildv1.BuildVolumeSourceTypeSecret,
+			Secret: &corev1.SecretVolumeSource{SecretName: "vault-certs"},
+		},
+		Mounts: []buildv1.BuildVolumeMount{{DestinationPath: "/etc/vault/certs"}},
+	}
+	configMapVolume := buildv1.BuildVolume{
+		Name: "entitlements",
+		Source: buildv1.BuildVolumeSource{
+			Type: buildv1.BuildVolumeSourceTypeConfigMap,
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "entitlements"},
+			},
+		},
+		Mounts: []buildv1.BuildVolumeMount{{DestinationPath: "/etc/pki/entitlement"}},
+	}
+	csiVolume := buildv1.BuildVolume{
+		Name: "shared-cache",
+		Source: buildv1.BuildVolumeSource{
+			Type: buildv1.BuildVolumeSourceTypeCSI,
+			CSI:  &corev1.CSIVolumeSource{Driver: "inline.storage.kubernetes.io"},
+		},
+		Mounts: []buildv1.BuildVolumeMount{{DestinationPath: "/var/cache/shared"}},
+	}
+	workDirVolume := *secretVolume.DeepCopy()
+	workDirVolume.Mounts = []buildv1.BuildVolumeMount{{DestinationPath: buildutil.BuildWorkDirMount + "/inputs"}}
+
+	tests := []struct {
+		name          string
+		volume        buildv1.BuildVolume
+		expectedMount string
+		expectFailure bool
+	}{
+		{
+			name:          "secret",
+			volume:        secretVolume,
+			expectedMount: strategy.PathForBuildVolume("vault-certs"),
+		},
+		{
+			name:          "configmap",
+			volume:        configMapVolume,
+			expectedMount: strategy.PathForBuildVolume("entitlements"),
+		},
+		{
+			name:          "csi",
+			volume:        csiVolume,
+			expectedMount: strategy.PathForBuildVolume("shared-cache"),
+		},
+		{
+			name:          "collision with the build working directory",
+			volume:        workDirVolume,
+			expectFailure: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "vault-certs", Namespace: "namespace"}}
+			configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "entitlements", Namespace: "namespace"}}
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap, secret, configMap)
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+			bc.createStrategy.(*typeBasedFactoryStrategy).dockerBuildStrategy.(*strategy.DockerBuildStrategy).BuildCSIVolumesEnabled = true
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.Strategy.DockerStrategy.Volumes = []buildv1.BuildVolume{tc.volume}
+
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pod, podErr := kubeClient.CoreV1().Pods("namespace").Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+
+			if tc.expectFailure {
+				if update.phase == nil || *update.phase != buildv1.BuildPhaseFailed || update.reason == nil || *update.reason != buildutil.StatusReasonInvalidBuildVolume {
+					t.Errorf("expected the build to fail with reason %s, got %v", buildutil.StatusReasonInvalidBuildVolume, update)
+				}
+				if update.message == nil || !strings.Contains(*update.message, "collides with the build working directory") {
+					t.Errorf("expected a message explaining the collision, got %v", update)
+				}
+				if podErr == nil {
+					t.Errorf("expected no build pod to be created, got %s", pod.Name)
+				}
+				return
+			}
+
+			if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+				t.Errorf("expected the build to be pending, got %v", update)
+			}
+			if podErr != nil {
+				t.Fatalf("expected a build pod to be created: %v", podErr)
+			}
+			found := false
+			for _, mount := range pod.Spec.Containers[0].VolumeMounts {
+				if mount.MountPath == tc.expectedMount {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("expected the build volume to be mounted at %s, got %v", tc.expectedMount, pod.Spec.Containers[0].VolumeMounts)
+			}
+		})
+	}
+}
+func TestCreateBuildPodPriorityClassName(t *testing.T) {
+	tests := []struct {
+		name                  string
+		defaultClass          string
+		buildConfigAnnotation string
+		buildAnnotation       string
+		overrideClass         string
+		expectedPriorityClass string
+	}{
+		{
+			name: "no priority class",
+		},
+		{
+			name:                  "default",
+			defaultClass:          "default-class",
+			expectedPriorityClass: "default-class",
+		},
+		{
+			name:                  "build config annotation wins over default",
+			defaultClass:          "default-class",
+			buildConfigAnnotation: "requested-class",
+			expectedPriorityClass: "requested-class",
+		},
+		{
+			name:                  "build annotation wins over build config annotation",
+			buildConfigAnnotation: "requested-class",
+			buildAnnotation:       "build-class",
+			expectedPriorityClass: "build-class",
+		},
+		{
+			name:                  "override wins over default",
+			defaultClass:          "default-class",
+			overrideClass:         "override-class",
+			expectedPriorityClass: "override-class",
+		},
+		{
+			name:                  "override wins over annotation",
+			defaultClass:          "default-class",
+			buildConfigAnnotation: "requested-class",
+			overrideClass:         "override-class",
+			expectedPriorityClass: "override-class",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buildConfig := &buildv1.BuildConfig{ObjectMeta: metav1.ObjectMeta{Name: "test-bc", Namespace: "namespace"}}
+			if len(tc.buildConfigAnnotation) != 0 {
+				buildConfig.Annotations = map[string]string{buildutil.BuildPriorityClassNameAnnotation: tc.buildConfigAnnotation}
+			}
+			bc := newFakeBuildController(fakeBuildClient(buildConfig), nil, nil, nil, nil)
+			defer bc.stop()
+			bc.buildDefaults.PriorityClassName = tc.defaultClass
+			bc.buildOverrides.PriorityClassName = tc.overrideClass
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			if len(tc.buildAnnotation) != 0 {
+				build.Annotations[buildutil.BuildPriorityClassNameAnnotation] = tc.buildAnnotation
+			}
+			pod, err := bc.createPodSpec(build, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pod.Spec.PriorityClassName != tc.expectedPriorityClass {
+				t.Errorf("expected priority class %q, got %q", tc.expectedPriorityClass, pod.Spec.PriorityClassName)
+			}
+		})
+	}
+}
+
+func TestCreatePodSpecCompletionDeadline(t *testing.T) {
+	deadline := func(seconds int64) *int64 { return &seconds }
+	tests := []struct {
+		name           string
+		build          *int64
+		defaults       *int64
+		legacy         bool
+		pendingTimeout time.Duration
+		expected       int64
+	}{
+		{
+			name:     "strategy default",
+			legacy:   true,
+			expected: 604800,
+		},
+		{
+			name:     "build defaults",
+			defaults: deadline(3600),
+			legacy:   true,
+			expected: 3600,
+		},
+		{
+			name:     "explicit value wins",
+			build:    deadline(600),
+			defaults: deadline(3600),
+			legacy:   true,
+			expected: 600,
+		},
+		{
+			name:     "controller enforced deadline without pending timeout",
+			build:    deadline(600),
+			expected: 604800,
+		},
+		{
+			name:     "controller enforced deadline longer than the strategy default",
+			build:    deadline(1209600),
+			expected: 1209600,
+		},
+		{
+			name:           "controller enforced deadline with pending timeout",
+			defaults:       deadline(3600),
+			pendingTimeout: 5 * time.Minute,
+			expected:       3900,
+		},
+		{
+			name:     "no deadline",
+			expected: 604800,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := newFakeBuildController(nil, nil, nil, nil, nil)
+			defer bc.stop()
+			bc.buildDefaults.CompletionDeadlineSeconds = tc.defaults
+			bc.legacyCompletionDeadline = tc.legacy
+			bc.pendingTimeout = tc.pendingTimeout
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.CompletionDeadlineSeconds = tc.build
+			pod, err := bc.createPodSpec(build, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if pod.Spec.ActiveDeadlineSeconds == nil || *pod.Spec.ActiveDeadlineSeconds != tc.expected {
+				t.Errorf("expected activeDeadlineSeconds %d, got %v", tc.expected, pod.Spec.ActiveDeadlineSeconds)
+			}
+		})
+	}
+}
+
 func TestCreateBuildPodWithImageStreamOutput(t *testing.T) {
 	imageStream := &imagev1.ImageStream{}
 	imageStream.Namespace = "isnamespace"
@@ -1078,6 +1718,29 @@ func TestCancelBuild(t *testing.T) {
 	}
 }
 
+func TestCancelBuildOfDeletedBuildConfig(t *testing.T) {
+	build := mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{})
+	build.Status.Cancelled = true
+	build.Status.Reason = buildutil.StatusReasonBuildConfigDeleted
+	build.Status.Message = "The build was cancelled because its BuildConfig was deleted."
+	bc := BuildController{
+		podClient: fake.NewSimpleClientset().CoreV1(),
+	}
+	update, err := bc.cancelBuild(build)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if update.phase == nil || *update.phase != buildv1.BuildPhaseCancelled {
+		t.Errorf("expected phase to be set to cancelled")
+	}
+	if update.reason == nil || *update.reason != buildutil.StatusReasonBuildConfigDeleted {
+		t.Errorf("expected status reason to be kept as %s, got %v", buildutil.StatusReasonBuildConfigDeleted, update)
+	}
+	if update.message == nil || *update.message != build.Status.Message {
+		t.Errorf("expected status message to be kept as %q, got %v", build.Status.Message, update)
+	}
+}
+
 func TestShouldIgnore(t *testing.T) {
 
 	setCompletionTimestamp := func(build *buildv1.Build) *buildv1.Build {
@@ -1116,6 +1779,11 @@ func TestShouldIgnore(t *testing.T) {
 			build:        setCompletionTimestamp(dockerStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{}))),
 			expectIgnore: true,
 		},
+		{
+			name:         "new pipeline build",
+			build:        pipelineStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{})),
+			expectIgnore: false,
+		},
 		{
 			name:         "running pipeline build",
 			build:        pipelineStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{})),
@@ -2035,6 +2703,13 @@ func dockerStrategy(build *buildv1.Build) *buildv1.Build {
 	return build
 }
 
+func sourceStrategy(build *buildv1.Build) *buildv1.Build {
+	build.Spec.Strategy = buildv1.BuildStrategy{
+		SourceStrategy: &buildv1.SourceBuildStrategy{},
+	}
+	return build
+}
+
 func pipelineStrategy(build *buildv1.Build) *buildv1.Build {
 	build.Spec.Strategy = buildv1.BuildStrategy{
 		JenkinsPipelineStrategy: &buildv1.JenkinsPipelineBuildStrategy{},
@@ -2111,6 +2786,8 @@ func (c *fakeBuildController) start() {
 		c.imageConfigStoreSynced,
 		c.openshiftConfigConfigMapStoreSynced,
 		c.controllerManagerConfigMapStoreSynced,
+		c.namespaceStoreSynced,
+		c.persistentVolumeClaimStoreSynced,
 		c.proxyCfgStoreSynced) {
 		panic("cannot sync cache")
 	}
@@ -2159,6 +2836,8 @@ func newFakeBuildController(buildClient buildv1client.Interface, imageClient ima
 		ImageContentSourcePolicyInformer:   operatorInformers.Operator().V1alpha1().ImageContentSourcePolicies(),
 		ImageDigestMirrorSetInformer:       configInformers.Config().V1().ImageDigestMirrorSets(),
 		ImageTagMirrorSetInformer:          configInformers.Config().V1().ImageTagMirrorSets(),
+		NamespaceInformer:                  kubeExternalInformers.Core().V1().Namespaces(),
+		PersistentVolumeClaimInformer:      kubeExternalInformers.Core().V1().PersistentVolumeClaims(),
 		PodInformer:                        kubeExternalInformers.Core().V1().Pods(),
 		SecretInformer:                     kubeExternalInformers.Core().V1().Secrets(),
 		ConfigMapInformer:                  kubeExternalInformers.Core().V1().ConfigMaps(),
@@ -2169,6 +2848,7 @@ func newFakeBuildController(buildClient buildv1client.Interface, imageClient ima
 		ImageConfigInformer:                configInformers.Config().V1().Images(),
 		KubeClient:                         kubeExternalClient,
 		BuildClient:                        buildClient,
+		ImageClient:                        imageClient,
 		DockerBuildStrategy: &strategy.DockerBuildStrategy{
 			Image: "test/image:latest",
 		},
@@ -2444,10 +3124,11 @@ func mockBuildSystemConfigMap(build *buildv1.Build, pod *corev1.Pod) *corev1.Con
 
 func TestPodStatusReporting(t *testing.T) {
 	cases := []struct {
-		name        string
-		pod         *corev1.Pod
-		isOOMKilled bool
-		isEvicted   bool
+		name               string
+		pod                *corev1.Pod
+		isOOMKilled        bool
+		isEvicted          bool
+		isDeadlineExceeded bool
 	}{
 		{
 			name: "running",
@@ -2593,6 +3274,93 @@ func TestPodStatusReporting(t *testing.T) {
 			},
 			isEvicted: true,
 		},
+		{
+			name: "pod-deadline-exceeded",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "deadline-pod",
+					Namespace: "default",
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "test",
+							Image: "quay.io/coreos/coreos:latest",
+						},
+					},
+				},
+				Status: corev1.PodStatus{
+					Phase:   corev1.PodFailed,
+					Reason:  "DeadlineExceeded",
+					Message: "Pod was active on the node longer than the specified deadline",
+				},
+			},
+			isDeadlineExceeded: true,
+		},
+		{
+			name: "pod-evicted-api",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "evicted-pod",
+					Namespace: "default",
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodFailed,
+					Conditions: []corev1.PodCondition{
+						{
+							Type:    corev1.DisruptionTarget,
+							Status:  corev1.ConditionTrue,
+							Reason:  "EvictionByEvictionAPI",
+							Message: "Eviction API: evicting",
+						},
+					},
+				},
+			},
+			isEvicted: true,
+		},
+		{
+			name: "pod-preempted",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "preempted-pod",
+					Namespace: "default",
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodFailed,
+					Conditions: []corev1.PodCondition{
+						{
+							Type:   corev1.DisruptionTarget,
+							Status: corev1.ConditionTrue,
+							Reason: corev1.PodReasonPreemptionByScheduler,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "container-evicted",
+			pod: &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "evicted-pod",
+					Namespace: "default",
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodFailed,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{
+							Name: "test",
+							State: corev1.ContainerState{
+								Terminated: &corev1.ContainerStateTerminated{
+									ExitCode: 137,
+									Reason:   "Evicted",
+								},
+							},
+						},
+					},
+				},
+			},
+			isEvicted: true,
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -2604,6 +3372,123 @@ func TestPodStatusReporting(t *testing.T) {
 			if evicted != tc.isEvicted {
 				t.Errorf("expected Evicted to be %v, got %v", tc.isEvicted, evicted)
 			}
+			if exceeded := isPodDeadlineExceeded(tc.pod); exceeded != tc.isDeadlineExceeded {
+				t.Errorf("expected DeadlineExceeded to be %v, got %v", tc.isDeadlineExceeded, exceeded)
+			}
+		})
+	}
+}
+
+func TestFailedBuildPodReasons(t *testing.T) {
+	terminated := func(name, reason string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name: name,
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: reason},
+			},
+		}
+	}
+	failedPod := func(reason, message string, limit string, statuses ...corev1.ContainerStatus) *corev1.Pod {
+		pod := &corev1.Pod{
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "git-clone"}},
+				Containers:     []corev1.Container{{Name: "docker-build"}},
+			},
+			Status: corev1.PodStatus{
+				Phase:             corev1.PodFailed,
+				Reason:            reason,
+				Message:           message,
+				ContainerStatuses: statuses,
+			},
+		}
+		if len(limit) > 0 {
+			pod.Spec.Containers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)}
+		}
+		return pod
+	}
+
+	tests := []struct {
+		name            string
+		pod             *corev1.Pod
+		expectedReason  buildv1.StatusReason
+		expectedMessage string
+	}{
+		{
+			name:            "build container oomkilled",
+			pod:             failedPod("", "", "512Mi", terminated("docker-build", "OOMKilled")),
+			expectedReason:  buildv1.StatusReasonOutOfMemoryKilled,
+			expectedMessage: `The build pod was killed due to an out of memory condition: container "docker-build" exceeded its memory limit of 512Mi.`,
+		},
+		{
+			name: "init container oomkilled",
+			pod: func() *corev1.Pod {
+				pod := failedPod("", "", "512Mi")
+				pod.Spec.InitContainers[0].Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("128Mi")}
+				pod.Status.InitContainerStatuses = []corev1.ContainerStatus{terminated("git-clone", "OOMKilled")}
+				return pod
+			}(),
+			expectedReason:  buildv1.StatusReasonOutOfMemoryKilled,
+			expectedMessage: `The build pod was killed due to an out of memory condition: container "git-clone" exceeded its memory limit of 128Mi.`,
+		},
+		{
+			name:            "pod oomkilled without memory limit",
+			pod:             failedPod("OOMKilled", "", ""),
+			expectedReason:  buildv1.StatusReasonOutOfMemoryKilled,
+			expectedMessage: `The build pod was killed due to an out of memory condition: container "docker-build" has no memory limit.`,
+		},
+		{
+			name:            "pod evicted",
+			pod:             failedPod("Evicted", "The node was low on resource: memory.", "", terminated("docker-build", "Error")),
+			expectedReason:  buildv1.StatusReasonBuildPodEvicted,
+			expectedMessage: "The node was low on resource: memory.",
+		},
+		{
+			name:            "build container evicted",
+			pod:             failedPod("", "", "", terminated("docker-build", "Evicted")),
+			expectedReason:  buildv1.StatusReasonBuildPodEvicted,
+			expectedMessage: "The build pod was evicted.",
+		},
+		{
+			name:            "build container failed",
+			pod:             failedPod("", "", "", terminated("docker-build", "Error")),
+			expectedReason:  buildv1.StatusReasonGenericBuildFailed,
+			expectedMessage: "Generic Build failure - check logs for details.",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := &BuildController{}
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+			update, err := bc.handleActiveBuild(build, tc.pod)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if update == nil || update.phase == nil || *update.phase != buildv1.BuildPhaseFailed {
+				t.Fatalf("expected the build to fail, got %v", update)
+			}
+			if *update.reason != tc.expectedReason {
+				t.Errorf("expected reason %s, got %s", tc.expectedReason, *update.reason)
+			}
+			if *update.message != tc.expectedMessage {
+				t.Errorf("expected message %q, got %q", tc.expectedMessage, *update.message)
+			}
+
+			// the build pod may report a generic failure through the build details first
+			build.Status.Phase = buildv1.BuildPhaseFailed
+			build.Status.Reason = buildv1.StatusReasonGenericBuildFailed
+			update, err = bc.handleCompletedBuild(build, tc.pod)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.expectedReason == buildv1.StatusReasonGenericBuildFailed {
+				if update.reason != nil {
+					t.Errorf("expected the reason of the failed build to be kept, got %s", *update.reason)
+				}
+				return
+			}
+			if update.reason == nil || *update.reason != tc.expectedReason {
+				t.Errorf("expected the reason of the failed build to be replaced with %s, got %v", tc.expectedReason, update)
+			}
 		})
 	}
 }
@@ -0,0 +1,219 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	sharedbuildutil "github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+// buildCacheVolumeName is the name of the build pod volume of the persistent build cache.
+const buildCacheVolumeName = "build-cache"
+
+// buildCache is the persistent build cache of the source builds of a BuildConfig, read from its
+// BuildCacheAnnotation.
+type buildCache struct {
+	// ClaimName is the name of an existing claim mounted as the build cache.
+	ClaimName string `json:"claimName,omitempty"`
+	// VolumeClaimTemplate is the template of the claim created for the BuildConfig when ClaimName
+	// is not set.
+	VolumeClaimTemplate *corev1.PersistentVolumeClaimTemplate `json:"volumeClaimTemplate,omitempty"`
+}
+
+func parseBuildCache(value string) (*buildCache, error) {
+	cache := &buildCache{}
+	if err := json.Unmarshal([]byte(value), cache); err != nil {
+		return nil, err
+	}
+	if (len(cache.ClaimName) == 0) == (cache.VolumeClaimTemplate == nil) {
+		return nil, fmt.Errorf("exactly one of claimName and volumeClaimTemplate must be set")
+	}
+	return cache, nil
+}
+
+// buildCacheClaimName returns the name of the claim of the build cache of the BuildConfig.
+func buildCacheClaimName(buildConfig *buildv1.BuildConfig, cache *buildCache) string {
+	if len(cache.ClaimName) > 0 {
+		return cache.ClaimName
+	}
+	return buildConfig.Name + "-build-cache"
+}
+
+// buildCacheFor returns the build cache of a source build and its BuildConfig, or nil when the
+// build has no build cache.
+func (bc *BuildController) buildCacheFor(build *buildv1.Build) (*buildCache, *buildv1.BuildConfig) {
+	if build.Spec.Strategy.SourceStrategy == nil {
+		return nil, nil
+	}
+	bcName := sharedbuildutil.ConfigNameForBuild(build)
+	if len(bcName) == 0 {
+		return nil, nil
+	}
+	buildConfig, err := bc.buildConfigLister.BuildConfigs(build.Namespace).Get(bcName)
+	if err != nil {
+		return nil, nil
+	}
+	value, ok := buildConfig.Annotations[buildutil.BuildCacheAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	cache, err := parseBuildCache(value)
+	if err != nil {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on buildconfig %s/%s: %v", buildutil.BuildCacheAnnotation, value, build.Namespace, bcName, err)
+		return nil, nil
+	}
+	return cache, buildConfig
+}
+
+// isSharedBuildCache returns true if the claim of the build cache can be mounted by the build pods
+// of several nodes at the same time.
+func (bc *BuildController) isSharedBuildCache(buildConfig *buildv1.BuildConfig, cache *buildCache) (bool, error) {
+	accessModes := []corev1.PersistentVolumeAccessMode{}
+	if cache.VolumeClaimTemplate != nil {
+		accessModes = cache.VolumeClaimTemplate.Spec.AccessModes
+	} else {
+		claim, err := bc.persistentVolumeClaimStore.PersistentVolumeClaims(buildConfig.Namespace).Get(cache.ClaimName)
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		accessModes = claim.Spec.AccessModes
+		if len(claim.Status.AccessModes) > 0 {
+			accessModes = claim.Status.AccessModes
+		}
+	}
+	for _, mode := range accessModes {
+		if mode == corev1.ReadWriteMany {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkBuildCacheInUse serializes the builds of a BuildConfig whose build cache claim can only be
+// mounted by one node: a build only gets its build pod when no other build of the BuildConfig has
+// one, and no build created before it is waiting for the claim. When the build has to wait, queued
+// is true and the returned update, if any, sets the queued condition explaining why.
+func (bc *BuildController) checkBuildCacheInUse(build *buildv1.Build) (queued bool, update *buildUpdate, err error) {
+	cache, buildConfig := bc.buildCacheFor(build)
+	if cache == nil {
+		return false, nil, nil
+	}
+	shared, err := bc.isSharedBuildCache(buildConfig, cache)
+	if err != nil || shared {
+		return false, nil, err
+	}
+
+	builds, err := buildutil.BuildConfigBuildsFromLister(bc.buildLister, build.Namespace, buildConfig.Name, func(b *buildv1.Build) bool {
+		return b.Name != build.Name && b.DeletionTimestamp == nil && !b.Status.Cancelled
+	})
+	if err != nil {
+		return false, nil, err
+	}
+	var active, ahead []string
+	for _, b := range builds {
+		switch b.Status.Phase {
+		case buildv1.BuildPhasePending, buildv1.BuildPhaseRunning:
+			active = append(active, b.Name)
+		case buildv1.BuildPhaseNew:
+			if createdBefore(b, build) {
+				ahead = append(ahead, b.Name)
+			}
+		}
+	}
+	claimName := buildCacheClaimName(buildConfig, cache)
+	var message string
+	switch {
+	case len(active) > 0:
+		sort.Strings(active)
+		message = fmt.Sprintf("Build is waiting for build %s to release the build cache claim %s.", active[0], claimName)
+	case len(ahead) > 0:
+		klog.V(4).Infof("Build %s is queued behind %d builds waiting for the build cache claim %s", buildDesc(build), len(ahead), claimName)
+		message = fmt.Sprintf("Build is queued behind other builds waiting for the build cache claim %s.", claimName)
+	default:
+		return false, nil, nil
+	}
+
+	klog.V(4).Infof("Build %s is queued: %s", buildDesc(build), message)
+	if existing := findBuildCondition(build, buildutil.BuildConditionQueued); existing != nil &&
+		existing.Status == corev1.ConditionTrue && existing.Reason == buildutil.BuildCacheInUseReason && existing.Message == message {
+		return true, nil, nil
+	}
+	update = &buildUpdate{}
+	update.setCondition(newQueuedCondition(corev1.ConditionTrue, buildutil.BuildCacheInUseReason, message))
+	return true, update, nil
+}
+
+// ensureBuildCacheClaim returns the name of the build cache claim of the build, creating the claim
+// from the template of the BuildConfig when it does not exist yet. The created claim is owned by
+// the BuildConfig. It returns an empty name for builds without a build cache.
+func (bc *BuildController) ensureBuildCacheClaim(build *buildv1.Build) (string, error) {
+	cache, buildConfig := bc.buildCacheFor(build)
+	if cache == nil {
+		return "", nil
+	}
+	claimName := buildCacheClaimName(buildConfig, cache)
+	if cache.VolumeClaimTemplate == nil {
+		return claimName, nil
+	}
+
+	t := true
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: *cache.VolumeClaimTemplate.ObjectMeta.DeepCopy(),
+		Spec:       *cache.VolumeClaimTemplate.Spec.DeepCopy(),
+	}
+	claim.Name = claimName
+	claim.Namespace = buildConfig.Namespace
+	if claim.Labels == nil {
+		claim.Labels = map[string]string{}
+	}
+	claim.Labels[buildv1.BuildConfigLabel] = buildutil.LabelValue(buildConfig.Name)
+	claim.OwnerReferences = []metav1.OwnerReference{
+		{
+			APIVersion: buildv1.GroupVersion.String(),
+			Kind:       "BuildConfig",
+			Name:       buildConfig.Name,
+			UID:        buildConfig.UID,
+			Controller: &t,
+		},
+	}
+	_, err := bc.kubeClient.CoreV1().PersistentVolumeClaims(claim.Namespace).Create(context.TODO(), claim, metav1.CreateOptions{})
+	if err != nil && !errors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create build cache claim %s/%s: %v", claim.Namespace, claim.Name, err)
+	}
+	return claimName, nil
+}
+
+// setupBuildCache mounts the build cache claim in the build container of the pod and tells the
+// builder image where to find it.
+func setupBuildCache(pod *corev1.Pod, claimName string) {
+	pod.Spec.Volumes = append(pod.Spec.Volumes, corev1.Volume{
+		Name: buildCacheVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+		},
+	})
+	for i := range pod.Spec.Containers {
+		c := &pod.Spec.Containers[i]
+		if c.Name != strategy.StiBuild {
+			continue
+		}
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{
+			Name:      buildCacheVolumeName,
+			MountPath: buildutil.BuildCacheMountPath,
+		})
+		c.Env = append(c.Env, corev1.EnvVar{Name: buildutil.BuildCacheDirEnvVar, Value: buildutil.BuildCacheMountPath})
+	}
+}
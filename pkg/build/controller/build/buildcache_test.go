@@ -0,0 +1,284 @@
+package build
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+func newBuildCacheController(cacheAnnotation string, builds []*buildv1.Build, objects ...runtime.Object) *BuildController {
+	buildConfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	buildConfigIndexer.Add(&buildv1.BuildConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-bc",
+			Namespace:   "namespace",
+			UID:         "bc-uid",
+			Annotations: map[string]string{buildutil.BuildCacheAnnotation: cacheAnnotation},
+		},
+	})
+	buildIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, b := range builds {
+		buildIndexer.Add(b)
+	}
+	claimIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, o := range objects {
+		if claim, ok := o.(*corev1.PersistentVolumeClaim); ok {
+			claimIndexer.Add(claim)
+		}
+	}
+	return &BuildController{
+		buildLister:                buildv1lister.NewBuildLister(buildIndexer),
+		buildConfigLister:          buildv1lister.NewBuildConfigLister(buildConfigIndexer),
+		persistentVolumeClaimStore: v1lister.NewPersistentVolumeClaimLister(claimIndexer),
+		kubeClient:                 fake.NewSimpleClientset(objects...),
+	}
+}
+
+func buildCacheTestBuild(name string, phase buildv1.BuildPhase, created time.Time) *buildv1.Build {
+	build := sourceStrategy(mockBuild(phase, buildv1.BuildOutput{}))
+	build.Name = name
+	build.CreationTimestamp = metav1.NewTime(created)
+	return build
+}
+
+func buildCacheClaim(name string, accessMode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "namespace"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{accessMode},
+		},
+	}
+}
+
+func TestParseBuildCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		expectErr bool
+	}{
+		{name: "claim name", value: `{"claimName":"maven-cache"}`},
+		{name: "claim template", value: `{"volumeClaimTemplate":{"spec":{"accessModes":["ReadWriteOnce"]}}}`},
+		{name: "both", value: `{"claimName":"maven-cache","volumeClaimTemplate":{"spec":{}}}`, expectErr: true},
+		{name: "neither", value: `{}`, expectErr: true},
+		{name: "invalid json", value: `maven-cache`, expectErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseBuildCache(tc.value)
+			if tc.expectErr != (err != nil) {
+				t.Errorf("expected error %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestBuildCacheClaim(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotation    string
+		build         *buildv1.Build
+		expectedClaim string
+		expectCreated bool
+	}{
+		{
+			name:          "existing claim",
+			annotation:    `{"claimName":"maven-cache"}`,
+			build:         sourceStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{})),
+			expectedClaim: "maven-cache",
+		},
+		{
+			name:          "claim template",
+			annotation:    `{"volumeClaimTemplate":{"spec":{"accessModes":["ReadWriteOnce"]}}}`,
+			build:         sourceStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{})),
+			expectedClaim: "test-bc-build-cache",
+			expectCreated: true,
+		},
+		{
+			name:       "invalid annotation",
+			annotation: `{}`,
+			build:      sourceStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{})),
+		},
+		{
+			name:       "docker build",
+			annotation: `{"claimName":"maven-cache"}`,
+			build:      dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{})),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bc := newBuildCacheController(tc.annotation, nil)
+			claimName, err := bc.ensureBuildCacheClaim(tc.build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if claimName != tc.expectedClaim {
+				t.Errorf("expected claim %q, got %q", tc.expectedClaim, claimName)
+			}
+			claim, err := bc.kubeClient.CoreV1().PersistentVolumeClaims("namespace").Get(context.TODO(), "test-bc-build-cache", metav1.GetOptions{})
+			if !tc.expectCreated {
+				if err == nil {
+					t.Errorf("expected no claim to be created")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected the claim to be created: %v", err)
+			}
+			if len(claim.OwnerReferences) != 1 || claim.OwnerReferences[0].Kind != "BuildConfig" || claim.OwnerReferences[0].UID != "bc-uid" {
+				t.Errorf("expected the claim to be owned by the buildconfig, got %#v", claim.OwnerReferences)
+			}
+
+			// the claim of the next build already exists
+			if _, err := bc.ensureBuildCacheClaim(tc.build); err != nil {
+				t.Errorf("unexpected error for an existing claim: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetupBuildCache(t *testing.T) {
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: strategy.GitCloneContainer}},
+			Containers:     []corev1.Container{{Name: strategy.StiBuild}},
+		},
+	}
+	setupBuildCache(pod, "maven-cache")
+
+	if len(pod.Spec.Volumes) != 1 || pod.Spec.Volumes[0].PersistentVolumeClaim == nil || pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != "maven-cache" {
+		t.Fatalf("expected a build cache volume for claim maven-cache, got %#v", pod.Spec.Volumes)
+	}
+	container := pod.Spec.Containers[0]
+	if len(container.VolumeMounts) != 1 || container.VolumeMounts[0].MountPath != buildutil.BuildCacheMountPath {
+		t.Errorf("expected the build cache to be mounted at %s, got %#v", buildutil.BuildCacheMountPath, container.VolumeMounts)
+	}
+	if len(container.Env) != 1 || container.Env[0].Name != buildutil.BuildCacheDirEnvVar || container.Env[0].Value != buildutil.BuildCacheMountPath {
+		t.Errorf("expected %s to be set, got %#v", buildutil.BuildCacheDirEnvVar, container.Env)
+	}
+	if len(pod.Spec.InitContainers[0].VolumeMounts) != 0 {
+		t.Errorf("expected the build cache to only be mounted in the build container")
+	}
+}
+
+func TestCheckBuildCacheInUse(t *testing.T) {
+	now := time.Now()
+	rwoAnnotation := `{"claimName":"maven-cache"}`
+	tests := []struct {
+		name        string
+		annotation  string
+		claim       *corev1.PersistentVolumeClaim
+		others      []*buildv1.Build
+		expectQueue bool
+	}{
+		{
+			name:        "running build holds a ReadWriteOnce claim",
+			annotation:  rwoAnnotation,
+			claim:       buildCacheClaim("maven-cache", corev1.ReadWriteOnce),
+			others:      []*buildv1.Build{buildCacheTestBuild("running", buildv1.BuildPhaseRunning, now.Add(-time.Minute))},
+			expectQueue: true,
+		},
+		{
+			name:        "pending build holds a claim that does not exist yet",
+			annotation:  rwoAnnotation,
+			others:      []*buildv1.Build{buildCacheTestBuild("pending", buildv1.BuildPhasePending, now.Add(-time.Minute))},
+			expectQueue: true,
+		},
+		{
+			name:        "older new build goes first",
+			annotation:  rwoAnnotation,
+			claim:       buildCacheClaim("maven-cache", corev1.ReadWriteOnce),
+			others:      []*buildv1.Build{buildCacheTestBuild("older", buildv1.BuildPhaseNew, now.Add(-time.Minute))},
+			expectQueue: true,
+		},
+		{
+			name:       "newer new build waits",
+			annotation: rwoAnnotation,
+			claim:      buildCacheClaim("maven-cache", corev1.ReadWriteOnce),
+			others:     []*buildv1.Build{buildCacheTestBuild("newer", buildv1.BuildPhaseNew, now.Add(time.Minute))},
+		},
+		{
+			name:       "completed build released the claim",
+			annotation: rwoAnnotation,
+			claim:      buildCacheClaim("maven-cache", corev1.ReadWriteOnce),
+			others:     []*buildv1.Build{buildCacheTestBuild("complete", buildv1.BuildPhaseComplete, now.Add(-time.Minute))},
+		},
+		{
+			name:       "ReadWriteMany claim is shared",
+			annotation: rwoAnnotation,
+			claim:      buildCacheClaim("maven-cache", corev1.ReadWriteMany),
+			others:     []*buildv1.Build{buildCacheTestBuild("running", buildv1.BuildPhaseRunning, now.Add(-time.Minute))},
+		},
+		{
+			name:       "ReadWriteMany claim template is shared",
+			annotation: `{"volumeClaimTemplate":{"spec":{"accessModes":["ReadWriteMany"]}}}`,
+			others:     []*buildv1.Build{buildCacheTestBuild("running", buildv1.BuildPhaseRunning, now.Add(-time.Minute))},
+		},
+		{
+			name:        "ReadWriteOnce claim template",
+			annotation:  `{"volumeClaimTemplate":{"spec":{"accessModes":["ReadWriteOnce"]}}}`,
+			others:      []*buildv1.Build{buildCacheTestBuild("running", buildv1.BuildPhaseRunning, now.Add(-time.Minute))},
+			expectQueue: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := buildCacheTestBuild("build", buildv1.BuildPhaseNew, now)
+			objects := []runtime.Object{}
+			if tc.claim != nil {
+				objects = append(objects, tc.claim)
+			}
+			bc := newBuildCacheController(tc.annotation, append(tc.others, build), objects...)
+
+			queued, update, err := bc.checkBuildCacheInUse(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if queued != tc.expectQueue {
+				t.Fatalf("expected queued %v, got %v", tc.expectQueue, queued)
+			}
+			if !queued {
+				return
+			}
+			if update == nil {
+				t.Fatalf("expected the queued condition to be set")
+			}
+			update.apply(build)
+			condition := findBuildCondition(build, buildutil.BuildConditionQueued)
+			if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != buildutil.BuildCacheInUseReason {
+				t.Fatalf("expected the build to be queued for its build cache, got %#v", condition)
+			}
+
+			// the condition is only updated once
+			if _, update, _ := bc.checkBuildCacheInUse(build); update != nil {
+				t.Errorf("expected no update of an unchanged queued condition, got %v", update)
+			}
+
+			// nor when another build queues up ahead of the build
+			older := buildCacheTestBuild("oldest", buildv1.BuildPhaseNew, now.Add(-time.Hour))
+			bc = newBuildCacheController(tc.annotation, append(tc.others, build, older), objects...)
+			if _, update, _ := bc.checkBuildCacheInUse(build); update != nil {
+				t.Errorf("expected no update when another build queued up ahead, got %v", update)
+			}
+
+			update = &buildUpdate{}
+			admitQueuedBuild(build, update)
+			update.apply(build)
+			condition = findBuildCondition(build, buildutil.BuildConditionQueued)
+			if condition.Status != corev1.ConditionFalse || condition.Message != "Build was admitted once the build cache was released." {
+				t.Errorf("expected the build to be admitted once the cache was released, got %#v", condition)
+			}
+		})
+	}
+}
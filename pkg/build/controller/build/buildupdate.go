@@ -9,6 +9,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 	"github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
 )
 
@@ -27,8 +28,17 @@ type buildUpdate struct {
 	completionTime    *metav1.Time
 	duration          *time.Duration
 	outputRef         *string
+	pushedImageRef    *string
+	additionalTagRefs *string
+	strippedEnv       *string
+	rescheduleAttempt *string
+	commitAnnotations map[string]string
+	cancelRequested   *string
 	logSnippet        *string
 	pushSecret        *corev1.LocalObjectReference
+	sourceSecret      *corev1.LocalObjectReference
+	stages            []buildv1.StageInfo
+	conditions        []buildv1.BuildCondition
 }
 
 func (u *buildUpdate) setPhase(phase buildv1.BuildPhase) {
@@ -59,6 +69,30 @@ func (u *buildUpdate) setOutputRef(ref string) {
 	u.outputRef = &ref
 }
 
+func (u *buildUpdate) setPushedImageRef(ref string) {
+	u.pushedImageRef = &ref
+}
+
+func (u *buildUpdate) setAdditionalTagRefs(refs string) {
+	u.additionalTagRefs = &refs
+}
+
+func (u *buildUpdate) setStrippedEnv(strippedEnv string) {
+	u.strippedEnv = &strippedEnv
+}
+
+func (u *buildUpdate) setRescheduleAttempt(attempt string) {
+	u.rescheduleAttempt = &attempt
+}
+
+func (u *buildUpdate) setCommitAnnotations(annotations map[string]string) {
+	u.commitAnnotations = annotations
+}
+
+func (u *buildUpdate) setCancelRequested(timestamp string) {
+	u.cancelRequested = &timestamp
+}
+
 func (u *buildUpdate) setPodNameAnnotation(podName string) {
 	u.podNameAnnotation = &podName
 }
@@ -71,6 +105,25 @@ func (u *buildUpdate) setPushSecret(pushSecret corev1.LocalObjectReference) {
 	u.pushSecret = &pushSecret
 }
 
+func (u *buildUpdate) setSourceSecret(sourceSecret corev1.LocalObjectReference) {
+	u.sourceSecret = &sourceSecret
+}
+
+func (u *buildUpdate) setStages(stages []buildv1.StageInfo) {
+	u.stages = stages
+}
+
+// setCondition sets the condition, replacing a condition of the same type set earlier.
+func (u *buildUpdate) setCondition(condition buildv1.BuildCondition) {
+	for i := range u.conditions {
+		if u.conditions[i].Type == condition.Type {
+			u.conditions[i] = condition
+			return
+		}
+	}
+	u.conditions = append(u.conditions, condition)
+}
+
 func (u *buildUpdate) reset() {
 	u.podNameAnnotation = nil
 	u.phase = nil
@@ -80,8 +133,17 @@ func (u *buildUpdate) reset() {
 	u.completionTime = nil
 	u.duration = nil
 	u.outputRef = nil
+	u.pushedImageRef = nil
+	u.additionalTagRefs = nil
+	u.strippedEnv = nil
+	u.rescheduleAttempt = nil
+	u.commitAnnotations = nil
+	u.cancelRequested = nil
 	u.logSnippet = nil
 	u.pushSecret = nil
+	u.sourceSecret = nil
+	u.stages = nil
+	u.conditions = nil
 }
 
 func (u *buildUpdate) isEmpty() bool {
@@ -93,8 +155,17 @@ func (u *buildUpdate) isEmpty() bool {
 		u.completionTime == nil &&
 		u.duration == nil &&
 		u.outputRef == nil &&
+		u.pushedImageRef == nil &&
+		u.additionalTagRefs == nil &&
+		u.strippedEnv == nil &&
+		u.rescheduleAttempt == nil &&
+		len(u.commitAnnotations) == 0 &&
+		u.cancelRequested == nil &&
 		u.logSnippet == nil &&
-		u.pushSecret == nil
+		u.pushSecret == nil &&
+		u.sourceSecret == nil &&
+		len(u.stages) == 0 &&
+		len(u.conditions) == 0
 }
 
 func (u *buildUpdate) apply(build *buildv1.Build) {
@@ -122,12 +193,74 @@ func (u *buildUpdate) apply(build *buildv1.Build) {
 	if u.outputRef != nil {
 		build.Status.OutputDockerImageReference = *u.outputRef
 	}
+	if u.pushedImageRef != nil {
+		if build.Annotations == nil {
+			build.Annotations = map[string]string{}
+		}
+		build.Annotations[buildutil.BuildPushedImageReferenceAnnotation] = *u.pushedImageRef
+	}
+	if u.additionalTagRefs != nil {
+		if build.Annotations == nil {
+			build.Annotations = map[string]string{}
+		}
+		build.Annotations[buildutil.BuildAdditionalTagReferencesAnnotation] = *u.additionalTagRefs
+	}
+	if u.strippedEnv != nil {
+		if build.Annotations == nil {
+			build.Annotations = map[string]string{}
+		}
+		build.Annotations[buildutil.BuildStrippedEnvAnnotation] = *u.strippedEnv
+	}
+	if u.rescheduleAttempt != nil {
+		if build.Annotations == nil {
+			build.Annotations = map[string]string{}
+		}
+		build.Annotations[buildutil.BuildPodRescheduleAttemptAnnotation] = *u.rescheduleAttempt
+	}
+	if len(u.commitAnnotations) > 0 {
+		if build.Annotations == nil {
+			build.Annotations = map[string]string{}
+		}
+		for key, value := range u.commitAnnotations {
+			build.Annotations[key] = value
+		}
+	}
+	if u.cancelRequested != nil {
+		if build.Annotations == nil {
+			build.Annotations = map[string]string{}
+		}
+		build.Annotations[buildutil.BuildCancelRequestedAnnotation] = *u.cancelRequested
+	}
 	if u.logSnippet != nil {
 		build.Status.LogSnippet = *u.logSnippet
 	}
 	if u.pushSecret != nil {
 		build.Spec.Output.PushSecret = u.pushSecret
 	}
+	if u.sourceSecret != nil {
+		build.Spec.Source.SourceSecret = u.sourceSecret
+	}
+	if len(u.stages) > 0 {
+		build.Status.Stages = u.stages
+	}
+	for _, condition := range u.conditions {
+		setBuildCondition(build, condition)
+	}
+}
+
+// setBuildCondition adds the condition to the build status or replaces the condition of the same type.
+func setBuildCondition(build *buildv1.Build, condition buildv1.BuildCondition) {
+	for i := range build.Status.Conditions {
+		if build.Status.Conditions[i].Type != condition.Type {
+			continue
+		}
+		if build.Status.Conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = build.Status.Conditions[i].LastTransitionTime
+		}
+		build.Status.Conditions[i] = condition
+		return
+	}
+	build.Status.Conditions = append(build.Status.Conditions, condition)
 }
 
 // String returns a string representation of this update
@@ -155,6 +288,24 @@ func (u *buildUpdate) String() string {
 	if u.outputRef != nil {
 		updates = append(updates, fmt.Sprintf("outputRef: %q", *u.outputRef))
 	}
+	if u.pushedImageRef != nil {
+		updates = append(updates, fmt.Sprintf("pushedImageRef: %q", *u.pushedImageRef))
+	}
+	if u.additionalTagRefs != nil {
+		updates = append(updates, fmt.Sprintf("additionalTagRefs: %q", *u.additionalTagRefs))
+	}
+	if u.strippedEnv != nil {
+		updates = append(updates, fmt.Sprintf("strippedEnv: %q", *u.strippedEnv))
+	}
+	if u.rescheduleAttempt != nil {
+		updates = append(updates, fmt.Sprintf("rescheduleAttempt: %q", *u.rescheduleAttempt))
+	}
+	if len(u.commitAnnotations) > 0 {
+		updates = append(updates, fmt.Sprintf("commitAnnotations: %v", u.commitAnnotations))
+	}
+	if u.cancelRequested != nil {
+		updates = append(updates, fmt.Sprintf("cancelRequested: %q", *u.cancelRequested))
+	}
 	if u.podNameAnnotation != nil {
 		updates = append(updates, fmt.Sprintf("podName: %q", *u.podNameAnnotation))
 	}
@@ -164,5 +315,14 @@ func (u *buildUpdate) String() string {
 	if u.pushSecret != nil {
 		updates = append(updates, fmt.Sprintf("pushSecret: %v", *u.pushSecret))
 	}
+	if u.sourceSecret != nil {
+		updates = append(updates, fmt.Sprintf("sourceSecret: %v", *u.sourceSecret))
+	}
+	for _, stage := range u.stages {
+		updates = append(updates, fmt.Sprintf("stage: %s (%dms)", stage.Name, stage.DurationMilliseconds))
+	}
+	for _, condition := range u.conditions {
+		updates = append(updates, fmt.Sprintf("condition: %s=%s (%s)", condition.Type, condition.Status, condition.Reason))
+	}
 	return fmt.Sprintf("buildUpdate(%s)", strings.Join(updates, ", "))
 }
@@ -90,6 +90,15 @@ func TestBuildUpdateSetters(t *testing.T) {
 			},
 			expected: "buildUpdate(podName: \"test-pod-name\")",
 		},
+		{
+			f: func(u *buildUpdate) {
+				u.setCondition(buildv1.BuildCondition{Type: "Queued", Status: "True", Reason: "Waiting"})
+			},
+			validateApply: func(b *buildv1.Build) bool {
+				return len(b.Status.Conditions) == 1 && b.Status.Conditions[0].Reason == "Waiting"
+			},
+			expected: "buildUpdate(condition: Queued=True (Waiting))",
+		},
 	}
 
 	for _, test := range tests {
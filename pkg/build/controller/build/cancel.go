@@ -0,0 +1,89 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// cancelRequestedTime returns the time the cancellation of the build was requested, as recorded by
+// the cancel requested annotation of the build.
+func cancelRequestedTime(build *buildv1.Build) (time.Time, bool) {
+	value, ok := build.Annotations[buildutil.BuildCancelRequestedAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	requested, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q of build %s: %v", buildutil.BuildCancelRequestedAnnotation, value, buildDesc(build), err)
+		return time.Time{}, false
+	}
+	return requested, true
+}
+
+// terminateBuildPod deletes the build pod of a cancelled build in two phases. The build pod is
+// first annotated as cancelled and deleted with the cancellation grace period, which lets the
+// builder trap SIGTERM and clean up, such as finish or abort a push; the update records when the
+// cancellation was requested. The build pod is force deleted once the grace period expired.
+// terminated is true once the build pod is gone, or completed, and the build can be cancelled.
+func (bc *BuildController) terminateBuildPod(build *buildv1.Build, podName string) (update *buildUpdate, terminated bool, err error) {
+	pods := bc.podClient.Pods(build.Namespace)
+	pod, err := pods.Get(context.TODO(), podName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, true, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("could not get build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+	}
+	key := resourceName(build.Namespace, build.Name)
+
+	// Build pods that already completed have nothing left to clean up.
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		if err := pods.Delete(context.TODO(), podName, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return nil, false, fmt.Errorf("could not delete build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+		}
+		return nil, true, nil
+	}
+
+	requested, ok := cancelRequestedTime(build)
+	if !ok {
+		if pod.Annotations[buildutil.BuildPodCancelledAnnotation] != "true" {
+			patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, buildutil.BuildPodCancelledAnnotation))
+			if _, err := pods.Patch(context.TODO(), podName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil && !errors.IsNotFound(err) {
+				return nil, false, fmt.Errorf("could not annotate build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+			}
+		}
+		gracePeriod := int64(bc.cancelGracePeriod.Seconds())
+		if err := pods.Delete(context.TODO(), podName, metav1.DeleteOptions{GracePeriodSeconds: &gracePeriod}); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, true, nil
+			}
+			return nil, false, fmt.Errorf("could not delete build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+		}
+		klog.V(4).Infof("Deleted build pod %s/%s of cancelled build %s with a grace period of %s", build.Namespace, podName, buildDesc(build), bc.cancelGracePeriod)
+		update = &buildUpdate{}
+		update.setCancelRequested(bc.clock.Now().UTC().Format(time.RFC3339))
+		bc.buildQueue.AddAfter(key, bc.cancelGracePeriod)
+		return update, false, nil
+	}
+
+	if remaining := requested.Add(bc.cancelGracePeriod).Sub(bc.clock.Now()); remaining > 0 {
+		bc.buildQueue.AddAfter(key, remaining)
+		return nil, false, nil
+	}
+	klog.V(2).Infof("Force deleting build pod %s/%s of cancelled build %s after the grace period of %s", build.Namespace, podName, buildDesc(build), bc.cancelGracePeriod)
+	forceDelete := int64(0)
+	if err := pods.Delete(context.TODO(), podName, metav1.DeleteOptions{GracePeriodSeconds: &forceDelete}); err != nil && !errors.IsNotFound(err) {
+		return nil, false, fmt.Errorf("could not force delete build pod %s/%s to cancel build %s: %v", build.Namespace, podName, buildDesc(build), err)
+	}
+	return nil, true, nil
+}
@@ -0,0 +1,134 @@
+package build
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildv1client "github.com/openshift/client-go/build/clientset/versioned/fake"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func TestCancelBuildGracefully(t *testing.T) {
+	requested := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clocktesting.NewFakeClock(requested)
+
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+	build.Status.Cancelled = true
+	pod := mockBuildPod(build)
+	pod.Status.Phase = corev1.PodRunning
+
+	// the fake client deletes pods at once, the build pod keeps terminating until it is force deleted
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap, pod)
+	var gracePeriods []int64
+	kubeClient.(*fake.Clientset).PrependReactor("delete", "pods", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		gracePeriod := action.(clientgotesting.DeleteActionImpl).DeleteOptions.GracePeriodSeconds
+		if gracePeriod == nil {
+			t.Fatalf("expected the build pod to be deleted with a grace period")
+		}
+		gracePeriods = append(gracePeriods, *gracePeriod)
+		return *gracePeriod > 0, nil, nil
+	})
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	bc.clock = fakeClock
+	bc.cancelGracePeriod = 30 * time.Second
+
+	// the build pod is signalled to terminate
+	update, err := bc.cancelBuild(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase != nil {
+		t.Fatalf("expected the build not to be cancelled before its build pod terminated, got %v", update)
+	}
+	if update.cancelRequested == nil || *update.cancelRequested != requested.Format(time.RFC3339) {
+		t.Errorf("expected the cancellation to be requested at %s, got %v", requested.Format(time.RFC3339), update)
+	}
+	if len(gracePeriods) != 1 || gracePeriods[0] != 30 {
+		t.Errorf("expected the build pod to be deleted with a grace period of 30 seconds, got %v", gracePeriods)
+	}
+	terminating, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if terminating.Annotations[buildutil.BuildPodCancelledAnnotation] != "true" {
+		t.Errorf("expected the build pod to be annotated as cancelled, got %v", terminating.Annotations)
+	}
+	update.apply(build)
+
+	// the build pod is still terminating within the grace period
+	fakeClock.SetTime(requested.Add(10 * time.Second))
+	update, err = bc.cancelBuild(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update != nil {
+		t.Errorf("expected no update within the grace period, got %v", update)
+	}
+	if len(gracePeriods) != 1 {
+		t.Errorf("expected the build pod not to be deleted again within the grace period, got %v", gracePeriods)
+	}
+
+	// the build pod is force deleted after the grace period
+	fakeClock.SetTime(requested.Add(31 * time.Second))
+	update, err = bc.cancelBuild(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gracePeriods) != 2 || gracePeriods[1] != 0 {
+		t.Errorf("expected the build pod to be force deleted, got %v", gracePeriods)
+	}
+	if update == nil || update.phase == nil || *update.phase != buildv1.BuildPhaseCancelled {
+		t.Fatalf("expected the build to be cancelled, got %v", update)
+	}
+	update.apply(build)
+	if build.Annotations[buildutil.BuildCancelRequestedAnnotation] != requested.Format(time.RFC3339) {
+		t.Errorf("expected the cancelled build to keep the time its cancellation was requested, got %v", build.Annotations)
+	}
+}
+
+func TestCancelBuildPodTerminated(t *testing.T) {
+	requested := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+	build.Status.Cancelled = true
+	build.Annotations[buildutil.BuildCancelRequestedAnnotation] = requested.Format(time.RFC3339)
+
+	// the build pod terminated within the grace period
+	var patchedBuild *buildv1.Build
+	buildClient := fakeBuildClient(build)
+	buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds",
+		func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			var err error
+			patchedBuild, err = applyBuildPatch(build, action.(clientgotesting.PatchActionImpl).Patch)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return true, patchedBuild, nil
+		})
+	bc := newFakeBuildController(buildClient, nil, nil, nil, nil)
+	defer bc.stop()
+	bc.clock = clocktesting.NewFakeClock(requested.Add(5 * time.Second))
+	bc.cancelGracePeriod = 30 * time.Second
+
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patchedBuild == nil || patchedBuild.Status.Phase != buildv1.BuildPhaseCancelled || patchedBuild.Status.Reason != buildv1.StatusReasonCancelledBuild {
+		t.Fatalf("expected the build to be cancelled, got %v", patchedBuild)
+	}
+	if patchedBuild.Status.CompletionTimestamp == nil || patchedBuild.Status.CompletionTimestamp.Time.Before(requested) {
+		t.Errorf("expected the cancellation to complete after it was requested at %s, got %v", requested, patchedBuild.Status.CompletionTimestamp)
+	}
+	if patchedBuild.Annotations[buildutil.BuildCancelRequestedAnnotation] != requested.Format(time.RFC3339) {
+		t.Errorf("expected the cancelled build to keep the time its cancellation was requested, got %v", patchedBuild.Annotations)
+	}
+}
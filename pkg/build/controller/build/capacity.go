@@ -0,0 +1,205 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+const (
+	// competingForCapacityIndex indexes the builds that competesForCapacity by namespace.
+	competingForCapacityIndex = "competingForCapacity"
+	// activeBuildPodIndex indexes the pending and running build pods by namespace.
+	activeBuildPodIndex = "activeBuildPod"
+)
+
+// isWaitingForCapacity returns true for new builds the cluster build pod limit holds back.
+func isWaitingForCapacity(build *buildv1.Build) bool {
+	condition := findBuildCondition(build, buildutil.BuildConditionWaitingForCapacity)
+	return build.Status.Phase == buildv1.BuildPhaseNew && isConcurrencyLimited(build) && condition != nil && condition.Status == corev1.ConditionTrue
+}
+
+// competesForCapacity returns true for builds waiting for capacity that are admitted under the
+// cluster build pod limit in capacityOrder. New builds that did not pass the earlier admission
+// steps yet, or that are queued behind the concurrent build limit of their namespace or their
+// build cache again, do not take a slot under the limit.
+func competesForCapacity(build *buildv1.Build) bool {
+	if !isWaitingForCapacity(build) {
+		return false
+	}
+	queued := findBuildCondition(build, buildutil.BuildConditionQueued)
+	return queued == nil || queued.Status != corev1.ConditionTrue
+}
+
+// competingForCapacityIndexFunc indexes the builds that competesForCapacity by namespace.
+func competingForCapacityIndexFunc(obj interface{}) ([]string, error) {
+	build, ok := obj.(*buildv1.Build)
+	if !ok || !competesForCapacity(build) {
+		return nil, nil
+	}
+	return []string{build.Namespace}, nil
+}
+
+// activeBuildPodIndexFunc indexes the pending and running build pods by namespace.
+func activeBuildPodIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !buildPodSelector().Matches(labels.Set(pod.Labels)) {
+		return nil, nil
+	}
+	if pod.Status.Phase != corev1.PodPending && pod.Status.Phase != corev1.PodRunning {
+		return nil, nil
+	}
+	return []string{pod.Namespace}, nil
+}
+
+// buildsCompetingForCapacity returns the builds that competesForCapacity in all namespaces.
+func (bc *BuildController) buildsCompetingForCapacity() ([]*buildv1.Build, error) {
+	var builds []*buildv1.Build
+	for _, namespace := range bc.buildIndexer.ListIndexFuncValues(competingForCapacityIndex) {
+		objs, err := bc.buildIndexer.ByIndex(competingForCapacityIndex, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			builds = append(builds, obj.(*buildv1.Build))
+		}
+	}
+	return builds, nil
+}
+
+// capacityOrder orders the builds waiting for capacity round robin across their namespaces: the
+// n-th waiting build of a namespace with m active build pods is admitted in round n+m, so that the
+// oldest waiting build of every namespace is admitted before the second oldest of any namespace.
+// Builds of the same round are admitted in creation timestamp order.
+func capacityOrder(waiting []*buildv1.Build, activeByNamespace map[string]int) []*buildv1.Build {
+	byNamespace := map[string][]*buildv1.Build{}
+	for _, b := range waiting {
+		byNamespace[b.Namespace] = append(byNamespace[b.Namespace], b)
+	}
+	round := map[*buildv1.Build]int{}
+	for namespace, builds := range byNamespace {
+		sort.Slice(builds, func(i, j int) bool { return createdBefore(builds[i], builds[j]) })
+		for i, b := range builds {
+			round[b] = activeByNamespace[namespace] + i
+		}
+	}
+	ordered := append([]*buildv1.Build{}, waiting...)
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if round[a] != round[b] {
+			return round[a] < round[b]
+		}
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return a.CreationTimestamp.Before(&b.CreationTimestamp)
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+	return ordered
+}
+
+// checkClusterCapacity decides whether a new build may get its build pod under the build pod
+// limit of the cluster. The pending and running build pods and the builds competing for capacity
+// are looked up in the indexes of the informer caches, so that the check does not go through all
+// the pods and builds of the cluster. The new builds beyond the limit wait for capacity and are
+// admitted in capacityOrder, so that a namespace with many builds cannot hold back the builds of
+// the other namespaces. When the build has to wait, queued is true and the returned update, if
+// any, sets the waiting for capacity condition explaining why.
+func (bc *BuildController) checkClusterCapacity(build *buildv1.Build) (queued bool, update *buildUpdate, err error) {
+	limit := bc.maxActiveBuildPods
+	if limit == 0 || !isConcurrencyLimited(build) {
+		return false, nil, nil
+	}
+
+	active := 0
+	activeByNamespace := map[string]int{}
+	for _, namespace := range bc.podIndexer.ListIndexFuncValues(activeBuildPodIndex) {
+		keys, err := bc.podIndexer.IndexKeys(activeBuildPodIndex, namespace)
+		if err != nil {
+			return false, nil, err
+		}
+		active += len(keys)
+		activeByNamespace[namespace] = len(keys)
+	}
+
+	builds, err := bc.buildsCompetingForCapacity()
+	if err != nil {
+		return false, nil, err
+	}
+	waiting := []*buildv1.Build{build}
+	for _, b := range builds {
+		if b.Namespace != build.Namespace || b.Name != build.Name {
+			waiting = append(waiting, b)
+		}
+	}
+	ahead := 0
+	for _, b := range capacityOrder(waiting, activeByNamespace) {
+		if b == build {
+			break
+		}
+		ahead++
+	}
+	if active+ahead < limit {
+		return false, nil, nil
+	}
+
+	// the message leaves out the counts of active and waiting builds, which change with every
+	// build admitted or completed in the cluster, so that waiting builds are not updated for them
+	message := fmt.Sprintf("Build is waiting for capacity under the limit of %d build pods in the cluster.", limit)
+	klog.V(4).Infof("Build %s is waiting for capacity: %d active build pods, %d waiting builds admitted before it", buildDesc(build), active, ahead)
+	if existing := findBuildCondition(build, buildutil.BuildConditionWaitingForCapacity); existing != nil &&
+		existing.Status == corev1.ConditionTrue && existing.Message == message {
+		return true, nil, nil
+	}
+	update = &buildUpdate{}
+	update.setCondition(newWaitingForCapacityCondition(corev1.ConditionTrue, buildutil.BuildPodLimitReachedReason, message))
+	return true, update, nil
+}
+
+// admitWaitingForCapacity marks a build that was held back by the cluster build pod limit as
+// admitted once its build pod is created.
+func admitWaitingForCapacity(build *buildv1.Build, update *buildUpdate) {
+	existing := findBuildCondition(build, buildutil.BuildConditionWaitingForCapacity)
+	if existing == nil || existing.Status != corev1.ConditionTrue || update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		return
+	}
+	update.setCondition(newWaitingForCapacityCondition(corev1.ConditionFalse, buildutil.BuildCapacityAvailableReason, "Build was admitted under the build pod limit of the cluster."))
+}
+
+// enqueueBuildsWaitingForCapacity requeues the builds competing for capacity in all namespaces,
+// in creation timestamp order, so that they are admitted once a build pod goes away.
+func (bc *BuildController) enqueueBuildsWaitingForCapacity() {
+	if bc.maxActiveBuildPods == 0 {
+		return
+	}
+	waiting, err := bc.buildsCompetingForCapacity()
+	if err != nil {
+		klog.V(2).Infof("Unable to list builds to admit builds waiting for capacity: %v", err)
+		return
+	}
+	sort.Slice(waiting, func(i, j int) bool { return createdBefore(waiting[i], waiting[j]) })
+	for _, b := range waiting {
+		bc.enqueueBuild(b)
+	}
+}
+
+func newWaitingForCapacityCondition(status corev1.ConditionStatus, reason, message string) buildv1.BuildCondition {
+	now := metav1.Now()
+	return buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionWaitingForCapacity,
+		Status:             status,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+}
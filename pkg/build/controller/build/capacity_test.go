@@ -0,0 +1,266 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func newCapacityController(limit int) (*BuildController, cache.Indexer, cache.Indexer) {
+	buildIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex:      cache.MetaNamespaceIndexFunc,
+		competingForCapacityIndex: competingForCapacityIndexFunc,
+	})
+	podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		activeBuildPodIndex:  activeBuildPodIndexFunc,
+	})
+	return &BuildController{
+		buildLister:        buildv1lister.NewBuildLister(buildIndexer),
+		podStore:           v1lister.NewPodLister(podIndexer),
+		buildIndexer:       buildIndexer,
+		podIndexer:         podIndexer,
+		buildQueue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		maxActiveBuildPods: limit,
+	}, buildIndexer, podIndexer
+}
+
+func activeBuildPod(build *buildv1.Build) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      buildutil.GetBuildPodName(build),
+			Namespace: build.Namespace,
+			Labels:    map[string]string{buildv1.BuildLabel: build.Name},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestCheckClusterCapacity(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		limit        int
+		activePods   int
+		finishedPods int
+		expectQueued bool
+	}{
+		{
+			name:       "unlimited",
+			activePods: 5,
+		},
+		{
+			name:       "below the limit",
+			limit:      3,
+			activePods: 2,
+		},
+		{
+			name:         "completed build pods do not count",
+			limit:        3,
+			activePods:   2,
+			finishedPods: 3,
+		},
+		{
+			name:         "at the limit",
+			limit:        3,
+			activePods:   3,
+			expectQueued: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bc, _, podIndexer := newCapacityController(tc.limit)
+			for i := 0; i < tc.activePods+tc.finishedPods; i++ {
+				running := concurrencyTestBuild(fmt.Sprintf("running-%d", i), buildv1.BuildPhaseRunning, now.Add(-time.Hour))
+				running.Namespace = fmt.Sprintf("namespace-%d", i%2)
+				pod := activeBuildPod(running)
+				if i >= tc.activePods {
+					pod.Status.Phase = corev1.PodSucceeded
+				}
+				podIndexer.Add(pod)
+			}
+
+			build := concurrencyTestBuild("new", buildv1.BuildPhaseNew, now)
+			queued, update, err := bc.checkClusterCapacity(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if queued != tc.expectQueued {
+				t.Fatalf("expected queued %v, got %v", tc.expectQueued, queued)
+			}
+			if !queued {
+				return
+			}
+			if update == nil || len(update.conditions) != 1 {
+				t.Fatalf("expected the waiting for capacity condition to be set, got %v", update)
+			}
+			condition := update.conditions[0]
+			if condition.Type != buildutil.BuildConditionWaitingForCapacity || condition.Status != corev1.ConditionTrue || condition.Reason != buildutil.BuildPodLimitReachedReason {
+				t.Errorf("unexpected condition %v", condition)
+			}
+
+			// the build does not update its condition while it keeps waiting
+			build.Status.Conditions = update.conditions
+			if queued, update, _ := bc.checkClusterCapacity(build); !queued || update != nil {
+				t.Errorf("expected the build to keep waiting without an update, got %v %v", queued, update)
+			}
+
+			// nor when the number of active build pods changes
+			podIndexer.Add(activeBuildPod(concurrencyTestBuild("running-more", buildv1.BuildPhaseRunning, now.Add(-time.Hour))))
+			if queued, update, _ := bc.checkClusterCapacity(build); !queued || update != nil {
+				t.Errorf("expected the build to keep waiting without an update, got %v %v", queued, update)
+			}
+
+			// the condition is cleared once the build pod is created
+			update = transitionToPhase(buildv1.BuildPhasePending, "", "")
+			admitWaitingForCapacity(build, update)
+			if len(update.conditions) != 1 || update.conditions[0].Status != corev1.ConditionFalse || update.conditions[0].Reason != buildutil.BuildCapacityAvailableReason {
+				t.Errorf("expected the waiting for capacity condition to be cleared, got %v", update.conditions)
+			}
+		})
+	}
+}
+
+func TestCheckClusterCapacityRoundRobin(t *testing.T) {
+	now := time.Now()
+	bc, buildIndexer, podIndexer := newCapacityController(3)
+
+	// namespace a creates its builds first, b and c shortly after. The informer cache gets copies
+	// of the builds, as it would from the API server, so that it indexes their updates.
+	var builds []*buildv1.Build
+	for namespace, count := range map[string]int{"a": 4, "b": 2, "c": 1} {
+		offset := map[string]time.Duration{"a": 0, "b": 10 * time.Minute, "c": 20 * time.Minute}[namespace]
+		for i := 1; i <= count; i++ {
+			build := concurrencyTestBuild(fmt.Sprintf("%s-%d", namespace, i), buildv1.BuildPhaseNew, now.Add(offset+time.Duration(i)*time.Second))
+			build.Namespace = namespace
+			builds = append(builds, build)
+			buildIndexer.Add(build.DeepCopy())
+		}
+	}
+
+	// admit evaluates the new builds in creation timestamp order, as the build queue does, and
+	// returns the builds that got their build pod.
+	admit := func() []string {
+		var admitted []string
+		for {
+			progress := false
+			ordered := append([]*buildv1.Build{}, builds...)
+			sort.Slice(ordered, func(i, j int) bool { return createdBefore(ordered[i], ordered[j]) })
+			for _, build := range ordered {
+				if build.Status.Phase != buildv1.BuildPhaseNew {
+					continue
+				}
+				queued, update, err := bc.checkClusterCapacity(build)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if queued {
+					if update != nil {
+						build.Status.Conditions = update.conditions
+						buildIndexer.Update(build.DeepCopy())
+					}
+					continue
+				}
+				build.Status.Phase = buildv1.BuildPhaseRunning
+				build.Status.Conditions = nil
+				buildIndexer.Update(build.DeepCopy())
+				podIndexer.Add(activeBuildPod(build))
+				admitted = append(admitted, build.Name)
+				progress = true
+			}
+			if !progress {
+				return admitted
+			}
+		}
+	}
+	complete := func(namespace, name string) {
+		for _, build := range builds {
+			if build.Namespace == namespace && build.Name == name {
+				podIndexer.Delete(activeBuildPod(build))
+				build.Status.Phase = buildv1.BuildPhaseComplete
+				buildIndexer.Update(build.DeepCopy())
+			}
+		}
+	}
+
+	// the builds of other namespaces use all the capacity when the new builds are created, so
+	// that all of them wait for capacity
+	var others []*corev1.Pod
+	for i := 0; i < 3; i++ {
+		other := concurrencyTestBuild(fmt.Sprintf("other-%d", i), buildv1.BuildPhaseRunning, now.Add(-time.Hour))
+		other.Namespace = "other"
+		others = append(others, activeBuildPod(other))
+		podIndexer.Add(others[i])
+	}
+	if admitted := admit(); len(admitted) != 0 {
+		t.Fatalf("expected no build to be admitted while the cluster is at capacity, got %v", admitted)
+	}
+	for _, pod := range others {
+		podIndexer.Delete(pod)
+	}
+
+	if admitted := admit(); !reflect.DeepEqual(admitted, []string{"a-1", "b-1", "c-1"}) {
+		t.Fatalf("expected the oldest build of every namespace to be admitted, got %v", admitted)
+	}
+	for _, build := range builds {
+		if build.Status.Phase == buildv1.BuildPhaseNew && !isWaitingForCapacity(build) {
+			t.Errorf("expected build %s/%s to wait for capacity", build.Namespace, build.Name)
+		}
+	}
+
+	complete("a", "a-1")
+	if admitted := admit(); !reflect.DeepEqual(admitted, []string{"a-2"}) {
+		t.Fatalf("expected a-2 to be admitted, got %v", admitted)
+	}
+	// namespace b has fewer active build pods than namespace a, even though a has older builds
+	complete("b", "b-1")
+	if admitted := admit(); !reflect.DeepEqual(admitted, []string{"b-2"}) {
+		t.Fatalf("expected b-2 to be admitted before the older builds of namespace a, got %v", admitted)
+	}
+	complete("c", "c-1")
+	if admitted := admit(); !reflect.DeepEqual(admitted, []string{"a-3"}) {
+		t.Fatalf("expected a-3 to be admitted, got %v", admitted)
+	}
+}
+
+func TestCheckClusterCapacityCountsCompetingBuildsOnly(t *testing.T) {
+	now := time.Now()
+	bc, buildIndexer, podIndexer := newCapacityController(2)
+	podIndexer.Add(activeBuildPod(concurrencyTestBuild("running", buildv1.BuildPhaseRunning, now.Add(-time.Hour))))
+
+	waitingCondition := newWaitingForCapacityCondition(corev1.ConditionTrue, buildutil.BuildPodLimitReachedReason, "waiting")
+	// stuck did not pass the earlier admission steps, such as its run policy or a missing image
+	stuck := concurrencyTestBuild("stuck", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	// queued waited for capacity, but is queued behind the concurrent build limit of its namespace again
+	queued := concurrencyTestBuild("queued", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	queued.Status.Conditions = []buildv1.BuildCondition{
+		waitingCondition,
+		newQueuedCondition(corev1.ConditionTrue, buildutil.BuildQueuedReason, "queued"),
+	}
+	buildIndexer.Add(stuck)
+	buildIndexer.Add(queued)
+
+	build := concurrencyTestBuild("new", buildv1.BuildPhaseNew, now)
+	if queued, _, err := bc.checkClusterCapacity(build); err != nil || queued {
+		t.Fatalf("expected the build to be admitted, got queued %v, err %v", queued, err)
+	}
+
+	waiting := concurrencyTestBuild("waiting", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	waiting.Status.Conditions = []buildv1.BuildCondition{waitingCondition}
+	buildIndexer.Add(waiting)
+	if queued, _, err := bc.checkClusterCapacity(build); err != nil || !queued {
+		t.Fatalf("expected the build to wait behind the older waiting build, got queued %v, err %v", queued, err)
+	}
+}
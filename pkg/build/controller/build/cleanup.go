@@ -0,0 +1,67 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// cleanupGeneratedObjects deletes the configMaps generated for the build pod of a completed build,
+// which are controlled by the build, once the retention of generated objects passed since the
+// build completed. The objects are kept while the build pod exists, because the build pod mounts
+// them and its logs are kept with it; the build is handled again when its build pod is deleted.
+// The configMaps and the build pod are looked up in the informer caches, so that the builds whose
+// objects were deleted only cost a cache lookup when they are handled again.
+func (bc *BuildController) cleanupGeneratedObjects(build *buildv1.Build) error {
+	if bc.generatedObjectsRetention <= 0 || build.Status.CompletionTimestamp == nil {
+		return nil
+	}
+	if remaining := build.Status.CompletionTimestamp.Add(bc.generatedObjectsRetention).Sub(bc.clock.Now()); remaining > 0 {
+		bc.buildQueue.AddAfter(resourceName(build.Namespace, build.Name), remaining)
+		return nil
+	}
+
+	var configMaps []string
+	for _, name := range buildPodConfigMapNames(build) {
+		configMap, err := bc.configMapStore.ConfigMaps(build.Namespace).Get(name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if metav1.IsControlledBy(configMap, build) {
+			configMaps = append(configMaps, name)
+		}
+	}
+	if len(configMaps) == 0 {
+		return nil
+	}
+
+	podName := buildutil.GetBuildPodName(build)
+	_, err := bc.podStore.Pods(build.Namespace).Get(podName)
+	if err == nil {
+		klog.V(4).Infof("Keeping the generated objects of build %s while its build pod %s/%s exists", buildDesc(build), build.Namespace, podName)
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		return fmt.Errorf("could not get build pod %s/%s of build %s: %v", build.Namespace, podName, buildDesc(build), err)
+	}
+
+	var errs []error
+	for _, name := range configMaps {
+		if err := bc.configMapClient.ConfigMaps(build.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("could not delete configMap %s/%s of build %s: %v", build.Namespace, name, buildDesc(build), err))
+			continue
+		}
+		klog.V(4).Infof("Deleted configMap %s/%s of completed build %s", build.Namespace, name, buildDesc(build))
+	}
+	return kerrors.NewAggregate(errs)
+}
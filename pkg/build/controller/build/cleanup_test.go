@@ -0,0 +1,117 @@
+package build
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+func TestCleanupGeneratedObjects(t *testing.T) {
+	completed := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{}))
+	build.UID = types.UID("build-uid")
+	build.Status.CompletionTimestamp = &metav1.Time{Time: completed}
+	build.Status.LogSnippet = "done"
+
+	owned := metav1.ObjectMeta{Namespace: build.Namespace, OwnerReferences: []metav1.OwnerReference{strategy.MakeOwnerReference(build)}}
+	caConfigMap := &corev1.ConfigMap{ObjectMeta: *owned.DeepCopy()}
+	caConfigMap.Name = buildutil.GetBuildCAConfigMapName(build)
+	systemConfigMap := &corev1.ConfigMap{ObjectMeta: *owned.DeepCopy()}
+	systemConfigMap.Name = buildutil.GetBuildSystemConfigMapName(build)
+	otherConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: build.Namespace}}
+	pod := mockBuildPod(build)
+	pod.Status.Phase = corev1.PodSucceeded
+
+	objects := []*corev1.ConfigMap{caConfigMap, systemConfigMap, otherConfigMap}
+	kubeObjects := []runtime.Object{registryCAConfigMap, pod}
+	for _, obj := range objects {
+		kubeObjects = append(kubeObjects, obj)
+	}
+	kubeClient := fakeKubeExternalClientSet(kubeObjects...)
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	fakeClock := clocktesting.NewFakeClock(completed.Add(10 * time.Minute))
+	bc.clock = fakeClock
+	bc.generatedObjectsRetention = time.Hour
+	configMapIndexer := bc.kubeExternalInformers.Core().V1().ConfigMaps().Informer().GetIndexer()
+	for _, obj := range objects {
+		if err := configMapIndexer.Add(obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	podIndexer := bc.kubeExternalInformers.Core().V1().Pods().Informer().GetIndexer()
+	if err := podIndexer.Add(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configMapExists := func(name string) bool {
+		_, err := kubeClient.CoreV1().ConfigMaps(build.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return err == nil
+	}
+	expectGeneratedObjects := func(exist bool) {
+		t.Helper()
+		for _, name := range []string{caConfigMap.Name, systemConfigMap.Name} {
+			if configMapExists(name) != exist {
+				t.Errorf("expected configMap %s to exist: %v", name, exist)
+			}
+		}
+		if !configMapExists(otherConfigMap.Name) {
+			t.Errorf("expected the configMap not owned by the build to be kept")
+		}
+	}
+
+	// the retention did not pass
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGeneratedObjects(true)
+
+	// the retention passed while the build pod exists
+	fakeClock.SetTime(completed.Add(2 * time.Hour))
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGeneratedObjects(true)
+
+	// the build pod was deleted
+	if err := kubeClient.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := podIndexer.Delete(pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectGeneratedObjects(false)
+
+	// once the informer observed the deletion, handling the build again makes no request
+	for _, obj := range []*corev1.ConfigMap{caConfigMap, systemConfigMap} {
+		if err := configMapIndexer.Delete(obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	fakeClient := kubeClient.(*fake.Clientset)
+	fakeClient.ClearActions()
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := fakeClient.Actions(); len(actions) != 0 {
+		t.Errorf("expected no requests for a build whose generated objects were deleted, got %v", actions)
+	}
+}
@@ -0,0 +1,112 @@
+package build
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// webhookRevision returns the git revision of the commit reported by the webhook that triggered
+// the build, or nil if the build was not triggered by a webhook with a git revision. The revision
+// of the build itself is used when the webhook cause does not carry one.
+func webhookRevision(build *buildv1.Build) *buildv1.GitSourceRevision {
+	for _, cause := range build.Spec.TriggeredBy {
+		var revision *buildv1.SourceRevision
+		switch {
+		case cause.GitHubWebHook != nil:
+			revision = cause.GitHubWebHook.Revision
+		case cause.GitLabWebHook != nil:
+			revision = cause.GitLabWebHook.Revision
+		case cause.BitbucketWebHook != nil:
+			revision = cause.BitbucketWebHook.Revision
+		case cause.GenericWebHook != nil:
+			revision = cause.GenericWebHook.Revision
+		default:
+			continue
+		}
+		if revision == nil {
+			revision = build.Spec.Revision
+		}
+		if revision != nil && revision.Git != nil {
+			return revision.Git
+		}
+	}
+	return nil
+}
+
+// formatCommitUser formats a source control user like git does, as "name <email>".
+func formatCommitUser(user buildv1.SourceControlUser) string {
+	name, email := strings.TrimSpace(user.Name), strings.TrimSpace(user.Email)
+	switch {
+	case len(email) == 0:
+		return name
+	case len(name) == 0:
+		return fmt.Sprintf("<%s>", email)
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
+
+// truncateCommitMessage truncates the commit message to MaxCommitMessageAnnotationLength bytes
+// without splitting a multibyte character, ending truncated messages with an ellipsis.
+func truncateCommitMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if len(message) <= buildutil.MaxCommitMessageAnnotationLength {
+		return message
+	}
+	const ellipsis = "..."
+	end := buildutil.MaxCommitMessageAnnotationLength - len(ellipsis)
+	for end > 0 && !utf8.RuneStart(message[end]) {
+		end--
+	}
+	return strings.TrimSpace(message[:end]) + ellipsis
+}
+
+// commitAnnotations returns the commit annotations of a build triggered by a webhook, or nil if
+// the webhook reported no commit.
+func commitAnnotations(build *buildv1.Build) map[string]string {
+	revision := webhookRevision(build)
+	if revision == nil {
+		return nil
+	}
+	annotations := map[string]string{}
+	add := func(key, value string) {
+		if len(value) > 0 {
+			annotations[key] = value
+		}
+	}
+	// The committer pushed the commit of a webhook when the author is not known.
+	author := formatCommitUser(revision.Author)
+	if len(author) == 0 {
+		author = formatCommitUser(revision.Committer)
+	}
+	add(buildutil.BuildCommitAuthorAnnotation, author)
+	add(buildutil.BuildCommitMessageAnnotation, truncateCommitMessage(revision.Message))
+	add(buildutil.BuildCommitIDAnnotation, strings.ToLower(strings.TrimSpace(revision.Commit)))
+	if build.Spec.Source.Git != nil {
+		add(buildutil.BuildCommitRefAnnotation, strings.TrimSpace(build.Spec.Source.Git.Ref))
+	}
+	return annotations
+}
+
+// setCommitAnnotations adds the commit annotations to the update of a new build triggered by a
+// webhook that lacks any of them, creating the update if there is none, so that tooling can
+// select and show builds by their commit without parsing their revision.
+func setCommitAnnotations(build *buildv1.Build, update *buildUpdate) *buildUpdate {
+	missing := map[string]string{}
+	for key, value := range commitAnnotations(build) {
+		if build.Annotations[key] != value {
+			missing[key] = value
+		}
+	}
+	if len(missing) == 0 {
+		return update
+	}
+	if update == nil {
+		update = &buildUpdate{}
+	}
+	update.setCommitAnnotations(missing)
+	return update
+}
@@ -0,0 +1,162 @@
+package build
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildv1client "github.com/openshift/client-go/build/clientset/versioned/fake"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func webhookRevisionFixture(message string) *buildv1.SourceRevision {
+	return &buildv1.SourceRevision{
+		Type: buildv1.BuildSourceGit,
+		Git: &buildv1.GitSourceRevision{
+			Commit:    "9A1B2C3D4E5F",
+			Author:    buildv1.SourceControlUser{Name: "Jane Doe", Email: "jane@example.com"},
+			Committer: buildv1.SourceControlUser{Name: "GitHub", Email: "noreply@github.com"},
+			Message:   message,
+		},
+	}
+}
+
+func TestSetCommitAnnotations(t *testing.T) {
+	longMessage := strings.Repeat("é", buildutil.MaxCommitMessageAnnotationLength)
+	tests := []struct {
+		name        string
+		causes      []buildv1.BuildTriggerCause
+		revision    *buildv1.SourceRevision
+		annotations map[string]string
+		expected    map[string]string
+	}{
+		{
+			name: "github webhook",
+			causes: []buildv1.BuildTriggerCause{{
+				Message:       "GitHub WebHook",
+				GitHubWebHook: &buildv1.GitHubWebHookCause{Revision: webhookRevisionFixture("Fix the build\n\nLonger description.\n")},
+			}},
+			expected: map[string]string{
+				buildutil.BuildCommitAuthorAnnotation:  "Jane Doe <jane@example.com>",
+				buildutil.BuildCommitMessageAnnotation: "Fix the build\n\nLonger description.",
+				buildutil.BuildCommitIDAnnotation:      "9a1b2c3d4e5f",
+				buildutil.BuildCommitRefAnnotation:     "main",
+			},
+		},
+		{
+			name: "gitlab webhook with the revision of the build",
+			causes: []buildv1.BuildTriggerCause{{
+				Message:       "GitLab WebHook",
+				GitLabWebHook: &buildv1.GitLabWebHookCause{},
+			}},
+			revision: &buildv1.SourceRevision{
+				Type: buildv1.BuildSourceGit,
+				Git: &buildv1.GitSourceRevision{
+					Commit:    "abcdef",
+					Committer: buildv1.SourceControlUser{Name: "John Doe"},
+					Message:   "Update README",
+				},
+			},
+			expected: map[string]string{
+				buildutil.BuildCommitAuthorAnnotation:  "John Doe",
+				buildutil.BuildCommitMessageAnnotation: "Update README",
+				buildutil.BuildCommitIDAnnotation:      "abcdef",
+				buildutil.BuildCommitRefAnnotation:     "main",
+			},
+		},
+		{
+			name: "annotations already set",
+			causes: []buildv1.BuildTriggerCause{{
+				GitHubWebHook: &buildv1.GitHubWebHookCause{Revision: webhookRevisionFixture("Fix the build")},
+			}},
+			annotations: map[string]string{
+				buildutil.BuildCommitAuthorAnnotation:  "Jane Doe <jane@example.com>",
+				buildutil.BuildCommitMessageAnnotation: "Fix the build",
+				buildutil.BuildCommitIDAnnotation:      "9a1b2c3d4e5f",
+				buildutil.BuildCommitRefAnnotation:     "main",
+			},
+		},
+		{
+			name: "image change trigger",
+			causes: []buildv1.BuildTriggerCause{{
+				Message:          "Image change",
+				ImageChangeBuild: &buildv1.ImageChangeCause{ImageID: "image"},
+			}},
+			revision: webhookRevisionFixture("Fix the build"),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.Source.Git = &buildv1.GitBuildSource{URI: "https://github.com/openshift/origin.git", Ref: "main"}
+			build.Spec.TriggeredBy = tc.causes
+			build.Spec.Revision = tc.revision
+			for k, v := range tc.annotations {
+				build.Annotations[k] = v
+			}
+
+			update := setCommitAnnotations(build, nil)
+			if tc.expected == nil {
+				if update != nil {
+					t.Fatalf("expected no update, got %v", update)
+				}
+				return
+			}
+			if update == nil || !reflect.DeepEqual(update.commitAnnotations, tc.expected) {
+				t.Fatalf("expected commit annotations %v, got %v", tc.expected, update)
+			}
+		})
+	}
+
+	t.Run("long message", func(t *testing.T) {
+		build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+		build.Spec.TriggeredBy = []buildv1.BuildTriggerCause{{
+			GitHubWebHook: &buildv1.GitHubWebHookCause{Revision: webhookRevisionFixture(longMessage)},
+		}}
+		message := setCommitAnnotations(build, nil).commitAnnotations[buildutil.BuildCommitMessageAnnotation]
+		if len(message) > buildutil.MaxCommitMessageAnnotationLength || !utf8.ValidString(message) || !strings.HasSuffix(message, "...") {
+			t.Errorf("expected the message to be truncated to %d bytes, got %d bytes", buildutil.MaxCommitMessageAnnotationLength, len(message))
+		}
+	})
+}
+
+func TestHandleWebhookBuildCommitAnnotations(t *testing.T) {
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+	build.Spec.Source.Git = &buildv1.GitBuildSource{URI: "https://gitlab.com/openshift/origin.git", Ref: "main"}
+	build.Spec.TriggeredBy = []buildv1.BuildTriggerCause{{
+		Message:       "GitLab WebHook",
+		GitLabWebHook: &buildv1.GitLabWebHookCause{CommonWebHookCause: buildv1.CommonWebHookCause{Revision: webhookRevisionFixture("Fix the build")}},
+	}}
+
+	var patchedBuild *buildv1.Build
+	buildClient := fakeBuildClient(build)
+	buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds",
+		func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			var err error
+			patchedBuild, err = applyBuildPatch(build, action.(clientgotesting.PatchActionImpl).Patch)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return true, patchedBuild, nil
+		})
+	bc := newFakeBuildController(buildClient, nil, nil, nil, nil)
+	defer bc.stop()
+
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patchedBuild == nil {
+		t.Fatalf("expected the build to be updated")
+	}
+	if author := patchedBuild.Annotations[buildutil.BuildCommitAuthorAnnotation]; author != "Jane Doe <jane@example.com>" {
+		t.Errorf("expected the commit author annotation, got %q", author)
+	}
+	if id := patchedBuild.Annotations[buildutil.BuildCommitIDAnnotation]; id != "9a1b2c3d4e5f" {
+		t.Errorf("expected the commit id annotation, got %q", id)
+	}
+}
@@ -0,0 +1,175 @@
+package build
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// maxConcurrentBuilds returns the maximum number of builds of the namespace that may have a build
+// pod at the same time, or 0 when the number is not limited.
+func (bc *BuildController) maxConcurrentBuilds(namespace string) int {
+	limit := bc.defaultMaxConcurrentBuilds
+	ns, err := bc.namespaceStore.Get(namespace)
+	if err != nil {
+		return limit
+	}
+	value, ok := ns.Annotations[buildutil.MaxConcurrentBuildsAnnotation]
+	if !ok {
+		return limit
+	}
+	nsLimit, err := strconv.Atoi(value)
+	if err != nil || nsLimit < 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on namespace %s", buildutil.MaxConcurrentBuildsAnnotation, value, namespace)
+		return limit
+	}
+	return nsLimit
+}
+
+// isConcurrencyLimited returns true for builds that count against the concurrent build limit.
+// Pipeline builds are run by Jenkins and never get a build pod from this controller.
+func isConcurrencyLimited(build *buildv1.Build) bool {
+	return build.Spec.Strategy.JenkinsPipelineStrategy == nil && build.DeletionTimestamp == nil && !build.Status.Cancelled
+}
+
+// createdBefore orders builds by creation timestamp, using the name to break ties.
+func createdBefore(a, b *buildv1.Build) bool {
+	if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+		return a.CreationTimestamp.Before(&b.CreationTimestamp)
+	}
+	return a.Name < b.Name
+}
+
+// isQueuedBehindConcurrencyLimit returns true for new builds the concurrent build limit of their
+// namespace holds back. New builds held back by their run policy or by a later admission step do
+// not have a build pod coming and do not take a slot under the limit.
+func isQueuedBehindConcurrencyLimit(build *buildv1.Build) bool {
+	condition := findBuildCondition(build, buildutil.BuildConditionQueued)
+	return build.Status.Phase == buildv1.BuildPhaseNew && condition != nil && condition.Status == corev1.ConditionTrue && condition.Reason == buildutil.BuildQueuedReason
+}
+
+// checkConcurrencyLimit decides whether a new build may get its build pod under the concurrent
+// build limit of its namespace. Builds are admitted in creation timestamp order: a build is only
+// admitted when the builds with a pod and the builds created before it that are queued behind the
+// limit leave room under the limit. When the build has to wait, queued is true and the returned update, if any, sets the
+// queued condition explaining why.
+func (bc *BuildController) checkConcurrencyLimit(build *buildv1.Build) (queued bool, update *buildUpdate, err error) {
+	limit := bc.maxConcurrentBuilds(build.Namespace)
+	if limit == 0 {
+		return false, nil, nil
+	}
+
+	builds, err := bc.buildLister.Builds(build.Namespace).List(labels.Everything())
+	if err != nil {
+		return false, nil, err
+	}
+	active, ahead := 0, 0
+	for _, b := range builds {
+		if b.Name == build.Name || !isConcurrencyLimited(b) {
+			continue
+		}
+		switch b.Status.Phase {
+		case buildv1.BuildPhasePending, buildv1.BuildPhaseRunning:
+			active++
+		case buildv1.BuildPhaseNew:
+			if createdBefore(b, build) && isQueuedBehindConcurrencyLimit(b) {
+				ahead++
+			}
+		}
+	}
+	if active+ahead < limit {
+		return false, nil, nil
+	}
+
+	// the message leaves out the counts of running and queued builds, which change with every build
+	// admitted or completed in the namespace, so that queued builds are not updated for them
+	message := fmt.Sprintf("Build is queued behind the limit of %d concurrent builds in namespace %s.", limit, build.Namespace)
+	klog.V(4).Infof("Build %s is queued: %d running, %d queued ahead of it", buildDesc(build), active, ahead)
+	if existing := findBuildCondition(build, buildutil.BuildConditionQueued); existing != nil &&
+		existing.Status == corev1.ConditionTrue && existing.Reason == buildutil.BuildQueuedReason && existing.Message == message {
+		return true, nil, nil
+	}
+	update = &buildUpdate{}
+	update.setCondition(newQueuedCondition(corev1.ConditionTrue, buildutil.BuildQueuedReason, message))
+	return true, update, nil
+}
+
+// admitQueuedBuild marks a build that was held back by the concurrent build limit or by its build
+// cache as admitted.
+func admitQueuedBuild(build *buildv1.Build, update *buildUpdate) {
+	existing := findBuildCondition(build, buildutil.BuildConditionQueued)
+	if existing == nil || existing.Status != corev1.ConditionTrue {
+		return
+	}
+	message := "Build was admitted under the concurrent build limit."
+	if existing.Reason == buildutil.BuildCacheInUseReason {
+		message = "Build was admitted once the build cache was released."
+	}
+	update.setCondition(newQueuedCondition(corev1.ConditionFalse, buildutil.BuildAdmittedReason, message))
+}
+
+// admitUnderConcurrencyLimit marks a build that was queued behind the concurrent build limit as
+// admitted when a later admission step holds it back, so that it does not keep the builds created
+// after it queued. It returns the update to make, creating it when needed.
+func admitUnderConcurrencyLimit(build *buildv1.Build, update *buildUpdate) *buildUpdate {
+	if !isQueuedBehindConcurrencyLimit(build) {
+		return update
+	}
+	if update == nil {
+		update = &buildUpdate{}
+	}
+	update.setCondition(newQueuedCondition(corev1.ConditionFalse, buildutil.BuildAdmittedReason, "Build was admitted under the concurrent build limit."))
+	return update
+}
+
+// enqueueQueuedBuilds requeues the new builds of a namespace with a concurrent build limit, in
+// creation timestamp order, so that they are admitted once an active build goes away.
+func (bc *BuildController) enqueueQueuedBuilds(namespace string) {
+	if bc.maxConcurrentBuilds(namespace) == 0 {
+		return
+	}
+	builds, err := bc.buildLister.Builds(namespace).List(labels.Everything())
+	if err != nil {
+		klog.V(2).Infof("Unable to list builds of namespace %s to admit queued builds: %v", namespace, err)
+		return
+	}
+	var queued []*buildv1.Build
+	for _, b := range builds {
+		if b.Status.Phase == buildv1.BuildPhaseNew && isConcurrencyLimited(b) {
+			queued = append(queued, b)
+		}
+	}
+	sort.Slice(queued, func(i, j int) bool { return createdBefore(queued[i], queued[j]) })
+	for _, b := range queued {
+		bc.enqueueBuild(b)
+	}
+}
+
+func findBuildCondition(build *buildv1.Build, conditionType buildv1.BuildConditionType) *buildv1.BuildCondition {
+	for i := range build.Status.Conditions {
+		if build.Status.Conditions[i].Type == conditionType {
+			return &build.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func newQueuedCondition(status corev1.ConditionStatus, reason, message string) buildv1.BuildCondition {
+	now := metav1.Now()
+	return buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionQueued,
+		Status:             status,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+}
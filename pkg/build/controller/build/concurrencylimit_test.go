@@ -0,0 +1,293 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func newConcurrencyLimitController(defaultLimit int, namespaces []*corev1.Namespace, builds []*buildv1.Build) (*BuildController, cache.Indexer) {
+	namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, ns := range namespaces {
+		namespaceIndexer.Add(ns)
+	}
+	buildIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, b := range builds {
+		buildIndexer.Add(b)
+	}
+	return &BuildController{
+		buildLister:                buildv1lister.NewBuildLister(buildIndexer),
+		namespaceStore:             v1lister.NewNamespaceLister(namespaceIndexer),
+		buildQueue:                 workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		defaultMaxConcurrentBuilds: defaultLimit,
+	}, buildIndexer
+}
+
+func limitedNamespace(annotations map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "namespace", Annotations: annotations}}
+}
+
+func concurrencyTestBuild(name string, phase buildv1.BuildPhase, created time.Time) *buildv1.Build {
+	build := dockerStrategy(mockBuild(phase, buildv1.BuildOutput{}))
+	build.Name = name
+	build.CreationTimestamp = metav1.NewTime(created)
+	return build
+}
+
+func TestMaxConcurrentBuilds(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit int
+		namespace    *corev1.Namespace
+		expected     int
+	}{
+		{
+			name:     "unlimited by default",
+			expected: 0,
+		},
+		{
+			name:         "cluster default",
+			defaultLimit: 5,
+			namespace:    limitedNamespace(nil),
+			expected:     5,
+		},
+		{
+			name:         "namespace annotation overrides the default",
+			defaultLimit: 5,
+			namespace:    limitedNamespace(map[string]string{buildutil.MaxConcurrentBuildsAnnotation: "2"}),
+			expected:     2,
+		},
+		{
+			name:         "namespace annotation removes the limit",
+			defaultLimit: 5,
+			namespace:    limitedNamespace(map[string]string{buildutil.MaxConcurrentBuildsAnnotation: "0"}),
+			expected:     0,
+		},
+		{
+			name:         "invalid annotation falls back to the default",
+			defaultLimit: 5,
+			namespace:    limitedNamespace(map[string]string{buildutil.MaxConcurrentBuildsAnnotation: "-1"}),
+			expected:     5,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var namespaces []*corev1.Namespace
+			if tc.namespace != nil {
+				namespaces = append(namespaces, tc.namespace)
+			}
+			bc, _ := newConcurrencyLimitController(tc.defaultLimit, namespaces, nil)
+			if actual := bc.maxConcurrentBuilds("namespace"); actual != tc.expected {
+				t.Errorf("expected limit %d, got %d", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckConcurrencyLimit(t *testing.T) {
+	now := time.Now()
+	running := concurrencyTestBuild("running", buildv1.BuildPhaseRunning, now.Add(-time.Hour))
+	pending := concurrencyTestBuild("pending", buildv1.BuildPhasePending, now.Add(-time.Hour))
+	complete := concurrencyTestBuild("complete", buildv1.BuildPhaseComplete, now.Add(-time.Hour))
+	older := concurrencyTestBuild("older", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	older.Status.Conditions = []buildv1.BuildCondition{newQueuedCondition(corev1.ConditionTrue, buildutil.BuildQueuedReason, "queued")}
+	// stuck is held back by an admission step other than the concurrent build limit, such as its
+	// run policy, a missing image or the build cache claim of its BuildConfig
+	stuck := concurrencyTestBuild("stuck", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	stuckOnCache := concurrencyTestBuild("stuck-on-cache", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	stuckOnCache.Status.Conditions = []buildv1.BuildCondition{newQueuedCondition(corev1.ConditionTrue, buildutil.BuildCacheInUseReason, "waiting")}
+	admitted := concurrencyTestBuild("admitted", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	admitted.Status.Conditions = []buildv1.BuildCondition{newQueuedCondition(corev1.ConditionFalse, buildutil.BuildAdmittedReason, "admitted")}
+	cancelled := concurrencyTestBuild("cancelled", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	cancelled.Status.Cancelled = true
+	pipeline := pipelineStrategy(concurrencyTestBuild("pipeline", buildv1.BuildPhaseRunning, now.Add(-time.Hour)))
+	build := concurrencyTestBuild("build", buildv1.BuildPhaseNew, now)
+
+	tests := []struct {
+		name            string
+		limit           string
+		builds          []*buildv1.Build
+		expectedQueued  bool
+		expectedMessage string
+	}{
+		{
+			name:   "no limit",
+			builds: []*buildv1.Build{running, pending, older},
+		},
+		{
+			name:   "room under the limit",
+			limit:  "3",
+			builds: []*buildv1.Build{running, complete, older},
+		},
+		{
+			name:            "limit reached by active builds",
+			limit:           "2",
+			builds:          []*buildv1.Build{running, pending, complete},
+			expectedQueued:  true,
+			expectedMessage: "Build is queued behind the limit of 2 concurrent builds in namespace namespace.",
+		},
+		{
+			name:            "limit reached by older queued builds",
+			limit:           "2",
+			builds:          []*buildv1.Build{running, older},
+			expectedQueued:  true,
+			expectedMessage: "Build is queued behind the limit of 2 concurrent builds in namespace namespace.",
+		},
+		{
+			name:   "older builds held back by other admission steps do not count",
+			limit:  "2",
+			builds: []*buildv1.Build{running, stuck, stuckOnCache, admitted},
+		},
+		{
+			name:   "cancelled and pipeline builds do not count",
+			limit:  "2",
+			builds: []*buildv1.Build{running, cancelled, pipeline},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var annotations map[string]string
+			if tc.limit != "" {
+				annotations = map[string]string{buildutil.MaxConcurrentBuildsAnnotation: tc.limit}
+			}
+			bc, _ := newConcurrencyLimitController(0, []*corev1.Namespace{limitedNamespace(annotations)}, append(tc.builds, build))
+
+			queued, update, err := bc.checkConcurrencyLimit(build)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if queued != tc.expectedQueued {
+				t.Fatalf("expected queued %v, got %v", tc.expectedQueued, queued)
+			}
+			if !queued {
+				if update != nil {
+					t.Errorf("expected no update for an admitted build, got %v", update)
+				}
+				return
+			}
+			if update == nil || len(update.conditions) != 1 {
+				t.Fatalf("expected an update setting the queued condition, got %v", update)
+			}
+			condition := update.conditions[0]
+			if condition.Type != buildutil.BuildConditionQueued || condition.Status != corev1.ConditionTrue || condition.Reason != buildutil.BuildQueuedReason {
+				t.Errorf("unexpected condition %#v", condition)
+			}
+			if condition.Message != tc.expectedMessage {
+				t.Errorf("expected message %q, got %q", tc.expectedMessage, condition.Message)
+			}
+			if update.phase != nil {
+				t.Errorf("expected queued build to stay in its phase, got %s", *update.phase)
+			}
+
+			// an unchanged condition does not need another update
+			update.apply(build)
+			defer func() { build.Status.Conditions = nil }()
+			queued, update, err = bc.checkConcurrencyLimit(build)
+			if err != nil || !queued || update != nil {
+				t.Errorf("expected the build to stay queued without another update, got queued %v, update %v, err %v", queued, update, err)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitAdmissionOrder(t *testing.T) {
+	now := time.Now()
+	first := concurrencyTestBuild("first", buildv1.BuildPhaseNew, now.Add(-3*time.Minute))
+	second := concurrencyTestBuild("second", buildv1.BuildPhaseNew, now.Add(-2*time.Minute))
+	third := concurrencyTestBuild("third", buildv1.BuildPhaseNew, now.Add(-time.Minute))
+	namespace := limitedNamespace(map[string]string{buildutil.MaxConcurrentBuildsAnnotation: "1"})
+	// the lister returns builds in no particular order, add them newest first
+	bc, buildIndexer := newConcurrencyLimitController(0, []*corev1.Namespace{namespace}, []*buildv1.Build{third, second, first})
+
+	transition := func(b *buildv1.Build, phase buildv1.BuildPhase) {
+		if phase == buildv1.BuildPhasePending {
+			update := &buildUpdate{}
+			admitQueuedBuild(b, update)
+			update.apply(b)
+		}
+		b.Status.Phase = phase
+		buildIndexer.Update(b)
+	}
+	// expectAdmitted evaluates the new builds oldest first, as the build queue does, and moves the
+	// admitted builds to the pending phase as creating their build pod would.
+	expectAdmitted := func(admitted ...*buildv1.Build) {
+		t.Helper()
+		expected := map[string]bool{}
+		for _, b := range admitted {
+			expected[b.Name] = true
+		}
+		for _, b := range []*buildv1.Build{first, second, third} {
+			if b.Status.Phase != buildv1.BuildPhaseNew {
+				continue
+			}
+			queued, update, err := bc.checkConcurrencyLimit(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if queued == expected[b.Name] {
+				t.Errorf("build %s: expected admitted %v, got queued %v", b.Name, expected[b.Name], queued)
+			}
+			if update != nil {
+				update.apply(b)
+			}
+			if !queued {
+				transition(b, buildv1.BuildPhasePending)
+			}
+		}
+	}
+
+	expectAdmitted(first)
+	if condition := findBuildCondition(first, buildutil.BuildConditionQueued); condition != nil {
+		t.Errorf("expected the admitted build not to be marked as queued, got %#v", condition)
+	}
+	expectAdmitted()
+
+	// completing the running build requeues the waiting builds oldest first
+	transition(first, buildv1.BuildPhaseComplete)
+	bc.enqueueQueuedBuilds("namespace")
+	for _, expected := range []string{"namespace/second", "namespace/third"} {
+		key, _ := bc.buildQueue.Get()
+		if key != expected {
+			t.Errorf("expected %s to be requeued, got %v", expected, key)
+		}
+		bc.buildQueue.Done(key)
+	}
+	expectAdmitted(second)
+
+	condition := findBuildCondition(second, buildutil.BuildConditionQueued)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != buildutil.BuildAdmittedReason {
+		t.Errorf("expected the queued condition of the admitted build to be cleared, got %#v", condition)
+	}
+	expectAdmitted()
+}
+
+func TestAdmitUnderConcurrencyLimit(t *testing.T) {
+	build := concurrencyTestBuild("build", buildv1.BuildPhaseNew, time.Now())
+	if update := admitUnderConcurrencyLimit(build, nil); update != nil {
+		t.Errorf("expected no update for a build that was not queued, got %v", update)
+	}
+
+	// a build admitted under the limit but waiting for capacity stops counting ahead of later builds
+	build.Status.Conditions = []buildv1.BuildCondition{newQueuedCondition(corev1.ConditionTrue, buildutil.BuildQueuedReason, "queued")}
+	update := admitUnderConcurrencyLimit(build, nil)
+	if update == nil {
+		t.Fatal("expected an update admitting the queued build")
+	}
+	update.apply(build)
+	if isQueuedBehindConcurrencyLimit(build) {
+		t.Errorf("expected the build not to be queued behind the limit, got %#v", build.Status.Conditions)
+	}
+	condition := findBuildCondition(build, buildutil.BuildConditionQueued)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != buildutil.BuildAdmittedReason {
+		t.Errorf("expected the queued condition to be cleared, got %#v", condition)
+	}
+}
@@ -0,0 +1,106 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+// completionDeadline returns the completion deadline of the build in seconds, including the default
+// deadline the build defaults gave its build pod, or nil if the build has none.
+func completionDeadline(build *buildv1.Build, pod *corev1.Pod) *int64 {
+	if pod != nil {
+		if podBuild, err := common.GetBuildFromPod(pod); err == nil && podBuild.Spec.CompletionDeadlineSeconds != nil {
+			return podBuild.Spec.CompletionDeadlineSeconds
+		}
+	}
+	return build.Spec.CompletionDeadlineSeconds
+}
+
+// setupPodActiveDeadline replaces the activeDeadlineSeconds of a build pod with a completion
+// deadline, which the kubelet counts from before the pod runs, by a backstop that only ends builds
+// the build controller failed to time out: the completion deadline is enforced by the controller
+// from the start of the build, after at most the pending timeout.
+func (bc *BuildController) setupPodActiveDeadline(pod *corev1.Pod) {
+	if bc.legacyCompletionDeadline {
+		return
+	}
+	podBuild, err := common.GetBuildFromPod(pod)
+	if err != nil || podBuild.Spec.CompletionDeadlineSeconds == nil {
+		return
+	}
+	deadline := podBuild.Spec.CompletionDeadlineSeconds
+	activeDeadline := int64(strategy.DefaultActiveDeadlineSeconds)
+	if bc.pendingTimeout > 0 {
+		activeDeadline = int64(bc.pendingTimeout.Seconds()) + *deadline
+	} else if *deadline > activeDeadline {
+		activeDeadline = *deadline
+	}
+	pod.Spec.ActiveDeadlineSeconds = &activeDeadline
+}
+
+// checkBuildDeadlines fails active builds that ran longer than their completion deadline since
+// they started, or whose build pod is pending for longer than the pending timeout. Other builds
+// are requeued for when their deadline expires. It returns the update of the active build
+// unchanged when no deadline expired.
+func (bc *BuildController) checkBuildDeadlines(build *buildv1.Build, pod *corev1.Pod, update *buildUpdate) (*buildUpdate, error) {
+	if bc.legacyCompletionDeadline || pod == nil {
+		return update, nil
+	}
+	phase := build.Status.Phase
+	startTime := build.Status.StartTimestamp
+	if update != nil {
+		if update.phase != nil {
+			phase = *update.phase
+		}
+		if update.startTime != nil {
+			startTime = update.startTime
+		}
+	}
+
+	var remaining time.Duration
+	var reason buildv1.StatusReason
+	var message string
+	switch {
+	case phase == buildv1.BuildPhaseRunning && startTime != nil:
+		deadline := completionDeadline(build, pod)
+		if deadline == nil {
+			return update, nil
+		}
+		remaining = startTime.Add(time.Duration(*deadline) * time.Second).Sub(bc.clock.Now())
+		reason = buildutil.StatusReasonBuildTimeout
+		message = fmt.Sprintf("The build exceeded its completion deadline of %d seconds after it started running.", *deadline)
+	case (phase == buildv1.BuildPhaseNew || phase == buildv1.BuildPhasePending) && bc.pendingTimeout > 0:
+		remaining = pod.CreationTimestamp.Add(bc.pendingTimeout).Sub(bc.clock.Now())
+		reason = buildutil.StatusReasonBuildPendingTimeout
+		message = fmt.Sprintf("The build pod was pending for longer than the pending timeout of %s.", bc.pendingTimeout)
+	default:
+		return update, nil
+	}
+
+	if remaining > 0 {
+		bc.buildQueue.AddAfter(resourceName(build.Namespace, build.Name), remaining)
+		return update, nil
+	}
+
+	klog.V(2).Infof("Failing build %s: %s", buildDesc(build), message)
+	err := bc.podClient.Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return update, fmt.Errorf("could not delete build pod %s/%s of timed out build %s: %v", pod.Namespace, pod.Name, buildDesc(build), err)
+	}
+	timeout := transitionToPhase(buildv1.BuildPhaseFailed, reason, message)
+	if startTime != nil && build.Status.StartTimestamp == nil {
+		timeout.setStartTime(*startTime)
+	}
+	return timeout, nil
+}
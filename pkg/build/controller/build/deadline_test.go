@@ -0,0 +1,181 @@
+package build
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func deadlineTestBuildPod(build *buildv1.Build, created time.Time) *corev1.Pod {
+	pod := mockBuildPod(build)
+	pod.CreationTimestamp = metav1.NewTime(created)
+	pod.Status.Phase = corev1.PodPending
+	return pod
+}
+
+// runningPod returns the pod after it started running at the given time.
+func runningPod(pod *corev1.Pod, started time.Time) *corev1.Pod {
+	pod = pod.DeepCopy()
+	startTime := metav1.NewTime(started)
+	pod.Status.Phase = corev1.PodRunning
+	pod.Status.StartTime = &startTime
+	return pod
+}
+
+func TestCompletionDeadlineFromBuildStart(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clocktesting.NewFakeClock(created)
+	deadline := int64(600)
+
+	build := dockerStrategy(mockBuild(buildv1.BuildPhasePending, buildv1.BuildOutput{}))
+	build.Spec.CompletionDeadlineSeconds = &deadline
+	pod := deadlineTestBuildPod(build, created)
+
+	bc := newFakeBuildController(nil, nil, fakeKubeExternalClientSet(pod), nil, nil)
+	defer bc.stop()
+	bc.clock = fakeClock
+
+	// the pod pends for longer than the completion deadline
+	fakeClock.SetTime(created.Add(15 * time.Minute))
+	update, err := bc.handleActiveBuild(build, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update != nil && update.phase != nil && *update.phase == buildv1.BuildPhaseFailed {
+		t.Fatalf("expected the pending build not to time out, got %v", update)
+	}
+
+	// and starts running
+	started := fakeClock.Now()
+	pod = runningPod(pod, started)
+	update, err = bc.handleActiveBuild(build, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update == nil || update.phase == nil || *update.phase != buildv1.BuildPhaseRunning {
+		t.Fatalf("expected the build to run, got %v", update)
+	}
+	update.apply(build)
+
+	// the build is still within its deadline once it started
+	fakeClock.SetTime(started.Add(9 * time.Minute))
+	update, err = bc.handleActiveBuild(build, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update != nil {
+		t.Fatalf("expected the running build not to time out, got %v", update)
+	}
+
+	// and completes
+	pod = pod.DeepCopy()
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}}}
+	update, err = bc.handleActiveBuild(build, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update == nil || update.phase == nil || *update.phase != buildv1.BuildPhaseComplete {
+		t.Errorf("expected the build to complete, got %v", update)
+	}
+}
+
+func TestCompletionDeadlineExceeded(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	deadline := int64(600)
+	tests := []struct {
+		name           string
+		phase          buildv1.BuildPhase
+		started        bool
+		pendingTimeout time.Duration
+		legacy         bool
+		now            time.Duration
+		expectedReason buildv1.StatusReason
+	}{
+		{
+			name:           "running longer than the deadline since the start",
+			phase:          buildv1.BuildPhaseRunning,
+			started:        true,
+			now:            20 * time.Minute,
+			expectedReason: buildutil.StatusReasonBuildTimeout,
+		},
+		{
+			name:    "running within the deadline since the start",
+			phase:   buildv1.BuildPhaseRunning,
+			started: true,
+			now:     14 * time.Minute,
+		},
+		{
+			name:           "pending longer than the pending timeout",
+			phase:          buildv1.BuildPhasePending,
+			pendingTimeout: 30 * time.Minute,
+			now:            31 * time.Minute,
+			expectedReason: buildutil.StatusReasonBuildPendingTimeout,
+		},
+		{
+			name:           "pending within the pending timeout",
+			phase:          buildv1.BuildPhasePending,
+			pendingTimeout: 30 * time.Minute,
+			now:            29 * time.Minute,
+		},
+		{
+			name:  "pending without a pending timeout",
+			phase: buildv1.BuildPhasePending,
+			now:   24 * time.Hour,
+		},
+		{
+			name:    "legacy deadline is left to the kubelet",
+			phase:   buildv1.BuildPhaseRunning,
+			started: true,
+			legacy:  true,
+			now:     20 * time.Minute,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := dockerStrategy(mockBuild(tc.phase, buildv1.BuildOutput{}))
+			build.Spec.CompletionDeadlineSeconds = &deadline
+			pod := deadlineTestBuildPod(build, created)
+			if tc.started {
+				// the build started running after pending for 5 minutes
+				pod = runningPod(pod, created.Add(5*time.Minute))
+				build.Status.StartTimestamp = pod.Status.StartTime
+			}
+
+			kubeClient := fakeKubeExternalClientSet(pod)
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+			bc.clock = clocktesting.NewFakeClock(created.Add(tc.now))
+			bc.pendingTimeout = tc.pendingTimeout
+			bc.legacyCompletionDeadline = tc.legacy
+
+			update, err := bc.handleActiveBuild(build, pod)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_, podErr := kubeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if len(tc.expectedReason) == 0 {
+				if update != nil && update.phase != nil && *update.phase == buildv1.BuildPhaseFailed {
+					t.Errorf("expected the build not to time out, got %v", update)
+				}
+				if podErr != nil {
+					t.Errorf("expected the build pod to be kept: %v", podErr)
+				}
+				return
+			}
+			if update == nil || update.phase == nil || *update.phase != buildv1.BuildPhaseFailed || *update.reason != tc.expectedReason {
+				t.Fatalf("expected the build to fail with reason %s, got %v", tc.expectedReason, update)
+			}
+			if podErr == nil {
+				t.Errorf("expected the build pod of the timed out build to be deleted")
+			}
+		})
+	}
+}
@@ -1,11 +1,15 @@
 package defaults
 
 import (
+	"regexp"
+	"strconv"
 	"strings"
 
 	"k8s.io/klog/v2"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	configv1 "github.com/openshift/api/config/v1"
@@ -18,6 +22,101 @@ import (
 type BuildDefaults struct {
 	Config       *openshiftcontrolplanev1.BuildDefaultsConfig
 	DefaultProxy *configv1.ProxySpec
+	// PriorityClassName is the priority class of build pods that do not request one.
+	PriorityClassName string
+	// CompletionDeadlineSeconds is the completion deadline of builds that do not set one.
+	CompletionDeadlineSeconds *int64
+	// Tolerations are added to build pods that do not already tolerate the same taints.
+	Tolerations []corev1.Toleration
+	// DefaultGitCloneDepth is the depth of the git clone of builds that do not set their own
+	// through the GIT_CLONE_DEPTH environment variable. A depth of 0 clones the full history.
+	DefaultGitCloneDepth *int32
+	// DisableSubmodules skips the git submodules of builds that do not set
+	// GIT_DISABLE_SUBMODULES themselves.
+	DisableSubmodules bool
+	// LogLevel is the log level of the build pods of builds that do not set their own through the
+	// BUILD_LOGLEVEL environment variable or the log level annotation. It is ignored unless it is
+	// from 0 to 5.
+	LogLevel *int32
+	// PodAffinity and PodAntiAffinity are set on build pods that do not have their own. Terms
+	// without a label selector select all build pods.
+	PodAffinity     *corev1.PodAffinity
+	PodAntiAffinity *corev1.PodAntiAffinity
+	// TopologySpreadConstraints are added to build pods that do not already spread over the same
+	// topology keys. Constraints without a label selector spread all build pods.
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint
+	// StrategyResources are the default resources of the builds of each strategy. They replace the
+	// resources of Config for the builds of the strategies that have them.
+	StrategyResources StrategyResources
+	// RescheduleLostPods creates a new build pod for running builds whose build pod was deleted, such
+	// as when its node failed, before they started to push their output image, at most
+	// MaxPodReschedules times per build. Builds decide for themselves through the
+	// BuildRescheduleLostPodAnnotation.
+	RescheduleLostPods bool
+	MaxPodReschedules  int
+	// ImageLabelTemplates are added to the output image labels of builds that do not set a label of
+	// the same name, after the image labels of Config. Their values may refer to the variables of
+	// imageLabelVariables as ${NAME}. Labels whose variables have no value for the build, and labels
+	// with invalid names, are not added.
+	ImageLabelTemplates []buildv1.ImageLabel
+}
+
+// imageLabelVariablePattern matches the variable references of image label templates.
+var imageLabelVariablePattern = regexp.MustCompile(`\$\{([A-Z_]+)\}`)
+
+// imageLabelVariables returns the values of the variables of image label templates for the build.
+func imageLabelVariables(build *buildv1.Build) map[string]string {
+	variables := map[string]string{
+		"BUILD_NAME":       build.Name,
+		"BUILD_NAMESPACE":  build.Namespace,
+		"BUILDCONFIG_NAME": sharedbuildutil.ConfigNameForBuild(build),
+	}
+	if git := build.Spec.Source.Git; git != nil {
+		variables["SOURCE_REF"] = git.Ref
+	}
+	if revision := build.Spec.Revision; revision != nil && revision.Git != nil {
+		variables["SOURCE_COMMIT"] = revision.Git.Commit
+	}
+	return variables
+}
+
+// InvalidImageLabelName returns the reasons the name of an image label is invalid for, or nil when
+// it is valid.
+func InvalidImageLabelName(name string) []string {
+	if len(name) == 0 {
+		return []string{"must not be empty"}
+	}
+	return validation.IsConfigMapKey(name)
+}
+
+// expandImageLabelTemplate substitutes the variables of the value of an image label template. It
+// returns false when the value refers to a variable without a value.
+func expandImageLabelTemplate(value string, variables map[string]string) (string, bool) {
+	ok := true
+	expanded := imageLabelVariablePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		v := variables[imageLabelVariablePattern.FindStringSubmatch(ref)[1]]
+		if len(v) == 0 {
+			ok = false
+		}
+		return v
+	})
+	return expanded, ok
+}
+
+// StrategyResources holds the default resources of the builds of each build strategy.
+type StrategyResources struct {
+	DockerStrategy *corev1.ResourceRequirements `json:"dockerStrategy,omitempty"`
+	SourceStrategy *corev1.ResourceRequirements `json:"sourceStrategy,omitempty"`
+	CustomStrategy *corev1.ResourceRequirements `json:"customStrategy,omitempty"`
+}
+
+// DeepCopy returns a copy of the strategy resources.
+func (r StrategyResources) DeepCopy() StrategyResources {
+	return StrategyResources{
+		DockerStrategy: r.DockerStrategy.DeepCopy(),
+		SourceStrategy: r.SourceStrategy.DeepCopy(),
+		CustomStrategy: r.CustomStrategy.DeepCopy(),
+	}
 }
 
 // ApplyDefaults applies configured build defaults to a build pod
@@ -31,6 +130,27 @@ func (b BuildDefaults) ApplyDefaults(pod *corev1.Pod) error {
 		b.applyPodProxyDefaults(pod, build.Spec.Strategy.CustomStrategy != nil)
 	}
 
+	if b.CompletionDeadlineSeconds != nil && build.Spec.CompletionDeadlineSeconds == nil {
+		klog.V(5).Infof("Setting default completion deadline of %d seconds on build %s/%s", *b.CompletionDeadlineSeconds, build.Namespace, build.Name)
+		deadline := *b.CompletionDeadlineSeconds
+		build.Spec.CompletionDeadlineSeconds = &deadline
+		pod.Spec.ActiveDeadlineSeconds = &deadline
+	}
+
+	if len(b.PriorityClassName) != 0 && len(pod.Spec.PriorityClassName) == 0 {
+		klog.V(5).Infof("Setting default priority class %s on pod %s/%s", b.PriorityClassName, pod.Namespace, pod.Name)
+		pod.Spec.PriorityClassName = b.PriorityClassName
+	}
+
+	for _, toleration := range b.Tolerations {
+		addDefaultToleration(toleration, &pod.Spec.Tolerations)
+	}
+
+	b.applyGitCloneDefaults(build)
+	b.applyLogLevelDefault(build)
+	b.applyPodSchedulingDefaults(pod)
+	b.applyResourceDefaults(build, pod)
+
 	if b.Config != nil {
 		klog.V(4).Infof("Applying defaults to build %s/%s", build.Namespace, build.Name)
 		b.applyBuildDefaults(build)
@@ -38,6 +158,7 @@ func (b BuildDefaults) ApplyDefaults(pod *corev1.Pod) error {
 		klog.V(4).Infof("Applying defaults to pod %s/%s", pod.Namespace, pod.Name)
 		b.applyPodDefaults(pod, build.Spec.Strategy.CustomStrategy != nil)
 	}
+	b.applyImageLabelTemplates(build)
 
 	err = setPodLogLevelFromBuild(pod, build)
 	if err != nil {
@@ -69,7 +190,7 @@ func setPodLogLevelFromBuild(pod *corev1.Pod, build *buildv1.Build) error {
 	buildLogLevel := "0" // The ultimate default for the build pod's loglevel if no actor sets BUILD_LOGLEVEL in the Build
 	for i := range envs {
 		env := envs[i]
-		if env.Name == "BUILD_LOGLEVEL" {
+		if env.Name == buildutil.BuildLogLevelEnvVar {
 			buildLogLevel = env.Value
 			break
 		}
@@ -140,9 +261,6 @@ func (b BuildDefaults) applyPodDefaults(pod *corev1.Pod, isCustomBuild bool) {
 		}
 	}
 
-	// Apply default resources
-	defaultResources := b.Config.Resources
-
 	allContainers := make([]*corev1.Container, 0, len(pod.Spec.Containers)+len(pod.Spec.InitContainers))
 	for i := range pod.Spec.Containers {
 		allContainers = append(allContainers, &pod.Spec.Containers[i])
@@ -159,7 +277,66 @@ func (b BuildDefaults) applyPodDefaults(pod *corev1.Pod, isCustomBuild bool) {
 		} else {
 			buildutil.MergeTrustedEnvWithoutDuplicates(b.Config.Env, &c.Env, false)
 		}
+	}
+}
+
+// resourceDefaults returns the default resources of the build: those of its strategy, or those
+// of Config for strategies without their own.
+func (b BuildDefaults) resourceDefaults(build *buildv1.Build) corev1.ResourceRequirements {
+	var strategyResources *corev1.ResourceRequirements
+	switch {
+	case build.Spec.Strategy.DockerStrategy != nil:
+		strategyResources = b.StrategyResources.DockerStrategy
+	case build.Spec.Strategy.SourceStrategy != nil:
+		strategyResources = b.StrategyResources.SourceStrategy
+	case build.Spec.Strategy.CustomStrategy != nil:
+		strategyResources = b.StrategyResources.CustomStrategy
+	}
+	if strategyResources != nil {
+		return *strategyResources
+	}
+	if b.Config != nil {
+		return b.Config.Resources
+	}
+	return corev1.ResourceRequirements{}
+}
+
+// applyResourceDefaults sets the default resources the build and its build pod containers do not
+// request or limit themselves. They are applied before the build pod is created, so that limit
+// ranges only default the resources that are still unset.
+func (b BuildDefaults) applyResourceDefaults(build *buildv1.Build, pod *corev1.Pod) {
+	defaultResources := b.resourceDefaults(build)
+	if len(defaultResources.Limits) == 0 && len(defaultResources.Requests) == 0 {
+		return
+	}
+
+	if build.Spec.Resources.Limits == nil {
+		build.Spec.Resources.Limits = corev1.ResourceList{}
+	}
+	for name, value := range defaultResources.Limits {
+		if _, ok := build.Spec.Resources.Limits[corev1.ResourceName(name)]; !ok {
+			klog.V(5).Infof("Setting default resource limit %s for build %s/%s to %v", name, build.Namespace, build.Name, value)
+			build.Spec.Resources.Limits[corev1.ResourceName(name)] = value
+		}
+	}
+	if build.Spec.Resources.Requests == nil {
+		build.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	for name, value := range defaultResources.Requests {
+		if _, ok := build.Spec.Resources.Requests[corev1.ResourceName(name)]; !ok {
+			klog.V(5).Infof("Setting default resource request %s for build %s/%s to %v", name, build.Namespace, build.Name, value)
+			build.Spec.Resources.Requests[corev1.ResourceName(name)] = value
+		}
+	}
 
+	allContainers := make([]*corev1.Container, 0, len(pod.Spec.Containers)+len(pod.Spec.InitContainers))
+	for i := range pod.Spec.Containers {
+		allContainers = append(allContainers, &pod.Spec.Containers[i])
+	}
+	for i := range pod.Spec.InitContainers {
+		allContainers = append(allContainers, &pod.Spec.InitContainers[i])
+	}
+	for _, c := range allContainers {
 		if c.Resources.Limits == nil {
 			c.Resources.Limits = corev1.ResourceList{}
 		}
@@ -235,26 +412,123 @@ func (b BuildDefaults) applyBuildDefaults(build *buildv1.Build) {
 		}
 	}
 
-	//Apply default resources
-	defaultResources := b.Config.Resources
-	if build.Spec.Resources.Limits == nil {
-		build.Spec.Resources.Limits = corev1.ResourceList{}
+}
+
+// applyGitCloneDefaults sets the default clone depth and submodule handling of builds with a git
+// source in the environment of the build, where the builder image reads them. Values set by the
+// build itself are kept.
+func (b BuildDefaults) applyGitCloneDefaults(build *buildv1.Build) {
+	if build.Spec.Source.Git == nil {
+		return
 	}
-	for name, value := range defaultResources.Limits {
-		if _, ok := build.Spec.Resources.Limits[corev1.ResourceName(name)]; !ok {
-			klog.V(5).Infof("Setting default resource limit %s for build %s/%s to %v", name, build.Namespace, build.Name, value)
-			build.Spec.Resources.Limits[corev1.ResourceName(name)] = value
+	if b.DefaultGitCloneDepth != nil && *b.DefaultGitCloneDepth >= 0 {
+		depth := strconv.Itoa(int(*b.DefaultGitCloneDepth))
+		klog.V(5).Infof("Setting default git clone depth of build %s/%s to %s", build.Namespace, build.Name, depth)
+		addDefaultEnvVar(build, corev1.EnvVar{Name: buildutil.GitCloneDepthEnvVar, Value: depth})
+	}
+	if b.DisableSubmodules {
+		klog.V(5).Infof("Disabling git submodules of build %s/%s by default", build.Namespace, build.Name)
+		addDefaultEnvVar(build, corev1.EnvVar{Name: buildutil.GitDisableSubmodulesEnvVar, Value: "true"})
+	}
+}
+
+// applyImageLabelTemplates adds the expanded image label templates to the output image labels of
+// the build. Labels the build sets itself take precedence.
+func (b BuildDefaults) applyImageLabelTemplates(build *buildv1.Build) {
+	if len(b.ImageLabelTemplates) == 0 {
+		return
+	}
+	variables := imageLabelVariables(build)
+	for _, template := range b.ImageLabelTemplates {
+		if reasons := InvalidImageLabelName(template.Name); len(reasons) > 0 {
+			klog.V(2).Infof("Ignoring the default image label %q of build %s/%s with an invalid name: %s", template.Name, build.Namespace, build.Name, strings.Join(reasons, ", "))
+			continue
+		}
+		value, ok := expandImageLabelTemplate(template.Value, variables)
+		if !ok {
+			klog.V(4).Infof("Skipping the default image label %s of build %s/%s, which refers to a variable without a value: %s", template.Name, build.Namespace, build.Name, template.Value)
+			continue
 		}
+		klog.V(5).Infof("Adding default image label %s=%s to build %s/%s", template.Name, value, build.Namespace, build.Name)
+		addDefaultLabel(buildv1.ImageLabel{Name: template.Name, Value: value}, &build.Spec.Output.ImageLabels)
 	}
-	if build.Spec.Resources.Requests == nil {
-		build.Spec.Resources.Requests = corev1.ResourceList{}
+}
+
+// applyLogLevelDefault sets the default log level of builds without BUILD_LOGLEVEL.
+func (b BuildDefaults) applyLogLevelDefault(build *buildv1.Build) {
+	if b.LogLevel == nil {
+		return
 	}
-	for name, value := range defaultResources.Requests {
-		if _, ok := build.Spec.Resources.Requests[corev1.ResourceName(name)]; !ok {
-			klog.V(5).Infof("Setting default resource request %s for build %s/%s to %v", name, build.Namespace, build.Name, value)
-			build.Spec.Resources.Requests[corev1.ResourceName(name)] = value
+	level := strconv.Itoa(int(*b.LogLevel))
+	if !buildutil.IsValidBuildLogLevel(level) {
+		klog.V(2).Infof("Ignoring the invalid default log level %s of build %s/%s", level, build.Namespace, build.Name)
+		return
+	}
+	klog.V(5).Infof("Setting default log level of build %s/%s to %s", build.Namespace, build.Name, level)
+	addDefaultEnvVar(build, corev1.EnvVar{Name: buildutil.BuildLogLevelEnvVar, Value: level})
+}
+
+// applyPodSchedulingDefaults merges the default pod affinity, anti-affinity and topology spread
+// constraints into the build pod. Affinity rules the pod already has are kept.
+func (b BuildDefaults) applyPodSchedulingDefaults(pod *corev1.Pod) {
+	if b.PodAffinity != nil || b.PodAntiAffinity != nil {
+		if pod.Spec.Affinity == nil {
+			pod.Spec.Affinity = &corev1.Affinity{}
 		}
 	}
+	if b.PodAffinity != nil && pod.Spec.Affinity.PodAffinity == nil {
+		klog.V(5).Infof("Setting default pod affinity on pod %s/%s", pod.Namespace, pod.Name)
+		affinity := b.PodAffinity.DeepCopy()
+		selectBuildPods(affinity.RequiredDuringSchedulingIgnoredDuringExecution, affinity.PreferredDuringSchedulingIgnoredDuringExecution)
+		pod.Spec.Affinity.PodAffinity = affinity
+	}
+	if b.PodAntiAffinity != nil && pod.Spec.Affinity.PodAntiAffinity == nil {
+		klog.V(5).Infof("Setting default pod anti-affinity on pod %s/%s", pod.Namespace, pod.Name)
+		antiAffinity := b.PodAntiAffinity.DeepCopy()
+		selectBuildPods(antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)
+		pod.Spec.Affinity.PodAntiAffinity = antiAffinity
+	}
+	for _, constraint := range b.TopologySpreadConstraints {
+		addDefaultTopologySpreadConstraint(*constraint.DeepCopy(), &pod.Spec.TopologySpreadConstraints)
+	}
+}
+
+// buildPodSelector selects all build pods by the build name label of the build controller.
+func buildPodSelector() *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: buildv1.BuildLabel, Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+}
+
+// selectBuildPods sets the build pod selector on the affinity terms that have no label selector.
+func selectBuildPods(required []corev1.PodAffinityTerm, preferred []corev1.WeightedPodAffinityTerm) {
+	for i := range required {
+		if required[i].LabelSelector == nil {
+			required[i].LabelSelector = buildPodSelector()
+		}
+	}
+	for i := range preferred {
+		if preferred[i].PodAffinityTerm.LabelSelector == nil {
+			preferred[i].PodAffinityTerm.LabelSelector = buildPodSelector()
+		}
+	}
+}
+
+// addDefaultTopologySpreadConstraint adds the constraint unless the pod already spreads over the
+// same topology key with the same behavior when the constraint is not satisfied.
+func addDefaultTopologySpreadConstraint(defaultConstraint corev1.TopologySpreadConstraint, constraints *[]corev1.TopologySpreadConstraint) {
+	for _, constraint := range *constraints {
+		if constraint.TopologyKey == defaultConstraint.TopologyKey && constraint.WhenUnsatisfiable == defaultConstraint.WhenUnsatisfiable {
+			return
+		}
+	}
+	if defaultConstraint.LabelSelector == nil {
+		defaultConstraint.LabelSelector = buildPodSelector()
+	}
+	klog.V(5).Infof("Adding default topology spread constraint %s:%s", defaultConstraint.TopologyKey, defaultConstraint.WhenUnsatisfiable)
+	*constraints = append(*constraints, defaultConstraint)
 }
 
 func addDefaultEnvVar(build *buildv1.Build, v corev1.EnvVar) {
@@ -284,6 +558,18 @@ func addDefaultNodeSelector(k, v string, selectors map[string]string) {
 	}
 }
 
+// addDefaultToleration adds the toleration unless the pod already has a toleration with the same
+// key and effect. A toleration without an effect tolerates all effects of its key.
+func addDefaultToleration(defaultToleration corev1.Toleration, tolerations *[]corev1.Toleration) {
+	for _, toleration := range *tolerations {
+		if toleration.Key == defaultToleration.Key && (len(toleration.Effect) == 0 || toleration.Effect == defaultToleration.Effect) {
+			return
+		}
+	}
+	klog.V(5).Infof("Adding default toleration %s:%s", defaultToleration.Key, defaultToleration.Effect)
+	*tolerations = append(*tolerations, defaultToleration)
+}
+
 func addDefaultAnnotation(k, v string, annotations map[string]string) {
 	if _, ok := annotations[k]; !ok {
 		annotations[k] = v
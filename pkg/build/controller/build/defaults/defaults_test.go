@@ -6,6 +6,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kapihelper "k8s.io/kubernetes/pkg/apis/core/helper"
 
 	buildv1 "github.com/openshift/api/build/v1"
@@ -23,7 +24,7 @@ func TestGitProxyDefaults(t *testing.T) {
 		GitNoProxy:    "no",
 	}
 
-	admitter := BuildDefaults{defaultsConfig, nil}
+	admitter := BuildDefaults{Config: defaultsConfig}
 	pod := testutil.Pod().WithBuild(t, testutil.Build().WithDockerStrategy().AsBuild())
 	err := admitter.ApplyDefaults((*corev1.Pod)(pod))
 	if err != nil {
@@ -63,7 +64,7 @@ func TestEnvDefaults(t *testing.T) {
 		},
 	}
 
-	admitter := BuildDefaults{defaultsConfig, nil}
+	admitter := BuildDefaults{Config: defaultsConfig}
 	pod := testutil.Pod().WithBuild(t, testutil.Build().WithSourceStrategy().AsBuild())
 	err := admitter.ApplyDefaults((*corev1.Pod)(pod))
 	if err != nil {
@@ -154,7 +155,7 @@ func TestGlobalProxyDefaults(t *testing.T) {
 		NoProxy:    "no",
 	}
 
-	admitter := BuildDefaults{nil, defaultsProxy}
+	admitter := BuildDefaults{DefaultProxy: defaultsProxy}
 
 	// source builds should have the defaulted env vars applied to the build pod
 	pod := testutil.Pod().WithBuild(t, testutil.Build().WithSourceStrategy().AsBuild())
@@ -336,7 +337,7 @@ func TestIncrementalDefaults(t *testing.T) {
 		},
 	}
 
-	admitter := BuildDefaults{defaultsConfig, nil}
+	admitter := BuildDefaults{Config: defaultsConfig}
 
 	pod := testutil.Pod().WithBuild(t, testutil.Build().WithSourceStrategy().AsBuild())
 	err := admitter.ApplyDefaults((*corev1.Pod)(pod))
@@ -485,7 +486,7 @@ func TestLabelDefaults(t *testing.T) {
 			ImageLabels: test.defaultLabels,
 		}
 
-		admitter := BuildDefaults{defaultsConfig, nil}
+		admitter := BuildDefaults{Config: defaultsConfig}
 		pod := testutil.Pod().WithBuild(t, testutil.Build().WithImageLabels(test.buildLabels).AsBuild())
 		err := admitter.ApplyDefaults((*corev1.Pod)(pod))
 		if err != nil {
@@ -612,6 +613,483 @@ func TestBuildDefaultsAnnotations(t *testing.T) {
 		}
 	}
 }
+func TestPriorityClassNameDefaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		requested string
+		defaults  string
+		expected  string
+	}{
+		{
+			name:     "default applied",
+			defaults: "default-class",
+			expected: "default-class",
+		},
+		{
+			name:      "requested class wins",
+			requested: "requested-class",
+			defaults:  "default-class",
+			expected:  "requested-class",
+		},
+		{
+			name:      "no default",
+			requested: "requested-class",
+			expected:  "requested-class",
+		},
+	}
+
+	for _, test := range tests {
+		defaults := BuildDefaults{PriorityClassName: test.defaults}
+		pod := testutil.Pod().WithBuild(t, testutil.Build().AsBuild())
+		pod.Spec.PriorityClassName = test.requested
+		if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if pod.Spec.PriorityClassName != test.expected {
+			t.Errorf("%s: expected priority class %q, got %q", test.name, test.expected, pod.Spec.PriorityClassName)
+		}
+	}
+}
+
+func TestCompletionDeadlineDefaults(t *testing.T) {
+	deadline := func(seconds int64) *int64 { return &seconds }
+	tests := []struct {
+		name     string
+		build    *int64
+		defaults *int64
+		expected *int64
+	}{
+		{
+			name: "no default",
+		},
+		{
+			name:     "default applied",
+			defaults: deadline(3600),
+			expected: deadline(3600),
+		},
+		{
+			name:     "explicit value wins",
+			build:    deadline(600),
+			defaults: deadline(3600),
+			expected: deadline(600),
+		},
+	}
+
+	for _, test := range tests {
+		build := testutil.Build().AsBuild()
+		build.Spec.CompletionDeadlineSeconds = test.build
+		pod := testutil.Pod().WithBuild(t, build)
+		pod.Spec.ActiveDeadlineSeconds = test.build
+		defaults := BuildDefaults{CompletionDeadlineSeconds: test.defaults}
+		if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(pod.Spec.ActiveDeadlineSeconds, test.expected) {
+			t.Errorf("%s: expected pod activeDeadlineSeconds %v, got %v", test.name, test.expected, pod.Spec.ActiveDeadlineSeconds)
+		}
+		podBuild := pod.GetBuild(t)
+		if !reflect.DeepEqual(podBuild.Spec.CompletionDeadlineSeconds, test.expected) {
+			t.Errorf("%s: expected build completionDeadlineSeconds %v, got %v", test.name, test.expected, podBuild.Spec.CompletionDeadlineSeconds)
+		}
+	}
+}
+
+func TestTolerationDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    []corev1.Toleration
+		defaults []corev1.Toleration
+		expected []corev1.Toleration
+	}{
+		{
+			name: "everything nil",
+		},
+		{
+			name: "no build tolerations, only defaults",
+			defaults: []corev1.Toleration{
+				{Key: "build-node", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+			},
+			expected: []corev1.Toleration{
+				{Key: "build-node", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+			},
+		},
+		{
+			name: "defaults are added to build tolerations",
+			build: []corev1.Toleration{
+				{Key: "toleration1", Value: "value1", Effect: corev1.TaintEffectNoSchedule},
+			},
+			defaults: []corev1.Toleration{
+				{Key: "build-node", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+				{Key: "toleration1", Value: "value2", Effect: corev1.TaintEffectNoExecute},
+			},
+			expected: []corev1.Toleration{
+				{Key: "toleration1", Value: "value1", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "build-node", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule},
+				{Key: "toleration1", Value: "value2", Effect: corev1.TaintEffectNoExecute},
+			},
+		},
+		{
+			name: "build tolerations win",
+			build: []corev1.Toleration{
+				{Key: "toleration1", Value: "value1", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "toleration2", Value: "value2"},
+			},
+			defaults: []corev1.Toleration{
+				{Key: "toleration1", Value: "value3", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "toleration2", Value: "value4", Effect: corev1.TaintEffectNoExecute},
+			},
+			expected: []corev1.Toleration{
+				{Key: "toleration1", Value: "value1", Effect: corev1.TaintEffectNoSchedule},
+				{Key: "toleration2", Value: "value2"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		defaults := BuildDefaults{Tolerations: test.defaults}
+		pod := testutil.Pod().WithTolerations(test.build).WithBuild(t, testutil.Build().AsBuild())
+		if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(pod.Spec.Tolerations, test.expected) {
+			t.Errorf("%s: expected tolerations %v, got %v", test.name, test.expected, pod.Spec.Tolerations)
+		}
+	}
+}
+
+func TestGitCloneDefaults(t *testing.T) {
+	depth := func(d int32) *int32 { return &d }
+	tests := []struct {
+		name              string
+		noGitSource       bool
+		env               []corev1.EnvVar
+		depth             *int32
+		disableSubmodules bool
+		expectedDepth     string
+		expectedDisable   string
+	}{
+		{
+			name: "no defaults",
+		},
+		{
+			name:              "defaults applied",
+			depth:             depth(1),
+			disableSubmodules: true,
+			expectedDepth:     "1",
+			expectedDisable:   "true",
+		},
+		{
+			name:          "default full clone",
+			depth:         depth(0),
+			expectedDepth: "0",
+		},
+		{
+			name:          "explicit depth is not overridden",
+			env:           []corev1.EnvVar{{Name: "GIT_CLONE_DEPTH", Value: "10"}},
+			depth:         depth(1),
+			expectedDepth: "10",
+		},
+		{
+			name:          "explicit full clone is not overridden",
+			env:           []corev1.EnvVar{{Name: "GIT_CLONE_DEPTH", Value: "0"}},
+			depth:         depth(1),
+			expectedDepth: "0",
+		},
+		{
+			name:              "explicit submodules are not disabled",
+			env:               []corev1.EnvVar{{Name: "GIT_DISABLE_SUBMODULES", Value: "false"}},
+			depth:             depth(1),
+			disableSubmodules: true,
+			expectedDepth:     "1",
+			expectedDisable:   "false",
+		},
+		{
+			name:              "negative depth is ignored",
+			depth:             depth(-1),
+			disableSubmodules: true,
+			expectedDisable:   "true",
+		},
+		{
+			name:              "no git source",
+			noGitSource:       true,
+			depth:             depth(1),
+			disableSubmodules: true,
+		},
+	}
+
+	for _, test := range tests {
+		build := testutil.Build().WithSourceStrategy().AsBuild()
+		build.Spec.Strategy.SourceStrategy.Env = test.env
+		if test.noGitSource {
+			build.Spec.Source.Git = nil
+		}
+		pod := testutil.Pod().WithBuild(t, build)
+		defaults := BuildDefaults{DefaultGitCloneDepth: test.depth, DisableSubmodules: test.disableSubmodules}
+		if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		env := map[string]string{}
+		for _, ev := range buildutil.GetBuildEnv(pod.GetBuild(t)) {
+			env[ev.Name] = ev.Value
+		}
+		if env["GIT_CLONE_DEPTH"] != test.expectedDepth {
+			t.Errorf("%s: expected GIT_CLONE_DEPTH %q, got %q", test.name, test.expectedDepth, env["GIT_CLONE_DEPTH"])
+		}
+		if env["GIT_DISABLE_SUBMODULES"] != test.expectedDisable {
+			t.Errorf("%s: expected GIT_DISABLE_SUBMODULES %q, got %q", test.name, test.expectedDisable, env["GIT_DISABLE_SUBMODULES"])
+		}
+	}
+}
+
+func TestLogLevelDefaults(t *testing.T) {
+	level := func(l int32) *int32 { return &l }
+	tests := []struct {
+		name          string
+		env           []corev1.EnvVar
+		level         *int32
+		expectedLevel string
+		expectedArg   string
+	}{
+		{
+			name:        "no default",
+			expectedArg: "--v=0",
+		},
+		{
+			name:          "default applied",
+			level:         level(4),
+			expectedLevel: "4",
+			expectedArg:   "--v=4",
+		},
+		{
+			name:          "explicit log level is not overridden",
+			env:           []corev1.EnvVar{{Name: "BUILD_LOGLEVEL", Value: "2"}},
+			level:         level(4),
+			expectedLevel: "2",
+			expectedArg:   "--v=2",
+		},
+		{
+			name:        "invalid default is ignored",
+			level:       level(6),
+			expectedArg: "--v=0",
+		},
+	}
+
+	for _, test := range tests {
+		build := testutil.Build().WithSourceStrategy().AsBuild()
+		build.Spec.Strategy.SourceStrategy.Env = test.env
+		pod := testutil.Pod().WithBuild(t, build)
+		defaults := BuildDefaults{LogLevel: test.level}
+		if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		env := map[string]string{}
+		for _, ev := range buildutil.GetBuildEnv(pod.GetBuild(t)) {
+			env[ev.Name] = ev.Value
+		}
+		if env["BUILD_LOGLEVEL"] != test.expectedLevel {
+			t.Errorf("%s: expected BUILD_LOGLEVEL %q, got %q", test.name, test.expectedLevel, env["BUILD_LOGLEVEL"])
+		}
+		if args := pod.Spec.Containers[0].Args; len(args) == 0 || args[len(args)-1] != test.expectedArg {
+			t.Errorf("%s: expected build pod argument %s, got %v", test.name, test.expectedArg, args)
+		}
+	}
+}
+
+func TestImageLabelTemplateDefaults(t *testing.T) {
+	templates := []buildv1.ImageLabel{
+		{Name: "io.example.build", Value: "${BUILD_NAMESPACE}/${BUILD_NAME}"},
+		{Name: "io.example.buildconfig", Value: "${BUILDCONFIG_NAME}"},
+		{Name: "io.example.commit", Value: "${SOURCE_COMMIT}@${SOURCE_REF}"},
+		{Name: "invalid label", Value: "${BUILD_NAME}"},
+	}
+	tests := []struct {
+		name        string
+		buildLabels []buildv1.ImageLabel
+		config      []buildv1.ImageLabel
+		commit      string
+		expected    []buildv1.ImageLabel
+	}{
+		{
+			name: "templates are expanded",
+			expected: []buildv1.ImageLabel{
+				{Name: "io.example.build", Value: "namespace/TestBuild"},
+				{Name: "io.example.buildconfig", Value: "app"},
+			},
+		},
+		{
+			name:   "commit of the build revision",
+			commit: "1234abcd",
+			expected: []buildv1.ImageLabel{
+				{Name: "io.example.build", Value: "namespace/TestBuild"},
+				{Name: "io.example.buildconfig", Value: "app"},
+				{Name: "io.example.commit", Value: "1234abcd@main"},
+			},
+		},
+		{
+			name:        "labels of the build take precedence",
+			buildLabels: []buildv1.ImageLabel{{Name: "io.example.buildconfig", Value: "mine"}},
+			expected: []buildv1.ImageLabel{
+				{Name: "io.example.buildconfig", Value: "mine"},
+				{Name: "io.example.build", Value: "namespace/TestBuild"},
+			},
+		},
+		{
+			name:   "labels of the defaults configuration take precedence",
+			config: []buildv1.ImageLabel{{Name: "io.example.build", Value: "static"}},
+			expected: []buildv1.ImageLabel{
+				{Name: "io.example.build", Value: "static"},
+				{Name: "io.example.buildconfig", Value: "app"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			build := testutil.Build().WithImageLabels(test.buildLabels).AsBuild()
+			build.Namespace = "namespace"
+			build.Annotations = map[string]string{buildv1.BuildConfigAnnotation: "app"}
+			build.Spec.Source.Git.Ref = "main"
+			if len(test.commit) > 0 {
+				build.Spec.Revision = &buildv1.SourceRevision{Git: &buildv1.GitSourceRevision{Commit: test.commit}}
+			}
+			pod := testutil.Pod().WithBuild(t, build)
+			defaults := BuildDefaults{ImageLabelTemplates: templates}
+			if test.config != nil {
+				defaults.Config = &openshiftcontrolplanev1.BuildDefaultsConfig{ImageLabels: test.config}
+			}
+			if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if labels := pod.GetBuild(t).Spec.Output.ImageLabels; !reflect.DeepEqual(labels, test.expected) {
+				t.Errorf("expected image labels %v, got %v", test.expected, labels)
+			}
+		})
+	}
+}
+
+func TestInvalidImageLabelName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"io.openshift.build.name": true,
+		"maintainer":              true,
+		"":                        false,
+		"invalid label":           false,
+		"io/openshift":            false,
+	} {
+		if reasons := InvalidImageLabelName(name); (len(reasons) == 0) != valid {
+			t.Errorf("expected image label name %q to be valid: %v, got %v", name, valid, reasons)
+		}
+	}
+}
+
+func TestPodSchedulingDefaults(t *testing.T) {
+	buildPods := &metav1.LabelSelector{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: buildv1.BuildLabel, Operator: metav1.LabelSelectorOpExists},
+		},
+	}
+	appPods := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "test"}}
+	spreadByHost := corev1.TopologySpreadConstraint{MaxSkew: 1, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway}
+	spreadByZone := corev1.TopologySpreadConstraint{MaxSkew: 2, TopologyKey: corev1.LabelTopologyZone, WhenUnsatisfiable: corev1.DoNotSchedule, LabelSelector: appPods}
+	antiAffinity := &corev1.PodAntiAffinity{
+		PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+			{Weight: 100, PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: corev1.LabelHostname}},
+		},
+	}
+	affinity := &corev1.PodAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+			{TopologyKey: corev1.LabelTopologyZone, LabelSelector: appPods},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		custom              bool
+		affinity            *corev1.Affinity
+		constraints         []corev1.TopologySpreadConstraint
+		defaults            BuildDefaults
+		expectedAffinity    *corev1.Affinity
+		expectedConstraints []corev1.TopologySpreadConstraint
+	}{
+		{
+			name: "no defaults",
+		},
+		{
+			name: "defaults select build pods",
+			defaults: BuildDefaults{
+				PodAffinity:               affinity,
+				PodAntiAffinity:           antiAffinity,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{spreadByHost, spreadByZone},
+			},
+			expectedAffinity: &corev1.Affinity{
+				PodAffinity: affinity,
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+						{Weight: 100, PodAffinityTerm: corev1.PodAffinityTerm{TopologyKey: corev1.LabelHostname, LabelSelector: buildPods}},
+					},
+				},
+			},
+			expectedConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway, LabelSelector: buildPods},
+				spreadByZone,
+			},
+		},
+		{
+			name:   "custom build affinity is kept",
+			custom: true,
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{},
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+						{TopologyKey: corev1.LabelTopologyZone, LabelSelector: appPods},
+					},
+				},
+			},
+			constraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 3, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway, LabelSelector: appPods},
+			},
+			defaults: BuildDefaults{
+				PodAffinity:               affinity,
+				PodAntiAffinity:           antiAffinity,
+				TopologySpreadConstraints: []corev1.TopologySpreadConstraint{spreadByHost, spreadByZone},
+			},
+			expectedAffinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{},
+				PodAffinity:  affinity,
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+						{TopologyKey: corev1.LabelTopologyZone, LabelSelector: appPods},
+					},
+				},
+			},
+			expectedConstraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 3, TopologyKey: corev1.LabelHostname, WhenUnsatisfiable: corev1.ScheduleAnyway, LabelSelector: appPods},
+				spreadByZone,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		build := testutil.Build()
+		if test.custom {
+			build = build.WithCustomStrategy()
+		}
+		pod := testutil.Pod().WithBuild(t, build.AsBuild())
+		pod.Spec.Affinity = test.affinity
+		pod.Spec.TopologySpreadConstraints = test.constraints
+		if err := test.defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+		if !reflect.DeepEqual(pod.Spec.Affinity, test.expectedAffinity) {
+			t.Errorf("%s: expected affinity %#v, got %#v", test.name, test.expectedAffinity, pod.Spec.Affinity)
+		}
+		if !reflect.DeepEqual(pod.Spec.TopologySpreadConstraints, test.expectedConstraints) {
+			t.Errorf("%s: expected topology spread constraints %#v, got %#v", test.name, test.expectedConstraints, pod.Spec.TopologySpreadConstraints)
+		}
+	}
+	if antiAffinity.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.LabelSelector != nil || spreadByHost.LabelSelector != nil {
+		t.Errorf("expected the defaults not to be modified")
+	}
+}
+
 func TestResourceDefaults(t *testing.T) {
 	tests := map[string]struct {
 		DefaultResource  corev1.ResourceRequirements
@@ -826,6 +1304,107 @@ func TestResourceDefaults(t *testing.T) {
 	}
 }
 
+func TestStrategyResourceDefaults(t *testing.T) {
+	memory := func(quantity string) *corev1.ResourceRequirements {
+		return &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(quantity)},
+		}
+	}
+	strategyResources := StrategyResources{
+		DockerStrategy: memory("4Gi"),
+		SourceStrategy: memory("1Gi"),
+	}
+	globalResources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("2Gi"),
+		},
+	}
+
+	tests := []struct {
+		name              string
+		build             *buildv1.Build
+		config            *openshiftcontrolplanev1.BuildDefaultsConfig
+		strategyResources StrategyResources
+		buildResources    corev1.ResourceRequirements
+		expectedRequests  corev1.ResourceList
+	}{
+		{
+			name:              "docker strategy",
+			build:             testutil.Build().WithDockerStrategy().AsBuild(),
+			config:            &openshiftcontrolplanev1.BuildDefaultsConfig{Resources: globalResources},
+			strategyResources: strategyResources,
+			expectedRequests:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+		},
+		{
+			name:              "source strategy",
+			build:             testutil.Build().WithSourceStrategy().AsBuild(),
+			config:            &openshiftcontrolplanev1.BuildDefaultsConfig{Resources: globalResources},
+			strategyResources: strategyResources,
+			expectedRequests:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+		},
+		{
+			name:              "custom strategy falls back to the global resources",
+			build:             testutil.Build().WithCustomStrategy().AsBuild(),
+			config:            &openshiftcontrolplanev1.BuildDefaultsConfig{Resources: globalResources},
+			strategyResources: strategyResources,
+			expectedRequests:  globalResources.Requests,
+		},
+		{
+			name:              "custom strategy",
+			build:             testutil.Build().WithCustomStrategy().AsBuild(),
+			config:            &openshiftcontrolplanev1.BuildDefaultsConfig{Resources: globalResources},
+			strategyResources: StrategyResources{CustomStrategy: memory("3Gi")},
+			expectedRequests:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("3Gi")},
+		},
+		{
+			name:              "strategy resources without a build defaults config",
+			build:             testutil.Build().WithDockerStrategy().AsBuild(),
+			strategyResources: strategyResources,
+			expectedRequests:  corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("4Gi")},
+		},
+		{
+			name:              "build resources are kept",
+			build:             testutil.Build().WithDockerStrategy().AsBuild(),
+			config:            &openshiftcontrolplanev1.BuildDefaultsConfig{Resources: globalResources},
+			strategyResources: StrategyResources{DockerStrategy: &globalResources},
+			buildResources:    *memory("8Gi"),
+			expectedRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			defaults := BuildDefaults{Config: tc.config, StrategyResources: tc.strategyResources}
+			build := tc.build
+			build.Spec.Resources = tc.buildResources
+			pod := testutil.Pod().WithBuild(t, build)
+			// the build pod containers get the resources of the build when the pod is created
+			for i := range pod.Spec.Containers {
+				pod.Spec.Containers[i].Resources = *tc.buildResources.DeepCopy()
+			}
+
+			if err := defaults.ApplyDefaults((*corev1.Pod)(pod)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			build, err := common.GetBuildFromPod((*corev1.Pod)(pod))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !kapihelper.Semantic.DeepEqual(tc.expectedRequests, build.Spec.Resources.Requests) {
+				t.Errorf("expected build resource requests %v, got %v", tc.expectedRequests, build.Spec.Resources.Requests)
+			}
+			for _, c := range pod.Spec.Containers {
+				if !kapihelper.Semantic.DeepEqual(tc.expectedRequests, c.Resources.Requests) {
+					t.Errorf("expected container %s resource requests %v, got %v", c.Name, tc.expectedRequests, c.Resources.Requests)
+				}
+			}
+		})
+	}
+}
+
 func TestSetBuildLogLevel(t *testing.T) {
 	build := testutil.Build().WithSourceStrategy()
 	pod := testutil.Pod().WithEnvVar("BUILD", "foo")
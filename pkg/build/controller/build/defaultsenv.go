@@ -0,0 +1,88 @@
+package build
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// buildDefaultsNamespace is the namespace of the secrets and config maps the environment variables
+// of the build defaults can reference.
+const buildDefaultsNamespace = "openshift-config"
+
+// buildDefaultsError is an error in the build defaults that keeps builds from getting a build pod
+// until the build defaults or the objects they reference are fixed.
+type buildDefaultsError struct {
+	reason string
+}
+
+// Error implements the error interface.
+func (e *buildDefaultsError) Error() string {
+	return e.reason
+}
+
+// resolveBuildDefaultsEnv returns the environment variables of the build defaults with the values
+// of the secret and config map keys of the openshift-config namespace they reference. The values
+// are resolved when the build pod is created, like the references of the build to objects of its
+// own namespace, so that builds do not read objects of the openshift-config namespace. Missing
+// optional references are dropped.
+func (bc *BuildController) resolveBuildDefaultsEnv(env []corev1.EnvVar) ([]corev1.EnvVar, error) {
+	resolved := make([]corev1.EnvVar, 0, len(env))
+	for _, e := range env {
+		if e.ValueFrom == nil || (e.ValueFrom.SecretKeyRef == nil && e.ValueFrom.ConfigMapKeyRef == nil) {
+			resolved = append(resolved, e)
+			continue
+		}
+		value, found, err := bc.buildDefaultsEnvValue(e.ValueFrom)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			optional := (e.ValueFrom.SecretKeyRef != nil && e.ValueFrom.SecretKeyRef.Optional != nil && *e.ValueFrom.SecretKeyRef.Optional) ||
+				(e.ValueFrom.ConfigMapKeyRef != nil && e.ValueFrom.ConfigMapKeyRef.Optional != nil && *e.ValueFrom.ConfigMapKeyRef.Optional)
+			if optional {
+				continue
+			}
+			return nil, &buildDefaultsError{reason: fmt.Sprintf("build defaults environment variable %s references %s, which does not exist", e.Name, buildDefaultsEnvSource(e.ValueFrom))}
+		}
+		resolved = append(resolved, corev1.EnvVar{Name: e.Name, Value: value})
+	}
+	return resolved, nil
+}
+
+// buildDefaultsEnvValue returns the value of the secret or config map key an environment variable
+// of the build defaults references, and false if the object or the key does not exist.
+func (bc *BuildController) buildDefaultsEnvValue(source *corev1.EnvVarSource) (string, bool, error) {
+	switch {
+	case source.SecretKeyRef != nil:
+		secret, err := bc.secretStore.Secrets(buildDefaultsNamespace).Get(source.SecretKeyRef.Name)
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		value, ok := secret.Data[source.SecretKeyRef.Key]
+		return string(value), ok, nil
+	case source.ConfigMapKeyRef != nil:
+		configMap, err := bc.openShiftConfigConfigMapStore.ConfigMaps(buildDefaultsNamespace).Get(source.ConfigMapKeyRef.Name)
+		if errors.IsNotFound(err) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		value, ok := configMap.Data[source.ConfigMapKeyRef.Key]
+		return value, ok, nil
+	}
+	return "", false, nil
+}
+
+// buildDefaultsEnvSource describes the secret or config map key an environment variable references.
+func buildDefaultsEnvSource(source *corev1.EnvVarSource) string {
+	if source.SecretKeyRef != nil {
+		return fmt.Sprintf("key %s of secret %s/%s", source.SecretKeyRef.Key, buildDefaultsNamespace, source.SecretKeyRef.Name)
+	}
+	return fmt.Sprintf("key %s of config map %s/%s", source.ConfigMapKeyRef.Key, buildDefaultsNamespace, source.ConfigMapKeyRef.Name)
+}
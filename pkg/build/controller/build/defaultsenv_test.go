@@ -0,0 +1,130 @@
+package build
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	openshiftcontrolplanev1 "github.com/openshift/api/openshiftcontrolplane/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
+)
+
+func TestCreateBuildPodWithBuildDefaultsEnvReferences(t *testing.T) {
+	proxySecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "proxy", Namespace: "openshift-config"},
+		Data:       map[string][]byte{"credentials": []byte("user:password")},
+	}
+	settings := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "openshift-config"},
+		Data:       map[string]string{"mirror": "https://mirror.example.com"},
+	}
+	secretRef := func(name, key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}}
+	}
+	configMapRef := func(name, key string) *corev1.EnvVarSource {
+		return &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}}
+	}
+	optional := true
+
+	tests := []struct {
+		name           string
+		defaultsEnv    []corev1.EnvVar
+		buildEnv       []corev1.EnvVar
+		expectedEnv    map[string]string
+		unexpectedEnv  []string
+		expectedReason buildv1.StatusReason
+	}{
+		{
+			name: "secret and config map references",
+			defaultsEnv: []corev1.EnvVar{
+				{Name: "PROXY_CREDENTIALS", ValueFrom: secretRef("proxy", "credentials")},
+				{Name: "MIRROR", ValueFrom: configMapRef("settings", "mirror")},
+				{Name: "LITERAL", Value: "value"},
+			},
+			expectedEnv: map[string]string{
+				"PROXY_CREDENTIALS": "user:password",
+				"MIRROR":            "https://mirror.example.com",
+				"LITERAL":           "value",
+			},
+		},
+		{
+			name:        "build environment takes precedence",
+			defaultsEnv: []corev1.EnvVar{{Name: "MIRROR", ValueFrom: configMapRef("settings", "mirror")}},
+			buildEnv:    []corev1.EnvVar{{Name: "MIRROR", Value: "https://build.example.com"}},
+			expectedEnv: map[string]string{"MIRROR": "https://build.example.com"},
+		},
+		{
+			name:           "missing key",
+			defaultsEnv:    []corev1.EnvVar{{Name: "PROXY_CREDENTIALS", ValueFrom: secretRef("proxy", "token")}},
+			expectedReason: buildutil.StatusReasonInvalidBuildDefaults,
+		},
+		{
+			name:           "missing config map",
+			defaultsEnv:    []corev1.EnvVar{{Name: "MIRROR", ValueFrom: configMapRef("missing", "mirror")}},
+			expectedReason: buildutil.StatusReasonInvalidBuildDefaults,
+		},
+		{
+			name: "missing optional key",
+			defaultsEnv: []corev1.EnvVar{{Name: "PROXY_CREDENTIALS", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "proxy"},
+				Key:                  "token",
+				Optional:             &optional,
+			}}}},
+			unexpectedEnv: []string{"PROXY_CREDENTIALS"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap, proxySecret, settings)
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+			bc.buildDefaults.Config = &openshiftcontrolplanev1.BuildDefaultsConfig{Env: tc.defaultsEnv}
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.Strategy.DockerStrategy.Env = tc.buildEnv
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.expectedReason) > 0 {
+				if update.phase == nil || *update.phase != buildv1.BuildPhaseFailed || *update.reason != tc.expectedReason {
+					t.Fatalf("expected the build to fail with reason %s, got %v", tc.expectedReason, update)
+				}
+				return
+			}
+			if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+				t.Fatalf("expected the build pod to be created, got %v", update)
+			}
+
+			pod, err := bc.podClient.Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			podBuild, err := common.GetBuildFromPod(pod)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			env := map[string]string{}
+			for _, e := range podBuild.Spec.Strategy.DockerStrategy.Env {
+				if e.ValueFrom != nil {
+					t.Errorf("expected build environment variable %s to be resolved, got %#v", e.Name, e.ValueFrom)
+				}
+				env[e.Name] = e.Value
+			}
+			for name, value := range tc.expectedEnv {
+				if env[name] != value {
+					t.Errorf("expected build environment variable %s=%q, got %q", name, value, env[name])
+				}
+			}
+			for _, name := range tc.unexpectedEnv {
+				if _, ok := env[name]; ok {
+					t.Errorf("expected build environment variable %s to be dropped, got %v", name, env)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package build
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	digest "github.com/opencontainers/go-digest"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+// buildContainerName returns the name of the container that pushes the output image of docker and
+// source builds, or an empty string for the other strategies.
+func buildContainerName(build *buildv1.Build) string {
+	switch {
+	case build.Spec.Strategy.DockerStrategy != nil:
+		return strategy.DockerBuild
+	case build.Spec.Strategy.SourceStrategy != nil:
+		return strategy.StiBuild
+	}
+	return ""
+}
+
+// pushedImageReference returns the fully qualified reference of the output image of the build
+// by digest.
+func pushedImageReference(build *buildv1.Build, imageDigest string) (string, error) {
+	ref, err := reference.Parse(build.Status.OutputDockerImageReference)
+	if err != nil {
+		return "", err
+	}
+	ref.Tag = ""
+	ref.ID = imageDigest
+	return ref.Exact(), nil
+}
+
+// setBuildImageDigest validates the digest of the image pushed by a completed docker or source
+// build, which the builder reports in the build status through the build details, and records the
+// reference of the image by digest. When the digest cannot be determined the ImageDigestRecorded
+// condition of the build explains why.
+func setBuildImageDigest(build *buildv1.Build, update *buildUpdate) {
+	phase := build.Status.Phase
+	if update.phase != nil {
+		phase = *update.phase
+	}
+	if phase != buildv1.BuildPhaseComplete || build.Spec.Output.To == nil || len(buildContainerName(build)) == 0 {
+		return
+	}
+
+	notRecorded := func(reason, message string) {
+		update.setCondition(buildv1.BuildCondition{
+			Type:               buildutil.BuildConditionImageDigestRecorded,
+			Status:             corev1.ConditionFalse,
+			Reason:             reason,
+			Message:            message,
+			LastUpdateTime:     metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+		})
+	}
+
+	if build.Status.Output.To == nil || len(build.Status.Output.To.ImageDigest) == 0 {
+		notRecorded(buildutil.ImageDigestNotReportedReason, "The builder did not report the digest of the pushed image.")
+		return
+	}
+	d, err := digest.Parse(build.Status.Output.To.ImageDigest)
+	if err != nil {
+		notRecorded(buildutil.InvalidImageDigestReason, fmt.Sprintf("The reported image digest %q is invalid: %v", build.Status.Output.To.ImageDigest, err))
+		return
+	}
+	imageDigest := d.String()
+
+	pushedRef, err := pushedImageReference(build, imageDigest)
+	if err != nil {
+		notRecorded(buildutil.InvalidImageDigestReason, fmt.Sprintf("The output image reference %q is invalid: %v", build.Status.OutputDockerImageReference, err))
+		return
+	}
+	update.setPushedImageRef(pushedRef)
+}
@@ -0,0 +1,130 @@
+package build
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+const testImageDigest = "sha256:4b825dc642cb6eb9a060e54bf8d69288fbee4904a8d5bd8e77a5e1bf8329ea0b"
+
+func completedDigestBuild(build *buildv1.Build) *buildv1.Build {
+	build.Spec.Output.To = &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.io/namespace/app:latest"}
+	build.Status.OutputDockerImageReference = "registry.io/namespace/app:latest"
+	return build
+}
+
+func terminatedBuildPod(build *buildv1.Build, containerName, message string) *corev1.Pod {
+	pod := mockBuildPod(build)
+	pod.Status.Phase = corev1.PodSucceeded
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{
+			Name: containerName,
+			State: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Message: message},
+			},
+		},
+	}
+	return pod
+}
+
+func TestSetBuildImageDigest(t *testing.T) {
+	tests := []struct {
+		name            string
+		build           *buildv1.Build
+		reportedDigest  string
+		expectedDigest  string
+		expectedRef     string
+		expectedReason  string
+		expectNoChanges bool
+	}{
+		{
+			name:           "docker build",
+			build:          dockerStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{})),
+			reportedDigest: testImageDigest,
+			expectedDigest: testImageDigest,
+			expectedRef:    "registry.io/namespace/app@" + testImageDigest,
+		},
+		{
+			name:           "source build",
+			build:          sourceStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{})),
+			reportedDigest: testImageDigest,
+			expectedDigest: testImageDigest,
+			expectedRef:    "registry.io/namespace/app@" + testImageDigest,
+		},
+		{
+			name:           "digest not reported",
+			build:          dockerStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{})),
+			expectedReason: buildutil.ImageDigestNotReportedReason,
+		},
+		{
+			name:           "malformed digest",
+			build:          dockerStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{})),
+			reportedDigest: "sha256:1234",
+			expectedReason: buildutil.InvalidImageDigestReason,
+		},
+		{
+			name:           "tag instead of digest",
+			build:          sourceStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{})),
+			reportedDigest: "latest",
+			expectedReason: buildutil.InvalidImageDigestReason,
+		},
+		{
+			name:            "failed build",
+			build:           dockerStrategy(mockBuild(buildv1.BuildPhaseFailed, buildv1.BuildOutput{})),
+			expectNoChanges: true,
+		},
+		{
+			name:            "custom build",
+			build:           mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{}),
+			reportedDigest:  testImageDigest,
+			expectNoChanges: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := completedDigestBuild(tc.build)
+			if len(tc.reportedDigest) > 0 {
+				build.Status.Output.To = &buildv1.BuildStatusOutputTo{ImageDigest: tc.reportedDigest}
+			}
+			pod := mockBuildPod(build)
+			pod.Status.Phase = corev1.PodSucceeded
+
+			update := &buildUpdate{}
+			setBuildCompletionData(build, pod, update)
+			update.apply(build)
+
+			condition := findBuildCondition(build, buildutil.BuildConditionImageDigestRecorded)
+			if tc.expectNoChanges {
+				if update.pushedImageRef != nil || condition != nil {
+					t.Errorf("expected no image digest changes, got %v", update)
+				}
+				return
+			}
+			if len(tc.expectedReason) > 0 {
+				if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != tc.expectedReason {
+					t.Fatalf("expected the image digest condition to be false with reason %s, got %#v", tc.expectedReason, condition)
+				}
+				if len(condition.Message) == 0 {
+					t.Errorf("expected the image digest condition to explain the reason")
+				}
+				if update.pushedImageRef != nil {
+					t.Errorf("expected no pushed image reference to be recorded, got %v", update)
+				}
+				return
+			}
+			if condition != nil {
+				t.Errorf("expected no image digest condition, got %#v", condition)
+			}
+			if build.Status.Output.To == nil || build.Status.Output.To.ImageDigest != tc.expectedDigest {
+				t.Errorf("expected image digest %s, got %#v", tc.expectedDigest, build.Status.Output.To)
+			}
+			if ref := build.Annotations[buildutil.BuildPushedImageReferenceAnnotation]; ref != tc.expectedRef {
+				t.Errorf("expected pushed image reference %s, got %s", tc.expectedRef, ref)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package build
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	sharedbuildutil "github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/library-go/pkg/image/imageutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// pendingScheduledImport returns true if the tag of the image stream is imported periodically by
+// a scheduled import policy and has no successfully imported image yet.
+func pendingScheduledImport(stream *imagev1.ImageStream, tag string) bool {
+	specTag, ok := imageutil.SpecHasTag(stream, tag)
+	if !ok || !specTag.ImportPolicy.Scheduled {
+		return false
+	}
+	for _, statusTag := range stream.Status.Tags {
+		if statusTag.Tag == tag {
+			return len(statusTag.Items) == 0
+		}
+	}
+	return true
+}
+
+// waitForImageImport returns true if the strategy image stream tag of a build whose image
+// references could not be resolved waits for its first scheduled import. The update sets the
+// waiting for image import condition of the build, and the build is requeued for when the image
+// import timeout expires; the image stream trigger queue reevaluates it when the image stream is
+// updated. Builds that waited longer than the image import timeout are failed by the update.
+func (bc *BuildController) waitForImageImport(build *buildv1.Build, update *buildUpdate) bool {
+	ref := sharedbuildutil.GetInputReference(build.Spec.Strategy)
+	if ref == nil || ref.Kind != "ImageStreamTag" {
+		return false
+	}
+	namespace := ref.Namespace
+	if len(namespace) == 0 {
+		namespace = build.Namespace
+	}
+	name, tag, ok := imageutil.SplitImageStreamTag(ref.Name)
+	if !ok {
+		return false
+	}
+	stream, err := bc.imageStreamStore.ImageStreams(namespace).Get(name)
+	if err != nil || !pendingScheduledImport(stream, tag) {
+		return false
+	}
+
+	existing := findBuildCondition(build, buildutil.BuildConditionWaitingForImageImport)
+	if bc.imageImportTimeout > 0 {
+		remaining := build.CreationTimestamp.Add(bc.imageImportTimeout).Sub(bc.clock.Now())
+		if remaining <= 0 {
+			message := fmt.Sprintf("The scheduled import of image stream tag %s/%s did not succeed within the image import timeout of %s.", namespace, ref.Name, bc.imageImportTimeout)
+			klog.V(2).Infof("Failing build %s: %s", buildDesc(build), message)
+			update.setPhase(buildv1.BuildPhaseFailed)
+			update.setReason(buildutil.StatusReasonImageImportTimeout)
+			update.setMessage(message)
+			update.setCondition(newWaitingForImageImportCondition(existing, corev1.ConditionFalse, buildutil.ImageImportTimedOutReason, message))
+			return true
+		}
+		bc.buildQueue.AddAfter(resourceName(build.Namespace, build.Name), remaining)
+	}
+
+	message := fmt.Sprintf("Waiting for the scheduled import of image stream tag %s/%s.", namespace, ref.Name)
+	klog.V(4).Infof("Build %s: %s", buildDesc(build), message)
+	if existing != nil && existing.Status == corev1.ConditionTrue && existing.Message == message {
+		return true
+	}
+	update.setCondition(newWaitingForImageImportCondition(existing, corev1.ConditionTrue, buildutil.ScheduledImportPendingReason, message))
+	return true
+}
+
+// setImageImported marks a build that waited for the scheduled import of its strategy image
+// stream tag as no longer waiting once its build pod is created.
+func setImageImported(build *buildv1.Build, update *buildUpdate) {
+	existing := findBuildCondition(build, buildutil.BuildConditionWaitingForImageImport)
+	if existing == nil || existing.Status != corev1.ConditionTrue || update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		return
+	}
+	update.setCondition(newWaitingForImageImportCondition(existing, corev1.ConditionFalse, buildutil.ImageImportedReason,
+		"The strategy image stream tag of the build was imported."))
+}
+
+func newWaitingForImageImportCondition(existing *buildv1.BuildCondition, status corev1.ConditionStatus, reason, message string) buildv1.BuildCondition {
+	now := metav1.Now()
+	condition := buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionWaitingForImageImport,
+		Status:             status,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	if existing != nil && existing.Status == status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	return condition
+}
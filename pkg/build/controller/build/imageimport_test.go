@@ -0,0 +1,169 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// scheduledImportStream returns an image stream whose latest tag is imported periodically and
+// was not imported yet.
+func scheduledImportStream(scheduled bool) *imagev1.ImageStream {
+	stream := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "namespace"},
+		Spec: imagev1.ImageStreamSpec{
+			Tags: []imagev1.TagReference{{
+				Name:         "latest",
+				From:         &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.example.com/base:latest"},
+				ImportPolicy: imagev1.TagImportPolicy{Scheduled: scheduled},
+			}},
+		},
+	}
+	stream.Status.DockerImageRepository = "registry.example.com/namespace/base"
+	return stream
+}
+
+// importedStream returns the image stream after the import of its latest tag succeeded.
+func importedStream(stream *imagev1.ImageStream) *imagev1.ImageStream {
+	stream = stream.DeepCopy()
+	stream.Status.Tags = []imagev1.NamedTagEventList{{
+		Tag: "latest",
+		Items: []imagev1.TagEvent{{
+			DockerImageReference: "registry.example.com/base@sha256:1234",
+			Image:                "sha256:1234",
+		}},
+	}}
+	return stream
+}
+
+func imageImportTestBuild(created time.Time) *buildv1.Build {
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{
+		To:         &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.example.com/output:latest"},
+		PushSecret: &corev1.LocalObjectReference{},
+	}))
+	build.CreationTimestamp = metav1.NewTime(created)
+	build.Spec.Strategy.DockerStrategy.From = &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "base:latest"}
+	return build
+}
+
+func TestCreateBuildPodWaitsForScheduledImport(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fakeClock := clocktesting.NewFakeClock(created.Add(5 * time.Minute))
+	stream := scheduledImportStream(true)
+	build := imageImportTestBuild(created)
+
+	bc := newFakeBuildController(nil, fakeImageClient(stream), nil, nil, nil)
+	defer bc.stop()
+	bc.clock = fakeClock
+	bc.imageImportTimeout = 15 * time.Minute
+
+	update, err := bc.createBuildPod(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase != nil || update.reason != nil {
+		t.Fatalf("expected the build to wait in the new phase, got %v", update)
+	}
+	if len(update.conditions) != 1 {
+		t.Fatalf("expected the waiting for image import condition, got %v", update.conditions)
+	}
+	condition := update.conditions[0]
+	if condition.Type != buildutil.BuildConditionWaitingForImageImport || condition.Status != corev1.ConditionTrue || condition.Reason != buildutil.ScheduledImportPendingReason {
+		t.Errorf("unexpected condition %#v", condition)
+	}
+	build.Status.Conditions = update.conditions
+
+	// the build is not updated again while it keeps waiting
+	update, err = bc.createBuildPod(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !update.isEmpty() {
+		t.Errorf("expected no update of the waiting build, got %v", update)
+	}
+
+	// the scheduled import succeeds after a delay
+	fakeClock.SetTime(created.Add(10 * time.Minute))
+	if err := bc.imageInformers.Image().V1().ImageStreams().Informer().GetIndexer().Update(importedStream(stream)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	update, err = bc.handleNewBuild(build, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		t.Fatalf("expected the build pod to be created, got %v", update)
+	}
+	condition = buildv1.BuildCondition{}
+	for _, c := range update.conditions {
+		if c.Type == buildutil.BuildConditionWaitingForImageImport {
+			condition = c
+		}
+	}
+	if condition.Status != corev1.ConditionFalse || condition.Reason != buildutil.ImageImportedReason {
+		t.Errorf("expected the build to no longer wait for the image import, got %#v", condition)
+	}
+}
+
+func TestCreateBuildPodScheduledImportTimeout(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		scheduled      bool
+		timeout        time.Duration
+		expectedPhase  *buildv1.BuildPhase
+		expectedReason buildv1.StatusReason
+	}{
+		{
+			name:           "import timed out",
+			scheduled:      true,
+			timeout:        15 * time.Minute,
+			expectedPhase:  phasePtr(buildv1.BuildPhaseFailed),
+			expectedReason: buildutil.StatusReasonImageImportTimeout,
+		},
+		{
+			name:      "no import timeout",
+			scheduled: true,
+		},
+		{
+			name:           "import not scheduled",
+			timeout:        15 * time.Minute,
+			expectedReason: buildv1.StatusReasonInvalidImageReference,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := imageImportTestBuild(created)
+			bc := newFakeBuildController(nil, fakeImageClient(scheduledImportStream(tc.scheduled)), nil, nil, nil)
+			defer bc.stop()
+			bc.clock = clocktesting.NewFakeClock(created.Add(20 * time.Minute))
+			bc.imageImportTimeout = tc.timeout
+
+			update, err := bc.createBuildPod(build)
+			if err != nil && tc.scheduled {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (update.phase == nil) != (tc.expectedPhase == nil) || (update.phase != nil && *update.phase != *tc.expectedPhase) {
+				t.Errorf("expected phase %v, got %v", tc.expectedPhase, update.phase)
+			}
+			if len(tc.expectedReason) == 0 {
+				if update.reason != nil {
+					t.Errorf("expected no reason, got %s", *update.reason)
+				}
+			} else if update.reason == nil || *update.reason != tc.expectedReason {
+				t.Errorf("expected reason %s, got %v", tc.expectedReason, update.reason)
+			}
+		})
+	}
+}
+
+func phasePtr(phase buildv1.BuildPhase) *buildv1.BuildPhase {
+	return &phase
+}
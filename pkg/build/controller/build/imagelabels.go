@@ -0,0 +1,23 @@
+package build
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	builddefaults "github.com/openshift/openshift-controller-manager/pkg/build/controller/build/defaults"
+)
+
+// reportInvalidImageLabelTemplates reports the image label templates of the build defaults with an
+// invalid name in events on the build. The build defaults do not add them to the output image
+// labels of the build.
+func (bc *BuildController) reportInvalidImageLabelTemplates(build *buildv1.Build) {
+	for _, template := range bc.defaults().ImageLabelTemplates {
+		if reasons := builddefaults.InvalidImageLabelName(template.Name); len(reasons) > 0 {
+			bc.recorder.Eventf(build, corev1.EventTypeWarning, buildutil.BuildInvalidImageLabelEventReason, buildutil.BuildInvalidImageLabelEventMessage,
+				template.Name, build.Namespace, build.Name, strings.Join(reasons, ", "))
+		}
+	}
+}
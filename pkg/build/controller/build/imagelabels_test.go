@@ -0,0 +1,67 @@
+package build
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
+)
+
+func TestCreateBuildPodImageLabelTemplates(t *testing.T) {
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	recorder := record.NewFakeRecorder(10)
+	bc.recorder = recorder
+	bc.buildDefaults.ImageLabelTemplates = []buildv1.ImageLabel{
+		{Name: "io.example.build", Value: "${BUILD_NAMESPACE}/${BUILD_NAME}"},
+		{Name: "io.example.buildconfig", Value: "${BUILDCONFIG_NAME}"},
+		{Name: "io example", Value: "invalid"},
+	}
+
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{
+		ImageLabels: []buildv1.ImageLabel{{Name: "io.example.buildconfig", Value: "mine"}},
+	}))
+	update, err := bc.createBuildPod(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		t.Fatalf("expected the build pod to be created, got %v", update)
+	}
+	pod, err := kubeClient.CoreV1().Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podBuild, err := common.GetBuildFromPod(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []buildv1.ImageLabel{
+		{Name: "io.example.buildconfig", Value: "mine"},
+		{Name: "io.example.build", Value: "namespace/data-build"},
+	}
+	if labels := podBuild.Spec.Output.ImageLabels; !reflect.DeepEqual(labels, expected) {
+		t.Errorf("expected image labels %v, got %v", expected, labels)
+	}
+
+	invalidLabelEvents := 0
+	for len(recorder.Events) > 0 {
+		if event := <-recorder.Events; strings.Contains(event, buildutil.BuildInvalidImageLabelEventReason) {
+			invalidLabelEvents++
+			if !strings.Contains(event, `"io example"`) {
+				t.Errorf("expected the event to name the invalid label, got %q", event)
+			}
+		}
+	}
+	if invalidLabelEvents != 1 {
+		t.Errorf("expected one invalid image label event, got %d", invalidLabelEvents)
+	}
+}
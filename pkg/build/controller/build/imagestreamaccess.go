@@ -0,0 +1,110 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/openshift/library-go/pkg/authorization/authorizationutil"
+	imageutil "github.com/openshift/library-go/pkg/image/imageutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// imageStreamAccess is the access of the build service account to the image stream of another
+// namespace that the build pushes its output to or pulls its builder image from with the
+// credentials of the service account.
+type imageStreamAccess struct {
+	namespace string
+	name      string
+	// verb is the verb on the imagestreams/layers subresource the registry authorizes the push or
+	// pull with.
+	verb string
+	// push is true for the output image stream of the build.
+	push bool
+}
+
+// imageStreamName returns the image stream of an ImageStream, ImageStreamTag or ImageStreamImage
+// reference.
+func imageStreamName(kind, name string) (string, bool) {
+	switch kind {
+	case "ImageStream":
+		return name, true
+	case "ImageStreamTag":
+		stream, _, ok := imageutil.SplitImageStreamTag(name)
+		return stream, ok
+	case "ImageStreamImage":
+		stream, _, ok := imageutil.SplitImageStreamImage(name)
+		return stream, ok
+	}
+	return "", false
+}
+
+// crossNamespaceImageStreamAccess returns the image streams of other namespaces the build pushes
+// to or pulls its source strategy builder image from, before its image references are resolved.
+// Builds with an explicit push or pull secret do not use the credentials of the service account.
+func crossNamespaceImageStreamAccess(build *buildv1.Build) []imageStreamAccess {
+	var access []imageStreamAccess
+	if to := build.Spec.Output.To; to != nil && build.Spec.Output.PushSecret == nil && len(to.Namespace) > 0 && to.Namespace != build.Namespace {
+		if name, ok := imageStreamName(to.Kind, to.Name); ok {
+			access = append(access, imageStreamAccess{namespace: to.Namespace, name: name, verb: "update", push: true})
+		}
+	}
+	if s := build.Spec.Strategy.SourceStrategy; s != nil && s.PullSecret == nil && len(s.From.Namespace) > 0 && s.From.Namespace != build.Namespace {
+		if name, ok := imageStreamName(s.From.Kind, s.From.Name); ok {
+			access = append(access, imageStreamAccess{namespace: s.From.Namespace, name: name, verb: "get"})
+		}
+	}
+	return access
+}
+
+// checkImageStreamAccess fails builds whose service account is not allowed to push to or pull from
+// the image streams of other namespaces that the build uses with the credentials of the service
+// account: the registry would reject the push at the end of the build. It returns a nil update when
+// the service account has access.
+func (bc *BuildController) checkImageStreamAccess(build *buildv1.Build) (*buildUpdate, error) {
+	access := crossNamespaceImageStreamAccess(build)
+	if len(access) == 0 {
+		return nil, nil
+	}
+	serviceAccountName := build.Spec.ServiceAccount
+	if len(serviceAccountName) == 0 {
+		serviceAccountName = buildutil.BuilderServiceAccountName
+	}
+	user := serviceaccount.UserInfo(build.Namespace, serviceAccountName, "")
+
+	for _, a := range access {
+		sar := authorizationutil.AddUserToSAR(user, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   a.namespace,
+					Verb:        a.verb,
+					Group:       imagev1.GroupName,
+					Resource:    "imagestreams",
+					Subresource: "layers",
+					Name:        a.name,
+				},
+			},
+		})
+		resp, err := bc.kubeClient.AuthorizationV1().SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to check the access of service account %s/%s to image stream %s/%s: %v", build.Namespace, serviceAccountName, a.namespace, a.name, err)
+		}
+		if resp.Status.Allowed {
+			continue
+		}
+		klog.V(4).Infof("Service account %s/%s of build %s has no %s access to image stream %s/%s: %s", build.Namespace, serviceAccountName, buildDesc(build), a.verb, a.namespace, a.name, resp.Status.Reason)
+		if a.push {
+			return transitionToPhase(buildv1.BuildPhaseFailed, buildutil.StatusReasonPushUnauthorized,
+				fmt.Sprintf("The service account %s is not allowed to push to image stream %s/%s.", serviceAccountName, a.namespace, a.name)), nil
+		}
+		return transitionToPhase(buildv1.BuildPhaseFailed, buildv1.StatusReasonPullBuilderImageFailed,
+			fmt.Sprintf("The service account %s is not allowed to pull from image stream %s/%s.", serviceAccountName, a.namespace, a.name)), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,121 @@
+package build
+
+import (
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func TestCreateBuildPodWithCrossNamespaceImageStreams(t *testing.T) {
+	tests := []struct {
+		name            string
+		allowedVerbs    []string
+		pushSecret      bool
+		expectedPhase   buildv1.BuildPhase
+		expectedReason  buildv1.StatusReason
+		expectedReviews []string
+	}{
+		{
+			name:            "push and pull granted",
+			allowedVerbs:    []string{"update", "get"},
+			expectedPhase:   buildv1.BuildPhasePending,
+			expectedReviews: []string{"update images/output", "get images/builder"},
+		},
+		{
+			name:            "push denied",
+			allowedVerbs:    []string{"get"},
+			expectedPhase:   buildv1.BuildPhaseFailed,
+			expectedReason:  buildutil.StatusReasonPushUnauthorized,
+			expectedReviews: []string{"update images/output"},
+		},
+		{
+			name:            "pull denied",
+			allowedVerbs:    []string{"update"},
+			expectedPhase:   buildv1.BuildPhaseFailed,
+			expectedReason:  buildv1.StatusReasonPullBuilderImageFailed,
+			expectedReviews: []string{"update images/output", "get images/builder"},
+		},
+		{
+			name:            "explicit push secret",
+			allowedVerbs:    []string{"get"},
+			pushSecret:      true,
+			expectedPhase:   buildv1.BuildPhasePending,
+			expectedReviews: []string{"get images/builder"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output := &imagev1.ImageStream{}
+			output.Namespace = "images"
+			output.Name = "output"
+			output.Status.DockerImageRepository = "registry.io/images/output"
+			builder := &imagev1.ImageStream{}
+			builder.Namespace = "images"
+			builder.Name = "builder"
+			builder.Status.DockerImageRepository = "registry.io/images/builder"
+			builder.Status.Tags = []imagev1.NamedTagEventList{{
+				Tag:   "latest",
+				Items: []imagev1.TagEvent{{DockerImageReference: "registry.io/images/builder@sha256:1234"}},
+			}}
+
+			pushSecret := &corev1.Secret{}
+			pushSecret.Namespace = "namespace"
+			pushSecret.Name = "push"
+			kubeClient := fakeKubeExternalClientSet(pushSecret).(*fake.Clientset)
+			var reviews []string
+			kubeClient.PrependReactor("create", "subjectaccessreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				sar := action.(clientgotesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				attrs := sar.Spec.ResourceAttributes
+				if sar.Spec.User != "system:serviceaccount:namespace:builder" || attrs.Group != imagev1.GroupName || attrs.Resource != "imagestreams" || attrs.Subresource != "layers" {
+					t.Errorf("unexpected subject access review: %#v", sar.Spec)
+				}
+				reviews = append(reviews, attrs.Verb+" "+attrs.Namespace+"/"+attrs.Name)
+				allowed := false
+				for _, verb := range tc.allowedVerbs {
+					allowed = allowed || verb == attrs.Verb
+				}
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed}}, nil
+			})
+			bc := newFakeBuildController(nil, fakeImageClient(output, builder), kubeClient, nil, nil)
+			defer bc.stop()
+
+			buildOutput := buildv1.BuildOutput{To: &corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "images", Name: "output:latest"}}
+			if tc.pushSecret {
+				buildOutput.PushSecret = &corev1.LocalObjectReference{Name: "push"}
+			}
+			build := sourceStrategy(mockBuild(buildv1.BuildPhaseNew, buildOutput))
+			build.Spec.Strategy.SourceStrategy.From = corev1.ObjectReference{Kind: "ImageStreamTag", Namespace: "images", Name: "builder:latest"}
+			bc.createStrategy = &testPodCreationStrategy{pod: mockBuildPod(build)}
+
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if update.phase == nil || *update.phase != tc.expectedPhase {
+				t.Fatalf("expected phase %s, got %v", tc.expectedPhase, update)
+			}
+			if len(tc.expectedReason) > 0 && (update.reason == nil || *update.reason != tc.expectedReason) {
+				t.Errorf("expected reason %s, got %v", tc.expectedReason, update)
+			}
+			if tc.expectedPhase == buildv1.BuildPhasePending && (update.outputRef == nil || *update.outputRef != "registry.io/images/output:latest") {
+				t.Errorf("expected the output to resolve through the image stream of the images namespace, got %v", update)
+			}
+			if len(reviews) != len(tc.expectedReviews) {
+				t.Fatalf("expected subject access reviews %v, got %v", tc.expectedReviews, reviews)
+			}
+			for i := range reviews {
+				if reviews[i] != tc.expectedReviews[i] {
+					t.Errorf("expected subject access reviews %v, got %v", tc.expectedReviews, reviews)
+				}
+			}
+		})
+	}
+}
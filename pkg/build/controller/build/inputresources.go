@@ -0,0 +1,69 @@
+package build
+
+import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// inputResourceGracePeriod is how long after their creation new builds wait for the secrets and
+// config maps they reference, which are often created together with the build, before they fail.
+const inputResourceGracePeriod = time.Minute
+
+// waitForInputResources returns the update of a new build that references secrets or config maps
+// which do not exist. Within the grace period after its creation, the update sets the waiting for
+// input resource condition of the build and the build is requeued for when the grace period
+// expires; the build also reevaluates when it is updated. Binary builds, whose input can not be
+// uploaded again, and builds that waited longer than the grace period are failed by the update.
+func (bc *BuildController) waitForInputResources(build *buildv1.Build, message string) *buildUpdate {
+	existing := findBuildCondition(build, buildutil.BuildConditionWaitingForInputResource)
+	remaining := build.CreationTimestamp.Add(inputResourceGracePeriod).Sub(bc.clock.Now())
+	if remaining <= 0 || build.Spec.Source.Type == buildv1.BuildSourceBinary {
+		klog.V(2).Infof("Failing build %s: %s", buildDesc(build), message)
+		update := transitionToPhase(buildv1.BuildPhaseFailed, buildutil.StatusReasonMissingInputResource, message)
+		if existing != nil {
+			update.setCondition(newWaitingForInputResourceCondition(existing, corev1.ConditionFalse, buildutil.InputResourceMissingReason, message))
+		}
+		return update
+	}
+
+	klog.V(4).Infof("Waiting %s for the input resources of build %s: %s", remaining, buildDesc(build), message)
+	bc.buildQueue.AddAfter(resourceName(build.Namespace, build.Name), remaining)
+	update := &buildUpdate{}
+	if existing == nil || existing.Status != corev1.ConditionTrue || existing.Message != message {
+		update.setCondition(newWaitingForInputResourceCondition(existing, corev1.ConditionTrue, buildutil.InputResourcePendingReason, message))
+	}
+	return update
+}
+
+// setInputResourcesFound marks a build that waited for the secrets and config maps it references
+// as no longer waiting once its build pod is created.
+func setInputResourcesFound(build *buildv1.Build, update *buildUpdate) {
+	existing := findBuildCondition(build, buildutil.BuildConditionWaitingForInputResource)
+	if existing == nil || existing.Status != corev1.ConditionTrue || update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		return
+	}
+	update.setCondition(newWaitingForInputResourceCondition(existing, corev1.ConditionFalse, buildutil.InputResourcesFoundReason,
+		"The secrets and config maps referenced by the build exist."))
+}
+
+func newWaitingForInputResourceCondition(existing *buildv1.BuildCondition, status corev1.ConditionStatus, reason, message string) buildv1.BuildCondition {
+	now := metav1.Now()
+	condition := buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionWaitingForInputResource,
+		Status:             status,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+	if existing != nil && existing.Status == status {
+		condition.LastTransitionTime = existing.LastTransitionTime
+	}
+	return condition
+}
@@ -0,0 +1,163 @@
+package build
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func TestCreateBuildPodInputResources(t *testing.T) {
+	sourceSecret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "git-credentials", Namespace: "namespace"}}
+	inputConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "namespace"}}
+	tests := []struct {
+		name            string
+		binary          bool
+		age             time.Duration
+		existing        []*corev1.Secret
+		configMaps      []*corev1.ConfigMap
+		expectedPhase   buildv1.BuildPhase
+		expectedReason  buildv1.StatusReason
+		expectedMissing []string
+	}{
+		{
+			name:          "present",
+			age:           inputResourceGracePeriod,
+			existing:      []*corev1.Secret{sourceSecret},
+			configMaps:    []*corev1.ConfigMap{inputConfigMap},
+			expectedPhase: buildv1.BuildPhasePending,
+		},
+		{
+			name:            "missing",
+			age:             inputResourceGracePeriod,
+			expectedPhase:   buildv1.BuildPhaseFailed,
+			expectedReason:  buildutil.StatusReasonMissingInputResource,
+			expectedMissing: []string{"ConfigMaps [settings]", "Secrets [git-credentials]"},
+		},
+		{
+			name:            "missing source secret",
+			age:             inputResourceGracePeriod,
+			configMaps:      []*corev1.ConfigMap{inputConfigMap},
+			expectedPhase:   buildv1.BuildPhaseFailed,
+			expectedReason:  buildutil.StatusReasonMissingInputResource,
+			expectedMissing: []string{"Secrets [git-credentials]"},
+		},
+		{
+			name:            "missing in a new build",
+			age:             10 * time.Second,
+			existing:        []*corev1.Secret{sourceSecret},
+			expectedMissing: []string{"ConfigMaps [settings]"},
+		},
+		{
+			name:            "missing in a new binary build",
+			binary:          true,
+			age:             10 * time.Second,
+			existing:        []*corev1.Secret{sourceSecret},
+			expectedPhase:   buildv1.BuildPhaseFailed,
+			expectedReason:  buildutil.StatusReasonMissingInputResource,
+			expectedMissing: []string{"ConfigMaps [settings]"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			bc := newFakeBuildController(nil, nil, fakeKubeExternalClientSet(registryCAConfigMap), nil, nil)
+			defer bc.stop()
+			bc.clock = clocktesting.NewFakeClock(now)
+			for _, secret := range tc.existing {
+				bc.kubeExternalInformers.Core().V1().Secrets().Informer().GetIndexer().Add(secret)
+			}
+			for _, configMap := range tc.configMaps {
+				bc.kubeExternalInformers.Core().V1().ConfigMaps().Informer().GetIndexer().Add(configMap)
+			}
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.CreationTimestamp = metav1.NewTime(now.Add(-tc.age))
+			build.Spec.Source.SourceSecret = &corev1.LocalObjectReference{Name: sourceSecret.Name}
+			build.Spec.Source.ConfigMaps = []buildv1.ConfigMapBuildSource{{ConfigMap: corev1.LocalObjectReference{Name: inputConfigMap.Name}}}
+			if tc.binary {
+				build.Spec.Source.Type = buildv1.BuildSourceBinary
+				build.Spec.Source.Binary = &buildv1.BinaryBuildSource{}
+			}
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.expectedPhase) == 0 {
+				if update.phase != nil {
+					t.Fatalf("expected the build to wait for its input resources, got %v", update)
+				}
+			} else if update.phase == nil || *update.phase != tc.expectedPhase {
+				t.Fatalf("expected phase %s, got %v", tc.expectedPhase, update)
+			}
+			if len(tc.expectedReason) > 0 && (update.reason == nil || *update.reason != tc.expectedReason) {
+				t.Errorf("expected reason %s, got %v", tc.expectedReason, update)
+			}
+
+			message := ""
+			if update.message != nil {
+				message = *update.message
+			}
+			if len(tc.expectedPhase) == 0 {
+				if len(update.conditions) != 1 || update.conditions[0].Type != buildutil.BuildConditionWaitingForInputResource || update.conditions[0].Status != corev1.ConditionTrue {
+					t.Fatalf("expected the waiting for input resource condition to be set, got %v", update.conditions)
+				}
+				message = update.conditions[0].Message
+			}
+			for _, missing := range tc.expectedMissing {
+				if !strings.Contains(message, missing) {
+					t.Errorf("expected the message to name %s, got %q", missing, message)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBuildPodInputResourcesAppearLater(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bc := newFakeBuildController(nil, nil, fakeKubeExternalClientSet(registryCAConfigMap), nil, nil)
+	defer bc.stop()
+	fakeClock := clocktesting.NewFakeClock(now)
+	bc.clock = fakeClock
+
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+	build.CreationTimestamp = metav1.NewTime(now)
+	build.Spec.Source.Secrets = []buildv1.SecretBuildSource{{Secret: corev1.LocalObjectReference{Name: "certs"}}}
+	update, err := bc.handleNewBuild(build, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase != nil || len(update.conditions) != 1 {
+		t.Fatalf("expected the build to wait for its input secret, got %v", update)
+	}
+	build.Status.Conditions = update.conditions
+
+	// waiting again does not update the build
+	update, err = bc.handleNewBuild(build, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !update.isEmpty() {
+		t.Errorf("expected no update while the build waits, got %v", update)
+	}
+
+	fakeClock.Step(10 * time.Second)
+	bc.kubeExternalInformers.Core().V1().Secrets().Informer().GetIndexer().Add(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "certs", Namespace: build.Namespace}})
+	update, err = bc.handleNewBuild(build, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		t.Fatalf("expected the build pod to be created, got %v", update)
+	}
+	condition := update.conditions
+	if len(condition) != 1 || condition[0].Status != corev1.ConditionFalse || condition[0].Reason != buildutil.InputResourcesFoundReason {
+		t.Errorf("expected the waiting for input resource condition to be cleared, got %v", condition)
+	}
+}
@@ -0,0 +1,35 @@
+package build
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	sharedbuildutil "github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// applyLogLevelAnnotation sets the BUILD_LOGLEVEL of the build to its log level annotation, which
+// replaces the log level of the build and the build defaults. An invalid log level is reported in
+// an event on the build and ignored.
+func (bc *BuildController) applyLogLevelAnnotation(build *buildv1.Build) {
+	level, ok := build.Annotations[buildutil.BuildLogLevelAnnotation]
+	if !ok {
+		return
+	}
+	if !buildutil.IsValidBuildLogLevel(level) {
+		bc.recorder.Eventf(build, corev1.EventTypeWarning, buildutil.BuildInvalidLogLevelEventReason, buildutil.BuildInvalidLogLevelEventMessage,
+			level, build.Namespace, build.Name, buildutil.MinBuildLogLevel, buildutil.MaxBuildLogLevel)
+		return
+	}
+	klog.V(4).Infof("Setting the log level of build %s to %s from its annotation", buildDesc(build), level)
+
+	env := sharedbuildutil.GetBuildEnv(build)
+	for i := range env {
+		if env[i].Name == buildutil.BuildLogLevelEnvVar {
+			env[i] = corev1.EnvVar{Name: buildutil.BuildLogLevelEnvVar, Value: level}
+			return
+		}
+	}
+	sharedbuildutil.SetBuildEnv(build, append(env, corev1.EnvVar{Name: buildutil.BuildLogLevelEnvVar, Value: level}))
+}
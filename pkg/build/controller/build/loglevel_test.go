@@ -0,0 +1,101 @@
+package build
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func TestCreateBuildPodLogLevel(t *testing.T) {
+	level := func(l int32) *int32 { return &l }
+	tests := []struct {
+		name          string
+		annotation    string
+		env           []corev1.EnvVar
+		defaultLevel  *int32
+		expectedArg   string
+		expectedEvent bool
+	}{
+		{
+			name:        "no log level",
+			expectedArg: "--v=0",
+		},
+		{
+			name:         "default log level",
+			defaultLevel: level(3),
+			expectedArg:  "--v=3",
+		},
+		{
+			name:         "annotation replaces the default",
+			annotation:   "5",
+			defaultLevel: level(3),
+			expectedArg:  "--v=5",
+		},
+		{
+			name:        "annotation replaces the build environment",
+			annotation:  "4",
+			env:         []corev1.EnvVar{{Name: "BUILD_LOGLEVEL", Value: "1"}},
+			expectedArg: "--v=4",
+		},
+		{
+			name:          "invalid annotation",
+			annotation:    "verbose",
+			defaultLevel:  level(3),
+			expectedArg:   "--v=3",
+			expectedEvent: true,
+		},
+		{
+			name:          "annotation out of range",
+			annotation:    "6",
+			expectedArg:   "--v=0",
+			expectedEvent: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+			recorder := record.NewFakeRecorder(10)
+			bc.recorder = recorder
+			bc.buildDefaults.LogLevel = tc.defaultLevel
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.Strategy.DockerStrategy.Env = tc.env
+			if len(tc.annotation) > 0 {
+				build.Annotations[buildutil.BuildLogLevelAnnotation] = tc.annotation
+			}
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+				t.Fatalf("expected the build pod to be created, got %v", update)
+			}
+			pod, err := kubeClient.CoreV1().Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if args := pod.Spec.Containers[0].Args; len(args) == 0 || args[len(args)-1] != tc.expectedArg {
+				t.Errorf("expected build pod argument %s, got %v", tc.expectedArg, args)
+			}
+
+			invalidLogLevelEvent := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, buildutil.BuildInvalidLogLevelEventReason) {
+					invalidLogLevelEvent = true
+				}
+			}
+			if invalidLogLevelEvent != tc.expectedEvent {
+				t.Errorf("expected an invalid log level event: %v, got %v", tc.expectedEvent, invalidLogLevelEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// orphanedBuildPodSweepInterval is how often the build controller looks for build pods whose build
+// no longer exists.
+const orphanedBuildPodSweepInterval = time.Minute
+
+// buildPodSelector selects the pods labeled with the name of their build.
+func buildPodSelector() labels.Selector {
+	requirement, err := labels.NewRequirement(buildv1.BuildLabel, selection.Exists, nil)
+	if err != nil {
+		panic(err)
+	}
+	return labels.NewSelector().Add(*requirement)
+}
+
+// isOrphanedBuildPod returns true if the build of the build pod does not exist anymore, or was
+// recreated with the same name. Pods of builds that are being deleted are not orphaned, the garbage
+// collector deletes them with their build.
+func (bc *BuildController) isOrphanedBuildPod(pod *corev1.Pod) (bool, error) {
+	build, err := bc.buildLister.Builds(pod.Namespace).Get(getBuildName(pod))
+	switch {
+	case errors.IsNotFound(err):
+		return true, nil
+	case err != nil:
+		return false, err
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "Build" && owner.UID != build.UID {
+		return true, nil
+	}
+	return false, nil
+}
+
+// pruneOrphanedBuildPods deletes the build pods whose build was deleted while the pod was kept,
+// such as by an orphaning delete, once they have been orphaned for the orphaned pod grace period.
+// An event is recorded on every deleted pod.
+func (bc *BuildController) pruneOrphanedBuildPods() {
+	pods, err := bc.podStore.List(buildPodSelector())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to list build pods: %v", err))
+		return
+	}
+
+	now := bc.clock.Now()
+	orphaned := map[string]time.Time{}
+	for _, pod := range pods {
+		if !isBuildPod(pod) || pod.DeletionTimestamp != nil {
+			continue
+		}
+		isOrphaned, err := bc.isOrphanedBuildPod(pod)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("unable to get the build of pod %s/%s: %v", pod.Namespace, pod.Name, err))
+			continue
+		}
+		if !isOrphaned {
+			continue
+		}
+
+		key := resourceName(pod.Namespace, pod.Name)
+		since, ok := bc.orphanedBuildPods[key]
+		if !ok {
+			since = now
+		}
+		if remaining := since.Add(bc.orphanedPodGracePeriod).Sub(now); remaining > 0 {
+			klog.V(4).Infof("Build pod %s of deleted build %s/%s will be deleted in %s", key, pod.Namespace, getBuildName(pod), remaining)
+			orphaned[key] = since
+			continue
+		}
+
+		err = bc.podClient.Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{Preconditions: metav1.NewUIDPreconditions(string(pod.UID))})
+		if err != nil && !errors.IsNotFound(err) {
+			utilruntime.HandleError(fmt.Errorf("unable to delete orphaned build pod %s: %v", key, err))
+			orphaned[key] = since
+			continue
+		}
+		klog.V(2).Infof("Deleted orphaned build pod %s of deleted build %s/%s", key, pod.Namespace, getBuildName(pod))
+		bc.recorder.Eventf(pod, corev1.EventTypeNormal, buildutil.BuildPodOrphanedEventReason, buildutil.BuildPodOrphanedEventMessage,
+			pod.Namespace, pod.Name, pod.Namespace, getBuildName(pod))
+	}
+	bc.orphanedBuildPods = orphaned
+}
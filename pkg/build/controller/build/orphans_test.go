@@ -0,0 +1,120 @@
+package build
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+func TestPruneOrphanedBuildPods(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newBuild := func(name string, uid types.UID) *buildv1.Build {
+		build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+		build.Name = name
+		build.UID = uid
+		return build
+	}
+	newBuildPod := func(build *buildv1.Build) *corev1.Pod {
+		pod := mockBuildPod(build)
+		pod.UID = types.UID(build.Name + "-pod")
+		pod.Labels = map[string]string{buildv1.BuildLabel: buildutil.LabelValue(build.Name)}
+		pod.OwnerReferences = []metav1.OwnerReference{strategy.MakeOwnerReference(build)}
+		return pod
+	}
+
+	running := newBuild("running", "running-uid")
+	terminating := newBuild("terminating", "terminating-uid")
+	terminating.DeletionTimestamp = &metav1.Time{Time: now}
+	deleted := newBuild("deleted", "deleted-uid")
+	recreated := newBuild("recreated", "recreated-uid")
+	pods := map[string]*corev1.Pod{
+		"running":     newBuildPod(running),
+		"terminating": newBuildPod(terminating),
+		"deleted":     newBuildPod(deleted),
+		"recreated":   newBuildPod(recreated),
+	}
+	otherPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: running.Namespace}}
+
+	objects := []runtime.Object{otherPod}
+	for _, pod := range pods {
+		objects = append(objects, pod)
+	}
+	kubeClient := fakeKubeExternalClientSet(objects...)
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	recorder := record.NewFakeRecorder(10)
+	bc.recorder = recorder
+	fakeClock := clocktesting.NewFakeClock(now)
+	bc.clock = fakeClock
+	bc.orphanedPodGracePeriod = 10 * time.Minute
+	for _, obj := range objects {
+		if err := bc.kubeExternalInformers.Core().V1().Pods().Informer().GetIndexer().Add(obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// the build was recreated with the same name after its pod was orphaned
+	for _, build := range []*buildv1.Build{running, terminating, newBuild("recreated", "new-uid")} {
+		if err := bc.buildInformers.Build().V1().Builds().Informer().GetIndexer().Add(build); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expectPods := func(step string, expected ...string) {
+		t.Helper()
+		for name, pod := range pods {
+			_, err := kubeClient.CoreV1().Pods(pod.Namespace).Get(context.TODO(), pod.Name, metav1.GetOptions{})
+			if err != nil && !errors.IsNotFound(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			exists := false
+			for _, e := range expected {
+				exists = exists || e == name
+			}
+			if (err == nil) != exists {
+				t.Errorf("%s: expected the pod of build %s to exist: %v", step, name, exists)
+			}
+		}
+		if _, err := kubeClient.CoreV1().Pods(otherPod.Namespace).Get(context.TODO(), otherPod.Name, metav1.GetOptions{}); err != nil {
+			t.Errorf("%s: expected the pod that is not a build pod to be kept: %v", step, err)
+		}
+	}
+
+	// the orphaned pods are found
+	bc.pruneOrphanedBuildPods()
+	expectPods("found", "running", "terminating", "deleted", "recreated")
+
+	// the grace period did not pass
+	fakeClock.Step(5 * time.Minute)
+	bc.pruneOrphanedBuildPods()
+	expectPods("grace period", "running", "terminating", "deleted", "recreated")
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no events, got %d", len(recorder.Events))
+	}
+
+	// the grace period passed
+	fakeClock.Step(5 * time.Minute)
+	bc.pruneOrphanedBuildPods()
+	expectPods("deleted", "running", "terminating")
+	events := []string{}
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	if len(events) != 2 || !strings.Contains(events[0], buildutil.BuildPodOrphanedEventReason) {
+		t.Errorf("expected an event for each deleted pod, got %v", events)
+	}
+	if len(bc.orphanedBuildPods) != 0 {
+		t.Errorf("expected the deleted pods to be forgotten, got %v", bc.orphanedBuildPods)
+	}
+}
@@ -6,19 +6,71 @@ import (
 	"k8s.io/klog/v2"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	openshiftcontrolplanev1 "github.com/openshift/api/openshiftcontrolplane/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 	"github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
 )
 
 type BuildOverrides struct {
 	Config *openshiftcontrolplanev1.BuildOverridesConfig
+	// PriorityClassName replaces the priority class of build pods when set.
+	PriorityClassName string
+	// AllowedEnvNames and DeniedEnvNames filter the environment of custom builder images. When
+	// AllowedEnvNames is set, only the listed variables are passed to the custom builder, and the
+	// variables of DeniedEnvNames are never passed. The variables the custom build strategy sets
+	// up for the builder cannot be filtered.
+	AllowedEnvNames []string
+	DeniedEnvNames  []string
+	// StrategyForcePull overrides the forcePull of the builds of each strategy. It replaces the
+	// forcePull of Config for the builds of the strategies that have one.
+	StrategyForcePull StrategyForcePull
 }
 
+// StrategyForcePull holds the forcePull override of the builds of each build strategy.
+type StrategyForcePull struct {
+	DockerStrategy *bool `json:"dockerStrategy,omitempty"`
+	SourceStrategy *bool `json:"sourceStrategy,omitempty"`
+	CustomStrategy *bool `json:"customStrategy,omitempty"`
+}
+
+// isSet returns true if the forcePull of the builds of any strategy is overridden.
+func (f StrategyForcePull) isSet() bool {
+	return f.DockerStrategy != nil || f.SourceStrategy != nil || f.CustomStrategy != nil
+}
+
+// protectedCustomBuildEnvNames are the environment variables the custom build strategy sets up for
+// the custom builder image, which the environment filter never strips.
+var protectedCustomBuildEnvNames = sets.New[string](
+	"BUILD",
+	"LANG",
+	"SOURCE_REPOSITORY",
+	"SOURCE_URI",
+	"SOURCE_CONTEXT_DIR",
+	"SOURCE_REF",
+	"SOURCE_SECRET_PATH",
+	"OUTPUT_REGISTRY",
+	"OUTPUT_IMAGE",
+	"PUSH_DOCKERCFG_PATH",
+	"PULL_DOCKERCFG_PATH",
+	"DOCKER_SOCKET",
+	"BUILD_MOUNT_ETC_PKI_CATRUST",
+	"BUILD_REGISTRIES_CONF_PATH",
+	"BUILD_REGISTRIES_DIR_PATH",
+	"BUILD_SIGNATURE_POLICY_PATH",
+	"BUILD_STORAGE_CONF_PATH",
+)
+
 // ApplyOverrides applies configured overrides to a build in a build pod
 func (b BuildOverrides) ApplyOverrides(pod *corev1.Pod) error {
-	if b.Config == nil {
+	if len(b.PriorityClassName) != 0 {
+		klog.V(5).Infof("Overriding priority class of pod %s/%s with %s", pod.Namespace, pod.Name, b.PriorityClassName)
+		pod.Spec.PriorityClassName = b.PriorityClassName
+	}
+
+	if b.Config == nil && !b.filtersEnv() && !b.StrategyForcePull.isSet() {
 		return nil
 	}
 
@@ -29,29 +81,12 @@ func (b BuildOverrides) ApplyOverrides(pod *corev1.Pod) error {
 
 	klog.V(4).Infof("Applying overrides to build %s/%s", build.Namespace, build.Name)
 
-	if b.Config.ForcePull != nil {
-		if build.Spec.Strategy.DockerStrategy != nil {
-			klog.V(5).Infof("Setting docker strategy ForcePull to %t in build %s/%s", *b.Config.ForcePull, build.Namespace, build.Name)
-			build.Spec.Strategy.DockerStrategy.ForcePull = *b.Config.ForcePull
-		}
-		if build.Spec.Strategy.SourceStrategy != nil {
-			klog.V(5).Infof("Setting source strategy ForcePull to %t in build %s/%s", *b.Config.ForcePull, build.Namespace, build.Name)
-			build.Spec.Strategy.SourceStrategy.ForcePull = *b.Config.ForcePull
-		}
-		if build.Spec.Strategy.CustomStrategy != nil {
-			pullPolicy := corev1.PullIfNotPresent
-			if *b.Config.ForcePull {
-				pullPolicy = corev1.PullAlways
-			}
-
-			err := applyPullPolicyToPod(pod, pullPolicy)
-			if err != nil {
-				return err
-			}
-
-			klog.V(5).Infof("Setting custom strategy ForcePull to %t in build %s/%s", *b.Config.ForcePull, build.Namespace, build.Name)
-			build.Spec.Strategy.CustomStrategy.ForcePull = *b.Config.ForcePull
-		}
+	b.applyEnvFilter(build, pod)
+	if err := b.applyForcePull(build, pod); err != nil {
+		return err
+	}
+	if b.Config == nil {
+		return common.SetBuildInPod(pod, build)
 	}
 
 	// Apply label overrides
@@ -84,17 +119,123 @@ func (b BuildOverrides) ApplyOverrides(pod *corev1.Pod) error {
 	}
 
 	// Override Tolerations
-	if len(b.Config.Tolerations) != 0 {
-		klog.V(5).Infof("Overriding tolerations for pod %s/%s", pod.Namespace, pod.Name)
-		pod.Spec.Tolerations = []corev1.Toleration{}
-		for _, toleration := range b.Config.Tolerations {
-			pod.Spec.Tolerations = append(pod.Spec.Tolerations, toleration)
-		}
+	for _, toleration := range b.Config.Tolerations {
+		klog.V(5).Infof("Adding override toleration %s:%s to build pod %s/%s", toleration.Key, toleration.Effect, pod.Namespace, pod.Name)
+		overrideToleration(toleration, &pod.Spec.Tolerations)
 	}
 
 	return common.SetBuildInPod(pod, build)
 }
 
+// forcePull returns the forcePull override of the builds of a strategy, which is the override of
+// the strategy when it has one, or else the forcePull of Config.
+func (b BuildOverrides) forcePull(strategyForcePull *bool) *bool {
+	if strategyForcePull != nil {
+		return strategyForcePull
+	}
+	if b.Config != nil {
+		return b.Config.ForcePull
+	}
+	return nil
+}
+
+// applyForcePull overrides the forcePull of the build strategy, even when the build sets it.
+func (b BuildOverrides) applyForcePull(build *buildv1.Build, pod *corev1.Pod) error {
+	if build.Spec.Strategy.DockerStrategy != nil {
+		if forcePull := b.forcePull(b.StrategyForcePull.DockerStrategy); forcePull != nil {
+			klog.V(5).Infof("Setting docker strategy ForcePull to %t in build %s/%s", *forcePull, build.Namespace, build.Name)
+			build.Spec.Strategy.DockerStrategy.ForcePull = *forcePull
+		}
+	}
+	if build.Spec.Strategy.SourceStrategy != nil {
+		if forcePull := b.forcePull(b.StrategyForcePull.SourceStrategy); forcePull != nil {
+			klog.V(5).Infof("Setting source strategy ForcePull to %t in build %s/%s", *forcePull, build.Namespace, build.Name)
+			build.Spec.Strategy.SourceStrategy.ForcePull = *forcePull
+		}
+	}
+	if build.Spec.Strategy.CustomStrategy != nil {
+		if forcePull := b.forcePull(b.StrategyForcePull.CustomStrategy); forcePull != nil {
+			pullPolicy := corev1.PullIfNotPresent
+			if *forcePull {
+				pullPolicy = corev1.PullAlways
+			}
+
+			err := applyPullPolicyToPod(pod, pullPolicy)
+			if err != nil {
+				return err
+			}
+
+			klog.V(5).Infof("Setting custom strategy ForcePull to %t in build %s/%s", *forcePull, build.Namespace, build.Name)
+			build.Spec.Strategy.CustomStrategy.ForcePull = *forcePull
+		}
+	}
+	return nil
+}
+
+// filtersEnv returns true if the environment of custom builder images is filtered.
+func (b BuildOverrides) filtersEnv() bool {
+	return len(b.AllowedEnvNames) != 0 || len(b.DeniedEnvNames) != 0
+}
+
+// envAllowed returns true if the environment variable may be passed to custom builder images.
+func (b BuildOverrides) envAllowed(name string) bool {
+	if protectedCustomBuildEnvNames.Has(name) {
+		return true
+	}
+	for _, denied := range b.DeniedEnvNames {
+		if name == denied {
+			return false
+		}
+	}
+	if len(b.AllowedEnvNames) == 0 {
+		return true
+	}
+	for _, allowed := range b.AllowedEnvNames {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEnvFilter strips the environment variables that are not allowed from the custom strategy of
+// the build and from the containers of its build pod, and records the names of the stripped
+// variables in the BuildStrippedEnvAnnotation of the build.
+func (b BuildOverrides) applyEnvFilter(build *buildv1.Build, pod *corev1.Pod) {
+	if !b.filtersEnv() || build.Spec.Strategy.CustomStrategy == nil {
+		return
+	}
+	stripped := sets.New[string]()
+	filter := func(env []corev1.EnvVar) []corev1.EnvVar {
+		var filtered []corev1.EnvVar
+		for _, e := range env {
+			if !b.envAllowed(e.Name) {
+				stripped.Insert(e.Name)
+				continue
+			}
+			filtered = append(filtered, e)
+		}
+		return filtered
+	}
+	build.Spec.Strategy.CustomStrategy.Env = filter(build.Spec.Strategy.CustomStrategy.Env)
+	for i := range pod.Spec.InitContainers {
+		pod.Spec.InitContainers[i].Env = filter(pod.Spec.InitContainers[i].Env)
+	}
+	for i := range pod.Spec.Containers {
+		pod.Spec.Containers[i].Env = filter(pod.Spec.Containers[i].Env)
+	}
+	if stripped.Len() == 0 {
+		return
+	}
+
+	names := sets.List(stripped)
+	klog.V(5).Infof("Stripping environment variables %v from custom build pod %s/%s", names, pod.Namespace, pod.Name)
+	if build.Annotations == nil {
+		build.Annotations = map[string]string{}
+	}
+	build.Annotations[buildutil.BuildStrippedEnvAnnotation] = strings.Join(names, ",")
+}
+
 func applyPullPolicyToPod(pod *corev1.Pod, pullPolicy corev1.PullPolicy) error {
 	for i := range pod.Spec.InitContainers {
 		klog.V(5).Infof("Setting ImagePullPolicy to %q on init container %s of pod %s/%s", pullPolicy, pod.Spec.InitContainers[i].Name, pod.Namespace, pod.Name)
@@ -107,6 +248,21 @@ func applyPullPolicyToPod(pod *corev1.Pod, pullPolicy corev1.PullPolicy) error {
 	return nil
 }
 
+// overrideToleration replaces the tolerations of the pod with the same key and effect by the
+// overriding toleration. An overriding toleration without an effect replaces the tolerations of
+// all effects of its key.
+func overrideToleration(overridingToleration corev1.Toleration, tolerations *[]corev1.Toleration) {
+	merged := make([]corev1.Toleration, 0, len(*tolerations)+1)
+	for _, toleration := range *tolerations {
+		if toleration.Key == overridingToleration.Key && (len(overridingToleration.Effect) == 0 || toleration.Effect == overridingToleration.Effect) {
+			klog.V(5).Infof("Replacing toleration %s:%s", toleration.Key, toleration.Effect)
+			continue
+		}
+		merged = append(merged, toleration)
+	}
+	*tolerations = append(merged, overridingToleration)
+}
+
 func overrideLabel(overridingLabel buildv1.ImageLabel, buildLabels *[]buildv1.ImageLabel) {
 	found := false
 	for i, lbl := range *buildLabels {
@@ -0,0 +1,68 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	sharedbuildutil "github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	metrics "github.com/openshift/openshift-controller-manager/pkg/build/metrics/prometheus"
+)
+
+// pipelineStrategyUnsupportedMessage explains why builds of the JenkinsPipeline strategy fail.
+const pipelineStrategyUnsupportedMessage = "The JenkinsPipeline build strategy was removed. Run the Jenkinsfile with OpenShift Pipelines or Jenkins directly, or change the build strategy of the build config."
+
+// rejectPipelineBuild fails a new build of the removed JenkinsPipeline strategy, which no build
+// pod or jenkins sync plugin ever runs, and marks its build config as unsupported.
+func (bc *BuildController) rejectPipelineBuild(build *buildv1.Build) *buildUpdate {
+	klog.V(2).Infof("Failing build %s of the unsupported JenkinsPipeline strategy", buildDesc(build))
+	metrics.RecordPipelineStrategyRejection(build)
+	if err := bc.markBuildConfigStrategyUnsupported(build); err != nil {
+		utilruntime.HandleError(err)
+	}
+	return transitionToPhase(buildv1.BuildPhaseFailed, buildutil.StatusReasonPipelineStrategyUnsupported, pipelineStrategyUnsupportedMessage)
+}
+
+// markBuildConfigStrategyUnsupported sets the strategy unsupported annotation on the build config of
+// a pipeline build and records a warning event on it, unless the build config already has the
+// annotation.
+func (bc *BuildController) markBuildConfigStrategyUnsupported(build *buildv1.Build) error {
+	bcName := sharedbuildutil.ConfigNameForBuild(build)
+	if len(bcName) == 0 {
+		return nil
+	}
+	buildConfig, err := bc.buildConfigLister.BuildConfigs(build.Namespace).Get(bcName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to get build config %s/%s of build %s: %v", build.Namespace, bcName, buildDesc(build), err)
+	}
+	if buildConfig.Annotations[buildutil.BuildConfigStrategyUnsupportedAnnotation] == pipelineStrategyUnsupportedMessage {
+		return nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{buildutil.BuildConfigStrategyUnsupportedAnnotation: pipelineStrategyUnsupportedMessage},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	patched, err := bc.buildConfigUpdater.BuildConfigs(build.Namespace).Patch(context.TODO(), bcName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to mark build config %s/%s of build %s as unsupported: %v", build.Namespace, bcName, buildDesc(build), err)
+	}
+	bc.recorder.Event(patched, corev1.EventTypeWarning, string(buildutil.StatusReasonPipelineStrategyUnsupported), pipelineStrategyUnsupportedMessage)
+	return nil
+}
@@ -0,0 +1,79 @@
+package build
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/component-base/metrics/testutil"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildv1client "github.com/openshift/client-go/build/clientset/versioned/fake"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	metrics "github.com/openshift/openshift-controller-manager/pkg/build/metrics/prometheus"
+)
+
+func TestHandlePipelineBuild(t *testing.T) {
+	metrics.Register()
+	build := pipelineStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+	buildConfig := &buildv1.BuildConfig{ObjectMeta: metav1.ObjectMeta{Name: "test-bc", Namespace: build.Namespace}}
+
+	var patchedBuild *buildv1.Build
+	buildClient := fakeBuildClient(build, buildConfig)
+	buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds",
+		func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			var err error
+			patchedBuild, err = applyBuildPatch(build, action.(clientgotesting.PatchActionImpl).Patch)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return true, patchedBuild, nil
+		})
+	bc := newFakeBuildController(buildClient, nil, nil, nil, nil)
+	defer bc.stop()
+	recorder := record.NewFakeRecorder(10)
+	bc.recorder = recorder
+	if err := bc.buildInformers.Build().V1().BuildConfigs().Informer().GetIndexer().Add(buildConfig); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rejections := metrics.PipelineStrategyRejections.WithLabelValues(build.Namespace, "test-bc")
+	rejectionsBefore, err := testutil.GetCounterMetricValue(rejections)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patchedBuild == nil || patchedBuild.Status.Phase != buildv1.BuildPhaseFailed || patchedBuild.Status.Reason != buildutil.StatusReasonPipelineStrategyUnsupported {
+		t.Fatalf("expected the pipeline build to fail, got %v", patchedBuild)
+	}
+	if rejectionsAfter, _ := testutil.GetCounterMetricValue(rejections); rejectionsAfter != rejectionsBefore+1 {
+		t.Errorf("expected %v pipeline strategy rejections, got %v", rejectionsBefore+1, rejectionsAfter)
+	}
+
+	updatedBuildConfig, err := buildClient.BuildV1().BuildConfigs(build.Namespace).Get(context.TODO(), buildConfig.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if message := updatedBuildConfig.Annotations[buildutil.BuildConfigStrategyUnsupportedAnnotation]; message != pipelineStrategyUnsupportedMessage {
+		t.Errorf("expected the build config to be marked as unsupported, got %q", message)
+	}
+	reasons := []string{}
+	for len(recorder.Events) > 0 {
+		reasons = append(reasons, <-recorder.Events)
+	}
+	if !strings.Contains(strings.Join(reasons, "\n"), string(buildutil.StatusReasonPipelineStrategyUnsupported)) {
+		t.Errorf("expected a warning event on the build config, got %v", reasons)
+	}
+
+	// the failed pipeline build is ignored
+	patchedBuild.Status.CompletionTimestamp = &metav1.Time{}
+	if !shouldIgnore(patchedBuild) {
+		t.Errorf("expected the failed pipeline build to be ignored")
+	}
+}
@@ -0,0 +1,93 @@
+package build
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// postCommitTimeoutExitCode is the exit code of timeout when it terminated the command it ran.
+const postCommitTimeoutExitCode = 124
+
+// postCommitTimeoutPattern matches the line the wrapped post-commit hook logs when timeout
+// terminated it, with the timeout in seconds.
+var postCommitTimeoutPattern = regexp.MustCompile(`The post-commit hook did not complete within (\d+) seconds`)
+
+// hasPostCommitHook returns true if the build runs a post-commit hook.
+func hasPostCommitHook(build *buildv1.Build) bool {
+	hook := build.Spec.PostCommit
+	return len(hook.Command) > 0 || len(hook.Args) > 0 || len(hook.Script) > 0
+}
+
+// shellQuote quotes a string as a single word of a shell command.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// postCommitTimeoutScript returns a script that runs command with timeout and logs that the hook
+// did not complete when timeout terminated it. The script exits with the exit code of the command.
+func postCommitTimeoutScript(seconds int64, command string) string {
+	return fmt.Sprintf(`timeout %d %s; status=$?; if [ $status -eq %d ]; then echo "The post-commit hook did not complete within %d seconds"; fi; exit $status`,
+		seconds, command, postCommitTimeoutExitCode, seconds)
+}
+
+// setupPostCommitTimeout wraps the post-commit hook of builds with the post-commit timeout of
+// their annotation in a script that runs the hook with timeout, so the image the hook runs in
+// needs /bin/sh and timeout. A script keeps running with `/bin/sh -ic` and its arguments, and a
+// command becomes the arguments of the wrapping script. Hooks that pass arguments to the image
+// entrypoint cannot be wrapped. An invalid or unsupported timeout is reported in an event on the
+// build and ignored.
+func (bc *BuildController) setupPostCommitTimeout(build *buildv1.Build) {
+	value, ok := build.Annotations[buildutil.BuildPostCommitTimeoutAnnotation]
+	if !ok || !hasPostCommitHook(build) {
+		return
+	}
+	hook := &build.Spec.PostCommit
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 || (len(hook.Script) == 0 && len(hook.Command) == 0) {
+		bc.recorder.Eventf(build, corev1.EventTypeWarning, buildutil.BuildInvalidPostCommitTimeoutEventReason, buildutil.BuildInvalidPostCommitTimeoutEventMessage,
+			value, build.Namespace, build.Name)
+		return
+	}
+	klog.V(4).Infof("Setting the post-commit timeout of build %s to %d seconds", buildDesc(build), seconds)
+
+	if len(hook.Script) > 0 {
+		// in the script, $0 is "/bin/sh" and the positional arguments are the arguments of the hook
+		hook.Script = postCommitTimeoutScript(seconds, fmt.Sprintf(`/bin/sh -ic %s "$0" "$@"`, shellQuote(hook.Script)))
+		return
+	}
+	hook.Args = append(append([]string{}, hook.Command...), hook.Args...)
+	hook.Command = nil
+	hook.Script = postCommitTimeoutScript(seconds, `"$@"`)
+}
+
+// setBuildPostCommitTimeout fails builds the builder reported as failed because of their
+// post-commit hook with the PostCommitHookTimeout reason, instead of the generic hook failure, when
+// the log tail of the build container shows that timeout terminated the hook.
+func setBuildPostCommitTimeout(build *buildv1.Build, pod *corev1.Pod, update *buildUpdate) {
+	phase := build.Status.Phase
+	if update.phase != nil {
+		phase = *update.phase
+	}
+	if phase != buildv1.BuildPhaseFailed || build.Status.Reason != buildv1.StatusReasonPostCommitHookFailed || len(buildContainerName(build)) == 0 {
+		return
+	}
+	matches := postCommitTimeoutPattern.FindAllStringSubmatch(buildContainerLogTail(build, pod), -1)
+	if len(matches) == 0 {
+		return
+	}
+	seconds, err := strconv.ParseInt(matches[len(matches)-1][1], 10, 64)
+	if err != nil {
+		return
+	}
+
+	update.setReason(buildutil.StatusReasonPostCommitHookTimeout)
+	update.setMessage(fmt.Sprintf("The post-commit hook did not complete within %d seconds. The output image was built but not pushed.", seconds))
+}
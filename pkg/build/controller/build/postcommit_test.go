@@ -0,0 +1,182 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+func TestCreateBuildPodPostCommitTimeout(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotation    string
+		hook          buildv1.BuildPostCommitSpec
+		expectedHook  buildv1.BuildPostCommitSpec
+		expectedEvent bool
+	}{
+		{
+			name:       "script",
+			annotation: "300",
+			hook:       buildv1.BuildPostCommitSpec{Script: "rake test $1", Args: []string{"--verbose"}},
+			expectedHook: buildv1.BuildPostCommitSpec{
+				Script: `timeout 300 /bin/sh -ic 'rake test $1' "$0" "$@"; status=$?; if [ $status -eq 124 ]; then echo "The post-commit hook did not complete within 300 seconds"; fi; exit $status`,
+				Args:   []string{"--verbose"},
+			},
+		},
+		{
+			name:       "script with quotes",
+			annotation: "60",
+			hook:       buildv1.BuildPostCommitSpec{Script: "echo 'done'"},
+			expectedHook: buildv1.BuildPostCommitSpec{
+				Script: `timeout 60 /bin/sh -ic 'echo '\''done'\''' "$0" "$@"; status=$?; if [ $status -eq 124 ]; then echo "The post-commit hook did not complete within 60 seconds"; fi; exit $status`,
+			},
+		},
+		{
+			name:       "command",
+			annotation: "300",
+			hook:       buildv1.BuildPostCommitSpec{Command: []string{"rake", "test"}, Args: []string{"--verbose"}},
+			expectedHook: buildv1.BuildPostCommitSpec{
+				Script: `timeout 300 "$@"; status=$?; if [ $status -eq 124 ]; then echo "The post-commit hook did not complete within 300 seconds"; fi; exit $status`,
+				Args:   []string{"rake", "test", "--verbose"},
+			},
+		},
+		{
+			name:         "no post-commit timeout",
+			hook:         buildv1.BuildPostCommitSpec{Script: "make test"},
+			expectedHook: buildv1.BuildPostCommitSpec{Script: "make test"},
+		},
+		{
+			name:       "no post-commit hook",
+			annotation: "300",
+		},
+		{
+			name:          "entrypoint arguments",
+			annotation:    "300",
+			hook:          buildv1.BuildPostCommitSpec{Args: []string{"rake", "test"}},
+			expectedHook:  buildv1.BuildPostCommitSpec{Args: []string{"rake", "test"}},
+			expectedEvent: true,
+		},
+		{
+			name:          "invalid post-commit timeout",
+			annotation:    "5m",
+			hook:          buildv1.BuildPostCommitSpec{Script: "make test"},
+			expectedHook:  buildv1.BuildPostCommitSpec{Script: "make test"},
+			expectedEvent: true,
+		},
+		{
+			name:          "zero post-commit timeout",
+			annotation:    "0",
+			hook:          buildv1.BuildPostCommitSpec{Script: "make test"},
+			expectedHook:  buildv1.BuildPostCommitSpec{Script: "make test"},
+			expectedEvent: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+			recorder := record.NewFakeRecorder(10)
+			bc.recorder = recorder
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.PostCommit = tc.hook
+			if len(tc.annotation) > 0 {
+				build.Annotations[buildutil.BuildPostCommitTimeoutAnnotation] = tc.annotation
+			}
+			if _, err := bc.createBuildPod(build); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(build.Spec.PostCommit, tc.hook) {
+				t.Errorf("expected the post-commit hook of the build to be unchanged, got %#v", build.Spec.PostCommit)
+			}
+			pod, err := kubeClient.CoreV1().Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			podBuild := &buildv1.Build{}
+			for _, e := range pod.Spec.Containers[0].Env {
+				if e.Name == "BUILD" {
+					if err := json.Unmarshal([]byte(e.Value), podBuild); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				}
+			}
+			if !reflect.DeepEqual(podBuild.Spec.PostCommit, tc.expectedHook) {
+				t.Errorf("expected the builder to run the post-commit hook %#v, got %#v", tc.expectedHook, podBuild.Spec.PostCommit)
+			}
+
+			invalidTimeoutEvent := false
+			for len(recorder.Events) > 0 {
+				if strings.Contains(<-recorder.Events, buildutil.BuildInvalidPostCommitTimeoutEventReason) {
+					invalidTimeoutEvent = true
+				}
+			}
+			if invalidTimeoutEvent != tc.expectedEvent {
+				t.Errorf("expected an invalid post-commit timeout event: %v, got %v", tc.expectedEvent, invalidTimeoutEvent)
+			}
+		})
+	}
+}
+
+func TestSetBuildPostCommitTimeout(t *testing.T) {
+	tests := []struct {
+		name            string
+		reason          buildv1.StatusReason
+		log             string
+		expectedReason  buildv1.StatusReason
+		expectedMessage string
+	}{
+		{
+			name:            "timeout",
+			reason:          buildv1.StatusReasonPostCommitHookFailed,
+			log:             "Running post commit hook ...\nThe post-commit hook did not complete within 300 seconds\nerror: build error: container \"openshift_s2i-build_app-1_namespace_post-commit_1a2b3c4d\" returned non-zero exit code: 124",
+			expectedReason:  buildutil.StatusReasonPostCommitHookTimeout,
+			expectedMessage: "The post-commit hook did not complete within 300 seconds. The output image was built but not pushed.",
+		},
+		{
+			name:            "failed hook",
+			reason:          buildv1.StatusReasonPostCommitHookFailed,
+			log:             "Running post commit hook ...\n1 examples, 1 failures\nerror: build error: container \"openshift_s2i-build_app-1_namespace_post-commit_1a2b3c4d\" returned non-zero exit code: 1",
+			expectedReason:  buildv1.StatusReasonPostCommitHookFailed,
+			expectedMessage: "Build failed because of post commit hook.",
+		},
+		{
+			name:            "other failure",
+			reason:          buildv1.StatusReasonGenericBuildFailed,
+			log:             "The post-commit hook did not complete within 300 seconds",
+			expectedReason:  buildv1.StatusReasonGenericBuildFailed,
+			expectedMessage: "Build failed because of post commit hook.",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseFailed, buildv1.BuildOutput{}))
+			build.Status.Reason = tc.reason
+			build.Status.Message = "Build failed because of post commit hook."
+			pod := terminatedBuildPod(build, strategy.DockerBuild, tc.log)
+			pod.Status.Phase = corev1.PodFailed
+
+			update := &buildUpdate{}
+			setBuildCompletionData(build, pod, update)
+			update.apply(build)
+
+			if build.Status.Reason != tc.expectedReason {
+				t.Errorf("expected reason %s, got %s", tc.expectedReason, build.Status.Reason)
+			}
+			if build.Status.Message != tc.expectedMessage {
+				t.Errorf("expected message %q, got %q", tc.expectedMessage, build.Status.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package build
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/library-go/pkg/image/reference"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// pushErrors match the errors of registries and of the image push client that the builder logs
+// when pushing the output image fails, most specific first. Registry errors are the error codes of
+// the distribution API, whose HTTP status is implied when the log does not include it.
+var pushErrors = []struct {
+	pattern    *regexp.Regexp
+	category   string
+	statusCode int
+}{
+	{regexp.MustCompile(`(?i)\bunauthorized\b|authentication required`), buildutil.PushUnauthorizedCategory, http.StatusUnauthorized},
+	{regexp.MustCompile(`(?i)\bdenied\b`), buildutil.PushUnauthorizedCategory, http.StatusForbidden},
+	{regexp.MustCompile(`(?i)unexpected http status: 5\d\d|i/o timeout|connection refused|no such host|tls handshake timeout`), buildutil.PushRegistryUnavailableCategory, 0},
+	{regexp.MustCompile(`(?i)blob upload (invalid|unknown)|uploading layer`), buildutil.PushBlobUploadFailedCategory, 0},
+	{regexp.MustCompile(`(?i)manifest invalid|uploading manifest`), buildutil.PushManifestInvalidCategory, 0},
+}
+
+// httpStatusPattern matches the HTTP status of failed registry requests in the errors of the image
+// push client.
+var httpStatusPattern = regexp.MustCompile(`(?i)http status: (\d{3})`)
+
+// pushFailure is why pushing the output image of a build failed.
+type pushFailure struct {
+	// Category is the error category, one of the push failure categories of buildutil.
+	Category string
+	// StatusCode is the HTTP status of the failed registry request, if known.
+	StatusCode int
+	// Error is the logged error.
+	Error string
+}
+
+// logPushFailure returns the push failure of the last line of the build log tail that matches one
+// of the pushErrors. Failures that match none of them are of the unknown category.
+func logPushFailure(log string) pushFailure {
+	lines := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		for _, e := range pushErrors {
+			if !e.pattern.MatchString(line) {
+				continue
+			}
+			failure := pushFailure{Category: e.category, StatusCode: e.statusCode, Error: line}
+			if m := httpStatusPattern.FindStringSubmatch(line); m != nil {
+				failure.StatusCode, _ = strconv.Atoi(m[1])
+			}
+			return failure
+		}
+	}
+	return pushFailure{Category: buildutil.PushUnknownCategory}
+}
+
+// buildContainerLogTail returns the tail of the log of the terminated build container of a build.
+// With the FallbackToLogsOnError termination message policy, the termination message of the failed
+// build container is the tail of its log. The log snippet of the build is used without a pod.
+func buildContainerLogTail(build *buildv1.Build, pod *corev1.Pod) string {
+	log := build.Status.LogSnippet
+	if pod == nil {
+		return log
+	}
+	containerName := buildContainerName(build)
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Terminated != nil && len(status.State.Terminated.Message) > 0 {
+			log = status.State.Terminated.Message
+		}
+	}
+	return log
+}
+
+// setBuildPushFailure sets the PushFailed condition of builds the builder reported as failed to
+// push their output image, with the registry host, the HTTP status and the error category found in
+// the log tail of the build container. Builds the registry refused the credentials of fail with the
+// PushUnauthorized reason instead of the generic push failure.
+func setBuildPushFailure(build *buildv1.Build, pod *corev1.Pod, update *buildUpdate) {
+	phase := build.Status.Phase
+	if update.phase != nil {
+		phase = *update.phase
+	}
+	containerName := buildContainerName(build)
+	if phase != buildv1.BuildPhaseFailed || build.Status.Reason != buildv1.StatusReasonPushImageToRegistryFailed || len(containerName) == 0 {
+		return
+	}
+	failure := logPushFailure(buildContainerLogTail(build, pod))
+
+	registry := "unknown"
+	if ref, err := reference.Parse(build.Status.OutputDockerImageReference); err == nil && len(ref.Registry) > 0 {
+		registry = ref.Registry
+	}
+	message := fmt.Sprintf("Pushing the output image to registry %s failed", registry)
+	if failure.StatusCode > 0 {
+		message = fmt.Sprintf("%s with HTTP status %d", message, failure.StatusCode)
+	}
+	if len(failure.Error) > 0 {
+		message = fmt.Sprintf("%s: %s", message, failure.Error)
+	}
+	update.setCondition(buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionPushFailed,
+		Status:             corev1.ConditionTrue,
+		Reason:             failure.Category,
+		Message:            strings.TrimSuffix(message, ".") + ".",
+		LastUpdateTime:     metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+	})
+	if failure.Category == buildutil.PushUnauthorizedCategory {
+		update.setReason(buildutil.StatusReasonPushUnauthorized)
+		update.setMessage(fmt.Sprintf("The registry %s refused the credentials of the build.", registry))
+	}
+}
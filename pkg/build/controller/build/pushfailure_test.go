@@ -0,0 +1,111 @@
+package build
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+func TestSetBuildPushFailure(t *testing.T) {
+	const pushing = "Successfully built 4b825dc642cb\nPushing image registry.io/namespace/app:latest ...\n"
+	tests := []struct {
+		name             string
+		log              string
+		buildReason      buildv1.StatusReason
+		expectedCategory string
+		expectedMessage  string
+		expectedReason   buildv1.StatusReason
+	}{
+		{
+			name:             "unauthorized",
+			log:              pushing + "error: build error: Failed to push image: unauthorized: authentication required",
+			expectedCategory: buildutil.PushUnauthorizedCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed with HTTP status 401: error: build error: Failed to push image: unauthorized: authentication required.",
+			expectedReason:   buildutil.StatusReasonPushUnauthorized,
+		},
+		{
+			name:             "forbidden",
+			log:              pushing + "error: build error: Failed to push image: denied: requested access to the resource is denied",
+			expectedCategory: buildutil.PushUnauthorizedCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed with HTTP status 403: error: build error: Failed to push image: denied: requested access to the resource is denied.",
+			expectedReason:   buildutil.StatusReasonPushUnauthorized,
+		},
+		{
+			name:             "blob upload",
+			log:              pushing + "error: build error: Failed to push image: writing blob: uploading layer chunked: blob upload invalid",
+			expectedCategory: buildutil.PushBlobUploadFailedCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed: error: build error: Failed to push image: writing blob: uploading layer chunked: blob upload invalid.",
+			expectedReason:   buildv1.StatusReasonPushImageToRegistryFailed,
+		},
+		{
+			name:             "invalid manifest",
+			log:              pushing + "error: build error: Failed to push image: uploading manifest latest to registry.io/namespace/app: manifest invalid: manifest invalid",
+			expectedCategory: buildutil.PushManifestInvalidCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed: error: build error: Failed to push image: uploading manifest latest to registry.io/namespace/app: manifest invalid: manifest invalid.",
+			expectedReason:   buildv1.StatusReasonPushImageToRegistryFailed,
+		},
+		{
+			name:             "registry outage",
+			log:              pushing + "error: build error: Failed to push image: uploading manifest latest to registry.io/namespace/app: received unexpected HTTP status: 503 Service Unavailable",
+			expectedCategory: buildutil.PushRegistryUnavailableCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed with HTTP status 503: error: build error: Failed to push image: uploading manifest latest to registry.io/namespace/app: received unexpected HTTP status: 503 Service Unavailable.",
+			expectedReason:   buildv1.StatusReasonPushImageToRegistryFailed,
+		},
+		{
+			name:             "no response from the registry",
+			log:              pushing + "error: build error: Failed to push image: pinging container registry registry.io: Get \"https://registry.io/v2/\": dial tcp 10.0.0.1:443: i/o timeout\n",
+			expectedCategory: buildutil.PushRegistryUnavailableCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed: error: build error: Failed to push image: pinging container registry registry.io: Get \"https://registry.io/v2/\": dial tcp 10.0.0.1:443: i/o timeout.",
+			expectedReason:   buildv1.StatusReasonPushImageToRegistryFailed,
+		},
+		{
+			name:             "unrecognized error",
+			log:              pushing + "error: build error: Failed to push image: something went wrong",
+			expectedCategory: buildutil.PushUnknownCategory,
+			expectedMessage:  "Pushing the output image to registry registry.io failed.",
+			expectedReason:   buildv1.StatusReasonPushImageToRegistryFailed,
+		},
+		{
+			name:           "other build failure",
+			log:            "error: build error: unauthorized: authentication required",
+			buildReason:    buildv1.StatusReasonPullBuilderImageFailed,
+			expectedReason: buildv1.StatusReasonPullBuilderImageFailed,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := completedDigestBuild(dockerStrategy(mockBuild(buildv1.BuildPhaseFailed, buildv1.BuildOutput{})))
+			build.Status.Reason = buildv1.StatusReasonPushImageToRegistryFailed
+			if len(tc.buildReason) > 0 {
+				build.Status.Reason = tc.buildReason
+			}
+			pod := terminatedBuildPod(build, strategy.DockerBuild, tc.log)
+			pod.Status.Phase = corev1.PodFailed
+
+			update := &buildUpdate{}
+			setBuildCompletionData(build, pod, update)
+			update.apply(build)
+
+			if build.Status.Reason != tc.expectedReason {
+				t.Errorf("expected reason %s, got %s", tc.expectedReason, build.Status.Reason)
+			}
+			condition := findBuildCondition(build, buildutil.BuildConditionPushFailed)
+			if len(tc.expectedCategory) == 0 {
+				if condition != nil {
+					t.Errorf("expected no push failed condition, got %#v", condition)
+				}
+				return
+			}
+			if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != tc.expectedCategory {
+				t.Fatalf("expected a push failed condition with category %s, got %#v", tc.expectedCategory, condition)
+			}
+			if condition.Message != tc.expectedMessage {
+				t.Errorf("expected message %q, got %q", tc.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
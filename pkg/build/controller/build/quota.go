@@ -0,0 +1,111 @@
+package build
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+var (
+	// exceededQuotaPattern matches the message the resource quota admission denies pods with when
+	// they exceed a quota, such as
+	// `exceeded quota: compute-resources, requested: limits.cpu=2,pods=1, used: ..., limited: ...`.
+	exceededQuotaPattern = regexp.MustCompile(`exceeded quota: ([^,]+), requested: (.*?), used: `)
+	// failedQuotaPattern matches the message the resource quota admission denies pods with when
+	// they do not set a resource a quota constrains, such as
+	// `failed quota: compute-resources: must specify limits.cpu,limits.memory`.
+	failedQuotaPattern = regexp.MustCompile(`failed quota: ([^:]+): must specify (.*)$`)
+)
+
+// quotaExceededError is returned when a resource quota denied the build pod of a build. The build
+// is retried with backoff for as long as the quota denies the build pod.
+type quotaExceededError struct {
+	quota     string
+	resources []string
+	err       error
+}
+
+func (e *quotaExceededError) Error() string {
+	return fmt.Sprintf("failed to create build pod: %v", e.err)
+}
+
+// message describes the quota and the resources it denied the build pod for.
+func (e *quotaExceededError) message() string {
+	if len(e.quota) == 0 {
+		return "The build pod was denied by a resource quota."
+	}
+	return fmt.Sprintf("The build pod was denied by resource quota %s, which is exceeded for %s.", e.quota, strings.Join(e.resources, ", "))
+}
+
+// isQuotaExceeded returns true if the error is a quotaExceededError.
+func isQuotaExceeded(err error) bool {
+	_, ok := err.(*quotaExceededError)
+	return ok
+}
+
+// parseQuotaExceeded returns a quotaExceededError with the name of the quota and the exceeded
+// resources if a resource quota denied the creation of a build pod with the error, or nil.
+func parseQuotaExceeded(err error) *quotaExceededError {
+	if !errors.IsForbidden(err) {
+		return nil
+	}
+	message := err.Error()
+	for _, pattern := range []*regexp.Regexp{exceededQuotaPattern, failedQuotaPattern} {
+		m := pattern.FindStringSubmatch(message)
+		if m == nil {
+			continue
+		}
+		quotaErr := &quotaExceededError{quota: strings.TrimSpace(m[1]), err: err}
+		for _, resource := range strings.Split(m[2], ",") {
+			name, _, _ := strings.Cut(strings.TrimSpace(resource), "=")
+			if len(name) > 0 {
+				quotaErr.resources = append(quotaErr.resources, name)
+			}
+		}
+		return quotaErr
+	}
+	if strings.Contains(message, "exceeded quota") {
+		return &quotaExceededError{err: err}
+	}
+	return nil
+}
+
+// setQuotaExceeded sets the quota exceeded condition of a build whose build pod was denied by a
+// resource quota, unless the build already has the same condition.
+func setQuotaExceeded(build *buildv1.Build, update *buildUpdate, quotaErr *quotaExceededError) {
+	message := quotaErr.message()
+	existing := findBuildCondition(build, buildutil.BuildConditionQuotaExceeded)
+	if existing != nil && existing.Status == corev1.ConditionTrue && existing.Message == message {
+		return
+	}
+	update.setCondition(newQuotaExceededCondition(corev1.ConditionTrue, buildutil.ResourceQuotaExceededReason, message))
+}
+
+// clearQuotaExceeded marks a build whose build pod was denied by a resource quota before as no
+// longer blocked once its build pod is created.
+func clearQuotaExceeded(build *buildv1.Build, update *buildUpdate) {
+	existing := findBuildCondition(build, buildutil.BuildConditionQuotaExceeded)
+	if existing == nil || existing.Status != corev1.ConditionTrue || update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		return
+	}
+	update.setCondition(newQuotaExceededCondition(corev1.ConditionFalse, buildutil.BuildPodCreatedReason, "The build pod was created."))
+}
+
+func newQuotaExceededCondition(status corev1.ConditionStatus, reason, message string) buildv1.BuildCondition {
+	now := metav1.Now()
+	return buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionQuotaExceeded,
+		Status:             status,
+		LastUpdateTime:     now,
+		LastTransitionTime: now,
+		Reason:             reason,
+		Message:            message,
+	}
+}
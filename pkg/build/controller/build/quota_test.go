@@ -0,0 +1,141 @@
+package build
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/component-base/metrics/testutil"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	metrics "github.com/openshift/openshift-controller-manager/pkg/build/metrics/prometheus"
+)
+
+func TestParseQuotaExceeded(t *testing.T) {
+	forbidden := func(message string) error {
+		return errors.NewForbidden(corev1.Resource("pods"), "data-build-build", fmt.Errorf("%s", message))
+	}
+	tests := []struct {
+		name              string
+		err               error
+		expectedQuota     string
+		expectedResources []string
+		notQuota          bool
+	}{
+		{
+			name:              "exceeded quota",
+			err:               forbidden("exceeded quota: compute-resources, requested: limits.cpu=2,pods=1, used: limits.cpu=3,pods=4, limited: limits.cpu=4,pods=4"),
+			expectedQuota:     "compute-resources",
+			expectedResources: []string{"limits.cpu", "pods"},
+		},
+		{
+			name:              "failed quota",
+			err:               forbidden("failed quota: compute-resources: must specify limits.cpu,limits.memory"),
+			expectedQuota:     "compute-resources",
+			expectedResources: []string{"limits.cpu", "limits.memory"},
+		},
+		{
+			name: "unparsed quota message",
+			err:  forbidden("exceeded quota: compute-resources"),
+		},
+		{
+			name:     "forbidden by another admission plugin",
+			err:      forbidden("unable to validate against any security context constraint"),
+			notQuota: true,
+		},
+		{
+			name:     "not forbidden",
+			err:      fmt.Errorf("exceeded quota: compute-resources, requested: pods=1, used: pods=4, limited: pods=4"),
+			notQuota: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			quotaErr := parseQuotaExceeded(tc.err)
+			if tc.notQuota {
+				if quotaErr != nil {
+					t.Fatalf("expected no quota error, got %v", quotaErr)
+				}
+				return
+			}
+			if quotaErr == nil {
+				t.Fatalf("expected a quota error")
+			}
+			if quotaErr.quota != tc.expectedQuota || !reflect.DeepEqual(quotaErr.resources, tc.expectedResources) {
+				t.Errorf("expected quota %q exceeded for %v, got %q exceeded for %v", tc.expectedQuota, tc.expectedResources, quotaErr.quota, quotaErr.resources)
+			}
+		})
+	}
+}
+
+func TestQuotaExceededCondition(t *testing.T) {
+	metrics.Register()
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap)
+	quotaExceeded := true
+	kubeClient.(*fake.Clientset).PrependReactor("create", "pods", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+		if !quotaExceeded {
+			return false, nil, nil
+		}
+		return true, nil, errors.NewForbidden(corev1.Resource("pods"), "data-build-build",
+			fmt.Errorf("exceeded quota: compute-resources, requested: pods=1, used: pods=4, limited: pods=4"))
+	})
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+
+	attemptsBefore, err := testutil.GetCounterMetricValue(metrics.QuotaBlockedPodCreations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	update, err := bc.handleNewBuild(build, nil)
+	if !isQuotaExceeded(err) {
+		t.Fatalf("expected a quota exceeded error, got %v", err)
+	}
+	if update.phase != nil || update.reason == nil || *update.reason != buildv1.StatusReasonCannotCreateBuildPod {
+		t.Errorf("expected the build to stay new, got %v", update)
+	}
+	condition := findBuildCondition(&buildv1.Build{Status: buildv1.BuildStatus{Conditions: update.conditions}}, buildutil.BuildConditionQuotaExceeded)
+	if condition == nil || condition.Status != corev1.ConditionTrue || condition.Reason != buildutil.ResourceQuotaExceededReason {
+		t.Fatalf("expected the quota exceeded condition, got %v", update.conditions)
+	}
+	if expected := "The build pod was denied by resource quota compute-resources, which is exceeded for pods."; condition.Message != expected {
+		t.Errorf("expected message %q, got %q", expected, condition.Message)
+	}
+	if attempts, _ := testutil.GetCounterMetricValue(metrics.QuotaBlockedPodCreations); attempts != attemptsBefore+1 {
+		t.Errorf("expected %v quota blocked pod creations, got %v", attemptsBefore+1, attempts)
+	}
+	update.apply(build)
+
+	// the condition is not updated while the quota keeps denying the build pod
+	update, err = bc.handleNewBuild(build, nil)
+	if !isQuotaExceeded(err) {
+		t.Fatalf("expected a quota exceeded error, got %v", err)
+	}
+	if len(update.conditions) != 0 {
+		t.Errorf("expected no update of the quota exceeded condition, got %v", update.conditions)
+	}
+	if attempts, _ := testutil.GetCounterMetricValue(metrics.QuotaBlockedPodCreations); attempts != attemptsBefore+2 {
+		t.Errorf("expected %v quota blocked pod creations, got %v", attemptsBefore+2, attempts)
+	}
+
+	// the quota admits the build pod
+	quotaExceeded = false
+	update, err = bc.handleNewBuild(build, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+		t.Fatalf("expected the build pod to be created, got %v", update)
+	}
+	update.apply(build)
+	condition = findBuildCondition(build, buildutil.BuildConditionQuotaExceeded)
+	if condition == nil || condition.Status != corev1.ConditionFalse || condition.Reason != buildutil.BuildPodCreatedReason {
+		t.Errorf("expected the quota exceeded condition to be cleared, got %v", condition)
+	}
+}
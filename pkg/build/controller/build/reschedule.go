@@ -0,0 +1,116 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// podRescheduleAttempt returns the number of build pods created for the build after its build pod
+// was lost.
+func podRescheduleAttempt(build *buildv1.Build) int {
+	attempt, err := strconv.Atoi(build.Annotations[buildutil.BuildPodRescheduleAttemptAnnotation])
+	if err != nil || attempt < 0 {
+		return 0
+	}
+	return attempt
+}
+
+// reschedulesLostPod returns true if the build gets a new build pod when its build pod is lost,
+// as requested by the BuildRescheduleLostPodAnnotation of the build or else by the build defaults.
+func reschedulesLostPod(build *buildv1.Build, rescheduleLostPods bool) bool {
+	value, ok := build.Annotations[buildutil.BuildRescheduleLostPodAnnotation]
+	if !ok {
+		return rescheduleLostPods
+	}
+	reschedule, err := strconv.ParseBool(value)
+	if err != nil {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q of build %s: %v", buildutil.BuildRescheduleLostPodAnnotation, value, buildDesc(build), err)
+		return rescheduleLostPods
+	}
+	return reschedule
+}
+
+// reachedPushStage returns true if the build may have pushed layers of its output image: a second
+// build pod would push the image again.
+func reachedPushStage(build *buildv1.Build) bool {
+	for _, stage := range build.Status.Stages {
+		if stage.Name == buildv1.StagePushImage {
+			return true
+		}
+	}
+	if build.Status.Output.To != nil && len(build.Status.Output.To.ImageDigest) > 0 {
+		return true
+	}
+	_, pushed := build.Annotations[buildutil.BuildPushedImageReferenceAnnotation]
+	return pushed
+}
+
+// isPodReschedule returns true if the update moves a running build whose build pod was lost back
+// to the New phase.
+func isPodReschedule(build *buildv1.Build, update *buildUpdate) bool {
+	return build.Status.Phase == buildv1.BuildPhaseRunning && update.phase != nil && *update.phase == buildv1.BuildPhaseNew && update.rescheduleAttempt != nil
+}
+
+// rescheduleLostPod returns an update that moves a running build whose build pod was deleted, such
+// as when its node failed, back to the New phase for the build controller to create a new build
+// pod. It returns nil if the build does not reschedule lost pods, already reached the push stage,
+// or exhausted its reschedule attempts, and must fail instead.
+//
+// The configMaps created for the lost build pod are deleted first: the build controls them, so
+// they outlive the pod and would keep the new build pod from creating its own.
+func (bc *BuildController) rescheduleLostPod(build *buildv1.Build) (*buildUpdate, error) {
+	defaults := bc.defaults()
+	if build.Status.Phase != buildv1.BuildPhaseRunning || !reschedulesLostPod(build, defaults.RescheduleLostPods) {
+		return nil, nil
+	}
+	if reachedPushStage(build) {
+		klog.V(2).Infof("Not rescheduling the lost build pod of build %s because the build reached the push stage", buildDesc(build))
+		return nil, nil
+	}
+	attempt := podRescheduleAttempt(build) + 1
+	if attempt > defaults.MaxPodReschedules {
+		klog.V(2).Infof("Not rescheduling the lost build pod of build %s after %d attempts", buildDesc(build), defaults.MaxPodReschedules)
+		return nil, nil
+	}
+	if err := bc.deleteBuildPodConfigMaps(build); err != nil {
+		return nil, err
+	}
+
+	klog.V(2).Infof("Rescheduling the lost build pod of build %s (attempt %d of %d)", buildDesc(build), attempt, defaults.MaxPodReschedules)
+	bc.recorder.Eventf(build, corev1.EventTypeWarning, buildutil.BuildPodRescheduledEventReason, buildutil.BuildPodRescheduledEventMessage,
+		build.Namespace, build.Name, attempt, defaults.MaxPodReschedules)
+	update := transitionToPhase(buildv1.BuildPhaseNew, buildv1.StatusReasonBuildPodDeleted,
+		fmt.Sprintf("The pod for this build was deleted before the build completed, creating a new build pod (attempt %d of %d).", attempt, defaults.MaxPodReschedules))
+	update.setRescheduleAttempt(strconv.Itoa(attempt))
+	return update, nil
+}
+
+// buildPodConfigMapNames returns the names of the certificate authority, proxy certificate
+// authority and build system configMaps created for the build pod of the build.
+func buildPodConfigMapNames(build *buildv1.Build) []string {
+	return []string{
+		buildutil.GetBuildCAConfigMapName(build),
+		buildutil.GetBuildGlobalCAConfigMapName(build),
+		buildutil.GetBuildSystemConfigMapName(build),
+	}
+}
+
+// deleteBuildPodConfigMaps deletes the configMaps created for the build pod of the build.
+func (bc *BuildController) deleteBuildPodConfigMaps(build *buildv1.Build) error {
+	for _, name := range buildPodConfigMapNames(build) {
+		err := bc.configMapClient.ConfigMaps(build.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete configMap %s/%s of the lost build pod of build %s: %v", build.Namespace, name, buildDesc(build), err)
+		}
+	}
+	return nil
+}
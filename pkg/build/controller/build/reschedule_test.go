@@ -0,0 +1,189 @@
+package build
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgotesting "k8s.io/client-go/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildv1client "github.com/openshift/client-go/build/clientset/versioned/fake"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
+)
+
+func TestHandleBuildWithLostPod(t *testing.T) {
+	tests := []struct {
+		name               string
+		rescheduleLostPods bool
+		annotations        map[string]string
+		stages             []buildv1.StageName
+		imageDigest        string
+		expectedPhase      buildv1.BuildPhase
+		expectedAttempt    string
+	}{
+		{
+			name:          "rescheduling disabled",
+			expectedPhase: buildv1.BuildPhaseError,
+		},
+		{
+			name:               "rescheduled by the build defaults",
+			rescheduleLostPods: true,
+			stages:             []buildv1.StageName{buildv1.StageFetchInputs, buildv1.StagePullImages, buildv1.StageBuild},
+			expectedPhase:      buildv1.BuildPhaseNew,
+			expectedAttempt:    "1",
+		},
+		{
+			name:            "rescheduled by the build annotation",
+			annotations:     map[string]string{buildutil.BuildRescheduleLostPodAnnotation: "true", buildutil.BuildPodRescheduleAttemptAnnotation: "1"},
+			expectedPhase:   buildv1.BuildPhaseNew,
+			expectedAttempt: "2",
+		},
+		{
+			name:               "opted out by the build annotation",
+			rescheduleLostPods: true,
+			annotations:        map[string]string{buildutil.BuildRescheduleLostPodAnnotation: "false"},
+			expectedPhase:      buildv1.BuildPhaseError,
+		},
+		{
+			name:               "post commit stage",
+			rescheduleLostPods: true,
+			stages:             []buildv1.StageName{buildv1.StageBuild, buildv1.StagePostCommit},
+			expectedPhase:      buildv1.BuildPhaseNew,
+			expectedAttempt:    "1",
+		},
+		{
+			name:               "push stage reached",
+			rescheduleLostPods: true,
+			stages:             []buildv1.StageName{buildv1.StageBuild, buildv1.StagePushImage},
+			expectedPhase:      buildv1.BuildPhaseError,
+		},
+		{
+			name:               "image pushed",
+			rescheduleLostPods: true,
+			imageDigest:        "sha256:1234",
+			expectedPhase:      buildv1.BuildPhaseError,
+		},
+		{
+			name:               "attempts exhausted",
+			rescheduleLostPods: true,
+			annotations:        map[string]string{buildutil.BuildPodRescheduleAttemptAnnotation: "2"},
+			expectedPhase:      buildv1.BuildPhaseError,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+			for k, v := range tc.annotations {
+				build.Annotations[k] = v
+			}
+			for _, stage := range tc.stages {
+				build.Status.Stages = append(build.Status.Stages, buildv1.StageInfo{Name: stage})
+			}
+			if len(tc.imageDigest) > 0 {
+				build.Status.Output.To = &buildv1.BuildStatusOutputTo{ImageDigest: tc.imageDigest}
+			}
+
+			var patchedBuild *buildv1.Build
+			buildClient := fakeBuildClient(build)
+			buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds",
+				func(action clientgotesting.Action) (bool, runtime.Object, error) {
+					var err error
+					patchedBuild, err = applyBuildPatch(build, action.(clientgotesting.PatchActionImpl).Patch)
+					if err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+					return true, patchedBuild, nil
+				})
+			bc := newFakeBuildController(buildClient, nil, fakeKubeExternalClientSet(registryCAConfigMap), nil, nil)
+			defer bc.stop()
+			bc.buildDefaults.RescheduleLostPods = tc.rescheduleLostPods
+			bc.buildDefaults.MaxPodReschedules = 2
+
+			if err := bc.handleBuild(build); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if patchedBuild == nil {
+				t.Fatalf("expected the build to be updated")
+			}
+			if patchedBuild.Status.Phase != tc.expectedPhase || patchedBuild.Status.Reason != buildv1.StatusReasonBuildPodDeleted {
+				t.Errorf("expected phase %s with reason %s, got %s with reason %s", tc.expectedPhase, buildv1.StatusReasonBuildPodDeleted, patchedBuild.Status.Phase, patchedBuild.Status.Reason)
+			}
+			if attempt := patchedBuild.Annotations[buildutil.BuildPodRescheduleAttemptAnnotation]; len(tc.expectedAttempt) > 0 && attempt != tc.expectedAttempt {
+				t.Errorf("expected reschedule attempt %s, got %q", tc.expectedAttempt, attempt)
+			}
+			if tc.expectedPhase == buildv1.BuildPhaseNew && patchedBuild.Status.CompletionTimestamp != nil {
+				t.Errorf("expected the rescheduled build not to be completed")
+			}
+		})
+	}
+}
+
+func TestHandleBuildWithLostPodCreatesNewPod(t *testing.T) {
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseRunning, buildv1.BuildOutput{}))
+	build.UID = types.UID("build-uid")
+	build.Annotations[buildutil.BuildRescheduleLostPodAnnotation] = "true"
+	lostPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: buildutil.GetBuildPodName(build), Namespace: build.Namespace, UID: types.UID("lost-pod-uid")}}
+
+	// the configMaps of the lost build pod are kept by their build owner reference
+	var objects []runtime.Object
+	for _, name := range []string{buildutil.GetBuildCAConfigMapName(build), buildutil.GetBuildGlobalCAConfigMapName(build), buildutil.GetBuildSystemConfigMapName(build)} {
+		objects = append(objects, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       build.Namespace,
+			OwnerReferences: []metav1.OwnerReference{makeBuildPodOwnerRef(lostPod), strategy.MakeOwnerReference(build)},
+		}})
+	}
+	kubeClient := fakeKubeExternalClientSet(append(objects, registryCAConfigMap)...)
+
+	var patchedBuild *buildv1.Build
+	buildClient := fakeBuildClient(build)
+	buildClient.(*fakebuildv1client.Clientset).PrependReactor("patch", "builds",
+		func(action clientgotesting.Action) (bool, runtime.Object, error) {
+			var err error
+			patchedBuild, err = applyBuildPatch(build, action.(clientgotesting.PatchActionImpl).Patch)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			build = patchedBuild
+			return true, patchedBuild, nil
+		})
+	bc := newFakeBuildController(buildClient, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	bc.buildDefaults.MaxPodReschedules = 2
+
+	// the lost build pod is rescheduled
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patchedBuild == nil || patchedBuild.Status.Phase != buildv1.BuildPhaseNew {
+		t.Fatalf("expected the build to be moved back to New, got %#v", patchedBuild)
+	}
+
+	// a new build pod is created with new configMaps
+	patchedBuild = nil
+	if err := bc.handleBuild(build); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if patchedBuild == nil || patchedBuild.Status.Phase != buildv1.BuildPhasePending {
+		t.Fatalf("expected the build to be Pending with a new build pod, got %#v", patchedBuild)
+	}
+	pod, err := kubeClient.CoreV1().Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected a new build pod: %v", err)
+	}
+	for _, object := range objects {
+		name := object.(*corev1.ConfigMap).Name
+		cm, err := kubeClient.CoreV1().ConfigMaps(build.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("expected configMap %s to be created for the new build pod: %v", name, err)
+		}
+		if !hasBuildPodOwnerRef(pod, cm) || hasBuildPodOwnerRef(lostPod, cm) {
+			t.Errorf("expected configMap %s to be owned by the new build pod, got %v", name, cm.OwnerReferences)
+		}
+	}
+}
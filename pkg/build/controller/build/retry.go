@@ -0,0 +1,322 @@
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	sharedbuildutil "github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// buildRetryPolicy is the retry policy of a build, read from the BuildRetryPolicyAnnotation of the
+// build or of its BuildConfig.
+type buildRetryPolicy struct {
+	// MaxRetries is the number of retry builds that may be started for a failed build, counting
+	// the retries of its retries.
+	MaxRetries int `json:"maxRetries"`
+	// BackoffSeconds is the delay between the failure of a build and the start of its retry.
+	BackoffSeconds int64 `json:"backoffSeconds,omitempty"`
+	// RetryableReasons are the failure reasons of the builds that are retried. The
+	// defaultRetryableReasons are used when it is empty.
+	RetryableReasons []buildv1.StatusReason `json:"retryableReasons,omitempty"`
+}
+
+// defaultRetryableReasons are the failure reasons caused by transient infrastructure problems.
+var defaultRetryableReasons = []buildv1.StatusReason{
+	buildv1.StatusReasonBuildPodEvicted,
+	buildv1.StatusReasonCannotCreateBuildPod,
+	buildv1.StatusReasonFetchSourceFailed,
+	buildv1.StatusReasonPullBuilderImageFailed,
+	buildv1.StatusReasonPushImageToRegistryFailed,
+}
+
+// badRevisionMessages are the messages of git when the requested revision does not exist. Fetching
+// the source of such a build fails the same way on every retry.
+var badRevisionMessages = []string{
+	"did not match any file(s) known to git",
+	"reference is not a tree",
+	"unknown revision",
+	"couldn't find remote ref",
+	"not a valid object name",
+	"bad revision",
+}
+
+func parseBuildRetryPolicy(value string) (*buildRetryPolicy, error) {
+	policy := &buildRetryPolicy{}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return nil, err
+	}
+	if policy.MaxRetries < 0 {
+		return nil, fmt.Errorf("maxRetries must not be negative")
+	}
+	if policy.BackoffSeconds < 0 {
+		return nil, fmt.Errorf("backoffSeconds must not be negative")
+	}
+	return policy, nil
+}
+
+// retryPolicy returns the retry policy of the build, or nil when failed builds are not retried.
+// The policy of the build takes precedence over the policy of its BuildConfig.
+func (bc *BuildController) retryPolicy(build *buildv1.Build) *buildRetryPolicy {
+	value, ok := build.Annotations[buildutil.BuildRetryPolicyAnnotation]
+	source := "build " + buildDesc(build)
+	if !ok {
+		bcName := sharedbuildutil.ConfigNameForBuild(build)
+		if len(bcName) == 0 {
+			return nil
+		}
+		buildConfig, err := bc.buildConfigLister.BuildConfigs(build.Namespace).Get(bcName)
+		if err != nil {
+			return nil
+		}
+		if value, ok = buildConfig.Annotations[buildutil.BuildRetryPolicyAnnotation]; !ok {
+			return nil
+		}
+		source = "buildconfig " + build.Namespace + "/" + bcName
+	}
+	policy, err := parseBuildRetryPolicy(value)
+	if err != nil {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on %s: %v", buildutil.BuildRetryPolicyAnnotation, value, source, err)
+		return nil
+	}
+	if policy.MaxRetries == 0 {
+		return nil
+	}
+	return policy
+}
+
+// retryLineage returns the name of the build that started the retry chain of the build and the
+// attempt number of the build, which is 0 for the original build.
+func retryLineage(build *buildv1.Build) (string, int) {
+	origin, ok := build.Annotations[buildutil.BuildRetryOfAnnotation]
+	if !ok || len(origin) == 0 {
+		return build.Name, 0
+	}
+	attempt, err := strconv.Atoi(build.Annotations[buildutil.BuildRetryAttemptAnnotation])
+	if err != nil || attempt < 0 {
+		return origin, 0
+	}
+	return origin, attempt
+}
+
+// isBadSourceRevision returns true when the source of the build could not be fetched because the
+// requested revision does not exist.
+func isBadSourceRevision(build *buildv1.Build) bool {
+	if build.Status.Reason != buildv1.StatusReasonFetchSourceFailed {
+		return false
+	}
+	output := strings.ToLower(build.Status.Message + "\n" + build.Status.LogSnippet)
+	for _, message := range badRevisionMessages {
+		if strings.Contains(output, message) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetryBuild returns true when the failed build has to be retried under the policy.
+func shouldRetryBuild(build *buildv1.Build, policy *buildRetryPolicy) bool {
+	if policy == nil || build.Status.Cancelled {
+		return false
+	}
+	if build.Status.Phase != buildv1.BuildPhaseFailed && build.Status.Phase != buildv1.BuildPhaseError {
+		return false
+	}
+	if _, attempt := retryLineage(build); attempt >= policy.MaxRetries {
+		return false
+	}
+	if isBadSourceRevision(build) {
+		return false
+	}
+	reasons := policy.RetryableReasons
+	if len(reasons) == 0 {
+		reasons = defaultRetryableReasons
+	}
+	for _, reason := range reasons {
+		if build.Status.Reason == reason {
+			return true
+		}
+	}
+	return false
+}
+
+// retryOfIndex indexes the retry builds by namespace, original build and attempt number.
+const retryOfIndex = "retryOf"
+
+func retryOfIndexKey(namespace, origin string, attempt int) string {
+	return namespace + "/" + origin + "/" + strconv.Itoa(attempt)
+}
+
+// retryOfIndexFunc indexes the builds started as the retry of a failed build by retryOfIndexKey.
+func retryOfIndexFunc(obj interface{}) ([]string, error) {
+	build, ok := obj.(*buildv1.Build)
+	if !ok {
+		return nil, nil
+	}
+	origin, attempt := retryLineage(build)
+	if attempt == 0 {
+		return nil, nil
+	}
+	return []string{retryOfIndexKey(build.Namespace, origin, attempt)}, nil
+}
+
+// isRetried returns true when the next attempt of the retry chain of the build was started.
+func (bc *BuildController) isRetried(build *buildv1.Build) (bool, error) {
+	origin, attempt := retryLineage(build)
+	keys, err := bc.buildIndexer.IndexKeys(retryOfIndex, retryOfIndexKey(build.Namespace, origin, attempt+1))
+	return len(keys) > 0, err
+}
+
+// scheduleBuildRetry queues the retry of a completed build once the backoff of its retry policy
+// has passed since the build completed. It is called for every failed build the build informer
+// lists, so that the retries that were pending when the controller restarted are started too.
+func (bc *BuildController) scheduleBuildRetry(build *buildv1.Build) {
+	if build.Status.Phase != buildv1.BuildPhaseFailed && build.Status.Phase != buildv1.BuildPhaseError {
+		return
+	}
+	policy := bc.retryPolicy(build)
+	if !shouldRetryBuild(build, policy) {
+		return
+	}
+	if retried, err := bc.isRetried(build); err != nil || retried {
+		return
+	}
+	backoff := time.Duration(policy.BackoffSeconds) * time.Second
+	if build.Status.CompletionTimestamp != nil {
+		backoff -= bc.clock.Since(build.Status.CompletionTimestamp.Time)
+	}
+	if backoff < 0 {
+		backoff = 0
+	}
+	klog.V(4).Infof("Build %s failed with retryable reason %s, retrying in %s", buildDesc(build), build.Status.Reason, backoff)
+	bc.buildRetryQueue.AddAfter(resourceName(build.Namespace, build.Name), backoff)
+}
+
+// startedRetry returns the retry build of a failed build whose retry was started before, or nil when
+// it does not exist. It is looked up through the API, since the informer may not have seen the
+// retry yet. A retry whose lineage has not been recorded yet still carries the
+// BuildRetryStartedAnnotation with the name of the cloned build.
+func (bc *BuildController) startedRetry(build *buildv1.Build, origin string, attempt int) (*buildv1.Build, error) {
+	builds, err := bc.buildPatcher.Builds(build.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the retry of build %s: %v", buildDesc(build), err)
+	}
+	for i := range builds.Items {
+		candidate := &builds.Items[i]
+		if candidate.Name == build.Name {
+			continue
+		}
+		if o, a := retryLineage(candidate); (o == origin && a == attempt) || candidate.Annotations[buildutil.BuildRetryStartedAnnotation] == build.Name {
+			return candidate, nil
+		}
+	}
+	return nil, nil
+}
+
+// handleBuildRetry clones a failed build as the next attempt of its retry chain. The failed build
+// is marked with the BuildRetryStartedAnnotation first, so that a failed build whose retry was
+// already started is not cloned again. The clone is annotated with the name of the original build
+// and its attempt number. The server copies the annotations of the failed build to the clone over
+// the annotations of the request, so the annotations of the clone are corrected when they do not
+// carry its lineage.
+func (bc *BuildController) handleBuildRetry(build *buildv1.Build) error {
+	policy := bc.retryPolicy(build)
+	if !shouldRetryBuild(build, policy) {
+		return nil
+	}
+	origin, attempt := retryLineage(build)
+	attempt++
+
+	// the retry may already have been started before the controller restarted
+	if retried, err := bc.isRetried(build); err != nil || retried {
+		if retried {
+			klog.V(4).Infof("Build %s was already retried", buildDesc(build))
+		}
+		return err
+	}
+
+	annotations := map[string]string{
+		buildutil.BuildRetryOfAnnotation:      origin,
+		buildutil.BuildRetryAttemptAnnotation: strconv.Itoa(attempt),
+	}
+
+	var retry *buildv1.Build
+	if build.Annotations[buildutil.BuildRetryStartedAnnotation] == build.Name {
+		started, err := bc.startedRetry(build, origin, attempt)
+		if err != nil {
+			return err
+		}
+		if started != nil && len(started.Annotations[buildutil.BuildRetryStartedAnnotation]) == 0 {
+			klog.V(4).Infof("Build %s was already retried as build %s", buildDesc(build), started.Name)
+			return nil
+		}
+		retry = started
+	} else {
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": map[string]string{buildutil.BuildRetryStartedAnnotation: build.Name}},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := bc.buildPatcher.Builds(build.Namespace).Patch(context.TODO(), build.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return fmt.Errorf("failed to mark build %s as retried: %v", buildDesc(build), err)
+		}
+	}
+
+	if retry == nil {
+		requestAnnotations := map[string]string{buildutil.BuildRetryStartedAnnotation: build.Name}
+		for k, v := range annotations {
+			requestAnnotations[k] = v
+		}
+		if value, ok := build.Annotations[buildutil.BuildRetryPolicyAnnotation]; ok {
+			requestAnnotations[buildutil.BuildRetryPolicyAnnotation] = value
+		}
+		request := &buildv1.BuildRequest{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        build.Name,
+				Annotations: requestAnnotations,
+			},
+			TriggeredBy: []buildv1.BuildTriggerCause{
+				{Message: fmt.Sprintf("Retry %d of build %s", attempt, origin)},
+			},
+		}
+		cloned, err := bc.buildCloner.Builds(build.Namespace).Clone(context.TODO(), build.Name, request, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to retry build %s: %v", buildDesc(build), err)
+		}
+		retry = cloned
+	}
+
+	if o, a := retryLineage(retry); o != origin || a != attempt || len(retry.Annotations[buildutil.BuildRetryStartedAnnotation]) > 0 {
+		// the started annotation of the clone is removed once its lineage is recorded
+		lineage := map[string]interface{}{buildutil.BuildRetryStartedAnnotation: nil}
+		for k, v := range annotations {
+			lineage[k] = v
+		}
+		patch, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{"annotations": lineage},
+		})
+		if err != nil {
+			return err
+		}
+		patched, err := bc.buildPatcher.Builds(build.Namespace).Patch(context.TODO(), retry.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to annotate build %s/%s as the retry of build %s: %v", build.Namespace, retry.Name, buildDesc(build), err)
+		}
+		retry = patched
+	}
+	klog.V(4).Infof("Retried build %s as build %s (attempt %d of %d)", buildDesc(build), retry.Name, attempt, policy.MaxRetries)
+	bc.recorder.Eventf(build, corev1.EventTypeNormal, buildutil.BuildRetriedEventReason,
+		fmt.Sprintf(buildutil.BuildRetriedEventMessage, build.Namespace, build.Name, build.Status.Reason, retry.Name, attempt, policy.MaxRetries))
+	return nil
+}
@@ -0,0 +1,409 @@
+package build
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	fakebuildv1client "github.com/openshift/client-go/build/clientset/versioned/fake"
+	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func newRetryController(buildConfigs []*buildv1.BuildConfig, builds []*buildv1.Build) (*BuildController, *fakebuildv1client.Clientset) {
+	buildConfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, buildConfig := range buildConfigs {
+		buildConfigIndexer.Add(buildConfig)
+	}
+	buildIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		cache.NamespaceIndex: cache.MetaNamespaceIndexFunc,
+		retryOfIndex:         retryOfIndexFunc,
+	})
+	for _, b := range builds {
+		buildIndexer.Add(b)
+	}
+	buildClient := fakebuildv1client.NewSimpleClientset()
+	for _, b := range builds {
+		buildClient.Tracker().Add(b.DeepCopy())
+	}
+	clones := 0
+	// the server copies the annotations of the cloned build over the annotations of the request
+	buildClient.PrependReactor("create", "builds", func(action clientesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "clone" {
+			return false, nil, nil
+		}
+		request := action.(clientesting.CreateAction).GetObject().(*buildv1.BuildRequest)
+		clone := &buildv1.Build{ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("test-bc-%d", len(builds)+clones+1),
+			Namespace:   action.GetNamespace(),
+			Annotations: map[string]string{},
+		}}
+		clones++
+		for k, v := range request.Annotations {
+			clone.Annotations[k] = v
+		}
+		for _, b := range builds {
+			if b.Name == request.Name {
+				for k, v := range b.Annotations {
+					clone.Annotations[k] = v
+				}
+			}
+		}
+		return true, clone, buildClient.Tracker().Add(clone)
+	})
+	return &BuildController{
+		buildLister:       buildv1lister.NewBuildLister(buildIndexer),
+		buildIndexer:      buildIndexer,
+		buildConfigLister: buildv1lister.NewBuildConfigLister(buildConfigIndexer),
+		buildCloner:       buildClient.BuildV1(),
+		buildPatcher:      buildClient.BuildV1(),
+		buildRetryQueue:   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		recorder:          &record.FakeRecorder{},
+		clock:             clocktesting.NewFakeClock(time.Now()),
+	}, buildClient
+}
+
+func retryPolicyBuildConfig(policy string) *buildv1.BuildConfig {
+	return &buildv1.BuildConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test-bc",
+			Namespace:   "namespace",
+			Annotations: map[string]string{buildutil.BuildRetryPolicyAnnotation: policy},
+		},
+	}
+}
+
+func failedBuild(name string, reason buildv1.StatusReason, origin string, attempt string) *buildv1.Build {
+	build := mockBuild(buildv1.BuildPhaseFailed, buildv1.BuildOutput{})
+	build.Name = name
+	build.Status.Reason = reason
+	if len(origin) > 0 {
+		build.Annotations[buildutil.BuildRetryOfAnnotation] = origin
+		build.Annotations[buildutil.BuildRetryAttemptAnnotation] = attempt
+	}
+	return build
+}
+
+func TestShouldRetryBuild(t *testing.T) {
+	policy := &buildRetryPolicy{MaxRetries: 2}
+	tests := []struct {
+		name     string
+		build    *buildv1.Build
+		policy   *buildRetryPolicy
+		expected bool
+	}{
+		{
+			name:     "evicted build",
+			build:    failedBuild("test-bc-1", buildv1.StatusReasonBuildPodEvicted, "", ""),
+			policy:   policy,
+			expected: true,
+		},
+		{
+			name:  "no policy",
+			build: failedBuild("test-bc-1", buildv1.StatusReasonBuildPodEvicted, "", ""),
+		},
+		{
+			name:   "reason is not retryable by default",
+			build:  failedBuild("test-bc-1", buildv1.StatusReasonGenericBuildFailed, "", ""),
+			policy: policy,
+		},
+		{
+			name:   "reason is not in the retryable reasons of the policy",
+			build:  failedBuild("test-bc-1", buildv1.StatusReasonBuildPodEvicted, "", ""),
+			policy: &buildRetryPolicy{MaxRetries: 2, RetryableReasons: []buildv1.StatusReason{buildv1.StatusReasonPushImageToRegistryFailed}},
+		},
+		{
+			name:     "reason is in the retryable reasons of the policy",
+			build:    failedBuild("test-bc-1", buildv1.StatusReasonGenericBuildFailed, "", ""),
+			policy:   &buildRetryPolicy{MaxRetries: 2, RetryableReasons: []buildv1.StatusReason{buildv1.StatusReasonGenericBuildFailed}},
+			expected: true,
+		},
+		{
+			name:     "retry below the maximum",
+			build:    failedBuild("test-bc-2", buildv1.StatusReasonBuildPodEvicted, "test-bc-1", "1"),
+			policy:   policy,
+			expected: true,
+		},
+		{
+			name:   "retry at the maximum",
+			build:  failedBuild("test-bc-3", buildv1.StatusReasonBuildPodEvicted, "test-bc-1", "2"),
+			policy: policy,
+		},
+		{
+			name: "cancelled build",
+			build: func() *buildv1.Build {
+				build := failedBuild("test-bc-1", buildv1.StatusReasonBuildPodEvicted, "", "")
+				build.Status.Phase = buildv1.BuildPhaseCancelled
+				build.Status.Cancelled = true
+				return build
+			}(),
+			policy: policy,
+		},
+		{
+			name: "complete build",
+			build: func() *buildv1.Build {
+				build := failedBuild("test-bc-1", "", "", "")
+				build.Status.Phase = buildv1.BuildPhaseComplete
+				return build
+			}(),
+			policy: policy,
+		},
+		{
+			name: "git clone timeout",
+			build: func() *buildv1.Build {
+				build := failedBuild("test-bc-1", buildv1.StatusReasonFetchSourceFailed, "", "")
+				build.Status.LogSnippet = "error: failed to fetch requested repository: Connection timed out"
+				return build
+			}(),
+			policy:   policy,
+			expected: true,
+		},
+		{
+			name: "bad commit",
+			build: func() *buildv1.Build {
+				build := failedBuild("test-bc-1", buildv1.StatusReasonFetchSourceFailed, "", "")
+				build.Status.LogSnippet = "fatal: reference is not a tree: 4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+				return build
+			}(),
+			policy: policy,
+		},
+		{
+			name: "bad commit with fetch source failures retryable by the policy",
+			build: func() *buildv1.Build {
+				build := failedBuild("test-bc-1", buildv1.StatusReasonFetchSourceFailed, "", "")
+				build.Status.LogSnippet = "error: pathspec 'does-not-exist' did not match any file(s) known to git"
+				return build
+			}(),
+			policy: &buildRetryPolicy{MaxRetries: 2, RetryableReasons: []buildv1.StatusReason{buildv1.StatusReasonFetchSourceFailed}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if actual := shouldRetryBuild(tc.build, tc.policy); actual != tc.expected {
+				t.Errorf("expected retry %v, got %v", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestBuildRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name               string
+		buildConfigPolicy  string
+		buildPolicy        string
+		expectedMaxRetries int
+	}{
+		{
+			name: "no policy",
+		},
+		{
+			name:               "policy of the buildconfig",
+			buildConfigPolicy:  `{"maxRetries":2,"backoffSeconds":30}`,
+			expectedMaxRetries: 2,
+		},
+		{
+			name:               "policy of the build takes precedence",
+			buildConfigPolicy:  `{"maxRetries":2}`,
+			buildPolicy:        `{"maxRetries":4}`,
+			expectedMaxRetries: 4,
+		},
+		{
+			name:              "policy of the build disables retries",
+			buildConfigPolicy: `{"maxRetries":2}`,
+			buildPolicy:       `{"maxRetries":0}`,
+		},
+		{
+			name:              "invalid policy",
+			buildConfigPolicy: `{"maxRetries":2,"backoffSeconds":-1}`,
+		},
+		{
+			name:              "malformed policy",
+			buildConfigPolicy: `maxRetries: 2`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buildConfigs []*buildv1.BuildConfig
+			if len(tc.buildConfigPolicy) > 0 {
+				buildConfigs = append(buildConfigs, retryPolicyBuildConfig(tc.buildConfigPolicy))
+			}
+			build := failedBuild("test-bc-1", buildv1.StatusReasonBuildPodEvicted, "", "")
+			if len(tc.buildPolicy) > 0 {
+				build.Annotations[buildutil.BuildRetryPolicyAnnotation] = tc.buildPolicy
+			}
+			bc, _ := newRetryController(buildConfigs, nil)
+
+			policy := bc.retryPolicy(build)
+			if tc.expectedMaxRetries == 0 {
+				if policy != nil {
+					t.Errorf("expected no retry policy, got %#v", policy)
+				}
+				return
+			}
+			if policy == nil || policy.MaxRetries != tc.expectedMaxRetries {
+				t.Errorf("expected a retry policy with %d retries, got %#v", tc.expectedMaxRetries, policy)
+			}
+		})
+	}
+}
+
+func TestBuildRetryLineage(t *testing.T) {
+	buildConfig := retryPolicyBuildConfig(`{"maxRetries":2}`)
+	original := failedBuild("test-bc-1", buildv1.StatusReasonBuildPodEvicted, "", "")
+	first := failedBuild("test-bc-2", buildv1.StatusReasonPushImageToRegistryFailed, "test-bc-1", "1")
+	second := failedBuild("test-bc-3", buildv1.StatusReasonBuildPodEvicted, "test-bc-1", "2")
+
+	expectRetry := func(t *testing.T, build *buildv1.Build, existing []*buildv1.Build, expectedAttempt string) {
+		t.Helper()
+		bc, buildClient := newRetryController([]*buildv1.BuildConfig{buildConfig}, append(existing, build))
+
+		bc.scheduleBuildRetry(build)
+		if err := bc.handleBuildRetry(build); err != nil {
+			t.Fatal(err)
+		}
+
+		var clones []clientesting.CreateAction
+		for _, action := range buildClient.Actions() {
+			if create, ok := action.(clientesting.CreateAction); ok && create.GetSubresource() == "clone" {
+				clones = append(clones, create)
+			}
+		}
+		if len(expectedAttempt) == 0 {
+			if len(clones) != 0 || bc.buildRetryQueue.Len() != 0 {
+				t.Errorf("expected build %s not to be retried, got %d clones and %d queued retries", build.Name, len(clones), bc.buildRetryQueue.Len())
+			}
+			return
+		}
+		if bc.buildRetryQueue.Len() != 1 {
+			t.Errorf("expected the retry of build %s to be queued, got %d queued retries", build.Name, bc.buildRetryQueue.Len())
+		}
+		if len(clones) != 1 {
+			t.Fatalf("expected build %s to be cloned once, got %d clones", build.Name, len(clones))
+		}
+		request := clones[0].GetObject().(*buildv1.BuildRequest)
+		if request.Name != build.Name {
+			t.Errorf("expected build %s to be cloned, got %s", build.Name, request.Name)
+		}
+		// the created build carries the lineage, whatever annotations the server copied to it
+		created, err := buildClient.BuildV1().Builds("namespace").List(context.TODO(), metav1.ListOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(created.Items) != len(existing)+2 {
+			t.Fatalf("expected one retry build to be created, got %d builds", len(created.Items))
+		}
+		retry := &created.Items[len(created.Items)-1]
+		if origin := retry.Annotations[buildutil.BuildRetryOfAnnotation]; origin != original.Name {
+			t.Errorf("expected the retry to be annotated with the original build %s, got %q", original.Name, origin)
+		}
+		if attempt := retry.Annotations[buildutil.BuildRetryAttemptAnnotation]; attempt != expectedAttempt {
+			t.Errorf("expected the retry to be annotated with attempt %s, got %q", expectedAttempt, attempt)
+		}
+		if o, a := retryLineage(retry); o != original.Name || fmt.Sprint(a) != expectedAttempt {
+			t.Errorf("expected the lineage of the retry to be %s attempt %s, got %s attempt %d", original.Name, expectedAttempt, o, a)
+		}
+		if started, ok := retry.Annotations[buildutil.BuildRetryStartedAnnotation]; ok {
+			t.Errorf("expected the started annotation to be removed from the retry, got %q", started)
+		}
+	}
+
+	t.Run("original build", func(t *testing.T) {
+		expectRetry(t, original, nil, "1")
+	})
+	t.Run("first retry", func(t *testing.T) {
+		expectRetry(t, first, []*buildv1.Build{original}, "2")
+	})
+	t.Run("last retry", func(t *testing.T) {
+		expectRetry(t, second, []*buildv1.Build{original, first}, "")
+	})
+	t.Run("already retried", func(t *testing.T) {
+		bc, buildClient := newRetryController([]*buildv1.BuildConfig{buildConfig}, []*buildv1.Build{original, first})
+		if err := bc.handleBuildRetry(original); err != nil {
+			t.Fatal(err)
+		}
+		if actions := buildClient.Actions(); len(actions) != 0 {
+			t.Errorf("expected no retry of an already retried build, got %v", actions)
+		}
+	})
+	t.Run("lineage of the retry not recorded", func(t *testing.T) {
+		bc, buildClient := newRetryController([]*buildv1.BuildConfig{buildConfig}, []*buildv1.Build{original})
+		buildClient.PrependReactor("patch", "builds", func(action clientesting.Action) (bool, runtime.Object, error) {
+			if action.(clientesting.PatchAction).GetName() == "test-bc-2" {
+				return true, nil, fmt.Errorf("conflict")
+			}
+			return false, nil, nil
+		})
+		if err := bc.handleBuildRetry(original); err == nil {
+			t.Fatalf("expected the failure to record the lineage of the retry to be returned")
+		}
+
+		// the requeued retry finds the clone of the marked build instead of cloning it again
+		marked, err := buildClient.BuildV1().Builds("namespace").Get(context.TODO(), original.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if started := marked.Annotations[buildutil.BuildRetryStartedAnnotation]; started != original.Name {
+			t.Fatalf("expected build %s to be marked as retried before it was cloned, got %q", original.Name, started)
+		}
+		buildClient.ReactionChain = buildClient.ReactionChain[1:]
+		if err := bc.handleBuildRetry(marked); err != nil {
+			t.Fatal(err)
+		}
+		clones := 0
+		for _, action := range buildClient.Actions() {
+			if action.GetVerb() == "create" && action.GetSubresource() == "clone" {
+				clones++
+			}
+		}
+		if clones != 1 {
+			t.Errorf("expected build %s to be cloned once, got %d clones", original.Name, clones)
+		}
+		retry, err := buildClient.BuildV1().Builds("namespace").Get(context.TODO(), "test-bc-2", metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o, a := retryLineage(retry); o != original.Name || a != 1 {
+			t.Errorf("expected the lineage of the retry to be %s attempt 1, got %s attempt %d", original.Name, o, a)
+		}
+	})
+}
+
+func TestScheduleBuildRetryAfterRestart(t *testing.T) {
+	buildConfig := retryPolicyBuildConfig(`{"maxRetries":2,"backoffSeconds":60}`)
+	failed := func(name string, completed time.Duration) *buildv1.Build {
+		build := failedBuild(name, buildv1.StatusReasonBuildPodEvicted, "", "")
+		completion := metav1.NewTime(time.Now().Add(-completed))
+		build.Status.CompletionTimestamp = &completion
+		return build
+	}
+	overdue := failed("overdue", 2*time.Minute)
+	backingOff := failed("backing-off", 10*time.Second)
+	retried := failed("retried", 2*time.Minute)
+	retry := failedBuild("retry", buildv1.StatusReasonBuildPodEvicted, "retried", "1")
+	retry.Status.Phase = buildv1.BuildPhaseRunning
+	bc, _ := newRetryController([]*buildv1.BuildConfig{buildConfig}, []*buildv1.Build{overdue, backingOff, retried, retry})
+	bc.buildQueue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+
+	// the build informer lists the builds when the controller starts
+	for _, build := range []*buildv1.Build{overdue, backingOff, retried, retry} {
+		bc.buildAdded(build)
+	}
+	if bc.buildRetryQueue.Len() != 1 {
+		t.Fatalf("expected only the retry of the overdue build to be queued, got %d queued retries", bc.buildRetryQueue.Len())
+	}
+	key, _ := bc.buildRetryQueue.Get()
+	if key != "namespace/overdue" {
+		t.Errorf("expected the retry of the overdue build to be queued, got %v", key)
+	}
+	bc.buildRetryQueue.Done(key)
+}
@@ -0,0 +1,71 @@
+package build
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// builderServiceAccountGracePeriod is how long after their creation new builds wait for their
+// service account and its dockercfg secret, which are created shortly after the namespace, before
+// they fail.
+const builderServiceAccountGracePeriod = 2 * time.Minute
+
+// checkBuilderServiceAccount fails new builds whose service account does not exist, or has no
+// dockercfg secret to push their output image with when the build does not set a push secret,
+// before the build pod is created: the build would otherwise fail late when it pushes. Builds
+// are requeued instead within the grace period after their creation. The service account and its
+// secrets are looked up in the informer caches. It returns a nil update when the build can push.
+func (bc *BuildController) checkBuilderServiceAccount(build *buildv1.Build) (*buildUpdate, error) {
+	serviceAccountName := build.Spec.ServiceAccount
+	if len(serviceAccountName) == 0 {
+		serviceAccountName = buildutil.BuilderServiceAccountName
+	}
+
+	var reason buildv1.StatusReason
+	var message string
+	serviceAccount, err := bc.serviceAccountStore.ServiceAccounts(build.Namespace).Get(serviceAccountName)
+	switch {
+	case errors.IsNotFound(err):
+		reason = buildutil.StatusReasonBuilderServiceAccountMissing
+		message = fmt.Sprintf("The service account %s of the build does not exist. Create it, or set the service account of the build to an existing one.", serviceAccountName)
+	case err != nil:
+		return nil, fmt.Errorf("unable to get service account %s/%s of build %s: %v", build.Namespace, serviceAccountName, buildDesc(build), err)
+	case build.Spec.Output.To != nil && build.Spec.Output.PushSecret == nil && !bc.hasDockerSecret(serviceAccount):
+		reason = buildutil.StatusReasonMissingPushSecret
+		message = fmt.Sprintf("The service account %s of the build has no dockercfg secret to push the output image with. Check that the image registry is enabled and the dockercfg secret of the service account exists, or set the push secret of the build output.", serviceAccountName)
+	default:
+		return nil, nil
+	}
+
+	if remaining := build.CreationTimestamp.Add(builderServiceAccountGracePeriod).Sub(bc.clock.Now()); remaining > 0 {
+		klog.V(4).Infof("Waiting %s for the service account %s/%s of build %s: %s", remaining, build.Namespace, serviceAccountName, buildDesc(build), reason)
+		bc.buildQueue.AddAfter(resourceName(build.Namespace, build.Name), remaining)
+		update := &buildUpdate{}
+		update.setReason(buildv1.StatusReasonCannotRetrieveServiceAccount)
+		update.setMessage(message)
+		return update, nil
+	}
+	return transitionToPhase(buildv1.BuildPhaseFailed, reason, message), nil
+}
+
+// hasDockerSecret returns true if the image pull secrets of the service account include an
+// existing dockercfg secret.
+func (bc *BuildController) hasDockerSecret(serviceAccount *corev1.ServiceAccount) bool {
+	for _, ref := range serviceAccount.ImagePullSecrets {
+		secret, err := bc.secretStore.Secrets(serviceAccount.Namespace).Get(ref.Name)
+		if err != nil {
+			continue
+		}
+		if secret.Type == corev1.SecretTypeDockercfg || secret.Type == corev1.SecretTypeDockerConfigJson {
+			return true
+		}
+	}
+	return false
+}
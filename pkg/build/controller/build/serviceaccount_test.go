@@ -0,0 +1,94 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clocktesting "k8s.io/utils/clock/testing"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func TestCreateBuildPodBuilderServiceAccount(t *testing.T) {
+	pusher := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "pusher", Namespace: "namespace"}}
+	output := buildv1.BuildOutput{To: &corev1.ObjectReference{Kind: "DockerImage", Name: "registry.example.com/namespace/output:latest"}}
+	tests := []struct {
+		name           string
+		serviceAccount string
+		output         buildv1.BuildOutput
+		age            time.Duration
+		expectedPhase  buildv1.BuildPhase
+		expectedReason buildv1.StatusReason
+		pullSecret     *corev1.LocalObjectReference
+	}{
+		{
+			name:          "builder service account with a push secret",
+			output:        output,
+			expectedPhase: buildv1.BuildPhasePending,
+		},
+		{
+			name:           "missing service account",
+			serviceAccount: "missing",
+			output:         output,
+			expectedPhase:  buildv1.BuildPhaseFailed,
+			expectedReason: buildutil.StatusReasonBuilderServiceAccountMissing,
+		},
+		{
+			name:           "missing service account of a new build",
+			serviceAccount: "missing",
+			output:         output,
+			age:            time.Minute,
+			expectedReason: buildv1.StatusReasonCannotRetrieveServiceAccount,
+		},
+		{
+			name:           "missing push secret",
+			serviceAccount: pusher.Name,
+			output:         output,
+			expectedPhase:  buildv1.BuildPhaseFailed,
+			expectedReason: buildutil.StatusReasonMissingPushSecret,
+		},
+		{
+			name:           "push secret of the build",
+			serviceAccount: pusher.Name,
+			pullSecret:     &corev1.LocalObjectReference{Name: "secret"},
+			output: buildv1.BuildOutput{
+				To:         output.To,
+				PushSecret: &corev1.LocalObjectReference{Name: "secret"},
+			},
+			expectedPhase: buildv1.BuildPhasePending,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			bc := newFakeBuildController(nil, nil, fakeKubeExternalClientSet(registryCAConfigMap, pusher), nil, nil)
+			defer bc.stop()
+			bc.clock = clocktesting.NewFakeClock(now)
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, tc.output))
+			build.CreationTimestamp = metav1.NewTime(now.Add(-builderServiceAccountGracePeriod))
+			if tc.age > 0 {
+				build.CreationTimestamp = metav1.NewTime(now.Add(-tc.age))
+			}
+			build.Spec.ServiceAccount = tc.serviceAccount
+			build.Spec.Strategy.DockerStrategy.PullSecret = tc.pullSecret
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tc.expectedPhase) == 0 {
+				if update.phase != nil {
+					t.Errorf("expected the build to stay new, got phase %s", *update.phase)
+				}
+			} else if update.phase == nil || *update.phase != tc.expectedPhase {
+				t.Errorf("expected phase %s, got %v", tc.expectedPhase, update)
+			}
+			if len(tc.expectedReason) > 0 && (update.reason == nil || *update.reason != tc.expectedReason) {
+				t.Errorf("expected reason %s, got %v", tc.expectedReason, update)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package build
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+// scpLikeURIPattern matches the scp-like syntax of git SSH URIs, such as
+// git@github.com:openshift/origin.git.
+var scpLikeURIPattern = regexp.MustCompile(`^(?:([^@/]+)@)?([^:/]+):([^/].*)?$`)
+
+// sourceURI is a git source URI, or a source secret match URI pattern in which any part of the
+// scheme, the host and the path may be matched by the * wildcard.
+type sourceURI struct {
+	scheme string
+	host   string
+	path   string
+}
+
+// parseSourceURI splits a git source URI or a source secret match URI pattern into its scheme,
+// host and path. The scp-like syntax of SSH URIs is read as the ssh scheme.
+func parseSourceURI(uri string) (sourceURI, bool) {
+	if !strings.Contains(uri, "://") {
+		m := scpLikeURIPattern.FindStringSubmatch(uri)
+		if m == nil {
+			return sourceURI{}, false
+		}
+		return sourceURI{scheme: "ssh", host: m[2], path: "/" + m[3]}, true
+	}
+	scheme, rest, _ := strings.Cut(uri, "://")
+	host, path, _ := strings.Cut(rest, "/")
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	// The wildcards of patterns are not valid in the host of a URL.
+	if !strings.Contains(host, "*") {
+		u, err := url.Parse("//" + host)
+		if err != nil {
+			return sourceURI{}, false
+		}
+		host = u.Host
+	}
+	return sourceURI{scheme: strings.ToLower(scheme), host: strings.ToLower(host), path: "/" + path}, len(host) > 0
+}
+
+// globMatch returns true if the value matches the pattern, in which * matches any sequence of
+// characters.
+func globMatch(pattern, value string) bool {
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	matched, err := regexp.MatchString(expr, value)
+	return err == nil && matched
+}
+
+// matches returns true if the source URI matches the pattern.
+func (p sourceURI) matches(uri sourceURI) bool {
+	return globMatch(p.scheme, uri.scheme) && globMatch(p.host, uri.host) && globMatch(p.path, uri.path)
+}
+
+// moreSpecific returns true if the pattern is more specific than the other pattern: patterns with
+// more literal characters in their host are more specific, then patterns with more literal
+// characters in their path, then patterns with a literal scheme.
+func (p sourceURI) moreSpecific(other sourceURI) bool {
+	literal := func(s string) int {
+		return len(strings.ReplaceAll(s, "*", ""))
+	}
+	if literal(p.host) != literal(other.host) {
+		return literal(p.host) > literal(other.host)
+	}
+	if literal(p.path) != literal(other.path) {
+		return literal(p.path) > literal(other.path)
+	}
+	return literal(p.scheme) > literal(other.scheme)
+}
+
+// sourceSecretMatch is a secret whose source secret match URI annotation matches the git URI of a
+// build.
+type sourceSecretMatch struct {
+	secret  string
+	pattern string
+	uri     sourceURI
+}
+
+// matchSourceSecret returns the secret whose source secret match URI patterns most specifically
+// match the git URI, and the number of secrets with a matching pattern. Secrets whose patterns are
+// as specific are chosen by name.
+func matchSourceSecret(secrets []*corev1.Secret, gitURI string) (*sourceSecretMatch, int) {
+	uri, ok := parseSourceURI(gitURI)
+	if !ok {
+		return nil, 0
+	}
+	sorted := make([]*corev1.Secret, len(secrets))
+	copy(sorted, secrets)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var best *sourceSecretMatch
+	candidates := 0
+	for _, secret := range sorted {
+		var secretMatch *sourceSecretMatch
+		for key, pattern := range secret.Annotations {
+			if !strings.HasPrefix(key, buildv1.BuildSourceSecretMatchURIAnnotationPrefix) {
+				continue
+			}
+			p, ok := parseSourceURI(pattern)
+			if !ok || !p.matches(uri) {
+				continue
+			}
+			if secretMatch == nil || p.moreSpecific(secretMatch.uri) || (!secretMatch.uri.moreSpecific(p) && pattern < secretMatch.pattern) {
+				secretMatch = &sourceSecretMatch{secret: secret.Name, pattern: pattern, uri: p}
+			}
+		}
+		if secretMatch == nil {
+			continue
+		}
+		candidates++
+		if best == nil || secretMatch.uri.moreSpecific(best.uri) {
+			best = secretMatch
+		}
+	}
+	return best, candidates
+}
+
+// resolveSourceSecret selects the source secret of git builds without one among the secrets of
+// the build namespace whose source secret match URI annotations match the git URI of the build.
+// It returns nil if no secret matches.
+func (bc *BuildController) resolveSourceSecret(build *buildv1.Build) (*sourceSecretMatch, error) {
+	if build.Spec.Source.Git == nil || build.Spec.Source.SourceSecret != nil {
+		return nil, nil
+	}
+	secrets, err := bc.secretStore.Secrets(build.Namespace).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the secrets of namespace %s to select the source secret of build %s: %v", build.Namespace, buildDesc(build), err)
+	}
+	match, candidates := matchSourceSecret(secrets, build.Spec.Source.Git.URI)
+	if match == nil {
+		return nil, nil
+	}
+	if candidates > 1 {
+		klog.V(2).Infof("%d secrets match the source URI %s of build %s, selected secret %s with the most specific pattern %s", candidates, build.Spec.Source.Git.URI, buildDesc(build), match.secret, match.pattern)
+	} else {
+		klog.V(4).Infof("Selected source secret %s with pattern %s for build %s", match.secret, match.pattern, buildDesc(build))
+	}
+	build.Spec.Source.SourceSecret = &corev1.LocalObjectReference{Name: match.secret}
+	return match, nil
+}
+
+// setSourceSecretSelected records the source secret the build controller selected for the build
+// in the SourceSecretSelected condition of the build.
+func setSourceSecretSelected(update *buildUpdate, match *sourceSecretMatch) {
+	update.setSourceSecret(corev1.LocalObjectReference{Name: match.secret})
+	update.setCondition(buildv1.BuildCondition{
+		Type:               buildutil.BuildConditionSourceSecretSelected,
+		Status:             corev1.ConditionTrue,
+		Reason:             buildutil.SourceSecretMatchedURIReason,
+		Message:            fmt.Sprintf("The source secret %s was selected because its source URI pattern %s matches the git URI of the build.", match.secret, match.pattern),
+		LastUpdateTime:     metav1.Now(),
+		LastTransitionTime: metav1.Now(),
+	})
+}
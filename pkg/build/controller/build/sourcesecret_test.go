@@ -0,0 +1,158 @@
+package build
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func sourceSecret(name string, patterns ...string) *corev1.Secret {
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "namespace", Annotations: map[string]string{}}}
+	for i, pattern := range patterns {
+		secret.Annotations[buildv1.BuildSourceSecretMatchURIAnnotationPrefix+fmt.Sprintf("%d", i)] = pattern
+	}
+	return secret
+}
+
+func TestMatchSourceSecret(t *testing.T) {
+	secrets := []*corev1.Secret{
+		sourceSecret("any", "*://*/*"),
+		sourceSecret("github", "https://github.com/*"),
+		sourceSecret("github-openshift", "https://github.com/openshift/*", "ssh://github.com/openshift/*"),
+		sourceSecret("github-subdomains", "https://*.github.com/*"),
+		sourceSecret("github-any-scheme", "*://github.com/openshift/*"),
+		sourceSecret("unrelated", "https://gitlab.com/*"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "namespace"}},
+	}
+	tests := []struct {
+		uri                string
+		expectedSecret     string
+		expectedPattern    string
+		expectedCandidates int
+	}{
+		{
+			uri:                "https://github.com/openshift/origin.git",
+			expectedSecret:     "github-openshift",
+			expectedPattern:    "https://github.com/openshift/*",
+			expectedCandidates: 4,
+		},
+		{
+			uri:                "https://user@github.com/openshift/origin.git",
+			expectedSecret:     "github-openshift",
+			expectedPattern:    "https://github.com/openshift/*",
+			expectedCandidates: 4,
+		},
+		{
+			uri:                "git@github.com:openshift/origin.git",
+			expectedSecret:     "github-openshift",
+			expectedPattern:    "ssh://github.com/openshift/*",
+			expectedCandidates: 3,
+		},
+		{
+			uri:                "https://github.com/kubernetes/kubernetes.git",
+			expectedSecret:     "github",
+			expectedPattern:    "https://github.com/*",
+			expectedCandidates: 2,
+		},
+		{
+			uri:                "https://enterprise.github.com/team/repo.git",
+			expectedSecret:     "github-subdomains",
+			expectedPattern:    "https://*.github.com/*",
+			expectedCandidates: 2,
+		},
+		{
+			uri:                "https://git.example.com/repo.git",
+			expectedSecret:     "any",
+			expectedPattern:    "*://*/*",
+			expectedCandidates: 1,
+		},
+		{
+			uri: "not a uri",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.uri, func(t *testing.T) {
+			match, candidates := matchSourceSecret(secrets, tc.uri)
+			if candidates != tc.expectedCandidates {
+				t.Errorf("expected %d matching secrets, got %d", tc.expectedCandidates, candidates)
+			}
+			if len(tc.expectedSecret) == 0 {
+				if match != nil {
+					t.Errorf("expected no secret to match, got %#v", match)
+				}
+				return
+			}
+			if match == nil || match.secret != tc.expectedSecret || match.pattern != tc.expectedPattern {
+				t.Errorf("expected secret %s to match with pattern %s, got %#v", tc.expectedSecret, tc.expectedPattern, match)
+			}
+		})
+	}
+}
+
+func TestMatchSourceSecretEquallySpecificPatterns(t *testing.T) {
+	secrets := []*corev1.Secret{
+		sourceSecret("second", "https://github.com/*"),
+		sourceSecret("first", "https://github.com/*"),
+	}
+	for i := 0; i < 5; i++ {
+		match, candidates := matchSourceSecret(secrets, "https://github.com/openshift/origin.git")
+		if candidates != 2 || match == nil || match.secret != "first" {
+			t.Fatalf("expected the secrets with equally specific patterns to be chosen by name, got %#v of %d", match, candidates)
+		}
+	}
+}
+
+func TestCreateBuildPodSelectsSourceSecret(t *testing.T) {
+	tests := []struct {
+		name           string
+		sourceSecret   *corev1.LocalObjectReference
+		expectedSecret string
+	}{
+		{
+			name:           "selected by source URI",
+			expectedSecret: "github-openshift",
+		},
+		{
+			name:         "explicit source secret",
+			sourceSecret: &corev1.LocalObjectReference{Name: "github"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kubeClient := fakeKubeExternalClientSet(registryCAConfigMap,
+				sourceSecret("github", "https://github.com/*"),
+				sourceSecret("github-openshift", "https://github.com/openshift/*"))
+			bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+			defer bc.stop()
+
+			build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+			build.Spec.Source.Git = &buildv1.GitBuildSource{URI: "https://github.com/openshift/ruby-hello-world.git"}
+			build.Spec.Source.SourceSecret = tc.sourceSecret
+
+			update, err := bc.createBuildPod(build)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if update.phase == nil || *update.phase != buildv1.BuildPhasePending {
+				t.Fatalf("expected the build pod to be created, got %v", update)
+			}
+			if len(tc.expectedSecret) == 0 {
+				if update.sourceSecret != nil || len(update.conditions) != 0 {
+					t.Errorf("expected no source secret to be selected, got %v", update)
+				}
+				return
+			}
+			if update.sourceSecret == nil || update.sourceSecret.Name != tc.expectedSecret {
+				t.Errorf("expected source secret %s to be selected, got %v", tc.expectedSecret, update)
+			}
+			if len(update.conditions) != 1 || update.conditions[0].Type != buildutil.BuildConditionSourceSecretSelected || update.conditions[0].Reason != buildutil.SourceSecretMatchedURIReason {
+				t.Errorf("expected the source secret selection to be recorded in the build status, got %v", update.conditions)
+			}
+		})
+	}
+}
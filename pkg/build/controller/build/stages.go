@@ -0,0 +1,134 @@
+package build
+
+import (
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+)
+
+var (
+	// buildStageNames are the stages the build controller records in the status of builds.
+	buildStageNames = sets.New[buildv1.StageName](
+		buildv1.StageFetchInputs,
+		buildv1.StagePullImages,
+		buildv1.StageBuild,
+		buildv1.StagePostCommit,
+		buildv1.StagePushImage,
+	)
+	// buildStepNames are the steps of the stages the build controller records.
+	buildStepNames = sets.New[buildv1.StepName](
+		buildv1.StepExecPostCommitHook,
+		buildv1.StepFetchGitSource,
+		buildv1.StepPullBaseImage,
+		buildv1.StepPullInputImage,
+		buildv1.StepPushImage,
+		buildv1.StepPushDockerImage,
+		buildv1.StepDockerBuild,
+	)
+)
+
+// timeSpan is the time between the start and the end of a stage or a step.
+type timeSpan struct {
+	start time.Time
+	end   time.Time
+}
+
+// merge extends the span to the start and the end of another report of the same stage or step.
+func (s *timeSpan) merge(start time.Time, durationMilliseconds int64) {
+	end := start.Add(time.Duration(durationMilliseconds) * time.Millisecond)
+	if s.start.IsZero() || start.Before(s.start) {
+		s.start = start
+	}
+	if end.After(s.end) {
+		s.end = end
+	}
+}
+
+// durationMilliseconds returns the duration of the span in milliseconds.
+func (s *timeSpan) durationMilliseconds() int64 {
+	return s.end.Sub(s.start).Milliseconds()
+}
+
+// validReport returns true if a stage or step report has a start time and no negative duration.
+func validReport(startTime metav1.Time, durationMilliseconds int64) bool {
+	return !startTime.IsZero() && durationMilliseconds >= 0
+}
+
+// mergeBuildStages merges the stages the builder reported into one stage of each name, which spans
+// from the earliest start to the latest end of its reports, and likewise for the steps of each
+// stage. Reports of unknown stages and steps, or without a start time or with a negative duration,
+// are dropped. The stages and their steps are ordered by their start time.
+func mergeBuildStages(stages []buildv1.StageInfo) []buildv1.StageInfo {
+	stageSpans := map[buildv1.StageName]*timeSpan{}
+	stepSpans := map[buildv1.StageName]map[buildv1.StepName]*timeSpan{}
+	for _, stage := range stages {
+		if !buildStageNames.Has(stage.Name) || !validReport(stage.StartTime, stage.DurationMilliseconds) {
+			klog.V(4).Infof("Ignoring invalid report of build stage %q", stage.Name)
+			continue
+		}
+		if stageSpans[stage.Name] == nil {
+			stageSpans[stage.Name] = &timeSpan{}
+			stepSpans[stage.Name] = map[buildv1.StepName]*timeSpan{}
+		}
+		stageSpans[stage.Name].merge(stage.StartTime.Time, stage.DurationMilliseconds)
+		for _, step := range stage.Steps {
+			if !buildStepNames.Has(step.Name) || !validReport(step.StartTime, step.DurationMilliseconds) {
+				klog.V(4).Infof("Ignoring invalid report of step %q of build stage %q", step.Name, stage.Name)
+				continue
+			}
+			if stepSpans[stage.Name][step.Name] == nil {
+				stepSpans[stage.Name][step.Name] = &timeSpan{}
+			}
+			stepSpans[stage.Name][step.Name].merge(step.StartTime.Time, step.DurationMilliseconds)
+		}
+	}
+	if len(stageSpans) == 0 {
+		return nil
+	}
+
+	merged := make([]buildv1.StageInfo, 0, len(stageSpans))
+	for name, span := range stageSpans {
+		stage := buildv1.StageInfo{
+			Name:                 name,
+			StartTime:            metav1.NewTime(span.start),
+			DurationMilliseconds: span.durationMilliseconds(),
+		}
+		for stepName, stepSpan := range stepSpans[name] {
+			stage.Steps = append(stage.Steps, buildv1.StepInfo{
+				Name:                 stepName,
+				StartTime:            metav1.NewTime(stepSpan.start),
+				DurationMilliseconds: stepSpan.durationMilliseconds(),
+			})
+		}
+		sort.Slice(stage.Steps, func(i, j int) bool {
+			if !stage.Steps[i].StartTime.Equal(&stage.Steps[j].StartTime) {
+				return stage.Steps[i].StartTime.Before(&stage.Steps[j].StartTime)
+			}
+			return stage.Steps[i].Name < stage.Steps[j].Name
+		})
+		merged = append(merged, stage)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		if !merged[i].StartTime.Equal(&merged[j].StartTime) {
+			return merged[i].StartTime.Before(&merged[j].StartTime)
+		}
+		return merged[i].Name < merged[j].Name
+	})
+	return merged
+}
+
+// setBuildStages records the stages the builder reported in the status of a completed build,
+// merged into one stage of each name.
+func setBuildStages(build *buildv1.Build, update *buildUpdate) {
+	stages := mergeBuildStages(build.Status.Stages)
+	if len(stages) == 0 || equality.Semantic.DeepEqual(stages, build.Status.Stages) {
+		return
+	}
+	update.setStages(stages)
+}
@@ -0,0 +1,118 @@
+package build
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	buildv1 "github.com/openshift/api/build/v1"
+)
+
+func TestMergeBuildStages(t *testing.T) {
+	at := func(seconds int64) metav1.Time {
+		return metav1.NewTime(time.Unix(1000+seconds, 0).UTC())
+	}
+	tests := []struct {
+		name     string
+		reported []buildv1.StageInfo
+		expected []buildv1.StageInfo
+	}{
+		{
+			name: "stages are ordered by start time",
+			reported: []buildv1.StageInfo{
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 5000},
+				{Name: buildv1.StagePullImages, StartTime: at(2), DurationMilliseconds: 8000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepPullInputImage, StartTime: at(6), DurationMilliseconds: 4000},
+					{Name: buildv1.StepPullBaseImage, StartTime: at(2), DurationMilliseconds: 4000},
+				}},
+				{Name: buildv1.StagePostCommit, StartTime: at(50), DurationMilliseconds: 10000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepExecPostCommitHook, StartTime: at(50), DurationMilliseconds: 10000},
+				}},
+			},
+			expected: []buildv1.StageInfo{
+				{Name: buildv1.StagePullImages, StartTime: at(2), DurationMilliseconds: 8000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepPullBaseImage, StartTime: at(2), DurationMilliseconds: 4000},
+					{Name: buildv1.StepPullInputImage, StartTime: at(6), DurationMilliseconds: 4000},
+				}},
+				{Name: buildv1.StagePostCommit, StartTime: at(50), DurationMilliseconds: 10000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepExecPostCommitHook, StartTime: at(50), DurationMilliseconds: 10000},
+				}},
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 5000},
+			},
+		},
+		{
+			name: "duplicate reports span from the earliest start to the latest end",
+			reported: []buildv1.StageInfo{
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 5000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepPushImage, StartTime: at(60), DurationMilliseconds: 5000},
+				}},
+				{Name: buildv1.StagePushImage, StartTime: at(62), DurationMilliseconds: 8000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepPushImage, StartTime: at(62), DurationMilliseconds: 8000},
+				}},
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 5000},
+			},
+			expected: []buildv1.StageInfo{
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 10000, Steps: []buildv1.StepInfo{
+					{Name: buildv1.StepPushImage, StartTime: at(60), DurationMilliseconds: 10000},
+				}},
+			},
+		},
+		{
+			name: "invalid reports are dropped",
+			reported: []buildv1.StageInfo{
+				{Name: "Unknown", StartTime: at(0), DurationMilliseconds: 1000},
+				{Name: buildv1.StageBuild, DurationMilliseconds: 1000},
+				{Name: buildv1.StagePullImages, StartTime: at(0), DurationMilliseconds: -1},
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 5000, Steps: []buildv1.StepInfo{
+					{Name: "Unknown", StartTime: at(60), DurationMilliseconds: 1000},
+					{Name: buildv1.StepPushImage, StartTime: at(60), DurationMilliseconds: -1000},
+				}},
+			},
+			expected: []buildv1.StageInfo{
+				{Name: buildv1.StagePushImage, StartTime: at(60), DurationMilliseconds: 5000},
+			},
+		},
+		{
+			name: "no reports",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			merged := mergeBuildStages(tc.reported)
+			if !equality.Semantic.DeepEqual(merged, tc.expected) {
+				t.Errorf("expected stages %#v, got %#v", tc.expected, merged)
+			}
+		})
+	}
+}
+
+func TestSetBuildStages(t *testing.T) {
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseComplete, buildv1.BuildOutput{}))
+	at := func(seconds int64) metav1.Time {
+		return metav1.NewTime(time.Unix(1000+seconds, 0).UTC())
+	}
+	build.Status.Stages = []buildv1.StageInfo{
+		{Name: buildv1.StagePushImage, StartTime: at(40), DurationMilliseconds: 5000},
+		{Name: buildv1.StageBuild, StartTime: at(0), DurationMilliseconds: 30000},
+		{Name: buildv1.StagePushImage, StartTime: at(45), DurationMilliseconds: 5000},
+	}
+
+	update := &buildUpdate{}
+	setBuildStages(build, update)
+	expected := []buildv1.StageInfo{
+		{Name: buildv1.StageBuild, StartTime: at(0), DurationMilliseconds: 30000},
+		{Name: buildv1.StagePushImage, StartTime: at(40), DurationMilliseconds: 10000},
+	}
+	if !equality.Semantic.DeepEqual(update.stages, expected) {
+		t.Fatalf("expected stages %#v, got %#v", expected, update.stages)
+	}
+
+	build.Status.Stages = update.stages
+	update = &buildUpdate{}
+	setBuildStages(build, update)
+	if !update.isEmpty() {
+		t.Errorf("expected no update of merged stages, got %v", update)
+	}
+}
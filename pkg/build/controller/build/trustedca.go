@@ -0,0 +1,103 @@
+package build
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+const (
+	// trustedCABundleNamespace and trustedCABundleName locate the configMap the trusted CA bundle
+	// of the cluster proxy configuration is injected into, which is copied into the proxy
+	// certificate authority configMap of each build.
+	trustedCABundleNamespace = "openshift-controller-manager"
+	trustedCABundleName      = "openshift-user-ca"
+)
+
+func isTrustedCABundle(configMap *corev1.ConfigMap) bool {
+	return configMap.Namespace == trustedCABundleNamespace && configMap.Name == trustedCABundleName
+}
+
+func (bc *BuildController) trustedCABundleAdded(obj interface{}) {
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("object was not a configMap: %+v", obj))
+		return
+	}
+	if isTrustedCABundle(configMap) {
+		bc.enqueueNotStartedBuilds()
+	}
+}
+
+func (bc *BuildController) trustedCABundleUpdated(old, curr interface{}) {
+	oldConfigMap, ok := old.(*corev1.ConfigMap)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("object was not a configMap: %+v", old))
+		return
+	}
+	configMap, ok := curr.(*corev1.ConfigMap)
+	if !ok {
+		utilruntime.HandleError(fmt.Errorf("object was not a configMap: %+v", curr))
+		return
+	}
+	if isTrustedCABundle(configMap) && !equality.Semantic.DeepEqual(oldConfigMap.Data, configMap.Data) {
+		bc.enqueueNotStartedBuilds()
+	}
+}
+
+// enqueueNotStartedBuilds requeues the new and pending builds, whose build pod may have been
+// created with a trusted CA bundle that changed since.
+func (bc *BuildController) enqueueNotStartedBuilds() {
+	builds, err := bc.buildLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to list builds to refresh their proxy certificate authorities: %v", err))
+		return
+	}
+	for _, build := range builds {
+		if build.Status.Phase == buildv1.BuildPhaseNew || build.Status.Phase == buildv1.BuildPhasePending {
+			bc.enqueueBuild(build)
+		}
+	}
+}
+
+// refreshBuildGlobalCAConfigMap updates the proxy certificate authority configMap of a build whose
+// build pod did not start yet to the current trusted CA bundle of the cluster, so that builds
+// created before the bundle changed do not run with the stale bundle. ConfigMaps that were not
+// created yet, or are not owned by the build pod, are left alone.
+func (bc *BuildController) refreshBuildGlobalCAConfigMap(build *buildv1.Build, pod *corev1.Pod) error {
+	name := buildutil.GetBuildGlobalCAConfigMapName(build)
+	configMap, err := bc.configMapClient.ConfigMaps(build.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not get the proxy certificate authority configMap of build %s: %v", buildDesc(build), err)
+	}
+	if !hasBuildPodOwnerRef(pod, configMap) {
+		return nil
+	}
+	data := bc.createBuildGlobalCAConfigMapSpec(build, pod).Data
+	if equality.Semantic.DeepEqual(configMap.Data, data) {
+		return nil
+	}
+
+	configMap = configMap.DeepCopy()
+	configMap.Data = data
+	if _, err := bc.configMapClient.ConfigMaps(build.Namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("could not refresh the proxy certificate authority configMap of build %s: %v", buildDesc(build), err)
+	}
+	klog.V(4).Infof("Refreshed proxy certificate authority configMap %s/%s for build %s", build.Namespace, name, buildDesc(build))
+	bc.recorder.Eventf(build, corev1.EventTypeNormal, buildutil.BuildTrustedCARefreshedEventReason, buildutil.BuildTrustedCARefreshedEventMessage,
+		name, build.Namespace, build.Name)
+	return nil
+}
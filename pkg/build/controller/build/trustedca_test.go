@@ -0,0 +1,93 @@
+package build
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
+)
+
+func trustedCABundle(bundle string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: trustedCABundleName, Namespace: trustedCABundleNamespace},
+		Data:       map[string]string{buildutil.GlobalCAConfigMapKey: bundle},
+	}
+}
+
+func TestRefreshBuildGlobalCAConfigMap(t *testing.T) {
+	kubeClient := fakeKubeExternalClientSet(registryCAConfigMap, trustedCABundle("old bundle"))
+	bc := newFakeBuildController(nil, nil, kubeClient, nil, nil)
+	defer bc.stop()
+	recorder := record.NewFakeRecorder(10)
+	bc.recorder = recorder
+
+	build := dockerStrategy(mockBuild(buildv1.BuildPhaseNew, buildv1.BuildOutput{}))
+	update, err := bc.createBuildPod(build)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	update.apply(build)
+	pod, err := kubeClient.CoreV1().Pods(build.Namespace).Get(context.TODO(), buildutil.GetBuildPodName(build), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod.Status.Phase = corev1.PodPending
+
+	globalCABundle := func() string {
+		configMap, err := kubeClient.CoreV1().ConfigMaps(build.Namespace).Get(context.TODO(), buildutil.GetBuildGlobalCAConfigMapName(build), metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return configMap.Data[buildutil.GlobalCAConfigMapKey]
+	}
+	setTrustedCABundle := func(bundle string) {
+		indexer := bc.kubeExternalInformers.Core().V1().ConfigMaps().Informer().GetIndexer()
+		if err := indexer.Update(trustedCABundle(bundle)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if bundle := globalCABundle(); bundle != "old bundle" {
+		t.Fatalf("expected the build to be created with the old bundle, got %q", bundle)
+	}
+
+	// the bundle is unchanged
+	if _, err := bc.handleActiveBuild(build, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(recorder.Events) != 0 {
+		t.Errorf("expected no refresh of the unchanged bundle, got event %s", <-recorder.Events)
+	}
+
+	// the bundle changes before the build pod starts
+	setTrustedCABundle("new bundle")
+	if _, err := bc.handleActiveBuild(build, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bundle := globalCABundle(); bundle != "new bundle" {
+		t.Errorf("expected the pending build to get the new bundle, got %q", bundle)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, buildutil.BuildTrustedCARefreshedEventReason) {
+			t.Errorf("unexpected event %s", event)
+		}
+	default:
+		t.Errorf("expected an event for the refreshed bundle")
+	}
+
+	// the bundle changes after the build pod started
+	setTrustedCABundle("newer bundle")
+	pod.Status.Phase = corev1.PodRunning
+	if _, err := bc.handleActiveBuild(build, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if bundle := globalCABundle(); bundle != "new bundle" {
+		t.Errorf("expected the running build to keep its bundle, got %q", bundle)
+	}
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,12 +12,15 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	kcontroller "k8s.io/kubernetes/pkg/controller"
 
@@ -62,13 +66,17 @@ type BuildConfigController struct {
 	buildConfigInformer cache.SharedIndexInformer
 
 	queue workqueue.RateLimitingInterface
+	// deletedQueue holds the deleted build configs whose builds have to be cancelled.
+	deletedQueue workqueue.RateLimitingInterface
 
 	buildConfigStoreSynced func() bool
 
 	recorder record.EventRecorder
+
+	buildRetention buildcommon.BuildRetentionPolicy
 }
 
-func NewBuildConfigController(buildClient buildclient.Interface, kubeExternalClient kubernetes.Interface, buildConfigInformer buildinformer.BuildConfigInformer, buildInformer buildinformer.BuildInformer) *BuildConfigController {
+func NewBuildConfigController(buildClient buildclient.Interface, kubeExternalClient kubernetes.Interface, buildConfigInformer buildinformer.BuildConfigInformer, buildInformer buildinformer.BuildInformer, buildRetention buildcommon.BuildRetentionPolicy) *BuildConfigController {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: kubeExternalClient.CoreV1().Events("")})
 
@@ -83,13 +91,17 @@ func NewBuildConfigController(buildClient buildclient.Interface, kubeExternalCli
 
 		buildConfigInformer: buildConfigInformer.Informer(),
 
-		queue:    workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "buildconfig"),
-		recorder: eventBroadcaster.NewRecorder(buildscheme.EncoderScheme, corev1.EventSource{Component: "buildconfig-controller"}),
+		queue:        workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "buildconfig"),
+		deletedQueue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "buildconfig-deleted"),
+		recorder:     eventBroadcaster.NewRecorder(buildscheme.EncoderScheme, corev1.EventSource{Component: "buildconfig-controller"}),
+
+		buildRetention: buildRetention,
 	}
 
 	c.buildConfigInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		UpdateFunc: c.buildConfigUpdated,
 		AddFunc:    c.buildConfigAdded,
+		DeleteFunc: c.buildConfigDeleted,
 	})
 
 	c.buildConfigStoreSynced = c.buildConfigInformer.HasSynced
@@ -99,7 +111,7 @@ func NewBuildConfigController(buildClient buildclient.Interface, kubeExternalCli
 func (c *BuildConfigController) handleBuildConfig(bc *buildv1.BuildConfig) error {
 	klog.V(4).Infof("Handling BuildConfig %s", bcDesc(bc))
 
-	if err := buildcommon.HandleBuildPruning(bc.Name, bc.Namespace, c.buildLister, c.buildConfigLister, c.buildGetter); err != nil {
+	if err := buildcommon.HandleBuildPruning(bc.Name, bc.Namespace, c.buildLister, c.buildConfigLister, c.buildGetter, c.buildRetention); err != nil {
 		utilruntime.HandleError(fmt.Errorf("failed to prune builds for %s/%s: %v", bc.Namespace, bc.Name, err))
 	}
 
@@ -113,6 +125,15 @@ func (c *BuildConfigController) handleBuildConfig(bc *buildv1.BuildConfig) error
 		return nil
 	}
 
+	if skippedGeneration, ok := initialBuildSkippedGeneration(bc); ok {
+		if bc.Generation <= skippedGeneration {
+			return nil
+		}
+		klog.V(4).Infof("BuildConfig %s changed since its initial build was skipped", bcDesc(bc))
+	} else if bc.Annotations[buildutil.SkipInitialBuildAnnotation] == "true" {
+		return c.skipInitialBuild(bc)
+	}
+
 	klog.V(4).Infof("Running build for BuildConfig %s", bcDesc(bc))
 
 	buildTriggerCauses := []buildv1.BuildTriggerCause{}
@@ -141,15 +162,132 @@ func (c *BuildConfigController) handleBuildConfig(bc *buildv1.BuildConfig) error
 			// Caused by a race condition between the ImageChangeTrigger and BuildConfigChangeTrigger
 			if !strings.Contains(instantiateErr.Error(), "does not match the build request LastVersion(0)") {
 				c.recorder.Event(bc, corev1.EventTypeWarning, "BuildConfigInstantiateFailed", instantiateErr.Error())
+				c.recordTriggerFailure(bc, buildutil.NewTriggerFailure(buildv1.ConfigChangeBuildTriggerType, err))
 			}
 			return &configControllerFatalError{err.Error()}
 		} else {
 			instantiateErr = fmt.Errorf("error instantiating Build from BuildConfig %s: %v", bcDesc(bc), err)
 			c.recorder.Event(bc, corev1.EventTypeWarning, "BuildConfigInstantiateFailed", instantiateErr.Error())
+			c.recordTriggerFailure(bc, buildutil.NewTriggerFailure(buildv1.ConfigChangeBuildTriggerType, err))
 			utilruntime.HandleError(instantiateErr)
 		}
 		return instantiateErr
 	}
+	c.recordTriggerFailure(bc, nil)
+	return nil
+}
+
+// recordTriggerFailure records the failure of the config change trigger of the build config to
+// instantiate a build in its last trigger failure annotation, or removes the annotation of an
+// earlier failure when failure is nil. The annotation is patched, the instantiation of a build
+// updates the build config.
+func (c *BuildConfigController) recordTriggerFailure(bc *buildv1.BuildConfig, failure *buildutil.TriggerFailure) {
+	if _, ok := bc.Annotations[buildutil.BuildConfigLastTriggerFailureAnnotation]; !ok && failure == nil {
+		return
+	}
+	patch, err := buildutil.TriggerFailurePatch(failure)
+	if err == nil {
+		_, err = c.buildConfigGetter.BuildConfigs(bc.Namespace).Patch(context.TODO(), bc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("unable to record the last trigger failure of BuildConfig %s: %v", bcDesc(bc), err))
+	}
+}
+
+// initialBuildSkippedGeneration returns the generation of the build config its initial build was
+// skipped at, if it was.
+func initialBuildSkippedGeneration(bc *buildv1.BuildConfig) (int64, bool) {
+	value, ok := bc.Annotations[buildutil.InitialBuildSkippedAnnotation]
+	if !ok {
+		return 0, false
+	}
+	generation, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on BuildConfig %s", buildutil.InitialBuildSkippedAnnotation, value, bcDesc(bc))
+		return 0, false
+	}
+	return generation, true
+}
+
+// skipInitialBuild records on a build config that asked for its initial build to be skipped the
+// generation it was skipped at, so that the build config is only built once its spec changes.
+func (c *BuildConfigController) skipInitialBuild(bc *buildv1.BuildConfig) error {
+	klog.V(4).Infof("Skipping the initial build of BuildConfig %s", bcDesc(bc))
+	bc = bc.DeepCopy()
+	if bc.Annotations == nil {
+		bc.Annotations = map[string]string{}
+	}
+	bc.Annotations[buildutil.InitialBuildSkippedAnnotation] = strconv.FormatInt(bc.Generation, 10)
+	if _, err := c.buildConfigGetter.BuildConfigs(bc.Namespace).Update(context.TODO(), bc, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("unable to record the skipped initial build of BuildConfig %s: %v", bcDesc(bc), err)
+	}
+	c.recorder.Eventf(bc, corev1.EventTypeNormal, "BuildConfigInitialBuildSkipped", "Skipped the initial build of BuildConfig %s/%s", bc.Namespace, bc.Name)
+	return nil
+}
+
+// deletedBuildConfig identifies a deleted build config in the deletedQueue.
+type deletedBuildConfig struct {
+	namespace string
+	name      string
+	// keepRunningBuilds is true when the running builds of the build config are not cancelled.
+	keepRunningBuilds bool
+}
+
+// handleBuildConfigDeleted cancels the builds of a deleted build config that did not complete, so
+// that they do not run against a build config that no longer exists. The builds are marked as
+// cancelled and the build controller deletes their pods.
+func (c *BuildConfigController) handleBuildConfigDeleted(deleted deletedBuildConfig) error {
+	if _, err := c.buildConfigLister.BuildConfigs(deleted.namespace).Get(deleted.name); err == nil {
+		klog.V(4).Infof("BuildConfig %s/%s was recreated, not cancelling its builds", deleted.namespace, deleted.name)
+		return nil
+	}
+
+	builds, err := buildutil.BuildConfigBuildsFromLister(c.buildLister, deleted.namespace, deleted.name, func(b *buildv1.Build) bool {
+		return shouldCancelForDeletedBuildConfig(b, deleted.keepRunningBuilds)
+	})
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, b := range builds {
+		if err := c.cancelBuildForDeletedBuildConfig(b, deleted.keepRunningBuilds); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func shouldCancelForDeletedBuildConfig(build *buildv1.Build, keepRunningBuilds bool) bool {
+	if buildutil.IsBuildComplete(build) || build.Status.Cancelled {
+		return false
+	}
+	return !keepRunningBuilds || build.Status.Phase != buildv1.BuildPhaseRunning
+}
+
+func (c *BuildConfigController) cancelBuildForDeletedBuildConfig(build *buildv1.Build, keepRunningBuilds bool) error {
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := c.buildGetter.Builds(build.Namespace).Get(context.TODO(), build.Name, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !shouldCancelForDeletedBuildConfig(latest, keepRunningBuilds) {
+			return nil
+		}
+		latest = latest.DeepCopy()
+		latest.Status.Cancelled = true
+		latest.Status.Reason = buildutil.StatusReasonBuildConfigDeleted
+		latest.Status.Message = "The build was cancelled because its BuildConfig was deleted."
+		_, err = c.buildGetter.Builds(build.Namespace).Update(context.TODO(), latest, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel build %s/%s of deleted BuildConfig: %v", build.Namespace, build.Name, err)
+	}
+	klog.V(4).Infof("Cancelled build %s/%s of deleted BuildConfig", build.Namespace, build.Name)
 	return nil
 }
 
@@ -178,6 +316,29 @@ func (c *BuildConfigController) buildConfigUpdated(old, cur interface{}) {
 	c.enqueueBuildConfig(bc)
 }
 
+// buildConfigDeleted is called by the buildconfig informer event handler whenever a
+// buildconfig is deleted
+func (c *BuildConfigController) buildConfigDeleted(obj interface{}) {
+	bc, ok := obj.(*buildv1.BuildConfig)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone: %+v", obj))
+			return
+		}
+		bc, ok = tombstone.Obj.(*buildv1.BuildConfig)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not a buildconfig: %+v", obj))
+			return
+		}
+	}
+	c.deletedQueue.Add(deletedBuildConfig{
+		namespace:         bc.Namespace,
+		name:              bc.Name,
+		keepRunningBuilds: bc.Annotations[buildutil.BuildConfigKeepRunningBuildsAnnotation] == "true",
+	})
+}
+
 func (c *BuildConfigController) getImageChangeTriggerInputReference(bc *buildv1.BuildConfig, trigger buildv1.BuildTriggerPolicy) *corev1.ObjectReference {
 	if trigger.ImageChange == nil {
 		return nil
@@ -202,6 +363,7 @@ func (c *BuildConfigController) enqueueBuildConfig(bc *buildv1.BuildConfig) {
 func (c *BuildConfigController) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
+	defer c.deletedQueue.ShutDown()
 
 	// Wait for the controller stores to sync before starting any work in this controller.
 	if !cache.WaitForCacheSync(stopCh, c.buildConfigStoreSynced) {
@@ -213,6 +375,7 @@ func (c *BuildConfigController) Run(workers int, stopCh <-chan struct{}) {
 
 	for i := 0; i < workers; i++ {
 		go wait.Until(c.worker, time.Second, stopCh)
+		go wait.Until(c.deletedWorker, time.Second, stopCh)
 	}
 
 	<-stopCh
@@ -251,6 +414,37 @@ func (c *BuildConfigController) work() bool {
 	return false
 }
 
+func (c *BuildConfigController) deletedWorker() {
+	for {
+		if quit := c.deletedWork(); quit {
+			return
+		}
+	}
+}
+
+// deletedWork gets the next deleted buildconfig from the deletedQueue and cancels its builds
+func (c *BuildConfigController) deletedWork() bool {
+	key, quit := c.deletedQueue.Get()
+	if quit {
+		return true
+	}
+	defer c.deletedQueue.Done(key)
+
+	err := c.handleBuildConfigDeleted(key.(deletedBuildConfig))
+	if err == nil {
+		c.deletedQueue.Forget(key)
+		return false
+	}
+	if c.deletedQueue.NumRequeues(key) < maxRetries {
+		klog.V(4).Infof("Retrying key %v: %v", key, err)
+		c.deletedQueue.AddRateLimited(key)
+		return false
+	}
+	utilruntime.HandleError(err)
+	c.deletedQueue.Forget(key)
+	return false
+}
+
 // handleError is called by the main work loop to check the return of calling handleBuildConfig
 // If an error occurred, then the key is re-added to the queue unless it has been retried too many
 // times.
@@ -1,17 +1,27 @@
 package controller
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	buildlister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 
 	"github.com/openshift/client-go/build/clientset/versioned/fake"
 )
@@ -106,6 +116,257 @@ func TestHandleBuildConfig(t *testing.T) {
 
 }
 
+func TestHandleBuildConfigTriggerFailure(t *testing.T) {
+	bc := buildConfigWithConfigChangeTrigger()
+	buildClient := fake.NewSimpleClientset(bc)
+	denied := true
+	buildClient.PrependReactor("create", "buildconfigs", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetSubresource() != "instantiate" {
+			return false, nil, nil
+		}
+		if denied {
+			return true, nil, kerrors.NewForbidden(buildv1.Resource("builds"), "testBuildConfig-1", fmt.Errorf("admission webhook \"builds.example.com\" denied the request"))
+		}
+		return true, &buildv1.Build{}, nil
+	})
+	recorder := record.NewFakeRecorder(10)
+	controller := &BuildConfigController{
+		buildLister:       &okBuildLister{},
+		buildConfigGetter: buildClient.BuildV1(),
+		buildGetter:       buildClient.BuildV1(),
+		buildConfigLister: &okBuildConfigGetter{BuildConfig: bc},
+		recorder:          recorder,
+	}
+	lastTriggerFailure := func() (string, bool) {
+		updated, err := buildClient.BuildV1().BuildConfigs(bc.Namespace).Get(context.TODO(), bc.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		value, ok := updated.Annotations[buildutil.BuildConfigLastTriggerFailureAnnotation]
+		return value, ok
+	}
+
+	// the admission webhook denies the build
+	if err := controller.handleBuildConfig(bc); !IsFatal(err) {
+		t.Fatalf("expected a fatal error, got %v", err)
+	}
+	value, ok := lastTriggerFailure()
+	if !ok {
+		t.Fatalf("expected the trigger failure to be recorded")
+	}
+	var failure buildutil.TriggerFailure
+	if err := json.Unmarshal([]byte(value), &failure); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failure.Reason != string(metav1.StatusReasonForbidden) || failure.TriggerType != buildv1.ConfigChangeBuildTriggerType || !strings.Contains(failure.Message, "denied the request") {
+		t.Errorf("unexpected trigger failure %#v", failure)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning BuildConfigInstantiateFailed") {
+			t.Errorf("unexpected event %s", event)
+		}
+	default:
+		t.Errorf("expected a warning event on the build config")
+	}
+
+	// the next build is instantiated
+	denied = false
+	bc.Annotations = map[string]string{buildutil.BuildConfigLastTriggerFailureAnnotation: value}
+	if err := controller.handleBuildConfig(bc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value, ok := lastTriggerFailure(); ok {
+		t.Errorf("expected the trigger failure to be cleared, got %s", value)
+	}
+}
+
+func TestHandleBuildConfigSkipInitialBuild(t *testing.T) {
+	bc := buildConfigWithConfigChangeTrigger()
+	bc.Namespace = "namespace"
+	bc.Generation = 1
+	bc.Annotations = map[string]string{buildutil.SkipInitialBuildAnnotation: "true"}
+
+	buildClient := fake.NewSimpleClientset(bc)
+	instantiated := 0
+	buildClient.PrependReactor("create", "buildconfigs", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.(ktesting.CreateAction).GetSubresource() != "instantiate" {
+			return false, nil, nil
+		}
+		instantiated++
+		return true, &buildv1.Build{}, nil
+	})
+	controller := &BuildConfigController{
+		buildLister:       &okBuildLister{},
+		buildConfigGetter: buildClient.BuildV1(),
+		buildGetter:       buildClient.BuildV1(),
+		buildConfigLister: &okBuildConfigGetter{BuildConfig: bc},
+		recorder:          &record.FakeRecorder{},
+	}
+	handle := func() *buildv1.BuildConfig {
+		current, err := buildClient.BuildV1().BuildConfigs(bc.Namespace).Get(context.TODO(), bc.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := controller.handleBuildConfig(current); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		current, err = buildClient.BuildV1().BuildConfigs(bc.Namespace).Get(context.TODO(), bc.Name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return current
+	}
+
+	// the build config is created
+	current := handle()
+	if instantiated != 0 {
+		t.Fatalf("expected the initial build to be skipped")
+	}
+	if current.Annotations[buildutil.InitialBuildSkippedAnnotation] != "1" {
+		t.Fatalf("expected the skipped initial build to be recorded, got annotations %v", current.Annotations)
+	}
+
+	// and resynced without changes
+	handle()
+	if instantiated != 0 {
+		t.Fatalf("expected no build before the build config changes")
+	}
+
+	// its spec changes
+	current.Generation = 2
+	current.Spec.Strategy.SourceStrategy.From.Name = "builderimage:v2"
+	if _, err := buildClient.BuildV1().BuildConfigs(bc.Namespace).Update(context.TODO(), current, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	handle()
+	if instantiated != 1 {
+		t.Errorf("expected the spec change to start a build, got %d builds", instantiated)
+	}
+}
+
+func TestHandleBuildConfigDeleted(t *testing.T) {
+	build := func(name string, phase buildv1.BuildPhase) *buildv1.Build {
+		b := &buildv1.Build{}
+		b.Name = name
+		b.Namespace = "namespace"
+		b.Labels = map[string]string{buildv1.BuildConfigLabel: "testBuildConfig"}
+		b.Annotations = map[string]string{buildv1.BuildConfigAnnotation: "testBuildConfig"}
+		b.Status.Phase = phase
+		return b
+	}
+	builds := func() []*buildv1.Build {
+		other := build("other-1", buildv1.BuildPhaseNew)
+		other.Labels[buildv1.BuildConfigLabel] = "other"
+		other.Annotations[buildv1.BuildConfigAnnotation] = "other"
+		return []*buildv1.Build{
+			build("testBuildConfig-1", buildv1.BuildPhaseComplete),
+			build("testBuildConfig-2", buildv1.BuildPhaseFailed),
+			build("testBuildConfig-3", buildv1.BuildPhaseRunning),
+			build("testBuildConfig-4", buildv1.BuildPhasePending),
+			build("testBuildConfig-5", buildv1.BuildPhaseNew),
+			other,
+		}
+	}
+
+	tests := []struct {
+		name              string
+		keepRunningBuilds bool
+		recreated         bool
+		expectCancelled   []string
+	}{
+		{
+			name:            "non-terminal builds are cancelled",
+			expectCancelled: []string{"testBuildConfig-3", "testBuildConfig-4", "testBuildConfig-5"},
+		},
+		{
+			name:              "running builds are kept",
+			keepRunningBuilds: true,
+			expectCancelled:   []string{"testBuildConfig-4", "testBuildConfig-5"},
+		},
+		{
+			name:      "recreated build config",
+			recreated: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var objects []runtime.Object
+			buildIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			for _, b := range builds() {
+				objects = append(objects, b)
+				buildIndexer.Add(b)
+			}
+			buildConfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			if tc.recreated {
+				bc := baseBuildConfig()
+				bc.Namespace = "namespace"
+				buildConfigIndexer.Add(bc)
+			}
+			buildClient := fake.NewSimpleClientset(objects...)
+			controller := &BuildConfigController{
+				buildLister:       buildlister.NewBuildLister(buildIndexer),
+				buildGetter:       buildClient.BuildV1(),
+				buildConfigLister: buildlister.NewBuildConfigLister(buildConfigIndexer),
+				recorder:          &record.FakeRecorder{},
+			}
+
+			err := controller.handleBuildConfigDeleted(deletedBuildConfig{
+				namespace:         "namespace",
+				name:              "testBuildConfig",
+				keepRunningBuilds: tc.keepRunningBuilds,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var cancelled []string
+			for _, action := range buildClient.Actions() {
+				if action.GetVerb() != "update" {
+					continue
+				}
+				b := action.(ktesting.UpdateAction).GetObject().(*buildv1.Build)
+				if !b.Status.Cancelled || b.Status.Reason != buildutil.StatusReasonBuildConfigDeleted {
+					t.Errorf("expected build %s to be cancelled with reason %s, got %#v", b.Name, buildutil.StatusReasonBuildConfigDeleted, b.Status)
+				}
+				cancelled = append(cancelled, b.Name)
+			}
+			sort.Strings(cancelled)
+			if !reflect.DeepEqual(cancelled, tc.expectCancelled) {
+				t.Errorf("expected builds %v to be cancelled, got %v", tc.expectCancelled, cancelled)
+			}
+		})
+	}
+}
+
+func TestBuildConfigDeleted(t *testing.T) {
+	regular := baseBuildConfig()
+	regular.Namespace = "namespace"
+	keep := baseBuildConfig()
+	keep.Namespace = "namespace"
+	keep.Name = "keep"
+	keep.Annotations = map[string]string{buildutil.BuildConfigKeepRunningBuildsAnnotation: "true"}
+
+	controller := &BuildConfigController{
+		deletedQueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	controller.buildConfigDeleted(regular)
+	controller.buildConfigDeleted(cache.DeletedFinalStateUnknown{Key: "namespace/keep", Obj: keep})
+
+	expected := []deletedBuildConfig{
+		{namespace: "namespace", name: "testBuildConfig"},
+		{namespace: "namespace", name: "keep", keepRunningBuilds: true},
+	}
+	for _, e := range expected {
+		key, _ := controller.deletedQueue.Get()
+		if key != e {
+			t.Errorf("expected %#v to be queued, got %#v", e, key)
+		}
+		controller.deletedQueue.Done(key)
+	}
+}
+
 func baseBuildConfig() *buildv1.BuildConfig {
 	bc := &buildv1.BuildConfig{}
 	bc.Name = "testBuildConfig"
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"strconv"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/clock"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	buildclientv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
@@ -35,9 +39,64 @@ func (b ByCreationTimestamp) Less(i, j int) bool {
 	return !b[j].CreationTimestamp.Time.After(b[i].CreationTimestamp.Time)
 }
 
-// HandleBuildPruning handles the deletion of old successful and failed builds
-// based on settings in the BuildConfig.
-func HandleBuildPruning(buildConfigName string, namespace string, buildLister buildlisterv1.BuildLister, buildConfigGetter buildlisterv1.BuildConfigLister, buildDeleter buildclientv1.BuildsGetter) error {
+// BuildRetentionPolicy holds the age based pruning settings shared by all build configs.
+type BuildRetentionPolicy struct {
+	// DefaultMaxAge is the maximum age of completed builds of build configs that do not set the
+	// buildutil.BuildRetentionAnnotation. Builds are not pruned by age when it is zero.
+	DefaultMaxAge time.Duration
+	// Clock is used to compute the age of builds. The real clock is used when unset.
+	Clock clock.PassiveClock
+}
+
+// maxAge returns the maximum age of the completed builds of the given build config.
+func (p BuildRetentionPolicy) maxAge(buildConfig *buildv1.BuildConfig) time.Duration {
+	value, ok := buildConfig.Annotations[buildutil.BuildRetentionAnnotation]
+	if !ok {
+		return p.DefaultMaxAge
+	}
+	maxAge, err := time.ParseDuration(value)
+	if err != nil || maxAge < 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on BuildConfig %s/%s", buildutil.BuildRetentionAnnotation, value, buildConfig.Namespace, buildConfig.Name)
+		return p.DefaultMaxAge
+	}
+	return maxAge
+}
+
+// cancelledBuildsHistoryLimit returns the number of cancelled builds of the build config to keep,
+// or false if cancelled builds share the failed builds history limit.
+func cancelledBuildsHistoryLimit(buildConfig *buildv1.BuildConfig) (int, bool) {
+	value, ok := buildConfig.Annotations[buildutil.CancelledBuildsHistoryLimitAnnotation]
+	if !ok {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		klog.V(2).Infof("Ignoring invalid %s annotation %q on BuildConfig %s/%s", buildutil.CancelledBuildsHistoryLimitAnnotation, value, buildConfig.Namespace, buildConfig.Name)
+		return 0, false
+	}
+	return limit, true
+}
+
+func (p BuildRetentionPolicy) now() time.Time {
+	if p.Clock == nil {
+		return time.Now()
+	}
+	return p.Clock.Now()
+}
+
+// buildFinishedAt returns the time a completed build finished, falling back to its creation time.
+func buildFinishedAt(build *buildv1.Build) time.Time {
+	if build.Status.CompletionTimestamp != nil {
+		return build.Status.CompletionTimestamp.Time
+	}
+	return build.CreationTimestamp.Time
+}
+
+// HandleBuildPruning handles the deletion of old successful, failed and cancelled
+// builds based on settings in the BuildConfig and the retention policy. Builds exceeding
+// either the history limits or the maximum age are deleted, except for the latest
+// successful build which is never pruned by age.
+func HandleBuildPruning(buildConfigName string, namespace string, buildLister buildlisterv1.BuildLister, buildConfigGetter buildlisterv1.BuildConfigLister, buildDeleter buildclientv1.BuildsGetter, retention BuildRetentionPolicy) error {
 	klog.V(4).Infof("Handling build pruning for %s/%s", namespace, buildConfigName)
 
 	buildConfig, err := buildConfigGetter.BuildConfigs(namespace).Get(buildConfigName)
@@ -48,13 +107,34 @@ func HandleBuildPruning(buildConfigName string, namespace string, buildLister bu
 	var buildsToDelete []*buildv1.Build
 	var errList []error
 
-	if buildConfig.Spec.SuccessfulBuildsHistoryLimit != nil {
-		successfulBuilds, err := buildutil.BuildConfigBuildsFromLister(buildLister, namespace, buildConfigName, func(build *buildv1.Build) bool { return build.Status.Phase == buildv1.BuildPhaseComplete })
+	successfulBuilds, err := buildutil.BuildConfigBuildsFromLister(buildLister, namespace, buildConfigName, func(build *buildv1.Build) bool { return build.Status.Phase == buildv1.BuildPhaseComplete })
+	if err != nil {
+		return err
+	}
+	sort.Sort(ByCreationTimestamp(successfulBuilds))
+
+	// cancelled builds are kept separately from the failed builds when the build config has a
+	// cancelled builds history limit, so that bursts of cancellations do not prune failed builds
+	cancelledLimit, separateCancelled := cancelledBuildsHistoryLimit(buildConfig)
+	failedBuilds, err := buildutil.BuildConfigBuildsFromLister(buildLister, namespace, buildConfigName, func(build *buildv1.Build) bool {
+		return build.Status.Phase == buildv1.BuildPhaseFailed || build.Status.Phase == buildv1.BuildPhaseError ||
+			(build.Status.Phase == buildv1.BuildPhaseCancelled && !separateCancelled)
+	})
+	if err != nil {
+		return err
+	}
+	sort.Sort(ByCreationTimestamp(failedBuilds))
+
+	var cancelledBuilds []*buildv1.Build
+	if separateCancelled {
+		cancelledBuilds, err = buildutil.BuildConfigBuildsFromLister(buildLister, namespace, buildConfigName, func(build *buildv1.Build) bool { return build.Status.Phase == buildv1.BuildPhaseCancelled })
 		if err != nil {
 			return err
 		}
-		sort.Sort(ByCreationTimestamp(successfulBuilds))
+		sort.Sort(ByCreationTimestamp(cancelledBuilds))
+	}
 
+	if buildConfig.Spec.SuccessfulBuildsHistoryLimit != nil {
 		successfulBuildsHistoryLimit := int(*buildConfig.Spec.SuccessfulBuildsHistoryLimit)
 		klog.V(5).Infof("Current successful builds: %v, SuccessfulBuildsHistoryLimit: %v", len(successfulBuilds), successfulBuildsHistoryLimit)
 		if len(successfulBuilds) > successfulBuildsHistoryLimit {
@@ -64,14 +144,6 @@ func HandleBuildPruning(buildConfigName string, namespace string, buildLister bu
 	}
 
 	if buildConfig.Spec.FailedBuildsHistoryLimit != nil {
-		failedBuilds, err := buildutil.BuildConfigBuildsFromLister(buildLister, namespace, buildConfigName, func(build *buildv1.Build) bool {
-			return build.Status.Phase == buildv1.BuildPhaseFailed || build.Status.Phase == buildv1.BuildPhaseCancelled || build.Status.Phase == buildv1.BuildPhaseError
-		})
-		if err != nil {
-			return err
-		}
-		sort.Sort(ByCreationTimestamp(failedBuilds))
-
 		failedBuildsHistoryLimit := int(*buildConfig.Spec.FailedBuildsHistoryLimit)
 		klog.V(5).Infof("Current failed builds: %v, FailedBuildsHistoryLimit: %v", len(failedBuilds), failedBuildsHistoryLimit)
 		if len(failedBuilds) > failedBuildsHistoryLimit {
@@ -80,9 +152,38 @@ func HandleBuildPruning(buildConfigName string, namespace string, buildLister bu
 		}
 	}
 
-	for i, b := range buildsToDelete {
+	if separateCancelled {
+		klog.V(5).Infof("Current cancelled builds: %v, CancelledBuildsHistoryLimit: %v", len(cancelledBuilds), cancelledLimit)
+		if len(cancelledBuilds) > cancelledLimit {
+			klog.V(5).Infof("Preparing to prune %v of %v cancelled builds", len(cancelledBuilds)-cancelledLimit, len(cancelledBuilds))
+			buildsToDelete = append(buildsToDelete, cancelledBuilds[cancelledLimit:]...)
+		}
+	}
+
+	if maxAge := retention.maxAge(buildConfig); maxAge > 0 {
+		cutoff := retention.now().Add(-maxAge)
+		klog.V(5).Infof("Preparing to prune builds finished before %v", cutoff)
+		candidates := append([]*buildv1.Build{}, failedBuilds...)
+		candidates = append(candidates, cancelledBuilds...)
+		// the latest successful build is kept regardless of its age
+		if len(successfulBuilds) > 1 {
+			candidates = append(candidates, successfulBuilds[1:]...)
+		}
+		for _, b := range candidates {
+			if buildFinishedAt(b).Before(cutoff) {
+				buildsToDelete = append(buildsToDelete, b)
+			}
+		}
+	}
+
+	deleted := sets.New[string]()
+	for _, b := range buildsToDelete {
+		if deleted.Has(b.Name) {
+			continue
+		}
+		deleted.Insert(b.Name)
 		klog.V(4).Infof("Pruning build: %s/%s", b.Namespace, b.Name)
-		if err := buildDeleter.Builds(b.Namespace).Delete(context.TODO(), buildsToDelete[i].Name, metav1.DeleteOptions{}); err != nil {
+		if err := buildDeleter.Builds(b.Namespace).Delete(context.TODO(), b.Name, metav1.DeleteOptions{}); err != nil {
 			errList = append(errList, err)
 		}
 	}
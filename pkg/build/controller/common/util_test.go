@@ -14,6 +14,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	buildfake "github.com/openshift/client-go/build/clientset/versioned/fake"
@@ -174,7 +175,7 @@ func TestHandleBuildPruning(t *testing.T) {
 	buildLister := &fakeBuildLister{client: buildClient.BuildV1(), namespace: "namespace"}
 	buildConfigLister := &fakeBuildConfigLister{client: buildClient.BuildV1(), namespace: "namespace"}
 
-	if err := HandleBuildPruning(bcName, build.Namespace, buildLister, buildConfigLister, buildClient.BuildV1()); err != nil {
+	if err := HandleBuildPruning(bcName, build.Namespace, buildLister, buildConfigLister, buildClient.BuildV1(), BuildRetentionPolicy{}); err != nil {
 		t.Errorf("error pruning builds: %v", err)
 	}
 
@@ -199,3 +200,222 @@ func TestHandleBuildPruning(t *testing.T) {
 	}
 
 }
+
+func TestHandleBuildPruningByAge(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	finished := func(name string, phase buildv1.BuildPhase, age time.Duration) buildv1.Build {
+		stamp := metav1.NewTime(now.Add(-age - time.Minute))
+		build := mockBuild(name, phase, &stamp)
+		completed := metav1.NewTime(now.Add(-age))
+		build.Status.CompletionTimestamp = &completed
+		return build
+	}
+	limit := func(i int32) *int32 { return &i }
+
+	tests := []struct {
+		name              string
+		annotations       map[string]string
+		successfulLimit   *int32
+		failedLimit       *int32
+		defaultMaxAge     time.Duration
+		builds            []buildv1.Build
+		expectedRemaining []string
+	}{
+		{
+			name:        "annotation prunes old builds",
+			annotations: map[string]string{buildutil.BuildRetentionAnnotation: "24h"},
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseComplete, 48*time.Hour),
+				finished("myapp-3", buildv1.BuildPhaseFailed, 2*time.Hour),
+				finished("myapp-4", buildv1.BuildPhaseCancelled, 30*time.Hour),
+				finished("myapp-5", buildv1.BuildPhaseError, 30*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1", "myapp-3"},
+		},
+		{
+			name:          "cluster default applies without annotation",
+			defaultMaxAge: 24 * time.Hour,
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseFailed, 48*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1"},
+		},
+		{
+			name:          "annotation overrides cluster default",
+			annotations:   map[string]string{buildutil.BuildRetentionAnnotation: "0"},
+			defaultMaxAge: 24 * time.Hour,
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseFailed, 48*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1", "myapp-2"},
+		},
+		{
+			name:          "invalid annotation falls back to cluster default",
+			annotations:   map[string]string{buildutil.BuildRetentionAnnotation: "a week"},
+			defaultMaxAge: 24 * time.Hour,
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseFailed, 48*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1"},
+		},
+		{
+			name:        "latest successful build is never pruned by age",
+			annotations: map[string]string{buildutil.BuildRetentionAnnotation: "24h"},
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, 72*time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseComplete, 96*time.Hour),
+				finished("myapp-3", buildv1.BuildPhaseFailed, time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1", "myapp-3"},
+		},
+		{
+			name:            "history limit is stricter than age",
+			annotations:     map[string]string{buildutil.BuildRetentionAnnotation: "24h"},
+			successfulLimit: limit(1),
+			failedLimit:     limit(1),
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseComplete, 2*time.Hour),
+				finished("myapp-3", buildv1.BuildPhaseFailed, time.Hour),
+				finished("myapp-4", buildv1.BuildPhaseFailed, 2*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1", "myapp-3"},
+		},
+		{
+			name:            "age is stricter than history limit",
+			annotations:     map[string]string{buildutil.BuildRetentionAnnotation: "24h"},
+			successfulLimit: limit(5),
+			failedLimit:     limit(5),
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseComplete, 48*time.Hour),
+				finished("myapp-3", buildv1.BuildPhaseFailed, 48*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1"},
+		},
+		{
+			name:        "running builds are not pruned",
+			annotations: map[string]string{buildutil.BuildRetentionAnnotation: "24h"},
+			builds: []buildv1.Build{
+				finished("myapp-1", buildv1.BuildPhaseComplete, time.Hour),
+				finished("myapp-2", buildv1.BuildPhaseRunning, 48*time.Hour),
+			},
+			expectedRemaining: []string{"myapp-1", "myapp-2"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			buildConfig := mockBuildConfig("myapp")
+			buildConfig.Annotations = tc.annotations
+			buildConfig.Spec.SuccessfulBuildsHistoryLimit = tc.successfulLimit
+			buildConfig.Spec.FailedBuildsHistoryLimit = tc.failedLimit
+
+			objects := []runtime.Object{&buildConfig}
+			for i := range tc.builds {
+				objects = append(objects, &tc.builds[i])
+			}
+			buildClient := buildfake.NewSimpleClientset(objects...)
+			buildLister := &fakeBuildLister{client: buildClient.BuildV1(), namespace: "namespace"}
+			buildConfigLister := &fakeBuildConfigLister{client: buildClient.BuildV1(), namespace: "namespace"}
+
+			retention := BuildRetentionPolicy{DefaultMaxAge: tc.defaultMaxAge, Clock: clocktesting.NewFakePassiveClock(now)}
+			if err := HandleBuildPruning(buildConfig.Name, "namespace", buildLister, buildConfigLister, buildClient.BuildV1(), retention); err != nil {
+				t.Fatalf("error pruning builds: %v", err)
+			}
+
+			remaining, err := buildClient.BuildV1().Builds("namespace").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, build := range remaining.Items {
+				names = append(names, build.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.expectedRemaining) {
+				t.Errorf("expected remaining builds %v, got %v", tc.expectedRemaining, names)
+			}
+		})
+	}
+}
+
+func TestHandleBuildPruningCancelledBuilds(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	created := func(name string, phase buildv1.BuildPhase, age time.Duration) buildv1.Build {
+		stamp := metav1.NewTime(now.Add(-age))
+		return mockBuild(name, phase, &stamp)
+	}
+	builds := []buildv1.Build{
+		created("myapp-1", buildv1.BuildPhaseCancelled, time.Minute),
+		created("myapp-2", buildv1.BuildPhaseCancelled, 2*time.Minute),
+		created("myapp-3", buildv1.BuildPhaseCancelled, 3*time.Minute),
+		created("myapp-4", buildv1.BuildPhaseFailed, time.Hour),
+		created("myapp-5", buildv1.BuildPhaseError, 2*time.Hour),
+		created("myapp-6", buildv1.BuildPhaseFailed, 3*time.Hour),
+		created("myapp-7", buildv1.BuildPhaseComplete, 4*time.Hour),
+	}
+
+	tests := []struct {
+		name              string
+		annotations       map[string]string
+		expectedRemaining []string
+	}{
+		{
+			name:              "cancelled builds share the failed builds history limit",
+			expectedRemaining: []string{"myapp-1", "myapp-2", "myapp-3", "myapp-7"},
+		},
+		{
+			name:              "cancelled builds history limit",
+			annotations:       map[string]string{buildutil.CancelledBuildsHistoryLimitAnnotation: "1"},
+			expectedRemaining: []string{"myapp-1", "myapp-4", "myapp-5", "myapp-6", "myapp-7"},
+		},
+		{
+			name:              "no cancelled builds kept",
+			annotations:       map[string]string{buildutil.CancelledBuildsHistoryLimitAnnotation: "0"},
+			expectedRemaining: []string{"myapp-4", "myapp-5", "myapp-6", "myapp-7"},
+		},
+		{
+			name:              "invalid cancelled builds history limit",
+			annotations:       map[string]string{buildutil.CancelledBuildsHistoryLimitAnnotation: "-1"},
+			expectedRemaining: []string{"myapp-1", "myapp-2", "myapp-3", "myapp-7"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// keeps 2 successful and 3 failed builds
+			buildConfig := mockBuildConfig("myapp")
+			buildConfig.Annotations = tc.annotations
+
+			objects := []runtime.Object{&buildConfig}
+			for i := range builds {
+				objects = append(objects, builds[i].DeepCopy())
+			}
+			buildClient := buildfake.NewSimpleClientset(objects...)
+			buildLister := &fakeBuildLister{client: buildClient.BuildV1(), namespace: "namespace"}
+			buildConfigLister := &fakeBuildConfigLister{client: buildClient.BuildV1(), namespace: "namespace"}
+
+			if err := HandleBuildPruning(buildConfig.Name, "namespace", buildLister, buildConfigLister, buildClient.BuildV1(), BuildRetentionPolicy{}); err != nil {
+				t.Fatalf("error pruning builds: %v", err)
+			}
+
+			remaining, err := buildClient.BuildV1().Builds("namespace").List(context.TODO(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, build := range remaining.Items {
+				names = append(names, build.Name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, tc.expectedRemaining) {
+				t.Errorf("expected remaining builds %v, got %v", tc.expectedRemaining, names)
+			}
+		})
+	}
+}
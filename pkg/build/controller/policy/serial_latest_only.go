@@ -2,6 +2,7 @@ package policy
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"k8s.io/klog/v2"
@@ -54,7 +55,8 @@ func (s *SerialLatestOnlyPolicy) Handles(policy buildv1.BuildRunPolicy) bool {
 }
 
 // cancelPreviousBuilds cancels all queued builds that have the build sequence number
-// lower than the given build. It retries the cancellation in case of conflict.
+// lower than the given build, with the SupersededByNewerBuild reason. It retries the
+// cancellation in case of conflict.
 func (s *SerialLatestOnlyPolicy) cancelPreviousBuilds(build *buildv1.Build) []error {
 	bcName := sharedbuildutil.ConfigNameForBuild(build)
 	if len(bcName) == 0 {
@@ -67,7 +69,7 @@ func (s *SerialLatestOnlyPolicy) cancelPreviousBuilds(build *buildv1.Build) []er
 	builds, err := buildutil.BuildConfigBuildsFromLister(s.BuildLister, build.Namespace, bcName, func(b *buildv1.Build) bool {
 		// Do not cancel the complete builds, builds that were already cancelled, or
 		// running builds.
-		if buildutil.IsBuildComplete(b) || b.Status.Phase == buildv1.BuildPhaseRunning {
+		if buildutil.IsBuildComplete(b) || b.Status.Cancelled || b.Status.Phase == buildv1.BuildPhaseRunning {
 			return false
 		}
 
@@ -85,6 +87,8 @@ func (s *SerialLatestOnlyPolicy) cancelPreviousBuilds(build *buildv1.Build) []er
 		err := wait.Poll(500*time.Millisecond, 5*time.Second, func() (bool, error) {
 			b = b.DeepCopy()
 			b.Status.Cancelled = true
+			b.Status.Reason = buildutil.StatusReasonSupersededByNewerBuild
+			b.Status.Message = fmt.Sprintf("The build was cancelled because build %s is newer.", build.Name)
 			_, err := s.BuildUpdater.Builds(b.Namespace).Update(context.TODO(), b, metav1.UpdateOptions{})
 			if err != nil && errors.IsConflict(err) {
 				klog.V(5).Infof("Error cancelling build %s/%s: %v (will retry)", b.Namespace, b.Name, err)
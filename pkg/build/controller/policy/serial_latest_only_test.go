@@ -6,6 +6,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 
 	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 )
 
 func TestSerialLatestOnlyIsRunnableNewBuilds(t *testing.T) {
@@ -86,6 +87,43 @@ func TestSerialLatestOnlyIsRunnableMixedRunning(t *testing.T) {
 	}
 }
 
+func TestSerialLatestOnlyCancelsSupersededBuilds(t *testing.T) {
+	allBuilds := []buildv1.Build{
+		addBuild("build-1", "sample-bc", buildv1.BuildPhaseRunning, buildv1.BuildRunPolicySerialLatestOnly),
+		addBuild("build-2", "sample-bc", buildv1.BuildPhaseNew, buildv1.BuildRunPolicySerialLatestOnly),
+		addBuild("build-3", "sample-bc", buildv1.BuildPhasePending, buildv1.BuildRunPolicySerialLatestOnly),
+		addBuild("build-4", "sample-bc", buildv1.BuildPhaseNew, buildv1.BuildRunPolicySerialLatestOnly),
+	}
+	client := newTestClient(allBuilds...)
+	lister := &fakeBuildLister{client}
+	policy := SerialLatestOnlyPolicy{BuildLister: lister, BuildUpdater: client}
+
+	// Only the newest build is handled, while build-1 is still running.
+	runnable, err := policy.IsRunnable(&allBuilds[3])
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if runnable {
+		t.Errorf("build-4 should not be runnable while build-1 is running")
+	}
+	builds, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, b := range builds {
+		switch b.Name {
+		case "build-2", "build-3":
+			if !b.Status.Cancelled || b.Status.Reason != buildutil.StatusReasonSupersededByNewerBuild {
+				t.Errorf("expected %s to be cancelled with reason %s, got %#v", b.Name, buildutil.StatusReasonSupersededByNewerBuild, b.Status)
+			}
+		default:
+			if b.Status.Cancelled {
+				t.Errorf("expected %s not to be cancelled", b.Name)
+			}
+		}
+	}
+}
+
 func TestSerialLatestOnlyIsRunnableBuildsWithErrors(t *testing.T) {
 	builds := []buildv1.Build{
 		addBuild("build-1", "sample-bc", buildv1.BuildPhaseNew, buildv1.BuildRunPolicySerialLatestOnly),
@@ -141,5 +141,8 @@ func (bs *CustomBuildStrategy) CreateBuildPod(build *buildv1.Build, additionalCA
 		setupBuilderAutonsUser(build, strategy.Env, pod)
 		setupBuilderDeviceFUSE(pod)
 	}
+	if err := setupAbsoluteInputSources(pod, &pod.Spec.Containers[0], build.Spec.Source.Secrets, build.Spec.Source.ConfigMaps); err != nil {
+		return pod, err
+	}
 	return pod, nil
 }
@@ -214,6 +214,9 @@ func (bs *DockerBuildStrategy) CreateBuildPod(build *buildv1.Build, additionalCA
 		setupBuilderDeviceFUSE(pod)
 	}
 	setupBlobCache(pod)
+	if err := setupAbsoluteInputSources(pod, &pod.Spec.Containers[0], build.Spec.Source.Secrets, build.Spec.Source.ConfigMaps); err != nil {
+		return pod, err
+	}
 	if err := setupBuildVolumes(pod, build.Spec.Strategy.DockerStrategy.Volumes, bs.BuildCSIVolumesEnabled); err != nil {
 		return pod, err
 	}
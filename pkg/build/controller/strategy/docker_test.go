@@ -168,6 +168,71 @@ func TestDockerBuildLongName(t *testing.T) {
 	}
 }
 
+func TestDockerCreateBuildPodPushAndPullSecrets(t *testing.T) {
+	tests := []struct {
+		name           string
+		pushSecret     *corev1.LocalObjectReference
+		pullSecret     *corev1.LocalObjectReference
+		expectedMounts map[string]string
+	}{
+		{
+			name:       "distinct push and pull secrets",
+			pushSecret: &corev1.LocalObjectReference{Name: "internal-registry"},
+			pullSecret: &corev1.LocalObjectReference{Name: "external-registry"},
+			expectedMounts: map[string]string{
+				"PUSH_DOCKERCFG_PATH": "internal-registry-push",
+				"PULL_DOCKERCFG_PATH": "external-registry-pull",
+			},
+		},
+		{
+			name:           "push secret only",
+			pushSecret:     &corev1.LocalObjectReference{Name: "registry"},
+			expectedMounts: map[string]string{"PUSH_DOCKERCFG_PATH": "registry-push"},
+		},
+		{
+			name:           "pull secret only",
+			pullSecret:     &corev1.LocalObjectReference{Name: "registry"},
+			expectedMounts: map[string]string{"PULL_DOCKERCFG_PATH": "registry-pull"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			strategy := DockerBuildStrategy{Image: "docker-test-image"}
+			build := mockDockerBuild()
+			build.Spec.Output.PushSecret = tc.pushSecret
+			build.Spec.Strategy.DockerStrategy.PullSecret = tc.pullSecret
+			pod, err := strategy.CreateBuildPod(build, nil, testInternalRegistryHost)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			container := pod.Spec.Containers[0]
+			for _, envName := range []string{"PUSH_DOCKERCFG_PATH", "PULL_DOCKERCFG_PATH"} {
+				var path string
+				for _, e := range container.Env {
+					if e.Name == envName {
+						path = e.Value
+					}
+				}
+				volumeName, expected := tc.expectedMounts[envName]
+				if !expected {
+					if len(path) > 0 {
+						t.Errorf("expected no %s, got %s", envName, path)
+					}
+					continue
+				}
+				mounted := false
+				for _, m := range container.VolumeMounts {
+					mounted = mounted || (m.Name == volumeName && m.MountPath == path)
+				}
+				if len(path) == 0 || !mounted {
+					t.Errorf("expected %s to point at the mount of volume %s, got %q in mounts %#v", envName, volumeName, path, container.VolumeMounts)
+				}
+			}
+		})
+	}
+}
+
 func mockDockerBuild() *buildv1.Build {
 	timeout := int64(60)
 	mountCA := true
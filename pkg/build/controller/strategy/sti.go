@@ -221,6 +221,9 @@ func (bs *SourceBuildStrategy) CreateBuildPod(build *buildv1.Build, additionalCA
 		setupBuilderDeviceFUSE(pod)
 	}
 	setupBlobCache(pod)
+	if err := setupAbsoluteInputSources(pod, &pod.Spec.Containers[0], build.Spec.Source.Secrets, build.Spec.Source.ConfigMaps); err != nil {
+		return pod, err
+	}
 	if err := setupBuildVolumes(pod, build.Spec.Strategy.SourceStrategy.Volumes, bs.BuildCSIVolumeseEnabled); err != nil {
 		return pod, err
 	}
@@ -255,7 +255,7 @@ func mockSTIBuild() *buildv1.Build {
 							Secret: corev1.LocalObjectReference{
 								Name: "secret",
 							},
-							DestinationDir: "/tmp",
+							DestinationDir: "tmp",
 						},
 					},
 					ConfigMaps: []buildv1.ConfigMapBuildSource{
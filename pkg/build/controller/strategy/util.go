@@ -47,6 +47,10 @@ const (
 	buildVolumeMountPath = "/var/run/openshift.io/volumes"
 	// buildVolumeSuffix is a suffix for BuildVolume names
 	buildVolumeSuffix = "user-build-volume"
+
+	// DefaultActiveDeadlineSeconds is the activeDeadlineSeconds of build pods whose build has no
+	// completion deadline: 1 week = 60 sec * 60 min * 24 hr * 7 days
+	DefaultActiveDeadlineSeconds = 604800
 )
 
 const (
@@ -56,6 +60,17 @@ const (
 )
 
 var (
+	// buildWorkingDirs are the directories the build itself works in. User defined BuildVolumes
+	// can't be mounted at, inside or above them.
+	buildWorkingDirs = []string{buildutil.BuildWorkDirMount, buildVolumeMountPath}
+
+	// systemDirs are the directories of the build container that configMap and secret build
+	// sources with an absolute destination directory can't be mounted at or above.
+	systemDirs = []string{"/", "/bin", "/boot", "/dev", "/etc", "/etc/pki", "/etc/pki/ca-trust", "/home", "/lib", "/lib64", "/proc", "/root", "/run", "/sbin", "/sys", "/tmp", "/usr", "/usr/bin", "/usr/lib", "/usr/lib64", "/usr/sbin", "/var", "/var/lib", "/var/run"}
+	// pseudoFilesystemDirs are the directories of the build container that configMap and secret
+	// build sources can't be mounted into at all.
+	pseudoFilesystemDirs = []string{"/dev", "/proc", "/sys"}
+
 	// BuildControllerRefKind contains the schema.GroupVersionKind for builds.
 	// This is used in the ownerRef of builder pods.
 	BuildControllerRefKind = buildv1.GroupVersion.WithKind("Build")
@@ -81,6 +96,18 @@ func IsFatal(err error) bool {
 	return isFatal
 }
 
+// BuildVolumeError is an error in the user provided build volumes of a build. The build can't get
+// a build pod until its volumes are fixed.
+type BuildVolumeError struct {
+	// Reason the build volumes are invalid
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *BuildVolumeError) Error() string {
+	return e.Reason
+}
+
 // setupDockerSocket configures the pod to support the host's Docker socket
 func setupDockerSocket(pod *corev1.Pod) {
 	dockerSocketVolume := corev1.Volume{
@@ -259,23 +286,91 @@ func setupSourceSecrets(pod *corev1.Pod, container *corev1.Container, sourceSecr
 }
 
 // setupInputConfigMaps mounts the configMaps referenced by the ConfigMapBuildSource
-// into a builder container.
+// into a builder container. ConfigMaps with an absolute destination directory are mounted
+// by setupAbsoluteInputSources instead.
 func setupInputConfigMaps(pod *corev1.Pod, container *corev1.Container, configs []buildv1.ConfigMapBuildSource) {
 	for _, c := range configs {
+		if filepath.IsAbs(c.DestinationDir) {
+			continue
+		}
 		mountConfigMapVolume(pod, container, c.ConfigMap.Name, filepath.Join(ConfigMapBuildSourceBaseMountPath, c.ConfigMap.Name), "build", nil)
 		klog.V(3).Infof("%s will be used as a build config in %s", c.ConfigMap.Name, ConfigMapBuildSourceBaseMountPath)
 	}
 }
 
 // setupInputSecrets mounts the secrets referenced by the SecretBuildSource
-// into a builder container.
+// into a builder container. Secrets with an absolute destination directory are mounted
+// by setupAbsoluteInputSources instead.
 func setupInputSecrets(pod *corev1.Pod, container *corev1.Container, secrets []buildv1.SecretBuildSource) {
 	for _, s := range secrets {
+		if filepath.IsAbs(s.DestinationDir) {
+			continue
+		}
 		mountSecretVolume(pod, container, s.Secret.Name, filepath.Join(SecretBuildSourceBaseMountPath, s.Secret.Name), "build", nil)
 		klog.V(3).Infof("%s will be used as a build secret in %s", s.Secret.Name, SecretBuildSourceBaseMountPath)
 	}
 }
 
+// setupAbsoluteInputSources mounts the secrets and configMaps of the build source with an
+// absolute destination directory at that directory of the builder container, rather than
+// copying their content into the source tree. It must run after the build controller mounted
+// its own volumes, so that the destination directories can be checked against them.
+func setupAbsoluteInputSources(pod *corev1.Pod, container *corev1.Container, secrets []buildv1.SecretBuildSource, configs []buildv1.ConfigMapBuildSource) error {
+	for _, s := range secrets {
+		if !filepath.IsAbs(s.DestinationDir) {
+			continue
+		}
+		if err := validateAbsoluteDestinationDir(container, "secret", s.Secret.Name, s.DestinationDir); err != nil {
+			return err
+		}
+		mountSecretVolume(pod, container, s.Secret.Name, filepath.Clean(s.DestinationDir), "build", nil)
+		klog.V(3).Infof("%s will be used as a build secret in %s", s.Secret.Name, s.DestinationDir)
+	}
+	for _, c := range configs {
+		if !filepath.IsAbs(c.DestinationDir) {
+			continue
+		}
+		if err := validateAbsoluteDestinationDir(container, "configMap", c.ConfigMap.Name, c.DestinationDir); err != nil {
+			return err
+		}
+		mountConfigMapVolume(pod, container, c.ConfigMap.Name, filepath.Clean(c.DestinationDir), "build", nil)
+		klog.V(3).Infof("%s will be used as a build config in %s", c.ConfigMap.Name, c.DestinationDir)
+	}
+	return nil
+}
+
+// validateAbsoluteDestinationDir returns an error if mounting a build source at the absolute
+// destination directory would shadow a system directory, a build working directory or a volume
+// already mounted in the container.
+func validateAbsoluteDestinationDir(container *corev1.Container, kind, name, destinationDir string) error {
+	dir := filepath.Clean(destinationDir)
+	for _, pseudoDir := range pseudoFilesystemDirs {
+		if isSameOrUnder(dir, pseudoDir) {
+			return &BuildVolumeError{Reason: fmt.Sprintf("%s build source %q destinationDir %q is inside the system directory %q", kind, name, destinationDir, pseudoDir)}
+		}
+	}
+	for _, systemDir := range systemDirs {
+		if isSameOrUnder(systemDir, dir) {
+			return &BuildVolumeError{Reason: fmt.Sprintf("%s build source %q destinationDir %q shadows the system directory %q", kind, name, destinationDir, systemDir)}
+		}
+	}
+	if workDir, ok := collidesWithBuildWorkingDir(dir); ok {
+		return &BuildVolumeError{Reason: fmt.Sprintf("%s build source %q destinationDir %q collides with the build working directory %q", kind, name, destinationDir, workDir)}
+	}
+	for _, vm := range container.VolumeMounts {
+		if isSameOrUnder(dir, vm.MountPath) || isSameOrUnder(vm.MountPath, dir) {
+			return &BuildVolumeError{Reason: fmt.Sprintf("%s build source %q destinationDir %q collides with the volume mounted at %q", kind, name, destinationDir, vm.MountPath)}
+		}
+	}
+	return nil
+}
+
+// isSameOrUnder returns true if the path is the directory or one of its subdirectories.
+func isSameOrUnder(path, dir string) bool {
+	path, dir = filepath.Clean(path), filepath.Clean(dir)
+	return path == dir || dir == "/" || strings.HasPrefix(path, dir+"/")
+}
+
 // addSourceEnvVars adds environment variables related to the source code
 // repository to builder container
 func addSourceEnvVars(source buildv1.BuildSource, output *[]corev1.EnvVar) {
@@ -341,7 +436,7 @@ func setupActiveDeadline(pod *corev1.Pod, build *buildv1.Build) *corev1.Pod {
 	// plugin, which will involve creating new operator, we use a long activeDeadlineSeconds as build are
 	// designed to terminate
 	var defActiveDeadline int64
-	defActiveDeadline = 604800 // 1 week = 60 sec * 60 min * 24 hr * 7 days
+	defActiveDeadline = DefaultActiveDeadlineSeconds
 	pod.Spec.ActiveDeadlineSeconds = &defActiveDeadline
 	return pod
 }
@@ -359,7 +454,9 @@ func getPodLabels(build *buildv1.Build) map[string]string {
 	return map[string]string{buildv1.BuildLabel: buildutil.LabelValue(build.Name)}
 }
 
-func makeOwnerReference(build *buildv1.Build) metav1.OwnerReference {
+// MakeOwnerReference returns the controller OwnerReference of the build onto the build pod and the
+// other objects created for the build, which are garbage collected with it.
+func MakeOwnerReference(build *buildv1.Build) metav1.OwnerReference {
 	t := true
 	return metav1.OwnerReference{
 		APIVersion: BuildControllerRefKind.GroupVersion().String(),
@@ -371,13 +468,13 @@ func makeOwnerReference(build *buildv1.Build) metav1.OwnerReference {
 }
 
 func setOwnerReference(pod *corev1.Pod, build *buildv1.Build) {
-	pod.OwnerReferences = []metav1.OwnerReference{makeOwnerReference(build)}
+	pod.OwnerReferences = []metav1.OwnerReference{MakeOwnerReference(build)}
 }
 
 // HasOwnerReference returns true if the build pod has an OwnerReference to the
 // build.
 func HasOwnerReference(pod *corev1.Pod, build *buildv1.Build) bool {
-	ref := makeOwnerReference(build)
+	ref := MakeOwnerReference(build)
 
 	for _, r := range pod.OwnerReferences {
 		if reflect.DeepEqual(r, ref) {
@@ -740,7 +837,10 @@ func setupBuildVolumes(pod *corev1.Pod, buildVolumes []buildv1.BuildVolume, csiV
 		for _, bvm := range buildVolume.Mounts {
 			if _, ok := usedUserVolumeMounts[bvm.DestinationPath]; ok {
 				// fail if a collision is found
-				return fmt.Errorf("user provided BuildVolumeMount path %q collides with VolumeMount path created by the build controller", bvm.DestinationPath)
+				return &BuildVolumeError{Reason: fmt.Sprintf("user provided BuildVolumeMount path %q collides with VolumeMount path created by the build controller", bvm.DestinationPath)}
+			}
+			if dir, ok := collidesWithBuildWorkingDir(bvm.DestinationPath); ok {
+				return &BuildVolumeError{Reason: fmt.Sprintf("user provided BuildVolumeMount path %q collides with the build working directory %q", bvm.DestinationPath, dir)}
 			}
 		}
 
@@ -756,18 +856,33 @@ func setupBuildVolumes(pod *corev1.Pod, buildVolumes []buildv1.BuildVolume, csiV
 			mountConfigMapVolume(pod, &pod.Spec.Containers[0], strings.ToLower(buildVolume.Source.ConfigMap.Name), PathForBuildVolume(buildVolume.Source.ConfigMap.Name), buildVolumeSuffix, &volumeSource)
 		case buildv1.BuildVolumeSourceTypeCSI:
 			if !csiVolumesEnabled {
-				return fmt.Errorf("csi volumes require the BuildCSIVolumes feature gate to be enabled")
+				return &BuildVolumeError{Reason: "csi volumes require the BuildCSIVolumes feature gate to be enabled"}
 			}
 			volumeSource.CSI = buildVolume.Source.CSI
 			mountCSIVolume(pod, &pod.Spec.Containers[0], strings.ToLower(buildVolume.Name), PathForBuildVolume(buildVolume.Name), buildVolumeSuffix, &volumeSource)
 		default:
-			return fmt.Errorf("encountered unsupported build volume source type %q", buildVolume.Source.Type)
+			return &BuildVolumeError{Reason: fmt.Sprintf("encountered unsupported build volume source type %q", buildVolume.Source.Type)}
 		}
 	}
 
 	return nil
 }
 
+// collidesWithBuildWorkingDir returns the build working directory that a build volume mounted at
+// the path would shadow or be mounted into.
+func collidesWithBuildWorkingDir(path string) (string, bool) {
+	if !filepath.IsAbs(path) {
+		return "", false
+	}
+	path = filepath.Clean(path)
+	for _, dir := range buildWorkingDirs {
+		if path == dir || strings.HasPrefix(path, dir+"/") || strings.HasPrefix(dir, strings.TrimSuffix(path, "/")+"/") {
+			return dir, true
+		}
+	}
+	return "", false
+}
+
 // NameForBuildVolume returns a valid pod volume name for the provided build volume name.
 func NameForBuildVolume(objName string) string {
 	// Volume names must be a valid DNS Label - see https://kubernetes.io/docs/concepts/overview/working-with-objects/names/#dns-label-names
@@ -12,6 +12,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 )
 
 const (
@@ -251,6 +252,104 @@ func TestMountConfigsAndSecrets(t *testing.T) {
 	}
 }
 
+func TestMountAbsoluteInputSources(t *testing.T) {
+	tests := []struct {
+		name           string
+		secrets        []buildv1.SecretBuildSource
+		configs        []buildv1.ConfigMapBuildSource
+		expectedMounts map[string]string
+		expectedError  string
+	}{
+		{
+			name: "absolute destinations are mounted at the requested path",
+			secrets: []buildv1.SecretBuildSource{
+				{Secret: corev1.LocalObjectReference{Name: "tls"}, DestinationDir: "/etc/pki/tls/private/"},
+				{Secret: corev1.LocalObjectReference{Name: "relative"}, DestinationDir: "secret/path"},
+			},
+			configs: []buildv1.ConfigMapBuildSource{
+				{ConfigMap: corev1.LocalObjectReference{Name: "certs"}, DestinationDir: "/etc/pki/tls/certs"},
+				{ConfigMap: corev1.LocalObjectReference{Name: "settings"}, DestinationDir: "some/path"},
+			},
+			expectedMounts: map[string]string{
+				"tls-build":      "/etc/pki/tls/private",
+				"relative-build": SecretBuildSourceBaseMountPath + "/relative",
+				"certs-build":    "/etc/pki/tls/certs",
+				"settings-build": ConfigMapBuildSourceBaseMountPath + "/settings",
+			},
+		},
+		{
+			name:          "system directory",
+			configs:       []buildv1.ConfigMapBuildSource{{ConfigMap: corev1.LocalObjectReference{Name: "certs"}, DestinationDir: "/etc"}},
+			expectedError: `configMap build source "certs" destinationDir "/etc" shadows the system directory "/etc"`,
+		},
+		{
+			name:          "parent of a system directory",
+			secrets:       []buildv1.SecretBuildSource{{Secret: corev1.LocalObjectReference{Name: "tls"}, DestinationDir: "/usr/"}},
+			expectedError: `secret build source "tls" destinationDir "/usr/" shadows the system directory "/usr"`,
+		},
+		{
+			name:          "root",
+			configs:       []buildv1.ConfigMapBuildSource{{ConfigMap: corev1.LocalObjectReference{Name: "certs"}, DestinationDir: "/"}},
+			expectedError: `configMap build source "certs" destinationDir "/" shadows the system directory "/"`,
+		},
+		{
+			name:          "pseudo filesystem",
+			configs:       []buildv1.ConfigMapBuildSource{{ConfigMap: corev1.LocalObjectReference{Name: "certs"}, DestinationDir: "/proc/sys/net"}},
+			expectedError: `configMap build source "certs" destinationDir "/proc/sys/net" is inside the system directory "/proc"`,
+		},
+		{
+			name:          "build working directory",
+			configs:       []buildv1.ConfigMapBuildSource{{ConfigMap: corev1.LocalObjectReference{Name: "certs"}, DestinationDir: buildutil.BuildWorkDirMount + "/inputs"}},
+			expectedError: `configMap build source "certs" destinationDir "` + buildutil.BuildWorkDirMount + `/inputs" collides with the build working directory "` + buildutil.BuildWorkDirMount + `"`,
+		},
+		{
+			name:          "build controller volume",
+			secrets:       []buildv1.SecretBuildSource{{Secret: corev1.LocalObjectReference{Name: "tls"}, DestinationDir: ConfigMapCertsMountPath}},
+			expectedError: `secret build source "tls" destinationDir "` + ConfigMapCertsMountPath + `" collides with the volume mounted at "` + ConfigMapCertsMountPath + `"`,
+		},
+		{
+			name: "two sources at the same path",
+			secrets: []buildv1.SecretBuildSource{
+				{Secret: corev1.LocalObjectReference{Name: "tls"}, DestinationDir: "/opt/app/config"},
+			},
+			configs: []buildv1.ConfigMapBuildSource{
+				{ConfigMap: corev1.LocalObjectReference{Name: "certs"}, DestinationDir: "/opt/app/config/certs"},
+			},
+			expectedError: `configMap build source "certs" destinationDir "/opt/app/config/certs" collides with the volume mounted at "/opt/app/config"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := emptyPod()
+			pod.Spec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "build-ca-bundles", MountPath: ConfigMapCertsMountPath}}
+			setupInputSecrets(&pod, &pod.Spec.Containers[0], tc.secrets)
+			setupInputConfigMaps(&pod, &pod.Spec.Containers[0], tc.configs)
+			err := setupAbsoluteInputSources(&pod, &pod.Spec.Containers[0], tc.secrets, tc.configs)
+			if len(tc.expectedError) > 0 {
+				if _, ok := err.(*BuildVolumeError); !ok || err.Error() != tc.expectedError {
+					t.Fatalf("expected build volume error %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			mounts := map[string]string{}
+			for _, m := range pod.Spec.Containers[0].VolumeMounts {
+				if m.Name != "build-ca-bundles" {
+					mounts[m.Name] = m.MountPath
+				}
+			}
+			if !reflect.DeepEqual(mounts, tc.expectedMounts) {
+				t.Errorf("expected mounts %v, got %v", tc.expectedMounts, mounts)
+			}
+			if len(pod.Spec.Volumes) != 4 {
+				t.Errorf("expected a volume per build source, got %#v", pod.Spec.Volumes)
+			}
+		})
+	}
+}
+
 func checkContainersMounts(containers []corev1.Container, t *testing.T) {
 	for _, c := range containers {
 		foundCA := false
@@ -736,6 +835,93 @@ func TestSetupBuildVolumes(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:             "BuildVolumeMount inside the build working directory should fail",
+			CSIVolumeEnabled: false,
+			ShouldFail:       true,
+			ErrorMessage:     "user provided BuildVolumeMount path \"/tmp/build/inputs\" collides with the build working directory \"/tmp/build\"",
+			BuildVolumes: []buildv1.BuildVolume{
+				{
+					Name: "one",
+					Source: buildv1.BuildVolumeSource{
+						Type: buildv1.BuildVolumeSourceTypeConfigMap,
+						ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "configmap-one"},
+						},
+					},
+					Mounts: []buildv1.BuildVolumeMount{
+						{
+							DestinationPath: "/tmp/build/inputs",
+						},
+					},
+				},
+			},
+			WantVolumes:      []corev1.Volume{},
+			WantVolumeMounts: []corev1.VolumeMount{},
+		},
+		{
+			Name:             "BuildVolumeMount above the build working directory should fail",
+			CSIVolumeEnabled: false,
+			ShouldFail:       true,
+			ErrorMessage:     "user provided BuildVolumeMount path \"/tmp/\" collides with the build working directory \"/tmp/build\"",
+			BuildVolumes: []buildv1.BuildVolume{
+				{
+					Name: "one",
+					Source: buildv1.BuildVolumeSource{
+						Type: buildv1.BuildVolumeSourceTypeSecret,
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: "secret-one",
+						},
+					},
+					Mounts: []buildv1.BuildVolumeMount{
+						{
+							DestinationPath: "/tmp/",
+						},
+					},
+				},
+			},
+			WantVolumes:      []corev1.Volume{},
+			WantVolumeMounts: []corev1.VolumeMount{},
+		},
+		{
+			Name:             "BuildVolumeMount next to the build working directory should succeed",
+			CSIVolumeEnabled: false,
+			ShouldFail:       false,
+			ErrorMessage:     "",
+			BuildVolumes: []buildv1.BuildVolume{
+				{
+					Name: "one",
+					Source: buildv1.BuildVolumeSource{
+						Type: buildv1.BuildVolumeSourceTypeSecret,
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: "secret-one",
+						},
+					},
+					Mounts: []buildv1.BuildVolumeMount{
+						{
+							DestinationPath: "/tmp/builder-certs",
+						},
+					},
+				},
+			},
+			WantVolumes: []corev1.Volume{
+				{
+					Name: NameForBuildVolume("secret-one"),
+					VolumeSource: corev1.VolumeSource{
+						Secret: &corev1.SecretVolumeSource{
+							SecretName: "secret-one",
+						},
+					},
+				},
+			},
+			WantVolumeMounts: []corev1.VolumeMount{
+				{
+					Name:      NameForBuildVolume("secret-one"),
+					ReadOnly:  true,
+					MountPath: PathForBuildVolume("secret-one"),
+				},
+			},
+		},
 		{
 			Name:             "UnSupported BuildVolumeSourceType should fail",
 			CSIVolumeEnabled: false,
@@ -6,22 +6,65 @@ import (
 	semver "github.com/blang/semver/v4"
 	"github.com/prometheus/client_golang/prometheus"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kselector "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	k8smetrics "k8s.io/component-base/metrics"
 	"k8s.io/component-base/metrics/legacyregistry"
 	"k8s.io/klog/v2"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	buildlister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 )
 
 const (
-	separator        = "_"
-	buildSubsystem   = "openshift_build"
-	buildCount       = "total"
-	buildCountQuery  = buildSubsystem + separator + buildCount
-	activeBuild      = "active_time_seconds"
-	activeBuildQuery = buildSubsystem + separator + activeBuild
+	separator             = "_"
+	buildSubsystem        = "openshift_build"
+	buildCount            = "total"
+	buildCountQuery       = buildSubsystem + separator + buildCount
+	activeBuild           = "active_time_seconds"
+	activeBuildQuery      = buildSubsystem + separator + activeBuild
+	waitingBuild          = "waiting"
+	waitingBuildQuery     = buildSubsystem + separator + waitingBuild
+	buildDuration         = "duration_seconds"
+	buildDurationQuery    = buildSubsystem + separator + buildDuration
+	stageDuration         = "stage_duration_seconds"
+	stageDurationQuery    = buildSubsystem + separator + stageDuration
+	pendingBuild          = "pending_total"
+	pendingBuildQuery     = buildSubsystem + separator + pendingBuild
+	serialized            = "serialized_total"
+	serializedQuery       = buildSubsystem + separator + serialized
+	quotaBlocked          = "quota_blocked_pod_creations_total"
+	quotaBlockedQuery     = buildSubsystem + separator + quotaBlocked
+	pipelineRejected      = "pipeline_strategy_rejections_total"
+	pipelineRejectedQuery = buildSubsystem + separator + pipelineRejected
+
+	buildConfigSubsystem     = "openshift_buildconfig"
+	buildConfigBuilds        = "builds_total"
+	buildConfigBuildsQuery   = buildConfigSubsystem + separator + buildConfigBuilds
+	lastSuccessfulBuild      = "last_successful_build_timestamp_seconds"
+	lastSuccessfulBuildQuery = buildConfigSubsystem + separator + lastSuccessfulBuild
+)
+
+// Reasons new builds cannot start for, recorded by RecordBuildPending.
+const (
+	// PendingReasonQuotaExceeded is recorded when a resource quota denied the build pod.
+	PendingReasonQuotaExceeded = "QuotaExceeded"
+	// PendingReasonSerialPolicy is recorded when the serial run policy waits for other builds of the
+	// build config.
+	PendingReasonSerialPolicy = "SerialPolicy"
+	// PendingReasonConcurrencyLimit is recorded when the concurrent build limit of the namespace is
+	// reached.
+	PendingReasonConcurrencyLimit = "ConcurrencyLimit"
+	// PendingReasonClusterCapacity is recorded when the build pod limit of the cluster is reached.
+	PendingReasonClusterCapacity = "ClusterCapacity"
+	// PendingReasonBuildCacheInUse is recorded when another build uses the build cache claim.
+	PendingReasonBuildCacheInUse = "BuildCacheInUse"
+	// PendingReasonImageNotResolved is recorded when an image reference of the build could not be
+	// resolved yet.
+	PendingReasonImageNotResolved = "ImageNotResolved"
 )
 
 var (
@@ -37,6 +80,63 @@ var (
 		[]string{"namespace", "name", "phase", "reason", "strategy"},
 		nil,
 	)
+	waitingBuildDesc = prometheus.NewDesc(
+		waitingBuildQuery,
+		"Counts new builds waiting for their build pod because of the concurrent build limit or a failed pod creation, such as a quota denial, by reason and strategy",
+		[]string{"reason", "strategy"},
+		nil,
+	)
+
+	// BuildDuration observes the time from the start to the completion of builds.
+	BuildDuration = k8smetrics.NewHistogramVec(&k8smetrics.HistogramOpts{
+		Name:    buildDurationQuery,
+		Help:    "Duration of completed builds from their start to their completion by strategy and terminal phase",
+		Buckets: k8smetrics.ExponentialBuckets(10, 2, 12),
+	}, []string{"strategy", "phase_result"})
+	// BuildStageDuration observes the duration of the stages of completed builds.
+	BuildStageDuration = k8smetrics.NewHistogramVec(&k8smetrics.HistogramOpts{
+		Name:    stageDurationQuery,
+		Help:    "Duration of the stages of completed builds by stage",
+		Buckets: k8smetrics.ExponentialBuckets(1, 2, 14),
+	}, []string{"stage"})
+	// PendingBuilds counts the new builds the build controller could not start by the reason they
+	// are held back for.
+	PendingBuilds = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: pendingBuildQuery,
+		Help: "Counts new builds the build controller cannot start yet by reason",
+	}, []string{"reason"})
+	// SerializedBuilds counts the builds the serial run policy held back.
+	SerializedBuilds = k8smetrics.NewCounter(&k8smetrics.CounterOpts{
+		Name: serializedQuery,
+		Help: "Counts builds held back by the serial run policy until the other builds of their build config completed",
+	})
+	// QuotaBlockedPodCreations counts the attempts to create a build pod that a resource quota
+	// denied.
+	QuotaBlockedPodCreations = k8smetrics.NewCounter(&k8smetrics.CounterOpts{
+		Name: quotaBlockedQuery,
+		Help: "Counts the build pod creation attempts denied by a resource quota",
+	})
+	// PipelineStrategyRejections counts the builds of the removed JenkinsPipeline strategy that
+	// the build controller failed, by the namespace and build config of the build.
+	PipelineStrategyRejections = k8smetrics.NewCounterVec(&k8smetrics.CounterOpts{
+		Name: pipelineRejectedQuery,
+		Help: "Counts the builds of the removed JenkinsPipeline strategy failed by the build controller by namespace and build config",
+	}, []string{"namespace", "buildconfig"})
+	// BuildConfigBuilds counts the builds of build configs that reached a terminal phase by the
+	// namespace and build config of the build and the terminal phase.
+	BuildConfigBuilds = k8smetrics.NewCounterVec(&k8smetrics.CounterOpts{
+		Name: buildConfigBuildsQuery,
+		Help: "Counts the builds of build configs that reached a terminal phase by namespace, build config and outcome",
+	}, []string{"namespace", "buildconfig", "outcome"})
+	// BuildConfigLastSuccessfulBuild is the completion time in unix epoch of the last build of
+	// build configs that completed, by the namespace and build config of the build.
+	BuildConfigLastSuccessfulBuild = k8smetrics.NewGaugeVec(&k8smetrics.GaugeOpts{
+		Name: lastSuccessfulBuildQuery,
+		Help: "Shows the completion time in unix epoch of the last successful build of build configs by namespace and build config",
+	}, []string{"namespace", "buildconfig"})
+	registerOnce sync.Once
+
+	pending = pendingTracker{reasons: map[string]string{}, serialized: sets.New[string]()}
 
 	bc             = buildCollector{}
 	cancelledPhase = string(buildv1.BuildPhaseCancelled)
@@ -62,9 +162,112 @@ func IntializeMetricsCollector(buildLister buildlister.BuildLister) {
 		bc.lister = buildLister
 		legacyregistry.MustRegister(&bc)
 	}
+	Register()
 	klog.V(4).Info("build metrics registered with prometheus")
 }
 
+// Register registers the metrics recorded by the build controller with the legacy registry.
+// It is safe to call it more than once.
+func Register() {
+	registerOnce.Do(func() {
+		legacyregistry.MustRegister(BuildDuration)
+		legacyregistry.MustRegister(BuildStageDuration)
+		legacyregistry.MustRegister(PendingBuilds)
+		legacyregistry.MustRegister(SerializedBuilds)
+		legacyregistry.MustRegister(QuotaBlockedPodCreations)
+		legacyregistry.MustRegister(PipelineStrategyRejections)
+		legacyregistry.MustRegister(BuildConfigBuilds)
+		legacyregistry.MustRegister(BuildConfigLastSuccessfulBuild)
+	})
+}
+
+// RecordBuildCompleted observes the duration of a build that reached a terminal phase and of its
+// stages. Builds that never started, such as builds cancelled while new, are not observed.
+func RecordBuildCompleted(b *buildv1.Build) {
+	for _, stage := range b.Status.Stages {
+		BuildStageDuration.WithLabelValues(string(stage.Name)).Observe(float64(stage.DurationMilliseconds) / 1000)
+	}
+	if b.Status.StartTimestamp == nil || b.Status.CompletionTimestamp == nil {
+		return
+	}
+	duration := b.Status.CompletionTimestamp.Sub(b.Status.StartTimestamp.Time)
+	BuildDuration.WithLabelValues(strategyType(b.Spec.Strategy), string(b.Status.Phase)).Observe(duration.Seconds())
+}
+
+// pendingTracker holds the reason each pending new build is held back for, so that PendingBuilds
+// counts every build once, and the builds SerializedBuilds counted.
+type pendingTracker struct {
+	lock       sync.Mutex
+	reasons    map[string]string
+	serialized sets.Set[string]
+}
+
+func (p *pendingTracker) set(key, reason string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if previous, ok := p.reasons[key]; ok {
+		if previous == reason {
+			return
+		}
+		PendingBuilds.WithLabelValues(previous).Dec()
+		delete(p.reasons, key)
+	}
+	if len(reason) == 0 {
+		return
+	}
+	p.reasons[key] = reason
+	PendingBuilds.WithLabelValues(reason).Inc()
+	if reason == PendingReasonSerialPolicy && !p.serialized.Has(key) {
+		p.serialized.Insert(key)
+		SerializedBuilds.Inc()
+	}
+}
+
+func (p *pendingTracker) remove(key string) {
+	p.set(key, "")
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.serialized.Delete(key)
+}
+
+// RecordBuildPending records the reason a new build cannot start for, one of the pending reasons,
+// replacing the reason recorded before. An empty reason records that the build is not held back.
+// Builds are counted by SerializedBuilds the first time the serial run policy holds them back.
+func RecordBuildPending(b *buildv1.Build, reason string) {
+	pending.set(b.Namespace+"/"+b.Name, reason)
+}
+
+// RecordQuotaBlockedPodCreation records an attempt to create a build pod that a resource quota
+// denied.
+func RecordQuotaBlockedPodCreation() {
+	QuotaBlockedPodCreations.Inc()
+}
+
+// RecordPipelineStrategyRejection records a build of the removed JenkinsPipeline strategy that
+// the build controller failed.
+func RecordPipelineStrategyRejection(b *buildv1.Build) {
+	PipelineStrategyRejections.WithLabelValues(b.Namespace, b.Labels[buildv1.BuildConfigLabel]).Inc()
+}
+
+// RecordBuildConfigBuildCompleted records the terminal phase of a build of a build config, and the
+// completion time of the last successful build of the build config. Builds without a build config
+// are not recorded.
+func RecordBuildConfigBuildCompleted(b *buildv1.Build) {
+	buildConfig := b.Labels[buildv1.BuildConfigLabel]
+	if len(buildConfig) == 0 {
+		return
+	}
+	BuildConfigBuilds.WithLabelValues(b.Namespace, buildConfig, string(b.Status.Phase)).Inc()
+	if b.Status.Phase == buildv1.BuildPhaseComplete && b.Status.CompletionTimestamp != nil {
+		BuildConfigLastSuccessfulBuild.WithLabelValues(b.Namespace, buildConfig).Set(float64(b.Status.CompletionTimestamp.Unix()))
+	}
+}
+
+// RecordBuildNotPending records that a build left the new phase or was deleted.
+func RecordBuildNotPending(b *buildv1.Build) {
+	pending.remove(b.Namespace + "/" + b.Name)
+}
+
 // Create satisfies the k8s metrics.Registerable interface. It is called when the metric is
 // registered with Prometheus via k8s metrics.
 func (bc *buildCollector) Create(v *semver.Version) bool {
@@ -85,6 +288,7 @@ func (bc *buildCollector) IsCreated() bool {
 func (bc *buildCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- buildCountDesc
 	ch <- activeBuildDesc
+	ch <- waitingBuildDesc
 }
 
 type collectKey struct {
@@ -105,14 +309,39 @@ func (bc *buildCollector) Collect(ch chan<- prometheus.Metric) {
 	// collectBuild will return counts for the build's phase/reason tuple,
 	// and counts for these tuples be added to the total amount posted to prometheus
 	counts := map[collectKey]int{}
+	waiting := map[collectKey]int{}
 	for _, b := range result {
 		k := bc.collectBuild(ch, b)
 		counts[k] = counts[k] + 1
+		if reason := waitingReason(b); len(reason) != 0 {
+			w := collectKey{reason: reason, strategy: k.strategy}
+			waiting[w] = waiting[w] + 1
+		}
 	}
 
 	for key, count := range counts {
 		addCountGauge(ch, buildCountDesc, key.phase, key.reason, key.strategy, float64(count))
 	}
+	for key, count := range waiting {
+		ch <- prometheus.MustNewConstMetric(waitingBuildDesc, prometheus.GaugeValue, float64(count), key.reason, key.strategy)
+	}
+}
+
+// waitingReason returns why a new build is waiting for its build pod, or an empty string when it
+// is not held back.
+func waitingReason(b *buildv1.Build) string {
+	if b.Status.Phase != buildv1.BuildPhaseNew {
+		return ""
+	}
+	for _, condition := range b.Status.Conditions {
+		if condition.Type == buildutil.BuildConditionQueued && condition.Status == corev1.ConditionTrue {
+			return condition.Reason
+		}
+	}
+	if b.Status.Reason == buildv1.StatusReasonCannotCreateBuildPod {
+		return string(b.Status.Reason)
+	}
+	return ""
 }
 
 func (bc *buildCollector) ClearState() {
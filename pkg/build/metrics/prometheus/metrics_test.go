@@ -9,12 +9,16 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/component-base/metrics/legacyregistry"
+	"k8s.io/component-base/metrics/testutil"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	buildv1lister "github.com/openshift/client-go/build/listers/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 )
 
 type fakeLister []*buildv1.Build
@@ -56,6 +60,10 @@ func TestBuildMetrics(t *testing.T) {
 		"openshift_build_active_time_seconds{name=\"testname1\",namespace=\"testnamespace\",phase=\"New\",reason=\"\",strategy=\"\"} 123",
 		"openshift_build_active_time_seconds{name=\"testname2\",namespace=\"testnamespace\",phase=\"Pending\",reason=\"\",strategy=\"\"} 123",
 		"openshift_build_active_time_seconds{name=\"testname3\",namespace=\"testnamespace\",phase=\"Running\",reason=\"\",strategy=\"\"} 123",
+		"# HELP openshift_build_waiting Counts new builds waiting for their build pod because of the concurrent build limit or a failed pod creation, such as a quota denial, by reason and strategy",
+		"# TYPE openshift_build_waiting gauge",
+		"openshift_build_waiting{reason=\"CannotCreateBuildPod\",strategy=\"\"} 1",
+		"openshift_build_waiting{reason=\"ConcurrentBuildLimitReached\",strategy=\"\"} 1",
 	}
 
 	buildLister := &fakeLister{
@@ -110,6 +118,36 @@ func TestBuildMetrics(t *testing.T) {
 				Phase: buildv1.BuildPhasePending,
 			},
 		},
+		{
+			Status: buildv1.BuildStatus{
+				Phase:  buildv1.BuildPhaseNew,
+				Reason: buildv1.StatusReasonCannotCreateBuildPod,
+			},
+		},
+		{
+			Status: buildv1.BuildStatus{
+				Phase: buildv1.BuildPhaseNew,
+				Conditions: []buildv1.BuildCondition{
+					{
+						Type:   buildutil.BuildConditionQueued,
+						Status: corev1.ConditionTrue,
+						Reason: buildutil.BuildQueuedReason,
+					},
+				},
+			},
+		},
+		{
+			Status: buildv1.BuildStatus{
+				Phase: buildv1.BuildPhasePending,
+				Conditions: []buildv1.BuildCondition{
+					{
+						Type:   buildutil.BuildConditionQueued,
+						Status: corev1.ConditionFalse,
+						Reason: buildutil.BuildAdmittedReason,
+					},
+				},
+			},
+		},
 		{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "testnamespace",
@@ -142,3 +180,190 @@ func TestBuildMetrics(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordBuildCompleted(t *testing.T) {
+	Register()
+	start := metav1.NewTime(time.Unix(1000, 0))
+	completion := metav1.NewTime(time.Unix(1090, 0))
+	sourceStrategy := buildv1.BuildStrategy{SourceStrategy: &buildv1.SourceBuildStrategy{}}
+
+	tests := []struct {
+		name          string
+		build         *buildv1.Build
+		expectedCount uint64
+		expectedSum   float64
+	}{
+		{
+			name: "completed build",
+			build: &buildv1.Build{
+				Spec:   buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: sourceStrategy}},
+				Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed, StartTimestamp: &start, CompletionTimestamp: &completion},
+			},
+			expectedCount: 1,
+			expectedSum:   90,
+		},
+		{
+			name: "build that never started",
+			build: &buildv1.Build{
+				Spec:   buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: sourceStrategy}},
+				Status: buildv1.BuildStatus{Phase: buildv1.BuildPhaseFailed, CompletionTimestamp: &completion},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			BuildDuration.Reset()
+			RecordBuildCompleted(tc.build)
+
+			observer := BuildDuration.WithLabelValues("Source", string(buildv1.BuildPhaseFailed))
+			count, err := testutil.GetHistogramMetricCount(observer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum, err := testutil.GetHistogramMetricValue(observer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tc.expectedCount || sum != tc.expectedSum {
+				t.Errorf("expected %d observations summing to %v, got %d summing to %v", tc.expectedCount, tc.expectedSum, count, sum)
+			}
+		})
+	}
+}
+
+func TestRecordBuildPending(t *testing.T) {
+	Register()
+	PendingBuilds.Reset()
+	pending = pendingTracker{reasons: map[string]string{}, serialized: sets.New[string]()}
+	serializedBefore, err := testutil.GetCounterMetricValue(SerializedBuilds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := func(step string, expected map[string]float64, serializedBuilds float64) {
+		for _, reason := range []string{PendingReasonSerialPolicy, PendingReasonConcurrencyLimit} {
+			value, err := testutil.GetGaugeMetricValue(PendingBuilds.WithLabelValues(reason))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != expected[reason] {
+				t.Errorf("%s: expected %v builds pending for %s, got %v", step, expected[reason], reason, value)
+			}
+		}
+		value, err := testutil.GetCounterMetricValue(SerializedBuilds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value-serializedBefore != serializedBuilds {
+			t.Errorf("%s: expected %v serialized builds, got %v", step, serializedBuilds, value-serializedBefore)
+		}
+	}
+
+	first := &buildv1.Build{ObjectMeta: metav1.ObjectMeta{Namespace: "testnamespace", Name: "first"}}
+	second := &buildv1.Build{ObjectMeta: metav1.ObjectMeta{Namespace: "testnamespace", Name: "second"}}
+
+	RecordBuildPending(first, PendingReasonSerialPolicy)
+	RecordBuildPending(first, PendingReasonSerialPolicy)
+	RecordBuildPending(second, PendingReasonSerialPolicy)
+	expect("serialized", map[string]float64{PendingReasonSerialPolicy: 2}, 2)
+
+	RecordBuildPending(first, PendingReasonConcurrencyLimit)
+	RecordBuildPending(first, PendingReasonSerialPolicy)
+	expect("reason changed back", map[string]float64{PendingReasonSerialPolicy: 2}, 2)
+
+	RecordBuildPending(first, "")
+	RecordBuildNotPending(second)
+	expect("not pending", map[string]float64{}, 2)
+}
+
+func TestRecordBuildCompletedStages(t *testing.T) {
+	Register()
+	start := metav1.NewTime(time.Unix(1000, 0))
+	completion := metav1.NewTime(time.Unix(1090, 0))
+	build := &buildv1.Build{
+		Spec: buildv1.BuildSpec{CommonSpec: buildv1.CommonSpec{Strategy: buildv1.BuildStrategy{DockerStrategy: &buildv1.DockerBuildStrategy{}}}},
+		Status: buildv1.BuildStatus{
+			Phase:               buildv1.BuildPhaseComplete,
+			StartTimestamp:      &start,
+			CompletionTimestamp: &completion,
+			Stages: []buildv1.StageInfo{
+				{Name: buildv1.StageBuild, StartTime: start, DurationMilliseconds: 60000},
+				{Name: buildv1.StagePostCommit, StartTime: start, DurationMilliseconds: 10000},
+				{Name: buildv1.StagePushImage, StartTime: start, DurationMilliseconds: 12500},
+			},
+		},
+	}
+	BuildStageDuration.Reset()
+	RecordBuildCompleted(build)
+
+	for stage, expected := range map[buildv1.StageName]float64{
+		buildv1.StageBuild:      60,
+		buildv1.StagePostCommit: 10,
+		buildv1.StagePushImage:  12.5,
+	} {
+		observer := BuildStageDuration.WithLabelValues(string(stage))
+		count, err := testutil.GetHistogramMetricCount(observer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum, err := testutil.GetHistogramMetricValue(observer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 || sum != expected {
+			t.Errorf("expected one observation of %v seconds for stage %s, got %d summing to %v", expected, stage, count, sum)
+		}
+	}
+}
+
+func TestRecordBuildConfigBuildCompleted(t *testing.T) {
+	Register()
+	BuildConfigBuilds.Reset()
+	BuildConfigLastSuccessfulBuild.Reset()
+	newBuild := func(buildConfig string, phase buildv1.BuildPhase, completion int64) *buildv1.Build {
+		b := &buildv1.Build{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "test", Name: "build"},
+			Status:     buildv1.BuildStatus{Phase: phase, CompletionTimestamp: &metav1.Time{Time: time.Unix(completion, 0)}},
+		}
+		if len(buildConfig) > 0 {
+			b.Labels = map[string]string{buildv1.BuildConfigLabel: buildConfig}
+		}
+		return b
+	}
+
+	for _, b := range []*buildv1.Build{
+		newBuild("bc", buildv1.BuildPhaseComplete, 1000),
+		newBuild("bc", buildv1.BuildPhaseFailed, 1100),
+		newBuild("bc", buildv1.BuildPhaseComplete, 1200),
+		newBuild("bc", buildv1.BuildPhaseCancelled, 1300),
+		newBuild("other", buildv1.BuildPhaseError, 1400),
+		newBuild("", buildv1.BuildPhaseComplete, 1500),
+	} {
+		RecordBuildConfigBuildCompleted(b)
+	}
+
+	expected := map[[2]string]float64{
+		{"bc", string(buildv1.BuildPhaseComplete)}:    2,
+		{"bc", string(buildv1.BuildPhaseFailed)}:      1,
+		{"bc", string(buildv1.BuildPhaseCancelled)}:   1,
+		{"bc", string(buildv1.BuildPhaseError)}:       0,
+		{"other", string(buildv1.BuildPhaseError)}:    1,
+		{"other", string(buildv1.BuildPhaseComplete)}: 0,
+		{"", string(buildv1.BuildPhaseComplete)}:      0,
+	}
+	for labels, count := range expected {
+		value, err := testutil.GetCounterMetricValue(BuildConfigBuilds.WithLabelValues("test", labels[0], labels[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != count {
+			t.Errorf("expected %v %s builds of build config %q, got %v", count, labels[1], labels[0], value)
+		}
+	}
+	lastSuccess, err := testutil.GetGaugeMetricValue(BuildConfigLastSuccessfulBuild.WithLabelValues("test", "bc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastSuccess != 1200 {
+		t.Errorf("expected the last successful build to complete at 1200, got %v", lastSuccess)
+	}
+}
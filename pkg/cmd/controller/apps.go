@@ -1,10 +1,15 @@
 package controller
 
 import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes"
 
+	"github.com/openshift/library-go/pkg/image/reference"
 	deployercontroller "github.com/openshift/openshift-controller-manager/pkg/apps/deployer"
 	deployconfigcontroller "github.com/openshift/openshift-controller-manager/pkg/apps/deploymentconfig"
+	appsmetrics "github.com/openshift/openshift-controller-manager/pkg/apps/metrics/prometheus"
 	"github.com/openshift/openshift-controller-manager/pkg/cmd/imageformat"
 )
 
@@ -22,19 +27,57 @@ func RunDeployerController(ctx *ControllerContext) (bool, error) {
 	imageTemplate := imageformat.NewDefaultImageTemplate()
 	imageTemplate.Format = ctx.OpenshiftControllerConfig.Deployer.ImageTemplateFormat.Format
 	imageTemplate.Latest = ctx.OpenshiftControllerConfig.Deployer.ImageTemplateFormat.Latest
+	settings := ctx.ControllerSettings.Deployer
+	deployerImage, err := deployerImageFor(&imageTemplate, settings.ImagePullPolicy)
+	if err != nil {
+		return true, err
+	}
 
 	go deployercontroller.NewDeployerController(
 		ctx.KubernetesInformers.Core().V1().ReplicationControllers(),
 		ctx.KubernetesInformers.Core().V1().Pods(),
+		ctx.ImageInformers.Image().V1().ImageStreams(),
 		kubeClient,
 		deployerServiceAccountName,
-		imageTemplate.ExpandOrDie("deployer"),
+		deployerImage,
 		nil,
+		deployercontroller.DeployerPodDefaults{
+			Resources:                 settings.Resources,
+			NodeSelector:              settings.NodeSelector,
+			Tolerations:               settings.Tolerations,
+			Affinity:                  settings.Affinity,
+			TopologySpreadConstraints: settings.TopologySpreadConstraints,
+			CompleteRetention:         settings.CompletePodRetention,
+			FailedRetention:           settings.FailedPodRetention,
+			FailedTTL:                 settings.FailedPodTTL.Duration,
+			FailureLogBytes:           settings.FailureLogBytes,
+			ImagePullPolicy:           settings.ImagePullPolicy,
+			ImagePullSecret:           settings.ImagePullSecret,
+		},
 	).Run(5, ctx.Stop)
 
 	return true, nil
 }
 
+// deployerImageFor expands the deployer image of the image template and validates it and the
+// deployer image pull policy, so that a misconfiguration fails the start of the controller
+// rather than every rollout.
+func deployerImageFor(imageTemplate *imageformat.ImageTemplate, pullPolicy corev1.PullPolicy) (string, error) {
+	image, err := imageTemplate.Expand("deployer")
+	if err != nil {
+		return "", fmt.Errorf("invalid deployer image template format %q: %v", imageTemplate.Format, err)
+	}
+	if _, err := reference.Parse(image); err != nil {
+		return "", fmt.Errorf("invalid deployer image %q: %v", image, err)
+	}
+	switch pullPolicy {
+	case "", corev1.PullAlways, corev1.PullIfNotPresent, corev1.PullNever:
+	default:
+		return "", fmt.Errorf("invalid deployer image pull policy %q", pullPolicy)
+	}
+	return image, nil
+}
+
 func RunDeploymentConfigController(ctx *ControllerContext) (bool, error) {
 	saName := infraDeploymentConfigControllerServiceAccountName
 
@@ -43,11 +86,13 @@ func RunDeploymentConfigController(ctx *ControllerContext) (bool, error) {
 		return true, err
 	}
 
+	appsmetrics.SetDeploymentConfigLabelLimit(ctx.ControllerSettings.DeploymentConfig.MetricsLabelLimit)
 	go deployconfigcontroller.NewDeploymentConfigController(
 		ctx.AppsInformers.Apps().V1().DeploymentConfigs(),
 		ctx.KubernetesInformers.Core().V1().ReplicationControllers(),
 		ctx.ClientBuilder.OpenshiftAppsClientOrDie(saName),
 		kubeClient,
+		ctx.ControllerSettings.DeploymentConfig.ConfigChangeDebounce.Duration,
 	).Run(5, ctx.Stop)
 
 	return true, nil
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/openshift/openshift-controller-manager/pkg/cmd/imageformat"
+)
+
+func TestDeployerImageFor(t *testing.T) {
+	testcases := []struct {
+		Format        string
+		PullPolicy    corev1.PullPolicy
+		ExpectedImage string
+		ExpectedErr   bool
+	}{
+		{
+			Format:        "mirror.example.com/openshift/origin-${component}:v4",
+			ExpectedImage: "mirror.example.com/openshift/origin-deployer:v4",
+		},
+		{
+			Format:        "mirror.example.com/openshift/deployer@sha256:2bb7e9d0b6c8a7f2a1f2bbd6e2e54a0c7a5b2d7ad9d1b06b7c6a5e97e8a90c5d",
+			PullPolicy:    corev1.PullAlways,
+			ExpectedImage: "mirror.example.com/openshift/deployer@sha256:2bb7e9d0b6c8a7f2a1f2bbd6e2e54a0c7a5b2d7ad9d1b06b7c6a5e97e8a90c5d",
+		},
+		{
+			Format:      "mirror.example.com/openshift/origin-${component}:${unknown}",
+			ExpectedErr: true,
+		},
+		{
+			Format:      "Mirror.example.com/OpenShift/${component}:v4",
+			ExpectedErr: true,
+		},
+		{
+			Format:      "mirror.example.com/openshift/origin-${component}:v4",
+			PullPolicy:  "Sometimes",
+			ExpectedErr: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		imageTemplate := imageformat.NewDefaultImageTemplate()
+		imageTemplate.Format = tc.Format
+		image, err := deployerImageFor(&imageTemplate, tc.PullPolicy)
+		if tc.ExpectedErr {
+			if err == nil {
+				t.Errorf("%s: expected an error, got image %q", tc.Format, image)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tc.Format, err)
+			continue
+		}
+		if image != tc.ExpectedImage {
+			t.Errorf("%s: expected image %q, got %q", tc.Format, tc.ExpectedImage, image)
+		}
+	}
+}
@@ -1,8 +1,11 @@
 package controller
 
 import (
+	"time"
+
 	"github.com/openshift/openshift-controller-manager/pkg/authorization/defaultrolebindings"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 func RunDefaultRoleBindingController(ctx *ControllerContext) (bool, error) {
@@ -11,7 +14,10 @@ func RunDefaultRoleBindingController(ctx *ControllerContext) (bool, error) {
 		return true, err
 	}
 
-	return runRoleBindingController(ctx, kubeClient, "DefaultRoleBindingController")
+	// The additional role bindings were validated when the controller settings were read.
+	return runRoleBindingController(ctx, kubeClient, defaultrolebindings.DefaultRoleBindingControllerName, defaultrolebindings.RoleBindingControllerOptions{
+		AdditionalRoleBindings: ctx.ControllerSettings.RoleBindings.AdditionalRoleBindings,
+	})
 }
 
 func RunBuilderRoleBindingController(ctx *ControllerContext) (bool, error) {
@@ -23,7 +29,7 @@ func RunBuilderRoleBindingController(ctx *ControllerContext) (bool, error) {
 		return true, err
 	}
 
-	return runRoleBindingController(ctx, kubeClient, "BuilderRoleBindingController")
+	return runRoleBindingController(ctx, kubeClient, defaultrolebindings.BuilderRoleBindingControllerName, defaultrolebindings.RoleBindingControllerOptions{})
 }
 
 func RunDeployerRoleBindingController(ctx *ControllerContext) (bool, error) {
@@ -35,7 +41,7 @@ func RunDeployerRoleBindingController(ctx *ControllerContext) (bool, error) {
 		return true, err
 	}
 
-	return runRoleBindingController(ctx, kubeClient, "DeployerRoleBindingController")
+	return runRoleBindingController(ctx, kubeClient, defaultrolebindings.DeployerRoleBindingControllerName, defaultrolebindings.RoleBindingControllerOptions{})
 }
 
 func RunImagePullerRoleBindingController(ctx *ControllerContext) (bool, error) {
@@ -47,16 +53,60 @@ func RunImagePullerRoleBindingController(ctx *ControllerContext) (bool, error) {
 		return true, err
 	}
 
-	return runRoleBindingController(ctx, kubeClient, "ImagePullerRoleBindingController")
+	return runRoleBindingController(ctx, kubeClient, defaultrolebindings.ImagePullerRoleBindingControllerName, defaultrolebindings.RoleBindingControllerOptions{})
+}
+
+// defaultRoleBindingsCleanupFeatureGate enables removal of the builder and deployer role bindings
+// when the Build or DeploymentConfig capability is disabled.
+const defaultRoleBindingsCleanupFeatureGate = "DefaultRoleBindingsCleanup"
+
+// maxCleanupDeletesPerMinute keeps the cleanup of role bindings across all namespaces from
+// flooding the API server.
+const maxCleanupDeletesPerMinute = 300
+
+func RunBuilderRoleBindingCleanupController(ctx *ControllerContext) (bool, error) {
+	return runRoleBindingCleanupController(ctx, defaultrolebindings.BuilderRoleBindingControllerName)
+}
+
+func RunDeployerRoleBindingCleanupController(ctx *ControllerContext) (bool, error) {
+	return runRoleBindingCleanupController(ctx, defaultrolebindings.DeployerRoleBindingControllerName)
+}
+
+func runRoleBindingCleanupController(ctx *ControllerContext, controllerName string) (bool, error) {
+	if !ctx.IsFeatureGateEnabled(defaultRoleBindingsCleanupFeatureGate) {
+		return false, nil
+	}
+	kubeClient, err := ctx.ClientBuilder.Client(infraDefaultRoleBindingsControllerServiceAccountName)
+	if err != nil {
+		return true, err
+	}
+
+	return runRoleBindingController(ctx, kubeClient, controllerName, defaultrolebindings.RoleBindingControllerOptions{
+		Cleanup:                    true,
+		MaxCleanupDeletesPerMinute: maxCleanupDeletesPerMinute,
+	})
 }
 
-func runRoleBindingController(cctx *ControllerContext, kubeClient kubernetes.Interface, controllerName string) (bool, error) {
+// roleBindingCreateRateLimiter is shared by all default rolebindings controllers so that together
+// they cannot hammer the API server when role binding creation fails cluster-wide.
+var roleBindingCreateRateLimiter = flowcontrol.NewTokenBucketRateLimiter(20, 50)
+
+func runRoleBindingController(cctx *ControllerContext, kubeClient kubernetes.Interface, controllerName string, options defaultrolebindings.RoleBindingControllerOptions) (bool, error) {
+	if options.SkippedNamespacePrefixes == nil {
+		options.SkippedNamespacePrefixes = cctx.ControllerSettings.RoleBindings.SkippedNamespacePrefixes
+	}
+	options.CreateRateLimiter = roleBindingCreateRateLimiter
+	options.MaxRetryDelay = 5 * time.Minute
+	options.MaxRetries = cctx.ControllerSettings.RoleBindings.MaxRetries
+	options.DryRun = cctx.ControllerSettings.RoleBindings.DryRun
+
 	go defaultrolebindings.NewRoleBindingsController(
 		cctx.KubernetesInformers.Rbac().V1().RoleBindings(),
 		cctx.KubernetesInformers.Core().V1().Namespaces(),
-		kubeClient.RbacV1(),
+		kubeClient,
 		controllerName,
-	).Run(5, cctx.Stop)
+		options,
+	).Run(cctx.ControllerSettings.RoleBindings.Workers, cctx.Stop)
 
 	return true, nil
 }
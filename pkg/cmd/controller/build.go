@@ -1,8 +1,6 @@
 package controller
 
 import (
-	"strings"
-
 	clientset "k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 
@@ -11,10 +9,16 @@ import (
 	builddefaults "github.com/openshift/openshift-controller-manager/pkg/build/controller/build/defaults"
 	buildoverrides "github.com/openshift/openshift-controller-manager/pkg/build/controller/build/overrides"
 	buildconfigcontroller "github.com/openshift/openshift-controller-manager/pkg/build/controller/buildconfig"
+	buildcommon "github.com/openshift/openshift-controller-manager/pkg/build/controller/common"
 	buildstrategy "github.com/openshift/openshift-controller-manager/pkg/build/controller/strategy"
 	"github.com/openshift/openshift-controller-manager/pkg/cmd/imageformat"
 )
 
+// buildRetentionPolicy returns the age based pruning policy of completed builds.
+func buildRetentionPolicy(settings BuildControllerSettings) buildcommon.BuildRetentionPolicy {
+	return buildcommon.BuildRetentionPolicy{DefaultMaxAge: settings.CompletedBuildMaxAge.Duration}
+}
+
 // RunController starts the build sync loop for builds and buildConfig processing.
 func RunBuildController(ctx *ControllerContext) (bool, error) {
 
@@ -36,6 +40,7 @@ func RunBuildController(ctx *ControllerContext) (bool, error) {
 		klog.Fatal(err)
 	}
 	securityClient := ctx.ClientBuilder.OpenshiftSecurityClientOrDie(infraBuildControllerServiceAccountName)
+	imageClient := ctx.ClientBuilder.OpenshiftImageClientOrDie(infraBuildControllerServiceAccountName)
 
 	buildInformer := ctx.BuildInformers.Build().V1().Builds()
 	buildConfigInformer := ctx.BuildInformers.Build().V1().BuildConfigs()
@@ -52,17 +57,11 @@ func RunBuildController(ctx *ControllerContext) (bool, error) {
 	imageContentSourcePolicyInformer := ctx.OperatorInformers.Operator().V1alpha1().ImageContentSourcePolicies()
 	imageDigestMirrorSetInformer := ctx.ConfigInformers.Config().V1().ImageDigestMirrorSets()
 	imageTagMirrorSetInformer := ctx.ConfigInformers.Config().V1().ImageTagMirrorSets()
+	namespaceInformer := ctx.KubernetesInformers.Core().V1().Namespaces()
+	persistentVolumeClaimInformer := ctx.KubernetesInformers.Core().V1().PersistentVolumeClaims()
 
-	fg := ctx.OpenshiftControllerConfig.FeatureGates
-	csiVolumesEnabled := false
-	if fg != nil {
-		for _, v := range fg {
-			v = strings.TrimSpace(v)
-			if v == "BuildCSIVolumes=true" {
-				csiVolumesEnabled = true
-			}
-		}
-	}
+	csiVolumesEnabled := ctx.IsFeatureGateEnabled("BuildCSIVolumes")
+	settings := ctx.ControllerSettings.Build
 
 	buildControllerParams := &buildcontroller.BuildControllerParams{
 		BuildInformer:                      buildInformer,
@@ -80,8 +79,11 @@ func RunBuildController(ctx *ControllerContext) (bool, error) {
 		ImageContentSourcePolicyInformer:   imageContentSourcePolicyInformer,
 		ImageDigestMirrorSetInformer:       imageDigestMirrorSetInformer,
 		ImageTagMirrorSetInformer:          imageTagMirrorSetInformer,
+		NamespaceInformer:                  namespaceInformer,
+		PersistentVolumeClaimInformer:      persistentVolumeClaimInformer,
 		KubeClient:                         externalKubeClient,
 		BuildClient:                        buildClient,
+		ImageClient:                        imageClient,
 		DockerBuildStrategy: &buildstrategy.DockerBuildStrategy{
 			Image:                  imageTemplate.ExpandOrDie("docker-builder"),
 			BuildCSIVolumesEnabled: csiVolumesEnabled,
@@ -91,10 +93,41 @@ func RunBuildController(ctx *ControllerContext) (bool, error) {
 			SecurityClient:          securityClient.SecurityV1(),
 			BuildCSIVolumeseEnabled: csiVolumesEnabled,
 		},
-		CustomBuildStrategy:      &buildstrategy.CustomBuildStrategy{},
-		BuildDefaults:            builddefaults.BuildDefaults{Config: ctx.OpenshiftControllerConfig.Build.BuildDefaults},
-		BuildOverrides:           buildoverrides.BuildOverrides{Config: ctx.OpenshiftControllerConfig.Build.BuildOverrides},
-		InternalRegistryHostname: ctx.OpenshiftControllerConfig.DockerPullSecret.InternalRegistryHostname,
+		CustomBuildStrategy: &buildstrategy.CustomBuildStrategy{},
+		BuildDefaults: builddefaults.BuildDefaults{
+			Config:                    ctx.OpenshiftControllerConfig.Build.BuildDefaults,
+			PriorityClassName:         settings.DefaultPriorityClassName,
+			CompletionDeadlineSeconds: settings.DefaultCompletionDeadlineSeconds,
+			Tolerations:               settings.DefaultTolerations,
+			DefaultGitCloneDepth:      settings.DefaultGitCloneDepth,
+			DisableSubmodules:         settings.DisableSubmodules,
+			LogLevel:                  settings.DefaultLogLevel,
+			PodAffinity:               settings.DefaultPodAffinity,
+			PodAntiAffinity:           settings.DefaultPodAntiAffinity,
+			TopologySpreadConstraints: settings.DefaultTopologySpreadConstraints,
+			StrategyResources:         settings.DefaultStrategyResources,
+			RescheduleLostPods:        settings.RescheduleLostPods,
+			MaxPodReschedules:         settings.MaxPodReschedules,
+			ImageLabelTemplates:       settings.DefaultImageLabels,
+		},
+		BuildOverrides: buildoverrides.BuildOverrides{
+			Config:            ctx.OpenshiftControllerConfig.Build.BuildOverrides,
+			PriorityClassName: settings.OverridePriorityClassName,
+			AllowedEnvNames:   settings.AllowedEnvNames,
+			DeniedEnvNames:    settings.DeniedEnvNames,
+			StrategyForcePull: settings.ForcePull,
+		},
+		InternalRegistryHostname:        ctx.OpenshiftControllerConfig.DockerPullSecret.InternalRegistryHostname,
+		BuildRetention:                  buildRetentionPolicy(settings),
+		MaxConcurrentBuildsPerNamespace: settings.MaxConcurrentBuildsPerNamespace,
+		MaxActiveBuildPods:              settings.MaxActiveBuildPods,
+		LegacyCompletionDeadline:        settings.LegacyCompletionDeadline,
+		BuildPendingTimeout:             settings.PendingTimeout.Duration,
+		ImageImportTimeout:              settings.ImageImportTimeout.Duration,
+		CancellationGracePeriod:         settings.CancellationGracePeriod.Duration,
+		GeneratedObjectsRetention:       settings.GeneratedObjectsRetention.Duration,
+		BuildConfigMetricsNamespaces:    settings.BuildConfigMetricsNamespaces,
+		OrphanedBuildPodGracePeriod:     settings.OrphanedPodGracePeriod.Duration,
 	}
 
 	go buildcontroller.NewBuildController(buildControllerParams).Run(5, ctx.Stop)
@@ -108,7 +141,7 @@ func RunBuildConfigChangeController(ctx *ControllerContext) (bool, error) {
 	buildConfigInformer := ctx.BuildInformers.Build().V1().BuildConfigs()
 	buildInformer := ctx.BuildInformers.Build().V1().Builds()
 
-	controller := buildconfigcontroller.NewBuildConfigController(buildClient, kubeExternalClient, buildConfigInformer, buildInformer)
+	controller := buildconfigcontroller.NewBuildConfigController(buildClient, kubeExternalClient, buildConfigInformer, buildInformer, buildRetentionPolicy(ctx.ControllerSettings.Build))
 	go controller.Run(5, ctx.Stop)
 	return true, nil
 }
@@ -33,6 +33,13 @@ var ControllerInitializers = map[openshiftcontrolplanev1.OpenShiftControllerName
 	openshiftcontrolplanev1.OpenShiftUnidlingController: RunUnidlingController,
 }
 
+// DisabledControllerInitializers are run in place of the matching ControllerInitializers entry when
+// that controller is disabled, e.g. to clean up after a capability that was turned off.
+var DisabledControllerInitializers = map[openshiftcontrolplanev1.OpenShiftControllerName]InitFunc{
+	openshiftcontrolplanev1.OpenShiftBuilderRoleBindingsController:  RunBuilderRoleBindingCleanupController,
+	openshiftcontrolplanev1.OpenShiftDeployerRoleBindingsController: RunDeployerRoleBindingCleanupController,
+}
+
 const (
 	infraOriginNamespaceServiceAccountName                      = "origin-namespace-controller"
 	infraServiceAccountControllerServiceAccountName             = "serviceaccount-controller"
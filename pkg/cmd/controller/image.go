@@ -44,7 +44,7 @@ func RunImageTriggerController(ctx *ControllerContext) (bool, error) {
 			Informer:  ctx.AppsInformers.Apps().V1().DeploymentConfigs().Informer(),
 			Store:     ctx.AppsInformers.Apps().V1().DeploymentConfigs().Informer().GetIndexer(),
 			TriggerFn: triggerdeploymentconfigs.NewDeploymentConfigTriggerIndexer,
-			Reactor:   &triggerdeploymentconfigs.DeploymentConfigReactor{Client: appsClient.AppsV1()},
+			Reactor:   &triggerdeploymentconfigs.DeploymentConfigReactor{Client: appsClient.AppsV1(), AccessReviewer: kclient.AuthorizationV1()},
 		})
 	}
 	if ctx.IsControllerEnabled(string(openshiftcontrolplanev1.OpenShiftBuildController)) {
@@ -57,7 +57,7 @@ func RunImageTriggerController(ctx *ControllerContext) (bool, error) {
 			Informer:  ctx.BuildInformers.Build().V1().BuildConfigs().Informer(),
 			Store:     ctx.BuildInformers.Build().V1().BuildConfigs().Informer().GetIndexer(),
 			TriggerFn: triggerbuildconfigs.NewBuildConfigTriggerIndexer,
-			Reactor:   triggerbuildconfigs.NewBuildConfigReactor(buildClient.BuildV1(), kclient.CoreV1().RESTClient()),
+			Reactor:   triggerbuildconfigs.NewBuildConfigReactor(buildClient.BuildV1(), kclient.AuthorizationV1(), kclient.CoreV1().RESTClient()),
 		})
 	}
 	sources = append(sources, imagetriggercontroller.TriggerSource{
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 func NewControllerContext(
 	ctx context.Context,
 	config openshiftcontrolplanev1.OpenShiftControllerManagerConfig,
+	settings ControllerSettings,
 	inClientConfig *rest.Config,
 ) (*ControllerContext, error) {
 
@@ -99,6 +101,7 @@ func NewControllerContext(
 
 	openshiftControllerContext := &ControllerContext{
 		OpenshiftControllerConfig: config,
+		ControllerSettings:        settings,
 
 		// k8s 1.21 rebase - SAControllerClientBuilder replaced with NewDynamicClientBuilder
 		// See https://github.com/kubernetes/kubernetes/pull/99291
@@ -134,6 +137,9 @@ func NewControllerContext(
 
 type ControllerContext struct {
 	OpenshiftControllerConfig openshiftcontrolplanev1.OpenShiftControllerManagerConfig
+	// ControllerSettings are the settings of the controllers the OpenshiftControllerConfig does not
+	// carry.
+	ControllerSettings ControllerSettings
 
 	// ClientBuilder will provide a client for this controller to use
 	ClientBuilder ControllerClientBuilder
@@ -195,6 +201,17 @@ func (c *ControllerContext) IsControllerEnabled(name string) bool {
 	return app.IsControllerEnabled(name, sets.String{}, c.OpenshiftControllerConfig.Controllers)
 }
 
+// IsFeatureGateEnabled returns true if the named feature gate is set to "true" in the
+// OpenShiftControllerManagerConfig feature gates.
+func (c *ControllerContext) IsFeatureGateEnabled(name string) bool {
+	for _, v := range c.OpenshiftControllerConfig.FeatureGates {
+		if strings.TrimSpace(v) == name+"=true" {
+			return true
+		}
+	}
+	return false
+}
+
 type ControllerClientBuilder interface {
 	clientbuilder.ControllerClientBuilder
 
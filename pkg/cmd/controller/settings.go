@@ -0,0 +1,267 @@
+package controller
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/yaml"
+
+	buildv1 "github.com/openshift/api/build/v1"
+	"github.com/openshift/openshift-controller-manager/pkg/authorization/defaultrolebindings"
+	builddefaults "github.com/openshift/openshift-controller-manager/pkg/build/controller/build/defaults"
+	buildoverrides "github.com/openshift/openshift-controller-manager/pkg/build/controller/build/overrides"
+)
+
+// ControllerSettings are the settings of the controllers that the OpenShiftControllerManagerConfig
+// does not carry. They are read at startup from the YAML or JSON file of the --controller-settings
+// flag. The settings the file does not set keep their DefaultControllerSettings.
+type ControllerSettings struct {
+	Build            BuildControllerSettings            `json:"build,omitempty"`
+	Deployer         DeployerControllerSettings         `json:"deployer,omitempty"`
+	DeploymentConfig DeploymentConfigControllerSettings `json:"deploymentConfig,omitempty"`
+	RoleBindings     RoleBindingControllerSettings      `json:"roleBindings,omitempty"`
+}
+
+// BuildControllerSettings are the settings of the build controllers. Most of them can also be set
+// per namespace, build config or build through annotations.
+type BuildControllerSettings struct {
+	// CompletedBuildMaxAge is the age after which the completed builds of build configs are pruned.
+	// Builds are not pruned by age when it is zero.
+	CompletedBuildMaxAge metav1.Duration `json:"completedBuildMaxAge,omitempty"`
+	// MaxConcurrentBuildsPerNamespace is the cluster default of the concurrent build limit of
+	// namespaces. Builds are not limited when it is zero.
+	MaxConcurrentBuildsPerNamespace int `json:"maxConcurrentBuildsPerNamespace,omitempty"`
+	// MaxActiveBuildPods is the limit of pending and running build pods across the cluster. Build
+	// pods are not limited when it is zero.
+	MaxActiveBuildPods int `json:"maxActiveBuildPods,omitempty"`
+	// DefaultPriorityClassName and OverridePriorityClassName are the priority class default and
+	// override of build pods.
+	DefaultPriorityClassName  string `json:"defaultPriorityClassName,omitempty"`
+	OverridePriorityClassName string `json:"overridePriorityClassName,omitempty"`
+	// AllowedEnvNames and DeniedEnvNames filter the environment variables passed to custom builder
+	// images. All variables are passed when both are empty.
+	AllowedEnvNames []string `json:"allowedEnvNames,omitempty"`
+	DeniedEnvNames  []string `json:"deniedEnvNames,omitempty"`
+	// ForcePull overrides the forcePull of the builds of each strategy, in place of the forcePull
+	// of the build overrides configuration.
+	ForcePull buildoverrides.StrategyForcePull `json:"forcePull,omitempty"`
+	// DefaultCompletionDeadlineSeconds is the completion deadline of builds that do not set their
+	// own. Build pods keep the one week deadline of the build strategies when it is unset.
+	DefaultCompletionDeadlineSeconds *int64 `json:"defaultCompletionDeadlineSeconds,omitempty"`
+	// DefaultTolerations are added to build pods that do not tolerate the same taints. The build
+	// overrides configuration carries the tolerations that replace conflicting ones.
+	DefaultTolerations []corev1.Toleration `json:"defaultTolerations,omitempty"`
+	// DefaultGitCloneDepth and DisableSubmodules are the git clone depth and submodule handling of
+	// builds that do not set GIT_CLONE_DEPTH or GIT_DISABLE_SUBMODULES.
+	DefaultGitCloneDepth *int32 `json:"defaultGitCloneDepth,omitempty"`
+	DisableSubmodules    bool   `json:"disableSubmodules,omitempty"`
+	// DefaultLogLevel is the log level of builds that do not set BUILD_LOGLEVEL or the log level
+	// annotation.
+	DefaultLogLevel *int32 `json:"defaultLogLevel,omitempty"`
+	// DefaultPodAffinity, DefaultPodAntiAffinity and DefaultTopologySpreadConstraints are merged
+	// into build pods without their own. Terms and constraints without a label selector select all
+	// build pods.
+	DefaultPodAffinity               *corev1.PodAffinity               `json:"defaultPodAffinity,omitempty"`
+	DefaultPodAntiAffinity           *corev1.PodAntiAffinity           `json:"defaultPodAntiAffinity,omitempty"`
+	DefaultTopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"defaultTopologySpreadConstraints,omitempty"`
+	// DefaultStrategyResources are the default resources of the builds of each strategy. The
+	// resources of the build defaults configuration apply to strategies without their own.
+	DefaultStrategyResources builddefaults.StrategyResources `json:"defaultStrategyResources,omitempty"`
+	// DefaultImageLabels are the default output image labels of builds, whose values may refer to
+	// the build name, namespace, build config name, git ref and commit.
+	DefaultImageLabels []buildv1.ImageLabel `json:"defaultImageLabels,omitempty"`
+	// RescheduleLostPods creates a new build pod, at most MaxPodReschedules times, for running
+	// builds whose build pod was deleted before they pushed their output image.
+	RescheduleLostPods bool `json:"rescheduleLostPods,omitempty"`
+	MaxPodReschedules  int  `json:"maxPodReschedules,omitempty"`
+	// LegacyCompletionDeadline measures the completion deadline of builds from the creation of
+	// their build pod instead of the start of the build.
+	LegacyCompletionDeadline bool `json:"legacyCompletionDeadline,omitempty"`
+	// PendingTimeout is how long build pods may be pending before their build fails. Build pods
+	// may be pending forever when it is zero.
+	PendingTimeout metav1.Duration `json:"pendingTimeout,omitempty"`
+	// ImageImportTimeout is how long new builds wait for the first scheduled import of their
+	// strategy image stream tag before they fail. Builds wait forever when it is zero.
+	ImageImportTimeout metav1.Duration `json:"imageImportTimeout,omitempty"`
+	// CancellationGracePeriod is how long the build pods of cancelled builds may take to terminate
+	// before they are force deleted.
+	CancellationGracePeriod metav1.Duration `json:"cancellationGracePeriod,omitempty"`
+	// GeneratedObjectsRetention is how long the configMaps generated for build pods are kept after
+	// their build completed. They are kept while the build pods exist, and forever when it is zero.
+	GeneratedObjectsRetention metav1.Duration `json:"generatedObjectsRetention,omitempty"`
+	// BuildConfigMetricsNamespaces are the namespaces whose build configs the per build config
+	// build metrics are recorded for. Recording them for every namespace of large clusters would
+	// create too many series.
+	BuildConfigMetricsNamespaces []string `json:"buildConfigMetricsNamespaces,omitempty"`
+	// OrphanedPodGracePeriod is how long build pods whose build was deleted, such as with an
+	// orphaning delete, are kept before they are deleted. They are never deleted when it is zero.
+	OrphanedPodGracePeriod metav1.Duration `json:"orphanedPodGracePeriod,omitempty"`
+}
+
+// DeployerControllerSettings are the settings of the deployer pods of deployment configs. Most of
+// them can also be set per deployment config through annotations.
+type DeployerControllerSettings struct {
+	// Resources are the resource requirements of the deployer pods of deployment configs whose
+	// strategy has none. The limit range of the namespace defaults them when empty.
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// NodeSelector and Tolerations are merged into the node selector and the tolerations deployer
+	// pods copy from the pod template of their deployment.
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	Tolerations  []corev1.Toleration `json:"tolerations,omitempty"`
+	// Affinity and TopologySpreadConstraints place the deployer pods of the deployment configs
+	// that do not set their own, for example on infra nodes.
+	Affinity                  *corev1.Affinity                  `json:"affinity,omitempty"`
+	TopologySpreadConstraints []corev1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// CompletePodRetention and FailedPodRetention are the numbers of complete and failed rollouts
+	// of a deployment config whose deployer and hook pods are kept. The pods of all rollouts are
+	// kept when they are null.
+	CompletePodRetention *int `json:"completePodRetention"`
+	FailedPodRetention   *int `json:"failedPodRetention"`
+	// FailedPodTTL is how long the deployer and hook pods of failed rollouts other than the latest
+	// of their deployment config are kept. They are kept forever when it is zero.
+	FailedPodTTL metav1.Duration `json:"failedPodTTL,omitempty"`
+	// FailureLogBytes is the size of the tail of the deployer pod log recorded on the deployments
+	// of failed rollouts. No log is recorded when it is zero.
+	FailureLogBytes int `json:"failureLogBytes,omitempty"`
+	// ImagePullPolicy and ImagePullSecret are the pull policy of the deployer image and a secret
+	// deployer pods pull it with, for example from a mirror registry. The deployer image itself is
+	// set by the image template format of the deployer configuration.
+	ImagePullPolicy corev1.PullPolicy `json:"imagePullPolicy,omitempty"`
+	ImagePullSecret string            `json:"imagePullSecret,omitempty"`
+}
+
+// DeploymentConfigControllerSettings are the settings of the deployment config controller.
+type DeploymentConfigControllerSettings struct {
+	// ConfigChangeDebounce is how long deployment configs wait for further changes before a config
+	// change rolls them out, unless they set their own with the config change debounce annotation.
+	// Config changes are rolled out at once when it is zero.
+	ConfigChangeDebounce metav1.Duration `json:"configChangeDebounce,omitempty"`
+	// MetricsLabelLimit limits the number of deployment configs the rollout metrics are labeled
+	// with. The rollouts of the other deployment configs are recorded under empty namespace and
+	// deployment config labels. Zero is unlimited.
+	MetricsLabelLimit int `json:"metricsLabelLimit,omitempty"`
+}
+
+// RoleBindingControllerSettings are the settings of the default rolebindings controllers.
+type RoleBindingControllerSettings struct {
+	// AdditionalRoleBindings are created by the DefaultRoleBindingController in every synced
+	// namespace next to the built-in default role bindings.
+	AdditionalRoleBindings []defaultrolebindings.AdditionalRoleBinding `json:"additionalRoleBindings,omitempty"`
+	// DryRun makes the controllers only report missing role bindings, through their logs and the
+	// missing role bindings metric, instead of creating them.
+	DryRun bool `json:"dryRun,omitempty"`
+	// SkippedNamespacePrefixes are the name prefixes of the namespaces the role binding controllers
+	// do not sync. An empty list syncs every namespace.
+	SkippedNamespacePrefixes []string `json:"skippedNamespacePrefixes,omitempty"`
+	// Workers is the number of namespaces each role binding controller syncs concurrently.
+	Workers int `json:"workers,omitempty"`
+	// MaxRetries is the number of times a failing namespace is retried before it is dropped until
+	// the next namespace resync. Zero retries forever.
+	MaxRetries int `json:"maxRetries,omitempty"`
+}
+
+// DefaultControllerSettings returns the settings the controllers run with when the settings file
+// does not set them.
+func DefaultControllerSettings() ControllerSettings {
+	return ControllerSettings{
+		Build: BuildControllerSettings{
+			MaxPodReschedules:         3,
+			ImageImportTimeout:        metav1.Duration{Duration: 15 * time.Minute},
+			CancellationGracePeriod:   metav1.Duration{Duration: 30 * time.Second},
+			GeneratedObjectsRetention: metav1.Duration{Duration: time.Hour},
+		},
+		Deployer: DeployerControllerSettings{
+			CompletePodRetention: pointer.Int(5),
+			FailedPodRetention:   pointer.Int(10),
+			FailureLogBytes:      4 * 1024,
+		},
+		DeploymentConfig: DeploymentConfigControllerSettings{
+			MetricsLabelLimit: 500,
+		},
+		RoleBindings: RoleBindingControllerSettings{
+			SkippedNamespacePrefixes: append([]string{}, defaultrolebindings.DefaultSkippedNamespacePrefixes...),
+			Workers:                  5,
+			MaxRetries:               15,
+		},
+	}
+}
+
+// ReadControllerSettings reads the controller settings from the given file on top of the
+// DefaultControllerSettings. It returns the defaults when the path is empty.
+func ReadControllerSettings(path string) (ControllerSettings, error) {
+	settings := DefaultControllerSettings()
+	if len(path) == 0 {
+		return settings, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return settings, err
+	}
+	if err := yaml.UnmarshalStrict(content, &settings); err != nil {
+		return settings, fmt.Errorf("invalid controller settings %s: %v", path, err)
+	}
+	if err := settings.validate(); err != nil {
+		return settings, fmt.Errorf("invalid controller settings %s: %v", path, err)
+	}
+	return settings, nil
+}
+
+func (s ControllerSettings) validate() error {
+	build, deployer := s.Build, s.Deployer
+	for name, value := range map[string]int{
+		"build.maxConcurrentBuildsPerNamespace": build.MaxConcurrentBuildsPerNamespace,
+		"build.maxActiveBuildPods":              build.MaxActiveBuildPods,
+		"build.maxPodReschedules":               build.MaxPodReschedules,
+		"deployer.failureLogBytes":              deployer.FailureLogBytes,
+		"deploymentConfig.metricsLabelLimit":    s.DeploymentConfig.MetricsLabelLimit,
+		"roleBindings.maxRetries":               s.RoleBindings.MaxRetries,
+	} {
+		if value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	for name, value := range map[string]*int{
+		"deployer.completePodRetention": deployer.CompletePodRetention,
+		"deployer.failedPodRetention":   deployer.FailedPodRetention,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	for name, value := range map[string]metav1.Duration{
+		"build.completedBuildMaxAge":            build.CompletedBuildMaxAge,
+		"build.pendingTimeout":                  build.PendingTimeout,
+		"build.imageImportTimeout":              build.ImageImportTimeout,
+		"build.cancellationGracePeriod":         build.CancellationGracePeriod,
+		"build.generatedObjectsRetention":       build.GeneratedObjectsRetention,
+		"build.orphanedPodGracePeriod":          build.OrphanedPodGracePeriod,
+		"deployer.failedPodTTL":                 deployer.FailedPodTTL,
+		"deploymentConfig.configChangeDebounce": s.DeploymentConfig.ConfigChangeDebounce,
+	} {
+		if value.Duration < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if build.DefaultCompletionDeadlineSeconds != nil && *build.DefaultCompletionDeadlineSeconds <= 0 {
+		return fmt.Errorf("build.defaultCompletionDeadlineSeconds must be positive")
+	}
+	if s.RoleBindings.Workers <= 0 {
+		return fmt.Errorf("roleBindings.workers must be positive")
+	}
+	for i, prefix := range s.RoleBindings.SkippedNamespacePrefixes {
+		if len(prefix) == 0 {
+			return fmt.Errorf("roleBindings.skippedNamespacePrefixes[%d] must not be empty", i)
+		}
+	}
+	if build.DefaultGitCloneDepth != nil && *build.DefaultGitCloneDepth < 0 {
+		return fmt.Errorf("build.defaultGitCloneDepth must not be negative")
+	}
+	if err := defaultrolebindings.ValidateAdditionalRoleBindings(defaultrolebindings.DefaultRoleBindingControllerName, s.RoleBindings.AdditionalRoleBindings); err != nil {
+		return fmt.Errorf("roleBindings.additionalRoleBindings: %v", err)
+	}
+	return nil
+}
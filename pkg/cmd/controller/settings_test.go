@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	"github.com/openshift/openshift-controller-manager/pkg/authorization/defaultrolebindings"
+)
+
+func TestReadControllerSettings(t *testing.T) {
+	testcases := []struct {
+		Name        string
+		Content     string
+		Expected    func(*ControllerSettings)
+		ExpectedErr bool
+	}{
+		{
+			Name: "empty file keeps the defaults",
+		},
+		{
+			Name: "build settings",
+			Content: `
+build:
+  maxConcurrentBuildsPerNamespace: 2
+  maxActiveBuildPods: 50
+  defaultCompletionDeadlineSeconds: 3600
+  defaultTolerations:
+  - key: builds
+    operator: Exists
+    effect: NoSchedule
+  forcePull:
+    sourceStrategy: true
+  imageImportTimeout: 0s
+  buildConfigMetricsNamespaces: [ci]
+  orphanedPodGracePeriod: 10m
+`,
+			Expected: func(s *ControllerSettings) {
+				s.Build.MaxConcurrentBuildsPerNamespace = 2
+				s.Build.MaxActiveBuildPods = 50
+				s.Build.DefaultCompletionDeadlineSeconds = pointer.Int64(3600)
+				s.Build.DefaultTolerations = []corev1.Toleration{{Key: "builds", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}}
+				s.Build.ForcePull.SourceStrategy = pointer.Bool(true)
+				s.Build.ImageImportTimeout = metav1.Duration{}
+				s.Build.BuildConfigMetricsNamespaces = []string{"ci"}
+				s.Build.OrphanedPodGracePeriod = metav1.Duration{Duration: 10 * time.Minute}
+			},
+		},
+		{
+			Name: "deployer settings",
+			Content: `
+deployer:
+  nodeSelector:
+    node-role.kubernetes.io/infra: ""
+  completePodRetention: null
+  failedPodRetention: 2
+  failedPodTTL: 24h
+  imagePullPolicy: Always
+  imagePullSecret: mirror
+`,
+			Expected: func(s *ControllerSettings) {
+				s.Deployer.NodeSelector = map[string]string{"node-role.kubernetes.io/infra": ""}
+				s.Deployer.CompletePodRetention = nil
+				s.Deployer.FailedPodRetention = pointer.Int(2)
+				s.Deployer.FailedPodTTL = metav1.Duration{Duration: 24 * time.Hour}
+				s.Deployer.ImagePullPolicy = corev1.PullAlways
+				s.Deployer.ImagePullSecret = "mirror"
+			},
+		},
+		{
+			Name:    "json",
+			Content: `{"build": {"completedBuildMaxAge": "72h"}, "deploymentConfig": {"configChangeDebounce": "2s", "metricsLabelLimit": 0}}`,
+			Expected: func(s *ControllerSettings) {
+				s.Build.CompletedBuildMaxAge = metav1.Duration{Duration: 72 * time.Hour}
+				s.DeploymentConfig.ConfigChangeDebounce = metav1.Duration{Duration: 2 * time.Second}
+				s.DeploymentConfig.MetricsLabelLimit = 0
+			},
+		},
+		{
+			Name: "role binding settings",
+			Content: `
+roleBindings:
+  additionalRoleBindings:
+  - name: monitoring-view
+    roleRef:
+      apiGroup: rbac.authorization.k8s.io
+      kind: ClusterRole
+      name: view
+    subjects:
+    - kind: ServiceAccount
+      name: monitoring
+  skippedNamespacePrefixes:
+  - openshift-
+  dryRun: true
+  workers: 10
+  maxRetries: 0
+`,
+			Expected: func(s *ControllerSettings) {
+				s.RoleBindings.AdditionalRoleBindings = []defaultrolebindings.AdditionalRoleBinding{{
+					Name:     "monitoring-view",
+					RoleRef:  rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "view"},
+					Subjects: []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "monitoring"}},
+				}}
+				s.RoleBindings.SkippedNamespacePrefixes = []string{"openshift-"}
+				s.RoleBindings.DryRun = true
+				s.RoleBindings.Workers = 10
+				s.RoleBindings.MaxRetries = 0
+			},
+		},
+		{
+			Name:        "invalid additional role binding",
+			Content:     "roleBindings:\n  additionalRoleBindings:\n  - name: admin\n    roleRef:\n      kind: ClusterRole\n      name: admin\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:        "no role binding workers",
+			Content:     "roleBindings:\n  workers: 0\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:    "no skipped namespaces",
+			Content: "roleBindings:\n  skippedNamespacePrefixes: []\n",
+			Expected: func(s *ControllerSettings) {
+				s.RoleBindings.SkippedNamespacePrefixes = []string{}
+			},
+		},
+		{
+			Name:        "empty skipped namespace prefix",
+			Content:     "roleBindings:\n  skippedNamespacePrefixes:\n  - \"\"\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:        "negative role binding retries",
+			Content:     "roleBindings:\n  maxRetries: -1\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:        "unknown setting",
+			Content:     "build:\n  maxActivePods: 5\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:        "negative limit",
+			Content:     "build:\n  maxActiveBuildPods: -1\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:        "negative retention",
+			Content:     "deployer:\n  failedPodRetention: -1\n",
+			ExpectedErr: true,
+		},
+		{
+			Name:        "negative duration",
+			Content:     "build:\n  pendingTimeout: -1m\n",
+			ExpectedErr: true,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.Name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings.yaml")
+			if err := os.WriteFile(path, []byte(tc.Content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			settings, err := ReadControllerSettings(path)
+			if tc.ExpectedErr {
+				if err == nil {
+					t.Errorf("expected an error, got %#v", settings)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			expected := DefaultControllerSettings()
+			if tc.Expected != nil {
+				tc.Expected(&expected)
+			}
+			if !reflect.DeepEqual(settings, expected) {
+				t.Errorf("expected settings %#v, got %#v", expected, settings)
+			}
+		})
+	}
+}
+
+func TestReadControllerSettingsWithoutFile(t *testing.T) {
+	settings, err := ReadControllerSettings("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(settings, DefaultControllerSettings()) {
+		t.Errorf("expected the default settings, got %#v", settings)
+	}
+	if _, err := ReadControllerSettings(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing settings file")
+	}
+}
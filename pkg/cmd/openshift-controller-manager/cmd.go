@@ -24,11 +24,15 @@ import (
 	openshiftcontrolplanev1 "github.com/openshift/api/openshiftcontrolplane/v1"
 	"github.com/openshift/library-go/pkg/config/helpers"
 	"github.com/openshift/library-go/pkg/serviceability"
+	origincontrollers "github.com/openshift/openshift-controller-manager/pkg/cmd/controller"
 )
 
 type OpenShiftControllerManager struct {
 	ConfigFilePath string
-	Output         io.Writer
+	// ControllerSettingsFilePath is the file of the controller settings the configuration file
+	// does not carry.
+	ControllerSettingsFilePath string
+	Output                     io.Writer
 }
 
 var longDescription = templates.LongDesc(`
@@ -66,6 +70,8 @@ func NewOpenShiftControllerManagerCommand(name string, out, errout io.Writer, ct
 	flags.StringVar(&options.ConfigFilePath, "config", options.ConfigFilePath, "Location of the master configuration file to run from.")
 	cmd.MarkFlagFilename("config", "yaml", "yml")
 	cmd.MarkFlagRequired("config")
+	flags.StringVar(&options.ControllerSettingsFilePath, "controller-settings", options.ControllerSettingsFilePath, "Location of the file of the controller settings the configuration file does not carry.")
+	cmd.MarkFlagFilename("controller-settings", "yaml", "yml", "json")
 
 	return cmd
 }
@@ -112,9 +118,14 @@ func (o *OpenShiftControllerManager) StartControllerManager(ctx context.Context)
 	}
 	setRecommendedOpenShiftControllerConfigDefaults(config)
 
+	settings, err := origincontrollers.ReadControllerSettings(o.ControllerSettingsFilePath)
+	if err != nil {
+		return err
+	}
+
 	clientConfig, err := helpers.GetKubeClientConfig(config.KubeClientConfig)
 	if err != nil {
 		return err
 	}
-	return RunOpenShiftControllerManager(config, clientConfig, ctx)
+	return RunOpenShiftControllerManager(config, settings, clientConfig, ctx)
 }
@@ -27,7 +27,7 @@ import (
 	"github.com/openshift/openshift-controller-manager/pkg/version"
 )
 
-func RunOpenShiftControllerManager(config *openshiftcontrolplanev1.OpenShiftControllerManagerConfig, clientConfig *rest.Config, ctx context.Context) error {
+func RunOpenShiftControllerManager(config *openshiftcontrolplanev1.OpenShiftControllerManagerConfig, settings origincontrollers.ControllerSettings, clientConfig *rest.Config, ctx context.Context) error {
 	serviceability.InitLogrusFromKlog()
 	kubeClient, err := kubernetes.NewForConfig(clientConfig)
 	if err != nil {
@@ -61,7 +61,7 @@ func RunOpenShiftControllerManager(config *openshiftcontrolplanev1.OpenShiftCont
 			klog.Fatal(err)
 		}
 
-		controllerContext, err := origincontrollers.NewControllerContext(c, *config, clientConfig)
+		controllerContext, err := origincontrollers.NewControllerContext(c, *config, settings, clientConfig)
 		if err != nil {
 			klog.Fatal(err)
 		}
@@ -140,6 +140,16 @@ func startControllers(controllerContext *origincontrollers.ControllerContext) er
 	for controllerName, initFn := range origincontrollers.ControllerInitializers {
 		if !controllerContext.IsControllerEnabled(string(controllerName)) {
 			klog.Warningf("%q is disabled", controllerName)
+			if disabledInitFn, ok := origincontrollers.DisabledControllerInitializers[controllerName]; ok {
+				started, err := disabledInitFn(controllerContext)
+				if err != nil {
+					klog.Fatalf("Error starting cleanup for disabled %q (%v)", controllerName, err)
+					return err
+				}
+				if started {
+					klog.Infof("Started cleanup for disabled %q", controllerName)
+				}
+			}
 			continue
 		}
 		klog.V(1).Infof("Starting %q", controllerName)
@@ -91,7 +91,7 @@ func (r tagRetriever) ImageStreamTag(namespace, name string) (ref string, rv int
 		streamCopy := is.DeepCopy()
 		// in case the api server has not yet picked up the internal registry hostname from the cluster wide
 		// OCM config, we use our copy here to facilitate leveraging pull through with local tag reference policy
-		streamCopy.Status.DockerImageRepository = fmt.Sprintf("%s/%s/%s", r.internalRegistryHostname, namespace, name)
+		streamCopy.Status.DockerImageRepository = fmt.Sprintf("%s/%s/%s", r.internalRegistryHostname, namespace, streamName)
 		ref, ok = imageutil.ResolveLatestTaggedImage(streamCopy, tag)
 	}
 	return ref, rv, ok
@@ -363,6 +363,12 @@ func (c *TriggerController) syncImageStream(key string) error {
 	// TODO: possibly filter for impossible triggers
 	for _, t := range triggered {
 		entry := t.(*trigger.CacheEntry)
+		if entry.Delay > 0 {
+			// an entry waiting in the queue keeps its earliest time, the changes until then are
+			// handled by a single reaction to the newest images
+			c.imageChangeQueue.AddAfter(entry.Key, entry.Delay)
+			continue
+		}
 		c.imageChangeQueue.Add(entry.Key)
 	}
 	return nil
@@ -22,16 +22,26 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/watch"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	clocktesting "k8s.io/utils/clock/testing"
 
 	appsv1 "github.com/openshift/api/apps/v1"
 	buildv1 "github.com/openshift/api/build/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+
 	imagev1 "github.com/openshift/api/image/v1"
+	appsfake "github.com/openshift/client-go/apps/clientset/versioned/fake"
 	v1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
 	imagev1lister "github.com/openshift/client-go/image/listers/image/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
 	"github.com/openshift/library-go/pkg/build/buildutil"
 	triggerutil "github.com/openshift/library-go/pkg/image/trigger"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
+	ocmbuildutil "github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 	"github.com/openshift/openshift-controller-manager/pkg/image/trigger"
 	"github.com/openshift/openshift-controller-manager/pkg/image/trigger/annotations"
 	"github.com/openshift/openshift-controller-manager/pkg/image/trigger/buildconfigs"
@@ -146,6 +156,7 @@ type fakeInstantiator struct {
 
 	namespace          string
 	req                *buildv1.BuildRequest
+	calls              int
 	buildConfigUpdater *fakeBuildConfigUpdater
 }
 
@@ -195,7 +206,7 @@ func (*fakeBuildConfigInterface) Watch(context.Context, metav1.ListOptions) (wat
 }
 
 func (*fakeBuildConfigInterface) Patch(context.Context, string, types.PatchType, []byte, metav1.PatchOptions, ...string) (*buildv1.BuildConfig, error) {
-	panic("implement me")
+	return &buildv1.BuildConfig{}, nil
 }
 
 func (f *fakeBuildConfigInterface) Instantiate(_ context.Context, _ string, buildRequest *buildv1.BuildRequest, _ metav1.CreateOptions) (*buildv1.Build, error) {
@@ -211,6 +222,7 @@ func (i *fakeInstantiator) Instantiate(namespace string, req *buildv1.BuildReque
 		return nil, i.err
 	}
 	i.req, i.namespace = req, namespace
+	i.calls++
 	return nil, nil
 }
 
@@ -345,7 +357,7 @@ func TestTriggerControllerSyncBuildConfigResource(t *testing.T) {
 			}
 			buildClient := fakeBuildConfigInstantiator(test.bc, test.is)
 			inst := buildClient.(*fakeInstantiator)
-			reaction := buildconfigs.NewBuildConfigReactor(inst, nil)
+			reaction := buildconfigs.NewBuildConfigReactor(inst, nil, nil)
 			controller := TriggerController{
 				triggerCache: NewTriggerCache(),
 				lister:       lister,
@@ -370,6 +382,270 @@ func TestTriggerControllerSyncBuildConfigResource(t *testing.T) {
 	}
 }
 
+func TestTriggerControllerSharedNamespaceImageStreamTag(t *testing.T) {
+	from := &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "base:latest", Namespace: "shared"}
+	apiVersionFrom := &corev1.ObjectReference{APIVersion: "image.openshift.io/v1", Kind: "ImageStreamTag", Name: "base:latest", Namespace: "shared"}
+	newBuildConfig := func(name string, from *corev1.ObjectReference) *buildv1.BuildConfig {
+		return &buildv1.BuildConfig{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "app",
+				Annotations: map[string]string{ocmbuildutil.BuildConfigImageChangeDebounceAnnotation: "0s"},
+			},
+			Spec: buildv1.BuildConfigSpec{
+				Triggers: []buildv1.BuildTriggerPolicy{{ImageChange: &buildv1.ImageChangeTrigger{From: from}}},
+			},
+		}
+	}
+	buildConfigs := map[string]*buildv1.BuildConfig{
+		"app/build1": newBuildConfig("build1", from),
+		"app/build2": newBuildConfig("build2", apiVersionFrom),
+	}
+
+	queue := &mockOperationQueue{}
+	store := &cache.FakeCustomStore{
+		GetByKeyFunc: func(key string) (interface{}, bool, error) {
+			bc, ok := buildConfigs[key]
+			return bc, ok, nil
+		},
+	}
+	inst := &fakeInstantiator{}
+	controller := TriggerController{
+		triggerCache:     NewTriggerCache(),
+		imageChangeQueue: queue,
+		triggerSources: map[string]TriggerSource{
+			"buildconfigs": {
+				Store:   store,
+				Reactor: buildconfigs.NewBuildConfigReactor(inst, nil, nil),
+			},
+		},
+		tagRetriever: fakeTagRetriever{{Namespace: "shared", Name: "base:latest", Ref: "image/base:2"}},
+	}
+	indexer := buildconfigs.NewBuildConfigTriggerIndexer("buildconfigs/")
+	for _, bc := range buildConfigs {
+		key, entry, _, err := indexer.Index(bc, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		controller.triggerCache.Add(key, entry)
+	}
+
+	// the image stream tag is updated in the shared namespace
+	if err := controller.syncImageStream("shared/base"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	queued := []string{}
+	for _, key := range queue.All() {
+		queued = append(queued, key.(string))
+	}
+	sort.Strings(queued)
+	if expected := []string{"buildconfigs/app/build1", "buildconfigs/app/build2"}; !reflect.DeepEqual(expected, queued) {
+		t.Fatalf("unexpected changes: %#v", queued)
+	}
+	for _, key := range queued {
+		inst.req = nil
+		if err := controller.syncResource(key); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if inst.namespace != "app" || inst.req == nil || inst.req.From == nil || inst.req.From.Namespace != "shared" || inst.req.TriggeredByImage.Name != "image/base:2" {
+			t.Errorf("%s: expected a build of the shared image, got %s %#v", key, inst.namespace, inst.req)
+		}
+	}
+}
+
+func TestTriggerControllerCrossNamespaceDeploymentConfig(t *testing.T) {
+	newDeploymentConfig := func(name, apiVersion string) *appsv1.DeploymentConfig {
+		return &appsv1.DeploymentConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "b"},
+			Spec: appsv1.DeploymentConfigSpec{
+				Triggers: []appsv1.DeploymentTriggerPolicy{{
+					Type: appsv1.DeploymentTriggerOnImageChange,
+					ImageChangeParams: &appsv1.DeploymentTriggerImageChangeParams{
+						Automatic:      true,
+						ContainerNames: []string{"app"},
+						From:           corev1.ObjectReference{APIVersion: apiVersion, Kind: "ImageStreamTag", Name: "base:latest", Namespace: "a"},
+					},
+				}},
+				Template: &corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "base:old"}}},
+				},
+			},
+		}
+	}
+	stream := &imagev1.ImageStream{
+		ObjectMeta: metav1.ObjectMeta{Name: "base", Namespace: "a", ResourceVersion: "2"},
+		Spec: imagev1.ImageStreamSpec{
+			Tags: []imagev1.TagReference{{Name: "latest", ReferencePolicy: imagev1.TagReferencePolicy{Type: imagev1.LocalTagReferencePolicy}}},
+		},
+		Status: imagev1.ImageStreamStatus{
+			Tags: []imagev1.NamedTagEventList{{
+				Tag:   "latest",
+				Items: []imagev1.TagEvent{{DockerImageReference: "quay.io/base@sha256:0000000000000000000000000000000000000000000000000000000000000001", Image: "sha256:0000000000000000000000000000000000000000000000000000000000000001"}},
+			}},
+		},
+	}
+	expectedImage := "image-registry.openshift-image-registry.svc:5000/a/base@sha256:0000000000000000000000000000000000000000000000000000000000000001"
+
+	tests := []struct {
+		name        string
+		allowed     bool
+		expectImage bool
+	}{
+		{name: "pull access", allowed: true, expectImage: true},
+		{name: "no pull access"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dcs := []*appsv1.DeploymentConfig{newDeploymentConfig("app1", ""), newDeploymentConfig("app2", "image.openshift.io/v1")}
+			var objects []runtime.Object
+			for _, dc := range dcs {
+				objects = append(objects, dc)
+			}
+			appsClient := appsfake.NewSimpleClientset(objects...)
+			kubeClient := &kubefake.Clientset{}
+			kubeClient.AddReactor("create", "subjectaccessreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				sar := action.(clientgotesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				if attrs := sar.Spec.ResourceAttributes; sar.Spec.User != "system:serviceaccount:b:default" || attrs.Namespace != "a" || attrs.Name != "base" || attrs.Subresource != "layers" {
+					t.Errorf("unexpected access review %#v", sar.Spec)
+				}
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: tc.allowed}}, nil
+			})
+
+			streamIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+			streamIndexer.Add(stream)
+			dcStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+			queue := &mockOperationQueue{}
+			controller := TriggerController{
+				triggerCache:     NewTriggerCache(),
+				imageChangeQueue: queue,
+				triggerSources: map[string]TriggerSource{
+					"deploymentconfigs": {
+						Store:   dcStore,
+						Reactor: &deploymentconfigs.DeploymentConfigReactor{Client: appsClient.AppsV1(), AccessReviewer: kubeClient.AuthorizationV1()},
+					},
+				},
+				tagRetriever: NewTagRetriever(imagev1lister.NewImageStreamLister(streamIndexer), "image-registry.openshift-image-registry.svc:5000"),
+			}
+			indexer := deploymentconfigs.NewDeploymentConfigTriggerIndexer("deploymentconfigs/")
+			for _, dc := range dcs {
+				dcStore.Add(dc)
+				key, entry, _, err := indexer.Index(dc, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				controller.triggerCache.Add(key, entry)
+			}
+
+			// the image stream tag is updated in namespace a
+			if err := controller.syncImageStream("a/base"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			queued := []string{}
+			for _, key := range queue.All() {
+				queued = append(queued, key.(string))
+			}
+			sort.Strings(queued)
+			if expected := []string{"deploymentconfigs/b/app1", "deploymentconfigs/b/app2"}; !reflect.DeepEqual(expected, queued) {
+				t.Fatalf("unexpected changes: %#v", queued)
+			}
+			for _, key := range queued {
+				err := controller.syncResource(key)
+				if tc.allowed != (err == nil) {
+					t.Fatalf("%s: unexpected error: %v", key, err)
+				}
+			}
+
+			for _, dc := range dcs {
+				actual, err := appsClient.AppsV1().DeploymentConfigs("b").Get(context.TODO(), dc.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if image := actual.Spec.Template.Spec.Containers[0].Image; tc.expectImage != (image == expectedImage) {
+					t.Errorf("%s: unexpected image %s", dc.Name, image)
+				}
+				condition := appsutil.GetDeploymentCondition(actual.Status, deployutil.ImageTriggerAccessDenied)
+				if tc.allowed != (condition == nil) {
+					t.Errorf("%s: unexpected image trigger access denied condition %#v", dc.Name, condition)
+				}
+				if condition != nil && (condition.Status != corev1.ConditionTrue || condition.Reason != deployutil.ImagePullAccessDeniedReason) {
+					t.Errorf("%s: unexpected image trigger access denied condition %#v", dc.Name, condition)
+				}
+			}
+		})
+	}
+}
+
+func TestTriggerControllerCoalesceImageChanges(t *testing.T) {
+	fakeClock := clocktesting.NewFakeClock(time.Now())
+	queue := workqueue.NewRateLimitingQueueWithDelayingInterface(workqueue.NewDelayingQueueWithCustomClock(fakeClock, "image-trigger-reactions"), workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+
+	bc := scenario_1_buildConfig_imageSource()
+	bc.Annotations = map[string]string{ocmbuildutil.BuildConfigImageChangeDebounceAnnotation: "10s"}
+	key, entry, _, err := buildconfigs.NewBuildConfigTriggerIndexer("buildconfigs/").Index(bc, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Delay != 10*time.Second {
+		t.Fatalf("unexpected delay: %s", entry.Delay)
+	}
+	store := &cache.FakeCustomStore{
+		GetByKeyFunc: func(key string) (interface{}, bool, error) {
+			return bc, true, nil
+		},
+	}
+	tags := &mockTagRetriever{}
+	inst := &fakeInstantiator{}
+	controller := &TriggerController{
+		triggerCache:     NewTriggerCache(),
+		imageChangeQueue: queue,
+		triggerSources: map[string]TriggerSource{
+			"buildconfigs": {
+				Store:   store,
+				Reactor: buildconfigs.NewBuildConfigReactor(inst, nil, nil),
+			},
+		},
+		tagRetriever:           tags,
+		resourceFailureDelayFn: defaultResourceFailureDelay,
+	}
+	controller.syncResourceFn = controller.syncResource
+	controller.triggerCache.Add(key, entry)
+
+	// the image is retagged five times within the debounce of the build config
+	for i := 3; i <= 7; i++ {
+		tags.tags = mockTags{
+			"other": namespaceTags{
+				"stream:2": streamTagResults{ref: fmt.Sprintf("image/result:%d", i), rv: int64(10 + i)},
+			},
+		}
+		if err := controller.syncImageStream("other/stream"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		fakeClock.Step(time.Second)
+	}
+	if queue.Len() != 0 {
+		t.Fatalf("expected the image changes to be coalesced, got %d queued", queue.Len())
+	}
+
+	fakeClock.Step(10 * time.Second)
+	for i := 0; queue.Len() == 0; i++ {
+		if i > 1000 {
+			t.Fatalf("expected the build config to be queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if queue.Len() != 1 {
+		t.Fatalf("expected the build config to be queued once, got %d", queue.Len())
+	}
+	controller.processNextResource()
+	if inst.calls != 1 {
+		t.Fatalf("expected a single build, got %d", inst.calls)
+	}
+	if inst.req.TriggeredByImage == nil || inst.req.TriggeredByImage.Name != "image/result:7" {
+		t.Errorf("expected a build of the newest image, got %#v", inst.req.TriggeredByImage)
+	}
+}
+
 func TestTriggerControllerSyncBuildConfigResourceErrorHandling(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -438,7 +714,7 @@ func TestTriggerControllerSyncBuildConfigResourceErrorHandling(t *testing.T) {
 		if test.err != nil {
 			inst.err = test.err
 		}
-		reaction := buildconfigs.NewBuildConfigReactor(inst, nil)
+		reaction := buildconfigs.NewBuildConfigReactor(inst, nil, nil)
 		controller := TriggerController{
 			triggerCache: NewTriggerCache(),
 			lister:       lister,
@@ -712,6 +988,7 @@ func scenario_1_buildConfig_strategy_cacheEntry() *trigger.CacheEntry {
 		Triggers: []triggerutil.ObjectFieldTrigger{
 			{From: triggerutil.ObjectReference{Kind: "ImageStreamTag", Name: "stream:1"}, FieldPath: "spec.strategy.*.from"},
 		},
+		Delay: buildconfigs.DefaultImageChangeDebounce,
 	}
 }
 
@@ -937,6 +1214,7 @@ func scenario_1_buildConfig_imageSource_cacheEntry() *trigger.CacheEntry {
 			{From: triggerutil.ObjectReference{Kind: "ImageStreamTag", Name: "stream:1"}, FieldPath: "spec.strategy.*.from"},
 			{From: triggerutil.ObjectReference{Kind: "ImageStreamTag", Name: "stream:2", Namespace: "other"}, FieldPath: "spec.triggers"},
 		},
+		Delay: buildconfigs.DefaultImageChangeDebounce,
 	}
 }
 
@@ -994,7 +1272,11 @@ func randomStreamTag(r *rand.Rand, maxStreams, maxTags int32) string {
 
 func benchmark_1_buildConfig(r *rand.Rand, identity, maxStreams, maxTags, triggers int32) *buildv1.BuildConfig {
 	bc := &buildv1.BuildConfig{
-		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("build-%d", identity), Namespace: "test"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("build-%d", identity),
+			Namespace:   "test",
+			Annotations: map[string]string{ocmbuildutil.BuildConfigImageChangeDebounceAnnotation: "0s"},
+		},
 		Spec: buildv1.BuildConfigSpec{
 			Triggers: []buildv1.BuildTriggerPolicy{
 				{ImageChange: &buildv1.ImageChangeTrigger{}},
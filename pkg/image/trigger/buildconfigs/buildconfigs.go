@@ -2,16 +2,22 @@ package buildconfigs
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 
 	"k8s.io/klog/v2"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	clientv1 "k8s.io/api/core/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	authorizationclientv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
@@ -19,8 +25,11 @@ import (
 	"k8s.io/kubernetes/pkg/api/legacyscheme"
 
 	buildv1 "github.com/openshift/api/build/v1"
+	imagev1 "github.com/openshift/api/image/v1"
 	buildclientv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	"github.com/openshift/library-go/pkg/authorization/authorizationutil"
 	"github.com/openshift/library-go/pkg/build/buildutil"
+	"github.com/openshift/library-go/pkg/image/imageutil"
 	triggerutil "github.com/openshift/library-go/pkg/image/trigger"
 	ocmbuildutil "github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 	"github.com/openshift/openshift-controller-manager/pkg/image/trigger"
@@ -28,6 +37,10 @@ import (
 
 const BuildTriggerCauseImageMsg = "Image change"
 
+// DefaultImageChangeDebounce is how long image change triggers coalesce the changes of their images
+// when the build config does not set its image change debounce annotation.
+const DefaultImageChangeDebounce = 5 * time.Second
+
 // calculateBuildConfigTriggers transforms a build config into a set of image change triggers.
 // It uses synthetic field paths since we don't need to generically transform the config.
 func calculateBuildConfigTriggers(bc *buildv1.BuildConfig) []triggerutil.ObjectFieldTrigger {
@@ -51,13 +64,14 @@ func calculateBuildConfigTriggers(bc *buildv1.BuildConfig) []triggerutil.ObjectF
 			continue
 		}
 
-		// add a trigger
+		// add a trigger, the image stream tags of build configs are always of the image API so the
+		// API version of the reference is not recorded: the trigger cache does not index triggers
+		// of other API versions by their image stream
 		triggers = append(triggers, triggerutil.ObjectFieldTrigger{
 			From: triggerutil.ObjectReference{
-				Name:       from.Name,
-				Namespace:  from.Namespace,
-				Kind:       from.Kind,
-				APIVersion: from.APIVersion,
+				Name:      from.Name,
+				Namespace: from.Namespace,
+				Kind:      from.Kind,
 			},
 			FieldPath: fieldPath,
 		})
@@ -65,6 +79,34 @@ func calculateBuildConfigTriggers(bc *buildv1.BuildConfig) []triggerutil.ObjectF
 	return triggers
 }
 
+// pausedImageChangeTriggers returns whether each image change trigger of the build config is paused.
+// Paused triggers stay in the trigger cache so that the images they miss are recorded, unpausing a
+// trigger has to be handled as a change of the triggers.
+func pausedImageChangeTriggers(bc *buildv1.BuildConfig) []bool {
+	var paused []bool
+	for _, t := range bc.Spec.Triggers {
+		if t.ImageChange != nil {
+			paused = append(paused, t.ImageChange.Paused)
+		}
+	}
+	return paused
+}
+
+// imageChangeDebounce returns how long the changes of the images of the image change triggers of
+// the build config are coalesced into a single build.
+func imageChangeDebounce(bc *buildv1.BuildConfig) time.Duration {
+	value, ok := bc.Annotations[ocmbuildutil.BuildConfigImageChangeDebounceAnnotation]
+	if !ok {
+		return DefaultImageChangeDebounce
+	}
+	debounce, err := time.ParseDuration(value)
+	if err != nil || debounce < 0 {
+		klog.V(4).Infof("Ignoring invalid image change debounce %q of BuildConfig %s/%s", value, bc.Namespace, bc.Name)
+		return DefaultImageChangeDebounce
+	}
+	return debounce
+}
+
 // buildConfigTriggerIndexer converts build config events into entries for the trigger cache, and
 // also calculates the latest state of the changes on the object.
 type buildConfigTriggerIndexer struct {
@@ -109,6 +151,10 @@ func (i buildConfigTriggerIndexer) Index(obj, old interface{}) (string, *trigger
 			change = cache.Added
 		case !reflect.DeepEqual(oldTriggers, triggers):
 			change = cache.Updated
+		case !reflect.DeepEqual(pausedImageChangeTriggers(old.(*buildv1.BuildConfig)), pausedImageChangeTriggers(bc)):
+			change = cache.Updated
+		case imageChangeDebounce(old.(*buildv1.BuildConfig)) != imageChangeDebounce(bc):
+			change = cache.Updated
 		}
 	}
 
@@ -118,6 +164,7 @@ func (i buildConfigTriggerIndexer) Index(obj, old interface{}) (string, *trigger
 			Key:       key,
 			Namespace: bc.Namespace,
 			Triggers:  triggers,
+			Delay:     imageChangeDebounce(bc),
 		}, change, nil
 	}
 	return "", nil, change, nil
@@ -134,15 +181,18 @@ type BuildConfigInstantiator interface {
 type buildConfigReactor struct {
 	instantiator  buildclientv1.BuildConfigsGetter
 	eventRecorder record.EventRecorder
+	// accessReviewer checks that the build service account can pull the image stream tags of
+	// other namespaces that trigger builds. No access is checked when it is nil.
+	accessReviewer authorizationclientv1.SubjectAccessReviewsGetter
 }
 
 // NewBuildConfigReactor creates a new buildConfigReactor
-func NewBuildConfigReactor(instantiator buildclientv1.BuildConfigsGetter, restclient rest.Interface) trigger.ImageReactor {
+func NewBuildConfigReactor(instantiator buildclientv1.BuildConfigsGetter, accessReviewer authorizationclientv1.SubjectAccessReviewsGetter, restclient rest.Interface) trigger.ImageReactor {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartRecordingToSink(&v1core.EventSinkImpl{Interface: v1core.New(restclient).Events("")})
 	eventRecorder := eventBroadcaster.NewRecorder(legacyscheme.Scheme, clientv1.EventSource{Component: "buildconfig-controller"})
 
-	return &buildConfigReactor{instantiator: instantiator, eventRecorder: eventRecorder}
+	return &buildConfigReactor{instantiator: instantiator, eventRecorder: eventRecorder, accessReviewer: accessReviewer}
 }
 
 // ImageChanged is passed a build config and a set of changes and updates the object if
@@ -152,15 +202,12 @@ func (r *buildConfigReactor) ImageChanged(obj runtime.Object, tagRetriever trigg
 
 	var request *buildv1.BuildRequest
 	var fired map[corev1.ObjectReference]string
+	pending := map[string]string{}
 	for _, t := range bc.Spec.Triggers {
 		p := t.ImageChange
 		if p == nil || (p.From != nil && p.From.Kind != "ImageStreamTag") {
 			continue
 		}
-		if p.Paused {
-			klog.V(5).Infof("Skipping paused build on bc: %s/%s for trigger: %+v", bc.Namespace, bc.Name, t)
-			continue
-		}
 		var from *corev1.ObjectReference
 		if p.From != nil {
 			from = p.From
@@ -172,6 +219,16 @@ func (r *buildConfigReactor) ImageChanged(obj runtime.Object, tagRetriever trigg
 			namespace = bc.Namespace
 		}
 
+		if p.Paused {
+			// record the image the trigger missed, it fires once for the newest image when unpaused
+			klog.V(5).Infof("Skipping paused build on bc: %s/%s for trigger: %+v", bc.Namespace, bc.Name, t)
+			latest, _, found := tagRetriever.ImageStreamTag(namespace, from.Name)
+			if found && !lastTriggeredImage(bc, p, latest) {
+				pending[namespace+"/"+from.Name] = latest
+			}
+			continue
+		}
+
 		// lookup the source if we haven't already retrieved it
 		var newSource bool
 		latest, found := fired[*from]
@@ -186,15 +243,7 @@ func (r *buildConfigReactor) ImageChanged(obj runtime.Object, tagRetriever trigg
 		// Determine whether this trigger has fired previously and is recorded in status;
 		// openshift-apiserver updates the status of image change triggers,
 		// including resolving LastTriggeredImageID and LastTriggerTime.
-		pStatus := ocmbuildutil.GetImageChangeTriggerStatusForImageChangeTrigger(p, bc)
-		if pStatus != nil && latest == pStatus.LastTriggeredImageID {
-			// LastTriggeredImageID is an image ref, despite the name
-			continue
-		}
-		// We still need to check the old spec field as well, in case the build config in question was last
-		// triggered while the cluster was at a level prior to 4.8.
-		// LastTriggeredImageID is an image ref, despite the name
-		if latest == p.LastTriggeredImageID {
+		if lastTriggeredImage(bc, p, latest) {
 			continue
 		}
 
@@ -233,17 +282,151 @@ func (r *buildConfigReactor) ImageChanged(obj runtime.Object, tagRetriever trigg
 		}
 	}
 
-	if request == nil {
+	if request != nil {
+		if failure, err := r.checkPullAccess(bc, fired); err != nil || failure != nil {
+			if failure != nil {
+				err = fmt.Errorf("error triggering Build for BuildConfig %s/%s: %s", bc.Namespace, bc.Name, failure.Message)
+				r.eventRecorder.Event(bc, corev1.EventTypeWarning, "BuildConfigTriggerFailed", err.Error())
+				r.recordTriggerFailure(bc, failure)
+			}
+			return err
+		}
+
+		// instantiate new build
+		klog.V(4).Infof("Requesting build for BuildConfig based on image triggers %s/%s: %#v", bc.Namespace, bc.Name, request)
+		_, err := r.instantiator.BuildConfigs(bc.Namespace).Instantiate(context.TODO(), bc.Namespace, request, metav1.CreateOptions{})
+		if err != nil {
+			instantiateErr := fmt.Errorf("error triggering Build for BuildConfig %s/%s: %v", bc.Namespace, bc.Name, err)
+			utilruntime.HandleError(instantiateErr)
+			r.eventRecorder.Event(bc, corev1.EventTypeWarning, "BuildConfigTriggerFailed", instantiateErr.Error())
+			r.recordTriggerFailure(bc, ocmbuildutil.NewTriggerFailure(buildv1.ImageChangeBuildTriggerType, err))
+			return err
+		}
+	}
+	if err := r.updatePendingImageChanges(bc, pending); err != nil {
+		return err
+	}
+	if request != nil {
+		r.recordTriggerFailure(bc, nil)
+	}
+	return nil
+}
+
+// checkPullAccess checks that the build service account of the build config is allowed to pull the
+// image stream tags of other namespaces that fired its image change triggers, which the build pulls
+// with the credentials of the service account. It returns the trigger failure of the first image
+// stream tag the service account is not allowed to pull, or nil when it can pull them all.
+func (r *buildConfigReactor) checkPullAccess(bc *buildv1.BuildConfig, fired map[corev1.ObjectReference]string) (*ocmbuildutil.TriggerFailure, error) {
+	if r.accessReviewer == nil {
+		return nil, nil
+	}
+	serviceAccountName := bc.Spec.ServiceAccount
+	if len(serviceAccountName) == 0 {
+		serviceAccountName = ocmbuildutil.BuilderServiceAccountName
+	}
+	user := serviceaccount.UserInfo(bc.Namespace, serviceAccountName, "")
+
+	for from := range fired {
+		if len(from.Namespace) == 0 || from.Namespace == bc.Namespace {
+			continue
+		}
+		stream, _, ok := imageutil.SplitImageStreamTag(from.Name)
+		if !ok {
+			continue
+		}
+		sar := authorizationutil.AddUserToSAR(user, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   from.Namespace,
+					Verb:        "get",
+					Group:       imagev1.GroupName,
+					Resource:    "imagestreams",
+					Subresource: "layers",
+					Name:        stream,
+				},
+			},
+		})
+		resp, err := r.accessReviewer.SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("unable to check the access of service account %s/%s to image stream %s/%s: %v", bc.Namespace, serviceAccountName, from.Namespace, stream, err)
+		}
+		if resp.Status.Allowed {
+			continue
+		}
+		klog.V(4).Infof("Service account %s/%s of BuildConfig %s/%s has no pull access to image stream %s/%s: %s", bc.Namespace, serviceAccountName, bc.Namespace, bc.Name, from.Namespace, stream, resp.Status.Reason)
+		return &ocmbuildutil.TriggerFailure{
+			Time:        metav1.Now(),
+			Reason:      ocmbuildutil.TriggerFailureReasonImagePullAccessDenied,
+			Message:     fmt.Sprintf("The service account %s is not allowed to pull image stream tag %s/%s.", serviceAccountName, from.Namespace, from.Name),
+			TriggerType: buildv1.ImageChangeBuildTriggerType,
+		}, nil
+	}
+	return nil, nil
+}
+
+// recordTriggerFailure records the failure of an image change trigger of the build config to
+// instantiate a build in its last trigger failure annotation, or removes the annotation of an
+// earlier failure when failure is nil. The annotation is patched, the instantiation of a build
+// updates the build config.
+func (r *buildConfigReactor) recordTriggerFailure(bc *buildv1.BuildConfig, failure *ocmbuildutil.TriggerFailure) {
+	if _, ok := bc.Annotations[ocmbuildutil.BuildConfigLastTriggerFailureAnnotation]; !ok && failure == nil {
+		return
+	}
+	patch, err := ocmbuildutil.TriggerFailurePatch(failure)
+	if err == nil {
+		_, err = r.instantiator.BuildConfigs(bc.Namespace).Patch(context.TODO(), bc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error recording the last trigger failure of BuildConfig %s/%s: %v", bc.Namespace, bc.Name, err))
+	}
+}
+
+// lastTriggeredImage returns true if the trigger has already fired for the image.
+func lastTriggeredImage(bc *buildv1.BuildConfig, p *buildv1.ImageChangeTrigger, latest string) bool {
+	// Determine whether this trigger has fired previously and is recorded in status;
+	// openshift-apiserver updates the status of image change triggers,
+	// including resolving LastTriggeredImageID and LastTriggerTime.
+	pStatus := ocmbuildutil.GetImageChangeTriggerStatusForImageChangeTrigger(p, bc)
+	if pStatus != nil && latest == pStatus.LastTriggeredImageID {
+		// LastTriggeredImageID is an image ref, despite the name
+		return true
+	}
+	// We still need to check the old spec field as well, in case the build config in question was last
+	// triggered while the cluster was at a level prior to 4.8.
+	// LastTriggeredImageID is an image ref, despite the name
+	return latest == p.LastTriggeredImageID
+}
+
+// updatePendingImageChanges records the images missed by the paused image change triggers of the
+// build config in its pending image changes annotation, and removes the annotation once no trigger
+// has a pending image. Only the annotation is patched, so the cached build config is not written
+// back over newer changes.
+func (r *buildConfigReactor) updatePendingImageChanges(bc *buildv1.BuildConfig, pending map[string]string) error {
+	current, ok := bc.Annotations[ocmbuildutil.BuildConfigPendingImageChangesAnnotation]
+	var value *string
+	if len(pending) > 0 {
+		data, err := json.Marshal(pending)
+		if err != nil {
+			return err
+		}
+		v := string(data)
+		value = &v
+	}
+	if (value == nil && !ok) || (value != nil && ok && current == *value) {
 		return nil
 	}
 
-	// instantiate new build
-	klog.V(4).Infof("Requesting build for BuildConfig based on image triggers %s/%s: %#v", bc.Namespace, bc.Name, request)
-	_, err := r.instantiator.BuildConfigs(bc.Namespace).Instantiate(context.TODO(), bc.Namespace, request, metav1.CreateOptions{})
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]*string{ocmbuildutil.BuildConfigPendingImageChangesAnnotation: value},
+		},
+	})
 	if err != nil {
-		instantiateErr := fmt.Errorf("error triggering Build for BuildConfig %s/%s: %v", bc.Namespace, bc.Name, err)
-		utilruntime.HandleError(instantiateErr)
-		r.eventRecorder.Event(bc, corev1.EventTypeWarning, "BuildConfigTriggerFailed", instantiateErr.Error())
+		return err
+	}
+	klog.V(4).Infof("Recording pending image changes of BuildConfig %s/%s: %s", bc.Namespace, bc.Name, patch)
+	if _, err := r.instantiator.BuildConfigs(bc.Namespace).Patch(context.TODO(), bc.Name, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("error recording pending image changes of BuildConfig %s/%s: %v", bc.Namespace, bc.Name, err)
 	}
-	return err
+	return nil
 }
@@ -2,20 +2,32 @@ package buildconfigs
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/diff"
 	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	clientgotesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	kapihelper "k8s.io/kubernetes/pkg/apis/core/helper"
 
 	buildv1 "github.com/openshift/api/build/v1"
 	buildapply "github.com/openshift/client-go/build/applyconfigurations/build/v1"
 	buildclientv1 "github.com/openshift/client-go/build/clientset/versioned/typed/build/v1"
+	ocmbuildutil "github.com/openshift/openshift-controller-manager/pkg/build/buildutil"
 )
 
 type fakeTagResponse struct {
@@ -78,6 +90,7 @@ type instantiator struct {
 	request *buildv1.BuildRequest
 	build   *buildv1.Build
 	err     error
+	patches [][]byte
 }
 
 func (i *instantiator) Instantiate(namespace string, request *buildv1.BuildRequest) (*buildv1.Build, error) {
@@ -131,8 +144,9 @@ func (*fakeBuildConfigInterface) Watch(context.Context, metav1.ListOptions) (wat
 	panic("implement me")
 }
 
-func (*fakeBuildConfigInterface) Patch(context.Context, string, types.PatchType, []byte, metav1.PatchOptions, ...string) (*buildv1.BuildConfig, error) {
-	panic("implement me")
+func (f *fakeBuildConfigInterface) Patch(_ context.Context, _ string, _ types.PatchType, data []byte, _ metav1.PatchOptions, _ ...string) (*buildv1.BuildConfig, error) {
+	f.inst.patches = append(f.inst.patches, data)
+	return &buildv1.BuildConfig{}, nil
 }
 
 func (f *fakeBuildConfigInterface) Instantiate(_ context.Context, _ string, buildRequest *buildv1.BuildRequest, _ metav1.CreateOptions) (*buildv1.Build, error) {
@@ -376,3 +390,276 @@ func TestBuildConfigReactor(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildConfigReactorPausedTriggers(t *testing.T) {
+	from := &corev1.ObjectReference{Name: "stream-1:1", Namespace: "other", Kind: "ImageStreamTag"}
+	bc := testBuildConfig([]buildv1.ImageChangeTrigger{
+		{
+			From:                 from,
+			LastTriggeredImageID: "image-lookup-1",
+			Paused:               true,
+		},
+	})
+
+	imageChanged := func(bc *buildv1.BuildConfig, ref string) *instantiator {
+		t.Helper()
+		instantiator := &instantiator{build: &buildv1.Build{}}
+		r := buildConfigReactor{instantiator: instantiator}
+		tags := fakeTagRetriever{{Namespace: "other", Name: "stream-1:1", Ref: ref, RV: 2}}
+		if err := r.ImageChanged(bc, tags); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// apply the patches of the annotations to the build config
+		for _, data := range instantiator.patches {
+			var patch struct {
+				Metadata struct {
+					Annotations map[string]*string `json:"annotations"`
+				} `json:"metadata"`
+			}
+			if err := json.Unmarshal(data, &patch); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for k, v := range patch.Metadata.Annotations {
+				if v == nil {
+					delete(bc.Annotations, k)
+					continue
+				}
+				if bc.Annotations == nil {
+					bc.Annotations = map[string]string{}
+				}
+				bc.Annotations[k] = *v
+			}
+		}
+		return instantiator
+	}
+	expectPending := func(bc *buildv1.BuildConfig, expected string) {
+		t.Helper()
+		if actual := bc.Annotations[ocmbuildutil.BuildConfigPendingImageChangesAnnotation]; actual != expected {
+			t.Errorf("expected pending image changes %q, got %q", expected, actual)
+		}
+	}
+
+	// no image change while paused
+	if inst := imageChanged(bc, "image-lookup-1"); inst.request != nil || len(inst.patches) > 0 {
+		t.Errorf("expected no build and no patch without an image change, got %v and %q", inst.request, inst.patches)
+	}
+
+	// every update while paused records the newest image without starting builds
+	for _, ref := range []string{"image-lookup-2", "image-lookup-3"} {
+		if inst := imageChanged(bc, ref); inst.request != nil {
+			t.Errorf("expected no build while paused, got %v", inst.request)
+		}
+		expectPending(bc, `{"other/stream-1:1":"`+ref+`"}`)
+	}
+	if inst := imageChanged(bc, "image-lookup-3"); len(inst.patches) > 0 {
+		t.Errorf("expected no patch of unchanged pending image changes, got %q", inst.patches)
+	}
+
+	// unpausing fires a single build for the newest image and clears the pending image
+	indexer := NewBuildConfigTriggerIndexer("buildconfigs/")
+	unpaused := bc.DeepCopy()
+	unpaused.Spec.Triggers[0].ImageChange.Paused = false
+	if _, entry, change, err := indexer.Index(unpaused, bc); err != nil || entry == nil || change != cache.Updated {
+		t.Errorf("expected unpausing to update the triggers, got %v %v %v", entry, change, err)
+	}
+	inst := imageChanged(unpaused, "image-lookup-3")
+	expected := testBuildRequest(from, "image-lookup-3", map[string]string{"stream-1:1": "image-lookup-3"})
+	if !reflect.DeepEqual(expected, inst.request) {
+		t.Errorf("not equal: %s", diff.ObjectReflectDiff(expected, inst.request))
+	}
+	if len(inst.patches) != 1 {
+		t.Fatalf("expected the pending image changes to be cleared, got %q", inst.patches)
+	}
+	if _, ok := unpaused.Annotations[ocmbuildutil.BuildConfigPendingImageChangesAnnotation]; ok {
+		t.Errorf("expected the pending image changes to be cleared, got %v", unpaused.Annotations)
+	}
+}
+
+func TestBuildConfigReactorTriggerFailure(t *testing.T) {
+	from := &corev1.ObjectReference{Name: "stream-1:1", Namespace: "other", Kind: "ImageStreamTag"}
+	bc := testBuildConfig([]buildv1.ImageChangeTrigger{{From: from, LastTriggeredImageID: "image-lookup-1"}})
+	tags := fakeTagRetriever{{Namespace: "other", Name: "stream-1:1", Ref: "image-lookup-2", RV: 2}}
+	lastTriggerFailure := func(patch []byte) *string {
+		t.Helper()
+		var p struct {
+			Metadata struct {
+				Annotations map[string]*string `json:"annotations"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(patch, &p); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		value, ok := p.Metadata.Annotations[ocmbuildutil.BuildConfigLastTriggerFailureAnnotation]
+		if !ok {
+			t.Fatalf("expected the patch to set the last trigger failure, got %s", patch)
+		}
+		return value
+	}
+
+	// the build is denied by a resource quota
+	inst := &instantiator{err: kerrors.NewForbidden(buildv1.Resource("builds"), "test-1", fmt.Errorf("exceeded quota: builds, requested: count/builds.build.openshift.io=1"))}
+	recorder := record.NewFakeRecorder(10)
+	r := buildConfigReactor{instantiator: inst, eventRecorder: recorder}
+	if err := r.ImageChanged(bc, tags); err == nil {
+		t.Fatalf("expected an instantiation error")
+	}
+	if len(inst.patches) != 1 {
+		t.Fatalf("expected the trigger failure to be recorded, got %d patches", len(inst.patches))
+	}
+	value := lastTriggerFailure(inst.patches[0])
+	if value == nil {
+		t.Fatalf("expected the last trigger failure to be set")
+	}
+	var failure ocmbuildutil.TriggerFailure
+	if err := json.Unmarshal([]byte(*value), &failure); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if failure.Reason != "QuotaExceeded" || failure.TriggerType != buildv1.ImageChangeBuildTriggerType || failure.Time.IsZero() || !strings.Contains(failure.Message, "exceeded quota") {
+		t.Errorf("unexpected trigger failure %#v", failure)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, "Warning BuildConfigTriggerFailed") {
+			t.Errorf("unexpected event %s", event)
+		}
+	default:
+		t.Errorf("expected a warning event on the build config")
+	}
+
+	// the next build is instantiated
+	bc.Annotations = map[string]string{ocmbuildutil.BuildConfigLastTriggerFailureAnnotation: *value}
+	inst = &instantiator{build: &buildv1.Build{}}
+	r = buildConfigReactor{instantiator: inst, eventRecorder: recorder}
+	if err := r.ImageChanged(bc, tags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inst.patches) != 1 || lastTriggerFailure(inst.patches[0]) != nil {
+		t.Errorf("expected the last trigger failure to be cleared, got %q", inst.patches)
+	}
+}
+
+func TestBuildConfigTriggerIndexerImageChangeDebounce(t *testing.T) {
+	newBuildConfig := func(debounce string) *buildv1.BuildConfig {
+		bc := &buildv1.BuildConfig{
+			ObjectMeta: metav1.ObjectMeta{Name: "build1", Namespace: "test"},
+			Spec: buildv1.BuildConfigSpec{
+				Triggers: []buildv1.BuildTriggerPolicy{
+					{ImageChange: &buildv1.ImageChangeTrigger{From: &corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream:1"}}},
+				},
+			},
+		}
+		if len(debounce) > 0 {
+			bc.Annotations = map[string]string{ocmbuildutil.BuildConfigImageChangeDebounceAnnotation: debounce}
+		}
+		return bc
+	}
+	tests := []struct {
+		name     string
+		debounce string
+		expected time.Duration
+	}{
+		{name: "default", expected: DefaultImageChangeDebounce},
+		{name: "annotation", debounce: "1m", expected: time.Minute},
+		{name: "disabled", debounce: "0s", expected: 0},
+		{name: "invalid", debounce: "soon", expected: DefaultImageChangeDebounce},
+		{name: "negative", debounce: "-1s", expected: DefaultImageChangeDebounce},
+	}
+	indexer := NewBuildConfigTriggerIndexer("buildconfigs/")
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, entry, _, err := indexer.Index(newBuildConfig(test.debounce), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if entry.Delay != test.expected {
+				t.Errorf("expected delay %s, got %s", test.expected, entry.Delay)
+			}
+		})
+	}
+
+	// changing the debounce updates the entry of the build config
+	_, entry, change, err := indexer.Index(newBuildConfig("30s"), newBuildConfig(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if change != cache.Updated || entry.Delay != 30*time.Second {
+		t.Errorf("expected the entry to be updated with the new delay, got %q %s", change, entry.Delay)
+	}
+}
+
+func TestBuildConfigReactorPullAccess(t *testing.T) {
+	tests := []struct {
+		name           string
+		from           *corev1.ObjectReference
+		allowed        bool
+		expectedReview bool
+		expectedBuild  bool
+	}{
+		{
+			name:           "shared namespace allowed",
+			from:           &corev1.ObjectReference{Name: "base:latest", Namespace: "shared", Kind: "ImageStreamTag"},
+			allowed:        true,
+			expectedReview: true,
+			expectedBuild:  true,
+		},
+		{
+			name:           "shared namespace denied",
+			from:           &corev1.ObjectReference{Name: "base:latest", Namespace: "shared", Kind: "ImageStreamTag"},
+			expectedReview: true,
+		},
+		{
+			name:          "same namespace",
+			from:          &corev1.ObjectReference{Name: "base:latest", Kind: "ImageStreamTag"},
+			expectedBuild: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bc := testBuildConfig([]buildv1.ImageChangeTrigger{{From: test.from, LastTriggeredImageID: "image-lookup-1"}})
+			bc.Spec.ServiceAccount = "pusher"
+			namespace := test.from.Namespace
+			if len(namespace) == 0 {
+				namespace = bc.Namespace
+			}
+			tags := fakeTagRetriever{{Namespace: namespace, Name: "base:latest", Ref: "image-lookup-2", RV: 2}}
+
+			var review *authorizationv1.SubjectAccessReview
+			kubeClient := fake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "subjectaccessreviews", func(action clientgotesting.Action) (bool, runtime.Object, error) {
+				review = action.(clientgotesting.CreateAction).GetObject().(*authorizationv1.SubjectAccessReview)
+				return true, &authorizationv1.SubjectAccessReview{Status: authorizationv1.SubjectAccessReviewStatus{Allowed: test.allowed}}, nil
+			})
+			inst := &instantiator{build: &buildv1.Build{}}
+			r := buildConfigReactor{instantiator: inst, eventRecorder: record.NewFakeRecorder(10), accessReviewer: kubeClient.AuthorizationV1()}
+			err := r.ImageChanged(bc, tags)
+
+			if (review != nil) != test.expectedReview {
+				t.Fatalf("expected an access review: %v, got %#v", test.expectedReview, review)
+			}
+			if review != nil {
+				attrs := review.Spec.ResourceAttributes
+				if review.Spec.User != "system:serviceaccount:default:pusher" || attrs.Namespace != "shared" || attrs.Name != "base" || attrs.Resource != "imagestreams" || attrs.Subresource != "layers" || attrs.Verb != "get" {
+					t.Errorf("unexpected access review %#v", review.Spec)
+				}
+			}
+			if test.expectedBuild {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if inst.request == nil || inst.request.TriggeredByImage.Name != "image-lookup-2" {
+					t.Errorf("expected a build of the new image, got %#v", inst.request)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error")
+			}
+			if inst.request != nil {
+				t.Errorf("expected no build, got %#v", inst.request)
+			}
+			if len(inst.patches) != 1 || !strings.Contains(string(inst.patches[0]), ocmbuildutil.TriggerFailureReasonImagePullAccessDenied) {
+				t.Errorf("expected the trigger failure to be recorded, got %q", inst.patches)
+			}
+		})
+	}
+}
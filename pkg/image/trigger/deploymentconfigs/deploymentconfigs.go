@@ -8,14 +8,24 @@ import (
 
 	"k8s.io/klog/v2"
 
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	authorizationclientv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"k8s.io/client-go/tools/cache"
 
 	appsv1 "github.com/openshift/api/apps/v1"
+	imagev1 "github.com/openshift/api/image/v1"
 	appsclient "github.com/openshift/client-go/apps/clientset/versioned/typed/apps/v1"
+	"github.com/openshift/library-go/pkg/apps/appsutil"
+	"github.com/openshift/library-go/pkg/authorization/authorizationutil"
+	"github.com/openshift/library-go/pkg/image/imageutil"
 	triggerutil "github.com/openshift/library-go/pkg/image/trigger"
+	"github.com/openshift/openshift-controller-manager/pkg/apps/deployutil"
 	"github.com/openshift/openshift-controller-manager/pkg/image/trigger"
 )
 
@@ -40,6 +50,22 @@ func namesInclude(names []string, name string) bool {
 	return false
 }
 
+// imageStreamTagReference returns the trigger reference to the image stream tag of an image change
+// trigger. The API version is dropped for the image API, the trigger cache indexes the image stream
+// tags of the triggers only when they have no API version.
+func imageStreamTagReference(from corev1.ObjectReference) triggerutil.ObjectReference {
+	ref := triggerutil.ObjectReference{
+		Name:       from.Name,
+		Namespace:  from.Namespace,
+		Kind:       from.Kind,
+		APIVersion: from.APIVersion,
+	}
+	if gv, err := schema.ParseGroupVersion(from.APIVersion); err == nil && (gv.Group == imagev1.GroupName || len(gv.Group) == 0) {
+		ref.APIVersion = ""
+	}
+	return ref
+}
+
 // calculateDeploymentConfigTrigger resolves a particular trigger against the deployment config and extracts a list of triggers.
 // It will silently ignore triggers that do not point to valid container. It returns empty if no triggers can be found.
 func calculateDeploymentConfigTrigger(t appsv1.DeploymentTriggerPolicy, dc *appsv1.DeploymentConfig) []triggerutil.ObjectFieldTrigger {
@@ -57,12 +83,7 @@ func calculateDeploymentConfigTrigger(t appsv1.DeploymentTriggerPolicy, dc *apps
 	for _, index := range indicesForContainerNames(dc.Spec.Template.Spec.Containers, t.ImageChangeParams.ContainerNames) {
 		fieldPath := fmt.Sprintf("spec.template.spec.containers[@name==\"%s\"].image", dc.Spec.Template.Spec.Containers[index].Name)
 		triggers = append(triggers, triggerutil.ObjectFieldTrigger{
-			From: triggerutil.ObjectReference{
-				Name:       from.Name,
-				Namespace:  from.Namespace,
-				Kind:       from.Kind,
-				APIVersion: from.APIVersion,
-			},
+			From:      imageStreamTagReference(from),
 			FieldPath: fieldPath,
 			Paused:    !t.ImageChangeParams.Automatic,
 		})
@@ -70,12 +91,7 @@ func calculateDeploymentConfigTrigger(t appsv1.DeploymentTriggerPolicy, dc *apps
 	for _, index := range indicesForContainerNames(dc.Spec.Template.Spec.InitContainers, t.ImageChangeParams.ContainerNames) {
 		fieldPath := fmt.Sprintf("spec.template.spec.initContainers[@name==\"%s\"].image", dc.Spec.Template.Spec.InitContainers[index].Name)
 		triggers = append(triggers, triggerutil.ObjectFieldTrigger{
-			From: triggerutil.ObjectReference{
-				Name:       from.Name,
-				Namespace:  from.Namespace,
-				Kind:       from.Kind,
-				APIVersion: from.APIVersion,
-			},
+			From:      imageStreamTagReference(from),
 			FieldPath: fieldPath,
 			Paused:    !t.ImageChangeParams.Automatic,
 		})
@@ -143,6 +159,10 @@ func (i deploymentConfigTriggerIndexer) Index(obj, old interface{}) (string, *tr
 		// and we need to make sure those changes get reconciled by re-resolving images
 		case !reflect.DeepEqual(dc.Spec.Template.Spec.Containers, oldDC.Spec.Template.Spec.Containers):
 			change = cache.Updated
+		// Resuming a paused deployment config re-resolves its triggers, so that it rolls out the
+		// images the image stream tags point to now.
+		case oldDC.Spec.Paused && !dc.Spec.Paused:
+			change = cache.Updated
 		}
 	}
 
@@ -160,12 +180,20 @@ func (i deploymentConfigTriggerIndexer) Index(obj, old interface{}) (string, *tr
 // DeploymentConfigReactor converts image trigger changes into updates on deployments.
 type DeploymentConfigReactor struct {
 	Client appsclient.DeploymentConfigsGetter
+	// AccessReviewer checks that the pod service account of deployment configs can pull the image
+	// stream tags of other namespaces their image change triggers reference. No access is checked
+	// when it is nil.
+	AccessReviewer authorizationclientv1.SubjectAccessReviewsGetter
 }
 
 // UpdateDeploymentConfigImages sets the latest image value from all triggers onto each container, returning false if
 // one or more triggers could not be resolved yet or an error. The returned dc will be copied if mutated.
+// Each trigger records its own last triggered image and only sets the images of the containers it
+// names. A container named by several triggers follows the first of them, so that the triggers do
+// not rewrite each other's image and fire again.
 func UpdateDeploymentConfigImages(dc *appsv1.DeploymentConfig, tagRetriever triggerutil.TagRetriever) (*appsv1.DeploymentConfig, bool, error) {
 	var updated *appsv1.DeploymentConfig
+	triggeredInitContainers, triggeredContainers := sets.NewString(), sets.NewString()
 
 	// copy the object and reset dc to the copy
 	copyObject := func() {
@@ -207,7 +235,11 @@ func UpdateDeploymentConfigImages(dc *appsv1.DeploymentConfig, tagRetriever trig
 		}
 
 		for i, c := range dc.Spec.Template.Spec.InitContainers {
-			if !namesInclude(p.ContainerNames, c.Name) || c.Image == ref {
+			if !namesInclude(p.ContainerNames, c.Name) || triggeredInitContainers.Has(c.Name) {
+				continue
+			}
+			triggeredInitContainers.Insert(c.Name)
+			if c.Image == ref {
 				continue
 			}
 			copyObject()
@@ -216,7 +248,11 @@ func UpdateDeploymentConfigImages(dc *appsv1.DeploymentConfig, tagRetriever trig
 		}
 
 		for i, c := range dc.Spec.Template.Spec.Containers {
-			if !namesInclude(p.ContainerNames, c.Name) || c.Image == ref {
+			if !namesInclude(p.ContainerNames, c.Name) || triggeredContainers.Has(c.Name) {
+				continue
+			}
+			triggeredContainers.Insert(c.Name)
+			if c.Image == ref {
 				continue
 			}
 			copyObject()
@@ -230,6 +266,19 @@ func UpdateDeploymentConfigImages(dc *appsv1.DeploymentConfig, tagRetriever trig
 // ImageChanged is passed a deployment config and a set of changes.
 func (r *DeploymentConfigReactor) ImageChanged(obj runtime.Object, tagRetriever triggerutil.TagRetriever) error {
 	dc := obj.(*appsv1.DeploymentConfig)
+
+	denied, err := r.checkPullAccess(dc)
+	if err != nil {
+		return err
+	}
+	dc, err = r.updateAccessDeniedCondition(dc, denied)
+	if err != nil {
+		return err
+	}
+	if len(denied) > 0 {
+		return fmt.Errorf("unable to update the images of deployment config %s/%s: %s", dc.Namespace, dc.Name, denied)
+	}
+
 	newDC := dc.DeepCopy()
 
 	updated, resolvable, err := UpdateDeploymentConfigImages(newDC, tagRetriever)
@@ -251,6 +300,94 @@ func (r *DeploymentConfigReactor) ImageChanged(obj runtime.Object, tagRetriever
 	return err
 }
 
+// checkPullAccess checks that the pod service account of the deployment config is allowed to pull
+// the image stream tags of other namespaces referenced by its automatic image change triggers, which
+// its pods pull with the credentials of the service account. It returns the message explaining the
+// first image stream tag the service account is not allowed to pull, or an empty message when it
+// can pull them all.
+func (r *DeploymentConfigReactor) checkPullAccess(dc *appsv1.DeploymentConfig) (string, error) {
+	if r.AccessReviewer == nil {
+		return "", nil
+	}
+	serviceAccountName := "default"
+	if dc.Spec.Template != nil && len(dc.Spec.Template.Spec.ServiceAccountName) > 0 {
+		serviceAccountName = dc.Spec.Template.Spec.ServiceAccountName
+	}
+	user := serviceaccount.UserInfo(dc.Namespace, serviceAccountName, "")
+
+	for _, t := range dc.Spec.Triggers {
+		p := t.ImageChangeParams
+		if p == nil || !p.Automatic || p.From.Kind != "ImageStreamTag" || len(p.From.Namespace) == 0 || p.From.Namespace == dc.Namespace {
+			continue
+		}
+		stream, _, ok := imageutil.SplitImageStreamTag(p.From.Name)
+		if !ok {
+			continue
+		}
+		sar := authorizationutil.AddUserToSAR(user, &authorizationv1.SubjectAccessReview{
+			Spec: authorizationv1.SubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   p.From.Namespace,
+					Verb:        "get",
+					Group:       imagev1.GroupName,
+					Resource:    "imagestreams",
+					Subresource: "layers",
+					Name:        stream,
+				},
+			},
+		})
+		resp, err := r.AccessReviewer.SubjectAccessReviews().Create(context.TODO(), sar, metav1.CreateOptions{})
+		if err != nil {
+			return "", fmt.Errorf("unable to check the access of service account %s/%s to image stream %s/%s: %v", dc.Namespace, serviceAccountName, p.From.Namespace, stream, err)
+		}
+		if resp.Status.Allowed {
+			continue
+		}
+		klog.V(4).Infof("Service account %s/%s of deployment config %s/%s has no pull access to image stream %s/%s: %s", dc.Namespace, serviceAccountName, dc.Namespace, dc.Name, p.From.Namespace, stream, resp.Status.Reason)
+		return fmt.Sprintf("The service account %s is not allowed to pull image stream tag %s/%s.", serviceAccountName, p.From.Namespace, p.From.Name), nil
+	}
+	return "", nil
+}
+
+// updateAccessDeniedCondition sets the image trigger access denied condition of the deployment
+// config to the message explaining the image stream tag its pod service account is not allowed to
+// pull, or removes an earlier condition when the message is empty. It returns the updated
+// deployment config, or the deployment config itself when the condition did not change.
+func (r *DeploymentConfigReactor) updateAccessDeniedCondition(dc *appsv1.DeploymentConfig, denied string) (*appsv1.DeploymentConfig, error) {
+	existing := appsutil.GetDeploymentCondition(dc.Status, deployutil.ImageTriggerAccessDenied)
+	if len(denied) == 0 && existing == nil {
+		return dc, nil
+	}
+	if len(denied) > 0 && existing != nil && existing.Status == corev1.ConditionTrue && existing.Message == denied {
+		return dc, nil
+	}
+
+	updated := dc.DeepCopy()
+	if len(denied) > 0 {
+		now := metav1.Now()
+		condition := appsv1.DeploymentCondition{
+			Type:               deployutil.ImageTriggerAccessDenied,
+			Status:             corev1.ConditionTrue,
+			LastUpdateTime:     now,
+			LastTransitionTime: now,
+			Reason:             deployutil.ImagePullAccessDeniedReason,
+			Message:            denied,
+		}
+		if existing != nil && existing.Status == corev1.ConditionTrue {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		appsutil.RemoveDeploymentCondition(&updated.Status, deployutil.ImageTriggerAccessDenied)
+		appsutil.SetDeploymentCondition(&updated.Status, condition)
+	} else {
+		appsutil.RemoveDeploymentCondition(&updated.Status, deployutil.ImageTriggerAccessDenied)
+	}
+	result, err := r.Client.DeploymentConfigs(dc.Namespace).UpdateStatus(context.TODO(), updated, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("error updating the image trigger access condition of deployment config %s/%s: %v", dc.Namespace, dc.Name, err)
+	}
+	return result, nil
+}
+
 func printDeploymentTriggers(triggers []appsv1.DeploymentTriggerPolicy) string {
 	var values []string
 	for _, t := range triggers {
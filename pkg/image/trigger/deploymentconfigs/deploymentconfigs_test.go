@@ -10,6 +10,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/diff"
 	testingcore "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	kapihelper "k8s.io/kubernetes/pkg/apis/core/helper"
 
 	appsv1 "github.com/openshift/api/apps/v1"
@@ -428,3 +429,126 @@ func TestDeploymentConfigReactor(t *testing.T) {
 		})
 	}
 }
+
+func TestDeploymentConfigTriggerIndexerResume(t *testing.T) {
+	paused := testDeploymentConfig([]appsv1.DeploymentTriggerImageChangeParams{
+		{Automatic: true, ContainerNames: []string{"first"}, From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream:1"}},
+	}, map[string]string{"first": "image/result:1"})
+	paused.Spec.Paused = true
+	resumed := paused.DeepCopy()
+	resumed.Spec.Paused = false
+
+	indexer := NewDeploymentConfigTriggerIndexer("deploymentconfigs/")
+	if _, _, change, _ := indexer.Index(resumed, paused); change != cache.Updated {
+		t.Errorf("expected resuming the deployment config to re-resolve its triggers, got change %q", change)
+	}
+	if _, _, change, _ := indexer.Index(paused, resumed); change != "" {
+		t.Errorf("expected pausing the deployment config to leave its triggers, got change %q", change)
+	}
+}
+
+func TestUpdateDeploymentConfigImagesPerTrigger(t *testing.T) {
+	params := func(first, second string) []appsv1.DeploymentTriggerImageChangeParams {
+		return []appsv1.DeploymentTriggerImageChangeParams{
+			{Automatic: true, ContainerNames: []string{"first"}, From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream-1:1"}, LastTriggeredImage: first},
+			{Automatic: true, ContainerNames: []string{"second"}, From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream-2:1"}, LastTriggeredImage: second},
+		}
+	}
+	tags := func(first, second string) fakeTagRetriever {
+		return fakeTagRetriever{
+			{Namespace: "default", Name: "stream-1:1", Ref: first, RV: 2},
+			{Namespace: "default", Name: "stream-2:1", Ref: second, RV: 2},
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		obj      *appsv1.DeploymentConfig
+		tags     fakeTagRetriever
+		expected *appsv1.DeploymentConfig
+	}{
+		{
+			name: "no change",
+			obj:  testDeploymentConfig(params("image-1", "image-2"), map[string]string{"first": "image-1", "second": "image-2", "third": "other"}),
+			tags: tags("image-1", "image-2"),
+		},
+		{
+			name:     "first trigger",
+			obj:      testDeploymentConfig(params("image-1", "image-2"), map[string]string{"first": "image-1", "second": "image-2", "third": "other"}),
+			tags:     tags("image-3", "image-2"),
+			expected: testDeploymentConfig(params("image-3", "image-2"), map[string]string{"first": "image-3", "second": "image-2", "third": "other"}),
+		},
+		{
+			name:     "second trigger to the image of the first",
+			obj:      testDeploymentConfig(params("image-1", "image-2"), map[string]string{"first": "image-1", "second": "image-2", "third": "other"}),
+			tags:     tags("image-1", "image-1"),
+			expected: testDeploymentConfig(params("image-1", "image-1"), map[string]string{"first": "image-1", "second": "image-1", "third": "other"}),
+		},
+		{
+			name:     "both triggers",
+			obj:      testDeploymentConfig(params("image-1", "image-2"), map[string]string{"first": "image-1", "second": "image-2", "third": "other"}),
+			tags:     tags("image-3", "image-4"),
+			expected: testDeploymentConfig(params("image-3", "image-4"), map[string]string{"first": "image-3", "second": "image-4", "third": "other"}),
+		},
+		{
+			// the container image was changed by hand, the trigger did not fire
+			name:     "container changed without the trigger",
+			obj:      testDeploymentConfig(params("image-1", "image-2"), map[string]string{"first": "image-1", "second": "manual", "third": "other"}),
+			tags:     tags("image-1", "image-2"),
+			expected: testDeploymentConfig(params("image-1", "image-2"), map[string]string{"first": "image-1", "second": "image-2", "third": "other"}),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			original := test.obj.DeepCopy()
+			updated, resolvable, err := UpdateDeploymentConfigImages(test.obj, test.tags)
+			if err != nil || !resolvable {
+				t.Fatalf("expected the triggers to resolve, got %v %v", resolvable, err)
+			}
+			if !reflect.DeepEqual(original, test.obj) {
+				t.Errorf("unexpected mutation of the deployment config: %s", diff.ObjectReflectDiff(original, test.obj))
+			}
+			if !reflect.DeepEqual(test.expected, updated) {
+				t.Fatalf("not equal: %s", diff.ObjectReflectDiff(test.expected, updated))
+			}
+			if updated == nil {
+				return
+			}
+			// the triggers are stable once their images are set
+			if again, _, _ := UpdateDeploymentConfigImages(updated, test.tags); again != nil {
+				t.Errorf("expected no further update, got %s", diff.ObjectReflectDiff(updated, again))
+			}
+		})
+	}
+}
+
+func TestUpdateDeploymentConfigImagesSharedContainer(t *testing.T) {
+	dc := testDeploymentConfig([]appsv1.DeploymentTriggerImageChangeParams{
+		{Automatic: true, ContainerNames: []string{"first"}, From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream-1:1"}},
+		{Automatic: true, ContainerNames: []string{"first", "second"}, From: corev1.ObjectReference{Kind: "ImageStreamTag", Name: "stream-2:1"}},
+	}, map[string]string{"first": "", "second": ""})
+	tags := fakeTagRetriever{
+		{Namespace: "default", Name: "stream-1:1", Ref: "image-1", RV: 2},
+		{Namespace: "default", Name: "stream-2:1", Ref: "image-2", RV: 2},
+	}
+
+	updated, resolvable, err := UpdateDeploymentConfigImages(dc, tags)
+	if err != nil || !resolvable || updated == nil {
+		t.Fatalf("expected the triggers to update the deployment config, got %v %v", resolvable, err)
+	}
+	// the container named by both triggers follows the first
+	for _, c := range updated.Spec.Template.Spec.Containers {
+		if expected := map[string]string{"first": "image-1", "second": "image-2"}[c.Name]; c.Image != expected {
+			t.Errorf("expected container %s to run image %s, got %s", c.Name, expected, c.Image)
+		}
+	}
+	for i, expected := range []string{"image-1", "image-2"} {
+		if actual := updated.Spec.Triggers[i].ImageChangeParams.LastTriggeredImage; actual != expected {
+			t.Errorf("expected trigger %d to have last triggered image %s, got %s", i, expected, actual)
+		}
+	}
+	if again, _, _ := UpdateDeploymentConfigImages(updated, tags); again != nil {
+		t.Errorf("expected no further update, got %s", diff.ObjectReflectDiff(updated, again))
+	}
+}
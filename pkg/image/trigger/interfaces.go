@@ -1,6 +1,8 @@
 package trigger
 
 import (
+	"time"
+
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/cache"
 
@@ -11,6 +13,9 @@ type CacheEntry struct {
 	Key       string
 	Namespace string
 	Triggers  []trigger.ObjectFieldTrigger
+	// Delay is how long the changes of the images of the triggers are coalesced before the object
+	// is queued, so that the object reacts once to the newest images of a burst of changes.
+	Delay time.Duration
 }
 
 type Indexer interface {